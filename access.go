@@ -0,0 +1,217 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrOutsideAccessWindow indicates a token is used outside of all its access windows
+	ErrOutsideAccessWindow = errors.New("token used outside of its access windows")
+
+	// ErrSourceNotAllowed indicates a token is used from an address outside of its allowed networks
+	ErrSourceNotAllowed = errors.New("token used from a source address that is not allowed")
+)
+
+var accessWindowDays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// AccessWindow is a recurring period of the week during which a token may be used
+type AccessWindow struct {
+	// Days the window starts on as mon, tue, wed, thu, fri, sat or sun, empty means every day
+	Days []string `json:"days,omitempty"`
+
+	// Start is the time of day, in HH:MM format, the window opens
+	Start string `json:"start"`
+
+	// End is the time of day, in HH:MM format, the window closes, 24:00 closes at midnight and windows ending before
+	// they start continue into the next day
+	End string `json:"end"`
+
+	// TimeZone is the IANA name of the time zone Start and End are in, defaults to UTC
+	TimeZone string `json:"tz,omitempty"`
+}
+
+// AccessRestrictions limit when and from where a client token may be used, the AAA service and the broker enforce
+// them using ClientIDClaims.CheckAccess
+type AccessRestrictions struct {
+	// Windows are periods during which the token may be used, empty means any time
+	Windows []AccessWindow `json:"windows,omitempty"`
+
+	// SourceCIDRs are the networks the token may be used from, empty means any address
+	SourceCIDRs []string `json:"source_cidrs,omitempty"`
+}
+
+// CheckAccess returns an error when the client may not use the token at t from the address source, source may be
+// nil when it is not known in which case tokens with source restrictions are rejected
+func (c *ClientIDClaims) CheckAccess(t time.Time, source net.IP) error {
+	return c.Access.Check(t, source)
+}
+
+// Check returns an error when t is outside all windows or source is outside all networks
+func (r *AccessRestrictions) Check(t time.Time, source net.IP) error {
+	if r == nil {
+		return nil
+	}
+
+	if len(r.Windows) > 0 {
+		open := false
+		for i := range r.Windows {
+			ok, err := r.Windows[i].contains(t)
+			if err != nil {
+				return err
+			}
+
+			if ok {
+				open = true
+				break
+			}
+		}
+
+		if !open {
+			return ErrOutsideAccessWindow
+		}
+	}
+
+	if len(r.SourceCIDRs) > 0 {
+		if source == nil {
+			return fmt.Errorf("%w: source address is not known", ErrSourceNotAllowed)
+		}
+
+		for _, cidr := range r.SourceCIDRs {
+			_, network, err := net.ParseCIDR(cidr)
+			if err != nil {
+				return fmt.Errorf("invalid source cidr %q: %w", cidr, err)
+			}
+
+			if network.Contains(source) {
+				return nil
+			}
+		}
+
+		return fmt.Errorf("%w: %s", ErrSourceNotAllowed, source)
+	}
+
+	return nil
+}
+
+// Validate ensures all windows and networks are valid
+func (r *AccessRestrictions) Validate() error {
+	if r == nil {
+		return nil
+	}
+
+	for i := range r.Windows {
+		_, _, _, err := r.Windows[i].parse()
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, cidr := range r.SourceCIDRs {
+		_, _, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return fmt.Errorf("invalid source cidr %q: %w", cidr, err)
+		}
+	}
+
+	return nil
+}
+
+// contains determines if t falls within the window
+func (w *AccessWindow) contains(t time.Time) (bool, error) {
+	start, end, loc, err := w.parse()
+	if err != nil {
+		return false, err
+	}
+
+	t = t.In(loc)
+	minute := t.Hour()*60 + t.Minute()
+
+	if start < end {
+		return w.onDay(t.Weekday()) && minute >= start && minute < end, nil
+	}
+
+	// the window continues into the next day so the remainder belongs to the previous day
+	if minute >= start && w.onDay(t.Weekday()) {
+		return true, nil
+	}
+
+	return minute < end && w.onDay((t.Weekday()+6)%7), nil
+}
+
+// onDay determines if the window opens on day
+func (w *AccessWindow) onDay(day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+
+	for _, d := range w.Days {
+		if accessWindowDays[strings.ToLower(d)] == day {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parse validates the window and returns its start and end as minutes of the day and its location
+func (w *AccessWindow) parse() (start int, end int, loc *time.Location, err error) {
+	for _, d := range w.Days {
+		if _, ok := accessWindowDays[strings.ToLower(d)]; !ok {
+			return 0, 0, nil, fmt.Errorf("invalid access window day %q", d)
+		}
+	}
+
+	start, err = parseTimeOfDay(w.Start)
+	if err != nil || start == 24*60 {
+		return 0, 0, nil, fmt.Errorf("invalid access window start %q", w.Start)
+	}
+
+	end, err = parseTimeOfDay(w.End)
+	if err != nil {
+		return 0, 0, nil, fmt.Errorf("invalid access window end %q", w.End)
+	}
+
+	if start == end {
+		return 0, 0, nil, fmt.Errorf("access window start and end cannot be equal")
+	}
+
+	loc = time.UTC
+	if w.TimeZone != "" {
+		loc, err = time.LoadLocation(w.TimeZone)
+		if err != nil {
+			return 0, 0, nil, fmt.Errorf("invalid access window time zone %q: %w", w.TimeZone, err)
+		}
+	}
+
+	return start, end, loc, nil
+}
+
+// parseTimeOfDay parses HH:MM into minutes since midnight, 24:00 is accepted as the end of the day
+func parseTimeOfDay(s string) (int, error) {
+	if s == "24:00" {
+		return 24 * 60, nil
+	}
+
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+
+	return t.Hour()*60 + t.Minute(), nil
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Access restrictions", func() {
+	// 2026-10-12 is a Monday
+	at := func(day int, hour int, minute int) time.Time {
+		return time.Date(2026, 10, 12+day, hour, minute, 0, 0, time.UTC)
+	}
+
+	It("Should allow unrestricted tokens", func() {
+		claims := &ClientIDClaims{}
+		Expect(claims.CheckAccess(time.Now(), nil)).To(Succeed())
+		Expect(claims.Access.Validate()).To(Succeed())
+	})
+
+	It("Should enforce windows", func() {
+		access := &AccessRestrictions{Windows: []AccessWindow{
+			{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "09:00", End: "17:30"},
+			{Days: []string{"Sat"}, Start: "22:00", End: "02:00"},
+		}}
+		Expect(access.Validate()).To(Succeed())
+
+		Expect(access.Check(at(0, 9, 0), nil)).To(Succeed())
+		Expect(access.Check(at(4, 17, 29), nil)).To(Succeed())
+		Expect(access.Check(at(0, 8, 59), nil)).To(MatchError(ErrOutsideAccessWindow))
+		Expect(access.Check(at(0, 17, 30), nil)).To(MatchError(ErrOutsideAccessWindow))
+
+		By("Continuing windows into the next day")
+		Expect(access.Check(at(5, 23, 0), nil)).To(Succeed())
+		Expect(access.Check(at(6, 1, 59), nil)).To(Succeed())
+		Expect(access.Check(at(6, 2, 0), nil)).To(MatchError(ErrOutsideAccessWindow))
+		Expect(access.Check(at(6, 23, 0), nil)).To(MatchError(ErrOutsideAccessWindow))
+		Expect(access.Check(at(5, 1, 0), nil)).To(MatchError(ErrOutsideAccessWindow))
+	})
+
+	It("Should support time zones", func() {
+		access := &AccessRestrictions{Windows: []AccessWindow{{Start: "09:00", End: "24:00", TimeZone: "Africa/Johannesburg"}}}
+		Expect(access.Check(at(0, 7, 0), nil)).To(Succeed())
+		Expect(access.Check(at(0, 21, 59), nil)).To(Succeed())
+		Expect(access.Check(at(0, 22, 0), nil)).To(MatchError(ErrOutsideAccessWindow))
+	})
+
+	It("Should enforce source networks", func() {
+		access := &AccessRestrictions{SourceCIDRs: []string{"192.168.1.0/24", "2001:db8::/32"}}
+		Expect(access.Check(time.Now(), net.ParseIP("192.168.1.10"))).To(Succeed())
+		Expect(access.Check(time.Now(), net.ParseIP("2001:db8::1"))).To(Succeed())
+		Expect(access.Check(time.Now(), net.ParseIP("10.0.0.1"))).To(MatchError("token used from a source address that is not allowed: 10.0.0.1"))
+		Expect(access.Check(time.Now(), nil)).To(MatchError(ErrSourceNotAllowed))
+	})
+
+	It("Should round trip through tokens and validate", func() {
+		claims, err := NewClientIDBuilder("up=bob").
+			WithAllowedAgents("*").
+			WithAccessRestrictions(AccessRestrictions{SourceCIDRs: []string{"10.0.0.0/8"}}).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		token, err := SignTokenWithKeyFile(claims, "testdata/ed25519/signer.seed")
+		Expect(err).ToNot(HaveOccurred())
+		parsed, err := ParseClientIDTokenWithKeyfile(token, "testdata/ed25519/signer.public", true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(parsed.CheckAccess(time.Now(), net.ParseIP("10.1.1.1"))).To(Succeed())
+
+		_, err = NewClientIDBuilder("up=bob").WithAccessRestrictions(AccessRestrictions{SourceCIDRs: []string{"10.0.0.0"}}).Build()
+		Expect(err).To(MatchError(ContainSubstring(`invalid source cidr "10.0.0.0"`)))
+
+		for _, w := range []AccessWindow{
+			{Days: []string{"monday"}, Start: "09:00", End: "10:00"},
+			{Start: "24:00", End: "10:00"},
+			{Start: "09:00", End: "9am"},
+			{Start: "09:00", End: "09:00"},
+			{Start: "09:00", End: "10:00", TimeZone: "Mars/Olympus"},
+		} {
+			Expect((&AccessRestrictions{Windows: []AccessWindow{w}}).Validate()).To(HaveOccurred())
+		}
+	})
+
+	It("Should enforce restrictions while parsing when requested", func() {
+		claims, err := NewClientIDBuilder("up=bob").
+			WithAccessRestrictions(AccessRestrictions{
+				Windows:     []AccessWindow{{Days: []string{"mon"}, Start: "09:00", End: "17:00"}},
+				SourceCIDRs: []string{"10.0.0.0/8"},
+			}).
+			WithValidity(12 * time.Hour).
+			WithOptions(WithClaimsClock(FixedClock(at(0, 8, 0)))).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		pk, pri := loadEd25519Seed("testdata/ed25519/signer.seed")
+		token, err := SignToken(claims, pri)
+		Expect(err).ToNot(HaveOccurred())
+
+		clock := WithVerificationClock(FixedClock(at(0, 10, 30)))
+		Expect(ParseToken(token, &ClientIDClaims{}, pk, clock)).To(Succeed())
+		Expect(ParseToken(token, &ClientIDClaims{}, pk, clock, WithAccessSource(net.ParseIP("10.1.1.1")))).To(Succeed())
+		Expect(ParseToken(token, &ClientIDClaims{}, pk, clock, WithAccessSource(net.ParseIP("192.168.1.1")))).To(MatchError(ErrSourceNotAllowed))
+		Expect(ParseToken(token, &ClientIDClaims{}, pk, clock, WithAccessSource(nil))).To(MatchError(ErrSourceNotAllowed))
+
+		late := WithVerificationClock(FixedClock(at(0, 17, 30)))
+		Expect(ParseToken(token, &ClientIDClaims{}, pk, late)).To(Succeed())
+		Expect(ParseToken(token, &ClientIDClaims{}, pk, late, WithAccessSource(net.ParseIP("10.1.1.1")))).To(MatchError(ErrOutsideAccessWindow))
+	})
+})
@@ -0,0 +1,211 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// ErrActionDenied indicates a client may not invoke an action on an agent
+var ErrActionDenied = errors.New("agent action denied")
+
+// ActionGrant allows invoking actions on agents, Agent and Actions hold patterns using path.Match syntax
+type ActionGrant struct {
+	// Agent is a pattern of agent names the grant applies to
+	Agent string `json:"agent"`
+
+	// Actions are patterns of action names the grant allows, empty means all
+	Actions []string `json:"actions,omitempty"`
+}
+
+// Allows determines if the grant allows action on agent
+func (g *ActionGrant) Allows(agent string, action string) bool {
+	ok, err := path.Match(g.Agent, agent)
+	if err != nil || !ok {
+		return false
+	}
+
+	return matchAnyPattern(g.Actions, action)
+}
+
+func (g *ActionGrant) validate() error {
+	if g.Agent == "" {
+		return fmt.Errorf("action grants require an agent")
+	}
+
+	for _, pattern := range append([]string{g.Agent}, g.Actions...) {
+		_, err := path.Match(pattern, "")
+		if err != nil {
+			return fmt.Errorf("invalid action grant pattern %q: %w", pattern, err)
+		}
+	}
+
+	return nil
+}
+
+// AllowsAction determines if the client may invoke action on agent. When the token has an ACL only its grants are
+// considered, otherwise AllowedAgents is used as in IsAgentActionAllowed
+func (c *ClientIDClaims) AllowsAction(agent string, action string) bool {
+	if len(c.ACL) == 0 {
+		return c.IsAgentActionAllowed(agent, action)
+	}
+
+	for i := range c.ACL {
+		if c.ACL[i].Allows(agent, action) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// allowsGrant determines if the client may invoke every action the grant g allows, patterns in g are only
+// covered by identical or broader patterns held by the client
+func (c *ClientIDClaims) allowsGrant(g ActionGrant) bool {
+	if len(c.ACL) == 0 {
+		return agentsAllowGrant(c.AllowedAgents, g)
+	}
+
+	if len(g.Actions) == 0 {
+		for i := range c.ACL {
+			if len(c.ACL[i].Actions) == 0 && patternCovers(c.ACL[i].Agent, g.Agent) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	for _, action := range g.Actions {
+		covered := false
+		for i := range c.ACL {
+			if !patternCovers(c.ACL[i].Agent, g.Agent) {
+				continue
+			}
+
+			if len(c.ACL[i].Actions) == 0 {
+				covered = true
+				break
+			}
+
+			for _, pattern := range c.ACL[i].Actions {
+				if patternCovers(pattern, action) {
+					covered = true
+					break
+				}
+			}
+		}
+
+		if !covered {
+			return false
+		}
+	}
+
+	return true
+}
+
+// agentAllowed determines if agent, in agent or agent.action form, is allowed by the list of allowed agents. This is
+// the only matcher for AllowedAgents style lists, entries are * for all agents, an agent name or agent.* for all
+// actions of the agent or agent.action for a single action, ACL grants are matched using path.Match patterns instead
+func agentAllowed(allowed []string, agent string) bool {
+	name, _, _ := strings.Cut(agent, ".")
+
+	for _, a := range allowed {
+		if a == "*" || a == agent || a == name || a == name+".*" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// agentsAllowGrant determines if the agent, agent.action or * entries in allowed permit every action the grant g allows
+func agentsAllowGrant(allowed []string, g ActionGrant) bool {
+	if hasPattern(g.Agent) || len(g.Actions) == 0 {
+		return agentAllowed(allowed, g.Agent)
+	}
+
+	for _, action := range g.Actions {
+		if hasPattern(action) && !agentAllowed(allowed, g.Agent) {
+			return false
+		}
+		if !hasPattern(action) && !agentAllowed(allowed, g.Agent+"."+action) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// uncoveredGrant is the agent of the first grant in acl that allowed does not permit, empty when all are permitted
+func uncoveredGrant(allowed []string, acl []ActionGrant) string {
+	for _, g := range acl {
+		if !agentsAllowGrant(allowed, g) {
+			return g.Agent
+		}
+	}
+
+	return ""
+}
+
+// patternCovers determines if everything matched by the path.Match pattern p is also matched by pattern
+func patternCovers(pattern string, p string) bool {
+	if pattern == "*" || pattern == p {
+		return true
+	}
+
+	if hasPattern(p) {
+		return false
+	}
+
+	ok, err := path.Match(pattern, p)
+
+	return err == nil && ok
+}
+
+func hasPattern(p string) bool {
+	return strings.ContainsAny(p, `*?[\`)
+}
+
+// AuthorizeAction returns an error when the client may not invoke action on agent
+func (c *ClientIDClaims) AuthorizeAction(agent string, action string) error {
+	if !c.AllowsAction(agent, action) {
+		return fmt.Errorf("%w: %s.%s", ErrActionDenied, agent, action)
+	}
+
+	return nil
+}
+
+func validateActionGrants(grants []ActionGrant) error {
+	for i := range grants {
+		err := grants[i].validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// actionGrantEntries flattens grants into agent.action entries, empty action lists match all
+func actionGrantEntries(grants []ActionGrant) []string {
+	var res []string
+
+	for _, g := range grants {
+		actions := g.Actions
+		if len(actions) == 0 {
+			actions = []string{"*"}
+		}
+
+		for _, a := range actions {
+			res = append(res, g.Agent+"."+a)
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,69 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Action ACLs", func() {
+	It("Should use AllowedAgents when there is no ACL", func() {
+		claims, err := NewClientIDClaims("up=bob", []string{"rpcutil", "puppet.status"}, "", nil, "", "", time.Hour, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(claims.AllowsAction("rpcutil", "ping")).To(BeTrue())
+		Expect(claims.AllowsAction("puppet", "status")).To(BeTrue())
+		Expect(claims.AllowsAction("puppet", "runonce")).To(BeFalse())
+	})
+
+	It("Should only use the ACL when set", func() {
+		claims, err := NewClientIDBuilder("up=bob").
+			WithAllowedAgents("*").
+			WithActionGrants(ActionGrant{Agent: "puppet", Actions: []string{"status", "last_*"}}, ActionGrant{Agent: "rpc*"}).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+
+		token, err := SignTokenWithKeyFile(claims, "testdata/ed25519/signer.seed")
+		Expect(err).ToNot(HaveOccurred())
+		parsed, err := ParseClientIDTokenWithKeyfile(token, "testdata/ed25519/signer.public", true)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(parsed.AllowsAction("puppet", "status")).To(BeTrue())
+		Expect(parsed.AllowsAction("puppet", "last_run_summary")).To(BeTrue())
+		Expect(parsed.AllowsAction("rpcutil", "ping")).To(BeTrue())
+		Expect(parsed.AllowsAction("puppet", "runonce")).To(BeFalse())
+		Expect(parsed.AllowsAction("service", "restart")).To(BeFalse())
+
+		Expect(parsed.AuthorizeAction("puppet", "status")).To(Succeed())
+		Expect(parsed.AuthorizeAction("puppet", "runonce")).To(MatchError("agent action denied: puppet.runonce"))
+		Expect(parsed.AuthorizeAction("puppet", "runonce")).To(MatchError(ErrActionDenied))
+	})
+
+	It("Should validate grants", func() {
+		_, err := NewClientIDBuilder("up=bob").WithActionGrants(ActionGrant{Actions: []string{"status"}}).Build()
+		Expect(err).To(MatchError("action grants require an agent"))
+
+		claims, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		claims.ACL = []ActionGrant{{Agent: "puppet", Actions: []string{"[status"}}}
+		Expect(claims.Validate()).To(MatchError(ContainSubstring(`invalid action grant pattern "[status"`)))
+	})
+
+	It("Should report adding an ACL as a reduction", func() {
+		claims, err := NewClientIDClaims("up=bob", []string{"*"}, "", nil, "", "", time.Hour, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		token, err := SignTokenWithKeyFile(claims, "testdata/ed25519/signer.seed")
+		Expect(err).ToNot(HaveOccurred())
+
+		claims.ACL = []ActionGrant{{Agent: "puppet"}}
+		report, err := CompatibilityCheck(token, claims)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.IsDowngrade()).To(BeTrue())
+		Expect(report.Reductions()[0].String()).To(Equal(`acl: added "puppet.*"`))
+	})
+})
@@ -0,0 +1,367 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// AttestationType is the kind of hardware that produced an attestation
+type AttestationType string
+
+const (
+	// TPMAttestation is a TPM 2.0 quote signed by an attestation key
+	TPMAttestation AttestationType = "tpm"
+
+	// SecureElementAttestation is a signature made by a key held in a secure element
+	SecureElementAttestation AttestationType = "secure_element"
+
+	// MaxAttestationSize is the combined size in bytes of the certificates, quote and signature of an attestation
+	MaxAttestationSize = 16 * 1024
+
+	// tpmGeneratedValue is the magic value that starts every TPMS_ATTEST structure
+	tpmGeneratedValue = 0xff544347
+
+	// tpmSTAttestQuote is the TPMI_ST_ATTEST type of quotes
+	tpmSTAttestQuote = 0x8018
+)
+
+var (
+	// ErrAttestationFailed indicates a hardware attestation could not be verified
+	ErrAttestationFailed = errors.New("hardware attestation failed")
+
+	// ErrNoAttestation indicates a token does not hold a hardware attestation
+	ErrNoAttestation = errors.New("no hardware attestation")
+)
+
+// HardwareAttestation proves a server key was created on genuine hardware.
+//
+// For TPM attestations Quote is the TPMS_ATTEST structure of a quote with the qualifying data set to
+// AttestationBinding and Signature is the signature of the attestation key over the quote. For secure elements
+// Signature is made over AttestationBinding by the device key. ECDSA signatures are ASN.1 encoded and RSA
+// signatures use PKCS #1 v1.5, both using SHA-256
+type HardwareAttestation struct {
+	// Type is the kind of hardware
+	Type AttestationType `json:"type"`
+
+	// Certificates are DER encoded, the first certificate holds the key that made Signature followed by any intermediates
+	Certificates [][]byte `json:"certs"`
+
+	// Quote is the TPMS_ATTEST structure of TPM attestations
+	Quote []byte `json:"quote,omitempty"`
+
+	// Signature is the signature proving possession of the hardware key
+	Signature []byte `json:"sig"`
+}
+
+// WithHardwareAttestation embeds att into servers created using NewServerClaims, typically during provisioning
+func WithHardwareAttestation(att HardwareAttestation) ClaimsOption {
+	return func(o *claimsOptions) error {
+		err := validateHardwareAttestation(&att)
+		if err != nil {
+			return err
+		}
+
+		o.attestation = &att
+
+		return nil
+	}
+}
+
+// AttestationBinding is the data hardware attests to, it binds the attestation to the identity and ed25519 public key of a server
+func AttestationBinding(identity string, pk ed25519.PublicKey) []byte {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("choria_attestation.%s.%s", identity, hex.EncodeToString(pk))))
+	return sum[:]
+}
+
+// NewSecureElementAttestation creates an attestation for the server identity and public key pk using signer, a key held
+// in a secure element, chain is the certificate of that key followed by any intermediates
+func NewSecureElementAttestation(identity string, pk ed25519.PublicKey, chain []*x509.Certificate, signer crypto.Signer) (*HardwareAttestation, error) {
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("certificate chain is required")
+	}
+
+	if signer == nil {
+		return nil, fmt.Errorf("signer is required")
+	}
+
+	sig, err := attestationSign(signer, AttestationBinding(identity, pk))
+	if err != nil {
+		return nil, err
+	}
+
+	att := &HardwareAttestation{Type: SecureElementAttestation, Signature: sig}
+	for _, c := range chain {
+		att.Certificates = append(att.Certificates, c.Raw)
+	}
+
+	return att, validateHardwareAttestation(att)
+}
+
+// AttestationVerifier verifies hardware attestations against trusted endorsement roots
+type AttestationVerifier struct {
+	roots *x509.CertPool
+}
+
+// NewAttestationVerifier creates a verifier trusting roots, the certificate authorities of the hardware vendors
+func NewAttestationVerifier(roots ...*x509.Certificate) (*AttestationVerifier, error) {
+	if len(roots) == 0 {
+		return nil, fmt.Errorf("at least one endorsement root is required")
+	}
+
+	pool := x509.NewCertPool()
+	for _, r := range roots {
+		pool.AddCert(r)
+	}
+
+	return &AttestationVerifier{roots: pool}, nil
+}
+
+// NewAttestationVerifierFromFile creates a verifier trusting the PEM encoded endorsement roots in file
+func NewAttestationVerifierFromFile(file string) (*AttestationVerifier, error) {
+	dat, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read endorsement roots: %w", err)
+	}
+
+	var roots []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, dat = pem.Decode(dat)
+		if block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid endorsement root in %s: %w", file, err)
+		}
+		roots = append(roots, cert)
+	}
+
+	return NewAttestationVerifier(roots...)
+}
+
+// Verify checks that the attestation in claims chains to a trusted root and attests to the identity and public key of the server
+func (v *AttestationVerifier) Verify(claims *ServerClaims) error {
+	return v.verifyAt(claims, currentTime())
+}
+
+// verifyAt verifies the attestation in claims with the certificate chain checked at now
+func (v *AttestationVerifier) verifyAt(claims *ServerClaims, now time.Time) error {
+	att := claims.Attestation
+	if att == nil {
+		return ErrNoAttestation
+	}
+
+	err := validateHardwareAttestation(att)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrAttestationFailed, err)
+	}
+
+	pk := claimsPublicKey(&claims.StandardClaims)
+	if pk == nil {
+		return fmt.Errorf("%w: token does not hold a public key", ErrAttestationFailed)
+	}
+
+	leaf, err := v.verifyChain(att.Certificates, now)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrAttestationFailed, err)
+	}
+
+	binding := AttestationBinding(claims.ChoriaIdentity, pk)
+	signed := binding
+
+	if att.Type == TPMAttestation {
+		qualifying, err := tpmQuoteQualifyingData(att.Quote)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrAttestationFailed, err)
+		}
+
+		if subtle.ConstantTimeCompare(qualifying, binding) != 1 {
+			return fmt.Errorf("%w: quote does not attest to the server key", ErrAttestationFailed)
+		}
+
+		signed = att.Quote
+	}
+
+	err = attestationVerify(leaf.PublicKey, signed, att.Signature)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrAttestationFailed, err)
+	}
+
+	return nil
+}
+
+// verifyChain parses the DER certificates and verifies the first one chains to a trusted root at now
+func (v *AttestationVerifier) verifyChain(certs [][]byte, now time.Time) (*x509.Certificate, error) {
+	var parsed []*x509.Certificate
+	for _, der := range certs {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("invalid certificate: %w", err)
+		}
+		parsed = append(parsed, cert)
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, c := range parsed[1:] {
+		intermediates.AddCert(c)
+	}
+
+	_, err := parsed[0].Verify(x509.VerifyOptions{
+		Roots:         v.roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return parsed[0], nil
+}
+
+// tpmQuoteQualifyingData extracts the extraData field of a TPMS_ATTEST quote structure
+func tpmQuoteQualifyingData(quote []byte) ([]byte, error) {
+	r := bytes.NewReader(quote)
+
+	var hdr struct {
+		Magic uint32
+		Type  uint16
+	}
+	err := binary.Read(r, binary.BigEndian, &hdr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid quote: %w", err)
+	}
+
+	if hdr.Magic != tpmGeneratedValue || hdr.Type != tpmSTAttestQuote {
+		return nil, fmt.Errorf("invalid quote: not a TPM generated quote")
+	}
+
+	// qualifiedSigner is a TPM2B_NAME we do not need
+	_, err = readTPM2B(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid quote: %w", err)
+	}
+
+	extra, err := readTPM2B(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid quote: %w", err)
+	}
+
+	return extra, nil
+}
+
+// readTPM2B reads a size prefixed TPM2B structure
+func readTPM2B(r *bytes.Reader) ([]byte, error) {
+	var size uint16
+	err := binary.Read(r, binary.BigEndian, &size)
+	if err != nil {
+		return nil, err
+	}
+
+	if int(size) > r.Len() {
+		return nil, fmt.Errorf("truncated structure")
+	}
+
+	buf := make([]byte, size)
+	_, err = r.Read(buf)
+
+	return buf, err
+}
+
+// attestationSign signs msg using SHA-256 for ECDSA and RSA keys and directly for ed25519 keys
+func attestationSign(signer crypto.Signer, msg []byte) ([]byte, error) {
+	switch signer.Public().(type) {
+	case ed25519.PublicKey:
+		return signer.Sign(rand.Reader, msg, crypto.Hash(0))
+	case *ecdsa.PublicKey, *rsa.PublicKey:
+		digest := sha256.Sum256(msg)
+		return signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	default:
+		return nil, fmt.Errorf("unsupported attestation key %T", signer.Public())
+	}
+}
+
+// attestationVerify verifies signatures made by attestationSign
+func attestationVerify(pub any, msg []byte, sig []byte) error {
+	digest := sha256.Sum256(msg)
+
+	var valid bool
+	switch pk := pub.(type) {
+	case ed25519.PublicKey:
+		valid = ed25519.Verify(pk, msg, sig)
+	case *ecdsa.PublicKey:
+		valid = ecdsa.VerifyASN1(pk, digest[:], sig)
+	case *rsa.PublicKey:
+		valid = rsa.VerifyPKCS1v15(pk, crypto.SHA256, digest[:], sig) == nil
+	default:
+		return fmt.Errorf("unsupported attestation key %T", pub)
+	}
+
+	if !valid {
+		return fmt.Errorf("invalid signature")
+	}
+
+	return nil
+}
+
+func validateHardwareAttestation(att *HardwareAttestation) error {
+	if att == nil {
+		return nil
+	}
+
+	switch att.Type {
+	case TPMAttestation:
+		if len(att.Quote) == 0 {
+			return fmt.Errorf("tpm attestations require a quote")
+		}
+	case SecureElementAttestation:
+		if len(att.Quote) > 0 {
+			return fmt.Errorf("secure element attestations cannot hold a quote")
+		}
+	default:
+		return fmt.Errorf("unsupported attestation type %q", att.Type)
+	}
+
+	if len(att.Certificates) == 0 {
+		return fmt.Errorf("attestation certificates are required")
+	}
+
+	if len(att.Signature) == 0 {
+		return fmt.Errorf("attestation signature is required")
+	}
+
+	size := len(att.Quote) + len(att.Signature)
+	for _, c := range att.Certificates {
+		size += len(c)
+	}
+
+	if size > MaxAttestationSize {
+		return fmt.Errorf("attestation exceeds %d bytes", MaxAttestationSize)
+	}
+
+	return nil
+}
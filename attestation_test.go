@@ -0,0 +1,211 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Hardware attestation", func() {
+	var (
+		root      *x509.Certificate
+		device    *x509.Certificate
+		deviceKey *ecdsa.PrivateKey
+		pub       ed25519.PublicKey
+		pri       ed25519.PrivateKey
+		verifier  *AttestationVerifier
+		newCert   func(cn string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey, ca bool) (*x509.Certificate, *ecdsa.PrivateKey)
+		tpmQuote  func(extra []byte) []byte
+	)
+
+	BeforeEach(func() {
+		newCert = func(cn string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey, ca bool) (*x509.Certificate, *ecdsa.PrivateKey) {
+			key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+
+			template := &x509.Certificate{
+				SerialNumber:          big.NewInt(time.Now().UnixNano()),
+				Subject:               pkix.Name{CommonName: cn},
+				NotBefore:             time.Now().Add(-time.Hour),
+				NotAfter:              time.Now().Add(time.Hour),
+				IsCA:                  ca,
+				BasicConstraintsValid: true,
+				KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+			}
+
+			signer := parentKey
+			if parent == nil {
+				parent, signer = template, key
+			}
+
+			der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, signer)
+			Expect(err).ToNot(HaveOccurred())
+			cert, err := x509.ParseCertificate(der)
+			Expect(err).ToNot(HaveOccurred())
+
+			return cert, key
+		}
+
+		tpmQuote = func(extra []byte) []byte {
+			buf := &bytes.Buffer{}
+			binary.Write(buf, binary.BigEndian, uint32(tpmGeneratedValue))
+			binary.Write(buf, binary.BigEndian, uint16(tpmSTAttestQuote))
+			binary.Write(buf, binary.BigEndian, uint16(4))
+			buf.Write([]byte{0, 11, 1, 2})
+			binary.Write(buf, binary.BigEndian, uint16(len(extra)))
+			buf.Write(extra)
+			buf.Write(make([]byte, 17))
+
+			return buf.Bytes()
+		}
+
+		var rootKey *ecdsa.PrivateKey
+		root, rootKey = newCert("Vendor Root CA", nil, nil, true)
+		device, deviceKey = newCert("Device", root, rootKey, false)
+
+		var err error
+		pub, pri, err = ed25519.GenerateKey(nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		verifier, err = NewAttestationVerifier(root)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Should verify secure element attestations", func() {
+		att, err := NewSecureElementAttestation("n1.example.net", pub, []*x509.Certificate{device}, deviceKey)
+		Expect(err).ToNot(HaveOccurred())
+
+		claims, err := NewServerClaims("n1.example.net", []string{"choria"}, "", nil, nil, pub, "", time.Hour, WithHardwareAttestation(*att))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(verifier.Verify(claims)).To(Succeed())
+
+		token, err := SignToken(claims, pri)
+		Expect(err).ToNot(HaveOccurred())
+		parsed, err := ParseServerToken(token, pub, WithRequiredAttestation(verifier))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(parsed.Attestation.Type).To(Equal(SecureElementAttestation))
+
+		claims.ChoriaIdentity = "n2.example.net"
+		Expect(verifier.Verify(claims)).To(MatchError(ContainSubstring("invalid signature")))
+	})
+
+	It("Should verify certificates using the verification clock", func() {
+		att, err := NewSecureElementAttestation("n1.example.net", pub, []*x509.Certificate{device}, deviceKey)
+		Expect(err).ToNot(HaveOccurred())
+		claims, err := NewServerClaims("n1.example.net", []string{"choria"}, "", nil, nil, pub, "", 3*time.Hour, WithHardwareAttestation(*att))
+		Expect(err).ToNot(HaveOccurred())
+		token, err := SignToken(claims, pri)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = ParseServerToken(token, pub, WithRequiredAttestation(verifier))
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = ParseServerToken(token, pub, WithRequiredAttestation(verifier), WithVerificationClock(FixedClock(time.Now().Add(2*time.Hour))))
+		Expect(err).To(MatchError(ErrAttestationFailed))
+		Expect(err).To(MatchError(ContainSubstring("expired")))
+	})
+
+	It("Should require server tokens to be parsed into server claims", func() {
+		att, err := NewSecureElementAttestation("n1.example.net", pub, []*x509.Certificate{device}, deviceKey)
+		Expect(err).ToNot(HaveOccurred())
+		claims, err := NewServerClaims("n1.example.net", []string{"choria"}, "", nil, nil, pub, "", time.Hour, WithHardwareAttestation(*att))
+		Expect(err).ToNot(HaveOccurred())
+		token, err := SignToken(claims, pri)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(ParseToken(token, &jwt.MapClaims{}, pub)).To(Succeed())
+		Expect(ParseToken(token, &jwt.MapClaims{}, pub, WithRequiredAttestation(verifier))).To(MatchError(ErrAttestationFailed))
+		Expect(ParseToken(token, &StandardClaims{}, pub, WithRequiredAttestation(verifier))).To(MatchError(ErrAttestationFailed))
+		Expect(ParseToken(token, &ServerClaims{}, pub, WithRequiredAttestation(verifier))).To(Succeed())
+	})
+
+	It("Should verify TPM attestations", func() {
+		quote := tpmQuote(AttestationBinding("n1.example.net", pub))
+		sig, err := attestationSign(deviceKey, quote)
+		Expect(err).ToNot(HaveOccurred())
+
+		att := HardwareAttestation{Type: TPMAttestation, Certificates: [][]byte{device.Raw}, Quote: quote, Signature: sig}
+		claims, err := NewServerClaims("n1.example.net", []string{"choria"}, "", nil, nil, pub, "", time.Hour, WithHardwareAttestation(att))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(verifier.Verify(claims)).To(Succeed())
+
+		otherPub, _, err := ed25519.GenerateKey(nil)
+		Expect(err).ToNot(HaveOccurred())
+		claims.PublicKey = hex.EncodeToString(otherPub)
+		Expect(verifier.Verify(claims)).To(MatchError("hardware attestation failed: quote does not attest to the server key"))
+
+		claims.Attestation.Quote = []byte{1, 2, 3}
+		Expect(verifier.Verify(claims)).To(MatchError(ContainSubstring("invalid quote")))
+	})
+
+	It("Should require trusted roots", func() {
+		otherRoot, otherKey := newCert("Other Root CA", nil, nil, true)
+		otherDevice, otherDeviceKey := newCert("Other Device", otherRoot, otherKey, false)
+
+		att, err := NewSecureElementAttestation("n1.example.net", pub, []*x509.Certificate{otherDevice}, otherDeviceKey)
+		Expect(err).ToNot(HaveOccurred())
+		claims, err := NewServerClaims("n1.example.net", []string{"choria"}, "", nil, nil, pub, "", time.Hour, WithHardwareAttestation(*att))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(verifier.Verify(claims)).To(MatchError(ErrAttestationFailed))
+
+		file := filepath.Join(GinkgoT().TempDir(), "roots.pem")
+		Expect(os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: otherRoot.Raw}), 0600)).To(Succeed())
+		other, err := NewAttestationVerifierFromFile(file)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(other.Verify(claims)).To(Succeed())
+	})
+
+	It("Should require attestations when configured", func() {
+		claims, err := NewServerClaims("n1.example.net", []string{"choria"}, "", nil, nil, pub, "", time.Hour)
+		Expect(err).ToNot(HaveOccurred())
+		token, err := SignToken(claims, pri)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(ParseToken(token, &ServerClaims{}, pub)).To(Succeed())
+		Expect(ParseToken(token, &ServerClaims{}, pub, WithRequiredAttestation(verifier))).To(MatchError(ErrNoAttestation))
+
+		report, err := ValidateToken(token, &ServerClaims{}, pub, WithRequiredAttestation(verifier))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.Err()).To(MatchError(ErrNoAttestation))
+
+		client, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		token, err = SignToken(client, pri)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ParseToken(token, &ClientIDClaims{}, pub, WithRequiredAttestation(verifier))).To(Succeed())
+	})
+
+	It("Should validate attestations", func() {
+		_, err := NewServerClaims("n1.example.net", []string{"choria"}, "", nil, nil, pub, "", time.Hour, WithHardwareAttestation(HardwareAttestation{Type: "other"}))
+		Expect(err).To(MatchError(`unsupported attestation type "other"`))
+
+		_, err = NewServerClaims("n1.example.net", []string{"choria"}, "", nil, nil, pub, "", time.Hour, WithHardwareAttestation(HardwareAttestation{Type: TPMAttestation, Certificates: [][]byte{device.Raw}, Signature: []byte{1}}))
+		Expect(err).To(MatchError("tpm attestations require a quote"))
+
+		_, err = NewServerClaims("n1.example.net", []string{"choria"}, "", nil, nil, pub, "", time.Hour, WithHardwareAttestation(HardwareAttestation{Type: SecureElementAttestation, Certificates: [][]byte{device.Raw}, Signature: make([]byte, MaxAttestationSize)}))
+		Expect(err).To(MatchError(ContainSubstring("attestation exceeds")))
+
+		_, err = NewAttestationVerifier()
+		Expect(err).To(MatchError("at least one endorsement root is required"))
+		Expect(ParseToken("", &ServerClaims{}, pub, WithRequiredAttestation(nil))).To(MatchError("attestation verifier is required"))
+	})
+})
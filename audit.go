@@ -0,0 +1,200 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// AuditRecord describes a single token issuance
+type AuditRecord struct {
+	// Time is when the token was signed
+	Time time.Time `json:"time"`
+
+	// Purpose is the purpose of the signed token
+	Purpose Purpose `json:"purpose"`
+
+	// Identity is the caller id, server identity or subject the token was issued to
+	Identity string `json:"identity,omitempty"`
+
+	// TokenID is the unique id of the token
+	TokenID string `json:"jti,omitempty"`
+
+	// Issuer is the issuer claim of the token
+	Issuer string `json:"issuer,omitempty"`
+
+	// Signer identifies the signing key, the hex encoded public key for ed25519, the sha256 fingerprint of the
+	// public key for RSA or vault:<name> for Vault Transit keys
+	Signer string `json:"signer"`
+
+	// Algorithm is the JWT signing algorithm
+	Algorithm string `json:"algorithm"`
+
+	// Permissions are the names of the permissions granted to client and server tokens
+	Permissions []string `json:"permissions,omitempty"`
+
+	// NotBefore is when the token becomes valid, when set
+	NotBefore time.Time `json:"not_before,omitempty"`
+
+	// ExpiresAt is when the token expires
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
+// AuditSink receives a record for every token signed, see SetAuditSink
+type AuditSink interface {
+	// RecordIssuance is called after a token is signed, the token is not returned to the caller when it fails
+	RecordIssuance(ctx context.Context, record AuditRecord) error
+}
+
+type auditSinkHolder struct {
+	sink AuditSink
+}
+
+var auditSink atomic.Value
+
+// SetAuditSink sets the sink recording all tokens signed, nil disables auditing.  When the sink fails signing fails
+// so that no token is issued without a record
+func SetAuditSink(sink AuditSink) {
+	auditSink.Store(auditSinkHolder{sink: sink})
+}
+
+func currentAuditSink() AuditSink {
+	h, _ := auditSink.Load().(auditSinkHolder)
+	return h.sink
+}
+
+// recordIssuance records the issuance of claims in the configured audit sink
+func recordIssuance(ctx context.Context, claims jwt.Claims, alg string, signer string) error {
+	sink := currentAuditSink()
+	if sink == nil {
+		return nil
+	}
+
+	record := AuditRecord{
+		Time:        time.Now().UTC(),
+		Identity:    claimsIdentity(claims),
+		Signer:      signer,
+		Algorithm:   alg,
+		Permissions: claimsPermissions(claims),
+	}
+
+	if sc, ok := claims.(standardClaimsProvider); ok {
+		std := sc.getStandardClaims()
+		record.Purpose = std.Purpose
+		record.TokenID = std.ID
+		record.Issuer = std.Issuer
+		record.ExpiresAt = std.ExpireTime()
+		if std.NotBefore != nil {
+			record.NotBefore = std.NotBefore.Time
+		}
+	}
+
+	err := sink.RecordIssuance(ctx, record)
+	if err != nil {
+		return fmt.Errorf("could not record token issuance: %w", err)
+	}
+
+	return nil
+}
+
+// signerIdentity identifies the private key pk in audit records
+func signerIdentity(pk any) string {
+	var pub crypto.PublicKey
+
+	switch pri := pk.(type) {
+	case ed25519.PrivateKey:
+		pub = pri.Public()
+	case *rsa.PrivateKey:
+		pub = &pri.PublicKey
+	case crypto.Signer:
+		pub = pri.Public()
+	default:
+		return ""
+	}
+
+	switch k := pub.(type) {
+	case ed25519.PublicKey:
+		return hex.EncodeToString(k)
+	default:
+		der, err := x509.MarshalPKIXPublicKey(k)
+		if err != nil {
+			return ""
+		}
+		sum := sha256.Sum256(der)
+
+		return "sha256:" + hex.EncodeToString(sum[:])
+	}
+}
+
+// JSONLinesAuditSink is an AuditSink appending each record as a line of JSON to a file
+type JSONLinesAuditSink struct {
+	file *os.File
+	mu   sync.Mutex
+}
+
+var _ AuditSink = (*JSONLinesAuditSink)(nil)
+
+// NewJSONLinesAuditSink opens, or creates using perm, the file at path for appending audit records
+func NewJSONLinesAuditSink(path string, perm os.FileMode) (*JSONLinesAuditSink, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	return &JSONLinesAuditSink{file: f}, nil
+}
+
+// RecordIssuance implements AuditSink, records are written to the file and synced to disk before returning
+func (s *JSONLinesAuditSink) RecordIssuance(_ context.Context, record AuditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return errors.New("audit sink is closed")
+	}
+
+	_, err = s.file.Write(line)
+	if err != nil {
+		return err
+	}
+
+	return s.file.Sync()
+}
+
+// Close closes the audit file, later records fail
+func (s *JSONLinesAuditSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+
+	err := s.file.Close()
+	s.file = nil
+
+	return err
+}
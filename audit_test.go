@@ -0,0 +1,137 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"bufio"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type ginkgoAuditSink struct {
+	records []AuditRecord
+	err     error
+}
+
+func (s *ginkgoAuditSink) RecordIssuance(_ context.Context, record AuditRecord) error {
+	if s.err != nil {
+		return s.err
+	}
+
+	s.records = append(s.records, record)
+
+	return nil
+}
+
+var _ = Describe("Audit", func() {
+	var (
+		pubK ed25519.PublicKey
+		priK ed25519.PrivateKey
+	)
+
+	BeforeEach(func() {
+		var err error
+		pubK, priK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		DeferCleanup(func() { SetAuditSink(nil) })
+	})
+
+	It("Should record every token signed", func() {
+		sink := &ginkgoAuditSink{}
+		SetAuditSink(sink)
+
+		client, err := NewClientIDClaims("up=bob", nil, "", nil, "", "acme", time.Hour, &ClientPermissions{FleetManagement: true}, nil)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = SignToken(client, priK)
+		Expect(err).ToNot(HaveOccurred())
+
+		server, err := NewServerClaims("n1.example.net", []string{"choria"}, "", nil, nil, pubK, "", time.Hour)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = SignToken(server, loadRSAPriKey("testdata/rsa/signer-key.pem"))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(sink.records).To(HaveLen(2))
+
+		rec := sink.records[0]
+		Expect(rec.Purpose).To(Equal(ClientIDPurpose))
+		Expect(rec.Identity).To(Equal("up=bob"))
+		Expect(rec.TokenID).To(Equal(client.ID))
+		Expect(rec.Issuer).To(Equal("acme"))
+		Expect(rec.Signer).To(Equal(hex.EncodeToString(pubK)))
+		Expect(rec.Algorithm).To(Equal("EdDSA"))
+		Expect(rec.Permissions).To(Equal([]string{"fleet_management"}))
+		Expect(rec.ExpiresAt).To(BeTemporally("==", client.ExpiresAt.Time))
+		Expect(rec.Time).To(BeTemporally("~", time.Now(), time.Second))
+
+		rec = sink.records[1]
+		Expect(rec.Purpose).To(Equal(ServerPurpose))
+		Expect(rec.Identity).To(Equal("n1.example.net"))
+		Expect(rec.Algorithm).To(Equal("RS256"))
+		Expect(rec.Signer).To(HavePrefix("sha256:"))
+	})
+
+	It("Should fail signing when the record can not be written", func() {
+		SetAuditSink(&ginkgoAuditSink{err: errors.New("disk full")})
+
+		client, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		token, err := SignToken(client, priK)
+		Expect(err).To(MatchError("could not record token issuance: disk full"))
+		Expect(token).To(BeEmpty())
+	})
+
+	Describe("JSONLinesAuditSink", func() {
+		It("Should append records to the file", func() {
+			path := filepath.Join(GinkgoT().TempDir(), "audit.jsonl")
+			sink, err := NewJSONLinesAuditSink(path, 0600)
+			Expect(err).ToNot(HaveOccurred())
+			SetAuditSink(sink)
+
+			for _, caller := range []string{"up=bob", "up=alice"} {
+				client, err := NewClientIDClaims(caller, nil, "", nil, "", "", time.Hour, nil, nil)
+				Expect(err).ToNot(HaveOccurred())
+				_, err = SignToken(client, priK)
+				Expect(err).ToNot(HaveOccurred())
+			}
+
+			Expect(sink.Close()).To(Succeed())
+
+			client, err := NewClientIDClaims("up=eve", nil, "", nil, "", "", time.Hour, nil, nil)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = SignToken(client, priK)
+			Expect(err).To(MatchError("could not record token issuance: audit sink is closed"))
+
+			stat, err := os.Stat(path)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stat.Mode().Perm()).To(Equal(os.FileMode(0600)))
+
+			f, err := os.Open(path)
+			Expect(err).ToNot(HaveOccurred())
+			defer f.Close()
+
+			var identities []string
+			scanner := bufio.NewScanner(f)
+			for scanner.Scan() {
+				var rec AuditRecord
+				Expect(json.Unmarshal(scanner.Bytes(), &rec)).To(Succeed())
+				Expect(rec.Purpose).To(Equal(ClientIDPurpose))
+				identities = append(identities, rec.Identity)
+			}
+			Expect(scanner.Err()).ToNot(HaveOccurred())
+			Expect(identities).To(Equal([]string{"up=bob", "up=alice"}))
+		})
+	})
+})
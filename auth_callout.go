@@ -0,0 +1,173 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !js
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v4"
+	natsjwt "github.com/nats-io/jwt/v2"
+	"github.com/nats-io/nkeys"
+)
+
+// AuthCalloutConfig configures an AuthCalloutResponder
+type AuthCalloutConfig struct {
+	// Collective is the collective connections are authorized for
+	Collective string
+
+	// TrustedKey verifies the presented tokens, see ParseToken for supported types
+	TrustedKey any
+
+	// Signer is the account key or account signing key that signs responses and user JWTs
+	Signer nkeys.KeyPair
+
+	// IssuerAccount is the public key of the account Signer signs for when it is a signing key
+	IssuerAccount string
+
+	// Account is the account connections are placed in when the server is not in operator mode
+	Account string
+
+	// ParseOptions are used when verifying presented tokens
+	ParseOptions []ParseOption
+}
+
+// AuthCalloutResponder answers NATS auth callout requests by verifying the Choria token presented in the connect
+// options, mapping its permissions and returning a signed authorization response. Encrypted requests are not
+// supported and must be decrypted before being passed to Respond
+type AuthCalloutResponder struct {
+	cfg AuthCalloutConfig
+}
+
+// NewAuthCalloutResponder creates a responder using cfg
+func NewAuthCalloutResponder(cfg AuthCalloutConfig) (*AuthCalloutResponder, error) {
+	err := validateCollectives([]string{cfg.Collective})
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.TrustedKey == nil {
+		return nil, fmt.Errorf("trusted key is required")
+	}
+
+	if cfg.Signer == nil {
+		return nil, fmt.Errorf("signer is required")
+	}
+
+	pub, err := cfg.Signer.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	if !nkeys.IsValidPublicAccountKey(pub) {
+		return nil, fmt.Errorf("signer must be an account key")
+	}
+
+	return &AuthCalloutResponder{cfg: cfg}, nil
+}
+
+// Respond handles the auth callout request JWT req, failed authorizations are returned as signed responses holding
+// the reason, an error is only returned when no response can be produced
+func (r *AuthCalloutResponder) Respond(req []byte) ([]byte, error) {
+	request, err := natsjwt.DecodeAuthorizationRequestClaims(string(req))
+	if err != nil {
+		return nil, fmt.Errorf("invalid authorization request: %w", err)
+	}
+
+	vr := &natsjwt.ValidationResults{}
+	request.Validate(vr)
+	if len(vr.Errors()) > 0 {
+		return nil, fmt.Errorf("invalid authorization request: %w", vr.Errors()[0])
+	}
+
+	res := natsjwt.NewAuthorizationResponseClaims(request.UserNkey)
+	res.Audience = request.Server.ID
+
+	pub, _ := r.cfg.Signer.PublicKey()
+	if r.cfg.IssuerAccount != "" && r.cfg.IssuerAccount != pub {
+		res.IssuerAccount = r.cfg.IssuerAccount
+	}
+
+	user, err := r.Authorize(request)
+	if err != nil {
+		res.Error = err.Error()
+	} else {
+		res.Jwt = user
+	}
+
+	token, err := res.Encode(r.cfg.Signer)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(token), nil
+}
+
+// Authorize verifies the token presented in request and creates the user JWT to return to the server
+func (r *AuthCalloutResponder) Authorize(request *natsjwt.AuthorizationRequestClaims) (string, error) {
+	token := request.ConnectOptions.Token
+	if token == "" {
+		return "", fmt.Errorf("no token presented")
+	}
+
+	var claims jwt.Claims
+	var err error
+
+	switch TokenPurpose(token) {
+	case ClientIDPurpose:
+		claims, err = ParseClientIDToken(token, r.cfg.TrustedKey, true, r.cfg.ParseOptions...)
+	case ServerPurpose:
+		claims, err = ParseServerToken(token, r.cfg.TrustedKey, r.cfg.ParseOptions...)
+	default:
+		return "", fmt.Errorf("unsupported token purpose %q", TokenPurpose(token))
+	}
+	if err != nil {
+		return "", err
+	}
+
+	err = verifySignedNonce(claims.(standardClaimsProvider).getStandardClaims(), request)
+	if err != nil {
+		return "", err
+	}
+
+	uc, err := NatsUserClaims(claims, r.cfg.Collective)
+	if err != nil {
+		return "", err
+	}
+
+	uc.Subject = request.UserNkey
+	uc.Audience = r.cfg.Account
+
+	return signNatsUserClaims(uc, r.cfg.Signer, r.cfg.IssuerAccount)
+}
+
+// verifySignedNonce ensures the client proved possession of the private key of tokens holding a public key
+func verifySignedNonce(std *StandardClaims, request *natsjwt.AuthorizationRequestClaims) error {
+	if std.PublicKey == "" {
+		return nil
+	}
+
+	pk := claimsPublicKey(std)
+	if pk == nil {
+		return fmt.Errorf("invalid public key in token")
+	}
+
+	nonce := request.ClientInformation.Nonce
+	if nonce == "" {
+		return fmt.Errorf("no nonce available to verify")
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(request.ConnectOptions.SignedNonce)
+	if err != nil {
+		sig, err = base64.StdEncoding.DecodeString(request.ConnectOptions.SignedNonce)
+	}
+	if err != nil || !ed25519.Verify(pk, []byte(nonce), sig) {
+		return fmt.Errorf("nonce signature verification failed")
+	}
+
+	return nil
+}
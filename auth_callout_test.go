@@ -0,0 +1,132 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !js
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"time"
+
+	natsjwt "github.com/nats-io/jwt/v2"
+	"github.com/nats-io/nkeys"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AuthCalloutResponder", func() {
+	var (
+		issuerPubK ed25519.PublicKey
+		issuerPriK ed25519.PrivateKey
+		clientPriK ed25519.PrivateKey
+		server     nkeys.KeyPair
+		account    nkeys.KeyPair
+		responder  *AuthCalloutResponder
+		token      string
+	)
+
+	BeforeEach(func() {
+		var err error
+		var clientPubK ed25519.PublicKey
+
+		issuerPubK, issuerPriK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		clientPubK, clientPriK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		server, err = nkeys.CreateServer()
+		Expect(err).ToNot(HaveOccurred())
+		account, err = nkeys.CreateAccount()
+		Expect(err).ToNot(HaveOccurred())
+
+		client, err := NewClientIDClaims("up=bob", nil, "choria", nil, "", "", time.Hour, &ClientPermissions{FleetManagement: true}, clientPubK)
+		Expect(err).ToNot(HaveOccurred())
+		token, err = SignToken(client, issuerPriK)
+		Expect(err).ToNot(HaveOccurred())
+
+		responder, err = NewAuthCalloutResponder(AuthCalloutConfig{Collective: "choria", TrustedKey: issuerPubK, Signer: account, Account: "CHORIA"})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	request := func(token string, signer ed25519.PrivateKey) ([]byte, string) {
+		user, err := nkeys.CreateUser()
+		Expect(err).ToNot(HaveOccurred())
+		userPub, _ := user.PublicKey()
+		serverPub, _ := server.PublicKey()
+
+		req := natsjwt.NewAuthorizationRequestClaims(userPub)
+		req.Server.ID = serverPub
+		req.Server.Name = "broker"
+		req.UserNkey = userPub
+		req.ClientInformation.Nonce = "nonce-1"
+		req.ConnectOptions.Token = token
+		if signer != nil {
+			req.ConnectOptions.SignedNonce = base64.RawURLEncoding.EncodeToString(ed25519.Sign(signer, []byte("nonce-1")))
+		}
+
+		jwt, err := req.Encode(server)
+		Expect(err).ToNot(HaveOccurred())
+
+		return []byte(jwt), userPub
+	}
+
+	It("Should validate the configuration", func() {
+		_, err := NewAuthCalloutResponder(AuthCalloutConfig{Collective: "choria"})
+		Expect(err).To(MatchError("trusted key is required"))
+
+		_, err = NewAuthCalloutResponder(AuthCalloutConfig{Collective: "choria", TrustedKey: issuerPubK, Signer: server})
+		Expect(err).To(MatchError("signer must be an account key"))
+	})
+
+	It("Should authorize valid tokens", func() {
+		req, userPub := request(token, clientPriK)
+
+		res, err := responder.Respond(req)
+		Expect(err).ToNot(HaveOccurred())
+
+		rc, err := natsjwt.DecodeAuthorizationResponseClaims(string(res))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rc.Error).To(BeEmpty())
+		Expect(rc.Subject).To(Equal(userPub))
+
+		uc, err := natsjwt.DecodeUserClaims(rc.Jwt)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(uc.Subject).To(Equal(userPub))
+		Expect(uc.Audience).To(Equal("CHORIA"))
+		Expect(uc.Name).To(Equal("up=bob"))
+		Expect(uc.Pub.Allow.Contains("choria.broadcast.agent.>")).To(BeTrue())
+	})
+
+	It("Should deny invalid connections", func() {
+		deny := func(req []byte) string {
+			res, err := responder.Respond(req)
+			Expect(err).ToNot(HaveOccurred())
+			rc, err := natsjwt.DecodeAuthorizationResponseClaims(string(res))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rc.Jwt).To(BeEmpty())
+
+			return rc.Error
+		}
+
+		req, _ := request(token, nil)
+		Expect(deny(req)).To(Equal("nonce signature verification failed"))
+
+		req, _ = request(token, issuerPriK)
+		Expect(deny(req)).To(Equal("nonce signature verification failed"))
+
+		req, _ = request("", nil)
+		Expect(deny(req)).To(Equal("no token presented"))
+
+		otherPubK, _, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		responder.cfg.TrustedKey = otherPubK
+		req, _ = request(token, clientPriK)
+		Expect(deny(req)).To(ContainSubstring("verification error"))
+
+		_, err = responder.Respond([]byte("garbage"))
+		Expect(err).To(MatchError(ContainSubstring("invalid authorization request")))
+	})
+})
@@ -0,0 +1,176 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"filippo.io/edwards25519"
+)
+
+// batchVerifyThreshold is the size below which signatures are verified individually, smaller batches gain nothing
+const batchVerifyThreshold = 4
+
+// batchSignature is a decoded signature awaiting batch verification
+type batchSignature struct {
+	idx int
+	msg []byte
+	sig []byte
+	r   *edwards25519.Point
+	s   *edwards25519.Scalar
+	k   *edwards25519.Scalar
+}
+
+// VerifyTokensBatch verifies the signatures of tokens signed directly by key using ed25519 batch verification, the
+// result holds an error for each token in the same order, nil when the signature is valid.
+//
+// Only signatures are checked, claims still have to be validated for example using ParseToken. Batches are verified
+// using the cofactored equation, a signer can craft signatures that pass here but fail individual verification
+func VerifyTokensBatch(tokens []string, key ed25519.PublicKey) []error {
+	res := make([]error, len(tokens))
+
+	err := ValidateEd25519PublicKey(key)
+	if err != nil {
+		for i := range res {
+			res[i] = err
+		}
+		return res
+	}
+
+	a, _ := new(edwards25519.Point).SetBytes(key)
+
+	var batch []*batchSignature
+	for i, token := range tokens {
+		bs, err := decodeBatchSignature(token, key)
+		if err != nil {
+			res[i] = err
+			continue
+		}
+
+		bs.idx = i
+		batch = append(batch, bs)
+	}
+
+	verifyBatch(batch, key, a, res)
+
+	return res
+}
+
+// verifyBatch checks all signatures in batch at once, failed batches are split to find the invalid signatures
+func verifyBatch(batch []*batchSignature, key ed25519.PublicKey, a *edwards25519.Point, res []error) {
+	if len(batch) == 0 {
+		return
+	}
+
+	if len(batch) < batchVerifyThreshold {
+		for _, bs := range batch {
+			if !ed25519.Verify(key, bs.msg, bs.sig) {
+				res[bs.idx] = errCompactVerification
+			}
+		}
+		return
+	}
+
+	ok, err := batchEquationHolds(batch, a)
+	if err != nil {
+		for _, bs := range batch {
+			res[bs.idx] = err
+		}
+		return
+	}
+
+	if ok {
+		return
+	}
+
+	verifyBatch(batch[:len(batch)/2], key, a, res)
+	verifyBatch(batch[len(batch)/2:], key, a, res)
+}
+
+// batchEquationHolds checks [8](-(Σ zᵢsᵢ)B + Σ zᵢRᵢ + (Σ zᵢkᵢ)A) = 0 for random 128 bit zᵢ
+func batchEquationHolds(batch []*batchSignature, a *edwards25519.Point) (bool, error) {
+	scalars := make([]*edwards25519.Scalar, 0, len(batch)+2)
+	points := make([]*edwards25519.Point, 0, len(batch)+2)
+
+	bs := edwards25519.NewScalar()
+	as := edwards25519.NewScalar()
+	zb := make([]byte, 32)
+
+	for _, sig := range batch {
+		_, err := rand.Read(zb[:16])
+		if err != nil {
+			return false, fmt.Errorf("could not generate batch coefficients: %w", err)
+		}
+
+		z, err := edwards25519.NewScalar().SetCanonicalBytes(zb)
+		if err != nil {
+			return false, err
+		}
+
+		bs.MultiplyAdd(z, sig.s, bs)
+		as.MultiplyAdd(z, sig.k, as)
+
+		scalars = append(scalars, z)
+		points = append(points, sig.r)
+	}
+
+	scalars = append(scalars, bs.Negate(bs), as)
+	points = append(points, edwards25519.NewGeneratorPoint(), a)
+
+	check := new(edwards25519.Point).VarTimeMultiScalarMult(scalars, points)
+
+	return check.MultByCofactor(check).Equal(edwards25519.NewIdentityPoint()) == 1, nil
+}
+
+// decodeBatchSignature splits token and decodes the signature components needed for batch verification
+func decodeBatchSignature(token string, key ed25519.PublicKey) (*batchSignature, error) {
+	i := strings.LastIndex(token, ".")
+	if i == -1 || strings.Count(token, ".") != 2 {
+		return nil, fmt.Errorf("token contains an invalid number of segments")
+	}
+
+	alg, err := tokenAlgorithm(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if alg != algEdDSA {
+		return nil, fmt.Errorf("unsupported signing method %v in token", alg)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(token[i+1:])
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return nil, errCompactVerification
+	}
+
+	r, err := new(edwards25519.Point).SetBytes(sig[:32])
+	if err != nil {
+		return nil, errCompactVerification
+	}
+
+	s, err := edwards25519.NewScalar().SetCanonicalBytes(sig[32:])
+	if err != nil {
+		return nil, errCompactVerification
+	}
+
+	msg := []byte(token[:i])
+
+	h := sha512.New()
+	h.Write(sig[:32])
+	h.Write(key)
+	h.Write(msg)
+
+	k, err := edwards25519.NewScalar().SetUniformBytes(h.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+
+	return &batchSignature{msg: msg, sig: sig, r: r, s: s, k: k}, nil
+}
@@ -0,0 +1,138 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func batchServerTokens(priK ed25519.PrivateKey, count int) ([]string, error) {
+	var tokens []string
+
+	for i := 0; i < count; i++ {
+		pubK, _, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+
+		server, err := NewServerClaims(fmt.Sprintf("n%d.example.net", i), []string{"choria"}, "", nil, nil, pubK, "", time.Hour)
+		if err != nil {
+			return nil, err
+		}
+
+		token, err := SignToken(server, priK)
+		if err != nil {
+			return nil, err
+		}
+
+		tokens = append(tokens, token)
+	}
+
+	return tokens, nil
+}
+
+var _ = Describe("VerifyTokensBatch", func() {
+	var (
+		pubK   ed25519.PublicKey
+		priK   ed25519.PrivateKey
+		tokens []string
+	)
+
+	BeforeEach(func() {
+		var err error
+		pubK, priK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		tokens, err = batchServerTokens(priK, 20)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Should verify valid tokens", func() {
+		Expect(VerifyTokensBatch(tokens, pubK)).To(HaveEach(BeNil()))
+		Expect(VerifyTokensBatch(tokens[:2], pubK)).To(HaveEach(BeNil()))
+		Expect(VerifyTokensBatch(nil, pubK)).To(BeEmpty())
+	})
+
+	It("Should identify the invalid tokens", func() {
+		_, otherPriK, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		other, err := batchServerTokens(otherPriK, 1)
+		Expect(err).ToNot(HaveOccurred())
+
+		parts := strings.Split(tokens[3], ".")
+		tampered, err := batchServerTokens(priK, 1)
+		Expect(err).ToNot(HaveOccurred())
+		tokens[3] = strings.Join([]string{parts[0], strings.Split(tampered[0], ".")[1], parts[2]}, ".")
+
+		tokens[7] = other[0]
+		tokens[11] = "a.b"
+
+		rsa, err := SignToken(&ServerClaims{ChoriaIdentity: "n1.example.net"}, loadRSAPriKey("testdata/rsa/signer-key.pem"))
+		Expect(err).ToNot(HaveOccurred())
+		tokens[15] = rsa
+
+		res := VerifyTokensBatch(tokens, pubK)
+		for i, err := range res {
+			switch i {
+			case 3, 7:
+				Expect(err).To(MatchError("ed25519: verification error"), fmt.Sprintf("token %d", i))
+				Expect(isSignatureError(err)).To(BeTrue())
+			case 11:
+				Expect(err).To(MatchError("token contains an invalid number of segments"))
+			case 15:
+				Expect(err).To(MatchError("unsupported signing method RS256 in token"))
+			default:
+				Expect(err).ToNot(HaveOccurred(), fmt.Sprintf("token %d", i))
+			}
+		}
+	})
+
+	It("Should reject invalid keys", func() {
+		res := VerifyTokensBatch(tokens[:2], ed25519.PublicKey(make([]byte, 32)))
+		Expect(res).To(HaveEach(MatchError(ErrZeroPublicKey)))
+	})
+})
+
+func benchmarkVerifyTokens(b *testing.B, verify func(tokens []string, pk ed25519.PublicKey)) {
+	pubK, priK, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	tokens, err := batchServerTokens(priK, 1000)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		verify(tokens, pubK)
+	}
+}
+
+func BenchmarkVerifyTokensBatch(b *testing.B) {
+	benchmarkVerifyTokens(b, func(tokens []string, pk ed25519.PublicKey) { VerifyTokensBatch(tokens, pk) })
+}
+
+func BenchmarkVerifyTokensIndividually(b *testing.B) {
+	benchmarkVerifyTokens(b, func(tokens []string, pk ed25519.PublicKey) {
+		for _, token := range tokens {
+			i := strings.LastIndex(token, ".")
+			sig, _ := base64.RawURLEncoding.DecodeString(token[i+1:])
+			ed25519.Verify(pk, []byte(token[:i]), sig)
+		}
+	})
+}
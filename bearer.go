@@ -0,0 +1,106 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// ErrPurposeNotAllowed indicates a token is of a purpose the verifier does not accept
+var ErrPurposeNotAllowed = errors.New("token purpose not allowed")
+
+// BearerVerifier verifies tokens presented as bearer tokens to network services, it is used by the HTTP and gRPC
+// authentication packages
+type BearerVerifier struct {
+	keyRing    *KeyRing
+	trustedKey any
+	purposes   []Purpose
+	opts       []ParseOption
+}
+
+// NewBearerVerifier creates a verifier accepting tokens of purposes, defaulting to client tokens, signed by a key in kr
+// or, when kr is nil, by trustedKey. When audience is set it must be one of the audiences of the token
+func NewBearerVerifier(kr *KeyRing, trustedKey any, purposes []Purpose, audience string, opts ...ParseOption) (*BearerVerifier, error) {
+	if kr == nil && trustedKey == nil {
+		return nil, fmt.Errorf("key ring or trusted key is required")
+	}
+
+	if len(purposes) == 0 {
+		purposes = []Purpose{ClientIDPurpose}
+	}
+
+	for _, p := range purposes {
+		_, err := NewClaimsForPurpose(p)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	opts = append([]ParseOption{}, opts...)
+	if audience != "" {
+		opts = append(opts, WithExpectedAudience(audience))
+	}
+
+	return &BearerVerifier{keyRing: kr, trustedKey: trustedKey, purposes: purposes, opts: opts}, nil
+}
+
+// Verify verifies token and enforces the access restrictions of client tokens for a caller connecting from source,
+// source may be nil when it is not known
+func (v *BearerVerifier) Verify(ctx context.Context, token string, source net.IP) (jwt.Claims, error) {
+	purpose := TokenPurpose(token)
+	if !v.allowed(purpose) {
+		return nil, fmt.Errorf("%w: %q", ErrPurposeNotAllowed, purpose)
+	}
+
+	claims, err := NewClaimsForPurpose(purpose)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := append(v.opts[:len(v.opts):len(v.opts)], WithAccessSource(source))
+
+	if v.keyRing != nil {
+		_, err = v.keyRing.ParseTokenContext(ctx, token, claims, opts...)
+	} else {
+		err = ParseTokenContext(ctx, token, claims, v.trustedKey, opts...)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+func (v *BearerVerifier) allowed(purpose Purpose) bool {
+	for _, p := range v.purposes {
+		if p == purpose {
+			return true
+		}
+	}
+
+	return false
+}
+
+// BearerToken extracts the token from an Authorization header value, empty when it is not a bearer token
+func BearerToken(authorization string) string {
+	scheme, token, ok := strings.Cut(authorization, " ")
+	if !ok || !strings.EqualFold(scheme, "bearer") {
+		return ""
+	}
+
+	return strings.TrimSpace(token)
+}
+
+// IsAccessDenied determines if err indicates a valid token may not be used for the request, for example because of
+// its purpose or access restrictions, rather than the token being invalid
+func IsAccessDenied(err error) bool {
+	return errors.Is(err, ErrPurposeNotAllowed) || errors.Is(err, ErrSourceNotAllowed) || errors.Is(err, ErrOutsideAccessWindow)
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BearerVerifier", func() {
+	var (
+		pubK  ed25519.PublicKey
+		priK  ed25519.PrivateKey
+		token string
+	)
+
+	BeforeEach(func() {
+		var err error
+		pubK, priK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		claims, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, nil, WithAudience("dashboard"))
+		Expect(err).ToNot(HaveOccurred())
+		claims.Access = &AccessRestrictions{SourceCIDRs: []string{"10.0.0.0/8"}}
+		token, err = SignToken(claims, priK)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Should validate its settings", func() {
+		_, err := NewBearerVerifier(nil, nil, nil, "")
+		Expect(err).To(MatchError("key ring or trusted key is required"))
+
+		_, err = NewBearerVerifier(nil, pubK, []Purpose{"unknown"}, "")
+		Expect(err).To(MatchError(ErrUnknownPurpose))
+	})
+
+	It("Should verify tokens using a key or key ring", func() {
+		kr, err := NewKeyRing(pubK)
+		Expect(err).ToNot(HaveOccurred())
+
+		for _, v := range []func() (*BearerVerifier, error){
+			func() (*BearerVerifier, error) { return NewBearerVerifier(nil, pubK, nil, "dashboard") },
+			func() (*BearerVerifier, error) { return NewBearerVerifier(kr, nil, nil, "dashboard") },
+		} {
+			verifier, err := v()
+			Expect(err).ToNot(HaveOccurred())
+
+			claims, err := verifier.Verify(context.Background(), token, net.ParseIP("10.1.1.1"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(claims.(*ClientIDClaims).CallerID).To(Equal("up=bob"))
+
+			_, err = verifier.Verify(context.Background(), token, net.ParseIP("192.168.1.1"))
+			Expect(err).To(MatchError(ErrSourceNotAllowed))
+			Expect(IsAccessDenied(err)).To(BeTrue())
+		}
+
+		verifier, err := NewBearerVerifier(nil, pubK, nil, "other")
+		Expect(err).ToNot(HaveOccurred())
+		_, err = verifier.Verify(context.Background(), token, net.ParseIP("10.1.1.1"))
+		Expect(err).To(HaveOccurred())
+		Expect(IsAccessDenied(err)).To(BeFalse())
+	})
+
+	It("Should enforce the purpose", func() {
+		verifier, err := NewBearerVerifier(nil, pubK, []Purpose{ServerPurpose}, "")
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = verifier.Verify(context.Background(), token, nil)
+		Expect(err).To(MatchError(ErrPurposeNotAllowed))
+		Expect(IsAccessDenied(err)).To(BeTrue())
+	})
+
+	It("Should extract bearer tokens", func() {
+		Expect(BearerToken("Bearer  x.y.z ")).To(Equal("x.y.z"))
+		Expect(BearerToken("bearer x.y.z")).To(Equal("x.y.z"))
+		Expect(BearerToken("Basic x")).To(BeEmpty())
+		Expect(BearerToken("")).To(BeEmpty())
+	})
+})
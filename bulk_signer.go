@@ -0,0 +1,181 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"context"
+	"crypto/rsa"
+	"fmt"
+	"runtime"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// BulkSigner signs large numbers of tokens concurrently, the key is loaded and validated once and shared by all workers
+type BulkSigner struct {
+	key      any
+	method   jwt.SigningMethod
+	sopts    *signOptions
+	workers  int
+	progress func(done int, total int)
+}
+
+// BulkSignerOption configures a BulkSigner
+type BulkSignerOption func(*BulkSigner) error
+
+// BulkSignResult is the outcome of signing one set of claims
+type BulkSignResult struct {
+	// Token is the signed token, empty on failure
+	Token string
+
+	// Error is the reason signing failed
+	Error error
+}
+
+// WithBulkWorkers sets how many tokens are signed concurrently, defaults to GOMAXPROCS
+func WithBulkWorkers(workers int) BulkSignerOption {
+	return func(s *BulkSigner) error {
+		if workers < 1 {
+			return fmt.Errorf("at least one worker is required")
+		}
+
+		s.workers = workers
+
+		return nil
+	}
+}
+
+// WithBulkProgress calls cb after every token is signed, calls are never concurrent
+func WithBulkProgress(cb func(done int, total int)) BulkSignerOption {
+	return func(s *BulkSigner) error {
+		if cb == nil {
+			return fmt.Errorf("progress callback is required")
+		}
+
+		s.progress = cb
+
+		return nil
+	}
+}
+
+// WithBulkSignOptions applies opts to every token that is signed
+func WithBulkSignOptions(opts ...SignOption) BulkSignerOption {
+	return func(s *BulkSigner) error {
+		sopts, err := newSignOptions(opts...)
+		if err != nil {
+			return err
+		}
+
+		s.sopts = sopts
+
+		return nil
+	}
+}
+
+// NewBulkSigner creates a BulkSigner using a key supported by SignToken
+func NewBulkSigner(key any, opts ...BulkSignerOption) (*BulkSigner, error) {
+	method, err := signingMethodFor(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if pri, ok := key.(*rsa.PrivateKey); ok {
+		pri.Precompute()
+	}
+
+	s := &BulkSigner{
+		key:     key,
+		method:  method,
+		sopts:   &signOptions{},
+		workers: runtime.GOMAXPROCS(0),
+	}
+
+	for _, opt := range opts {
+		err = opt(s)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// NewBulkSignerWithKeyFile creates a BulkSigner using a key file supported by SignTokenWithKeyFile
+func NewBulkSignerWithKeyFile(pkFile string, opts ...BulkSignerOption) (*BulkSigner, error) {
+	key, err := loadSigningKeyFile(pkFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewBulkSigner(key, opts...)
+}
+
+// Sign signs all claims and returns a result for each in the same order, claims not signed before ctx is done
+// fail with the context error
+func (s *BulkSigner) Sign(ctx context.Context, claims []jwt.Claims) []BulkSignResult {
+	res := make([]BulkSignResult, len(claims))
+	work := make(chan int)
+
+	sopts := *s.sopts
+	sopts.ctx = ctx
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		done int
+	)
+
+	workers := s.workers
+	if workers > len(claims) {
+		workers = len(claims)
+	}
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for idx := range work {
+				res[idx].Token, res[idx].Error = signTokenWithMethod(claims[idx], s.key, s.method, &sopts)
+
+				if s.progress != nil {
+					mu.Lock()
+					done++
+					s.progress(done, len(claims))
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for i := range claims {
+		if ctx.Err() != nil {
+			res[i].Error = ctx.Err()
+			continue
+		}
+
+		select {
+		case work <- i:
+		case <-ctx.Done():
+			res[i].Error = ctx.Err()
+		}
+	}
+
+	close(work)
+	wg.Wait()
+
+	return res
+}
+
+// SignServers signs server claims, see Sign
+func (s *BulkSigner) SignServers(ctx context.Context, servers []*ServerClaims) []BulkSignResult {
+	claims := make([]jwt.Claims, len(servers))
+	for i, server := range servers {
+		claims[i] = server
+	}
+
+	return s.Sign(ctx, claims)
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BulkSigner", func() {
+	var servers []*ServerClaims
+
+	BeforeEach(func() {
+		servers = nil
+
+		for i := 0; i < 50; i++ {
+			pubK, _, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+			server, err := NewServerClaims(fmt.Sprintf("n%d.example.net", i), []string{"choria"}, "", nil, nil, pubK, "", time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+			servers = append(servers, server)
+		}
+	})
+
+	It("Should validate its options", func() {
+		_, err := NewBulkSigner("x")
+		Expect(err).To(MatchError("unsupported private key"))
+
+		_, priK, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = NewBulkSigner(priK, WithBulkWorkers(0))
+		Expect(err).To(MatchError("at least one worker is required"))
+		_, err = NewBulkSigner(priK, WithBulkSignOptions(WithCodec("unknown")))
+		Expect(err).To(MatchError(`unknown codec "unknown"`))
+
+		_, err = NewBulkSignerWithKeyFile("testdata/missing.seed")
+		Expect(err).To(MatchError(ContainSubstring("could not read signing key")))
+	})
+
+	It("Should sign all claims in order and report progress", func() {
+		var progress []int
+
+		signer, err := NewBulkSignerWithKeyFile("testdata/ed25519/signer.seed", WithBulkWorkers(4), WithBulkProgress(func(done int, total int) {
+			Expect(total).To(Equal(50))
+			progress = append(progress, done)
+		}))
+		Expect(err).ToNot(HaveOccurred())
+
+		res := signer.SignServers(context.Background(), servers)
+		Expect(res).To(HaveLen(50))
+		Expect(progress).To(HaveLen(50))
+		Expect(progress[49]).To(Equal(50))
+
+		for i, r := range res {
+			Expect(r.Error).ToNot(HaveOccurred())
+
+			server, err := ParseServerTokenWithKeyfile(r.Token, "testdata/ed25519/signer.public")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(server.ChoriaIdentity).To(Equal(fmt.Sprintf("n%d.example.net", i)))
+		}
+	})
+
+	It("Should support RSA keys and sign options", func() {
+		signer, err := NewBulkSignerWithKeyFile("testdata/rsa/signer-key.pem", WithBulkSignOptions(WithCompression()))
+		Expect(err).ToNot(HaveOccurred())
+
+		res := signer.Sign(context.Background(), []jwt.Claims{servers[0]})
+		Expect(res[0].Error).ToNot(HaveOccurred())
+		Expect(IsCompressedToken(res[0].Token)).To(BeTrue())
+
+		server, err := ParseServerTokenWithKeyfile(res[0].Token, "testdata/rsa/signer-public.pem")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(server.ChoriaIdentity).To(Equal("n0.example.net"))
+	})
+
+	It("Should stop when the context is cancelled", func() {
+		_, priK, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		signer, err := NewBulkSigner(priK)
+		Expect(err).ToNot(HaveOccurred())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		for _, r := range signer.SignServers(ctx, servers) {
+			Expect(r.Error).To(MatchError(context.Canceled))
+			Expect(r.Token).To(BeEmpty())
+		}
+	})
+})
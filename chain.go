@@ -0,0 +1,251 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrInvalidIssuerChain indicates the issuer chain embedded in a token could not be verified
+var ErrInvalidIssuerChain = errors.New("invalid issuer chain")
+
+// ErrChainDepthExceeded indicates a chain issuer delegated to more levels of chain issuers than it is allowed to
+var ErrChainDepthExceeded = errors.New("issuer chain exceeds the permitted delegation depth")
+
+// ErrChainDelegationExpired indicates a chain issuer issued a token but its delegation to do so has lapsed
+var ErrChainDelegationExpired = errors.New("chain issuer delegation has expired")
+
+// WithChainDelegationExpiry time boxes the ability of a chain issuer to issue tokens, tokens issued by the chain issuer
+// are rejected after t even when the chain issuer token is still valid. The org issuer signs the expiry along with the
+// chain issuer so tokens issued by it are only accepted when they embed their issuer chain, see WithIssuerChain
+func WithChainDelegationExpiry(t time.Time) ClaimsOption {
+	return func(o *claimsOptions) error {
+		if t.IsZero() {
+			return fmt.Errorf("chain delegation expiry cannot be zero")
+		}
+
+		o.chainDelegation = t
+
+		return nil
+	}
+}
+
+// WithChainMaxDepth allows a chain issuer to delegate issuing to depth further levels of chain issuers, by default
+// chain issuers may only issue tokens and not further delegate
+func WithChainMaxDepth(depth int) ClaimsOption {
+	return func(o *claimsOptions) error {
+		if depth < 0 {
+			return fmt.Errorf("chain max depth cannot be negative")
+		}
+
+		o.chainMaxDepth = depth
+
+		return nil
+	}
+}
+
+// WithIssuerChain embeds the token of the chain issuer signing the claims, verifiers holding only the org issuer
+// public key can then verify the chain issuer token, including its expiry, along with the token being signed.
+//
+// When the chain issuer was itself issued by a chain issuer its embedded issuer chain is carried over, this is
+// required to verify tokens issued at any depth below the first chain issuer.
+//
+// The claims must already have their issuer set using AddChainIssuerData or SetChainIssuer with the same chain issuer
+func WithIssuerChain(issuerToken string) SignOption {
+	return func(o *signOptions) error {
+		if issuerToken == "" {
+			return fmt.Errorf("issuer token is required")
+		}
+
+		o.issuerChain = []string{issuerToken}
+
+		return nil
+	}
+}
+
+// IssuerChainLinkError indicates which token in an embedded issuer chain failed to verify, links are numbered
+// from 1 starting with the chain issuer signed by the org issuer
+type IssuerChainLinkError struct {
+	// Link is the position of the failed token in the issuer chain
+	Link int
+
+	// TokenID is the id of the failed token when it could be decoded
+	TokenID string
+
+	// Err is the reason the link failed
+	Err error
+}
+
+func (e *IssuerChainLinkError) Error() string {
+	if e.TokenID == "" {
+		return fmt.Sprintf("link %d: %v", e.Link, e.Err)
+	}
+
+	return fmt.Sprintf("link %d (%s): %v", e.Link, e.TokenID, e.Err)
+}
+
+func (e *IssuerChainLinkError) Unwrap() error {
+	return e.Err
+}
+
+// embedIssuerChain stores chain in claims after checking that the last token in the chain is the issuer of the claims,
+// the issuer chain of the last token is prepended so the full path to the org issuer is embedded
+func embedIssuerChain(sc *StandardClaims, chain []string) error {
+	if !strings.HasPrefix(sc.Issuer, ChainIssuerPrefix) {
+		return fmt.Errorf("%w: only tokens issued by chain issuers can embed an issuer chain", ErrInvalidIssuerChain)
+	}
+
+	issuer := &ClientIDClaims{}
+	_, err := parseUnverified(chain[len(chain)-1], issuer)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidIssuerChain, err)
+	}
+
+	if sc.Issuer != chainIssuerName(&issuer.StandardClaims) {
+		return fmt.Errorf("%w: issuer token does not match the token issuer", ErrInvalidIssuerChain)
+	}
+
+	if len(chain) == 1 && len(issuer.IssuerChain) > 0 {
+		chain = append(append([]string{}, issuer.IssuerChain...), chain[0])
+	}
+
+	err = verifyChainDepth(chain)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidIssuerChain, err)
+	}
+
+	sc.IssuerChain = chain
+
+	return nil
+}
+
+// chainIssuerName is the issuer set on tokens issued by the chain issuer c
+func chainIssuerName(c *StandardClaims) string {
+	return fmt.Sprintf("%s%s.%s", ChainIssuerPrefix, c.ID, c.PublicKey)
+}
+
+// verifyIssuerChain verifies any issuer chain embedded in sc against the org issuer key pk, the first chain issuer
+// token must be signed by the org issuer and every following one by the chain issuer before it, no token may be
+// expired at now, the delegation depth of every chain issuer must be honoured and the last one must be the issuer of sc.
+// Every link is verified once using the key of the link before it. The verified chain issuers are returned in chain order
+func verifyIssuerChain(sc *StandardClaims, pk ed25519.PublicKey, now time.Time) ([]*ClientIDClaims, error) {
+	if len(sc.IssuerChain) == 0 {
+		return nil, nil
+	}
+
+	var parent *ClientIDClaims
+	var issuers []*ClientIDClaims
+
+	for i, token := range sc.IssuerChain {
+		issuer := &ClientIDClaims{}
+		err := verifyIssuerChainLink(token, issuer, parent, pk, now)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidIssuerChain, &IssuerChainLinkError{Link: i + 1, TokenID: issuer.ID, Err: err})
+		}
+
+		issuers = append(issuers, issuer)
+		parent = issuer
+	}
+
+	err := verifyChainDepth(sc.IssuerChain)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidIssuerChain, err)
+	}
+
+	if sc.Issuer != chainIssuerName(&parent.StandardClaims) {
+		return nil, fmt.Errorf("%w: issuer token does not match the token issuer", ErrInvalidIssuerChain)
+	}
+
+	if !strings.HasPrefix(sc.TrustChainSignature, parent.TrustChainSignature+".") {
+		return nil, fmt.Errorf("%w: trust chain signature does not extend that of the issuer token", ErrInvalidIssuerChain)
+	}
+
+	return issuers, nil
+}
+
+// verifyIssuerChainLink parses and verifies token into issuer, parent is the chain issuer before it in the chain
+// and nil for the first one which must be issued by the org issuer pk
+func verifyIssuerChainLink(token string, issuer *ClientIDClaims, parent *ClientIDClaims, pk ed25519.PublicKey, now time.Time) error {
+	signer := pk
+	if parent != nil {
+		signer = claimsPublicKey(&parent.StandardClaims)
+		if signer == nil {
+			return fmt.Errorf("previous chain issuer has an invalid public key")
+		}
+	}
+
+	// the signer of every link is known from the link before it so links are not resolved through their own
+	// embedded issuer chains, this keeps verifying a chain linear in its length
+	popts := &parseOptions{signerKnown: true, clock: func() time.Time { return now }}
+	err := parseTokenWithOptions(token, issuer, signer, popts)
+	if err != nil {
+		return err
+	}
+
+	err = verifyChainDelegationExpiry(issuer, now)
+	if err != nil {
+		return err
+	}
+
+	if parent == nil {
+		if issuer.Issuer != OrgIssuerPrefix+hex.EncodeToString(pk) || !issuer.IsChainedIssuer(true) {
+			return fmt.Errorf("issuer token is not a chain issuer of the org issuer")
+		}
+
+		return nil
+	}
+
+	if issuer.Issuer != chainIssuerName(&parent.StandardClaims) {
+		return fmt.Errorf("issuer token was not issued by the previous chain issuer")
+	}
+
+	if !strings.HasPrefix(issuer.TrustChainSignature, parent.TrustChainSignature+".") {
+		return fmt.Errorf("trust chain signature does not extend that of the previous chain issuer")
+	}
+
+	_, _, tcs, sig, err := issuer.ParseChainIssuerData()
+	if err != nil {
+		return err
+	}
+
+	ok, err := ed25519Verify(signer, []byte(fmt.Sprintf("%s.%s", issuer.ID, tcs)), sig)
+	if err != nil || !ok {
+		return fmt.Errorf("invalid chain signature")
+	}
+
+	return nil
+}
+
+// verifyChainDelegationExpiry ensures the chain issuer may still issue tokens at now
+func verifyChainDelegationExpiry(issuer *ClientIDClaims, now time.Time) error {
+	if issuer.ChainDelegationExpiresAt == nil || now.Before(issuer.ChainDelegationExpiresAt.Time) {
+		return nil
+	}
+
+	return fmt.Errorf("%w at %s", ErrChainDelegationExpired, issuer.ChainDelegationExpiresAt.Time.Format(time.RFC3339))
+}
+
+// verifyChainDepth ensures every chain issuer in chain has no more chain issuers below it than its ChainMaxDepth allows
+func verifyChainDepth(chain []string) error {
+	for i, token := range chain {
+		issuer := &ClientIDClaims{}
+		_, err := parseUnverified(token, issuer)
+		if err != nil {
+			return err
+		}
+
+		below := len(chain) - i - 1
+		if below > issuer.ChainMaxDepth {
+			return fmt.Errorf("%w: chain issuer %s may delegate %d levels but %d follow it", ErrChainDepthExceeded, issuer.ID, issuer.ChainMaxDepth, below)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,181 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// ErrIssuerConstraintViolation indicates a token grants access that a chain issuer above it may not grant
+var ErrIssuerConstraintViolation = errors.New("token exceeds the constraints of its chain issuer")
+
+// ChainIssuerConstraints limit the access a chain issuer may grant in the tokens it, and any chain issuer below it, issues.
+// Unset fields place no constraint. The org issuer signs the constraints along with the chain issuer so tokens issued
+// by a constrained chain issuer are only accepted when they embed their issuer chain, see WithIssuerChain
+type ChainIssuerConstraints struct {
+	// AllowedAgents are the agent or agent.action names that may be granted to clients
+	AllowedAgents []string `json:"agents,omitempty"`
+
+	// Permissions are the client permissions that may be granted
+	Permissions *ClientPermissions `json:"permissions,omitempty"`
+
+	// ServerPermissions are the server permissions that may be granted
+	ServerPermissions *ServerPermissions `json:"server_permissions,omitempty"`
+
+	// Collectives are the collectives servers may be members of
+	Collectives []string `json:"collectives,omitempty"`
+
+	// PublishSubjects must cover any additional publish subjects that are granted
+	PublishSubjects []string `json:"pub_subjects,omitempty"`
+
+	// SubscribeSubjects must cover any additional subscribe subjects that are granted
+	SubscribeSubjects []string `json:"sub_subjects,omitempty"`
+}
+
+// IssuerConstraintError describes how a token exceeds the constraints of a chain issuer, it matches ErrIssuerConstraintViolation
+type IssuerConstraintError struct {
+	// IssuerID is the token id of the constrained chain issuer
+	IssuerID string
+
+	// TokenID is the token id of the token exceeding the constraints
+	TokenID string
+
+	// Reason describes the access that was not allowed
+	Reason string
+}
+
+func (e *IssuerConstraintError) Error() string {
+	return fmt.Sprintf("%s: token %s exceeds the constraints of chain issuer %s: %s", ErrIssuerConstraintViolation, e.TokenID, e.IssuerID, e.Reason)
+}
+
+func (e *IssuerConstraintError) Is(target error) bool {
+	return target == ErrIssuerConstraintViolation
+}
+
+// WithIssuerConstraints limits the access a chain issuer created using NewClientIDClaims may grant
+func WithIssuerConstraints(constraints ChainIssuerConstraints) ClaimsOption {
+	return func(o *claimsOptions) error {
+		o.issuerConstraints = &constraints
+		return nil
+	}
+}
+
+// verifyIssuerConstraints ensures that every token issued below a constrained chain issuer in issuers, including
+// claims itself, only grants access allowed by those constraints
+func verifyIssuerConstraints(issuers []*ClientIDClaims, claims jwt.Claims) error {
+	for i, issuer := range issuers {
+		if issuer.IssuerConstraints == nil {
+			continue
+		}
+
+		for _, issued := range issuers[i+1:] {
+			err := issuer.IssuerConstraints.check(issuer.ID, issued)
+			if err != nil {
+				return err
+			}
+		}
+
+		err := issuer.IssuerConstraints.check(issuer.ID, claims)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// check verifies claims against the constraints of the chain issuer issuerID
+func (c *ChainIssuerConstraints) check(issuerID string, claims jwt.Claims) error {
+	var reason, tokenID string
+
+	switch t := claims.(type) {
+	case *ClientIDClaims:
+		tokenID = t.ID
+		reason = c.checkClient(t)
+	case *ServerClaims:
+		tokenID = t.ID
+		reason = c.checkServer(t)
+	default:
+		reason = fmt.Sprintf("%T can not be checked against the constraints", claims)
+	}
+
+	if reason == "" {
+		return nil
+	}
+
+	return &IssuerConstraintError{IssuerID: issuerID, TokenID: tokenID, Reason: reason}
+}
+
+func (c *ChainIssuerConstraints) checkClient(t *ClientIDClaims) string {
+	if c.AllowedAgents != nil {
+		for _, agent := range t.AllowedAgents {
+			if !agentAllowed(c.AllowedAgents, agent) {
+				return fmt.Sprintf("agent %s is not allowed", agent)
+			}
+		}
+
+		agent := uncoveredGrant(c.AllowedAgents, t.ACL)
+		if agent != "" {
+			return fmt.Sprintf("acl grant for agent %s is not allowed", agent)
+		}
+	}
+
+	if c.Permissions != nil && t.Permissions != nil {
+		name := missingPermission(c.Permissions, t.Permissions)
+		if name != "" {
+			return fmt.Sprintf("permission %s is not allowed", name)
+		}
+	}
+
+	return c.checkSubjects(t.AdditionalPublishSubjects, t.AdditionalSubscribeSubjects)
+}
+
+func (c *ChainIssuerConstraints) checkServer(t *ServerClaims) string {
+	if c.ServerPermissions != nil && t.Permissions != nil {
+		name := missingPermission(c.ServerPermissions, t.Permissions)
+		if name != "" {
+			return fmt.Sprintf("server permission %s is not allowed", name)
+		}
+	}
+
+	if c.Collectives != nil {
+		for _, collective := range t.Collectives {
+			allowed := false
+			for _, a := range c.Collectives {
+				if a == collective {
+					allowed = true
+					break
+				}
+			}
+
+			if !allowed {
+				return fmt.Sprintf("collective %s is not allowed", collective)
+			}
+		}
+	}
+
+	return c.checkSubjects(t.AdditionalPublishSubjects, t.AdditionalSubscribeSubjects)
+}
+
+func (c *ChainIssuerConstraints) checkSubjects(pub []string, sub []string) string {
+	if c.PublishSubjects != nil {
+		subj := uncoveredSubject(c.PublishSubjects, pub)
+		if subj != "" {
+			return fmt.Sprintf("publish subject %s is not allowed", subj)
+		}
+	}
+
+	if c.SubscribeSubjects != nil {
+		subj := uncoveredSubject(c.SubscribeSubjects, sub)
+		if subj != "" {
+			return fmt.Sprintf("subscribe subject %s is not allowed", subj)
+		}
+	}
+
+	return ""
+}
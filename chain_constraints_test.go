@@ -0,0 +1,167 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ChainIssuerConstraints", func() {
+	var (
+		orgPubK     ed25519.PublicKey
+		orgPriK     ed25519.PrivateKey
+		handlerPriK ed25519.PrivateKey
+		handler     *ClientIDClaims
+		handlerJWT  string
+	)
+
+	BeforeEach(func() {
+		var err error
+		var handlerPubK ed25519.PublicKey
+
+		orgPubK, orgPriK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		handlerPubK, handlerPriK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		constraints := ChainIssuerConstraints{
+			AllowedAgents:   []string{"rpcutil", "puppet.status"},
+			Permissions:     &ClientPermissions{FleetManagement: true},
+			Collectives:     []string{"choria"},
+			PublishSubjects: []string{"custom.>"},
+		}
+
+		handler, err = NewClientIDClaims("aaa=login", nil, "", nil, "", "", time.Hour, &ClientPermissions{AuthenticationDelegator: true}, handlerPubK, WithIssuerConstraints(constraints))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(handler.AddOrgIssuerData(orgPriK)).To(Succeed())
+		handlerJWT, err = SignToken(handler, orgPriK)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	issueClient := func(agents []string, perms *ClientPermissions, pub []string) string {
+		userPubK, _, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		user, err := NewClientIDClaims("up=bob", agents, "", nil, "", "", time.Hour, perms, userPubK)
+		Expect(err).ToNot(HaveOccurred())
+		user.AdditionalPublishSubjects = pub
+		Expect(user.AddChainIssuerData(handler, handlerPriK)).To(Succeed())
+
+		token, err := SignToken(user, handlerPriK, WithIssuerChain(handlerJWT))
+		Expect(err).ToNot(HaveOccurred())
+
+		return token
+	}
+
+	It("Should allow tokens within the constraints", func() {
+		token := issueClient([]string{"rpcutil", "puppet.status"}, &ClientPermissions{FleetManagement: true}, []string{"custom.foo"})
+		_, err := ParseClientIDToken(token, orgPubK, true)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Should reject tokens exceeding the constraints", func() {
+		token := issueClient(nil, &ClientPermissions{FleetManagement: true, OrgAdmin: true}, nil)
+		_, err := ParseClientIDToken(token, orgPubK, true)
+		Expect(err).To(MatchError(ErrIssuerConstraintViolation))
+
+		var cErr *IssuerConstraintError
+		Expect(errors.As(err, &cErr)).To(BeTrue())
+		Expect(cErr.IssuerID).To(Equal(handler.ID))
+		Expect(cErr.Reason).To(Equal("permission org_admin is not allowed"))
+
+		token = issueClient([]string{"puppet"}, nil, nil)
+		_, err = ParseClientIDToken(token, orgPubK, true)
+		Expect(err).To(MatchError(ContainSubstring("agent puppet is not allowed")))
+
+		token = issueClient([]string{"*"}, nil, nil)
+		_, err = ParseClientIDToken(token, orgPubK, true)
+		Expect(err).To(MatchError(ContainSubstring("agent * is not allowed")))
+
+		userPubK, _, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		user, err := NewClientIDClaims("up=bob", []string{"rpcutil"}, "", nil, "", "", time.Hour, nil, userPubK)
+		Expect(err).ToNot(HaveOccurred())
+		user.ACL = []ActionGrant{{Agent: "*"}}
+		Expect(user.AddChainIssuerData(handler, handlerPriK)).To(Succeed())
+		token, err = SignToken(user, handlerPriK, WithIssuerChain(handlerJWT))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = ParseClientIDToken(token, orgPubK, true)
+		Expect(err).To(MatchError(ContainSubstring("acl grant for agent * is not allowed")))
+
+		user.ACL = []ActionGrant{{Agent: "puppet", Actions: []string{"status"}}}
+		token, err = SignToken(user, handlerPriK, WithIssuerChain(handlerJWT))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = ParseClientIDToken(token, orgPubK, true)
+		Expect(err).ToNot(HaveOccurred())
+
+		token = issueClient(nil, nil, []string{"other.>"})
+		_, err = ParseClientIDToken(token, orgPubK, true)
+		Expect(err).To(MatchError(ContainSubstring("publish subject other.> is not allowed")))
+	})
+
+	It("Should constrain server tokens", func() {
+		serverPubK, _, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		server, err := NewServerClaims("n1.example.net", []string{"choria", "other"}, "", nil, nil, serverPubK, "", time.Hour)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(server.AddChainIssuerData(handler, handlerPriK)).To(Succeed())
+
+		token, err := SignToken(server, handlerPriK, WithIssuerChain(handlerJWT))
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = ParseServerToken(token, orgPubK)
+		Expect(err).To(MatchError(ErrIssuerConstraintViolation))
+		Expect(err).To(MatchError(ContainSubstring("collective other is not allowed")))
+	})
+
+	It("Should reject tokens issued by constrained chain issuers without an issuer chain", func() {
+		userPubK, _, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		user, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, &ClientPermissions{OrgAdmin: true}, userPubK)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(user.AddChainIssuerData(handler, handlerPriK)).To(Succeed())
+		token, err := SignToken(user, handlerPriK)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = ParseClientIDToken(token, orgPubK, true)
+		Expect(err).To(MatchError(ErrorNotSignedByIssuer))
+		Expect(err).To(MatchError(ContainSubstring("chain issuer was not signed by the org issuer")))
+
+		_, err = ParseClientIDToken(token, orgPubK, true, WithRequiredIssuerChain())
+		Expect(err).To(MatchError(ErrorNotSignedByIssuer))
+	})
+
+	It("Should reject chain issuers with altered constraints", func() {
+		handler.IssuerConstraints.Permissions = &ClientPermissions{FleetManagement: true, OrgAdmin: true}
+		Expect(handler.IsChainedIssuer(true)).To(BeFalse())
+
+		handler.IssuerConstraints = nil
+		Expect(handler.IsChainedIssuer(true)).To(BeFalse())
+	})
+
+	It("Should deny claims it can not check", func() {
+		err := handler.IssuerConstraints.check(handler.ID, &ProvisioningClaims{})
+		Expect(err).To(MatchError(ErrIssuerConstraintViolation))
+		Expect(err).To(MatchError(ContainSubstring("*tokens.ProvisioningClaims can not be checked against the constraints")))
+	})
+
+	It("Should report violations when explaining chains", func() {
+		token := issueClient(nil, &ClientPermissions{StreamsAdmin: true}, nil)
+
+		exp, err := ExplainChain(token, orgPubK)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(exp.Valid).To(BeFalse())
+		Expect(exp.Links[1].Constraints.IssuerConstraints.AllowedAgents).To(Equal([]string{"rpcutil", "puppet.status"}))
+
+		link, check := exp.FirstFailure()
+		Expect(link.Role).To(Equal(ChainLinkToken))
+		Expect(check.Name).To(Equal("issuer constraints"))
+	})
+})
@@ -0,0 +1,486 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// ChainLinkRole is the role a link plays in a chain of trust
+type ChainLinkRole string
+
+const (
+	// ChainLinkTrustRoot is the org issuer key the chain is verified against
+	ChainLinkTrustRoot ChainLinkRole = "trust_root"
+
+	// ChainLinkIssuer is a chain issuer that was permitted by the org issuer to issue tokens
+	ChainLinkIssuer ChainLinkRole = "chain_issuer"
+
+	// ChainLinkToken is the token being explained
+	ChainLinkToken ChainLinkRole = "token"
+)
+
+// ChainCheck is the result of a single verification step performed on a link
+type ChainCheck struct {
+	// Name describes the check
+	Name string `json:"name"`
+
+	// Passed indicates the check succeeded
+	Passed bool `json:"passed"`
+
+	// Error is the reason the check failed
+	Error string `json:"error,omitempty"`
+}
+
+// ChainLinkConstraints are the restrictions a link places on what the holder can do
+type ChainLinkConstraints struct {
+	AllowedAgents     []string           `json:"agents,omitempty"`
+	Permissions       *ClientPermissions `json:"permissions,omitempty"`
+	ServerPermissions *ServerPermissions `json:"server_permissions,omitempty"`
+	Collectives       []string           `json:"collectives,omitempty"`
+	PublishSubjects   []string           `json:"pub_subjects,omitempty"`
+	SubscribeSubjects []string           `json:"sub_subjects,omitempty"`
+	PolicyLanguage    PolicyLanguage     `json:"policy_language,omitempty"`
+	ChainMaxDepth     int                `json:"chain_max_depth,omitempty"`
+
+	// IssuerConstraints limit what a chain issuer may grant in the tokens it issues
+	IssuerConstraints *ChainIssuerConstraints `json:"issuer_constraints,omitempty"`
+}
+
+// ChainLink describes one hop in a chain of trust and how it verified
+type ChainLink struct {
+	Role      ChainLinkRole `json:"role"`
+	Purpose   Purpose       `json:"purpose,omitempty"`
+	Identity  string        `json:"identity,omitempty"`
+	TokenID   string        `json:"jti,omitempty"`
+	Issuer    string        `json:"issuer,omitempty"`
+	PublicKey string        `json:"public_key,omitempty"`
+	NotBefore time.Time     `json:"not_before,omitempty"`
+	IssuedAt  time.Time     `json:"issued_at,omitempty"`
+	ExpiresAt time.Time     `json:"expires_at,omitempty"`
+
+	// DelegationExpiresAt is when a chain issuer loses the ability to issue tokens
+	DelegationExpiresAt time.Time `json:"delegation_expires_at,omitempty"`
+
+	// Embedded indicates the full token for the link was available, chain issuers are otherwise only known from the issuer of the token
+	Embedded bool `json:"embedded"`
+
+	Constraints *ChainLinkConstraints `json:"constraints,omitempty"`
+	Checks      []*ChainCheck         `json:"checks,omitempty"`
+	Valid       bool                  `json:"valid"`
+}
+
+// ChainExplanation is the resolved chain of trust for a token, ordered from the trust root to the token
+type ChainExplanation struct {
+	TrustRoot string       `json:"trust_root"`
+	Links     []*ChainLink `json:"links"`
+	Valid     bool         `json:"valid"`
+}
+
+// FirstFailure is the first link, starting from the trust root, with a failed check, nil when the chain is valid
+func (e *ChainExplanation) FirstFailure() (*ChainLink, *ChainCheck) {
+	for _, link := range e.Links {
+		for _, check := range link.Checks {
+			if !check.Passed {
+				return link, check
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// String renders the chain as indented text suitable for showing operators
+func (e *ChainExplanation) String() string {
+	var b strings.Builder
+
+	for i, link := range e.Links {
+		indent := strings.Repeat("  ", i)
+		status := "valid"
+		if !link.Valid {
+			status = "INVALID"
+		}
+
+		fmt.Fprintf(&b, "%s%s %s (%s)\n", indent, link.Role, link.describe(), status)
+
+		if !link.ExpiresAt.IsZero() {
+			fmt.Fprintf(&b, "%s  validity: %s - %s\n", indent, link.NotBefore.Format(time.RFC3339), link.ExpiresAt.Format(time.RFC3339))
+		}
+
+		for _, check := range link.Checks {
+			if check.Passed {
+				fmt.Fprintf(&b, "%s  ok: %s\n", indent, check.Name)
+			} else {
+				fmt.Fprintf(&b, "%s  failed: %s: %s\n", indent, check.Name, check.Error)
+			}
+		}
+	}
+
+	return b.String()
+}
+
+func (l *ChainLink) describe() string {
+	if l.Identity != "" {
+		return l.Identity
+	}
+
+	return l.PublicKey
+}
+
+func (l *ChainLink) check(name string, err error) bool {
+	c := &ChainCheck{Name: name, Passed: err == nil}
+	if err != nil {
+		c.Error = err.Error()
+		l.Valid = false
+	}
+
+	l.Checks = append(l.Checks, c)
+
+	return err == nil
+}
+
+// ExplainChain resolves the chain of trust of token against the org issuer key trustRoot and verifies every link
+// separately, the result shows which hop of a delegated issuer setup failed to verify. An error is only returned
+// when the token can not be decoded
+func ExplainChain(token string, trustRoot ed25519.PublicKey) (*ChainExplanation, error) {
+	claims := newClaimsForPurpose(TokenPurpose(token))
+	_, err := parseUnverified(token, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	sc, ok := claims.(standardClaimsProvider)
+	if !ok {
+		return nil, fmt.Errorf("unsupported claims %T", claims)
+	}
+	std := sc.getStandardClaims()
+
+	exp := &ChainExplanation{TrustRoot: hex.EncodeToString(trustRoot)}
+	root := &ChainLink{Role: ChainLinkTrustRoot, PublicKey: exp.TrustRoot, Embedded: true, Valid: true}
+	root.check("ed25519 public key", ValidateEd25519PublicKey(trustRoot))
+	exp.Links = append(exp.Links, root)
+
+	leaf := newChainLink(ChainLinkToken, claims)
+	signer := trustRoot
+
+	switch {
+	case strings.HasPrefix(std.Issuer, ChainIssuerPrefix) && len(std.IssuerChain) > 1:
+		links, last := explainIssuerChain(std.IssuerChain, trustRoot)
+		exp.Links = append(exp.Links, links...)
+		signer = nil
+
+		if last != nil {
+			signer = claimsPublicKey(&last.StandardClaims)
+			leaf.check("signed by the chain issuer", explainChainLink(std, last))
+		}
+
+	case strings.HasPrefix(std.Issuer, ChainIssuerPrefix):
+		issuer, issuerPk := explainChainIssuer(std, trustRoot)
+		exp.Links = append(exp.Links, issuer)
+		signer = issuerPk
+
+		if issuerPk != nil {
+			_, _, tcs, sig, _ := std.ParseChainIssuerData()
+			leaf.check("signed by the chain issuer", verifyChainSignature(issuerPk, fmt.Sprintf("%s.%s", std.ID, tcs), sig))
+		}
+
+	default:
+		leaf.check("issued by the trust root", explainOrgIssuer(std, trustRoot))
+	}
+
+	if signer == nil {
+		leaf.check("token signature", fmt.Errorf("signer public key is unknown"))
+	} else {
+		leaf.check("token signature", verifyTokenSignature(token, signer))
+	}
+
+	leaf.check("validity window", (&parseOptions{}).verifyTimes(claims))
+
+	if v, ok := claims.(Validator); ok {
+		leaf.check("claims", v.Validate())
+	}
+
+	if len(std.IssuerChain) > 0 {
+		leaf.check("issuer constraints", explainIssuerConstraints(std.IssuerChain, claims))
+	}
+
+	exp.Links = append(exp.Links, leaf)
+
+	exp.Valid = true
+	for _, link := range exp.Links {
+		exp.Valid = exp.Valid && link.Valid
+	}
+
+	return exp, nil
+}
+
+// explainOrgIssuer checks tokens that are not issued by a chain issuer, org issued chain issuers must have a tcs made by the org issuer
+func explainOrgIssuer(std *StandardClaims, trustRoot ed25519.PublicKey) error {
+	if !strings.HasPrefix(std.Issuer, OrgIssuerPrefix) {
+		return nil
+	}
+
+	if std.TrustChainSignature == "" {
+		if std.Issuer != OrgIssuerPrefix+hex.EncodeToString(trustRoot) {
+			return fmt.Errorf("issuer %s is not the trust root", std.Issuer)
+		}
+
+		return nil
+	}
+
+	ok, _, err := std.IsSignedByIssuer(trustRoot)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("trust chain signature was not made by the trust root")
+	}
+
+	return nil
+}
+
+// explainChainIssuer builds the link for the chain issuer of std, using the embedded issuer token when available
+func explainChainIssuer(std *StandardClaims, trustRoot ed25519.PublicKey) (*ChainLink, ed25519.PublicKey) {
+	link := &ChainLink{Role: ChainLinkIssuer, Valid: true}
+
+	hID, hPubk, tcs, _, err := std.ParseChainIssuerData()
+	if !link.check("issuer data", err) {
+		return link, nil
+	}
+
+	link.TokenID = hID
+	link.PublicKey = hex.EncodeToString(hPubk)
+	link.Issuer = OrgIssuerPrefix + hex.EncodeToString(trustRoot)
+	if std.IssuerExpiresAt != nil {
+		link.ExpiresAt = std.IssuerExpiresAt.Time
+	}
+
+	// constrained chain issuers have their limits signed by the trust root, these are only known from the embedded token
+	data := fmt.Sprintf("%s.%s", hID, hex.EncodeToString(hPubk))
+
+	if len(std.IssuerChain) > 0 {
+		link.Embedded = true
+
+		issuer := &ClientIDClaims{}
+		_, err = parseUnverified(std.IssuerChain[len(std.IssuerChain)-1], issuer)
+		if err == nil {
+			filled := newChainLink(ChainLinkIssuer, issuer)
+			filled.Embedded, filled.Checks, filled.Valid = true, link.Checks, link.Valid
+			link = filled
+
+			if d, err := issuer.OrgIssuerChainData(); err == nil {
+				data = string(d)
+			}
+		}
+	}
+
+	tcsSig, err := hex.DecodeString(tcs)
+	if err != nil {
+		link.check("signed by the trust root", fmt.Errorf("invalid trust chain signature: %w", err))
+	} else {
+		link.check("signed by the trust root", verifyChainSignature(trustRoot, data, tcsSig))
+	}
+
+	if std.IssuerExpiresAt == nil {
+		link.check("issuer expiry", fmt.Errorf("no issuer expires set"))
+	} else if currentTime().After(std.IssuerExpiresAt.Time) {
+		link.check("issuer expiry", fmt.Errorf("issuer expired at %s", std.IssuerExpiresAt.Time.Format(time.RFC3339)))
+	} else {
+		link.check("issuer expiry", nil)
+	}
+
+	if link.Embedded {
+		_, err = verifyIssuerChain(std, trustRoot, currentTime())
+		link.check("embedded issuer chain", err)
+	}
+
+	return link, hPubk
+}
+
+// explainIssuerChain builds a link for every chain issuer in a multi level issuer chain, it returns the last chain
+// issuer when every link could be decoded
+func explainIssuerChain(chain []string, trustRoot ed25519.PublicKey) ([]*ChainLink, *ClientIDClaims) {
+	var links []*ChainLink
+	var parent *ClientIDClaims
+
+	signer := trustRoot
+
+	for i, token := range chain {
+		issuer := &ClientIDClaims{}
+		_, err := parseUnverified(token, issuer)
+		if err != nil {
+			link := &ChainLink{Role: ChainLinkIssuer, Embedded: true, Valid: true}
+			link.check("issuer data", err)
+
+			return append(links, link), nil
+		}
+
+		link := newChainLink(ChainLinkIssuer, issuer)
+
+		if parent == nil {
+			if !strings.HasPrefix(issuer.Issuer, OrgIssuerPrefix) || issuer.TrustChainSignature == "" {
+				link.check("signed by the trust root", fmt.Errorf("issuer %s is not a chain issuer of the trust root", issuer.Issuer))
+			} else {
+				link.check("signed by the trust root", explainOrgIssuer(&issuer.StandardClaims, trustRoot))
+			}
+		} else {
+			link.check("signed by the chain issuer", explainChainLink(&issuer.StandardClaims, parent))
+		}
+
+		if signer == nil {
+			link.check("token signature", fmt.Errorf("signer public key is unknown"))
+		} else {
+			link.check("token signature", verifyTokenSignature(token, signer))
+		}
+
+		link.check("validity window", (&parseOptions{}).verifyTimes(issuer))
+		link.check("delegation expiry", verifyChainDelegationExpiry(issuer, currentTime()))
+
+		below := len(chain) - i - 1
+		if below > issuer.ChainMaxDepth {
+			link.check("delegation depth", fmt.Errorf("%w: may delegate %d levels but %d follow it", ErrChainDepthExceeded, issuer.ChainMaxDepth, below))
+		} else {
+			link.check("delegation depth", nil)
+		}
+
+		links = append(links, link)
+		parent = issuer
+		signer = claimsPublicKey(&issuer.StandardClaims)
+	}
+
+	return links, parent
+}
+
+// explainChainLink checks that child was created by the chain issuer parent
+func explainChainLink(child *StandardClaims, parent *ClientIDClaims) error {
+	if child.Issuer != chainIssuerName(&parent.StandardClaims) {
+		return fmt.Errorf("issuer %s is not the previous chain issuer", child.Issuer)
+	}
+
+	_, pk, tcs, sig, err := child.ParseChainIssuerData()
+	if err != nil {
+		return err
+	}
+
+	if tcs != parent.TrustChainSignature {
+		return fmt.Errorf("trust chain signature does not extend that of the previous chain issuer")
+	}
+
+	return verifyChainSignature(pk, fmt.Sprintf("%s.%s", child.ID, tcs), sig)
+}
+
+// explainIssuerConstraints checks claims against the constraints of the chain issuers in chain without verifying them
+func explainIssuerConstraints(chain []string, claims jwt.Claims) error {
+	var issuers []*ClientIDClaims
+
+	for _, token := range chain {
+		issuer := &ClientIDClaims{}
+		_, err := parseUnverified(token, issuer)
+		if err != nil {
+			return err
+		}
+
+		issuers = append(issuers, issuer)
+	}
+
+	return verifyIssuerConstraints(issuers, claims)
+}
+
+// claimsPublicKey decodes the public key held in std, nil when it is not set or invalid
+func claimsPublicKey(std *StandardClaims) ed25519.PublicKey {
+	pk, err := hex.DecodeString(std.PublicKey)
+	if err != nil || len(pk) != ed25519.PublicKeySize {
+		return nil
+	}
+
+	return pk
+}
+
+// newChainLink creates a link describing claims
+func newChainLink(role ChainLinkRole, claims jwt.Claims) *ChainLink {
+	link := &ChainLink{Role: role, Embedded: true, Valid: true, Identity: claimsIdentity(claims)}
+
+	if sc, ok := claims.(standardClaimsProvider); ok {
+		std := sc.getStandardClaims()
+		link.Purpose = std.Purpose
+		link.TokenID = std.ID
+		link.Issuer = std.Issuer
+		link.PublicKey = std.PublicKey
+		link.ExpiresAt = std.ExpireTime()
+		if std.NotBefore != nil {
+			link.NotBefore = std.NotBefore.Time
+		}
+		if std.IssuedAt != nil {
+			link.IssuedAt = std.IssuedAt.Time
+		}
+		if std.ChainDelegationExpiresAt != nil {
+			link.DelegationExpiresAt = std.ChainDelegationExpiresAt.Time
+		}
+	}
+
+	switch c := claims.(type) {
+	case *ClientIDClaims:
+		lang, _ := c.PolicyLanguage()
+		link.Constraints = &ChainLinkConstraints{
+			AllowedAgents:     c.AllowedAgents,
+			Permissions:       c.Permissions,
+			PublishSubjects:   c.AdditionalPublishSubjects,
+			SubscribeSubjects: c.AdditionalSubscribeSubjects,
+			PolicyLanguage:    lang,
+			ChainMaxDepth:     c.ChainMaxDepth,
+			IssuerConstraints: c.IssuerConstraints,
+		}
+	case *ServerClaims:
+		link.Constraints = &ChainLinkConstraints{
+			ServerPermissions: c.Permissions,
+			Collectives:       c.Collectives,
+			PublishSubjects:   c.AdditionalPublishSubjects,
+		}
+	}
+
+	return link
+}
+
+// verifyChainSignature verifies the ed25519 signature sig over msg
+func verifyChainSignature(pk ed25519.PublicKey, msg string, sig []byte) error {
+	ok, err := ed25519Verify(pk, []byte(msg), sig)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("invalid signature")
+	}
+
+	return nil
+}
+
+// verifyTokenSignature verifies only the signature of token using key, claims are not validated
+func verifyTokenSignature(token string, key any) error {
+	t, err := parseUnverified(token, &jwt.MapClaims{})
+	if err != nil {
+		return err
+	}
+
+	alg := t.Method.Alg()
+	valid := false
+	for _, m := range validMethods {
+		if m == alg {
+			valid = true
+		}
+	}
+	if !valid {
+		return fmt.Errorf("signing method %v is invalid", alg)
+	}
+
+	idx := strings.LastIndex(token, ".")
+
+	return t.Method.Verify(token[:idx], token[idx+1:], key)
+}
@@ -0,0 +1,209 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ExplainChain", func() {
+	var (
+		orgPubK     ed25519.PublicKey
+		orgPriK     ed25519.PrivateKey
+		handlerPriK ed25519.PrivateKey
+		handler     *ClientIDClaims
+		handlerJWT  string
+		user        *ClientIDClaims
+	)
+
+	BeforeEach(func() {
+		var err error
+		var handlerPubK ed25519.PublicKey
+
+		orgPubK, orgPriK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		handlerPubK, handlerPriK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		handler, err = NewClientIDClaims("aaa=login", nil, "", nil, "", "", time.Hour, &ClientPermissions{AuthenticationDelegator: true}, handlerPubK)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(handler.AddOrgIssuerData(orgPriK)).To(Succeed())
+		handlerJWT, err = SignToken(handler, orgPriK)
+		Expect(err).ToNot(HaveOccurred())
+
+		userPubK, _, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		user, err = NewClientIDClaims("up=bob", []string{"rpcutil"}, "", nil, "", "", time.Hour, nil, userPubK)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(user.AddChainIssuerData(handler, handlerPriK)).To(Succeed())
+	})
+
+	It("Should fail for invalid tokens", func() {
+		_, err := ExplainChain("invalid", orgPubK)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Should explain valid chains", func() {
+		token, err := SignToken(user, handlerPriK, WithIssuerChain(handlerJWT))
+		Expect(err).ToNot(HaveOccurred())
+
+		exp, err := ExplainChain(token, orgPubK)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(exp.Valid).To(BeTrue())
+		Expect(exp.TrustRoot).To(Equal(hex.EncodeToString(orgPubK)))
+		Expect(exp.Links).To(HaveLen(3))
+
+		link, check := exp.FirstFailure()
+		Expect(link).To(BeNil())
+		Expect(check).To(BeNil())
+
+		Expect(exp.Links[0].Role).To(Equal(ChainLinkTrustRoot))
+
+		issuer := exp.Links[1]
+		Expect(issuer.Role).To(Equal(ChainLinkIssuer))
+		Expect(issuer.Embedded).To(BeTrue())
+		Expect(issuer.Identity).To(Equal("aaa=login"))
+		Expect(issuer.TokenID).To(Equal(handler.ID))
+		Expect(issuer.Constraints.Permissions.AuthenticationDelegator).To(BeTrue())
+		Expect(issuer.Valid).To(BeTrue())
+
+		leaf := exp.Links[2]
+		Expect(leaf.Role).To(Equal(ChainLinkToken))
+		Expect(leaf.Identity).To(Equal("up=bob"))
+		Expect(leaf.Constraints.AllowedAgents).To(Equal([]string{"rpcutil"}))
+		Expect(leaf.ExpiresAt.IsZero()).To(BeFalse())
+		Expect(leaf.Valid).To(BeTrue())
+
+		_, err = json.Marshal(exp)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Should explain chains without an embedded issuer token", func() {
+		token, err := SignToken(user, handlerPriK)
+		Expect(err).ToNot(HaveOccurred())
+
+		exp, err := ExplainChain(token, orgPubK)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(exp.Valid).To(BeTrue())
+		Expect(exp.Links[1].Embedded).To(BeFalse())
+		Expect(exp.Links[1].TokenID).To(Equal(handler.ID))
+		Expect(exp.Links[1].Identity).To(BeEmpty())
+	})
+
+	It("Should identify chain issuers not trusted by the trust root", func() {
+		token, err := SignToken(user, handlerPriK)
+		Expect(err).ToNot(HaveOccurred())
+
+		otherPubK, _, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		exp, err := ExplainChain(token, otherPubK)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(exp.Valid).To(BeFalse())
+
+		link, check := exp.FirstFailure()
+		Expect(link.Role).To(Equal(ChainLinkIssuer))
+		Expect(check.Name).To(Equal("signed by the trust root"))
+		Expect(exp.Links[2].Valid).To(BeTrue())
+		Expect(exp.String()).To(ContainSubstring("failed: signed by the trust root: invalid signature"))
+	})
+
+	It("Should identify expired and forged tokens", func() {
+		user.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+		token, err := SignToken(user, handlerPriK)
+		Expect(err).ToNot(HaveOccurred())
+
+		exp, err := ExplainChain(token, orgPubK)
+		Expect(err).ToNot(HaveOccurred())
+		link, check := exp.FirstFailure()
+		Expect(link.Role).To(Equal(ChainLinkToken))
+		Expect(check.Name).To(Equal("validity window"))
+
+		user.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Minute))
+		_, otherPriK, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		token, err = SignToken(user, otherPriK)
+		Expect(err).ToNot(HaveOccurred())
+
+		exp, err = ExplainChain(token, orgPubK)
+		Expect(err).ToNot(HaveOccurred())
+		link, check = exp.FirstFailure()
+		Expect(link.Role).To(Equal(ChainLinkToken))
+		Expect(check.Name).To(Equal("token signature"))
+	})
+
+	It("Should explain tokens issued by the trust root", func() {
+		exp, err := ExplainChain(handlerJWT, orgPubK)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(exp.Valid).To(BeTrue())
+		Expect(exp.Links).To(HaveLen(2))
+
+		otherPubK, _, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		exp, err = ExplainChain(handlerJWT, otherPubK)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(exp.Valid).To(BeFalse())
+
+		link, check := exp.FirstFailure()
+		Expect(link.Role).To(Equal(ChainLinkToken))
+		Expect(check.Name).To(Equal("issued by the trust root"))
+	})
+
+	It("Should explain multi level chains", func() {
+		regionalPubK, regionalPriK, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		teamPubK, teamPriK, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		explain := func(depth int) *ChainExplanation {
+			regional, err := NewClientIDClaims("aaa=regional", nil, "", nil, "", "", time.Hour, &ClientPermissions{AuthenticationDelegator: true}, regionalPubK, WithChainMaxDepth(depth))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(regional.AddOrgIssuerData(orgPriK)).To(Succeed())
+			regionalJWT, err := SignToken(regional, orgPriK)
+			Expect(err).ToNot(HaveOccurred())
+
+			team, err := NewClientIDClaims("aaa=team", nil, "", nil, "", "", time.Hour, &ClientPermissions{AuthenticationDelegator: true}, teamPubK)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(team.AddChainIssuerData(regional, regionalPriK)).To(Succeed())
+			teamJWT, err := SignToken(team, regionalPriK, WithIssuerChain(regionalJWT))
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(user.AddChainIssuerData(team, teamPriK)).To(Succeed())
+			user.IssuerChain = []string{regionalJWT, teamJWT}
+			token, err := SignToken(user, teamPriK)
+			Expect(err).ToNot(HaveOccurred())
+
+			exp, err := ExplainChain(token, orgPubK)
+			Expect(err).ToNot(HaveOccurred())
+
+			return exp
+		}
+
+		exp := explain(1)
+		Expect(exp.Valid).To(BeTrue())
+		Expect(exp.Links).To(HaveLen(4))
+		Expect(exp.Links[1].Identity).To(Equal("aaa=regional"))
+		Expect(exp.Links[1].Constraints.ChainMaxDepth).To(Equal(1))
+		Expect(exp.Links[2].Identity).To(Equal("aaa=team"))
+		Expect(exp.Links[2].Role).To(Equal(ChainLinkIssuer))
+		Expect(exp.Links[3].Identity).To(Equal("up=bob"))
+
+		exp = explain(0)
+		Expect(exp.Valid).To(BeFalse())
+		link, check := exp.FirstFailure()
+		Expect(link.Identity).To(Equal("aaa=regional"))
+		Expect(check.Name).To(Equal("delegation depth"))
+		Expect(exp.Links[2].Valid).To(BeTrue())
+		Expect(exp.Links[3].Valid).To(BeTrue())
+	})
+})
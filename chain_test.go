@@ -0,0 +1,442 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Issuer chains", func() {
+	var (
+		orgPubK     ed25519.PublicKey
+		orgPriK     ed25519.PrivateKey
+		handlerPubK ed25519.PublicKey
+		handlerPriK ed25519.PrivateKey
+		handler     *ClientIDClaims
+		handlerJWT  string
+		user        *ClientIDClaims
+	)
+
+	BeforeEach(func() {
+		var err error
+		orgPubK, orgPriK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		handlerPubK, handlerPriK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		handler, err = NewClientIDClaims("aaa=login", nil, "", nil, "", "", time.Hour, &ClientPermissions{AuthenticationDelegator: true}, handlerPubK)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(handler.AddOrgIssuerData(orgPriK)).To(Succeed())
+		handlerJWT, err = SignToken(handler, orgPriK)
+		Expect(err).ToNot(HaveOccurred())
+
+		userPubK, _, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		user, err = NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, userPubK)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(user.AddChainIssuerData(handler, handlerPriK)).To(Succeed())
+	})
+
+	Describe("IsSignedByIssuer", func() {
+		It("Should require the chain issuer to be signed by the org issuer", func() {
+			ok, _, err := user.IsSignedByIssuer(orgPubK)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ok).To(BeTrue())
+
+			otherPubK, _, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+			ok, _, err = user.IsSignedByIssuer(otherPubK)
+			Expect(err).To(MatchError("chain issuer was not signed by the org issuer"))
+			Expect(ok).To(BeFalse())
+
+			token, err := SignToken(user, handlerPriK)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = ParseClientIDToken(token, otherPubK, true)
+			Expect(err).To(MatchError(ErrorNotSignedByIssuer))
+			_, err = ParseClientIDToken(token, otherPubK, true, WithCompactVerification())
+			Expect(err).To(MatchError(ErrorNotSignedByIssuer))
+		})
+
+		It("Should reject chain issuers created without the org issuer", func() {
+			rogue, err := NewClientIDClaims("aaa=rogue", nil, "", nil, "", "", time.Hour, nil, handlerPubK)
+			Expect(err).ToNot(HaveOccurred())
+			_, roguePriK, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rogue.AddOrgIssuerData(roguePriK)).To(Succeed())
+
+			userPubK, _, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+			forged, err := NewClientIDClaims("up=admin", nil, "", nil, "", "", time.Hour, nil, userPubK)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(forged.AddChainIssuerData(rogue, handlerPriK)).To(Succeed())
+
+			token, err := SignToken(forged, handlerPriK)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = ParseClientIDToken(token, orgPubK, true)
+			Expect(err).To(MatchError(ErrorNotSignedByIssuer))
+		})
+	})
+
+	Describe("WithIssuerChain", func() {
+		It("Should validate the chain", func() {
+			_, err := SignToken(user, handlerPriK, WithIssuerChain(""))
+			Expect(err).To(MatchError("issuer token is required"))
+
+			_, err = SignToken(user, handlerPriK, WithIssuerChain("invalid"))
+			Expect(err).To(MatchError(ErrInvalidIssuerChain))
+
+			other, err := NewClientIDClaims("aaa=other", nil, "", nil, "", "", time.Hour, nil, handlerPubK)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(other.AddOrgIssuerData(orgPriK)).To(Succeed())
+			otherJWT, err := SignToken(other, orgPriK)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = SignToken(user, handlerPriK, WithIssuerChain(otherJWT))
+			Expect(err).To(MatchError("invalid issuer chain: issuer token does not match the token issuer"))
+
+			plain, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, nil)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = SignToken(plain, orgPriK, WithIssuerChain(handlerJWT))
+			Expect(err).To(MatchError("invalid issuer chain: only tokens issued by chain issuers can embed an issuer chain"))
+
+			_, err = SignToken(&jwt.RegisteredClaims{}, orgPriK, WithIssuerChain(handlerJWT))
+			Expect(err).To(MatchError("invalid issuer chain: claims do not support issuer chains"))
+		})
+
+		It("Should embed and verify the chain", func() {
+			token, err := SignToken(user, handlerPriK, WithIssuerChain(handlerJWT))
+			Expect(err).ToNot(HaveOccurred())
+
+			parsed, err := ParseClientIDToken(token, orgPubK, true, WithRequiredIssuerChain())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(parsed.IssuerChain).To(Equal([]string{handlerJWT}))
+
+			_, err = ParseClientIDToken(token, orgPubK, true, WithCompactVerification(), WithRequiredIssuerChain())
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("Should reject expired chain issuers", func() {
+			handler.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+			expiredJWT, err := SignToken(handler, orgPriK)
+			Expect(err).ToNot(HaveOccurred())
+
+			token, err := SignToken(user, handlerPriK, WithIssuerChain(expiredJWT))
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = ParseClientIDToken(token, orgPubK, true)
+			Expect(err).To(MatchError(ErrInvalidIssuerChain))
+			Expect(err).To(MatchError(ContainSubstring("expired")))
+		})
+
+		It("Should reject chain issuer tokens not signed by the org issuer", func() {
+			_, otherPriK, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+			badJWT, err := SignToken(handler, otherPriK)
+			Expect(err).ToNot(HaveOccurred())
+
+			token, err := SignToken(user, handlerPriK, WithIssuerChain(badJWT))
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = ParseClientIDToken(token, orgPubK, true)
+			Expect(err).To(MatchError(ErrInvalidIssuerChain))
+		})
+	})
+
+	Describe("Multi level chains", func() {
+		var (
+			regionalPriK ed25519.PrivateKey
+			teamPriK     ed25519.PrivateKey
+			leaf         *ClientIDClaims
+		)
+
+		// newRegionalChain creates a regional signer issued by the org issuer with a team signer below it
+		newRegionalChain := func(depth int) (string, string) {
+			regionalPubK, priK, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+			regionalPriK = priK
+			teamPubK, priK, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+			teamPriK = priK
+
+			regional, err := NewClientIDClaims("aaa=regional", nil, "", nil, "", "", time.Hour, &ClientPermissions{AuthenticationDelegator: true}, regionalPubK, WithChainMaxDepth(depth))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(regional.AddOrgIssuerData(orgPriK)).To(Succeed())
+			regionalJWT, err := SignToken(regional, orgPriK)
+			Expect(err).ToNot(HaveOccurred())
+
+			team, err := NewClientIDClaims("aaa=team", nil, "", nil, "", "", time.Hour, &ClientPermissions{AuthenticationDelegator: true}, teamPubK)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(team.AddChainIssuerData(regional, regionalPriK)).To(Succeed())
+			teamJWT, err := SignToken(team, regionalPriK, WithIssuerChain(regionalJWT))
+			Expect(err).ToNot(HaveOccurred())
+
+			leafPubK, _, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+			leaf, err = NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, leafPubK)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(leaf.AddChainIssuerData(team, teamPriK)).To(Succeed())
+
+			return regionalJWT, teamJWT
+		}
+
+		It("Should verify tokens issued by intermediate chain issuers", func() {
+			regionalJWT, teamJWT := newRegionalChain(1)
+
+			team, err := ParseClientIDToken(teamJWT, orgPubK, true)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(team.CallerID).To(Equal("aaa=team"))
+
+			token, err := SignToken(leaf, teamPriK, WithIssuerChain(teamJWT))
+			Expect(err).ToNot(HaveOccurred())
+
+			parsed, err := ParseClientIDToken(token, orgPubK, true)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(parsed.CallerID).To(Equal("up=bob"))
+			Expect(parsed.IssuerChain).To(Equal([]string{regionalJWT, teamJWT}))
+
+			otherPubK, _, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = ParseClientIDToken(token, otherPubK, true)
+			Expect(err).To(MatchError(ErrorNotSignedByIssuer))
+		})
+
+		It("Should require the issuer chain", func() {
+			newRegionalChain(1)
+
+			token, err := SignToken(leaf, teamPriK)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = ParseClientIDToken(token, orgPubK, true)
+			Expect(err).To(MatchError(ContainSubstring("multi level chain issued tokens require an embedded issuer chain")))
+		})
+
+		It("Should identify the failed link", func() {
+			regionalJWT, teamJWT := newRegionalChain(1)
+
+			team := &ClientIDClaims{}
+			_, err := parseUnverified(teamJWT, team)
+			Expect(err).ToNot(HaveOccurred())
+			_, otherPriK, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+			forgedJWT, err := SignToken(team, otherPriK)
+			Expect(err).ToNot(HaveOccurred())
+
+			leaf.IssuerChain = []string{regionalJWT, forgedJWT}
+			token, err := SignToken(leaf, teamPriK)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = ParseClientIDToken(token, orgPubK, true)
+			Expect(err).To(MatchError(ErrInvalidIssuerChain))
+
+			var linkErr *IssuerChainLinkError
+			Expect(errors.As(err, &linkErr)).To(BeTrue())
+			Expect(linkErr.Link).To(Equal(2))
+			Expect(linkErr.TokenID).To(Equal(team.ID))
+		})
+
+		It("Should verify every link of the chain once", func() {
+			_, teamJWT := newRegionalChain(1)
+
+			token, err := SignToken(leaf, teamPriK, WithIssuerChain(teamJWT))
+			Expect(err).ToNot(HaveOccurred())
+
+			tracer := &ginkgoTracer{}
+			SetTracer(tracer)
+			DeferCleanup(func() { SetTracer(nil) })
+
+			_, err = ParseClientIDToken(token, orgPubK, true)
+			Expect(err).ToNot(HaveOccurred())
+
+			var chains int
+			for _, span := range tracer.spans {
+				if span.name == "tokens.verify_chain" {
+					chains++
+				}
+			}
+			Expect(chains).To(Equal(1))
+		})
+
+		It("Should enforce the delegation depth", func() {
+			regionalJWT, teamJWT := newRegionalChain(0)
+
+			_, err := SignToken(leaf, teamPriK, WithIssuerChain(teamJWT))
+			Expect(err).To(MatchError(ErrChainDepthExceeded))
+
+			leaf.IssuerChain = []string{regionalJWT, teamJWT}
+			token, err := SignToken(leaf, teamPriK)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = ParseClientIDToken(token, orgPubK, true)
+			Expect(err).To(MatchError(ErrInvalidIssuerChain))
+			Expect(err).To(MatchError(ErrChainDepthExceeded))
+		})
+	})
+
+	Describe("AddOrgIssuerDataUsingSigner", func() {
+		It("Should require an ed25519 signer", func() {
+			Expect(handler.AddOrgIssuerDataUsingSigner(nil)).To(MatchError("signer is required"))
+
+			rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(handler.AddOrgIssuerDataUsingSigner(rsaKey)).To(MatchError("signer does not hold an ed25519 key"))
+		})
+
+		It("Should create chain issuers using a remote signer", func() {
+			kms := &testRemoteSigner{key: orgPriK}
+
+			issuer, err := NewClientIDClaims("aaa=login", nil, "", nil, "", "", time.Hour, &ClientPermissions{AuthenticationDelegator: true}, handlerPubK)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(issuer.AddOrgIssuerDataUsingSigner(kms)).To(Succeed())
+			Expect(issuer.IsChainedIssuer(true)).To(BeTrue())
+
+			issuerJWT, err := SignToken(issuer, kms)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = ParseClientIDToken(issuerJWT, orgPubK, true)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(user.AddChainIssuerData(issuer, handlerPriK)).To(Succeed())
+			token, err := SignToken(user, handlerPriK, WithIssuerChain(issuerJWT))
+			Expect(err).ToNot(HaveOccurred())
+			_, err = ParseClientIDToken(token, orgPubK, true)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("Should detect invalid signatures", func() {
+			_, otherPriK, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+
+			kms := &testRemoteSigner{key: orgPriK, signWith: otherPriK}
+			Expect(handler.AddOrgIssuerDataUsingSigner(kms)).To(MatchError("signer produced an invalid signature"))
+		})
+	})
+
+	Describe("WithChainDelegationExpiry", func() {
+		It("Should validate the expiry", func() {
+			_, err := NewClientIDClaims("aaa=login", nil, "", nil, "", "", time.Hour, nil, handlerPubK, WithChainDelegationExpiry(time.Time{}))
+			Expect(err).To(MatchError("chain delegation expiry cannot be zero"))
+
+			_, err = NewClientIDClaims("aaa=login", nil, "", nil, "", "", time.Hour, nil, handlerPubK, WithChainDelegationExpiry(time.Now().Add(2*time.Hour)))
+			Expect(err).To(MatchError("chain delegation expiry cannot be after the expiry time"))
+		})
+
+		It("Should limit the expiry of issued tokens", func() {
+			delegation := time.Now().Add(time.Minute).Truncate(time.Second)
+			issuer, err := NewClientIDClaims("aaa=login", nil, "", nil, "", "", time.Hour, &ClientPermissions{AuthenticationDelegator: true}, handlerPubK, WithChainDelegationExpiry(delegation))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(issuer.ChainDelegationExpiresAt.Time).To(BeTemporally("==", delegation))
+			Expect(issuer.AddOrgIssuerData(orgPriK)).To(Succeed())
+
+			Expect(user.AddChainIssuerData(issuer, handlerPriK)).To(Succeed())
+			Expect(user.IssuerExpiresAt.Time).To(BeTemporally("==", delegation))
+			Expect(user.ExpiresAt.Time).To(BeTemporally("==", delegation))
+		})
+
+		It("Should reject tokens issued after the delegation lapsed", func() {
+			issuer, err := NewClientIDClaims("aaa=login", nil, "", nil, "", "", time.Hour, &ClientPermissions{AuthenticationDelegator: true}, handlerPubK, WithChainDelegationExpiry(time.Now().Add(-time.Minute)))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(issuer.AddOrgIssuerData(orgPriK)).To(Succeed())
+			issuerJWT, err := SignToken(issuer, orgPriK)
+			Expect(err).ToNot(HaveOccurred())
+
+			// the issuer token remains valid
+			_, err = ParseClientIDToken(issuerJWT, orgPubK, true)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(user.AddChainIssuerData(issuer, handlerPriK)).To(Succeed())
+			user.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Hour))
+			user.IssuerExpiresAt = user.ExpiresAt
+			token, err := SignToken(user, handlerPriK, WithIssuerChain(issuerJWT))
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = ParseClientIDToken(token, orgPubK, true)
+			Expect(err).To(MatchError(ErrInvalidIssuerChain))
+			Expect(err).To(MatchError(ErrChainDelegationExpired))
+
+			exp, err := ExplainChain(token, orgPubK)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(exp.Valid).To(BeFalse())
+			Expect(exp.Links[1].DelegationExpiresAt.IsZero()).To(BeFalse())
+
+			// without the issuer chain the delegation expiry is unknown so the token is not accepted
+			user.IssuerChain = nil
+			token, err = SignToken(user, handlerPriK)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = ParseClientIDToken(token, orgPubK, true)
+			Expect(err).To(MatchError(ErrorNotSignedByIssuer))
+		})
+
+		It("Should bind the delegation expiry to the org issuer signature", func() {
+			delegation := time.Now().Add(time.Minute)
+			issuer, err := NewClientIDClaims("aaa=login", nil, "", nil, "", "", time.Hour, &ClientPermissions{AuthenticationDelegator: true}, handlerPubK, WithChainDelegationExpiry(delegation))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(issuer.AddOrgIssuerData(orgPriK)).To(Succeed())
+			Expect(issuer.IsChainedIssuer(true)).To(BeTrue())
+
+			issuer.ChainDelegationExpiresAt = jwt.NewNumericDate(delegation.Add(time.Minute))
+			Expect(issuer.IsChainedIssuer(true)).To(BeFalse())
+
+			issuer.ChainDelegationExpiresAt = nil
+			Expect(issuer.IsChainedIssuer(true)).To(BeFalse())
+		})
+	})
+
+	Describe("WithChainMaxDepth", func() {
+		It("Should set the permitted delegation depth", func() {
+			_, err := NewClientIDClaims("aaa=login", nil, "", nil, "", "", time.Hour, nil, handlerPubK, WithChainMaxDepth(-1))
+			Expect(err).To(MatchError("chain max depth cannot be negative"))
+
+			deep, err := NewClientIDClaims("aaa=login", nil, "", nil, "", "", time.Hour, nil, handlerPubK, WithChainMaxDepth(2))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deep.ChainMaxDepth).To(Equal(2))
+			Expect(handler.ChainMaxDepth).To(Equal(0))
+
+			deep.ChainMaxDepth = -1
+			Expect(deep.Validate()).To(MatchError("chain max depth cannot be negative"))
+		})
+	})
+
+	Describe("WithRequiredIssuerChain", func() {
+		It("Should require chain issued tokens to embed the chain", func() {
+			token, err := SignToken(user, handlerPriK)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = ParseClientIDToken(token, orgPubK, true)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = ParseClientIDToken(token, orgPubK, true, WithRequiredIssuerChain())
+			Expect(err).To(MatchError("could not parse client id token: invalid issuer chain: issuer chain is required"))
+
+			Expect(ParseToken(handlerJWT, &ClientIDClaims{}, orgPubK, WithRequiredIssuerChain())).To(Succeed())
+		})
+	})
+})
+
+// testRemoteSigner behaves like a KMS or HSM signer that does not expose the private key
+type testRemoteSigner struct {
+	key      ed25519.PrivateKey
+	signWith ed25519.PrivateKey
+}
+
+func (s *testRemoteSigner) Public() crypto.PublicKey {
+	return s.key.Public()
+}
+
+func (s *testRemoteSigner) Sign(_ io.Reader, msg []byte, _ crypto.SignerOpts) ([]byte, error) {
+	if s.signWith != nil {
+		return ed25519.Sign(s.signWith, msg), nil
+	}
+
+	return ed25519.Sign(s.key, msg), nil
+}
@@ -7,7 +7,6 @@ package tokens
 import (
 	"crypto/ed25519"
 	"crypto/md5"
-	"encoding/hex"
 	"fmt"
 	"os"
 	"strings"
@@ -73,6 +72,9 @@ type ClientIDClaims struct {
 	// OPAPolicy is a Open Policy Agent document to be used by the signer to limit the users actions
 	OPAPolicy string `json:"opa_policy,omitempty"`
 
+	// CELPolicy is a Common Expression Language policy limiting the users actions, an alternative to OPAPolicy
+	CELPolicy string `json:"cel_policy,omitempty"`
+
 	// Permissions sets additional permissions for a client
 	Permissions *ClientPermissions `json:"permissions,omitempty"`
 
@@ -82,6 +84,27 @@ type ClientIDClaims struct {
 	// AdditionalSubscribeSubjects are additional subjects the client can subscribe to
 	AdditionalSubscribeSubjects []string `json:"sub_subjects,omitempty"`
 
+	// Scout grants rights over Choria Scout checks
+	Scout *ScoutPermissions `json:"scout,omitempty"`
+
+	// ACL lists the agent and action patterns the user can invoke, when set it is used instead of AllowedAgents
+	ACL []ActionGrant `json:"acl,omitempty"`
+
+	// Machines grants lifecycle operations on Autonomous Agents
+	Machines []MachineGrant `json:"machines,omitempty"`
+
+	// Delegation is the chain of tokens this token was delegated from, the direct parent is last
+	Delegation []DelegationLink `json:"delegation,omitempty"`
+
+	// OnBehalfOf is the end user a privileged service is acting for, requires the AuthenticationDelegator permission
+	OnBehalfOf *OnBehalfOf `json:"on_behalf_of,omitempty"`
+
+	// Access restricts when and from where the token may be used
+	Access *AccessRestrictions `json:"access,omitempty"`
+
+	// Limits are resource ceilings the broker should enforce for the client
+	Limits *ResourceLimits `json:"limits,omitempty"`
+
 	StandardClaims
 }
 
@@ -95,27 +118,36 @@ func (c *ClientIDClaims) UniqueID() (id string, uid string) {
 	return c.CallerID, fmt.Sprintf("%x", md5.Sum([]byte(c.CallerID)))
 }
 
+// IsAgentActionAllowed determines if AllowedAgents grants access to action on agent, entries may be *, an agent name or agent.action
+func (c *ClientIDClaims) IsAgentActionAllowed(agent string, action string) bool {
+	return agentAllowed(c.AllowedAgents, agent+"."+action)
+}
+
 // NewClientIDClaims generates new ClientIDClaims
-func NewClientIDClaims(callerID string, allowedAgents []string, org string, properties map[string]string, opaPolicy string, issuer string, validity time.Duration, perms *ClientPermissions, pk ed25519.PublicKey) (*ClientIDClaims, error) {
+func NewClientIDClaims(callerID string, allowedAgents []string, org string, properties map[string]string, opaPolicy string, issuer string, validity time.Duration, perms *ClientPermissions, pk ed25519.PublicKey, opts ...ClaimsOption) (*ClientIDClaims, error) {
 	if callerID == "" {
 		return nil, fmt.Errorf("caller id is required")
 	}
 
-	stdClaims, err := newStandardClaims(issuer, ClientIDPurpose, validity, false)
+	if pk != nil {
+		opts = append([]ClaimsOption{withPublicKey(pk)}, opts...)
+	}
+
+	copts, err := newClaimsOptions(opts...)
 	if err != nil {
 		return nil, err
 	}
 
-	pubKey := ""
-	if pk != nil {
-		pubKey = hex.EncodeToString(pk)
+	stdClaims, err := newStandardClaims(issuer, ClientIDPurpose, validity, false, opts...)
+	if err != nil {
+		return nil, err
 	}
 
 	if org == "" {
 		org = defaultOrg
 	}
 
-	stdClaims.PublicKey = pubKey
+	stdClaims.IssuerConstraints = copts.issuerConstraints
 
 	return &ClientIDClaims{
 		CallerID:         callerID,
@@ -124,6 +156,7 @@ func NewClientIDClaims(callerID string, allowedAgents []string, org string, prop
 		UserProperties:   properties,
 		OPAPolicy:        opaPolicy,
 		Permissions:      perms,
+		Limits:           copts.limits,
 		StandardClaims:   *stdClaims,
 	}, nil
 }
@@ -139,7 +172,7 @@ func NewClientIDClaims(callerID string, allowedAgents []string, org string, prop
 // An empty callerid will result in an error
 func UnverifiedCallerFromClientIDToken(token string) (*jwt.Token, string, error) {
 	claims := &ClientIDClaims{}
-	t, _, err := new(jwt.Parser).ParseUnverified(token, claims)
+	t, err := parseUnverified(token, claims)
 	if err != nil {
 		return nil, "", err
 	}
@@ -158,7 +191,7 @@ func UnverifiedCallerFromClientIDToken(token string) (*jwt.Token, string, error)
 // IsClientIDTokenString calls IsClientIDToken on the token in a string
 func IsClientIDTokenString(token string) (bool, error) {
 	claims := &ClientIDClaims{}
-	_, _, err := new(jwt.Parser).ParseUnverified(token, claims)
+	_, err := parseUnverified(token, claims)
 	if err != nil {
 		return false, err
 	}
@@ -174,7 +207,7 @@ func IsClientIDToken(claims StandardClaims) bool {
 // ParseClientIDTokenUnverified parses the client token in an unverified manner.
 func ParseClientIDTokenUnverified(token string) (*ClientIDClaims, error) {
 	claims := &ClientIDClaims{}
-	_, _, err := new(jwt.Parser).ParseUnverified(token, claims)
+	_, err := parseUnverified(token, claims)
 	if err != nil {
 		return nil, err
 	}
@@ -187,9 +220,9 @@ func ParseClientIDTokenUnverified(token string) (*ClientIDClaims, error) {
 }
 
 // ParseClientIDToken parses token and verifies it with pk
-func ParseClientIDToken(token string, pk any, verifyPurpose bool) (*ClientIDClaims, error) {
+func ParseClientIDToken(token string, pk any, verifyPurpose bool, opts ...ParseOption) (*ClientIDClaims, error) {
 	claims := &ClientIDClaims{}
-	err := ParseToken(token, claims, pk)
+	err := ParseToken(token, claims, pk, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("could not parse client id token: %w", err)
 	}
@@ -208,8 +241,52 @@ func ParseClientIDToken(token string, pk any, verifyPurpose bool) (*ClientIDClai
 	return claims, nil
 }
 
+// Validate checks the caller id of client tokens, the policy, any impersonation, access restrictions, resource limits
+// and any action, scout or machine grants
+func (c *ClientIDClaims) Validate() error {
+	if IsClientIDToken(c.StandardClaims) && c.CallerID == "" {
+		return fmt.Errorf("caller id is required")
+	}
+
+	_, err := c.PolicyLanguage()
+	if err != nil {
+		return err
+	}
+
+	if c.ChainMaxDepth < 0 {
+		return fmt.Errorf("chain max depth cannot be negative")
+	}
+
+	err = c.validateImpersonation()
+	if err != nil {
+		return err
+	}
+
+	err = c.Access.Validate()
+	if err != nil {
+		return err
+	}
+
+	err = c.Limits.Validate()
+	if err != nil {
+		return err
+	}
+
+	err = c.Scout.validate()
+	if err != nil {
+		return err
+	}
+
+	err = validateActionGrants(c.ACL)
+	if err != nil {
+		return err
+	}
+
+	return validateMachineGrants(c.Machines)
+}
+
 // ParseClientIDTokenWithKeyfile parses token and verifies it with the RSA Public key in pkFile, does not support ed25519 public keys in a file
-func ParseClientIDTokenWithKeyfile(token string, pkFile string, verifyPurpose bool) (*ClientIDClaims, error) {
+func ParseClientIDTokenWithKeyfile(token string, pkFile string, verifyPurpose bool, opts ...ParseOption) (*ClientIDClaims, error) {
 	if pkFile == "" {
 		return nil, fmt.Errorf("invalid public key file")
 	}
@@ -224,5 +301,5 @@ func ParseClientIDTokenWithKeyfile(token string, pkFile string, verifyPurpose bo
 		return nil, err
 	}
 
-	return ParseClientIDToken(token, pk, verifyPurpose)
+	return ParseClientIDToken(token, pk, verifyPurpose, opts...)
 }
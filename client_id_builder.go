@@ -0,0 +1,255 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// ClientIDBuilder composes ClientIDClaims step by step, errors are recorded and reported by Build or Sign
+//
+//	token, err := tokens.NewClientIDBuilder("up=bob").
+//		WithAllowedAgents("rpcutil", "puppet.status").
+//		WithPermissions(&tokens.ClientPermissions{FleetManagement: true}).
+//		WithValidity(time.Hour).
+//		WithPublicKey(pubK).
+//		Sign(signerKey)
+type ClientIDBuilder struct {
+	callerID    string
+	agents      []string
+	org         string
+	properties  map[string]string
+	opaPolicy   string
+	celPolicy   string
+	issuer      string
+	validity    time.Duration
+	expires     time.Time
+	perms       *ClientPermissions
+	pk          ed25519.PublicKey
+	pubSubjects []string
+	subSubjects []string
+	onBehalfOf  *OnBehalfOf
+	acl         []ActionGrant
+	access      *AccessRestrictions
+	opts        []ClaimsOption
+	err         error
+}
+
+// NewClientIDBuilder starts building a client token for callerID
+func NewClientIDBuilder(callerID string) *ClientIDBuilder {
+	return &ClientIDBuilder{callerID: callerID}
+}
+
+// WithAllowedAgents adds agent or agent.action names the user can access
+func (b *ClientIDBuilder) WithAllowedAgents(agents ...string) *ClientIDBuilder {
+	b.agents = append(b.agents, agents...)
+	return b
+}
+
+// WithActionGrants adds grants to the ACL, see ClientIDClaims.AllowsAction
+func (b *ClientIDBuilder) WithActionGrants(grants ...ActionGrant) *ClientIDBuilder {
+	b.acl = append(b.acl, grants...)
+	return b
+}
+
+// WithAccessRestrictions limits when and from where the token may be used
+func (b *ClientIDBuilder) WithAccessRestrictions(access AccessRestrictions) *ClientIDBuilder {
+	b.access = &access
+	return b
+}
+
+// WithOrganization sets the organization unit, defaults to choria
+func (b *ClientIDBuilder) WithOrganization(org string) *ClientIDBuilder {
+	b.org = org
+	return b
+}
+
+// WithProperty sets a user property
+func (b *ClientIDBuilder) WithProperty(key string, value string) *ClientIDBuilder {
+	if b.properties == nil {
+		b.properties = make(map[string]string)
+	}
+
+	b.properties[key] = value
+
+	return b
+}
+
+// WithOPAPolicy sets the Open Policy Agent policy
+func (b *ClientIDBuilder) WithOPAPolicy(policy string) *ClientIDBuilder {
+	b.opaPolicy = policy
+	return b
+}
+
+// WithOPAPolicyFile sets the Open Policy Agent policy from the contents of file
+func (b *ClientIDBuilder) WithOPAPolicyFile(file string) *ClientIDBuilder {
+	policy, err := os.ReadFile(file)
+	if err != nil {
+		b.setErr(fmt.Errorf("could not read OPA policy: %w", err))
+		return b
+	}
+
+	b.opaPolicy = string(policy)
+
+	return b
+}
+
+// WithCELPolicy sets the Common Expression Language policy, tokens cannot hold both an OPA and CEL policy
+func (b *ClientIDBuilder) WithCELPolicy(policy string) *ClientIDBuilder {
+	b.celPolicy = policy
+	return b
+}
+
+// WithIssuer sets the issuer
+func (b *ClientIDBuilder) WithIssuer(issuer string) *ClientIDBuilder {
+	b.issuer = issuer
+	return b
+}
+
+// WithValidity sets how long the token will be valid for, replaces any expiry set using WithExpiry
+func (b *ClientIDBuilder) WithValidity(validity time.Duration) *ClientIDBuilder {
+	if validity < 0 {
+		b.setErr(fmt.Errorf("validity cannot be negative"))
+		return b
+	}
+
+	b.validity = validity
+	b.expires = time.Time{}
+
+	return b
+}
+
+// WithExpiry sets the time the token will expire, replaces any validity set using WithValidity
+func (b *ClientIDBuilder) WithExpiry(t time.Time) *ClientIDBuilder {
+	b.expires = t
+	b.validity = 0
+
+	return b
+}
+
+// WithPermissions sets the client permissions
+func (b *ClientIDBuilder) WithPermissions(perms *ClientPermissions) *ClientIDBuilder {
+	b.perms = perms
+	return b
+}
+
+// WithPublicKey sets the ed25519 public key of the user
+func (b *ClientIDBuilder) WithPublicKey(pk ed25519.PublicKey) *ClientIDBuilder {
+	b.pk = pk
+	return b
+}
+
+// WithPublicKeyHex sets the ed25519 public key of the user from its hex encoded form
+func (b *ClientIDBuilder) WithPublicKeyHex(pk string) *ClientIDBuilder {
+	k, err := hex.DecodeString(pk)
+	if err != nil {
+		b.setErr(fmt.Errorf("invalid public key: %w", err))
+		return b
+	}
+
+	b.pk = k
+
+	return b
+}
+
+// WithPublishSubjects adds additional subjects the client can publish to
+func (b *ClientIDBuilder) WithPublishSubjects(subjects ...string) *ClientIDBuilder {
+	b.pubSubjects = append(b.pubSubjects, subjects...)
+	return b
+}
+
+// WithSubscribeSubjects adds additional subjects the client can subscribe to
+func (b *ClientIDBuilder) WithSubscribeSubjects(subjects ...string) *ClientIDBuilder {
+	b.subSubjects = append(b.subSubjects, subjects...)
+	return b
+}
+
+// WithOnBehalfOf records the end user the token acts for, requires the AuthenticationDelegator permission
+func (b *ClientIDBuilder) WithOnBehalfOf(obo OnBehalfOf) *ClientIDBuilder {
+	b.onBehalfOf = &obo
+	return b
+}
+
+// WithOptions adds claims options like WithAudience or WithCustomClaims
+func (b *ClientIDBuilder) WithOptions(opts ...ClaimsOption) *ClientIDBuilder {
+	b.opts = append(b.opts, opts...)
+	return b
+}
+
+// Build creates the claims, returning the first error encountered while building
+func (b *ClientIDBuilder) Build() (*ClientIDClaims, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	validity := b.validity
+	if !b.expires.IsZero() {
+		validity = time.Until(b.expires)
+		if validity <= 0 {
+			return nil, fmt.Errorf("expiry must be in the future")
+		}
+	}
+
+	claims, err := NewClientIDClaims(b.callerID, b.agents, b.org, b.properties, b.opaPolicy, b.issuer, validity, b.perms, b.pk, b.opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if !b.expires.IsZero() {
+		claims.ExpiresAt = jwt.NewNumericDate(b.expires)
+	}
+
+	claims.AdditionalPublishSubjects = b.pubSubjects
+	claims.AdditionalSubscribeSubjects = b.subSubjects
+	claims.CELPolicy = b.celPolicy
+	claims.ACL = b.acl
+	claims.Access = b.access
+
+	err = claims.Access.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	err = validateActionGrants(claims.ACL)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = claims.PolicyLanguage()
+	if err != nil {
+		return nil, err
+	}
+
+	if b.onBehalfOf != nil {
+		err = claims.SetOnBehalfOf(*b.onBehalfOf)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return claims, nil
+}
+
+// Sign builds the claims and signs them using key, see SignToken for supported keys
+func (b *ClientIDBuilder) Sign(key any) (string, error) {
+	claims, err := b.Build()
+	if err != nil {
+		return "", err
+	}
+
+	return SignToken(claims, key)
+}
+
+func (b *ClientIDBuilder) setErr(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
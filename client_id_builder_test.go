@@ -0,0 +1,111 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ClientIDBuilder", func() {
+	var (
+		pubK ed25519.PublicKey
+		priK ed25519.PrivateKey
+	)
+
+	BeforeEach(func() {
+		var err error
+		pubK, priK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Should build complete claims", func() {
+		userPubK, _, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		perms := &ClientPermissions{FleetManagement: true}
+		claims, err := NewClientIDBuilder("up=bob").
+			WithAllowedAgents("rpcutil").
+			WithAllowedAgents("puppet.status").
+			WithOrganization("acme").
+			WithProperty("group", "admins").
+			WithOPAPolicy("package io.choria.aaasvc").
+			WithIssuer("ginkgo").
+			WithValidity(2 * time.Hour).
+			WithPermissions(perms).
+			WithPublicKeyHex(hex.EncodeToString(userPubK)).
+			WithPublishSubjects("x.y").
+			WithSubscribeSubjects("y.z").
+			WithOptions(WithAudience("ginkgo")).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(claims.CallerID).To(Equal("up=bob"))
+		Expect(claims.AllowedAgents).To(Equal([]string{"rpcutil", "puppet.status"}))
+		Expect(claims.OrganizationUnit).To(Equal("acme"))
+		Expect(claims.UserProperties).To(Equal(map[string]string{"group": "admins"}))
+		Expect(claims.OPAPolicy).To(Equal("package io.choria.aaasvc"))
+		Expect(claims.Issuer).To(Equal("ginkgo"))
+		Expect(claims.ExpiresAt.Time).To(BeTemporally("~", time.Now().Add(2*time.Hour), time.Second))
+		Expect(claims.Permissions).To(Equal(perms))
+		Expect(claims.PublicKey).To(Equal(hex.EncodeToString(userPubK)))
+		Expect(claims.AdditionalPublishSubjects).To(Equal([]string{"x.y"}))
+		Expect(claims.AdditionalSubscribeSubjects).To(Equal([]string{"y.z"}))
+		Expect(claims.Audience).To(ContainElement("ginkgo"))
+	})
+
+	It("Should sign tokens", func() {
+		token, err := NewClientIDBuilder("up=bob").WithExpiry(time.Now().Add(3 * time.Hour)).Sign(priK)
+		Expect(err).ToNot(HaveOccurred())
+
+		claims, err := ParseClientIDToken(token, pubK, true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(claims.CallerID).To(Equal("up=bob"))
+		Expect(claims.ExpiresAt.Time).To(BeTemporally("~", time.Now().Add(3*time.Hour), time.Second))
+	})
+
+	It("Should read OPA policies from files", func() {
+		file := filepath.Join(GinkgoT().TempDir(), "policy.rego")
+		Expect(os.WriteFile(file, []byte("package x"), 0600)).To(Succeed())
+
+		claims, err := NewClientIDBuilder("up=bob").WithOPAPolicyFile(file).Build()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(claims.OPAPolicy).To(Equal("package x"))
+	})
+
+	It("Should support CEL policies", func() {
+		claims, err := NewClientIDBuilder("up=bob").WithCELPolicy(`agent == "puppet"`).Build()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(claims.CELPolicy).To(Equal(`agent == "puppet"`))
+		Expect(claims.PolicyLanguage()).To(Equal(PolicyLanguageCEL))
+	})
+
+	It("Should report the first error", func() {
+		_, err := NewClientIDBuilder("up=bob").
+			WithOPAPolicyFile("/nonexisting").
+			WithPublicKeyHex("x").
+			Sign(priK)
+		Expect(err).To(MatchError(ContainSubstring("could not read OPA policy")))
+
+		_, err = NewClientIDBuilder("up=bob").WithValidity(-time.Hour).Build()
+		Expect(err).To(MatchError("validity cannot be negative"))
+
+		_, err = NewClientIDBuilder("up=bob").WithExpiry(time.Now().Add(-time.Hour)).Build()
+		Expect(err).To(MatchError("expiry must be in the future"))
+
+		_, err = NewClientIDBuilder("").Build()
+		Expect(err).To(MatchError("caller id is required"))
+
+		_, err = NewClientIDBuilder("up=bob").WithOPAPolicy("package x").WithCELPolicy("true").Build()
+		Expect(err).To(MatchError("tokens may only hold one of an opa or cel policy"))
+	})
+})
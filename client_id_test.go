@@ -80,6 +80,63 @@ var _ = Describe("ClientIDClaims", func() {
 			Expect(claims.ExpiresAt.Time).To(BeTemporally("~", time.Now().Add(time.Hour), time.Second))
 			Expect(claims.Permissions).To(Equal(perms))
 			Expect(claims.Permissions.OrgAdmin).To(BeTrue())
+			Expect(claims.Audience).To(BeEmpty())
+		})
+
+		It("Should validate the public key", func() {
+			_, err := NewClientIDClaims("up=ginkgo", nil, "choria", nil, "", "Ginkgo", time.Hour, nil, pubK[:20])
+			Expect(err).To(MatchError(ErrInvalidPublicKeySize))
+
+			_, err = NewClientIDClaims("up=ginkgo", nil, "choria", nil, "", "I-"+hex.EncodeToString(pubK), time.Hour, nil, pubK)
+			Expect(err).To(MatchError(ErrIssuerPublicKey))
+
+			claims, err := NewClientIDClaims("up=ginkgo", nil, "choria", nil, "", "I-"+hex.EncodeToString(pubK), time.Hour, nil, pubK, AllowIssuerPublicKey())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(claims.PublicKey).To(Equal(hex.EncodeToString(pubK)))
+		})
+
+		It("Should not set an issuer holding the public key of the token", func() {
+			orgPubK, orgPriK, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+
+			claims, err := NewClientIDClaims("up=ginkgo", nil, "choria", nil, "", "", time.Hour, &ClientPermissions{AuthenticationDelegator: true}, orgPubK)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(claims.AddOrgIssuerData(orgPriK)).To(MatchError(ErrIssuerPublicKey))
+			Expect(claims.AddOrgIssuerDataUsingSigner(orgPriK)).To(MatchError(ErrIssuerPublicKey))
+			Expect(claims.SetOrgIssuer(orgPubK)).To(MatchError(ErrIssuerPublicKey))
+			Expect(claims.Issuer).ToNot(HavePrefix(OrgIssuerPrefix))
+
+			user, err := NewClientIDClaims("up=bob", nil, "choria", nil, "", "", time.Hour, nil, orgPubK)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(user.SetChainIssuer(claims)).To(MatchError(ErrIssuerPublicKey))
+
+			claims, err = NewClientIDClaims("up=ginkgo", nil, "choria", nil, "", "", time.Hour, nil, orgPubK, AllowIssuerPublicKey())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(claims.AddOrgIssuerData(orgPriK)).To(Succeed())
+			Expect(claims.Issuer).To(Equal("I-" + hex.EncodeToString(orgPubK)))
+		})
+
+		It("Should support custom claims", func() {
+			claims, err := NewClientIDClaims("up=ginkgo", nil, "choria", nil, "", "Ginkgo", time.Hour, nil, pubK, WithCustomClaims(map[string]any{"team": "ops"}))
+			Expect(err).ToNot(HaveOccurred())
+			team, ok := claims.CustomClaimString("team")
+			Expect(ok).To(BeTrue())
+			Expect(team).To(Equal("ops"))
+
+			_, err = NewClientIDClaims("up=ginkgo", nil, "choria", nil, "", "Ginkgo", time.Hour, nil, pubK, WithCustomClaims(map[string]any{"": "ops"}))
+			Expect(err).To(MatchError("custom claim names cannot be empty"))
+
+			_, err = NewClientIDClaims("up=ginkgo", nil, "choria", nil, "", "Ginkgo", time.Hour, nil, pubK, WithCustomClaims(map[string]any{"fn": func() {}}))
+			Expect(err).To(MatchError(MatchRegexp(`invalid custom claim "fn"`)))
+		})
+
+		It("Should support setting an audience", func() {
+			claims, err := NewClientIDClaims("up=ginkgo", nil, "choria", nil, "", "Ginkgo", time.Hour, nil, pubK, WithAudience("choria_broker", "choria_aaa"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(claims.Audience).To(Equal(jwt.ClaimStrings{"choria_broker", "choria_aaa"}))
+
+			_, err = NewClientIDClaims("up=ginkgo", nil, "choria", nil, "", "Ginkgo", time.Hour, nil, pubK, WithAudience(""))
+			Expect(err).To(MatchError("audience cannot be empty"))
 		})
 	})
 
@@ -107,6 +164,50 @@ var _ = Describe("ClientIDClaims", func() {
 			Expect(err).ToNot(HaveOccurred())
 			Expect(claims.CallerID).To(Equal("up=ginkgo"))
 		})
+
+		It("Should enforce the audience when requested", func() {
+			claims, err := NewClientIDClaims("up=ginkgo", nil, "choria", nil, "", "Ginkgo", time.Hour, nil, pubK, WithAudience("choria_broker"))
+			Expect(err).ToNot(HaveOccurred())
+			token, err := SignToken(claims, loadRSAPriKey("testdata/rsa/signer-key.pem"))
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = ParseClientIDToken(token, loadRSAPubKey("testdata/rsa/signer-public.pem"), true, WithExpectedAudience("choria_aaa"))
+			Expect(err).To(MatchError(jwt.ErrTokenInvalidAudience))
+
+			_, err = ParseClientIDToken(validToken, loadRSAPubKey("testdata/rsa/signer-public.pem"), true, WithExpectedAudience("choria_broker"))
+			Expect(err).To(MatchError(jwt.ErrTokenInvalidAudience))
+
+			parsed, err := ParseClientIDToken(token, loadRSAPubKey("testdata/rsa/signer-public.pem"), true, WithExpectedAudience("choria_broker"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(parsed.Audience).To(Equal(jwt.ClaimStrings{"choria_broker"}))
+		})
+	})
+
+	Describe("Validate", func() {
+		It("Should require a caller id for client tokens", func() {
+			claims, err := NewClientIDClaims("up=ginkgo", nil, "choria", nil, "", "Ginkgo", time.Hour, nil, pubK)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(claims.Validate()).To(Succeed())
+
+			claims.CallerID = ""
+			Expect(claims.Validate()).To(MatchError("caller id is required"))
+
+			token, err := SignToken(claims, loadRSAPriKey("testdata/rsa/signer-key.pem"))
+			Expect(err).ToNot(HaveOccurred())
+			_, err = ParseClientIDToken(token, loadRSAPubKey("testdata/rsa/signer-public.pem"), false)
+			Expect(err).To(MatchError("could not parse client id token: caller id is required"))
+
+			claims.Purpose = ProvisioningPurpose
+			Expect(claims.Validate()).To(Succeed())
+		})
+
+		It("Should validate machine grants", func() {
+			claims, err := NewClientIDClaims("up=ginkgo", nil, "choria", nil, "", "Ginkgo", time.Hour, nil, pubK)
+			Expect(err).ToNot(HaveOccurred())
+
+			claims.Machines = []MachineGrant{{Machines: []string{"["}}}
+			Expect(claims.Validate()).To(MatchError("autonomous agent grants require at least one action"))
+		})
 	})
 
 	Describe("ParseClientIDTokenWithKeyfile", func() {
@@ -117,6 +218,23 @@ var _ = Describe("ClientIDClaims", func() {
 		})
 	})
 
+	Describe("IsAgentActionAllowed", func() {
+		It("Should match agents and actions", func() {
+			claims := &ClientIDClaims{AllowedAgents: []string{"rpcutil", "puppet.status"}}
+			Expect(claims.IsAgentActionAllowed("rpcutil", "ping")).To(BeTrue())
+			Expect(claims.IsAgentActionAllowed("puppet", "status")).To(BeTrue())
+			Expect(claims.IsAgentActionAllowed("puppet", "enable")).To(BeFalse())
+			Expect(claims.IsAgentActionAllowed("service", "stop")).To(BeFalse())
+
+			claims.AllowedAgents = []string{"*"}
+			Expect(claims.IsAgentActionAllowed("service", "stop")).To(BeTrue())
+
+			claims.AllowedAgents = []string{"puppet.*"}
+			Expect(claims.IsAgentActionAllowed("puppet", "disable")).To(BeTrue())
+			Expect(claims.IsAgentActionAllowed("service", "stop")).To(BeFalse())
+		})
+	})
+
 	Describe("IsClientIDToken", func() {
 		It("Should correctly check", func() {
 			Expect(IsClientIDTokenString(validToken)).To(BeTrue())
@@ -0,0 +1,42 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+type clockHolder struct {
+	now func() time.Time
+}
+
+var clock atomic.Value
+
+// SetClock sets the function used to determine the current time when issuing, renewing and validating tokens,
+// allowing tests and simulations to exercise expiry deterministically. nil restores the system clock
+func SetClock(now func() time.Time) {
+	clock.Store(clockHolder{now: now})
+}
+
+// currentTime is the time according to the clock set using SetClock
+func currentTime() time.Time {
+	h, _ := clock.Load().(clockHolder)
+	if h.now == nil {
+		return time.Now()
+	}
+
+	return h.now()
+}
+
+// Now is the current time according to the clock set using SetClock, for packages building on tokens
+func Now() time.Time {
+	return currentTime()
+}
+
+// FixedClock is a clock that always returns t, suitable for use with SetClock and the various clock options
+func FixedClock(t time.Time) func() time.Time {
+	return func() time.Time { return t }
+}
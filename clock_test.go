@@ -0,0 +1,89 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Clock", func() {
+	var (
+		pk       ed25519.PublicKey
+		signer   ed25519.PrivateKey
+		issued   time.Time
+		newToken func(opts ...ClaimsOption) string
+	)
+
+	BeforeEach(func() {
+		var err error
+		pk, signer, err = ed25519.GenerateKey(nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		issued = time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+		newToken = func(opts ...ClaimsOption) string {
+			claims, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, pk, opts...)
+			Expect(err).ToNot(HaveOccurred())
+			token, err := SignToken(claims, signer)
+			Expect(err).ToNot(HaveOccurred())
+			return token
+		}
+
+		DeferCleanup(func() { SetClock(nil) })
+	})
+
+	It("Should use the global clock", func() {
+		SetClock(FixedClock(issued))
+
+		token := newToken()
+		claims := &ClientIDClaims{}
+		Expect(ParseToken(token, claims, pk)).To(Succeed())
+		Expect(claims.IssuedAt.Time).To(BeTemporally("==", issued))
+		Expect(claims.ExpiresAt.Time).To(BeTemporally("==", issued.Add(time.Hour)))
+		Expect(claims.IsExpired()).To(BeFalse())
+
+		SetClock(FixedClock(issued.Add(2 * time.Hour)))
+		Expect(claims.IsExpired()).To(BeTrue())
+		Expect(ParseToken(token, &ClientIDClaims{}, pk)).To(MatchError(ContainSubstring("token is expired by 1h0m0s")))
+
+		report, err := ValidateToken(token, &ClientIDClaims{}, pk)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.HasReason(FailureExpired)).To(BeTrue())
+
+		SetClock(nil)
+		Expect(ParseToken(token, &ClientIDClaims{}, pk)).To(MatchError(ContainSubstring("token is expired")))
+	})
+
+	It("Should support per call clocks", func() {
+		token := newToken(WithClaimsClock(FixedClock(issued)))
+
+		Expect(ParseToken(token, &ClientIDClaims{}, pk, WithVerificationClock(FixedClock(issued.Add(time.Minute))))).To(Succeed())
+		Expect(ParseToken(token, &ClientIDClaims{}, pk, WithVerificationClock(FixedClock(issued.Add(-time.Minute))))).To(MatchError(ContainSubstring("token is not valid yet")))
+
+		_, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, pk, WithClaimsClock(nil))
+		Expect(err).To(MatchError("clock is required"))
+		Expect(ParseToken(token, &ClientIDClaims{}, pk, WithVerificationClock(nil))).To(MatchError("clock is required"))
+	})
+
+	It("Should renew using the clock", func() {
+		token := newToken(WithClaimsClock(FixedClock(issued)))
+
+		_, err := RenewToken(token, signer, 0)
+		Expect(err).To(MatchError(ErrTokenNotRenewable))
+
+		renewed := issued.Add(30 * time.Minute)
+		token, err = RenewToken(token, signer, 0, WithRenewalClock(FixedClock(renewed)))
+		Expect(err).ToNot(HaveOccurred())
+
+		claims := &ClientIDClaims{}
+		Expect(ParseToken(token, claims, pk, WithVerificationClock(FixedClock(renewed)))).To(Succeed())
+		Expect(claims.IssuedAt.Time).To(BeTemporally("==", renewed))
+		Expect(claims.ExpiresAt.Time).To(BeTemporally("==", renewed.Add(time.Hour)))
+	})
+})
@@ -0,0 +1,75 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"io"
+
+	"github.com/choria-io/tokens"
+)
+
+func chainCommand(ctx context.Context, args []string, out io.Writer) error {
+	if len(args) == 0 || args[0] != "explain" {
+		return fmt.Errorf("sub command required, one of explain")
+	}
+
+	var (
+		trustRoot string
+		asJSON    bool
+	)
+
+	fs := newFlagSet("chain explain", out)
+	fs.StringVar(&trustRoot, "trust-root", "", "Public key of the org issuer at the root of the chain")
+	fs.BoolVar(&asJSON, "json", false, "Produce JSON output")
+	err := fs.Parse(args[1:])
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		return fmt.Errorf("a token file is required")
+	}
+	if trustRoot == "" {
+		return fmt.Errorf("a trust root is required")
+	}
+
+	token, err := readToken(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	pk, err := verifierFor(ctx, trustRoot)
+	if err != nil {
+		return err
+	}
+
+	edpk, ok := pk.(ed25519.PublicKey)
+	if !ok {
+		return fmt.Errorf("trust root is not an ed25519 public key")
+	}
+
+	explanation, err := tokens.ExplainChain(token, edpk)
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		err = printJSON(out, explanation)
+	} else {
+		_, err = fmt.Fprint(out, explanation.String())
+	}
+	if err != nil {
+		return err
+	}
+
+	if !explanation.Valid {
+		return errInvalid
+	}
+
+	return nil
+}
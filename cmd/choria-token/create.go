@@ -0,0 +1,304 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/choria-io/tokens"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// commonCreateFlags are the flags shared by all token types
+type commonCreateFlags struct {
+	key      string
+	output   string
+	issuer   string
+	org      string
+	validity time.Duration
+	audience listFlag
+}
+
+func (c *commonCreateFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&c.key, "key", "", "Key used to sign the token")
+	fs.StringVar(&c.output, "output", "", "File to save the token in, prints the token when not set")
+	fs.StringVar(&c.issuer, "issuer", "", "Issuer of the token")
+	fs.StringVar(&c.org, "org", "", "Organization unit the token belongs to")
+	fs.DurationVar(&c.validity, "validity", time.Hour, "How long the token is valid for")
+	fs.Var(&c.audience, "audience", "Audience the token is intended for, may be repeated")
+}
+
+func (c *commonCreateFlags) claimsOptions() []tokens.ClaimsOption {
+	if len(c.audience) == 0 {
+		return nil
+	}
+
+	return []tokens.ClaimsOption{tokens.WithAudience(c.audience...)}
+}
+
+// chainFlags configure tokens that are part of an issuer chain
+type chainFlags struct {
+	orgIssuer   bool
+	chainIssuer string
+}
+
+func (c *chainFlags) register(fs *flag.FlagSet) {
+	fs.BoolVar(&c.orgIssuer, "org-issuer", false, "Adds org issuer data using the signing key, creating a chain issuer")
+	fs.StringVar(&c.chainIssuer, "chain-issuer", "", "File holding the token of the chain issuer owning the signing key")
+}
+
+// apply adds org or chain issuer data to std using signer
+func (c *chainFlags) apply(std *tokens.StandardClaims, signer crypto.Signer) error {
+	switch {
+	case c.orgIssuer && c.chainIssuer != "":
+		return fmt.Errorf("only one of --org-issuer and --chain-issuer can be set")
+
+	case c.orgIssuer:
+		return std.AddOrgIssuerDataUsingSigner(signer)
+
+	case c.chainIssuer != "":
+		token, err := readToken(c.chainIssuer)
+		if err != nil {
+			return err
+		}
+
+		issuer, err := tokens.ParseClientIDTokenUnverified(token)
+		if err != nil {
+			return err
+		}
+
+		edk, ok := signer.(ed25519.PrivateKey)
+		if !ok {
+			return fmt.Errorf("chain issuers require a ed25519 signing key")
+		}
+
+		return std.AddChainIssuerData(issuer, edk)
+	}
+
+	return nil
+}
+
+func createCommand(ctx context.Context, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("token type required, one of client, server or provisioning")
+	}
+
+	switch args[0] {
+	case "client":
+		return createClient(ctx, args[1:], out)
+	case "server":
+		return createServer(ctx, args[1:], out)
+	case "provisioning":
+		return createProvisioning(ctx, args[1:], out)
+	default:
+		return fmt.Errorf("unknown token type %q, one of client, server or provisioning", args[0])
+	}
+}
+
+func createClient(ctx context.Context, args []string, out io.Writer) error {
+	var (
+		common      commonCreateFlags
+		chain       chainFlags
+		agents      listFlag
+		permissions listFlag
+		pubSubjects listFlag
+		properties  = mapFlag{}
+		callerID    string
+		publicKey   string
+		opaPolicy   string
+	)
+
+	fs := newFlagSet("create client", out)
+	common.register(fs)
+	chain.register(fs)
+	fs.StringVar(&callerID, "caller", "", "Caller ID of the client")
+	fs.StringVar(&publicKey, "public-key", "", "Hex encoded ed25519 public key of the client")
+	fs.StringVar(&opaPolicy, "opa-policy", "", "Open Policy Agent policy restricting the client")
+	fs.Var(&agents, "agent", "Agent the client may access, may be repeated")
+	fs.Var(&permissions, "permission", "Permission to grant, for example fleet_management, may be repeated")
+	fs.Var(&pubSubjects, "publish", "Additional subject the client may publish to, may be repeated")
+	fs.Var(properties, "property", "User property as key=value, may be repeated")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	perms := &tokens.ClientPermissions{}
+	err = parsePermissions(permissions, perms)
+	if err != nil {
+		return err
+	}
+
+	pk, err := ed25519PublicKey(publicKey)
+	if err != nil {
+		return err
+	}
+
+	signer, err := signerFor(ctx, common.key)
+	if err != nil {
+		return err
+	}
+
+	claims, err := tokens.NewClientIDClaims(callerID, agents, common.org, properties, opaPolicy, common.issuer, common.validity, perms, pk, common.claimsOptions()...)
+	if err != nil {
+		return err
+	}
+	claims.AdditionalPublishSubjects = pubSubjects
+
+	err = chain.apply(&claims.StandardClaims, signer)
+	if err != nil {
+		return err
+	}
+
+	return signAndWrite(claims, signer, common.output, out)
+}
+
+func createServer(ctx context.Context, args []string, out io.Writer) error {
+	var (
+		common      commonCreateFlags
+		chain       chainFlags
+		collectives listFlag
+		permissions listFlag
+		pubSubjects listFlag
+		identity    string
+		publicKey   string
+	)
+
+	fs := newFlagSet("create server", out)
+	common.register(fs)
+	chain.register(fs)
+	fs.StringVar(&identity, "identity", "", "Identity of the server")
+	fs.StringVar(&publicKey, "public-key", "", "Hex encoded ed25519 public key of the server")
+	fs.Var(&collectives, "collective", "Collective the server belongs to, may be repeated")
+	fs.Var(&permissions, "permission", "Permission to grant, for example submission, may be repeated")
+	fs.Var(&pubSubjects, "publish", "Additional subject the server may publish to, may be repeated")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	perms := &tokens.ServerPermissions{}
+	err = parsePermissions(permissions, perms)
+	if err != nil {
+		return err
+	}
+
+	pk, err := ed25519PublicKey(publicKey)
+	if err != nil {
+		return err
+	}
+
+	signer, err := signerFor(ctx, common.key)
+	if err != nil {
+		return err
+	}
+
+	claims, err := tokens.NewServerClaims(identity, collectives, common.org, perms, pubSubjects, pk, common.issuer, common.validity, common.claimsOptions()...)
+	if err != nil {
+		return err
+	}
+
+	err = chain.apply(&claims.StandardClaims, signer)
+	if err != nil {
+		return err
+	}
+
+	return signAndWrite(claims, signer, common.output, out)
+}
+
+func createProvisioning(ctx context.Context, args []string, out io.Writer) error {
+	var (
+		common    commonCreateFlags
+		urls      listFlag
+		secure    bool
+		byDefault bool
+		token     string
+		user      string
+		password  string
+		srvDomain string
+		regData   string
+		facts     string
+	)
+
+	fs := newFlagSet("create provisioning", out)
+	common.register(fs)
+	fs.Var(&urls, "url", "Provisioning broker URL, may be repeated")
+	fs.BoolVar(&secure, "secure", true, "Use TLS when connecting to the provisioning brokers")
+	fs.BoolVar(&byDefault, "default", false, "Provision servers without configuration by default")
+	fs.StringVar(&token, "token", "", "Token servers present to the provisioner")
+	fs.StringVar(&user, "user", "", "User to connect to the provisioning brokers as")
+	fs.StringVar(&password, "password", "", "Password to connect to the provisioning brokers with")
+	fs.StringVar(&srvDomain, "srv-domain", "", "Domain used to find provisioning brokers using SRV records")
+	fs.StringVar(&regData, "registration-data", "", "File servers publish registration data from")
+	fs.StringVar(&facts, "facts", "", "File servers read facts from")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	signer, err := signerFor(ctx, common.key)
+	if err != nil {
+		return err
+	}
+
+	claims, err := tokens.NewProvisioningClaims(secure, byDefault, token, user, password, urls, srvDomain, regData, facts, common.org, common.issuer, common.validity, common.claimsOptions()...)
+	if err != nil {
+		return err
+	}
+
+	return signAndWrite(claims, signer, common.output, out)
+}
+
+// parsePermissions sets the boolean fields of perms named by their JSON names in names
+func parsePermissions(names []string, perms any) error {
+	set := map[string]bool{}
+	for _, n := range names {
+		set[n] = true
+	}
+
+	j, err := json.Marshal(set)
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(j))
+	dec.DisallowUnknownFields()
+	err = dec.Decode(perms)
+	if err != nil {
+		return fmt.Errorf("invalid permissions: %w", err)
+	}
+
+	return nil
+}
+
+// signAndWrite signs claims and saves the token to file, or prints it when file is empty
+func signAndWrite(claims jwt.Claims, signer crypto.Signer, file string, out io.Writer) error {
+	token, err := tokens.SignToken(claims, signer)
+	if err != nil {
+		return err
+	}
+
+	return writeToken(token, file, out)
+}
+
+func writeToken(token string, file string, out io.Writer) error {
+	if file == "" {
+		_, err := fmt.Fprintln(out, token)
+		return err
+	}
+
+	return tokens.SaveToken(token, file, 0600)
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/choria-io/tokens"
+)
+
+func inspectCommand(ctx context.Context, args []string, out io.Writer) error {
+	var key string
+
+	fs := newFlagSet("inspect", out)
+	fs.StringVar(&key, "key", "", "Public key or certificate used to verify the token")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		return fmt.Errorf("a token file is required")
+	}
+
+	token, err := readToken(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	var res any
+	if key == "" {
+		res, err = tokens.ParseAnyTokenUnverified(token)
+	} else {
+		var pk any
+		pk, err = verifierFor(ctx, key)
+		if err != nil {
+			return err
+		}
+
+		res, err = tokens.DescribeToken(token, pk)
+	}
+	if err != nil {
+		return err
+	}
+
+	return printJSON(out, res)
+}
+
+func printJSON(out io.Writer, v any) error {
+	j, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(out, string(j))
+
+	return err
+}
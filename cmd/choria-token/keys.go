@@ -0,0 +1,74 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/choria-io/tokens"
+)
+
+// signerFor resolves a key reference to a signer
+func signerFor(ctx context.Context, ref string) (crypto.Signer, error) {
+	if ref == "" {
+		return nil, fmt.Errorf("a signing key is required")
+	}
+
+	resolver, err := tokens.NewKeyRefResolver()
+	if err != nil {
+		return nil, err
+	}
+
+	return resolver.Signer(ctx, ref)
+}
+
+// verifierFor resolves a key reference to a public key
+func verifierFor(ctx context.Context, ref string) (crypto.PublicKey, error) {
+	resolver, err := tokens.NewKeyRefResolver()
+	if err != nil {
+		return nil, err
+	}
+
+	return resolver.PublicKey(ctx, ref)
+}
+
+// ed25519PublicKey parses a hex encoded ed25519 public key, empty values are allowed
+func ed25519PublicKey(hexKey string) (ed25519.PublicKey, error) {
+	if hexKey == "" {
+		return nil, nil
+	}
+
+	pk, err := tokens.ReadVerificationKey(strings.NewReader(hexKey))
+	if err != nil {
+		return nil, err
+	}
+
+	edpk, ok := pk.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key is not an ed25519 key")
+	}
+
+	return edpk, nil
+}
+
+// readToken reads a token from a file, - reads from standard input
+func readToken(file string) (string, error) {
+	if file == "-" {
+		return tokens.ReadToken(os.Stdin)
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	return tokens.ReadToken(f)
+}
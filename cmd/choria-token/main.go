@@ -0,0 +1,139 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Command choria-token creates, inspects, verifies and renews Choria tokens using the public API of the tokens package
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+)
+
+// errInvalid indicates a token failed verification, the details have already been written to the output
+var errInvalid = errors.New("token is not valid")
+
+type command struct {
+	description string
+	run         func(ctx context.Context, args []string, out io.Writer) error
+}
+
+var commands = map[string]command{
+	"create":  {"Creates and signs client, server and provisioning tokens", createCommand},
+	"inspect": {"Shows the contents of a token, verifying it when a key is given", inspectCommand},
+	"verify":  {"Verifies a token and lists every problem found", verifyCommand},
+	"renew":   {"Signs a renewed copy of a token", renewCommand},
+	"chain":   {"Explains the issuer chain of a token", chainCommand},
+}
+
+func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	os.Exit(run(ctx, os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the command given in args and returns the process exit code
+func run(ctx context.Context, args []string, out io.Writer, errOut io.Writer) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		usage(errOut)
+		return 1
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(errOut, "choria-token: unknown command %q\n\n", args[0])
+		usage(errOut)
+		return 1
+	}
+
+	err := cmd.run(ctx, args[1:], out)
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, flag.ErrHelp):
+		return 1
+	case errors.Is(err, errInvalid):
+		return 2
+	default:
+		fmt.Fprintf(errOut, "choria-token %s: %v\n", args[0], err)
+		return 1
+	}
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: choria-token <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-8s %s\n", name, commands[name].description)
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Keys are file names or references like env://VARIABLE and vault://key")
+}
+
+// newFlagSet creates a flag set for a sub command that reports errors rather than exiting
+func newFlagSet(name string, out io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet("choria-token "+name, flag.ContinueOnError)
+	fs.SetOutput(out)
+
+	return fs
+}
+
+// listFlag is a flag that may be given many times, values may also be comma separated
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(v string) error {
+	for _, i := range strings.Split(v, ",") {
+		i = strings.TrimSpace(i)
+		if i != "" {
+			*l = append(*l, i)
+		}
+	}
+
+	return nil
+}
+
+// mapFlag is a flag holding key=value pairs that may be given many times
+type mapFlag map[string]string
+
+func (m mapFlag) String() string {
+	pairs := make([]string, 0, len(m))
+	for k, v := range m {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ",")
+}
+
+func (m mapFlag) Set(v string) error {
+	k, val, ok := strings.Cut(v, "=")
+	if !ok || k == "" {
+		return fmt.Errorf("expected key=value")
+	}
+
+	m[k] = val
+
+	return nil
+}
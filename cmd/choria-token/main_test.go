@@ -0,0 +1,142 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/choria-io/tokens"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestChoriaToken(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Commands/choria-token")
+}
+
+var _ = Describe("choria-token", func() {
+	var (
+		td                       string
+		signerSeed, signerPublic string
+		otherSeed, otherPublic   string
+		stdout, stderr           *bytes.Buffer
+		execute                  func(args ...string) int
+	)
+
+	BeforeEach(func() {
+		td = GinkgoT().TempDir()
+		signerSeed = "../../testdata/ed25519/signer.seed"
+		signerPublic = "../../testdata/ed25519/signer.public"
+		otherSeed = "../../testdata/ed25519/other.seed"
+		otherPublic = "../../testdata/ed25519/other.public"
+
+		execute = func(args ...string) int {
+			stdout = &bytes.Buffer{}
+			stderr = &bytes.Buffer{}
+			return run(context.Background(), args, stdout, stderr)
+		}
+	})
+
+	It("Should show usage", func() {
+		Expect(execute()).To(Equal(1))
+		Expect(stderr.String()).To(ContainSubstring("renew    Signs a renewed copy of a token"))
+
+		Expect(execute("other")).To(Equal(1))
+		Expect(stderr.String()).To(ContainSubstring(`unknown command "other"`))
+	})
+
+	It("Should create, inspect and verify client tokens", func() {
+		out := filepath.Join(td, "client.jwt")
+		Expect(execute("create", "client", "--key", signerSeed, "--caller", "up=bob", "--agent", "rpcutil,puppet", "--permission", "fleet_management", "--property", "group=admins", "--audience", "choria", "--output", out)).To(Equal(0), stderr.String())
+
+		token, err := os.ReadFile(out)
+		Expect(err).ToNot(HaveOccurred())
+		claims, err := tokens.ParseClientIDTokenWithKeyfile(string(token), signerPublic, true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(claims.CallerID).To(Equal("up=bob"))
+		Expect(claims.AllowedAgents).To(Equal([]string{"rpcutil", "puppet"}))
+		Expect(claims.Permissions.FleetManagement).To(BeTrue())
+		Expect(claims.UserProperties).To(HaveKeyWithValue("group", "admins"))
+
+		Expect(execute("inspect", out)).To(Equal(0))
+		Expect(stdout.String()).To(ContainSubstring(`"callerid": "up=bob"`))
+
+		Expect(execute("inspect", "--key", signerPublic, out)).To(Equal(0))
+		Expect(stdout.String()).To(ContainSubstring(`"valid": true`))
+
+		Expect(execute("verify", "--key", signerPublic, "--audience", "choria", out)).To(Equal(0))
+		Expect(stdout.String()).To(Equal("choria_client_id token is valid\n"))
+
+		Expect(execute("verify", "--key", otherPublic, "--audience", "other", out)).To(Equal(2))
+		Expect(stdout.String()).To(ContainSubstring("choria_client_id token is not valid:"))
+		Expect(stdout.String()).To(ContainSubstring("signature:"))
+		Expect(stdout.String()).To(ContainSubstring("audience:"))
+
+		Expect(execute("create", "client", "--key", signerSeed, "--caller", "up=bob", "--permission", "unknown")).To(Equal(1))
+		Expect(stderr.String()).To(ContainSubstring("invalid permissions"))
+	})
+
+	It("Should create server and provisioning tokens", func() {
+		Expect(execute("create", "server", "--key", signerSeed, "--identity", "n1.example.net", "--collective", "choria", "--public-key", strings.Repeat("a", 64), "--permission", "submission")).To(Equal(0), stderr.String())
+		claims, err := tokens.ParseServerTokenUnverified(strings.TrimSpace(stdout.String()))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(claims.ChoriaIdentity).To(Equal("n1.example.net"))
+		Expect(claims.Permissions.Submission).To(BeTrue())
+
+		Expect(execute("create", "provisioning", "--key", signerSeed, "--url", "nats://prov.example.net:4222", "--token", "secret")).To(Equal(0), stderr.String())
+		prov, err := tokens.ParseProvisionTokenUnverified(strings.TrimSpace(stdout.String()))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(prov.URLs).To(Equal("nats://prov.example.net:4222"))
+
+		Expect(execute("create", "other")).To(Equal(1))
+		Expect(execute("create", "server", "--identity", "n1.example.net", "--collective", "choria")).To(Equal(1))
+		Expect(stderr.String()).To(ContainSubstring("a signing key is required"))
+	})
+
+	It("Should renew tokens", func() {
+		out := filepath.Join(td, "client.jwt")
+		Expect(execute("create", "client", "--key", signerSeed, "--caller", "up=bob", "--output", out)).To(Equal(0), stderr.String())
+
+		renewed := filepath.Join(td, "renewed.jwt")
+		Expect(execute("renew", "--key", signerSeed, "--validity", "2h", "--output", renewed, out)).To(Equal(0), stderr.String())
+
+		token, err := os.ReadFile(renewed)
+		Expect(err).ToNot(HaveOccurred())
+		claims, err := tokens.ParseClientIDTokenWithKeyfile(string(token), signerPublic, true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(claims.ReissuedFrom).ToNot(BeEmpty())
+
+		Expect(execute("renew", "--key", otherSeed, out)).To(Equal(1))
+		Expect(stderr.String()).To(ContainSubstring("token cannot be renewed"))
+	})
+
+	It("Should create and explain issuer chains", func() {
+		handlerPub, handlerPri, err := ed25519.GenerateKey(nil)
+		Expect(err).ToNot(HaveOccurred())
+		handlerSeed := filepath.Join(td, "handler.seed")
+		Expect(os.WriteFile(handlerSeed, []byte(hex.EncodeToString(handlerPri.Seed())), 0600)).To(Succeed())
+
+		handler := filepath.Join(td, "handler.jwt")
+		Expect(execute("create", "client", "--key", otherSeed, "--org-issuer", "--caller", "aaa=login", "--permission", "authentication_delegator", "--public-key", hex.EncodeToString(handlerPub), "--output", handler)).To(Equal(0), stderr.String())
+
+		user := filepath.Join(td, "user.jwt")
+		Expect(execute("create", "client", "--key", handlerSeed, "--chain-issuer", handler, "--caller", "up=bob", "--output", user)).To(Equal(0), stderr.String())
+
+		Expect(execute("chain", "explain", "--trust-root", otherPublic, user)).To(Equal(0), stderr.String())
+		Expect(stdout.String()).To(ContainSubstring("up=bob"))
+
+		Expect(execute("chain", "explain", "--trust-root", signerPublic, user)).To(Equal(2))
+		Expect(execute("create", "client", "--key", handlerSeed, "--chain-issuer", handler, "--org-issuer", "--caller", "up=bob")).To(Equal(1))
+		Expect(stderr.String()).To(ContainSubstring("only one of --org-issuer and --chain-issuer"))
+	})
+})
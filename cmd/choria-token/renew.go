@@ -0,0 +1,65 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/choria-io/tokens"
+)
+
+func renewCommand(ctx context.Context, args []string, out io.Writer) error {
+	var (
+		key       string
+		verifyKey string
+		output    string
+		validity  time.Duration
+		grace     time.Duration
+	)
+
+	fs := newFlagSet("renew", out)
+	fs.StringVar(&key, "key", "", "Key used to sign the renewed token")
+	fs.StringVar(&verifyKey, "verify-key", "", "Public key used to verify the token, defaults to the public key of the signing key")
+	fs.StringVar(&output, "output", "", "File to save the token in, prints the token when not set")
+	fs.DurationVar(&validity, "validity", 0, "How long the renewed token is valid for, defaults to the validity of the token")
+	fs.DurationVar(&grace, "grace", 0, "Allows tokens that expired less than this long ago to be renewed")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		return fmt.Errorf("a token file is required")
+	}
+
+	token, err := readToken(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	signer, err := signerFor(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	opts := []tokens.RenewOption{tokens.WithRenewalGracePeriod(grace)}
+	if verifyKey != "" {
+		pk, err := verifierFor(ctx, verifyKey)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, tokens.WithRenewalVerificationKey(pk))
+	}
+
+	renewed, err := tokens.RenewToken(token, signer, validity, opts...)
+	if err != nil {
+		return err
+	}
+
+	return writeToken(renewed, output, out)
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/choria-io/tokens"
+)
+
+func verifyCommand(ctx context.Context, args []string, out io.Writer) error {
+	var (
+		key         string
+		audience    string
+		leeway      time.Duration
+		maxValidity time.Duration
+		asJSON      bool
+	)
+
+	fs := newFlagSet("verify", out)
+	fs.StringVar(&key, "key", "", "Public key or certificate used to verify the token")
+	fs.StringVar(&audience, "audience", "", "Audience the token must be issued for")
+	fs.DurationVar(&leeway, "leeway", 0, "Allowed clock skew when checking token times")
+	fs.DurationVar(&maxValidity, "max-validity", 0, "Longest validity period to accept")
+	fs.BoolVar(&asJSON, "json", false, "Produce JSON output")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		return fmt.Errorf("a token file is required")
+	}
+	if key == "" {
+		return fmt.Errorf("a verification key is required")
+	}
+
+	token, err := readToken(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	pk, err := verifierFor(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	claims, err := tokens.NewClaimsForPurpose(tokens.TokenPurpose(token))
+	if err != nil {
+		return err
+	}
+
+	opts := []tokens.ParseOption{tokens.WithLeeway(leeway)}
+	if audience != "" {
+		opts = append(opts, tokens.WithExpectedAudience(audience))
+	}
+	if maxValidity > 0 {
+		opts = append(opts, tokens.WithMaxValidity(maxValidity))
+	}
+
+	report, err := tokens.ValidateTokenContext(ctx, token, claims, pk, opts...)
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		err = printJSON(out, report)
+		if err != nil {
+			return err
+		}
+	} else if report.Valid() {
+		fmt.Fprintf(out, "%s token is valid\n", report.Purpose)
+	} else {
+		fmt.Fprintf(out, "%s token is not valid:\n", report.Purpose)
+		for _, p := range report.Problems {
+			fmt.Fprintf(out, "  %s: %s\n", p.Reason, p.Message)
+		}
+	}
+
+	if !report.Valid() {
+		return errInvalid
+	}
+
+	return nil
+}
@@ -0,0 +1,355 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Codec serialises token claims in a format other than JSON, tokens using a codec identify it in the cty header.
+//
+// Codecs operate on the generic representation of the claims JSON so claim types only need JSON struct tags,
+// Unmarshal must return maps with string keys
+type Codec interface {
+	// ContentType is the value of the cty header identifying tokens using this codec
+	ContentType() string
+
+	// Marshal encodes claims
+	Marshal(claims map[string]any) ([]byte, error)
+
+	// Unmarshal decodes data produced by Marshal, maps holding the same key more than once must be rejected using ErrDuplicateClaimKey
+	Unmarshal(data []byte) (map[string]any, error)
+}
+
+var (
+	// ErrUnknownCodec indicates a token or option referenced a codec that is not registered
+	ErrUnknownCodec = errors.New("unknown codec")
+
+	// ErrDuplicateClaimKey indicates claims encoded using a codec hold the same key more than once
+	ErrDuplicateClaimKey = errors.New("duplicate claim key")
+)
+
+var (
+	codecs   = map[string]Codec{}
+	codecsMu sync.RWMutex
+)
+
+// RegisterCodec registers a codec that can be selected when signing tokens using WithCodec, tokens with a
+// cty header matching the codec content type are decoded using it
+func RegisterCodec(codec Codec) error {
+	if codec == nil {
+		return fmt.Errorf("codec is required")
+	}
+
+	cty := codec.ContentType()
+	if cty == "" || strings.EqualFold(cty, "JWT") {
+		return fmt.Errorf("invalid codec content type %q", cty)
+	}
+
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+
+	_, ok := codecs[cty]
+	if ok {
+		return fmt.Errorf("codec %s is already registered", cty)
+	}
+
+	codecs[cty] = codec
+
+	return nil
+}
+
+// RegisteredCodecs lists the content types of all registered codecs
+func RegisteredCodecs() []string {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+
+	var res []string
+	for cty := range codecs {
+		res = append(res, cty)
+	}
+
+	sort.Strings(res)
+
+	return res
+}
+
+func codecFor(cty string) (Codec, bool) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+
+	c, ok := codecs[cty]
+
+	return c, ok
+}
+
+type codecHeader struct {
+	Alg string `json:"alg"`
+	Cty string `json:"cty,omitempty"`
+	Zip string `json:"zip,omitempty"`
+}
+
+// tokenEncoding determines the codec used by token, nil for JSON tokens, and if its claims are compressed. Tokens
+// with a cty header that does not match a registered codec are rejected
+func tokenEncoding(token string) (codec Codec, compressed bool, err error) {
+	if plainJSONToken(token) {
+		return nil, false, nil
+	}
+
+	hdr, _, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, false, nil
+	}
+
+	hdrb, err := base64.RawURLEncoding.DecodeString(hdr)
+	if err != nil {
+		return nil, false, nil
+	}
+
+	h := codecHeader{}
+	err = json.Unmarshal(hdrb, &h)
+	if err != nil {
+		return nil, false, nil
+	}
+
+	if h.Cty != "" {
+		codec, ok = codecFor(h.Cty)
+		if !ok {
+			return nil, false, fmt.Errorf("%w %q", ErrUnknownCodec, h.Cty)
+		}
+	}
+
+	return codec, h.Zip == compressionDeflate, nil
+}
+
+// plainJSONToken determines without allocating that token has neither a cty nor a zip header, false when the header needs the regular decoder
+func plainJSONToken(token string) bool {
+	hdr, _, ok := tokenSegments(token)
+	if !ok {
+		return false
+	}
+
+	bp := scanBuffers.Get().(*[]byte)
+	defer scanBuffers.Put(bp)
+
+	buf, ok := b64URLDecode((*bp)[:0], hdr)
+	*bp = buf[:0]
+	if !ok {
+		return false
+	}
+
+	cty, zip, _, ok := jsonStringFields(buf, "cty", "zip", true)
+
+	return ok && cty == nil && zip == nil
+}
+
+// parseUnverified decodes token into claims without verifying it, tokens using a registered codec or compression are supported
+func parseUnverified(token string, claims jwt.Claims) (*jwt.Token, error) {
+	codec, compressed, err := tokenEncoding(token)
+	if err != nil {
+		return nil, err
+	}
+	if codec == nil && !compressed {
+		t, _, err := new(jwt.Parser).ParseUnverified(token, claims)
+		return t, err
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("token contains an invalid number of segments")
+	}
+
+	hdrb, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("could not decode token header: %w", err)
+	}
+
+	header := map[string]any{}
+	err = json.Unmarshal(hdrb, &header)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode token header: %w", err)
+	}
+
+	alg, _ := header["alg"].(string)
+	method := jwt.GetSigningMethod(alg)
+	if method == nil {
+		return nil, fmt.Errorf("signing method (alg) is unavailable")
+	}
+
+	err = decodeClaims(codec, compressed, parts[1], claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &jwt.Token{Raw: token, Method: method, Header: header, Claims: claims, Signature: parts[2]}, nil
+}
+
+// parseTokenWithCodec verifies a token encoded using a codec or compression and decodes it into claims
+func parseTokenWithCodec(token string, claims jwt.Claims, pk any, popts *parseOptions) (isRSA bool, err error) {
+	t, err := parseUnverified(token, claims)
+	if err != nil {
+		return false, &jwt.ValidationError{Inner: err, Errors: jwt.ValidationErrorMalformed}
+	}
+
+	alg := t.Method.Alg()
+
+	valid := false
+	for _, m := range validMethods {
+		if m == alg {
+			valid = true
+		}
+	}
+	if !valid {
+		return false, &jwt.ValidationError{Inner: fmt.Errorf("signing method %v is invalid", alg), Errors: jwt.ValidationErrorSignatureInvalid}
+	}
+
+	key, isRSA, err := popts.verificationKey(alg, claims, pk)
+	if err != nil {
+		return isRSA, &jwt.ValidationError{Inner: err, Errors: jwt.ValidationErrorUnverifiable}
+	}
+
+	err = t.Method.Verify(token[:strings.LastIndex(token, ".")], t.Signature, key)
+	if err != nil {
+		return isRSA, &jwt.ValidationError{Inner: err, Errors: jwt.ValidationErrorSignatureInvalid}
+	}
+
+	return isRSA, nil
+}
+
+// signWithCodec creates a token with claims encoded using codec, or JSON when nil, and optionally compressed
+func signWithCodec(claims jwt.Claims, codec Codec, compress bool, method jwt.SigningMethod, key any) (string, error) {
+	header := map[string]any{"alg": method.Alg(), "typ": "JWT"}
+	if codec != nil {
+		header["cty"] = codec.ContentType()
+	}
+	if compress {
+		header["zip"] = compressionDeflate
+	}
+
+	hdr, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+
+	dat, err := encodeClaims(codec, claims)
+	if err != nil {
+		return "", err
+	}
+
+	if compress {
+		dat, err = deflateClaims(dat)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	ss := base64.RawURLEncoding.EncodeToString(hdr) + "." + base64.RawURLEncoding.EncodeToString(dat)
+
+	sig, err := method.Sign(ss, key)
+	if err != nil {
+		return "", err
+	}
+
+	return ss + "." + sig, nil
+}
+
+func encodeClaims(codec Codec, claims jwt.Claims) ([]byte, error) {
+	j, err := json.Marshal(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	if codec == nil {
+		return j, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(j))
+	dec.UseNumber()
+
+	generic := map[string]any{}
+	err = dec.Decode(&generic)
+	if err != nil {
+		return nil, err
+	}
+
+	dat, err := codec.Marshal(normalizeJSONNumbers(generic).(map[string]any))
+	if err != nil {
+		return nil, fmt.Errorf("could not encode claims using %s: %w", codec.ContentType(), err)
+	}
+
+	return dat, nil
+}
+
+func decodeClaims(codec Codec, compressed bool, payload string, claims jwt.Claims) error {
+	dat, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return fmt.Errorf("could not decode token claims: %w", err)
+	}
+
+	if compressed {
+		dat, err = inflateClaims(dat)
+		if err != nil {
+			return err
+		}
+	}
+
+	if codec == nil {
+		return json.Unmarshal(dat, claims)
+	}
+
+	generic, err := codec.Unmarshal(dat)
+	if err != nil {
+		return fmt.Errorf("could not decode claims using %s: %w", codec.ContentType(), err)
+	}
+
+	j, err := json.Marshal(generic)
+	if err != nil {
+		return fmt.Errorf("could not decode claims using %s: %w", codec.ContentType(), err)
+	}
+
+	return json.Unmarshal(j, claims)
+}
+
+// normalizeJSONNumbers converts json.Number values to int64 or float64 so codecs encode them as numbers
+func normalizeJSONNumbers(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		for k, e := range val {
+			val[k] = normalizeJSONNumbers(e)
+		}
+		return val
+
+	case []any:
+		for i, e := range val {
+			val[i] = normalizeJSONNumbers(e)
+		}
+		return val
+
+	case json.Number:
+		i, err := val.Int64()
+		if err == nil {
+			return i
+		}
+
+		f, err := val.Float64()
+		if err == nil {
+			return f
+		}
+
+		return val.String()
+
+	default:
+		return v
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const ginkgoCodecContentType = "ginkgo+json"
+
+type ginkgoCodec struct{}
+
+func (c *ginkgoCodec) ContentType() string { return ginkgoCodecContentType }
+
+func (c *ginkgoCodec) Marshal(claims map[string]any) ([]byte, error) {
+	j, err := json.Marshal(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(base64.StdEncoding.EncodeToString(j)), nil
+}
+
+func (c *ginkgoCodec) Unmarshal(data []byte) (map[string]any, error) {
+	j, err := base64.StdEncoding.DecodeString(string(data))
+	if err != nil {
+		return nil, err
+	}
+
+	claims := map[string]any{}
+	err = json.Unmarshal(j, &claims)
+
+	return claims, err
+}
+
+var _ = Describe("Codecs", func() {
+	var (
+		pubK ed25519.PublicKey
+		priK ed25519.PrivateKey
+	)
+
+	BeforeEach(func() {
+		if _, ok := codecFor(ginkgoCodecContentType); !ok {
+			Expect(RegisterCodec(&ginkgoCodec{})).To(Succeed())
+		}
+
+		var err error
+		pubK, priK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Describe("RegisterCodec", func() {
+		It("Should validate codecs", func() {
+			Expect(RegisterCodec(nil)).To(MatchError("codec is required"))
+			Expect(RegisterCodec(&ginkgoCodec{})).To(MatchError("codec ginkgo+json is already registered"))
+			Expect(RegisteredCodecs()).To(ContainElement(ginkgoCodecContentType))
+		})
+	})
+
+	Describe("SignToken", func() {
+		It("Should require a known codec", func() {
+			claims, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = SignToken(claims, priK, WithCodec("unknown"))
+			Expect(err).To(MatchError(`unknown codec "unknown"`))
+		})
+
+		It("Should sign and parse tokens using the codec", func() {
+			claims, err := NewClientIDClaims("up=bob", []string{"rpcutil"}, "acme", map[string]string{"group": "admins"}, "", "ginkgo", time.Hour, &ClientPermissions{FleetManagement: true}, nil, WithCustomClaims(map[string]any{"count": 10, "ratio": 1.5}))
+			Expect(err).ToNot(HaveOccurred())
+
+			token, err := SignToken(claims, priK, WithCodec(ginkgoCodecContentType))
+			Expect(err).ToNot(HaveOccurred())
+
+			hdr, err := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[0])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(hdr)).To(ContainSubstring(`"cty":"ginkgo+json"`))
+
+			Expect(TokenPurpose(token)).To(Equal(ClientIDPurpose))
+			Expect(TokenSigningAlgorithm(token)).To(Equal("EdDSA"))
+
+			parsed, err := ParseClientIDToken(token, pubK, true)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(parsed.CallerID).To(Equal("up=bob"))
+			Expect(parsed.UserProperties).To(Equal(map[string]string{"group": "admins"}))
+			Expect(parsed.Permissions.FleetManagement).To(BeTrue())
+			Expect(parsed.ExpiresAt.Time.Equal(claims.ExpiresAt.Time)).To(BeTrue())
+			Expect(parsed.CustomClaims).To(Equal(map[string]any{"count": float64(10), "ratio": 1.5}))
+
+			compact, err := ParseClientIDToken(token, pubK, true, WithCompactVerification())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(compact).To(Equal(parsed))
+
+			unverified, err := ParseClientIDTokenUnverified(token)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(unverified.CallerID).To(Equal("up=bob"))
+
+			any, err := ParseAnyToken(token, pubK)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(any).To(BeAssignableToTypeOf(&ClientIDClaims{}))
+		})
+
+		It("Should detect tampering and wrong keys", func() {
+			claims, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			token, err := SignToken(claims, priK, WithCodec(ginkgoCodecContentType))
+			Expect(err).ToNot(HaveOccurred())
+
+			otherPubK, _, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = ParseClientIDToken(token, otherPubK, true)
+			Expect(err).To(MatchError(ContainSubstring("verification error")))
+			Expect(isSignatureError(err)).To(BeTrue())
+
+			claims.CallerID = "up=mallory"
+			forged, err := SignToken(claims, priK, WithCodec(ginkgoCodecContentType))
+			Expect(err).ToNot(HaveOccurred())
+
+			parts := strings.Split(token, ".")
+			tampered := strings.Join([]string{parts[0], strings.Split(forged, ".")[1], parts[2]}, ".")
+			_, err = ParseClientIDToken(tampered, pubK, true)
+			Expect(err).To(MatchError(ContainSubstring("verification error")))
+		})
+
+		It("Should support RSA keys", func() {
+			claims, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			token, err := SignToken(claims, loadRSAPriKey("testdata/rsa/signer-key.pem"), WithCodec(ginkgoCodecContentType))
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = ParseClientIDToken(token, loadRSAPubKey("testdata/rsa/signer-public.pem"), true)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = ParseClientIDToken(token, pubK, true)
+			Expect(err).To(MatchError(ContainSubstring("rsa public key required")))
+		})
+	})
+
+	Describe("tokenEncoding", func() {
+		sign := func(header string, compress bool) string {
+			claims, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, nil)
+			Expect(err).ToNot(HaveOccurred())
+			dat, err := json.Marshal(claims)
+			Expect(err).ToNot(HaveOccurred())
+			if compress {
+				dat, err = deflateClaims(dat)
+				Expect(err).ToNot(HaveOccurred())
+			}
+
+			ss := base64.RawURLEncoding.EncodeToString([]byte(header)) + "." + base64.RawURLEncoding.EncodeToString(dat)
+			sig, err := jwt.SigningMethodEdDSA.Sign(ss, priK)
+			Expect(err).ToNot(HaveOccurred())
+
+			return ss + "." + sig
+		}
+
+		It("Should detect plain JSON tokens without decoding the header", func() {
+			Expect(plainJSONToken(sign(`{"alg":"EdDSA","typ":"JWT"}`, false))).To(BeTrue())
+			Expect(plainJSONToken(sign(`{"alg":"EdDSA","typ":"JWT","zip":"DEF"}`, true))).To(BeFalse())
+			Expect(plainJSONToken(sign(`{"alg":"EdDSA","typ":"JWT","cty":"ginkgo+json"}`, false))).To(BeFalse())
+
+			codec, compressed, err := tokenEncoding(sign(`{"alg":"EdDSA","typ":"JWT"}`, false))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(codec).To(BeNil())
+			Expect(compressed).To(BeFalse())
+		})
+
+		It("Should reject tokens using unknown codecs", func() {
+			for _, token := range []string{sign(`{"alg":"EdDSA","typ":"JWT","cty":"unknown"}`, false), sign(`{"alg":"EdDSA","typ":"JWT","cty":"unknown","zip":"DEF"}`, true)} {
+				_, err := ParseClientIDToken(token, pubK, true)
+				Expect(err).To(MatchError(ErrUnknownCodec))
+
+				_, err = ParseClientIDTokenUnverified(token)
+				Expect(err).To(MatchError(ErrUnknownCodec))
+			}
+
+			_, err := ParseClientIDToken(sign(`{"alg":"EdDSA","typ":"JWT","zip":"DEF"}`, true), pubK, true)
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+})
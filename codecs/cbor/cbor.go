@@ -0,0 +1,80 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package cbor provides a CBOR codec for token claims, importing the package registers the codec
+package cbor
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/choria-io/tokens"
+	fxcbor "github.com/fxamacker/cbor/v2"
+)
+
+// ContentType is the cty header value of tokens with CBOR encoded claims
+const ContentType = "cbor"
+
+// Codec encodes claims using CBOR
+type Codec struct {
+	enc fxcbor.EncMode
+	dec fxcbor.DecMode
+}
+
+var _ tokens.Codec = (*Codec)(nil)
+
+func init() {
+	codec, err := New()
+	if err != nil {
+		panic(err)
+	}
+
+	err = tokens.RegisterCodec(codec)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// New creates a new CBOR codec
+func New() (*Codec, error) {
+	enc, err := fxcbor.CoreDetEncOptions().EncMode()
+	if err != nil {
+		return nil, err
+	}
+
+	dec, err := fxcbor.DecOptions{DefaultMapType: reflect.TypeOf(map[string]any(nil)), DupMapKey: fxcbor.DupMapKeyEnforcedAPF}.DecMode()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Codec{enc: enc, dec: dec}, nil
+}
+
+// ContentType is the cty header value identifying CBOR encoded tokens
+func (c *Codec) ContentType() string {
+	return ContentType
+}
+
+// Marshal encodes claims using deterministic CBOR encoding
+func (c *Codec) Marshal(claims map[string]any) ([]byte, error) {
+	return c.enc.Marshal(claims)
+}
+
+// Unmarshal decodes CBOR encoded claims, maps holding duplicate keys are rejected
+func (c *Codec) Unmarshal(data []byte) (map[string]any, error) {
+	claims := map[string]any{}
+
+	err := c.dec.Unmarshal(data, &claims)
+	if err != nil {
+		var dupErr *fxcbor.DupMapKeyError
+		if errors.As(err, &dupErr) {
+			return nil, fmt.Errorf("%w %v", tokens.ErrDuplicateClaimKey, dupErr.Key)
+		}
+
+		return nil, err
+	}
+
+	return claims, nil
+}
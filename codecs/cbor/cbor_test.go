@@ -0,0 +1,78 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cbor
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/choria-io/tokens"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestCBOR(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Codecs/CBOR")
+}
+
+var _ = Describe("Codec", func() {
+	It("Should be registered", func() {
+		Expect(tokens.RegisteredCodecs()).To(ContainElement(ContentType))
+	})
+
+	It("Should round trip nested claims", func() {
+		c, err := New()
+		Expect(err).ToNot(HaveOccurred())
+
+		dat, err := c.Marshal(map[string]any{"a": int64(1), "b": map[string]any{"c": []any{"d", 1.5}}})
+		Expect(err).ToNot(HaveOccurred())
+
+		claims, err := c.Unmarshal(dat)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(claims["b"]).To(BeAssignableToTypeOf(map[string]any{}))
+		Expect(claims["b"].(map[string]any)["c"]).To(HaveLen(2))
+	})
+
+	It("Should reject duplicate keys", func() {
+		c, err := New()
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = c.Unmarshal([]byte{0xa2, 0x61, 'a', 0x01, 0x61, 'a', 0x02})
+		Expect(err).To(MatchError(tokens.ErrDuplicateClaimKey))
+
+		_, err = c.Unmarshal([]byte{0xa1, 0x61, 'a', 0xa2, 0x61, 'b', 0x01, 0x61, 'b', 0x02})
+		Expect(err).To(MatchError(tokens.ErrDuplicateClaimKey))
+	})
+
+	It("Should sign and verify tokens", func() {
+		pubK, priK, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		perms := &tokens.ClientPermissions{FleetManagement: true}
+		claims, err := tokens.NewClientIDClaims("up=bob", []string{"rpcutil"}, "acme", map[string]string{"group": "admins"}, "", "ginkgo", time.Hour, perms, nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		token, err := tokens.SignToken(claims, priK, tokens.WithCodec(ContentType))
+		Expect(err).ToNot(HaveOccurred())
+
+		json, err := tokens.SignToken(claims, priK)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(len(token)).To(BeNumerically("<", len(json)))
+
+		Expect(tokens.TokenPurpose(token)).To(Equal(tokens.ClientIDPurpose))
+
+		parsed, err := tokens.ParseClientIDToken(token, pubK, true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(parsed.CallerID).To(Equal("up=bob"))
+		Expect(parsed.AllowedAgents).To(Equal([]string{"rpcutil"}))
+		Expect(parsed.UserProperties).To(Equal(map[string]string{"group": "admins"}))
+		Expect(parsed.Permissions).To(Equal(perms))
+		Expect(parsed.ID).To(Equal(claims.ID))
+		Expect(parsed.ExpiresAt.Time.Equal(claims.ExpiresAt.Time)).To(BeTrue())
+	})
+})
@@ -0,0 +1,103 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package msgpack provides a MessagePack codec for token claims, importing the package registers the codec
+package msgpack
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/choria-io/tokens"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// ContentType is the cty header value of tokens with MessagePack encoded claims
+const ContentType = "msgpack"
+
+// Codec encodes claims using MessagePack
+type Codec struct{}
+
+var _ tokens.Codec = (*Codec)(nil)
+
+func init() {
+	err := tokens.RegisterCodec(New())
+	if err != nil {
+		panic(err)
+	}
+}
+
+// New creates a new MessagePack codec
+func New() *Codec {
+	return &Codec{}
+}
+
+// ContentType is the cty header value identifying MessagePack encoded tokens
+func (c *Codec) ContentType() string {
+	return ContentType
+}
+
+// Marshal encodes claims using MessagePack with map keys sorted
+func (c *Codec) Marshal(claims map[string]any) ([]byte, error) {
+	var buf bytes.Buffer
+
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetSortMapKeys(true)
+
+	err := enc.Encode(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes MessagePack encoded claims, nested maps decode with string keys and maps holding duplicate keys are rejected
+func (c *Codec) Unmarshal(data []byte) (map[string]any, error) {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetMapDecoder(decodeMap)
+
+	v, err := dec.DecodeInterface()
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("claims must be a map, got %T", v)
+	}
+
+	return claims, nil
+}
+
+// decodeMap decodes a map with string keys rejecting keys seen more than once
+func decodeMap(dec *msgpack.Decoder) (any, error) {
+	n, err := dec.DecodeMapLen()
+	if err != nil {
+		return nil, err
+	}
+	if n == -1 {
+		return nil, nil
+	}
+
+	m := make(map[string]any, n)
+	for i := 0; i < n; i++ {
+		k, err := dec.DecodeString()
+		if err != nil {
+			return nil, err
+		}
+
+		_, ok := m[k]
+		if ok {
+			return nil, fmt.Errorf("%w %q", tokens.ErrDuplicateClaimKey, k)
+		}
+
+		m[k], err = dec.DecodeInterface()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
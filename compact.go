@@ -0,0 +1,91 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var (
+	// errCompactUnsupported indicates a token that the compact verifier does not handle
+	errCompactUnsupported = errors.New("token not supported by the compact verifier")
+
+	// errCompactVerification indicates the signature did not match, it matches the error from the jwt parser
+	errCompactVerification = errors.New("ed25519: verification error")
+)
+
+type compactHeader struct {
+	Alg string `json:"alg"`
+	Cty string `json:"cty,omitempty"`
+	Zip string `json:"zip,omitempty"`
+}
+
+// parseTokenCompact verifies a ed25519 signed token and decodes it into claims without using the jwt parser,
+// only the signature is checked, callers must validate the claims. Chain issuers are verified as at now
+func parseTokenCompact(ctx context.Context, token string, claims jwt.Claims, pk ed25519.PublicKey, now time.Time) error {
+	if len(pk) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid ed25519 public key size")
+	}
+
+	hdr, rest, ok := strings.Cut(token, ".")
+	if !ok {
+		return fmt.Errorf("token contains an invalid number of segments")
+	}
+
+	payload, sig, ok := strings.Cut(rest, ".")
+	if !ok || strings.Contains(sig, ".") {
+		return fmt.Errorf("token contains an invalid number of segments")
+	}
+
+	hdrb, err := base64.RawURLEncoding.DecodeString(hdr)
+	if err != nil {
+		return fmt.Errorf("could not decode token header: %w", err)
+	}
+
+	h := compactHeader{}
+	err = json.Unmarshal(hdrb, &h)
+	if err != nil {
+		return fmt.Errorf("could not decode token header: %w", err)
+	}
+
+	if h.Alg != algEdDSA || h.Cty != "" || h.Zip != "" {
+		return errCompactUnsupported
+	}
+
+	payloadb, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return fmt.Errorf("could not decode token claims: %w", err)
+	}
+
+	err = json.Unmarshal(payloadb, claims)
+	if err != nil {
+		return fmt.Errorf("could not decode token claims: %w", err)
+	}
+
+	sigb, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || len(sigb) != ed25519.SignatureSize {
+		return errCompactVerification
+	}
+
+	pk, err = chainSigningKey(ctx, claims, pk, now)
+	if err != nil {
+		return err
+	}
+
+	if !ed25519.Verify(pk, []byte(token[:len(hdr)+1+len(payload)]), sigb) {
+		return errCompactVerification
+	}
+
+	return nil
+}
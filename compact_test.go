@@ -0,0 +1,160 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Compact Verification", func() {
+	var (
+		pubK ed25519.PublicKey
+		priK ed25519.PrivateKey
+	)
+
+	BeforeEach(func() {
+		var err error
+		pubK, priK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Should parse tokens identically to the jwt parser", func() {
+		claims, err := NewClientIDClaims("up=bob", []string{"rpcutil"}, "acme", map[string]string{"group": "admins"}, "", "ginkgo", time.Hour, &ClientPermissions{FleetManagement: true}, nil, WithAudience("choria_broker"))
+		Expect(err).ToNot(HaveOccurred())
+		token, err := SignToken(claims, priK)
+		Expect(err).ToNot(HaveOccurred())
+
+		standard, err := ParseClientIDToken(token, pubK, true)
+		Expect(err).ToNot(HaveOccurred())
+
+		compact, err := ParseClientIDToken(token, pubK, true, WithCompactVerification(), WithExpectedAudience("choria_broker"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(compact).To(Equal(standard))
+	})
+
+	It("Should detect invalid signatures and tampering", func() {
+		claims, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		token, err := SignToken(claims, priK)
+		Expect(err).ToNot(HaveOccurred())
+
+		otherPubK, _, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		err = ParseToken(token, &ClientIDClaims{}, otherPubK, WithCompactVerification())
+		Expect(err).To(MatchError("ed25519: verification error"))
+		Expect(isSignatureError(err)).To(BeTrue())
+
+		parts := strings.Split(token, ".")
+		claims.CallerID = "up=mallory"
+		forged, err := SignToken(claims, priK)
+		Expect(err).ToNot(HaveOccurred())
+		tampered := strings.Join([]string{parts[0], strings.Split(forged, ".")[1], parts[2]}, ".")
+		err = ParseToken(tampered, &ClientIDClaims{}, pubK, WithCompactVerification())
+		Expect(err).To(MatchError("ed25519: verification error"))
+
+		err = ParseToken(parts[0]+"."+parts[1], &ClientIDClaims{}, pubK, WithCompactVerification())
+		Expect(err).To(MatchError("token contains an invalid number of segments"))
+
+		err = ParseToken(token, &ClientIDClaims{}, ed25519.PublicKey{1, 2, 3}, WithCompactVerification())
+		Expect(err).To(MatchError("invalid ed25519 public key size"))
+	})
+
+	It("Should validate times and claims", func() {
+		claims, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+		token, err := SignToken(claims, priK)
+		Expect(err).ToNot(HaveOccurred())
+
+		err = ParseToken(token, &ClientIDClaims{}, pubK, WithCompactVerification())
+		Expect(err).To(MatchError(jwt.ErrTokenExpired))
+
+		claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Hour))
+		claims.CallerID = ""
+		token, err = SignToken(claims, priK)
+		Expect(err).ToNot(HaveOccurred())
+
+		err = ParseToken(token, &ClientIDClaims{}, pubK, WithCompactVerification())
+		Expect(err).To(MatchError("caller id is required"))
+	})
+
+	It("Should verify chain issued tokens", func() {
+		handlerPubK, handlerPriK, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		handler, err := NewClientIDClaims("handler", nil, "", nil, "", "", time.Hour, nil, handlerPubK)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(handler.AddOrgIssuerData(priK)).To(Succeed())
+
+		userPubK, _, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		user, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, userPubK)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(user.AddChainIssuerData(handler, handlerPriK)).To(Succeed())
+
+		token, err := SignToken(user, handlerPriK)
+		Expect(err).ToNot(HaveOccurred())
+
+		parsed, err := ParseClientIDToken(token, pubK, true, WithCompactVerification())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(parsed.CallerID).To(Equal("up=bob"))
+	})
+
+	It("Should use the jwt parser for other tokens", func() {
+		token, err := os.ReadFile("testdata/rsa/good-provisioning.jwt")
+		Expect(err).ToNot(HaveOccurred())
+
+		err = ParseToken(string(token), &ProvisioningClaims{}, loadRSAPubKey("testdata/rsa/signer-public.pem"), WithCompactVerification())
+		Expect(err).ToNot(HaveOccurred())
+
+		err = ParseToken(string(token), &ProvisioningClaims{}, pubK, WithCompactVerification())
+		Expect(err).To(MatchError("rsa public key required"))
+	})
+})
+
+func benchmarkParseClientToken(b *testing.B, opts ...ParseOption) {
+	pubK, priK, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	claims, err := NewClientIDClaims("up=bob", []string{"rpcutil"}, "", nil, "", "", time.Hour, nil, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	token, err := SignToken(claims, priK)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		err = ParseToken(token, &ClientIDClaims{}, pubK, opts...)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseToken(b *testing.B) {
+	benchmarkParseClientToken(b)
+}
+
+func BenchmarkParseTokenCompact(b *testing.B) {
+	benchmarkParseClientToken(b, WithCompactVerification())
+}
@@ -0,0 +1,341 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// ErrIncompatibleReplacement indicates new claims cannot replace a token at all, for example when the purpose differs
+var ErrIncompatibleReplacement = errors.New("incompatible token replacement")
+
+// CompatibilityChange is a single difference between a token and the claims replacing it
+type CompatibilityChange struct {
+	// Field is the claim that changed, nested fields are dot separated
+	Field string `json:"field"`
+
+	// Old is the value being removed or replaced, empty when a value was added
+	Old string `json:"old,omitempty"`
+
+	// New is the value being added or replacing the old one, empty when a value was removed
+	New string `json:"new,omitempty"`
+
+	// Reduction indicates the change reduces the access of the token holder
+	Reduction bool `json:"reduction"`
+}
+
+func (c *CompatibilityChange) String() string {
+	switch {
+	case c.New == "":
+		return fmt.Sprintf("%s: removed %q", c.Field, c.Old)
+	case c.Old == "":
+		return fmt.Sprintf("%s: added %q", c.Field, c.New)
+	default:
+		return fmt.Sprintf("%s: changed from %q to %q", c.Field, c.Old, c.New)
+	}
+}
+
+// CompatibilityReport describes how replacing a token with new claims would change the access of the holder
+type CompatibilityReport struct {
+	// Purpose is the purpose of the token
+	Purpose Purpose `json:"purpose"`
+
+	// Changes are all the differences found, in the order they were checked
+	Changes []*CompatibilityChange `json:"changes,omitempty"`
+}
+
+// IsDowngrade determines if any change reduces access and so should be explicitly confirmed
+func (r *CompatibilityReport) IsDowngrade() bool {
+	return len(r.Reductions()) > 0
+}
+
+// Reductions are the changes that reduce access
+func (r *CompatibilityReport) Reductions() []*CompatibilityChange {
+	var res []*CompatibilityChange
+	for _, c := range r.Changes {
+		if c.Reduction {
+			res = append(res, c)
+		}
+	}
+
+	return res
+}
+
+func (r *CompatibilityReport) changed(field string, old string, new string, reduction bool) {
+	if old == new {
+		return
+	}
+
+	r.Changes = append(r.Changes, &CompatibilityChange{Field: field, Old: old, New: new, Reduction: reduction})
+}
+
+// compareList records removed entries as reductions and added entries as changes
+func (r *CompatibilityReport) compareList(field string, old []string, new []string) {
+	r.compareEntries(field, old, new, func(string) bool { return false })
+}
+
+// compareAgents compares agent lists, a wildcard in the new list retains access to all agents
+func (r *CompatibilityReport) compareAgents(old []string, new []string) {
+	wildcard := false
+	for _, v := range new {
+		if v == "*" {
+			wildcard = true
+		}
+	}
+
+	r.compareEntries("agents", old, new, func(string) bool { return wildcard })
+}
+
+// compareSubjects compares subject lists, removed subjects covered by a subject in the new list retain access
+func (r *CompatibilityReport) compareSubjects(field string, old []string, new []string) {
+	r.compareEntries(field, old, new, func(subj string) bool { return uncoveredSubject(new, []string{subj}) == "" })
+}
+
+// compareEntries records removed entries as reductions unless retained reports that access is kept, added entries are recorded as changes
+func (r *CompatibilityReport) compareEntries(field string, old []string, new []string, retained func(string) bool) {
+	oldSet := make(map[string]struct{}, len(old))
+	newSet := make(map[string]struct{}, len(new))
+	for _, v := range old {
+		oldSet[v] = struct{}{}
+	}
+	for _, v := range new {
+		newSet[v] = struct{}{}
+	}
+
+	for _, v := range sortedUnique(old) {
+		if _, ok := newSet[v]; !ok {
+			r.changed(field, v, "", !retained(v))
+		}
+	}
+
+	for _, v := range sortedUnique(new) {
+		if _, ok := oldSet[v]; !ok {
+			r.changed(field, "", v, false)
+		}
+	}
+}
+
+// accessString is the JSON form of access restrictions, empty when there are none
+func accessString(access *AccessRestrictions) string {
+	if access == nil {
+		return ""
+	}
+
+	j, err := json.Marshal(access)
+	if err != nil {
+		return fmt.Sprintf("%v", *access)
+	}
+
+	return string(j)
+}
+
+// compareACL compares action grants, adding an ACL to a token that relied on AllowedAgents restricts it
+func (r *CompatibilityReport) compareACL(old []ActionGrant, new []ActionGrant) {
+	if len(old) == 0 && len(new) > 0 {
+		for _, v := range sortedUnique(actionGrantEntries(new)) {
+			r.changed("acl", "", v, true)
+		}
+
+		return
+	}
+
+	r.compareList("acl", actionGrantEntries(old), actionGrantEntries(new))
+}
+
+// comparePermissions compares structs made up of boolean permission flags
+func (r *CompatibilityReport) comparePermissions(field string, old any, new any) {
+	ov := reflect.Indirect(reflect.ValueOf(old))
+	nv := reflect.Indirect(reflect.ValueOf(new))
+
+	t := reflect.TypeOf(old).Elem()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Type.Kind() != reflect.Bool {
+			continue
+		}
+
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "" {
+			name = f.Name
+		}
+
+		had := ov.IsValid() && ov.Field(i).Bool()
+		has := nv.IsValid() && nv.Field(i).Bool()
+
+		switch {
+		case had && !has:
+			r.changed(field, name, "", true)
+		case has && !had:
+			r.changed(field, "", name, false)
+		}
+	}
+}
+
+// CompatibilityCheck reports how replacing oldToken with newClaims would change the access of the holder, so that automated
+// renewal pipelines can require confirmation for downgrades. The old token is not verified, only client and server tokens are supported
+func CompatibilityCheck(oldToken string, newClaims jwt.Claims) (*CompatibilityReport, error) {
+	purpose := TokenPurpose(oldToken)
+
+	switch purpose {
+	case ClientIDPurpose:
+		nc, ok := newClaims.(*ClientIDClaims)
+		if !ok {
+			return nil, fmt.Errorf("%w: new claims are not client claims", ErrIncompatibleReplacement)
+		}
+
+		oc := &ClientIDClaims{}
+		_, err := parseUnverified(oldToken, oc)
+		if err != nil {
+			return nil, err
+		}
+
+		return compareClientClaims(oc, nc), nil
+
+	case ServerPurpose:
+		nc, ok := newClaims.(*ServerClaims)
+		if !ok {
+			return nil, fmt.Errorf("%w: new claims are not server claims", ErrIncompatibleReplacement)
+		}
+
+		oc := &ServerClaims{}
+		_, err := parseUnverified(oldToken, oc)
+		if err != nil {
+			return nil, err
+		}
+
+		return compareServerClaims(oc, nc), nil
+
+	default:
+		return nil, fmt.Errorf("%w: compatibility checks are not supported for %q tokens", ErrIncompatibleReplacement, purpose)
+	}
+}
+
+func compareClientClaims(oc *ClientIDClaims, nc *ClientIDClaims) *CompatibilityReport {
+	r := &CompatibilityReport{Purpose: ClientIDPurpose}
+
+	r.changed("callerid", oc.CallerID, nc.CallerID, true)
+	r.changed("ou", oc.OrganizationUnit, nc.OrganizationUnit, true)
+	r.compareAgents(oc.AllowedAgents, nc.AllowedAgents)
+	r.compareACL(oc.ACL, nc.ACL)
+	r.changed("opa_policy", oc.OPAPolicy, nc.OPAPolicy, nc.OPAPolicy != "")
+	r.changed("cel_policy", oc.CELPolicy, nc.CELPolicy, nc.CELPolicy != "")
+	r.comparePermissions("permissions", oc.Permissions, nc.Permissions)
+	r.compareSubjects("pub_subjects", oc.AdditionalPublishSubjects, nc.AdditionalPublishSubjects)
+	r.compareSubjects("sub_subjects", oc.AdditionalSubscribeSubjects, nc.AdditionalSubscribeSubjects)
+
+	var oscout, nscout ScoutPermissions
+	if oc.Scout != nil {
+		oscout = *oc.Scout
+	}
+	if nc.Scout != nil {
+		nscout = *nc.Scout
+	}
+	r.compareList("scout.view", oscout.View, nscout.View)
+	r.compareList("scout.trigger", oscout.Trigger, nscout.Trigger)
+	r.compareList("scout.maintenance", oscout.Maintenance, nscout.Maintenance)
+
+	r.compareList("machines", machineGrantEntries(oc.Machines), machineGrantEntries(nc.Machines))
+	r.compareLimits(oc.Limits, nc.Limits)
+	r.changed("access", accessString(oc.Access), accessString(nc.Access), nc.Access != nil)
+
+	keys := map[string]struct{}{}
+	for k := range oc.UserProperties {
+		keys[k] = struct{}{}
+	}
+	for k := range nc.UserProperties {
+		keys[k] = struct{}{}
+	}
+	for _, k := range sortedKeys(keys) {
+		r.changed("user_properties."+k, oc.UserProperties[k], nc.UserProperties[k], false)
+	}
+
+	return r
+}
+
+func compareServerClaims(oc *ServerClaims, nc *ServerClaims) *CompatibilityReport {
+	r := &CompatibilityReport{Purpose: ServerPurpose}
+
+	r.changed("identity", oc.ChoriaIdentity, nc.ChoriaIdentity, true)
+	r.changed("ou", oc.OrganizationUnit, nc.OrganizationUnit, true)
+	r.compareList("collectives", oc.Collectives, nc.Collectives)
+	r.comparePermissions("permissions", oc.Permissions, nc.Permissions)
+	r.compareSubjects("pub_subjects", oc.AdditionalPublishSubjects, nc.AdditionalPublishSubjects)
+	r.compareSubjects("sub_subjects", oc.AdditionalSubscribeSubjects, nc.AdditionalSubscribeSubjects)
+	r.compareLimits(oc.Limits, nc.Limits)
+
+	return r
+}
+
+// machineGrantEntries flattens grants into action:machine@node entries, empty machine and node lists match all
+func machineGrantEntries(grants []MachineGrant) []string {
+	var res []string
+
+	for _, g := range grants {
+		machines := g.Machines
+		if len(machines) == 0 {
+			machines = []string{"*"}
+		}
+		nodes := g.Nodes
+		if len(nodes) == 0 {
+			nodes = []string{"*"}
+		}
+
+		for _, a := range g.Actions {
+			for _, m := range machines {
+				for _, n := range nodes {
+					res = append(res, fmt.Sprintf("%s:%s@%s", a, m, n))
+				}
+			}
+		}
+	}
+
+	return res
+}
+
+func sortedUnique(list []string) []string {
+	set := make(map[string]struct{}, len(list))
+	for _, v := range list {
+		set[v] = struct{}{}
+	}
+
+	return sortedKeys(set)
+}
+
+func sortedKeys(set map[string]struct{}) []string {
+	res := make([]string, 0, len(set))
+	for k := range set {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+
+	return res
+}
+
+// compareLimits records changes to resource limits, new or lowered limits are reductions
+func (r *CompatibilityReport) compareLimits(old *ResourceLimits, new *ResourceLimits) {
+	var o, n ResourceLimits
+	if old != nil {
+		o = *old
+	}
+	if new != nil {
+		n = *new
+	}
+
+	compare := func(field string, ov int64, nv int64) {
+		r.changed("limits."+field, strconv.FormatInt(ov, 10), strconv.FormatInt(nv, 10), nv != 0 && (ov == 0 || nv < ov))
+	}
+
+	compare("max_payload", o.MaxPayload, n.MaxPayload)
+	compare("max_connections", o.MaxConnections, n.MaxConnections)
+	compare("max_subscriptions", o.MaxSubscriptions, n.MaxSubscriptions)
+}
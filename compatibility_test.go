@@ -0,0 +1,199 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CompatibilityCheck", func() {
+	var (
+		pubK ed25519.PublicKey
+		priK ed25519.PrivateKey
+	)
+
+	BeforeEach(func() {
+		var err error
+		pubK, priK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	sign := func(claims any) string {
+		var token string
+		var err error
+
+		switch c := claims.(type) {
+		case *ClientIDClaims:
+			token, err = SignToken(c, priK)
+		case *ServerClaims:
+			token, err = SignToken(c, priK)
+		}
+		Expect(err).ToNot(HaveOccurred())
+
+		return token
+	}
+
+	Describe("Client tokens", func() {
+		var old *ClientIDClaims
+
+		BeforeEach(func() {
+			var err error
+			old, err = NewClientIDClaims("up=bob", []string{"rpcutil", "puppet"}, "choria", map[string]string{"group": "admins"}, "", "", time.Hour, &ClientPermissions{FleetManagement: true, StreamsUser: true}, pubK)
+			Expect(err).ToNot(HaveOccurred())
+			old.Scout = &ScoutPermissions{View: []string{"*"}, Trigger: []string{"disk"}}
+			old.Machines = []MachineGrant{{Actions: []MachineAction{MachineViewAction, MachineStopAction}}}
+		})
+
+		newClaims := func() *ClientIDClaims {
+			nc, err := NewClientIDClaims("up=bob", []string{"rpcutil", "puppet"}, "choria", map[string]string{"group": "admins"}, "", "", time.Hour, &ClientPermissions{FleetManagement: true, StreamsUser: true}, pubK)
+			Expect(err).ToNot(HaveOccurred())
+			nc.Scout = &ScoutPermissions{View: []string{"*"}, Trigger: []string{"disk"}}
+			nc.Machines = []MachineGrant{{Actions: []MachineAction{MachineViewAction, MachineStopAction}}}
+
+			return nc
+		}
+
+		It("Should report no changes for equivalent claims", func() {
+			report, err := CompatibilityCheck(sign(old), newClaims())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(report.Purpose).To(Equal(ClientIDPurpose))
+			Expect(report.Changes).To(BeEmpty())
+			Expect(report.IsDowngrade()).To(BeFalse())
+		})
+
+		It("Should treat additions as upgrades", func() {
+			nc := newClaims()
+			nc.AllowedAgents = append(nc.AllowedAgents, "package")
+			nc.Permissions.EventsViewer = true
+			nc.AdditionalSubscribeSubjects = []string{"x.>"}
+
+			report, err := CompatibilityCheck(sign(old), nc)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(report.IsDowngrade()).To(BeFalse())
+			Expect(report.Changes).To(HaveLen(3))
+			Expect(report.Changes[0].String()).To(Equal(`agents: added "package"`))
+			Expect(report.Changes[1].String()).To(Equal(`permissions: added "events_viewer"`))
+			Expect(report.Changes[2].String()).To(Equal(`sub_subjects: added "x.>"`))
+		})
+
+		It("Should detect reductions", func() {
+			nc := newClaims()
+			nc.AllowedAgents = []string{"rpcutil"}
+			nc.Permissions = nil
+			nc.OPAPolicy = "package io\ndefault allow = false"
+			nc.CELPolicy = "false"
+			nc.Scout.Trigger = nil
+			nc.Machines = []MachineGrant{{Actions: []MachineAction{MachineViewAction}}}
+			nc.CallerID = "up=alice"
+
+			report, err := CompatibilityCheck(sign(old), nc)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(report.IsDowngrade()).To(BeTrue())
+
+			var reductions []string
+			for _, c := range report.Reductions() {
+				reductions = append(reductions, c.String())
+			}
+			Expect(reductions).To(Equal([]string{
+				`callerid: changed from "up=bob" to "up=alice"`,
+				`agents: removed "puppet"`,
+				`opa_policy: added "package io\ndefault allow = false"`,
+				`cel_policy: added "false"`,
+				`permissions: removed "streams_user"`,
+				`permissions: removed "fleet_management"`,
+				`scout.trigger: removed "disk"`,
+				`machines: removed "stop:*@*"`,
+			}))
+		})
+
+		It("Should consider a new wildcard as retaining access", func() {
+			nc := newClaims()
+			nc.AllowedAgents = []string{"*"}
+
+			report, err := CompatibilityCheck(sign(old), nc)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(report.IsDowngrade()).To(BeFalse())
+			Expect(report.Changes).To(HaveLen(3))
+		})
+
+		It("Should only treat wildcards as retaining access for agents", func() {
+			nc := newClaims()
+			nc.Scout.Trigger = []string{"*"}
+
+			report, err := CompatibilityCheck(sign(old), nc)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(report.IsDowngrade()).To(BeTrue())
+			Expect(report.Reductions()[0].String()).To(Equal(`scout.trigger: removed "disk"`))
+		})
+
+		It("Should consider covering subjects as retaining access", func() {
+			old.AdditionalSubscribeSubjects = []string{"x.y.z", "other.a"}
+			nc := newClaims()
+			nc.AdditionalSubscribeSubjects = []string{"x.>", "*"}
+
+			report, err := CompatibilityCheck(sign(old), nc)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(report.Reductions()).To(HaveLen(1))
+			Expect(report.Reductions()[0].String()).To(Equal(`sub_subjects: removed "other.a"`))
+
+			nc.AdditionalSubscribeSubjects = []string{"x.y.z", "other.*"}
+			report, err = CompatibilityCheck(sign(old), nc)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(report.IsDowngrade()).To(BeFalse())
+		})
+
+		It("Should report property changes without flagging them", func() {
+			nc := newClaims()
+			nc.UserProperties = map[string]string{"group": "users"}
+
+			report, err := CompatibilityCheck(sign(old), nc)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(report.IsDowngrade()).To(BeFalse())
+			Expect(report.Changes).To(HaveLen(1))
+			Expect(report.Changes[0].Field).To(Equal("user_properties.group"))
+		})
+	})
+
+	Describe("Server tokens", func() {
+		It("Should detect lost collectives and permissions", func() {
+			old, err := NewServerClaims("example.net", []string{"c1", "c2"}, "choria", &ServerPermissions{Submission: true, Streams: true}, nil, pubK, "", time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+
+			nc, err := NewServerClaims("example.net", []string{"c1", "c3"}, "choria", &ServerPermissions{Submission: true}, nil, pubK, "", time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+
+			report, err := CompatibilityCheck(sign(old), nc)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(report.Purpose).To(Equal(ServerPurpose))
+			Expect(report.IsDowngrade()).To(BeTrue())
+			Expect(report.Reductions()).To(HaveLen(2))
+			Expect(report.Reductions()[0].String()).To(Equal(`collectives: removed "c2"`))
+			Expect(report.Reductions()[1].String()).To(Equal(`permissions: removed "streams"`))
+			Expect(report.Changes).To(HaveLen(3))
+		})
+	})
+
+	It("Should reject mismatched or unsupported purposes", func() {
+		client, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		server, err := NewServerClaims("example.net", []string{"c1"}, "choria", nil, nil, pubK, "", time.Hour)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = CompatibilityCheck(sign(client), server)
+		Expect(err).To(MatchError(ErrIncompatibleReplacement))
+		Expect(err).To(MatchError("incompatible token replacement: new claims are not client claims"))
+
+		_, err = CompatibilityCheck(sign(server), client)
+		Expect(err).To(MatchError("incompatible token replacement: new claims are not server claims"))
+
+		_, err = CompatibilityCheck("invalid", client)
+		Expect(err).To(MatchError(`incompatible token replacement: compatibility checks are not supported for "" tokens`))
+	})
+})
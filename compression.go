@@ -0,0 +1,70 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"bytes"
+	"compress/flate"
+	"fmt"
+	"io"
+)
+
+// compressionDeflate is the zip header value of tokens with deflate compressed claims
+const compressionDeflate = "DEF"
+
+// maxInflatedClaimsSize limits the size of decompressed claims to guard against compression bombs
+const maxInflatedClaimsSize = 1024 * 1024
+
+// WithCompression deflate compresses the claims, reducing the size of tokens carrying large policies, the zip header
+// identifies compressed tokens and they are decompressed transparently when parsed
+func WithCompression() SignOption {
+	return func(o *signOptions) error {
+		o.compress = true
+		return nil
+	}
+}
+
+// IsCompressedToken determines if the claims in token are compressed
+func IsCompressedToken(token string) bool {
+	_, compressed, _ := tokenEncoding(token)
+	return compressed
+}
+
+func deflateClaims(dat []byte) ([]byte, error) {
+	buf := &bytes.Buffer{}
+
+	w, err := flate.NewWriter(buf, flate.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = w.Write(dat)
+	if err != nil {
+		return nil, fmt.Errorf("could not compress claims: %w", err)
+	}
+
+	err = w.Close()
+	if err != nil {
+		return nil, fmt.Errorf("could not compress claims: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func inflateClaims(dat []byte) ([]byte, error) {
+	r := flate.NewReader(bytes.NewReader(dat))
+	defer r.Close()
+
+	res, err := io.ReadAll(io.LimitReader(r, maxInflatedClaimsSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("could not decompress claims: %w", err)
+	}
+
+	if len(res) > maxInflatedClaimsSize {
+		return nil, fmt.Errorf("could not decompress claims: exceeds %d bytes", maxInflatedClaimsSize)
+	}
+
+	return res, nil
+}
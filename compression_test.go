@@ -0,0 +1,85 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Compression", func() {
+	var (
+		pubK   ed25519.PublicKey
+		priK   ed25519.PrivateKey
+		client *ClientIDClaims
+	)
+
+	BeforeEach(func() {
+		var err error
+		pubK, priK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		policy := strings.Repeat("allow { input.agent == \"rpcutil\" }\n", 200)
+		client, err = NewClientIDClaims("up=bob", []string{"rpcutil"}, "", nil, policy, "", time.Hour, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Should compress and transparently decompress claims", func() {
+		plain, err := SignToken(client, priK)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(IsCompressedToken(plain)).To(BeFalse())
+
+		compressed, err := SignToken(client, priK, WithCompression())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(IsCompressedToken(compressed)).To(BeTrue())
+		Expect(len(compressed)).To(BeNumerically("<", len(plain)/4))
+
+		parsed, err := ParseClientIDToken(compressed, pubK, true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(parsed.OPAPolicy).To(Equal(client.OPAPolicy))
+
+		Expect(TokenPurpose(compressed)).To(Equal(ClientIDPurpose))
+
+		parsed, err = ParseClientIDToken(compressed, pubK, true, WithCompactVerification())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(parsed.CallerID).To(Equal("up=bob"))
+	})
+
+	It("Should combine with codecs", func() {
+		if _, ok := codecFor(ginkgoCodecContentType); !ok {
+			Expect(RegisterCodec(&ginkgoCodec{})).To(Succeed())
+		}
+
+		token, err := SignToken(client, priK, WithCodec(ginkgoCodecContentType), WithCompression())
+		Expect(err).ToNot(HaveOccurred())
+
+		parsed, err := ParseClientIDToken(token, pubK, true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(parsed.OPAPolicy).To(Equal(client.OPAPolicy))
+	})
+
+	It("Should detect tampering and compression bombs", func() {
+		token, err := SignToken(client, priK, WithCompression())
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = ParseClientIDToken(token, ed25519.PublicKey(bytes.Repeat([]byte{1}, 32)), true)
+		Expect(err).To(HaveOccurred())
+
+		bomb, err := deflateClaims(bytes.Repeat([]byte(" "), maxInflatedClaimsSize+10))
+		Expect(err).ToNot(HaveOccurred())
+
+		parts := strings.Split(token, ".")
+		parts[1] = base64.RawURLEncoding.EncodeToString(bomb)
+		_, err = ParseTokenUnverified(strings.Join(parts, "."))
+		Expect(err).To(MatchError(ContainSubstring("could not decompress claims: exceeds 1048576 bytes")))
+	})
+})
@@ -0,0 +1,131 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var (
+	ErrNotAConfigToken         = errors.New("not a configuration token")
+	ErrConfigRecipientMismatch = errors.New("configuration is not intended for this recipient")
+)
+
+// ConfigClaims is a issuer signed envelope holding a configuration payload destined for a specific server
+//
+// The "purpose" claim should be set to ConfigPurpose
+type ConfigClaims struct {
+	// Recipient is the identity of the server the configuration is for
+	Recipient string `json:"recipient"`
+
+	// RecipientTokenID is the token id of the server token the configuration is bound to, when set the
+	// configuration is only valid for that specific server token
+	RecipientTokenID string `json:"recipient_jti,omitempty"`
+
+	// ContentType describes the format of the payload, for example application/json
+	ContentType string `json:"content_type,omitempty"`
+
+	// Payload is the configuration data
+	Payload []byte `json:"payload"`
+
+	StandardClaims
+}
+
+// NewConfigClaims creates a configuration envelope for the server identified by recipient, when the recipient
+// token has a token id the envelope is bound to that token
+func NewConfigClaims(recipient *ServerClaims, contentType string, payload []byte, issuer string, validity time.Duration, opts ...ClaimsOption) (*ConfigClaims, error) {
+	if recipient == nil || recipient.ChoriaIdentity == "" {
+		return nil, fmt.Errorf("recipient is required")
+	}
+
+	if len(payload) == 0 {
+		return nil, fmt.Errorf("payload is required")
+	}
+
+	stdClaims, err := newStandardClaims(issuer, ConfigPurpose, validity, false, append([]ClaimsOption{WithAudience(recipient.ChoriaIdentity)}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &ConfigClaims{
+		Recipient:        recipient.ChoriaIdentity,
+		RecipientTokenID: recipient.TokenID(),
+		ContentType:      contentType,
+		Payload:          payload,
+		StandardClaims:   *stdClaims,
+	}
+	claims.Subject = recipient.ChoriaIdentity
+
+	return claims, nil
+}
+
+// VerifyRecipient checks that the configuration is intended for the server holding recipient
+func (c *ConfigClaims) VerifyRecipient(recipient *ServerClaims) error {
+	if recipient == nil {
+		return fmt.Errorf("recipient is required")
+	}
+
+	if c.Recipient != recipient.ChoriaIdentity {
+		return fmt.Errorf("%w: expected %s got %s", ErrConfigRecipientMismatch, c.Recipient, recipient.ChoriaIdentity)
+	}
+
+	if c.RecipientTokenID != "" && c.RecipientTokenID != recipient.TokenID() {
+		return fmt.Errorf("%w: bound to a different server token", ErrConfigRecipientMismatch)
+	}
+
+	return nil
+}
+
+// IsConfigToken determines if this is a configuration token
+func IsConfigToken(claims StandardClaims) bool {
+	return claims.Purpose == ConfigPurpose
+}
+
+// ParseConfigToken parses token and verifies it with pk, it does not verify the recipient
+func ParseConfigToken(token string, pk any, opts ...ParseOption) (*ConfigClaims, error) {
+	claims := &ConfigClaims{}
+	err := ParseToken(token, claims, pk, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse configuration token: %w", err)
+	}
+
+	if !IsConfigToken(claims.StandardClaims) {
+		return nil, ErrNotAConfigToken
+	}
+
+	if claims.Recipient == "" {
+		return nil, fmt.Errorf("no recipient in configuration token")
+	}
+
+	// if we have a tcs we require an issuer expiry to be set and it to not have expired
+	if !claims.verifyIssuerExpiry(claims.TrustChainSignature != "") {
+		return nil, jwt.ErrTokenExpired
+	}
+
+	return claims, nil
+}
+
+// VerifyConfigToken parses token, verifies it with pk and ensures it is intended for the server holding recipient, returning the payload
+func VerifyConfigToken(token string, pk any, recipient *ServerClaims, opts ...ParseOption) ([]byte, error) {
+	if recipient == nil {
+		return nil, fmt.Errorf("recipient is required")
+	}
+
+	claims, err := ParseConfigToken(token, pk, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	err = claims.VerifyRecipient(recipient)
+	if err != nil {
+		return nil, err
+	}
+
+	return claims.Payload, nil
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ConfigClaims", func() {
+	var (
+		pubK   ed25519.PublicKey
+		priK   ed25519.PrivateKey
+		server *ServerClaims
+		err    error
+	)
+
+	BeforeEach(func() {
+		pubK, priK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		srvPubK, _, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		server, err = NewServerClaims("n1.example.net", []string{"choria"}, "", nil, nil, srvPubK, "", time.Hour)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Describe("NewConfigClaims", func() {
+		It("Should validate the input", func() {
+			_, err := NewConfigClaims(nil, "", []byte("x"), "", time.Hour)
+			Expect(err).To(MatchError("recipient is required"))
+
+			_, err = NewConfigClaims(server, "", nil, "", time.Hour)
+			Expect(err).To(MatchError("payload is required"))
+		})
+
+		It("Should create envelopes bound to the recipient", func() {
+			claims, err := NewConfigClaims(server, "application/json", []byte(`{"x":1}`), "ginkgo", time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(claims.Purpose).To(Equal(ConfigPurpose))
+			Expect(claims.Recipient).To(Equal("n1.example.net"))
+			Expect(claims.RecipientTokenID).To(Equal(server.TokenID()))
+			Expect(claims.Audience).To(ContainElement("n1.example.net"))
+			Expect(claims.ContentType).To(Equal("application/json"))
+		})
+	})
+
+	Describe("VerifyConfigToken", func() {
+		var token string
+
+		BeforeEach(func() {
+			claims, err := NewConfigClaims(server, "application/json", []byte(`{"x":1}`), "ginkgo", time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+
+			token, err = SignToken(claims, priK)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("Should return the payload for the recipient", func() {
+			payload, err := VerifyConfigToken(token, pubK, server)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(payload)).To(Equal(`{"x":1}`))
+		})
+
+		It("Should reject other recipients", func() {
+			other := *server
+			other.ChoriaIdentity = "n2.example.net"
+			_, err := VerifyConfigToken(token, pubK, &other)
+			Expect(err).To(MatchError(ErrConfigRecipientMismatch))
+
+			other = *server
+			other.ID = "other"
+			_, err = VerifyConfigToken(token, pubK, &other)
+			Expect(err).To(MatchError(ErrConfigRecipientMismatch))
+
+			_, err = VerifyConfigToken(token, pubK, nil)
+			Expect(err).To(MatchError("recipient is required"))
+		})
+
+		It("Should reject tokens from untrusted issuers", func() {
+			otherPubK, _ := loadEd25519Seed("testdata/ed25519/other.seed")
+			_, err := VerifyConfigToken(token, otherPubK, server)
+			Expect(err).To(MatchError(ContainSubstring("could not parse configuration token")))
+		})
+
+		It("Should reject other tokens", func() {
+			st, err := SignToken(server, priK)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = ParseConfigToken(st, pubK)
+			Expect(err).To(MatchError(ErrNotAConfigToken))
+		})
+	})
+})
@@ -0,0 +1,90 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+	"io"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// ContextSigner is a crypto.Signer, typically backed by a KMS or HSM, that can be cancelled, SignTokenContext
+// passes its context to SignContext
+type ContextSigner interface {
+	crypto.Signer
+	SignContext(ctx context.Context, rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error)
+}
+
+// contextSigner binds a ContextSigner to the context of a single signing operation
+type contextSigner struct {
+	ctx    context.Context
+	signer ContextSigner
+}
+
+func (s *contextSigner) Public() crypto.PublicKey {
+	return s.signer.Public()
+}
+
+func (s *contextSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	return s.signer.SignContext(s.ctx, rand, digest, opts)
+}
+
+// withParseContext sets the context passed to introspection and revocation checks
+func withParseContext(ctx context.Context) ParseOption {
+	return func(o *parseOptions) error {
+		o.ctx = ctx
+		return nil
+	}
+}
+
+// withSignContext sets the context used to trace signing
+func withSignContext(ctx context.Context) SignOption {
+	return func(o *signOptions) error {
+		o.ctx = ctx
+		return nil
+	}
+}
+
+// ParseTokenContext behaves like ParseToken, ctx is passed to introspection and to revocation checkers implementing
+// ContextRevocationChecker so remote lookups respect the deadline of the caller
+func ParseTokenContext(ctx context.Context, token string, claims jwt.Claims, pk any, opts ...ParseOption) error {
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+
+	opts = append(opts[:len(opts):len(opts)], withParseContext(ctx))
+
+	err = ParseToken(token, claims, pk, opts...)
+	if err != nil && ctx.Err() != nil {
+		return fmt.Errorf("%w: %w", ctx.Err(), err)
+	}
+
+	return err
+}
+
+// SignTokenContext behaves like SignToken, signers implementing ContextSigner receive ctx
+func SignTokenContext(ctx context.Context, claims jwt.Claims, pk any, opts ...SignOption) (string, error) {
+	err := ctx.Err()
+	if err != nil {
+		return "", err
+	}
+
+	if cs, ok := pk.(ContextSigner); ok {
+		pk = &contextSigner{ctx: ctx, signer: cs}
+	}
+
+	opts = append(opts[:len(opts):len(opts)], withSignContext(ctx))
+
+	token, err := SignToken(claims, pk, opts...)
+	if err != nil && ctx.Err() != nil {
+		return "", fmt.Errorf("%w: %w", ctx.Err(), err)
+	}
+
+	return token, err
+}
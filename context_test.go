@@ -0,0 +1,124 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"io"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type ginkgoCtxKey struct{}
+
+type ginkgoContextChecker struct {
+	seen any
+}
+
+func (c *ginkgoContextChecker) IsRevoked(jwt.Claims) (bool, error) {
+	return false, nil
+}
+
+func (c *ginkgoContextChecker) IsRevokedContext(ctx context.Context, _ jwt.Claims) (bool, error) {
+	c.seen = ctx.Value(ginkgoCtxKey{})
+
+	if _, ok := ctx.Deadline(); ok {
+		<-ctx.Done()
+		return false, ctx.Err()
+	}
+
+	return false, nil
+}
+
+type ginkgoContextSigner struct {
+	ed25519.PrivateKey
+	seen any
+}
+
+func (s *ginkgoContextSigner) SignContext(ctx context.Context, rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	s.seen = ctx.Value(ginkgoCtxKey{})
+
+	err := ctx.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Sign(rand, digest, opts)
+}
+
+var _ = Describe("Context", func() {
+	var (
+		pubK   ed25519.PublicKey
+		priK   ed25519.PrivateKey
+		client *ClientIDClaims
+		token  string
+		ctx    context.Context
+	)
+
+	BeforeEach(func() {
+		var err error
+		pubK, priK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		client, err = NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		token, err = SignToken(client, priK)
+		Expect(err).ToNot(HaveOccurred())
+
+		ctx = context.WithValue(context.Background(), ginkgoCtxKey{}, "ginkgo")
+	})
+
+	Describe("ParseTokenContext", func() {
+		It("Should fail for cancelled contexts", func() {
+			cctx, cancel := context.WithCancel(ctx)
+			cancel()
+
+			err := ParseTokenContext(cctx, token, &ClientIDClaims{}, pubK)
+			Expect(err).To(MatchError(context.Canceled))
+		})
+
+		It("Should pass the context to revocation checkers", func() {
+			checker := &ginkgoContextChecker{}
+			claims := &ClientIDClaims{}
+			Expect(ParseTokenContext(ctx, token, claims, pubK, WithRevocationChecker(checker))).To(Succeed())
+			Expect(checker.seen).To(Equal("ginkgo"))
+			Expect(claims.CallerID).To(Equal("up=bob"))
+
+			tctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+			defer cancel()
+
+			err := ParseTokenContext(tctx, token, &ClientIDClaims{}, pubK, WithRevocationChecker(checker))
+			Expect(err).To(MatchError(context.DeadlineExceeded))
+			Expect(err).To(MatchError(ContainSubstring("could not check revocation status")))
+		})
+	})
+
+	Describe("SignTokenContext", func() {
+		It("Should sign using regular keys", func() {
+			signed, err := SignTokenContext(ctx, client, priK)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ParseToken(signed, &ClientIDClaims{}, pubK)).To(Succeed())
+		})
+
+		It("Should pass the context to context signers", func() {
+			signer := &ginkgoContextSigner{PrivateKey: priK}
+			signed, err := SignTokenContext(ctx, client, signer)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(signer.seen).To(Equal("ginkgo"))
+			Expect(ParseToken(signed, &ClientIDClaims{}, pubK)).To(Succeed())
+
+			cctx, cancel := context.WithCancel(ctx)
+			cancel()
+			_, err = SignTokenContext(cctx, client, signer)
+			Expect(err).To(MatchError(context.Canceled))
+		})
+	})
+})
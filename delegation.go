@@ -0,0 +1,282 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// ErrDelegationExceedsParent indicates a delegated token would have access the parent token does not have
+var ErrDelegationExceedsParent = errors.New("delegated access exceeds the parent token")
+
+// DelegationLink records a token in the chain of tokens a delegated token was derived from
+type DelegationLink struct {
+	// CallerID is the caller id of the parent token
+	CallerID string `json:"callerid"`
+
+	// TokenID is the token id, the jti claim, of the parent token
+	TokenID string `json:"jti"`
+
+	// Issuer is the issuer of the parent token
+	Issuer string `json:"iss,omitempty"`
+}
+
+// DelegationRestrictions narrows the access of a delegated token, unset fields are inherited from the parent token
+type DelegationRestrictions struct {
+	// CallerID is the caller id for the delegated token, defaults to the caller id of the parent. It must be a
+	// sub identity of the parent in the form <parent caller id>/<name>
+	CallerID string
+
+	// AllowedAgents are agent or agent.action names that must all be allowed by the parent, any ACL of the parent is
+	// not inherited when set
+	AllowedAgents []string
+
+	// ACL are action grants that must all be allowed by the parent
+	ACL []ActionGrant
+
+	// Permissions must only enable permissions the parent has
+	Permissions *ClientPermissions
+
+	// AdditionalPublishSubjects must all be covered by the publish subjects of the parent
+	AdditionalPublishSubjects []string
+
+	// AdditionalSubscribeSubjects must all be covered by the subscribe subjects of the parent
+	AdditionalSubscribeSubjects []string
+
+	// Validity is how long the delegated token is valid for, it never outlives the parent
+	Validity time.Duration
+}
+
+// DelegateClientToken verifies the client token parent using the public key of signer and signs a new client token for
+// childPubKey whose access is a subset of the parent's, narrowed by restrictions. The parent is recorded in the Delegation
+// claim of the new token along with any delegation chain the parent holds.
+//
+// The OPA or CEL policy, user properties, Scout and Autonomous Agent grants of the parent are retained unchanged.
+// Chain issuers and tokens issued by them cannot be delegated, delegated tokens can never issue tokens
+func DelegateClientToken(parent string, childPubKey ed25519.PublicKey, restrictions DelegationRestrictions, signer any) (string, error) {
+	if restrictions.Validity <= 0 {
+		return "", fmt.Errorf("validity is required")
+	}
+
+	err := ValidateEd25519PublicKey(childPubKey)
+	if err != nil {
+		return "", err
+	}
+
+	s, ok := signer.(crypto.Signer)
+	if !ok {
+		return "", fmt.Errorf("unsupported signing key %T", signer)
+	}
+
+	pc, err := ParseClientIDToken(parent, s.Public(), true)
+	if err != nil {
+		return "", fmt.Errorf("invalid parent token: %w", err)
+	}
+
+	if strings.HasPrefix(pc.Issuer, ChainIssuerPrefix) {
+		return "", fmt.Errorf("tokens issued by chain issuers cannot be delegated")
+	}
+
+	// a narrowed token may never gain the ability to issue tokens
+	if pc.IsChainedIssuer(false) {
+		return "", fmt.Errorf("chain issuer tokens cannot be delegated")
+	}
+
+	child := *pc
+	child.Delegation = append(append([]DelegationLink{}, pc.Delegation...), DelegationLink{CallerID: pc.CallerID, TokenID: pc.ID, Issuer: pc.Issuer})
+	child.PublicKey = hex.EncodeToString(childPubKey)
+	child.TrustChainSignature = ""
+	child.ChainMaxDepth = 0
+	child.ChainDelegationExpiresAt = nil
+	child.IssuerConstraints = nil
+	child.ReissuedFrom = ""
+
+	if restrictions.CallerID != "" {
+		if !isSubIdentity(pc.CallerID, restrictions.CallerID) {
+			return "", fmt.Errorf("%w: caller id %s is not a sub identity of %s", ErrDelegationExceedsParent, restrictions.CallerID, pc.CallerID)
+		}
+		child.CallerID = restrictions.CallerID
+	}
+
+	if restrictions.AllowedAgents != nil {
+		for _, agent := range restrictions.AllowedAgents {
+			if !pc.allowsGrant(agentGrant(agent)) {
+				return "", fmt.Errorf("%w: agent %s is not allowed", ErrDelegationExceedsParent, agent)
+			}
+		}
+		child.AllowedAgents = restrictions.AllowedAgents
+
+		// the ACL takes precedence over the allowed agents so keeping the parent ACL would undo the restriction
+		child.ACL = nil
+	}
+
+	if restrictions.ACL != nil {
+		err = validateActionGrants(restrictions.ACL)
+		if err != nil {
+			return "", err
+		}
+
+		for _, grant := range restrictions.ACL {
+			if !pc.allowsGrant(grant) {
+				return "", fmt.Errorf("%w: action grant %s %v is not allowed", ErrDelegationExceedsParent, grant.Agent, grant.Actions)
+			}
+		}
+		child.ACL = restrictions.ACL
+	}
+
+	if restrictions.Permissions != nil {
+		err = permissionsSubset(pc.Permissions, restrictions.Permissions)
+		if err != nil {
+			return "", err
+		}
+		child.Permissions = restrictions.Permissions
+	}
+
+	// acting on behalf of others requires the authentication delegator permission which the child may not have kept
+	if child.Permissions == nil || !child.Permissions.AuthenticationDelegator {
+		child.OnBehalfOf = nil
+	}
+
+	if restrictions.AdditionalPublishSubjects != nil {
+		err = subjectsSubset("publish", pc.AdditionalPublishSubjects, restrictions.AdditionalPublishSubjects)
+		if err != nil {
+			return "", err
+		}
+		child.AdditionalPublishSubjects = restrictions.AdditionalPublishSubjects
+	}
+
+	if restrictions.AdditionalSubscribeSubjects != nil {
+		err = subjectsSubset("subscribe", pc.AdditionalSubscribeSubjects, restrictions.AdditionalSubscribeSubjects)
+		if err != nil {
+			return "", err
+		}
+		child.AdditionalSubscribeSubjects = restrictions.AdditionalSubscribeSubjects
+	}
+
+	now := jwt.NewNumericDate(currentTime().UTC())
+	child.ID, err = newTokenID(now.Time)
+	if err != nil {
+		return "", err
+	}
+
+	child.IssuedAt = now
+	child.NotBefore = now
+	child.ExpiresAt = jwt.NewNumericDate(now.Add(restrictions.Validity))
+	if pc.ExpiresAt != nil && pc.ExpiresAt.Before(child.ExpiresAt.Time) {
+		child.ExpiresAt = pc.ExpiresAt
+	}
+
+	return SignToken(&child, signer)
+}
+
+// isSubIdentity determines if child is a caller id below parent, like up=bob/ci below up=bob
+func isSubIdentity(parent string, child string) bool {
+	name, ok := strings.CutPrefix(child, parent+"/")
+
+	return ok && name != "" && strings.TrimSpace(name) == name
+}
+
+// agentGrant is the action grant equivalent to agent in agent or agent.action form
+func agentGrant(agent string) ActionGrant {
+	name, action, ok := strings.Cut(agent, ".")
+	if !ok {
+		return ActionGrant{Agent: name}
+	}
+
+	return ActionGrant{Agent: name, Actions: []string{action}}
+}
+
+func permissionsSubset(parent *ClientPermissions, child *ClientPermissions) error {
+	name := missingPermission(parent, child)
+	if name != "" {
+		return fmt.Errorf("%w: permission %s is not held", ErrDelegationExceedsParent, name)
+	}
+
+	return nil
+}
+
+// missingPermission is the json name of the first permission enabled in the permissions struct child but not in parent
+func missingPermission(parent any, child any) string {
+	pv := reflect.Indirect(reflect.ValueOf(parent))
+	cv := reflect.ValueOf(child).Elem()
+	t := cv.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Type.Kind() != reflect.Bool || !cv.Field(i).Bool() {
+			continue
+		}
+
+		if !pv.IsValid() || !pv.Field(i).Bool() {
+			name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+			return name
+		}
+	}
+
+	return ""
+}
+
+func subjectsSubset(kind string, parent []string, child []string) error {
+	subj := uncoveredSubject(parent, child)
+	if subj != "" {
+		return fmt.Errorf("%w: %s subject %s is not allowed", ErrDelegationExceedsParent, kind, subj)
+	}
+
+	return nil
+}
+
+// uncoveredSubject is the first subject in child not covered by any subject in parent
+func uncoveredSubject(parent []string, child []string) string {
+	for _, subj := range child {
+		covered := false
+		for _, p := range parent {
+			if subjectCovers(p, subj) {
+				covered = true
+				break
+			}
+		}
+
+		if !covered {
+			return subj
+		}
+	}
+
+	return ""
+}
+
+// subjectCovers determines if every subject matched by the NATS subject subj is also matched by pattern
+func subjectCovers(pattern string, subj string) bool {
+	pt := strings.Split(pattern, ".")
+	st := strings.Split(subj, ".")
+
+	for i, p := range pt {
+		if p == ">" {
+			return i < len(st)
+		}
+
+		if i >= len(st) {
+			return false
+		}
+
+		switch {
+		case p == "*":
+			if st[i] == ">" {
+				return false
+			}
+		case p != st[i]:
+			return false
+		}
+	}
+
+	return len(pt) == len(st)
+}
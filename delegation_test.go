@@ -0,0 +1,235 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DelegateClientToken", func() {
+	var (
+		signerPubK ed25519.PublicKey
+		signerPriK ed25519.PrivateKey
+		childPubK  ed25519.PublicKey
+		parent     string
+		pc         *ClientIDClaims
+	)
+
+	BeforeEach(func() {
+		var err error
+		signerPubK, signerPriK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		childPubK, _, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		pc, err = NewClientIDClaims("up=bob", []string{"rpcutil", "puppet.status"}, "choria", map[string]string{"team": "ops"}, "", "ginkgo", time.Hour, &ClientPermissions{FleetManagement: true, StreamsUser: true}, signerPubK)
+		Expect(err).ToNot(HaveOccurred())
+		pc.AdditionalPublishSubjects = []string{"ci.>"}
+		pc.AdditionalSubscribeSubjects = []string{"ci.*.events"}
+
+		parent, err = SignToken(pc, signerPriK)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Should validate the arguments", func() {
+		_, err := DelegateClientToken(parent, childPubK, DelegationRestrictions{}, signerPriK)
+		Expect(err).To(MatchError("validity is required"))
+
+		_, err = DelegateClientToken(parent, ed25519.PublicKey(make([]byte, 32)), DelegationRestrictions{Validity: time.Minute}, signerPriK)
+		Expect(err).To(MatchError(ErrZeroPublicKey))
+
+		_, err = DelegateClientToken(parent, childPubK, DelegationRestrictions{Validity: time.Minute}, "x")
+		Expect(err).To(MatchError("unsupported signing key string"))
+
+		_, otherPriK, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = DelegateClientToken(parent, childPubK, DelegationRestrictions{Validity: time.Minute}, otherPriK)
+		Expect(err).To(MatchError(ContainSubstring("invalid parent token")))
+	})
+
+	It("Should inherit the parent access and record the chain", func() {
+		token, err := DelegateClientToken(parent, childPubK, DelegationRestrictions{Validity: time.Minute}, signerPriK)
+		Expect(err).ToNot(HaveOccurred())
+
+		child, err := ParseClientIDToken(token, signerPubK, true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(child.CallerID).To(Equal("up=bob"))
+		Expect(child.AllowedAgents).To(Equal(pc.AllowedAgents))
+		Expect(child.Permissions).To(Equal(pc.Permissions))
+		Expect(child.UserProperties).To(Equal(pc.UserProperties))
+		Expect(child.PublicKey).To(Equal(hex.EncodeToString(childPubK)))
+		Expect(child.ID).ToNot(Equal(pc.ID))
+		Expect(child.ExpiresAt.Time).To(BeTemporally("~", time.Now().Add(time.Minute), 2*time.Second))
+		Expect(child.Delegation).To(Equal([]DelegationLink{{CallerID: "up=bob", TokenID: pc.ID, Issuer: "ginkgo"}}))
+
+		tid, err := child.TokenIDTime()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tid.Equal(child.IssuedAt.Time)).To(BeTrue())
+	})
+
+	It("Should narrow access within the parent", func() {
+		token, err := DelegateClientToken(parent, childPubK, DelegationRestrictions{
+			CallerID:                    "up=bob/ci",
+			AllowedAgents:               []string{"rpcutil.ping", "puppet.status"},
+			Permissions:                 &ClientPermissions{FleetManagement: true},
+			AdditionalPublishSubjects:   []string{"ci.build.>"},
+			AdditionalSubscribeSubjects: []string{"ci.build.events"},
+			Validity:                    time.Minute,
+		}, signerPriK)
+		Expect(err).ToNot(HaveOccurred())
+
+		child, err := ParseClientIDToken(token, signerPubK, true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(child.CallerID).To(Equal("up=bob/ci"))
+		Expect(child.AllowedAgents).To(Equal([]string{"rpcutil.ping", "puppet.status"}))
+		Expect(child.Permissions).To(Equal(&ClientPermissions{FleetManagement: true}))
+
+		report, err := CompatibilityCheck(parent, child)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.IsDowngrade()).To(BeTrue())
+	})
+
+	It("Should refuse to widen access", func() {
+		for _, r := range []DelegationRestrictions{
+			{AllowedAgents: []string{"puppet"}},
+			{AllowedAgents: []string{"puppet.disable"}},
+			{Permissions: &ClientPermissions{OrgAdmin: true}},
+			{AdditionalPublishSubjects: []string{"other.>"}},
+			{AdditionalPublishSubjects: []string{">"}},
+			{AdditionalSubscribeSubjects: []string{"ci.>"}},
+			{CallerID: "up=alice"},
+			{CallerID: "up=bobby"},
+			{CallerID: "up=bob/"},
+			{CallerID: "up=bob/ x"},
+		} {
+			r.Validity = time.Minute
+			_, err := DelegateClientToken(parent, childPubK, r, signerPriK)
+			Expect(err).To(MatchError(ErrDelegationExceedsParent), "%#v", r)
+		}
+	})
+
+	It("Should intersect the parent ACL", func() {
+		pc.ACL = []ActionGrant{{Agent: "puppet", Actions: []string{"status", "last_*"}}, {Agent: "rpcutil"}}
+		parent, err := SignToken(pc, signerPriK)
+		Expect(err).ToNot(HaveOccurred())
+
+		token, err := DelegateClientToken(parent, childPubK, DelegationRestrictions{AllowedAgents: []string{"puppet.status"}, Validity: time.Minute}, signerPriK)
+		Expect(err).ToNot(HaveOccurred())
+		child, err := ParseClientIDToken(token, signerPubK, true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(child.ACL).To(BeEmpty())
+		Expect(child.AllowsAction("puppet", "status")).To(BeTrue())
+		Expect(child.AllowsAction("rpcutil", "ping")).To(BeFalse())
+
+		token, err = DelegateClientToken(parent, childPubK, DelegationRestrictions{ACL: []ActionGrant{{Agent: "puppet", Actions: []string{"last_run"}}}, Validity: time.Minute}, signerPriK)
+		Expect(err).ToNot(HaveOccurred())
+		child, err = ParseClientIDToken(token, signerPubK, true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(child.AllowsAction("puppet", "last_run")).To(BeTrue())
+		Expect(child.AllowsAction("puppet", "status")).To(BeFalse())
+		Expect(child.AllowsAction("rpcutil", "ping")).To(BeFalse())
+
+		for _, r := range []DelegationRestrictions{
+			{AllowedAgents: []string{"puppet"}},
+			{AllowedAgents: []string{"puppet.disable"}},
+			{ACL: []ActionGrant{{Agent: "puppet"}}},
+			{ACL: []ActionGrant{{Agent: "p*", Actions: []string{"status"}}}},
+			{ACL: []ActionGrant{{Agent: "puppet", Actions: []string{"*"}}}},
+		} {
+			r.Validity = time.Minute
+			_, err := DelegateClientToken(parent, childPubK, r, signerPriK)
+			Expect(err).To(MatchError(ErrDelegationExceedsParent), "%#v", r)
+		}
+
+		_, err = DelegateClientToken(parent, childPubK, DelegationRestrictions{ACL: []ActionGrant{{Agent: "puppet", Actions: []string{"stat[us"}}}, Validity: time.Minute}, signerPriK)
+		Expect(err).To(MatchError(ContainSubstring("invalid action grant pattern")))
+	})
+
+	It("Should check ACL restrictions against the parent allowed agents", func() {
+		_, err := DelegateClientToken(parent, childPubK, DelegationRestrictions{ACL: []ActionGrant{{Agent: "rpcutil"}, {Agent: "puppet", Actions: []string{"status"}}}, Validity: time.Minute}, signerPriK)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = DelegateClientToken(parent, childPubK, DelegationRestrictions{ACL: []ActionGrant{{Agent: "puppet", Actions: []string{"s*"}}}, Validity: time.Minute}, signerPriK)
+		Expect(err).To(MatchError(ErrDelegationExceedsParent))
+	})
+
+	It("Should only act on behalf of others while holding the authentication delegator permission", func() {
+		pc.Permissions = &ClientPermissions{AuthenticationDelegator: true, FleetManagement: true}
+		Expect(pc.SetOnBehalfOf(OnBehalfOf{CallerID: "up=alice"})).To(Succeed())
+		parent, err := SignToken(pc, signerPriK)
+		Expect(err).ToNot(HaveOccurred())
+
+		token, err := DelegateClientToken(parent, childPubK, DelegationRestrictions{Validity: time.Minute}, signerPriK)
+		Expect(err).ToNot(HaveOccurred())
+		child, err := ParseClientIDToken(token, signerPubK, true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(child.OnBehalfOf.CallerID).To(Equal("up=alice"))
+
+		token, err = DelegateClientToken(parent, childPubK, DelegationRestrictions{Permissions: &ClientPermissions{FleetManagement: true}, Validity: time.Minute}, signerPriK)
+		Expect(err).ToNot(HaveOccurred())
+		child, err = ParseClientIDToken(token, signerPubK, true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(child.OnBehalfOf).To(BeNil())
+	})
+
+	It("Should not outlive the parent", func() {
+		token, err := DelegateClientToken(parent, childPubK, DelegationRestrictions{Validity: 24 * time.Hour}, signerPriK)
+		Expect(err).ToNot(HaveOccurred())
+
+		child, err := ParseClientIDTokenUnverified(token)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(child.ExpiresAt.Time.Equal(pc.ExpiresAt.Time)).To(BeTrue())
+	})
+
+	It("Should extend an existing chain", func() {
+		first, err := DelegateClientToken(parent, childPubK, DelegationRestrictions{CallerID: "up=bob/build", Validity: time.Minute}, signerPriK)
+		Expect(err).ToNot(HaveOccurred())
+		fc, err := ParseClientIDTokenUnverified(first)
+		Expect(err).ToNot(HaveOccurred())
+
+		second, err := DelegateClientToken(first, childPubK, DelegationRestrictions{CallerID: "up=bob/build/job", Validity: time.Minute}, signerPriK)
+		Expect(err).ToNot(HaveOccurred())
+		sc, err := ParseClientIDTokenUnverified(second)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(sc.Delegation).To(HaveLen(2))
+		Expect(sc.Delegation[0].TokenID).To(Equal(pc.ID))
+		Expect(sc.Delegation[1]).To(Equal(DelegationLink{CallerID: "up=bob/build", TokenID: fc.ID, Issuer: "ginkgo"}))
+	})
+
+	It("Should not delegate chain issuers", func() {
+		holderPubK, _, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		issuer, err := NewClientIDClaims("aaa=login", nil, "", nil, "", "", time.Hour, &ClientPermissions{AuthenticationDelegator: true}, holderPubK, WithChainMaxDepth(2))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(issuer.AddOrgIssuerData(signerPriK)).To(Succeed())
+		token, err := SignToken(issuer, signerPriK)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = DelegateClientToken(token, childPubK, DelegationRestrictions{Validity: time.Minute}, signerPriK)
+		Expect(err).To(MatchError("chain issuer tokens cannot be delegated"))
+	})
+
+	Describe("subjectCovers", func() {
+		It("Should match NATS wildcards", func() {
+			Expect(subjectCovers("a.b", "a.b")).To(BeTrue())
+			Expect(subjectCovers("a.*", "a.b")).To(BeTrue())
+			Expect(subjectCovers("a.*", "a.*")).To(BeTrue())
+			Expect(subjectCovers("a.*", "a.>")).To(BeFalse())
+			Expect(subjectCovers("a.>", "a.b.c")).To(BeTrue())
+			Expect(subjectCovers("a.>", "a.>")).To(BeTrue())
+			Expect(subjectCovers("a.>", "a")).To(BeFalse())
+			Expect(subjectCovers("a.b", "a.b.c")).To(BeFalse())
+			Expect(subjectCovers("a.b.c", "a.b")).To(BeFalse())
+			Expect(subjectCovers(">", "x.y")).To(BeTrue())
+		})
+	})
+})
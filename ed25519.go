@@ -5,12 +5,56 @@
 package tokens
 
 import (
+	"bytes"
+	"crypto"
 	"crypto/ed25519"
-	"encoding/hex"
+	"crypto/rand"
+	"errors"
 	"fmt"
 	"os"
+
+	"filippo.io/edwards25519"
 )
 
+var (
+	// ErrInvalidPublicKeySize indicates a public key is not ed25519.PublicKeySize bytes long
+	ErrInvalidPublicKeySize = errors.New("invalid ed25519 public key size")
+
+	// ErrZeroPublicKey indicates a public key consisting only of zero bytes
+	ErrZeroPublicKey = errors.New("ed25519 public key is all zeros")
+
+	// ErrPublicKeyNotOnCurve indicates a public key that does not decode to a point on the curve
+	ErrPublicKeyNotOnCurve = errors.New("ed25519 public key is not a valid curve point")
+
+	// ErrWeakPublicKey indicates a public key that is a small order point and cannot be used safely
+	ErrWeakPublicKey = errors.New("ed25519 public key is a small order point")
+
+	// ErrIssuerPublicKey indicates a token embeds the public key of its own issuer
+	ErrIssuerPublicKey = errors.New("public key is the same as the issuer public key")
+)
+
+// ValidateEd25519PublicKey checks that pk is a usable ed25519 public key
+func ValidateEd25519PublicKey(pk ed25519.PublicKey) error {
+	if len(pk) != ed25519.PublicKeySize {
+		return ErrInvalidPublicKeySize
+	}
+
+	if bytes.Equal(pk, make([]byte, ed25519.PublicKeySize)) {
+		return ErrZeroPublicKey
+	}
+
+	point, err := new(edwards25519.Point).SetBytes(pk)
+	if err != nil {
+		return ErrPublicKeyNotOnCurve
+	}
+
+	if new(edwards25519.Point).MultByCofactor(point).Equal(edwards25519.NewIdentityPoint()) == 1 {
+		return ErrWeakPublicKey
+	}
+
+	return nil
+}
+
 func ed25519Sign(pk ed25519.PrivateKey, msg []byte) ([]byte, error) {
 	if len(pk) != ed25519.PrivateKeySize {
 		return nil, fmt.Errorf("invalid private key size")
@@ -19,6 +63,33 @@ func ed25519Sign(pk ed25519.PrivateKey, msg []byte) ([]byte, error) {
 	return ed25519.Sign(pk, msg), nil
 }
 
+// ed25519SignWithSigner signs msg using an ed25519 key held by signer, like a KMS or HSM, and confirms the signature is valid
+func ed25519SignWithSigner(signer crypto.Signer, msg []byte) ([]byte, ed25519.PublicKey, error) {
+	if signer == nil {
+		return nil, nil, fmt.Errorf("signer is required")
+	}
+
+	pk, ok := signer.Public().(ed25519.PublicKey)
+	if !ok {
+		return nil, nil, fmt.Errorf("signer does not hold an ed25519 key")
+	}
+
+	sig, err := signer.Sign(rand.Reader, msg, crypto.Hash(0))
+	if err != nil {
+		return nil, nil, fmt.Errorf("signing failed: %w", err)
+	}
+
+	valid, err := ed25519Verify(pk, msg, sig)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !valid {
+		return nil, nil, fmt.Errorf("signer produced an invalid signature")
+	}
+
+	return sig, pk, nil
+}
+
 func ed25519Verify(pk ed25519.PublicKey, msg []byte, sig []byte) (bool, error) {
 	if len(pk) != ed25519.PublicKeySize {
 		return false, fmt.Errorf("invalid public key size")
@@ -36,18 +107,24 @@ func ed25519SignWithSeedFile(f string, msg []byte) ([]byte, error) {
 	return ed25519Sign(pri, msg)
 }
 
+// ed25519KeyPairFromSeedFile loads a hex encoded seed or an ed25519 key in PKCS#8 PEM or OpenSSH format from f
 func ed25519KeyPairFromSeedFile(f string) (ed25519.PublicKey, ed25519.PrivateKey, error) {
 	ss, err := os.ReadFile(f)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	seed, err := hex.DecodeString(string(ss))
+	key, err := parseSigningKey(ss)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	return ed25519KeyPairFromSeed(seed)
+	priK, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, nil, fmt.Errorf("%v does not hold an ed25519 key", f)
+	}
+
+	return priK.Public().(ed25519.PublicKey), priK, nil
 }
 
 func ed25519KeyPairFromSeed(seed []byte) (ed25519.PublicKey, ed25519.PrivateKey, error) {
@@ -5,6 +5,8 @@
 package tokens
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
 	"encoding/hex"
 	"os"
 	"path/filepath"
@@ -42,4 +44,25 @@ var _ = Describe("Ed25519", func() {
 			Expect(hex.EncodeToString(sig)).To(Equal("5971db5ce8eec72d586b0630e2cdd9464e6800b973e6c58575a4072018ca51a93f2e1988d47e058bb19c18d57a44ffa9931b6b7e2f70b5e44ddc50339a8c790b"))
 		})
 	})
+	Describe("ValidateEd25519PublicKey", func() {
+		It("Should detect invalid keys", func() {
+			decode := func(s string) ed25519.PublicKey {
+				b, err := hex.DecodeString(s)
+				Expect(err).ToNot(HaveOccurred())
+				return b
+			}
+
+			Expect(ValidateEd25519PublicKey(nil)).To(MatchError(ErrInvalidPublicKeySize))
+			Expect(ValidateEd25519PublicKey(make([]byte, 31))).To(MatchError(ErrInvalidPublicKeySize))
+			Expect(ValidateEd25519PublicKey(make([]byte, 32))).To(MatchError(ErrZeroPublicKey))
+			Expect(ValidateEd25519PublicKey(decode("0200000000000000000000000000000000000000000000000000000000000000"))).To(MatchError(ErrPublicKeyNotOnCurve))
+			Expect(ValidateEd25519PublicKey(decode("0100000000000000000000000000000000000000000000000000000000000000"))).To(MatchError(ErrWeakPublicKey))
+		})
+
+		It("Should accept valid keys", func() {
+			pubK, _, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ValidateEd25519PublicKey(pubK)).To(Succeed())
+		})
+	})
 })
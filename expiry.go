@@ -0,0 +1,208 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ExpiryWarning describes a token that entered the final window of its validity
+type ExpiryWarning struct {
+	// Name is the name the token was watched as, empty for OnExpiryWarning
+	Name string
+
+	// Purpose is the purpose of the token
+	Purpose Purpose
+
+	// Identity is the caller id, server identity or subject of the token
+	Identity string
+
+	// TokenID is the unique id of the token
+	TokenID string
+
+	// ExpiresAt is when the token, or its issuer, expires
+	ExpiresAt time.Time
+
+	// Remaining is the validity left when the warning was raised, negative for expired tokens
+	Remaining time.Duration
+}
+
+// Expired indicates the token already expired when the warning was raised
+func (w ExpiryWarning) Expired() bool {
+	return w.Remaining <= 0
+}
+
+// expiryDetails decodes, without verifying, token and describes its expiry
+func expiryDetails(name string, token string) (*ExpiryWarning, error) {
+	purpose := TokenPurpose(token)
+	claims := newClaimsForPurpose(purpose)
+
+	_, err := parseUnverified(token, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	sc, ok := claims.(standardClaimsProvider)
+	if !ok {
+		return nil, fmt.Errorf("unsupported token purpose: %v", purpose)
+	}
+
+	std := sc.getStandardClaims()
+	exp := std.ExpireTime()
+	if exp.IsZero() {
+		return nil, fmt.Errorf("token does not expire")
+	}
+
+	return &ExpiryWarning{
+		Name:      name,
+		Purpose:   purpose,
+		Identity:  claimsIdentity(claims),
+		TokenID:   std.ID,
+		ExpiresAt: exp,
+	}, nil
+}
+
+// OnExpiryWarning calls fn once token has less than window of validity left, immediately when it already does.
+// The returned function cancels the pending callback, the token is not verified
+func OnExpiryWarning(token string, window time.Duration, fn func(ExpiryWarning)) (stop func(), err error) {
+	if fn == nil {
+		return nil, fmt.Errorf("callback is required")
+	}
+
+	warning, err := expiryDetails("", token)
+	if err != nil {
+		return nil, err
+	}
+
+	timer := time.AfterFunc(time.Until(warning.ExpiresAt.Add(-window)), func() {
+		w := *warning
+		w.Remaining = time.Until(w.ExpiresAt)
+		fn(w)
+	})
+
+	return func() { timer.Stop() }, nil
+}
+
+// ExpiryWatcher periodically checks a set of tokens and calls a function once for every token that enters the final
+// window of its validity, renewed tokens are warned about again once they enter the window
+type ExpiryWatcher struct {
+	window   time.Duration
+	interval time.Duration
+	fn       func(ExpiryWarning)
+	onError  func(name string, err error)
+	sources  map[string]TokenSource
+	warned   map[string]string
+	mu       sync.Mutex
+}
+
+// NewExpiryWatcher creates an ExpiryWatcher checking tokens every interval and calling fn for tokens with less than
+// window of validity left, onError is called when a token cannot be read and may be nil
+func NewExpiryWatcher(window time.Duration, interval time.Duration, fn func(ExpiryWarning), onError func(name string, err error)) (*ExpiryWatcher, error) {
+	if fn == nil {
+		return nil, fmt.Errorf("callback is required")
+	}
+
+	if interval <= 0 {
+		return nil, fmt.Errorf("interval must be positive")
+	}
+
+	return &ExpiryWatcher{
+		window:   window,
+		interval: interval,
+		fn:       fn,
+		onError:  onError,
+		sources:  map[string]TokenSource{},
+		warned:   map[string]string{},
+	}, nil
+}
+
+// Watch adds, or replaces, the token called name, use FileTokenSource to watch a token on disk
+func (w *ExpiryWatcher) Watch(name string, source TokenSource) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.sources[name] = source
+	delete(w.warned, name)
+}
+
+// Unwatch stops watching the token called name
+func (w *ExpiryWatcher) Unwatch(name string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	delete(w.sources, name)
+	delete(w.warned, name)
+}
+
+// Check checks all tokens once, Run calls it every interval
+func (w *ExpiryWatcher) Check() {
+	w.mu.Lock()
+	sources := make(map[string]TokenSource, len(w.sources))
+	for name, source := range w.sources {
+		sources[name] = source
+	}
+	w.mu.Unlock()
+
+	for name, source := range sources {
+		warning, err := w.check(name, source)
+		if err != nil {
+			if w.onError != nil {
+				w.onError(name, err)
+			}
+			continue
+		}
+
+		if warning != nil {
+			w.fn(*warning)
+		}
+	}
+}
+
+// check determines if the token called name needs a warning that was not yet raised
+func (w *ExpiryWatcher) check(name string, source TokenSource) (*ExpiryWarning, error) {
+	token, err := source()
+	if err != nil {
+		return nil, err
+	}
+
+	warning, err := expiryDetails(name, token)
+	if err != nil {
+		return nil, err
+	}
+
+	warning.Remaining = time.Until(warning.ExpiresAt)
+	if warning.Remaining > w.window {
+		return nil, nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, ok := w.sources[name]; !ok || w.warned[name] == warning.TokenID {
+		return nil, nil
+	}
+	w.warned[name] = warning.TokenID
+
+	return warning, nil
+}
+
+// Run checks all tokens immediately and then every interval until ctx is done
+func (w *ExpiryWatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.Check()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Expiry", func() {
+	var priK ed25519.PrivateKey
+
+	BeforeEach(func() {
+		var err error
+		_, priK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	issue := func(caller string, expires time.Duration) (string, *ClientIDClaims) {
+		claims, err := NewClientIDClaims(caller, nil, "", nil, "", "", time.Hour, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(expires))
+		token, err := SignToken(claims, priK)
+		Expect(err).ToNot(HaveOccurred())
+
+		return token, claims
+	}
+
+	Describe("OnExpiryWarning", func() {
+		It("Should fire once the token enters the window", func() {
+			token, claims := issue("up=bob", 2*time.Second)
+
+			warnings := make(chan ExpiryWarning, 1)
+			start := time.Now()
+			stop, err := OnExpiryWarning(token, 500*time.Millisecond, func(w ExpiryWarning) { warnings <- w })
+			Expect(err).ToNot(HaveOccurred())
+			defer stop()
+
+			var w ExpiryWarning
+			Eventually(warnings, 2*time.Second).Should(Receive(&w))
+			Expect(time.Since(start)).To(BeNumerically(">=", 400*time.Millisecond))
+			Expect(w.Purpose).To(Equal(ClientIDPurpose))
+			Expect(w.Identity).To(Equal("up=bob"))
+			Expect(w.TokenID).To(Equal(claims.ID))
+			Expect(w.Expired()).To(BeFalse())
+			Expect(w.Remaining).To(BeNumerically("<=", 500*time.Millisecond))
+		})
+
+		It("Should fire immediately for tokens within the window and support stopping", func() {
+			token, _ := issue("up=bob", -time.Minute)
+
+			warnings := make(chan ExpiryWarning, 1)
+			_, err := OnExpiryWarning(token, time.Hour, func(w ExpiryWarning) { warnings <- w })
+			Expect(err).ToNot(HaveOccurred())
+
+			var w ExpiryWarning
+			Eventually(warnings).Should(Receive(&w))
+			Expect(w.Expired()).To(BeTrue())
+
+			token, _ = issue("up=bob", time.Hour)
+			stop, err := OnExpiryWarning(token, 59*time.Minute+58*time.Second, func(w ExpiryWarning) { warnings <- w })
+			Expect(err).ToNot(HaveOccurred())
+			stop()
+			Consistently(warnings, 1500*time.Millisecond).ShouldNot(Receive())
+
+			_, err = OnExpiryWarning("invalid", time.Hour, func(ExpiryWarning) {})
+			Expect(err).To(HaveOccurred())
+			_, err = OnExpiryWarning(token, time.Hour, nil)
+			Expect(err).To(MatchError("callback is required"))
+		})
+	})
+
+	Describe("ExpiryWatcher", func() {
+		It("Should warn once per token", func() {
+			var (
+				warnings []ExpiryWarning
+				failures []string
+				mu       sync.Mutex
+			)
+
+			w, err := NewExpiryWatcher(time.Hour, time.Hour, func(w ExpiryWarning) {
+				mu.Lock()
+				warnings = append(warnings, w)
+				mu.Unlock()
+			}, func(name string, err error) {
+				mu.Lock()
+				failures = append(failures, name)
+				mu.Unlock()
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			current, _ := issue("up=bob", 30*time.Minute)
+			w.Watch("bob", func() (string, error) { return current, nil })
+
+			fresh, _ := issue("up=alice", 24*time.Hour)
+			w.Watch("alice", func() (string, error) { return fresh, nil })
+			w.Watch("broken", func() (string, error) { return "", errors.New("no token") })
+
+			w.Check()
+			w.Check()
+			Expect(warnings).To(HaveLen(1))
+			Expect(warnings[0].Name).To(Equal("bob"))
+			Expect(failures).To(Equal([]string{"broken", "broken"}))
+
+			By("Warning again for renewed tokens")
+			current, _ = issue("up=bob", 20*time.Minute)
+			w.Check()
+			Expect(warnings).To(HaveLen(2))
+
+			w.Unwatch("bob")
+			current, _ = issue("up=bob", 10*time.Minute)
+			w.Check()
+			Expect(warnings).To(HaveLen(2))
+		})
+
+		It("Should check periodically", func() {
+			warnings := make(chan ExpiryWarning, 10)
+			w, err := NewExpiryWatcher(time.Hour, 10*time.Millisecond, func(w ExpiryWarning) { warnings <- w }, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go w.Run(ctx)
+
+			token, _ := issue("up=bob", 30*time.Minute)
+			w.Watch("bob", func() (string, error) { return token, nil })
+
+			Eventually(warnings).Should(Receive())
+			Consistently(warnings, 100*time.Millisecond).ShouldNot(Receive())
+		})
+
+		It("Should validate arguments", func() {
+			_, err := NewExpiryWatcher(time.Hour, time.Minute, nil, nil)
+			Expect(err).To(MatchError("callback is required"))
+			_, err = NewExpiryWatcher(time.Hour, 0, func(ExpiryWarning) {}, nil)
+			Expect(err).To(MatchError("interval must be positive"))
+		})
+	})
+})
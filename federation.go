@@ -0,0 +1,332 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var (
+	// ErrNotAFederationTrustToken indicates a token is not a federation trust statement
+	ErrNotAFederationTrustToken = errors.New("not a federation trust token")
+
+	// ErrNotFederated indicates a foreign token was not issued by any trusted organization
+	ErrNotFederated = errors.New("token is not issued by a federated organization")
+
+	// ErrOutsideFederationScope indicates a foreign token grants access beyond what its organization is trusted for
+	ErrOutsideFederationScope = errors.New("token exceeds the scope of the federation trust")
+
+	// ErrFederationTrustExpired indicates the federation trust statement for an organization has expired
+	ErrFederationTrustExpired = errors.New("federation trust has expired")
+)
+
+// FederationTrust is a statement by an org issuer that it trusts the org issuer of another organization for specific collectives and subjects
+type FederationTrust struct {
+	// Organization is the name of the trusted organization
+	Organization string `json:"organization"`
+
+	// IssuerPublicKey is the hex encoded ed25519 public key of the org issuer of the trusted organization
+	IssuerPublicKey string `json:"issuer_public_key"`
+
+	// Collectives are the collectives servers of the trusted organization may be members of
+	Collectives []string `json:"collectives,omitempty"`
+
+	// PublishSubjects must cover any additional publish subjects granted to tokens of the trusted organization
+	PublishSubjects []string `json:"pub_subjects,omitempty"`
+
+	// SubscribeSubjects must cover any additional subscribe subjects granted to tokens of the trusted organization
+	SubscribeSubjects []string `json:"sub_subjects,omitempty"`
+
+	// AllowedAgents are the agent or agent.action names clients of the trusted organization may be granted
+	AllowedAgents []string `json:"agents,omitempty"`
+
+	// Permissions are the client permissions clients of the trusted organization may hold, org admin is never federated
+	Permissions *ClientPermissions `json:"permissions,omitempty"`
+
+	// ServerPermissions are the server permissions servers of the trusted organization may hold
+	ServerPermissions *ServerPermissions `json:"server_permissions,omitempty"`
+}
+
+// FederationTrustClaims is a federation trust statement signed by the trusting org issuer
+//
+// The "purpose" claim should be set to FederationTrustPurpose
+type FederationTrustClaims struct {
+	FederationTrust
+
+	StandardClaims
+}
+
+// FederationVerifier verifies tokens issued by foreign organizations using federation trust statements made by the local org issuer
+type FederationVerifier struct {
+	trusts  []*FederationTrust
+	keys    []ed25519.PublicKey
+	expires []time.Time
+}
+
+func (t *FederationTrust) publicKey() (ed25519.PublicKey, error) {
+	pk, err := hex.DecodeString(t.IssuerPublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+
+	err = ValidateEd25519PublicKey(pk)
+	if err != nil {
+		return nil, err
+	}
+
+	return pk, nil
+}
+
+// Validate checks the trusted organization, its key and that the trust is scoped to at least one collective or subject
+func (t *FederationTrust) Validate() error {
+	if t.Organization == "" {
+		return fmt.Errorf("organization is required")
+	}
+
+	_, err := t.publicKey()
+	if err != nil {
+		return err
+	}
+
+	if len(t.Collectives) == 0 && len(t.PublishSubjects) == 0 && len(t.SubscribeSubjects) == 0 {
+		return fmt.Errorf("at least one collective or subject is required")
+	}
+
+	if t.Permissions != nil && t.Permissions.OrgAdmin {
+		return fmt.Errorf("org admin access can not be federated")
+	}
+
+	err = validateCollectives(t.Collectives)
+	if err != nil {
+		return err
+	}
+
+	for _, subj := range append(append([]string{}, t.PublishSubjects...), t.SubscribeSubjects...) {
+		err = validateSubject(subj)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// AllowsCollective determines if the trusted organization may take part in collective
+func (t *FederationTrust) AllowsCollective(collective string) bool {
+	for _, c := range t.Collectives {
+		if c == collective {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Permits ensures claims issued by the trusted organization stay within the scope of the trust, agents and permissions
+// must be listed in the trust and cover any ACL grants, org admins are never federated and claims other than client and server claims are rejected
+func (t *FederationTrust) Permits(claims jwt.Claims) error {
+	var pub, sub []string
+
+	switch c := claims.(type) {
+	case *ClientIDClaims:
+		if c.Permissions != nil && c.Permissions.OrgAdmin {
+			return fmt.Errorf("%w: org admin access is not federated", ErrOutsideFederationScope)
+		}
+
+		if c.Permissions != nil {
+			name := missingPermission(t.Permissions, c.Permissions)
+			if name != "" {
+				return fmt.Errorf("%w: permission %s is not trusted", ErrOutsideFederationScope, name)
+			}
+		}
+
+		for _, agent := range c.AllowedAgents {
+			if !agentAllowed(t.AllowedAgents, agent) {
+				return fmt.Errorf("%w: agent %s is not trusted", ErrOutsideFederationScope, agent)
+			}
+		}
+
+		agent := uncoveredGrant(t.AllowedAgents, c.ACL)
+		if agent != "" {
+			return fmt.Errorf("%w: acl grant for agent %s is not trusted", ErrOutsideFederationScope, agent)
+		}
+
+		pub, sub = c.AdditionalPublishSubjects, c.AdditionalSubscribeSubjects
+
+	case *ServerClaims:
+		for _, collective := range c.Collectives {
+			if !t.AllowsCollective(collective) {
+				return fmt.Errorf("%w: collective %s is not trusted", ErrOutsideFederationScope, collective)
+			}
+		}
+
+		if c.Permissions != nil {
+			name := missingPermission(t.ServerPermissions, c.Permissions)
+			if name != "" {
+				return fmt.Errorf("%w: server permission %s is not trusted", ErrOutsideFederationScope, name)
+			}
+		}
+
+		pub, sub = c.AdditionalPublishSubjects, c.AdditionalSubscribeSubjects
+
+	default:
+		return fmt.Errorf("%w: %T can not be federated", ErrOutsideFederationScope, claims)
+	}
+
+	subj := uncoveredSubject(t.PublishSubjects, pub)
+	if subj != "" {
+		return fmt.Errorf("%w: publish subject %s is not trusted", ErrOutsideFederationScope, subj)
+	}
+
+	subj = uncoveredSubject(t.SubscribeSubjects, sub)
+	if subj != "" {
+		return fmt.Errorf("%w: subscribe subject %s is not trusted", ErrOutsideFederationScope, subj)
+	}
+
+	return nil
+}
+
+// Validate checks that the document holds a valid federation trust
+func (c *FederationTrustClaims) Validate() error {
+	if c.Purpose != FederationTrustPurpose {
+		return ErrNotAFederationTrustToken
+	}
+
+	return c.FederationTrust.Validate()
+}
+
+// SignFederationTrust creates a federation trust statement, signer is the org issuer of the trusting organization
+func SignFederationTrust(trust *FederationTrust, issuer string, validity time.Duration, signer any, opts ...ClaimsOption) (string, error) {
+	if trust == nil {
+		return "", fmt.Errorf("federation trust is required")
+	}
+
+	err := trust.Validate()
+	if err != nil {
+		return "", err
+	}
+
+	stdClaims, err := newStandardClaims(issuer, FederationTrustPurpose, validity, false, opts...)
+	if err != nil {
+		return "", err
+	}
+
+	return SignToken(&FederationTrustClaims{FederationTrust: *trust, StandardClaims: *stdClaims}, signer)
+}
+
+// LoadFederationTrust verifies a federation trust statement using pk, the org issuer of the trusting organization
+func LoadFederationTrust(doc string, pk any, opts ...ParseOption) (*FederationTrust, error) {
+	claims, err := loadFederationTrust(doc, pk, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &claims.FederationTrust, nil
+}
+
+func loadFederationTrust(doc string, pk any, opts ...ParseOption) (*FederationTrustClaims, error) {
+	claims := &FederationTrustClaims{}
+	err := ParseToken(strings.TrimSpace(doc), claims, pk, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse federation trust: %w", err)
+	}
+
+	return claims, nil
+}
+
+// LoadFederationTrustFile verifies the federation trust statement in file using pk
+func LoadFederationTrustFile(file string, pk any, opts ...ParseOption) (*FederationTrust, error) {
+	doc, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read federation trust: %w", err)
+	}
+
+	return LoadFederationTrust(string(doc), pk, opts...)
+}
+
+// NewFederationVerifier creates a verifier from federation trust statements docs, each must be signed by localIssuer.
+// Trusts are no longer honoured once their statement expires
+func NewFederationVerifier(localIssuer any, docs ...string) (*FederationVerifier, error) {
+	v := &FederationVerifier{}
+
+	for i, doc := range docs {
+		claims, err := loadFederationTrust(doc, localIssuer)
+		if err != nil {
+			return nil, fmt.Errorf("federation trust %d: %w", i, err)
+		}
+
+		pk, err := claims.FederationTrust.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("federation trust %d: %w", i, err)
+		}
+
+		var expires time.Time
+		if claims.ExpiresAt != nil {
+			expires = claims.ExpiresAt.Time
+		}
+
+		v.trusts = append(v.trusts, &claims.FederationTrust)
+		v.keys = append(v.keys, pk)
+		v.expires = append(v.expires, expires)
+	}
+
+	return v, nil
+}
+
+// Organizations lists the federated organizations in the order their trusts were loaded
+func (v *FederationVerifier) Organizations() []string {
+	var res []string
+	for _, t := range v.trusts {
+		res = append(res, t.Organization)
+	}
+
+	return res
+}
+
+// ParseToken verifies a token issued by a federated organization into claims, the token must be signed by the org issuer
+// of a trusted organization, including tokens issued by its chain issuers, and stay within the scope of the trust.
+// The trust that matched is returned
+func (v *FederationVerifier) ParseToken(token string, claims jwt.Claims, opts ...ParseOption) (*FederationTrust, error) {
+	popts, err := newParseOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+
+	for i, trust := range v.trusts {
+		if !v.expires[i].IsZero() && !popts.now().Before(v.expires[i]) {
+			errs = append(errs, fmt.Errorf("%s: %w", trust.Organization, ErrFederationTrustExpired))
+			continue
+		}
+
+		err := ParseToken(token, claims, v.keys[i], opts...)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", trust.Organization, err))
+			continue
+		}
+
+		err = trust.Permits(claims)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", trust.Organization, err)
+		}
+
+		return trust, nil
+	}
+
+	if len(errs) == 0 {
+		return nil, ErrNotFederated
+	}
+
+	return nil, fmt.Errorf("%w: %w", ErrNotFederated, errors.Join(errs...))
+}
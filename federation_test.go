@@ -0,0 +1,219 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Federation", func() {
+	var (
+		localPubK  ed25519.PublicKey
+		localPriK  ed25519.PrivateKey
+		remotePubK ed25519.PublicKey
+		remotePriK ed25519.PrivateKey
+		trust      *FederationTrust
+	)
+
+	BeforeEach(func() {
+		var err error
+		localPubK, localPriK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		remotePubK, remotePriK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		trust = &FederationTrust{
+			Organization:    "partner",
+			IssuerPublicKey: hex.EncodeToString(remotePubK),
+			Collectives:     []string{"shared"},
+			PublishSubjects: []string{"partner.>"},
+		}
+	})
+
+	newServer := func(collectives ...string) string {
+		serverPubK, _, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		server, err := NewServerClaims("n1.partner.net", collectives, "", nil, nil, serverPubK, "", time.Hour)
+		Expect(err).ToNot(HaveOccurred())
+		token, err := SignToken(server, remotePriK)
+		Expect(err).ToNot(HaveOccurred())
+
+		return token
+	}
+
+	Describe("Validate", func() {
+		It("Should validate the trust", func() {
+			Expect(trust.Validate()).To(Succeed())
+			Expect((&FederationTrust{}).Validate()).To(MatchError("organization is required"))
+			Expect((&FederationTrust{Organization: "x", IssuerPublicKey: "x"}).Validate()).To(MatchError(ContainSubstring("invalid public key")))
+
+			trust.Collectives, trust.PublishSubjects = nil, nil
+			Expect(trust.Validate()).To(MatchError("at least one collective or subject is required"))
+
+			trust.PublishSubjects = []string{"partner..x"}
+			Expect(trust.Validate()).To(MatchError(ErrInvalidSubject))
+
+			trust.PublishSubjects = []string{"partner.>"}
+			trust.Permissions = &ClientPermissions{OrgAdmin: true}
+			Expect(trust.Validate()).To(MatchError("org admin access can not be federated"))
+		})
+	})
+
+	Describe("Documents", func() {
+		It("Should sign and load trust statements", func() {
+			doc, err := SignFederationTrust(trust, "I-local", time.Hour, localPriK)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(TokenPurpose(doc)).To(Equal(FederationTrustPurpose))
+
+			_, err = LoadFederationTrust(doc, remotePubK)
+			Expect(err).To(HaveOccurred())
+
+			file := filepath.Join(GinkgoT().TempDir(), "partner.jwt")
+			Expect(os.WriteFile(file, []byte(doc+"\n"), 0600)).To(Succeed())
+
+			loaded, err := LoadFederationTrustFile(file, localPubK)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(loaded).To(Equal(trust))
+
+			_, err = LoadFederationTrust(newServer("shared"), remotePubK)
+			Expect(err).To(MatchError(ErrNotAFederationTrustToken))
+		})
+	})
+
+	Describe("FederationVerifier", func() {
+		var verifier *FederationVerifier
+
+		BeforeEach(func() {
+			doc, err := SignFederationTrust(trust, "I-local", time.Hour, localPriK)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = NewFederationVerifier(remotePubK, doc)
+			Expect(err).To(MatchError(ContainSubstring("federation trust 0")))
+
+			verifier, err = NewFederationVerifier(localPubK, doc)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(verifier.Organizations()).To(Equal([]string{"partner"}))
+		})
+
+		It("Should verify tokens from federated organizations", func() {
+			claims := &ServerClaims{}
+			matched, err := verifier.ParseToken(newServer("shared"), claims)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(matched.Organization).To(Equal("partner"))
+			Expect(claims.ChoriaIdentity).To(Equal("n1.partner.net"))
+		})
+
+		It("Should reject tokens outside the trust scope", func() {
+			_, err := verifier.ParseToken(newServer("shared", "private"), &ServerClaims{})
+			Expect(err).To(MatchError(ErrOutsideFederationScope))
+			Expect(err).To(MatchError(ContainSubstring("collective private is not trusted")))
+
+			client, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, &ClientPermissions{OrgAdmin: true}, nil)
+			Expect(err).ToNot(HaveOccurred())
+			token, err := SignToken(client, remotePriK)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = verifier.ParseToken(token, &ClientIDClaims{})
+			Expect(err).To(MatchError(ContainSubstring("org admin access is not federated")))
+
+			client.Permissions = nil
+			client.AdditionalSubscribeSubjects = []string{"other.>"}
+			token, err = SignToken(client, remotePriK)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = verifier.ParseToken(token, &ClientIDClaims{})
+			Expect(err).To(MatchError(ContainSubstring("subscribe subject other.> is not trusted")))
+		})
+
+		It("Should only allow agents and permissions listed in the trust", func() {
+			client, err := NewClientIDClaims("up=bob", []string{"rpcutil"}, "", nil, "", "", time.Hour, nil, nil)
+			Expect(err).ToNot(HaveOccurred())
+			token, err := SignToken(client, remotePriK)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = verifier.ParseToken(token, &ClientIDClaims{})
+			Expect(err).To(MatchError(ContainSubstring("agent rpcutil is not trusted")))
+
+			client.AllowedAgents = nil
+			client.Permissions = &ClientPermissions{StreamsAdmin: true}
+			token, err = SignToken(client, remotePriK)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = verifier.ParseToken(token, &ClientIDClaims{})
+			Expect(err).To(MatchError(ContainSubstring("permission streams_admin is not trusted")))
+
+			serverPubK, _, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+			server, err := NewServerClaims("n1.partner.net", []string{"shared"}, "", &ServerPermissions{Submission: true}, nil, serverPubK, "", time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+			token, err = SignToken(server, remotePriK)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = verifier.ParseToken(token, &ServerClaims{})
+			Expect(err).To(MatchError(ContainSubstring("server permission submission is not trusted")))
+
+			trust.AllowedAgents = []string{"rpcutil"}
+			trust.Permissions = &ClientPermissions{StreamsAdmin: true}
+			doc, err := SignFederationTrust(trust, "I-local", time.Hour, localPriK)
+			Expect(err).ToNot(HaveOccurred())
+			verifier, err = NewFederationVerifier(localPubK, doc)
+			Expect(err).ToNot(HaveOccurred())
+
+			client.AllowedAgents = []string{"rpcutil"}
+			token, err = SignToken(client, remotePriK)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = verifier.ParseToken(token, &ClientIDClaims{})
+			Expect(err).ToNot(HaveOccurred())
+
+			client.ACL = []ActionGrant{{Agent: "*"}}
+			token, err = SignToken(client, remotePriK)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = verifier.ParseToken(token, &ClientIDClaims{})
+			Expect(err).To(MatchError(ErrOutsideFederationScope))
+			Expect(err).To(MatchError(ContainSubstring("acl grant for agent * is not trusted")))
+
+			client.ACL = []ActionGrant{{Agent: "rpcutil", Actions: []string{"ping"}}}
+			token, err = SignToken(client, remotePriK)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = verifier.ParseToken(token, &ClientIDClaims{})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("Should reject claims it can not scope", func() {
+			Expect(trust.Permits(&ProvisioningClaims{})).To(MatchError(ErrOutsideFederationScope))
+		})
+
+		It("Should stop honouring expired trusts", func() {
+			doc, err := SignFederationTrust(trust, "I-local", time.Minute, localPriK)
+			Expect(err).ToNot(HaveOccurred())
+			verifier, err = NewFederationVerifier(localPubK, doc)
+			Expect(err).ToNot(HaveOccurred())
+
+			token := newServer("shared")
+			_, err = verifier.ParseToken(token, &ServerClaims{})
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = verifier.ParseToken(token, &ServerClaims{}, WithVerificationClock(func() time.Time { return time.Now().Add(2 * time.Minute) }))
+			Expect(err).To(MatchError(ErrNotFederated))
+			Expect(err).To(MatchError(ErrFederationTrustExpired))
+		})
+
+		It("Should reject tokens from other organizations", func() {
+			client, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, nil)
+			Expect(err).ToNot(HaveOccurred())
+			token, err := SignToken(client, localPriK)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = verifier.ParseToken(token, &ClientIDClaims{})
+			Expect(err).To(MatchError(ErrNotFederated))
+
+			_, err = (&FederationVerifier{}).ParseToken(token, &ClientIDClaims{})
+			Expect(err).To(MatchError(ErrNotFederated))
+		})
+	})
+})
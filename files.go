@@ -0,0 +1,126 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !js
+
+package tokens
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// TokenBackupSuffix is appended to the file name of the backup kept by WithTokenBackup
+const TokenBackupSuffix = ".bak"
+
+// SaveOption configures optional behavior when saving tokens
+type SaveOption func(*saveOptions) error
+
+type saveOptions struct {
+	backup bool
+}
+
+// WithTokenBackup keeps the previous token, when there is one, in a file with TokenBackupSuffix appended
+func WithTokenBackup() SaveOption {
+	return func(o *saveOptions) error {
+		o.backup = true
+		return nil
+	}
+}
+
+// SaveToken durably writes token to outFile, the token is written to a temporary file that is synced to disk and
+// renamed over outFile so a crash never leaves a truncated token behind
+func SaveToken(token string, outFile string, perm os.FileMode, opts ...SaveOption) error {
+	sopts := &saveOptions{}
+	for _, opt := range opts {
+		err := opt(sopts)
+		if err != nil {
+			return err
+		}
+	}
+
+	if sopts.backup {
+		previous, err := os.ReadFile(outFile)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+		case err != nil:
+			return err
+		default:
+			err = writeFileAtomic(outFile+TokenBackupSuffix, previous, perm)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return writeFileAtomic(outFile, []byte(token), perm)
+}
+
+// SaveAndSignTokenWithKeyFile signs a token using SignTokenWithKeyFile and saves it to outFile using SaveToken
+func SaveAndSignTokenWithKeyFile(claims jwt.Claims, pkFile string, outFile string, perm os.FileMode, opts ...SaveOption) error {
+	token, err := SignTokenWithKeyFile(claims, pkFile)
+	if err != nil {
+		return err
+	}
+
+	return SaveToken(token, outFile, perm, opts...)
+}
+
+// writeFileAtomic writes data to path using a temporary file in the same directory that is synced and renamed over
+// path, readers see either the old or the new content. When path is a symlink the file it points to is replaced so
+// links like those in Kubernetes secret mounts are kept
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	target, err := filepath.EvalSymlinks(path)
+	switch {
+	case err == nil:
+		path = target
+	case !errors.Is(err, fs.ErrNotExist):
+		return err
+	}
+
+	tf, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tf.Name())
+
+	_, err = tf.Write(data)
+	if err == nil {
+		err = tf.Chmod(perm)
+	}
+	if err == nil {
+		err = tf.Sync()
+	}
+	cerr := tf.Close()
+	if err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tf.Name(), path)
+	if err != nil {
+		return err
+	}
+
+	syncDir(filepath.Dir(path))
+
+	return nil
+}
+
+// syncDir syncs the directory dir so renames in it are durable, not all platforms support this so failures are ignored
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+
+	d.Sync()
+	d.Close()
+}
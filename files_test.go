@@ -0,0 +1,87 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !js
+
+package tokens
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("writeFileAtomic", func() {
+	It("Should replace files without leaving temporary files", func() {
+		dir := GinkgoT().TempDir()
+		path := filepath.Join(dir, "token.jwt")
+
+		Expect(writeFileAtomic(path, []byte("first"), 0600)).To(Succeed())
+		Expect(writeFileAtomic(path, []byte("second"), 0640)).To(Succeed())
+
+		content, err := os.ReadFile(path)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(content)).To(Equal("second"))
+
+		stat, err := os.Stat(path)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stat.Mode().Perm()).To(Equal(os.FileMode(0640)))
+
+		entries, err := os.ReadDir(dir)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(entries).To(HaveLen(1))
+
+		Expect(writeFileAtomic(filepath.Join(dir, "missing", "token.jwt"), []byte("x"), 0600)).ToNot(Succeed())
+	})
+})
+
+var _ = Describe("SaveToken", func() {
+	var dir string
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+	})
+
+	It("Should save tokens keeping an optional backup", func() {
+		path := filepath.Join(dir, "token.jwt")
+
+		Expect(SaveToken("first", path, 0600, WithTokenBackup())).To(Succeed())
+		Expect(filepath.Join(dir, "token.jwt"+TokenBackupSuffix)).ToNot(BeAnExistingFile())
+
+		Expect(SaveToken("second", path, 0600, WithTokenBackup())).To(Succeed())
+		Expect(SaveToken("third", path, 0600)).To(Succeed())
+
+		content, err := os.ReadFile(path)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(content)).To(Equal("third"))
+
+		backup, err := os.ReadFile(path + TokenBackupSuffix)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(backup)).To(Equal("first"))
+	})
+
+	It("Should sign and save tokens atomically", func() {
+		path := filepath.Join(dir, "token.jwt")
+		Expect(os.WriteFile(path, []byte("previous"), 0600)).To(Succeed())
+
+		claims, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(SaveAndSignTokenWithKeyFile(claims, "testdata/rsa/signer-key.pem", path, 0640, WithTokenBackup())).To(Succeed())
+
+		token, err := os.ReadFile(path)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = ParseClientIDToken(string(token), loadRSAPubKey("testdata/rsa/signer-public.pem"), true)
+		Expect(err).ToNot(HaveOccurred())
+
+		backup, err := os.ReadFile(path + TokenBackupSuffix)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(backup)).To(Equal("previous"))
+
+		Expect(SaveAndSignTokenWithKeyFile(claims, "/nonexisting", path, 0640)).ToNot(Succeed())
+		Expect(os.ReadFile(path)).To(Equal(token))
+	})
+})
@@ -0,0 +1,97 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// ErrPurposeMismatch indicates a token has a purpose that does not match the claims it is parsed into
+var ErrPurposeMismatch = errors.New("token purpose does not match the claims type")
+
+// ParseTokenAs parses and verifies token using key into a new T, for example ParseTokenAs[ClientIDClaims](token, pk).
+// T is the claims type itself, *T must implement jwt.Claims so pointer types like *ClientIDClaims do not compile.
+//
+// When T is registered for any purpose using RegisterPurpose the token must have one of those purposes
+func ParseTokenAs[T any, PT interface {
+	*T
+	jwt.Claims
+}](token string, key any, opts ...ParseOption) (*T, error) {
+	claims := PT(new(T))
+
+	err := ParseToken(token, claims, key, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	err = verifyPurposeForType(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return (*T)(claims), nil
+}
+
+// ParseTokenUnverifiedAs parses token into a new T without verifying it, the purpose is checked as in ParseTokenAs
+func ParseTokenUnverifiedAs[T any, PT interface {
+	*T
+	jwt.Claims
+}](token string) (*T, error) {
+	claims := PT(new(T))
+
+	_, err := parseUnverified(token, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	err = verifyPurposeForType(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return (*T)(claims), nil
+}
+
+// verifyPurposeForType ensures claims has a purpose registered for its type, types that are not registered are not checked
+func verifyPurposeForType(claims any) error {
+	sc, ok := claims.(standardClaimsProvider)
+	if !ok {
+		return nil
+	}
+
+	std := sc.getStandardClaims()
+	purpose := std.Purpose
+	if purpose == UnknownPurpose && std.Subject == string(ProvisioningPurpose) {
+		purpose = ProvisioningPurpose
+	}
+
+	ct := reflect.TypeOf(claims)
+	registered := false
+
+	purposesMu.RLock()
+	defer purposesMu.RUnlock()
+
+	for p, factory := range purposes {
+		if reflect.TypeOf(factory()) != ct {
+			continue
+		}
+
+		if p == purpose {
+			return nil
+		}
+
+		registered = true
+	}
+
+	if registered {
+		return fmt.Errorf("%w: %q for %s", ErrPurposeMismatch, purpose, ct.Elem().Name())
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Generics", func() {
+	var (
+		pubK  ed25519.PublicKey
+		priK  ed25519.PrivateKey
+		token string
+	)
+
+	BeforeEach(func() {
+		var err error
+		pubK, priK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		claims, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		token, err = SignToken(claims, priK)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Describe("ParseTokenAs", func() {
+		It("Should parse into the requested type", func() {
+			claims, err := ParseTokenAs[ClientIDClaims](token, pubK)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(claims.CallerID).To(Equal("up=bob"))
+		})
+
+		It("Should parse through the pointer type of the claims", func() {
+			claims, err := ParseTokenAs[ClientIDClaims, *ClientIDClaims](token, pubK)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(claims.CallerID).To(Equal("up=bob"))
+
+			unverified, err := ParseTokenUnverifiedAs[ClientIDClaims, *ClientIDClaims](token)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(unverified.CallerID).To(Equal("up=bob"))
+		})
+
+		It("Should verify the token", func() {
+			otherPubK, _ := loadEd25519Seed("testdata/ed25519/other.seed")
+			_, err := ParseTokenAs[ClientIDClaims](token, otherPubK)
+			Expect(err).To(MatchError(jwt.ErrTokenSignatureInvalid))
+		})
+
+		It("Should check the purpose of registered types", func() {
+			_, err := ParseTokenAs[ServerClaims](token, pubK)
+			Expect(err).To(MatchError(ErrPurposeMismatch))
+
+			std, err := ParseTokenAs[StandardClaims](token, pubK)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(std.Purpose).To(Equal(ClientIDPurpose))
+		})
+
+		It("Should support non package claims", func() {
+			claims, err := ParseTokenAs[jwt.RegisteredClaims](token, pubK)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(claims.ID).ToNot(BeEmpty())
+		})
+	})
+
+	Describe("ParseTokenUnverifiedAs", func() {
+		It("Should parse without verifying", func() {
+			claims, err := ParseTokenUnverifiedAs[ClientIDClaims](token)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(claims.CallerID).To(Equal("up=bob"))
+
+			_, err = ParseTokenUnverifiedAs[ServerClaims](token)
+			Expect(err).To(MatchError(ErrPurposeMismatch))
+
+			_, err = ParseTokenUnverifiedAs[ClientIDClaims]("garbage")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -3,11 +3,17 @@ module github.com/choria-io/tokens
 go 1.20
 
 require (
+	filippo.io/edwards25519 v1.1.0
+	github.com/fxamacker/cbor/v2 v2.6.0
 	github.com/golang-jwt/jwt/v4 v4.5.0
+	github.com/nats-io/jwt/v2 v2.5.5
+	github.com/nats-io/nkeys v0.4.7
 	github.com/onsi/ginkgo/v2 v2.16.0
 	github.com/onsi/gomega v1.31.1
 	github.com/segmentio/ksuid v1.0.4
 	github.com/sirupsen/logrus v1.9.3
+	github.com/vmihailenco/msgpack/v5 v5.3.5
+	golang.org/x/crypto v0.21.0
 )
 
 require (
@@ -15,11 +21,12 @@ require (
 	github.com/go-task/slim-sprig v0.0.0-20230315185526-52ccab3ef572 // indirect
 	github.com/google/go-cmp v0.6.0 // indirect
 	github.com/google/pprof v0.0.0-20240227163752-401108e1b7e7 // indirect
-	github.com/stretchr/testify v1.8.1 // indirect
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
+	github.com/x448/float16 v0.8.4 // indirect
 	golang.org/x/net v0.22.0 // indirect
 	golang.org/x/sys v0.18.0 // indirect
 	golang.org/x/text v0.14.0 // indirect
 	golang.org/x/tools v0.19.0 // indirect
-	google.golang.org/protobuf v1.28.1 // indirect
+	google.golang.org/protobuf v1.31.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,227 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package grpcauth authenticates gRPC calls using Choria tokens, clients attach tokens using Credentials and
+// servers verify them using the interceptors of an Authenticator which store the verified claims in the context
+package grpcauth
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+
+	"github.com/choria-io/tokens"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// metadataKey is the metadata key holding the bearer token
+const metadataKey = "authorization"
+
+type contextKey struct{}
+
+// Config configures an Authenticator
+type Config struct {
+	// KeyRing holds the keys trusted to sign tokens, a tokens.TrustConfig can supply this and ParseOptions
+	KeyRing *tokens.KeyRing
+
+	// TrustedKey is used when KeyRing is not set, see tokens.ParseToken for supported types
+	TrustedKey any
+
+	// Purposes are the token purposes accepted, defaults to client tokens only
+	Purposes []tokens.Purpose
+
+	// Audience when set must be one of the audiences of the token
+	Audience string
+
+	// ParseOptions are used when verifying tokens
+	ParseOptions []tokens.ParseOption
+
+	// SkipMethods are full method names, like /grpc.health.v1.Health/Check, that do not require authentication
+	SkipMethods []string
+
+	// Log receives messages about failed authentications, can be nil
+	Log *logrus.Entry
+}
+
+// Authenticator verifies tokens presented on gRPC calls
+type Authenticator struct {
+	cfg      Config
+	verifier *tokens.BearerVerifier
+	skip     map[string]struct{}
+}
+
+// NewAuthenticator creates an Authenticator using cfg
+func NewAuthenticator(cfg Config) (*Authenticator, error) {
+	verifier, err := tokens.NewBearerVerifier(cfg.KeyRing, cfg.TrustedKey, cfg.Purposes, cfg.Audience, cfg.ParseOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Log == nil {
+		log := logrus.New()
+		log.SetOutput(io.Discard)
+		cfg.Log = logrus.NewEntry(log)
+	}
+
+	a := &Authenticator{cfg: cfg, verifier: verifier, skip: map[string]struct{}{}}
+	for _, m := range cfg.SkipMethods {
+		a.skip[m] = struct{}{}
+	}
+
+	return a, nil
+}
+
+// Authenticate verifies the token in the incoming metadata of ctx and returns a context holding the claims, access
+// restrictions of client tokens are checked against the peer address. Failures are gRPC status errors with the
+// Unauthenticated or PermissionDenied codes
+func (a *Authenticator) Authenticate(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	var token string
+	for _, v := range md.Get(metadataKey) {
+		token = tokens.BearerToken(v)
+		if token != "" {
+			break
+		}
+	}
+
+	if token == "" {
+		return nil, status.Error(codes.Unauthenticated, "no bearer token")
+	}
+
+	claims, err := a.verifier.Verify(ctx, token, peerIP(ctx))
+	if tokens.IsAccessDenied(err) {
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "invalid token: %v", err)
+	}
+
+	return ContextWithClaims(ctx, claims), nil
+}
+
+// peerIP is the address of the peer making the call, nil when it is not known
+func peerIP(ctx context.Context) net.IP {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return nil
+	}
+
+	if tcp, ok := p.Addr.(*net.TCPAddr); ok {
+		return tcp.IP
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return nil
+	}
+
+	return net.ParseIP(host)
+}
+
+func (a *Authenticator) authenticate(ctx context.Context, method string) (context.Context, error) {
+	if _, ok := a.skip[method]; ok {
+		return ctx, nil
+	}
+
+	actx, err := a.Authenticate(ctx)
+	if err != nil {
+		a.cfg.Log.Warnf("Authentication failed for %s: %v", method, err)
+		return nil, err
+	}
+
+	return actx, nil
+}
+
+// UnaryServerInterceptor authenticates unary calls
+func (a *Authenticator) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := a.authenticate(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor authenticates streaming calls
+func (a *Authenticator) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := a.authenticate(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// authenticatedStream is a server stream whose context holds the verified claims
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+// Credentials attaches tokens to outgoing calls, it implements credentials.PerRPCCredentials
+type Credentials struct {
+	source     tokens.TokenSource
+	requireTLS bool
+}
+
+var _ credentials.PerRPCCredentials = (*Credentials)(nil)
+
+// NewCredentials creates credentials reading the token from source on every call, tokens are only sent over
+// TLS connections unless insecure is true
+func NewCredentials(source tokens.TokenSource, insecure bool) (*Credentials, error) {
+	if source == nil {
+		return nil, fmt.Errorf("token source is required")
+	}
+
+	return &Credentials{source: source, requireTLS: !insecure}, nil
+}
+
+// GetRequestMetadata implements credentials.PerRPCCredentials
+func (c *Credentials) GetRequestMetadata(_ context.Context, _ ...string) (map[string]string, error) {
+	token, err := c.source()
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]string{metadataKey: "Bearer " + token}, nil
+}
+
+// RequireTransportSecurity implements credentials.PerRPCCredentials
+func (c *Credentials) RequireTransportSecurity() bool {
+	return c.requireTLS
+}
+
+// ContextWithClaims stores claims in ctx, used by the interceptors and useful in tests of services
+func ContextWithClaims(ctx context.Context, claims jwt.Claims) context.Context {
+	return context.WithValue(ctx, contextKey{}, claims)
+}
+
+// ClaimsFromContext retrieves the claims stored by the interceptors
+func ClaimsFromContext(ctx context.Context) (jwt.Claims, bool) {
+	claims, ok := ctx.Value(contextKey{}).(jwt.Claims)
+	return claims, ok
+}
+
+// ClientClaimsFromContext retrieves the claims stored by the interceptors when they are client claims
+func ClientClaimsFromContext(ctx context.Context) (*tokens.ClientIDClaims, bool) {
+	claims, ok := ctx.Value(contextKey{}).(*tokens.ClientIDClaims)
+	return claims, ok
+}
@@ -0,0 +1,204 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package grpcauth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/choria-io/tokens"
+	"github.com/golang-jwt/jwt/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestGRPCAuth(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "GRPC/Auth")
+}
+
+var _ = Describe("Authenticator", func() {
+	var (
+		pub    ed25519.PublicKey
+		pri    ed25519.PrivateKey
+		token  string
+		seen   chan jwt.Claims
+		start  func(cfg Config) *grpc.Server
+		dial   func(srv *grpc.Server, token string) healthpb.HealthClient
+		signed func(callerID string, opts ...tokens.ClaimsOption) string
+	)
+
+	BeforeEach(func() {
+		var err error
+		pub, pri, err = ed25519.GenerateKey(nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		signed = func(callerID string, opts ...tokens.ClaimsOption) string {
+			claims, err := tokens.NewClientIDClaims(callerID, nil, "", nil, "", "", time.Hour, nil, nil, opts...)
+			Expect(err).ToNot(HaveOccurred())
+			t, err := tokens.SignToken(claims, pri)
+			Expect(err).ToNot(HaveOccurred())
+			return t
+		}
+		token = signed("up=bob", tokens.WithAudience("inventory"))
+
+		seen = make(chan jwt.Claims, 10)
+
+		start = func(cfg Config) *grpc.Server {
+			auth, err := NewAuthenticator(cfg)
+			Expect(err).ToNot(HaveOccurred())
+
+			record := func(ctx context.Context) {
+				if claims, ok := ClaimsFromContext(ctx); ok {
+					seen <- claims
+				}
+			}
+
+			srv := grpc.NewServer(
+				grpc.ChainUnaryInterceptor(auth.UnaryServerInterceptor(), func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+					record(ctx)
+					return handler(ctx, req)
+				}),
+				grpc.ChainStreamInterceptor(auth.StreamServerInterceptor(), func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+					record(ss.Context())
+					return handler(srv, ss)
+				}),
+			)
+			healthpb.RegisterHealthServer(srv, health.NewServer())
+			DeferCleanup(srv.Stop)
+
+			return srv
+		}
+
+		dial = func(srv *grpc.Server, token string) healthpb.HealthClient {
+			lis := bufconn.Listen(1024 * 1024)
+			go srv.Serve(lis)
+
+			opts := []grpc.DialOption{
+				grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+				grpc.WithTransportCredentials(insecure.NewCredentials()),
+			}
+
+			if token != "" {
+				creds, err := NewCredentials(func() (string, error) { return token, nil }, true)
+				Expect(err).ToNot(HaveOccurred())
+				opts = append(opts, grpc.WithPerRPCCredentials(creds))
+			}
+
+			conn, err := grpc.Dial("bufnet", opts...)
+			Expect(err).ToNot(HaveOccurred())
+			DeferCleanup(conn.Close)
+
+			return healthpb.NewHealthClient(conn)
+		}
+	})
+
+	It("Should validate the configuration", func() {
+		_, err := NewAuthenticator(Config{})
+		Expect(err).To(MatchError("key ring or trusted key is required"))
+
+		_, err = NewCredentials(nil, false)
+		Expect(err).To(MatchError("token source is required"))
+
+		creds, err := NewCredentials(func() (string, error) { return token, nil }, false)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(creds.RequireTransportSecurity()).To(BeTrue())
+		Expect(creds.GetRequestMetadata(context.Background())).To(HaveKeyWithValue("authorization", "Bearer "+token))
+	})
+
+	It("Should authenticate unary calls", func() {
+		client := dial(start(Config{TrustedKey: pub, Audience: "inventory"}), token)
+
+		_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+		Expect(err).ToNot(HaveOccurred())
+
+		var claims jwt.Claims
+		Eventually(seen).Should(Receive(&claims))
+		Expect(claims.(*tokens.ClientIDClaims).CallerID).To(Equal("up=bob"))
+	})
+
+	It("Should authenticate streaming calls", func() {
+		kr, err := tokens.NewKeyRing(pub)
+		Expect(err).ToNot(HaveOccurred())
+		client := dial(start(Config{KeyRing: kr}), token)
+
+		stream, err := client.Watch(context.Background(), &healthpb.HealthCheckRequest{})
+		Expect(err).ToNot(HaveOccurred())
+		res, err := stream.Recv()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.Status).To(Equal(healthpb.HealthCheckResponse_SERVING))
+
+		var claims jwt.Claims
+		Eventually(seen).Should(Receive(&claims))
+		cc, ok := ClientClaimsFromContext(ContextWithClaims(context.Background(), claims))
+		Expect(ok).To(BeTrue())
+		Expect(cc.CallerID).To(Equal("up=bob"))
+	})
+
+	It("Should reject unauthenticated calls", func() {
+		srv := start(Config{TrustedKey: pub, Audience: "inventory", SkipMethods: []string{"/grpc.health.v1.Health/Watch"}})
+
+		_, err := dial(srv, "").Check(context.Background(), &healthpb.HealthCheckRequest{})
+		Expect(status.Code(err)).To(Equal(codes.Unauthenticated))
+
+		_, err = dial(srv, signed("up=bob")).Check(context.Background(), &healthpb.HealthCheckRequest{})
+		Expect(status.Code(err)).To(Equal(codes.Unauthenticated))
+		Expect(err).To(MatchError(ContainSubstring("invalid token")))
+
+		stream, err := dial(srv, "").Watch(context.Background(), &healthpb.HealthCheckRequest{})
+		Expect(err).ToNot(HaveOccurred())
+		_, err = stream.Recv()
+		Expect(err).ToNot(HaveOccurred())
+		Consistently(seen).ShouldNot(Receive())
+	})
+
+	It("Should enforce the purpose", func() {
+		claims, err := tokens.NewServerClaims("n1.example.net", []string{"choria"}, "", nil, nil, pub, "", time.Hour)
+		Expect(err).ToNot(HaveOccurred())
+		server, err := tokens.SignToken(claims, pri)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = dial(start(Config{TrustedKey: pub}), server).Check(context.Background(), &healthpb.HealthCheckRequest{})
+		Expect(status.Code(err)).To(Equal(codes.PermissionDenied))
+
+		_, err = dial(start(Config{TrustedKey: pub, Purposes: []tokens.Purpose{tokens.ServerPurpose}}), server).Check(context.Background(), &healthpb.HealthCheckRequest{})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Should enforce access restrictions using the peer address", func() {
+		claims, err := tokens.NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		claims.Access = &tokens.AccessRestrictions{SourceCIDRs: []string{"192.0.2.0/24"}}
+		restricted, err := tokens.SignToken(claims, pri)
+		Expect(err).ToNot(HaveOccurred())
+
+		auth, err := NewAuthenticator(Config{TrustedKey: pub})
+		Expect(err).ToNot(HaveOccurred())
+
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+restricted))
+
+		_, err = auth.Authenticate(peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("192.0.2.10"), Port: 1234}}))
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = auth.Authenticate(peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 1234}}))
+		Expect(status.Code(err)).To(Equal(codes.PermissionDenied))
+
+		// bufconn peers have no ip address
+		_, err = dial(start(Config{TrustedKey: pub}), restricted).Check(context.Background(), &healthpb.HealthCheckRequest{})
+		Expect(status.Code(err)).To(Equal(codes.PermissionDenied))
+	})
+})
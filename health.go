@@ -0,0 +1,235 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// HealthStatus is a monitoring status, the values match the Nagios plugin exit codes
+type HealthStatus int
+
+const (
+	// HealthOK indicates the token is valid and not close to expiry
+	HealthOK HealthStatus = 0
+
+	// HealthWarning indicates the token is valid but will expire within the warning threshold
+	HealthWarning HealthStatus = 1
+
+	// HealthCritical indicates the token is invalid, expired or will expire within the critical threshold
+	HealthCritical HealthStatus = 2
+
+	// HealthUnknown indicates the token could not be read
+	HealthUnknown HealthStatus = 3
+)
+
+const (
+	// DefaultHealthWarningThreshold is the default remaining validity below which tokens are in warning state
+	DefaultHealthWarningThreshold = 7 * 24 * time.Hour
+
+	// DefaultHealthCriticalThreshold is the default remaining validity below which tokens are in critical state
+	DefaultHealthCriticalThreshold = 24 * time.Hour
+)
+
+// String returns the Nagios style name of the status
+func (s HealthStatus) String() string {
+	switch s {
+	case HealthOK:
+		return "OK"
+	case HealthWarning:
+		return "WARNING"
+	case HealthCritical:
+		return "CRITICAL"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (s HealthStatus) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// HealthThresholds configures when tokens are considered in warning or critical state, zero values use the defaults
+type HealthThresholds struct {
+	// Warning is the remaining validity below which the token is in warning state
+	Warning time.Duration
+
+	// Critical is the remaining validity below which the token is in critical state
+	Critical time.Duration
+}
+
+// TokenHealth is the result of a health check, suitable for rendering as JSON
+type TokenHealth struct {
+	// Status is the overall health of the token
+	Status HealthStatus `json:"status"`
+
+	// Message is a human readable summary of the health
+	Message string `json:"message"`
+
+	// Purpose is the purpose of the token
+	Purpose Purpose `json:"purpose,omitempty"`
+
+	// Identity is the caller id or identity the token is for
+	Identity string `json:"identity,omitempty"`
+
+	// TokenID is the unique id of the token
+	TokenID string `json:"token_id,omitempty"`
+
+	// Issuer is the issuer of the token
+	Issuer string `json:"issuer,omitempty"`
+
+	// ExpiresAt is when the token, or its issuer, expires
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+
+	// ExpiresIn is the remaining validity, negative when expired
+	ExpiresIn time.Duration `json:"expires_in"`
+
+	// Verified indicates the signature was verified using the key ring
+	Verified bool `json:"verified"`
+
+	// Error is the reason the token failed verification
+	Error string `json:"error,omitempty"`
+}
+
+// String returns a Nagios plugin style status line
+func (h *TokenHealth) String() string {
+	return fmt.Sprintf("%s: %s", h.Status, h.Message)
+}
+
+// CheckTokenHealth checks the token, or the token held in the file source, for validity and upcoming expiry.
+//
+// When keys is nil or empty the signature is not verified and only expiry is checked, this is noted in the message.
+// Any token that fails verification is in critical state
+func CheckTokenHealth(source string, keys *KeyRing, thresholds HealthThresholds) *TokenHealth {
+	if thresholds.Warning == 0 {
+		thresholds.Warning = DefaultHealthWarningThreshold
+	}
+	if thresholds.Critical == 0 {
+		thresholds.Critical = DefaultHealthCriticalThreshold
+	}
+
+	res := &TokenHealth{Status: HealthUnknown}
+
+	token, err := readHealthCheckSource(source)
+	if err != nil {
+		res.Error = err.Error()
+		res.Message = fmt.Sprintf("could not read token: %v", err)
+		return res
+	}
+
+	res.Purpose = TokenPurpose(token)
+	claims := newClaimsForPurpose(res.Purpose)
+
+	_, err = parseUnverified(token, claims)
+	if err != nil {
+		res.Status = HealthCritical
+		res.Error = err.Error()
+		res.Message = fmt.Sprintf("invalid token: %v", err)
+		return res
+	}
+
+	res.Identity = claimsIdentity(claims)
+	if sc, ok := claims.(standardClaimsProvider); ok {
+		std := sc.getStandardClaims()
+		res.TokenID = std.TokenID()
+		res.Issuer = std.Issuer
+		res.ExpiresAt = std.ExpireTime()
+		if !res.ExpiresAt.IsZero() {
+			res.ExpiresIn = time.Until(res.ExpiresAt).Round(time.Second)
+		}
+	}
+
+	name := res.Identity
+	if name == "" {
+		name = "unknown identity"
+	}
+
+	if keys != nil && keys.Len() > 0 {
+		_, err = keys.ParseToken(token, newClaimsForPurpose(res.Purpose))
+		switch {
+		case err == nil:
+			res.Verified = true
+		case isTimeValidationError(err):
+			// the signature was verified, the time based problems are reported below
+			res.Verified = true
+		default:
+			res.Status = HealthCritical
+			res.Error = err.Error()
+			res.Message = fmt.Sprintf("token for %s failed verification: %v", name, err)
+			return res
+		}
+	}
+
+	unverified := ""
+	if !res.Verified {
+		unverified = " (signature not verified)"
+	}
+
+	switch {
+	case res.ExpiresAt.IsZero():
+		res.Status = HealthWarning
+		res.Message = fmt.Sprintf("token for %s does not expire%s", name, unverified)
+	case res.ExpiresIn <= 0:
+		res.Status = HealthCritical
+		res.Message = fmt.Sprintf("token for %s expired %v ago%s", name, -res.ExpiresIn, unverified)
+	case res.ExpiresIn < thresholds.Critical:
+		res.Status = HealthCritical
+		res.Message = fmt.Sprintf("token for %s expires in %v%s", name, res.ExpiresIn, unverified)
+	case res.ExpiresIn < thresholds.Warning:
+		res.Status = HealthWarning
+		res.Message = fmt.Sprintf("token for %s expires in %v%s", name, res.ExpiresIn, unverified)
+	default:
+		res.Status = HealthOK
+		res.Message = fmt.Sprintf("token for %s expires in %v%s", name, res.ExpiresIn, unverified)
+	}
+
+	if res.Status != HealthCritical && res.Verified && err != nil {
+		// not yet valid or issued in the future
+		res.Status = HealthCritical
+		res.Error = err.Error()
+		res.Message = fmt.Sprintf("token for %s is not valid: %v", name, err)
+	}
+
+	return res
+}
+
+// readHealthCheckSource reads the token from a file when source is a path to an existing file
+func readHealthCheckSource(source string) (string, error) {
+	source = strings.TrimSpace(source)
+	if source == "" {
+		return "", fmt.Errorf("no token supplied")
+	}
+
+	if strings.Count(source, ".") == 2 && !strings.ContainsAny(source, "/\\") {
+		_, err := os.Stat(source)
+		if err != nil {
+			return source, nil
+		}
+	}
+
+	dat, err := os.ReadFile(source)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(dat)), nil
+}
+
+// isTimeValidationError determines if err was caused only by the exp, nbf or iat checks
+func isTimeValidationError(err error) bool {
+	var ve *jwt.ValidationError
+	if !errors.As(err, &ve) {
+		return false
+	}
+
+	return ve.Errors&^(jwt.ValidationErrorExpired|jwt.ValidationErrorNotValidYet|jwt.ValidationErrorIssuedAt) == 0
+}
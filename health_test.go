@@ -0,0 +1,120 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CheckTokenHealth", func() {
+	var (
+		pubK ed25519.PublicKey
+		priK ed25519.PrivateKey
+		kr   *KeyRing
+	)
+
+	BeforeEach(func() {
+		var err error
+		pubK, priK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		kr, err = NewKeyRing(pubK)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	clientToken := func(validity time.Duration) string {
+		claims, err := NewClientIDClaims("up=ginkgo", nil, "", nil, "", "ginkgo", time.Hour, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(validity))
+
+		token, err := SignToken(claims, priK)
+		Expect(err).ToNot(HaveOccurred())
+
+		return token
+	}
+
+	It("Should handle unreadable sources", func() {
+		res := CheckTokenHealth("", kr, HealthThresholds{})
+		Expect(res.Status).To(Equal(HealthUnknown))
+		Expect(res.String()).To(Equal("UNKNOWN: could not read token: no token supplied"))
+
+		res = CheckTokenHealth("/nonexisting/token.jwt", kr, HealthThresholds{})
+		Expect(res.Status).To(Equal(HealthUnknown))
+	})
+
+	It("Should detect invalid tokens", func() {
+		res := CheckTokenHealth("a.b.c", kr, HealthThresholds{})
+		Expect(res.Status).To(Equal(HealthCritical))
+		Expect(res.Message).To(HavePrefix("invalid token"))
+	})
+
+	It("Should report healthy tokens", func() {
+		res := CheckTokenHealth(clientToken(30*24*time.Hour), kr, HealthThresholds{})
+		Expect(res.Status).To(Equal(HealthOK))
+		Expect(res.Verified).To(BeTrue())
+		Expect(res.Purpose).To(Equal(ClientIDPurpose))
+		Expect(res.Identity).To(Equal("up=ginkgo"))
+		Expect(res.Issuer).To(Equal("ginkgo"))
+		Expect(res.TokenID).ToNot(BeEmpty())
+		Expect(res.ExpiresIn).To(BeNumerically("~", 30*24*time.Hour, time.Second))
+		Expect(res.String()).To(HavePrefix("OK: token for up=ginkgo expires in"))
+
+		j, err := json.Marshal(res)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(j)).To(ContainSubstring(`"status":"OK"`))
+	})
+
+	It("Should apply the thresholds", func() {
+		res := CheckTokenHealth(clientToken(3*24*time.Hour), kr, HealthThresholds{})
+		Expect(res.Status).To(Equal(HealthWarning))
+
+		res = CheckTokenHealth(clientToken(time.Hour), kr, HealthThresholds{})
+		Expect(res.Status).To(Equal(HealthCritical))
+
+		res = CheckTokenHealth(clientToken(3*time.Hour), kr, HealthThresholds{Warning: 4 * time.Hour, Critical: 2 * time.Hour})
+		Expect(res.Status).To(Equal(HealthWarning))
+
+		res = CheckTokenHealth(clientToken(-time.Hour), kr, HealthThresholds{})
+		Expect(res.Status).To(Equal(HealthCritical))
+		Expect(res.Verified).To(BeTrue())
+		Expect(res.Message).To(MatchRegexp("expired 1h0m[01]s ago"))
+	})
+
+	It("Should detect tokens that fail verification", func() {
+		otherPubK, _ := loadEd25519Seed("testdata/ed25519/other.seed")
+		other, err := NewKeyRing(otherPubK)
+		Expect(err).ToNot(HaveOccurred())
+
+		res := CheckTokenHealth(clientToken(30*24*time.Hour), other, HealthThresholds{})
+		Expect(res.Status).To(Equal(HealthCritical))
+		Expect(res.Verified).To(BeFalse())
+		Expect(res.Message).To(ContainSubstring("failed verification"))
+	})
+
+	It("Should support checking without keys", func() {
+		res := CheckTokenHealth(clientToken(30*24*time.Hour), nil, HealthThresholds{})
+		Expect(res.Status).To(Equal(HealthOK))
+		Expect(res.Verified).To(BeFalse())
+		Expect(res.Message).To(HaveSuffix("(signature not verified)"))
+	})
+
+	It("Should read tokens from files", func() {
+		file := filepath.Join(GinkgoT().TempDir(), "token.jwt")
+		Expect(os.WriteFile(file, []byte(clientToken(30*24*time.Hour)+"\n"), 0600)).To(Succeed())
+
+		res := CheckTokenHealth(file, kr, HealthThresholds{})
+		Expect(res.Status).To(Equal(HealthOK))
+		Expect(res.Verified).To(BeTrue())
+	})
+})
@@ -0,0 +1,140 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package httpauth provides net/http middleware authenticating requests using Choria tokens passed as bearer
+// tokens, verified claims are stored in the request context
+package httpauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/choria-io/tokens"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrNoToken indicates the request did not include a bearer token
+var ErrNoToken = errors.New("no bearer token")
+
+// ErrPurposeNotAllowed indicates the token is of a purpose the middleware does not accept
+var ErrPurposeNotAllowed = tokens.ErrPurposeNotAllowed
+
+type contextKey struct{}
+
+// Config configures the middleware
+type Config struct {
+	// KeyRing holds the keys trusted to sign tokens, a tokens.TrustConfig can supply this and ParseOptions
+	KeyRing *tokens.KeyRing
+
+	// TrustedKey is used when KeyRing is not set, see tokens.ParseToken for supported types
+	TrustedKey any
+
+	// Purposes are the token purposes accepted, defaults to client tokens only
+	Purposes []tokens.Purpose
+
+	// Audience when set must be one of the audiences of the token
+	Audience string
+
+	// ParseOptions are used when verifying tokens
+	ParseOptions []tokens.ParseOption
+
+	// OnError writes the response for requests that failed authentication, defaults to a 401 response
+	OnError func(w http.ResponseWriter, r *http.Request, err error)
+
+	// Log receives messages about failed authentications, can be nil
+	Log *logrus.Entry
+}
+
+// Middleware creates middleware that authenticates requests using cfg
+func Middleware(cfg Config) (func(http.Handler) http.Handler, error) {
+	verifier, err := tokens.NewBearerVerifier(cfg.KeyRing, cfg.TrustedKey, cfg.Purposes, cfg.Audience, cfg.ParseOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.OnError == nil {
+		cfg.OnError = unauthorized
+	}
+
+	if cfg.Log == nil {
+		log := logrus.New()
+		log.SetOutput(io.Discard)
+		cfg.Log = logrus.NewEntry(log)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, err := authenticate(verifier, r)
+			if err != nil {
+				cfg.Log.Warnf("Authentication failed for %s %s from %s: %v", r.Method, r.URL.Path, r.RemoteAddr, err)
+				cfg.OnError(w, r, err)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(ContextWithClaims(r.Context(), claims)))
+		})
+	}, nil
+}
+
+// authenticate verifies the bearer token in r, access restrictions of client tokens are checked against the remote address
+func authenticate(verifier *tokens.BearerVerifier, r *http.Request) (jwt.Claims, error) {
+	token, err := BearerToken(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return verifier.Verify(r.Context(), token, remoteIP(r))
+}
+
+// remoteIP is the address the request was made from, nil when it can not be determined
+func remoteIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	return net.ParseIP(host)
+}
+
+// BearerToken extracts the token from the Authorization header of r
+func BearerToken(r *http.Request) (string, error) {
+	token := tokens.BearerToken(r.Header.Get("Authorization"))
+	if token == "" {
+		return "", ErrNoToken
+	}
+
+	return token, nil
+}
+
+func unauthorized(w http.ResponseWriter, _ *http.Request, err error) {
+	desc := "invalid_token"
+	if errors.Is(err, ErrNoToken) {
+		desc = "invalid_request"
+	}
+
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf("Bearer error=%q", desc))
+	http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+}
+
+// ContextWithClaims stores claims in ctx, used by the middleware and useful in tests of handlers
+func ContextWithClaims(ctx context.Context, claims jwt.Claims) context.Context {
+	return context.WithValue(ctx, contextKey{}, claims)
+}
+
+// ClaimsFromContext retrieves the claims stored by the middleware
+func ClaimsFromContext(ctx context.Context) (jwt.Claims, bool) {
+	claims, ok := ctx.Value(contextKey{}).(jwt.Claims)
+	return claims, ok
+}
+
+// ClientClaimsFromContext retrieves the claims stored by the middleware when they are client claims
+func ClientClaimsFromContext(ctx context.Context) (*tokens.ClientIDClaims, bool) {
+	claims, ok := ctx.Value(contextKey{}).(*tokens.ClientIDClaims)
+	return claims, ok
+}
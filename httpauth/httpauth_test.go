@@ -0,0 +1,155 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package httpauth
+
+import (
+	"crypto/ed25519"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/choria-io/tokens"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestHTTPAuth(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "HTTP/Auth")
+}
+
+var _ = Describe("Middleware", func() {
+	var (
+		pub    ed25519.PublicKey
+		pri    ed25519.PrivateKey
+		client string
+		server string
+		next   http.Handler
+		serve  func(cfg Config, token string) *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		var err error
+		pub, pri, err = ed25519.GenerateKey(nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		cc, err := tokens.NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, nil, tokens.WithAudience("dashboard"))
+		Expect(err).ToNot(HaveOccurred())
+		client, err = tokens.SignToken(cc, pri)
+		Expect(err).ToNot(HaveOccurred())
+
+		sc, err := tokens.NewServerClaims("n1.example.net", []string{"choria"}, "", nil, nil, pub, "", time.Hour)
+		Expect(err).ToNot(HaveOccurred())
+		server, err = tokens.SignToken(sc, pri)
+		Expect(err).ToNot(HaveOccurred())
+
+		next = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := ClaimsFromContext(r.Context())
+			Expect(ok).To(BeTrue())
+
+			if cc, ok := ClientClaimsFromContext(r.Context()); ok {
+				w.Write([]byte(cc.CallerID))
+				return
+			}
+
+			w.Write([]byte(claims.(*tokens.ServerClaims).ChoriaIdentity))
+		})
+
+		serve = func(cfg Config, token string) *httptest.ResponseRecorder {
+			mw, err := Middleware(cfg)
+			Expect(err).ToNot(HaveOccurred())
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if token != "" {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+
+			rec := httptest.NewRecorder()
+			mw(next).ServeHTTP(rec, req)
+
+			return rec
+		}
+	})
+
+	It("Should validate the configuration", func() {
+		_, err := Middleware(Config{})
+		Expect(err).To(MatchError("key ring or trusted key is required"))
+
+		_, err = Middleware(Config{TrustedKey: pub, Purposes: []tokens.Purpose{"other"}})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Should authenticate client tokens", func() {
+		rec := serve(Config{TrustedKey: pub, Audience: "dashboard"}, client)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(Equal("up=bob"))
+
+		kr, err := tokens.NewKeyRing(pub)
+		Expect(err).ToNot(HaveOccurred())
+		rec = serve(Config{KeyRing: kr}, client)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+	})
+
+	It("Should enforce access restrictions using the remote address", func() {
+		kr, err := tokens.NewKeyRing(pub)
+		Expect(err).ToNot(HaveOccurred())
+
+		for cidr, code := range map[string]int{"192.0.2.0/24": http.StatusOK, "10.0.0.0/8": http.StatusUnauthorized} {
+			cc, err := tokens.NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, nil)
+			Expect(err).ToNot(HaveOccurred())
+			cc.Access = &tokens.AccessRestrictions{SourceCIDRs: []string{cidr}}
+			token, err := tokens.SignToken(cc, pri)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(serve(Config{TrustedKey: pub}, token).Code).To(Equal(code))
+			Expect(serve(Config{KeyRing: kr}, token).Code).To(Equal(code))
+		}
+	})
+
+	It("Should reject invalid requests", func() {
+		rec := serve(Config{TrustedKey: pub}, "")
+		Expect(rec.Code).To(Equal(http.StatusUnauthorized))
+		Expect(rec.Header().Get("WWW-Authenticate")).To(Equal(`Bearer error="invalid_request"`))
+
+		rec = serve(Config{TrustedKey: pub, Audience: "other"}, client)
+		Expect(rec.Code).To(Equal(http.StatusUnauthorized))
+		Expect(rec.Header().Get("WWW-Authenticate")).To(Equal(`Bearer error="invalid_token"`))
+
+		otherPub, _, err := ed25519.GenerateKey(nil)
+		Expect(err).ToNot(HaveOccurred())
+		rec = serve(Config{TrustedKey: otherPub}, client)
+		Expect(rec.Code).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("Should enforce the purpose", func() {
+		var failure error
+		onError := func(w http.ResponseWriter, _ *http.Request, err error) {
+			failure = err
+			w.WriteHeader(http.StatusForbidden)
+		}
+
+		rec := serve(Config{TrustedKey: pub, OnError: onError}, server)
+		Expect(rec.Code).To(Equal(http.StatusForbidden))
+		Expect(failure).To(MatchError(ErrPurposeNotAllowed))
+
+		rec = serve(Config{TrustedKey: pub, Purposes: []tokens.Purpose{tokens.ClientIDPurpose, tokens.ServerPurpose}}, server)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(Equal("n1.example.net"))
+	})
+
+	It("Should extract bearer tokens", func() {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		_, err := BearerToken(req)
+		Expect(err).To(MatchError(ErrNoToken))
+
+		req.Header.Set("Authorization", "Basic abc")
+		_, err = BearerToken(req)
+		Expect(err).To(MatchError(ErrNoToken))
+
+		req.Header.Set("Authorization", "bearer abc")
+		Expect(BearerToken(req)).To(Equal("abc"))
+	})
+})
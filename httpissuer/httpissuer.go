@@ -0,0 +1,385 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package httpissuer provides a http.Handler that issues Choria tokens to callers authenticated by an existing
+// client token. Callers post a tokens.SignedRequest holding their token and a TokenRequest signed using the private
+// key of that token, a policy callback decides if the request is allowed and may adjust the claims before they are signed
+package httpissuer
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/choria-io/tokens"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultValidity is the validity of issued tokens when neither the request nor the configuration sets one
+	DefaultValidity = time.Hour
+
+	// DefaultMaxRequestAge is how old a request may be when the configuration does not set a limit
+	DefaultMaxRequestAge = time.Minute
+
+	// maxRequestSize is the largest request body accepted
+	maxRequestSize = 64 * 1024
+
+	// requestSigningContext prefixes the signed payload so the signature can not be used in another protocol
+	requestSigningContext = "choria-io/tokens httpissuer token request\n"
+)
+
+var (
+	// ErrRequestDenied indicates the policy refused to issue the requested token
+	ErrRequestDenied = errors.New("token request denied")
+
+	// ErrInvalidRequest indicates the request could not be understood or was not correctly signed
+	ErrInvalidRequest = errors.New("invalid token request")
+)
+
+// Policy decides if requester may be issued claims, minted from req, by returning ErrRequestDenied or another error.
+// Policies may adjust the claims before they are signed
+type Policy func(ctx context.Context, requester *tokens.ClientIDClaims, req *TokenRequest, claims jwt.Claims) error
+
+// TokenRequest describes the token being requested
+type TokenRequest struct {
+	// Purpose is the kind of token requested, client and server tokens are supported
+	Purpose tokens.Purpose `json:"purpose"`
+
+	// Identity is the caller id of client tokens or the identity of server tokens
+	Identity string `json:"identity"`
+
+	// PublicKey is the hex encoded ed25519 public key of the holder of the new token
+	PublicKey string `json:"public_key"`
+
+	// Validity is how long the token should be valid for, defaults to the configured validity
+	Validity string `json:"validity,omitempty"`
+
+	// Agents are the agents a client token may access
+	Agents []string `json:"agents,omitempty"`
+
+	// Properties are user properties for client tokens
+	Properties map[string]string `json:"properties,omitempty"`
+
+	// Collectives are the collectives a server token belongs to
+	Collectives []string `json:"collectives,omitempty"`
+
+	// Time is when the request was made, old requests are rejected
+	Time time.Time `json:"time"`
+
+	// Nonce makes every request unique, the issuer accepts every nonce only once
+	Nonce string `json:"nonce"`
+}
+
+// Response is the body returned by the Issuer
+type Response struct {
+	// Token is the issued token
+	Token string `json:"token,omitempty"`
+
+	// Error describes why no token was issued
+	Error string `json:"error,omitempty"`
+}
+
+// NewSignedRequest creates the JSON encoded tokens.SignedRequest for req authenticated by token, signer is the
+// private key of token
+func NewSignedRequest(token string, signer crypto.Signer, req *TokenRequest) ([]byte, error) {
+	if req.Time.IsZero() {
+		req.Time = tokens.Now().UTC()
+	}
+
+	if req.Nonce == "" {
+		nonce := make([]byte, 16)
+		_, err := rand.Read(nonce)
+		if err != nil {
+			return nil, err
+		}
+		req.Nonce = hex.EncodeToString(nonce)
+	}
+
+	rj, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return tokens.SignRequest(token, signer, append([]byte(requestSigningContext), rj...))
+}
+
+// Config configures an Issuer
+type Config struct {
+	// KeyRing holds the keys trusted to sign the tokens of callers
+	KeyRing *tokens.KeyRing
+
+	// TrustedKey is a ed25519.PublicKey or *rsa.PublicKey verifying the tokens of callers when KeyRing is not set
+	TrustedKey any
+
+	// Signer signs issued tokens, see tokens.SignToken for supported types
+	Signer any
+
+	// Policy decides which requests are allowed
+	Policy Policy
+
+	// Issuer is the issuer set in issued tokens
+	Issuer string
+
+	// DefaultValidity is used for requests that do not specify a validity, defaults to DefaultValidity
+	DefaultValidity time.Duration
+
+	// MaxValidity is the longest validity that may be requested, unlimited when 0
+	MaxValidity time.Duration
+
+	// MaxRequestAge is how old requests may be, defaults to DefaultMaxRequestAge
+	MaxRequestAge time.Duration
+
+	// ReplayGuard records the nonces of requests so each request is accepted only once, defaults to one keeping
+	// nonces in memory. Issuers behind a load balancer should share a guard backed by a shared store
+	ReplayGuard *tokens.ReplayGuard
+
+	// ParseOptions are used when verifying the tokens of callers
+	ParseOptions []tokens.ParseOption
+
+	// ClaimsOptions are used when creating the claims of issued tokens
+	ClaimsOptions []tokens.ClaimsOption
+
+	// Log receives messages about issued and refused tokens, can be nil
+	Log *logrus.Entry
+}
+
+// Issuer is a http.Handler issuing tokens
+type Issuer struct {
+	cfg Config
+}
+
+// New creates an Issuer using cfg
+func New(cfg Config) (*Issuer, error) {
+	if cfg.KeyRing == nil && cfg.TrustedKey == nil {
+		return nil, fmt.Errorf("key ring or trusted key is required")
+	}
+
+	if cfg.KeyRing == nil {
+		kr, err := tokens.NewKeyRing(cfg.TrustedKey)
+		if err != nil {
+			return nil, err
+		}
+		cfg.KeyRing = kr
+	}
+
+	if cfg.Signer == nil {
+		return nil, fmt.Errorf("signer is required")
+	}
+
+	if cfg.Policy == nil {
+		return nil, fmt.Errorf("policy is required")
+	}
+
+	if cfg.DefaultValidity == 0 {
+		cfg.DefaultValidity = DefaultValidity
+	}
+
+	if cfg.MaxRequestAge == 0 {
+		cfg.MaxRequestAge = DefaultMaxRequestAge
+	}
+
+	if cfg.MaxValidity > 0 && cfg.DefaultValidity > cfg.MaxValidity {
+		return nil, fmt.Errorf("default validity exceeds the maximum validity")
+	}
+
+	if cfg.ReplayGuard == nil {
+		guard, err := tokens.NewReplayGuard(nil, 2*cfg.MaxRequestAge)
+		if err != nil {
+			return nil, err
+		}
+		cfg.ReplayGuard = guard
+	}
+
+	if cfg.Log == nil {
+		log := logrus.New()
+		log.SetOutput(io.Discard)
+		cfg.Log = logrus.NewEntry(log)
+	}
+
+	return &Issuer{cfg: cfg}, nil
+}
+
+// ServeHTTP implements http.Handler
+func (i *Issuer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		i.respond(w, http.StatusMethodNotAllowed, Response{Error: "only POST requests are supported"})
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestSize))
+	if err != nil {
+		i.respond(w, http.StatusBadRequest, Response{Error: fmt.Sprintf("%s: %v", ErrInvalidRequest, err)})
+		return
+	}
+
+	token, err := i.Issue(r.Context(), body)
+	switch {
+	case err == nil:
+		i.respond(w, http.StatusOK, Response{Token: token})
+	case errors.Is(err, ErrRequestDenied):
+		i.respond(w, http.StatusForbidden, Response{Error: err.Error()})
+	case errors.Is(err, ErrInvalidRequest):
+		i.respond(w, http.StatusBadRequest, Response{Error: err.Error()})
+	case tokens.VerificationFailureReason(err) != tokens.FailureOther:
+		i.respond(w, http.StatusUnauthorized, Response{Error: err.Error()})
+	default:
+		// details are logged by Issue and not shared with the caller
+		i.respond(w, http.StatusInternalServerError, Response{Error: http.StatusText(http.StatusInternalServerError)})
+	}
+}
+
+// Issue authenticates the JSON encoded tokens.SignedRequest in body, applies the policy and returns the signed token
+func (i *Issuer) Issue(ctx context.Context, body []byte) (string, error) {
+	requester, req, err := i.authenticate(ctx, body)
+	if err != nil {
+		i.cfg.Log.Warnf("Refusing token request: %v", err)
+		return "", err
+	}
+
+	log := i.cfg.Log.WithFields(logrus.Fields{"requester": requester.CallerID, "purpose": req.Purpose, "identity": req.Identity})
+
+	claims, err := i.mint(req)
+	if err != nil {
+		log.Warnf("Could not create claims: %v", err)
+		return "", err
+	}
+
+	err = i.cfg.Policy(ctx, requester, req, claims)
+	if err != nil {
+		log.Warnf("Policy refused token request: %v", err)
+		if !errors.Is(err, ErrRequestDenied) {
+			err = fmt.Errorf("%w: %w", ErrRequestDenied, err)
+		}
+		return "", err
+	}
+
+	token, err := tokens.SignTokenContext(ctx, claims, i.cfg.Signer)
+	if err != nil {
+		log.Errorf("Could not sign token: %v", err)
+		return "", err
+	}
+
+	log.Infof("Issued token")
+
+	return token, nil
+}
+
+// authenticate verifies the token of the caller, the signature of the request and that it was not seen before
+func (i *Issuer) authenticate(ctx context.Context, body []byte) (*tokens.ClientIDClaims, *TokenRequest, error) {
+	sr := &tokens.SignedRequest{}
+	err := json.Unmarshal(body, sr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+	}
+
+	if sr.Token == "" || len(sr.Payload) == 0 || sr.Signature == "" {
+		return nil, nil, fmt.Errorf("%w: token, payload and signature are required", ErrInvalidRequest)
+	}
+
+	if tokens.TokenPurpose(sr.Token) != tokens.ClientIDPurpose {
+		return nil, nil, fmt.Errorf("%w: requests must be made using a client token", ErrInvalidRequest)
+	}
+
+	payload, claims, err := tokens.VerifyRequest(body, i.cfg.KeyRing, i.cfg.ParseOptions...)
+	if errors.Is(err, tokens.ErrInvalidRequestSignature) {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	requester, ok := claims.(*tokens.ClientIDClaims)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: requests must be made using a client token", ErrInvalidRequest)
+	}
+
+	rj, ok := bytes.CutPrefix(payload, []byte(requestSigningContext))
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: payload is not a token request", ErrInvalidRequest)
+	}
+
+	req := &TokenRequest{}
+	err = json.Unmarshal(rj, req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+	}
+
+	age := tokens.Now().Sub(req.Time)
+	if req.Time.IsZero() || age > i.cfg.MaxRequestAge || age < -i.cfg.MaxRequestAge {
+		return nil, nil, fmt.Errorf("%w: request time is outside the allowed range", ErrInvalidRequest)
+	}
+
+	if req.Nonce == "" {
+		return nil, nil, fmt.Errorf("%w: nonce is required", ErrInvalidRequest)
+	}
+
+	err = i.cfg.ReplayGuard.UseNonce(ctx, req.Nonce, req.Time.Add(i.cfg.MaxRequestAge))
+	if errors.Is(err, tokens.ErrNonceReplayed) {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return requester, req, nil
+}
+
+// mint creates the claims described by req
+func (i *Issuer) mint(req *TokenRequest) (jwt.Claims, error) {
+	validity := i.cfg.DefaultValidity
+	if req.Validity != "" {
+		var err error
+		validity, err = time.ParseDuration(req.Validity)
+		if err != nil || validity <= 0 {
+			return nil, fmt.Errorf("%w: invalid validity %q", ErrInvalidRequest, req.Validity)
+		}
+	}
+
+	if i.cfg.MaxValidity > 0 && validity > i.cfg.MaxValidity {
+		return nil, fmt.Errorf("%w: validity exceeds %v", ErrRequestDenied, i.cfg.MaxValidity)
+	}
+
+	pk, err := hex.DecodeString(req.PublicKey)
+	if err != nil || len(pk) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("%w: invalid public key", ErrInvalidRequest)
+	}
+
+	var claims jwt.Claims
+
+	switch req.Purpose {
+	case tokens.ClientIDPurpose:
+		claims, err = tokens.NewClientIDClaims(req.Identity, req.Agents, "", req.Properties, "", i.cfg.Issuer, validity, nil, pk, i.cfg.ClaimsOptions...)
+	case tokens.ServerPurpose:
+		claims, err = tokens.NewServerClaims(req.Identity, req.Collectives, "", nil, nil, pk, i.cfg.Issuer, validity, i.cfg.ClaimsOptions...)
+	default:
+		return nil, fmt.Errorf("%w: unsupported purpose %q", ErrInvalidRequest, req.Purpose)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+	}
+
+	return claims, nil
+}
+
+func (i *Issuer) respond(w http.ResponseWriter, code int, res Response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	err := json.NewEncoder(w).Encode(res)
+	if err != nil {
+		i.cfg.Log.Warnf("Could not write response: %v", err)
+	}
+}
@@ -0,0 +1,299 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package httpissuer
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/choria-io/tokens"
+	"github.com/golang-jwt/jwt/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type failingStore struct{}
+
+func (failingStore) MarkSeen(context.Context, string, time.Time) (bool, error) {
+	return false, errors.New("store down")
+}
+
+func TestHTTPIssuer(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "HTTP/Issuer")
+}
+
+var _ = Describe("Issuer", func() {
+	var (
+		issuerPub    ed25519.PublicKey
+		issuerPri    ed25519.PrivateKey
+		callerPri    ed25519.PrivateKey
+		callerToken  string
+		holderPub    ed25519.PublicKey
+		srv          *httptest.Server
+		policy       Policy
+		post         func(body []byte) (int, *Response)
+		tokenRequest func() *TokenRequest
+	)
+
+	BeforeEach(func() {
+		var err error
+		issuerPub, issuerPri, err = ed25519.GenerateKey(nil)
+		Expect(err).ToNot(HaveOccurred())
+		callerPub, pri, err := ed25519.GenerateKey(nil)
+		Expect(err).ToNot(HaveOccurred())
+		callerPri = pri
+		holderPub, _, err = ed25519.GenerateKey(nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		claims, err := tokens.NewClientIDClaims("up=admin", nil, "", nil, "", "", time.Hour, nil, callerPub)
+		Expect(err).ToNot(HaveOccurred())
+		callerToken, err = tokens.SignToken(claims, issuerPri)
+		Expect(err).ToNot(HaveOccurred())
+
+		policy = func(_ context.Context, requester *tokens.ClientIDClaims, req *TokenRequest, claims jwt.Claims) error {
+			if requester.CallerID != "up=admin" || req.Identity == "denied" {
+				return ErrRequestDenied
+			}
+
+			if c, ok := claims.(*tokens.ClientIDClaims); ok {
+				c.UserProperties = map[string]string{"issued_by": requester.CallerID}
+			}
+
+			return nil
+		}
+
+		issuer, err := New(Config{
+			TrustedKey: issuerPub,
+			Signer:     issuerPri,
+			Policy: func(ctx context.Context, r *tokens.ClientIDClaims, req *TokenRequest, c jwt.Claims) error {
+				return policy(ctx, r, req, c)
+			},
+			Issuer:      "choria-aaa",
+			MaxValidity: 24 * time.Hour,
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		srv = httptest.NewServer(issuer)
+		DeferCleanup(srv.Close)
+
+		post = func(body []byte) (int, *Response) {
+			resp, err := http.Post(srv.URL, "application/json", bytes.NewReader(body))
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+
+			res := &Response{}
+			Expect(json.NewDecoder(resp.Body).Decode(res)).To(Succeed())
+
+			return resp.StatusCode, res
+		}
+
+		tokenRequest = func() *TokenRequest {
+			return &TokenRequest{Purpose: tokens.ClientIDPurpose, Identity: "up=bob", PublicKey: hex.EncodeToString(holderPub), Agents: []string{"rpcutil"}, Validity: "2h"}
+		}
+	})
+
+	It("Should validate the configuration", func() {
+		_, err := New(Config{})
+		Expect(err).To(MatchError("key ring or trusted key is required"))
+		_, err = New(Config{TrustedKey: issuerPub, Signer: issuerPri})
+		Expect(err).To(MatchError("policy is required"))
+		_, err = New(Config{TrustedKey: issuerPub, Signer: issuerPri, Policy: policy, MaxValidity: time.Minute})
+		Expect(err).To(MatchError("default validity exceeds the maximum validity"))
+	})
+
+	It("Should issue allowed tokens", func() {
+		sr, err := NewSignedRequest(callerToken, callerPri, tokenRequest())
+		Expect(err).ToNot(HaveOccurred())
+
+		code, res := post(sr)
+		Expect(code).To(Equal(http.StatusOK), res.Error)
+
+		claims, err := tokens.ParseClientIDToken(res.Token, issuerPub, true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(claims.CallerID).To(Equal("up=bob"))
+		Expect(claims.Issuer).To(Equal("choria-aaa"))
+		Expect(claims.PublicKey).To(Equal(hex.EncodeToString(holderPub)))
+		Expect(claims.AllowedAgents).To(Equal([]string{"rpcutil"}))
+		Expect(claims.UserProperties).To(HaveKeyWithValue("issued_by", "up=admin"))
+		Expect(claims.ExpiresAt.Sub(claims.IssuedAt.Time)).To(Equal(2 * time.Hour))
+
+		req := tokenRequest()
+		req.Purpose = tokens.ServerPurpose
+		req.Identity = "n1.example.net"
+		req.Collectives = []string{"choria"}
+		sr, err = NewSignedRequest(callerToken, callerPri, req)
+		Expect(err).ToNot(HaveOccurred())
+
+		code, res = post(sr)
+		Expect(code).To(Equal(http.StatusOK), res.Error)
+		server, err := tokens.ParseServerToken(res.Token, issuerPub)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(server.ChoriaIdentity).To(Equal("n1.example.net"))
+	})
+
+	It("Should refuse invalid requests", func() {
+		sr, err := NewSignedRequest(callerToken, callerPri, tokenRequest())
+		Expect(err).ToNot(HaveOccurred())
+		envelope := &tokens.SignedRequest{}
+		Expect(json.Unmarshal(sr, envelope)).To(Succeed())
+		envelope.Payload[len(envelope.Payload)-2] = ' '
+		tampered, err := json.Marshal(envelope)
+		Expect(err).ToNot(HaveOccurred())
+		code, res := post(tampered)
+		Expect(code).To(Equal(http.StatusBadRequest))
+		Expect(res.Error).To(ContainSubstring(tokens.ErrInvalidRequestSignature.Error()))
+
+		req := tokenRequest()
+		req.Time = time.Now().Add(-time.Hour)
+		sr, err = NewSignedRequest(callerToken, callerPri, req)
+		Expect(err).ToNot(HaveOccurred())
+		code, res = post(sr)
+		Expect(code).To(Equal(http.StatusBadRequest))
+		Expect(res.Error).To(ContainSubstring("request time is outside the allowed range"))
+
+		req = tokenRequest()
+		req.Purpose = tokens.ProvisioningPurpose
+		sr, err = NewSignedRequest(callerToken, callerPri, req)
+		Expect(err).ToNot(HaveOccurred())
+		code, res = post(sr)
+		Expect(code).To(Equal(http.StatusBadRequest))
+		Expect(res.Error).To(ContainSubstring("unsupported purpose"))
+
+		code, _ = post([]byte("{}"))
+		Expect(code).To(Equal(http.StatusBadRequest))
+
+		code, _ = post([]byte("x"))
+		Expect(code).To(Equal(http.StatusBadRequest))
+
+		resp, err := http.Get(srv.URL)
+		Expect(err).ToNot(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusMethodNotAllowed))
+	})
+
+	It("Should accept each request once", func() {
+		sr, err := NewSignedRequest(callerToken, callerPri, tokenRequest())
+		Expect(err).ToNot(HaveOccurred())
+
+		code, res := post(sr)
+		Expect(code).To(Equal(http.StatusOK), res.Error)
+
+		code, res = post(sr)
+		Expect(code).To(Equal(http.StatusBadRequest))
+		Expect(res.Error).To(ContainSubstring(tokens.ErrNonceReplayed.Error()))
+
+		req := tokenRequest()
+		req.Time = time.Now()
+		rj, err := json.Marshal(req)
+		Expect(err).ToNot(HaveOccurred())
+		sr, err = tokens.SignRequest(callerToken, callerPri, append([]byte(requestSigningContext), rj...))
+		Expect(err).ToNot(HaveOccurred())
+		code, res = post(sr)
+		Expect(code).To(Equal(http.StatusBadRequest))
+		Expect(res.Error).To(ContainSubstring("nonce is required"))
+	})
+
+	It("Should check request times using the tokens clock", func() {
+		tokens.SetClock(tokens.FixedClock(time.Now().Add(10 * time.Minute)))
+		DeferCleanup(func() { tokens.SetClock(nil) })
+
+		sr, err := NewSignedRequest(callerToken, callerPri, tokenRequest())
+		Expect(err).ToNot(HaveOccurred())
+		code, res := post(sr)
+		Expect(code).To(Equal(http.StatusOK), res.Error)
+
+		req := tokenRequest()
+		req.Time = time.Now()
+		sr, err = NewSignedRequest(callerToken, callerPri, req)
+		Expect(err).ToNot(HaveOccurred())
+		code, res = post(sr)
+		Expect(code).To(Equal(http.StatusBadRequest))
+		Expect(res.Error).To(ContainSubstring("request time is outside the allowed range"))
+	})
+
+	It("Should only accept signatures made for token requests", func() {
+		req := tokenRequest()
+		req.Time = time.Now()
+		req.Nonce = "n1"
+		rj, err := json.Marshal(req)
+		Expect(err).ToNot(HaveOccurred())
+		sr, err := tokens.SignRequest(callerToken, callerPri, rj)
+		Expect(err).ToNot(HaveOccurred())
+
+		code, res := post(sr)
+		Expect(code).To(Equal(http.StatusBadRequest))
+		Expect(res.Error).To(ContainSubstring("payload is not a token request"))
+	})
+
+	It("Should not disclose internal errors", func() {
+		guard, err := tokens.NewReplayGuard(failingStore{}, time.Minute)
+		Expect(err).ToNot(HaveOccurred())
+		issuer, err := New(Config{TrustedKey: issuerPub, Signer: issuerPri, Policy: policy, ReplayGuard: guard})
+		Expect(err).ToNot(HaveOccurred())
+		failing := httptest.NewServer(issuer)
+		DeferCleanup(failing.Close)
+
+		sr, err := NewSignedRequest(callerToken, callerPri, tokenRequest())
+		Expect(err).ToNot(HaveOccurred())
+		resp, err := http.Post(failing.URL, "application/json", bytes.NewReader(sr))
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+
+		res := &Response{}
+		Expect(json.NewDecoder(resp.Body).Decode(res)).To(Succeed())
+		Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+		Expect(res.Error).To(Equal("Internal Server Error"))
+	})
+
+	It("Should refuse untrusted callers", func() {
+		_, otherPri, err := ed25519.GenerateKey(nil)
+		Expect(err).ToNot(HaveOccurred())
+		claims, err := tokens.NewClientIDClaims("up=admin", nil, "", nil, "", "", time.Hour, nil, callerPri.Public().(ed25519.PublicKey))
+		Expect(err).ToNot(HaveOccurred())
+		token, err := tokens.SignToken(claims, otherPri)
+		Expect(err).ToNot(HaveOccurred())
+
+		sr, err := NewSignedRequest(token, callerPri, tokenRequest())
+		Expect(err).ToNot(HaveOccurred())
+		code, _ := post(sr)
+		Expect(code).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("Should apply the policy", func() {
+		req := tokenRequest()
+		req.Identity = "denied"
+		sr, err := NewSignedRequest(callerToken, callerPri, req)
+		Expect(err).ToNot(HaveOccurred())
+		code, res := post(sr)
+		Expect(code).To(Equal(http.StatusForbidden))
+		Expect(res.Error).To(Equal(ErrRequestDenied.Error()))
+
+		policy = func(context.Context, *tokens.ClientIDClaims, *TokenRequest, jwt.Claims) error {
+			return fmt.Errorf("agents not allowed")
+		}
+		sr, err = NewSignedRequest(callerToken, callerPri, tokenRequest())
+		Expect(err).ToNot(HaveOccurred())
+		code, res = post(sr)
+		Expect(code).To(Equal(http.StatusForbidden))
+		Expect(res.Error).To(Equal("token request denied: agents not allowed"))
+
+		req = tokenRequest()
+		req.Validity = "48h"
+		sr, err = NewSignedRequest(callerToken, callerPri, req)
+		Expect(err).ToNot(HaveOccurred())
+		code, _ = post(sr)
+		Expect(code).To(Equal(http.StatusForbidden))
+	})
+})
@@ -0,0 +1,150 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// ErrImpersonationNotPermitted indicates a token acts on behalf of a user without holding the AuthenticationDelegator permission
+var ErrImpersonationNotPermitted = errors.New("token may not act on behalf of other users")
+
+// OnBehalfOf identifies the end user a privileged service token is acting for
+type OnBehalfOf struct {
+	// CallerID is the caller id of the end user
+	CallerID string `json:"callerid"`
+
+	// Reason is why the service is acting for the user, recorded for auditing
+	Reason string `json:"reason,omitempty"`
+
+	// RequestID correlates the token with the request received by the service
+	RequestID string `json:"request_id,omitempty"`
+
+	// AuthenticatedAt is when the service authenticated the end user
+	AuthenticatedAt *jwt.NumericDate `json:"auth_time,omitempty"`
+}
+
+// Impersonation is an audit record describing a verified token that acts on behalf of an end user
+type Impersonation struct {
+	// Actor is the caller id of the service holding the token
+	Actor string `json:"actor"`
+
+	// Subject is the caller id of the end user the service acts for
+	Subject string `json:"subject"`
+
+	// TokenID is the id of the token
+	TokenID string `json:"jti,omitempty"`
+
+	// Issuer is the issuer of the token
+	Issuer string `json:"iss,omitempty"`
+
+	// Reason is why the service is acting for the user
+	Reason string `json:"reason,omitempty"`
+
+	// RequestID correlates the token with the request received by the service
+	RequestID string `json:"request_id,omitempty"`
+
+	// AuthenticatedAt is when the service authenticated the end user
+	AuthenticatedAt time.Time `json:"auth_time,omitempty"`
+}
+
+func (o *OnBehalfOf) validate(actor string) error {
+	if o.CallerID == "" {
+		return fmt.Errorf("on behalf of caller id is required")
+	}
+
+	if o.CallerID == actor {
+		return fmt.Errorf("tokens cannot act on behalf of their own caller id")
+	}
+
+	return nil
+}
+
+// SetOnBehalfOf records that the token acts for the end user in obo, the token must hold the AuthenticationDelegator permission
+func (c *ClientIDClaims) SetOnBehalfOf(obo OnBehalfOf) error {
+	prev := c.OnBehalfOf
+	c.OnBehalfOf = &obo
+
+	err := c.validateImpersonation()
+	if err != nil {
+		c.OnBehalfOf = prev
+		return err
+	}
+
+	return nil
+}
+
+// IsImpersonating determines if the token acts on behalf of an end user
+func (c *ClientIDClaims) IsImpersonating() bool {
+	return c.OnBehalfOf != nil
+}
+
+// EffectiveCallerID is the caller id of the end user when acting on their behalf, else the caller id of the token
+func (c *ClientIDClaims) EffectiveCallerID() string {
+	if c.OnBehalfOf != nil {
+		return c.OnBehalfOf.CallerID
+	}
+
+	return c.CallerID
+}
+
+// Impersonation creates an audit record exposing both the service and end user identities, nil when not impersonating
+func (c *ClientIDClaims) Impersonation() *Impersonation {
+	if c.OnBehalfOf == nil {
+		return nil
+	}
+
+	record := &Impersonation{
+		Actor:     c.CallerID,
+		Subject:   c.OnBehalfOf.CallerID,
+		TokenID:   c.ID,
+		Issuer:    c.Issuer,
+		Reason:    c.OnBehalfOf.Reason,
+		RequestID: c.OnBehalfOf.RequestID,
+	}
+
+	if c.OnBehalfOf.AuthenticatedAt != nil {
+		record.AuthenticatedAt = c.OnBehalfOf.AuthenticatedAt.Time
+	}
+
+	return record
+}
+
+// validateImpersonation ensures only tokens holding the AuthenticationDelegator permission act for other users
+func (c *ClientIDClaims) validateImpersonation() error {
+	if c.OnBehalfOf == nil {
+		return nil
+	}
+
+	err := c.OnBehalfOf.validate(c.CallerID)
+	if err != nil {
+		return err
+	}
+
+	if c.Permissions == nil || !c.Permissions.AuthenticationDelegator {
+		return ErrImpersonationNotPermitted
+	}
+
+	return nil
+}
+
+// ParseImpersonatingClientIDToken verifies a client token using pk and requires that it acts on behalf of an end user,
+// the returned record exposes both identities for auditing
+func ParseImpersonatingClientIDToken(token string, pk any, opts ...ParseOption) (*ClientIDClaims, *Impersonation, error) {
+	claims, err := ParseClientIDToken(token, pk, true, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !claims.IsImpersonating() {
+		return nil, nil, fmt.Errorf("token does not act on behalf of a user")
+	}
+
+	return claims, claims.Impersonation(), nil
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Impersonation", func() {
+	var (
+		pubK    ed25519.PublicKey
+		priK    ed25519.PrivateKey
+		service *ClientIDClaims
+	)
+
+	BeforeEach(func() {
+		var err error
+		pubK, priK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		service, err = NewClientIDClaims("svc=aaa", []string{"*"}, "", nil, "", "ginkgo", time.Hour, &ClientPermissions{AuthenticationDelegator: true, FleetManagement: true}, nil)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Describe("SetOnBehalfOf", func() {
+		It("Should validate the end user", func() {
+			Expect(service.SetOnBehalfOf(OnBehalfOf{})).To(MatchError("on behalf of caller id is required"))
+			Expect(service.SetOnBehalfOf(OnBehalfOf{CallerID: "svc=aaa"})).To(MatchError("tokens cannot act on behalf of their own caller id"))
+			Expect(service.IsImpersonating()).To(BeFalse())
+		})
+
+		It("Should require the delegator permission", func() {
+			service.Permissions.AuthenticationDelegator = false
+			Expect(service.SetOnBehalfOf(OnBehalfOf{CallerID: "up=bob"})).To(MatchError(ErrImpersonationNotPermitted))
+			Expect(service.OnBehalfOf).To(BeNil())
+
+			service.Permissions = nil
+			Expect(service.SetOnBehalfOf(OnBehalfOf{CallerID: "up=bob"})).To(MatchError(ErrImpersonationNotPermitted))
+		})
+
+		It("Should expose both identities", func() {
+			Expect(service.EffectiveCallerID()).To(Equal("svc=aaa"))
+			Expect(service.Impersonation()).To(BeNil())
+
+			auth := time.Now().Add(-time.Minute).Truncate(time.Second)
+			Expect(service.SetOnBehalfOf(OnBehalfOf{CallerID: "up=bob", Reason: "web ui", RequestID: "r1", AuthenticatedAt: jwt.NewNumericDate(auth)})).To(Succeed())
+			Expect(service.IsImpersonating()).To(BeTrue())
+			Expect(service.EffectiveCallerID()).To(Equal("up=bob"))
+
+			id, _ := service.UniqueID()
+			Expect(id).To(Equal("svc=aaa"))
+
+			record := service.Impersonation()
+			Expect(record.Actor).To(Equal("svc=aaa"))
+			Expect(record.Subject).To(Equal("up=bob"))
+			Expect(record.TokenID).To(Equal(service.ID))
+			Expect(record.Issuer).To(Equal("ginkgo"))
+			Expect(record.Reason).To(Equal("web ui"))
+			Expect(record.RequestID).To(Equal("r1"))
+			Expect(record.AuthenticatedAt.Equal(auth)).To(BeTrue())
+		})
+	})
+
+	Describe("Verification", func() {
+		It("Should verify impersonating tokens", func() {
+			Expect(service.SetOnBehalfOf(OnBehalfOf{CallerID: "up=bob", Reason: "web ui"})).To(Succeed())
+			token, err := SignToken(service, priK)
+			Expect(err).ToNot(HaveOccurred())
+
+			claims, record, err := ParseImpersonatingClientIDToken(token, pubK)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(claims.CallerID).To(Equal("svc=aaa"))
+			Expect(record.Subject).To(Equal("up=bob"))
+			Expect(record.Actor).To(Equal("svc=aaa"))
+		})
+
+		It("Should require impersonation", func() {
+			token, err := SignToken(service, priK)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, _, err = ParseImpersonatingClientIDToken(token, pubK)
+			Expect(err).To(MatchError("token does not act on behalf of a user"))
+		})
+
+		It("Should reject tokens that impersonate without permission", func() {
+			service.Permissions.AuthenticationDelegator = false
+			service.OnBehalfOf = &OnBehalfOf{CallerID: "up=bob"}
+			token, err := SignToken(service, priK)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = ParseClientIDToken(token, pubK, true)
+			Expect(err).To(MatchError(ErrImpersonationNotPermitted))
+		})
+	})
+
+	Describe("Builder", func() {
+		It("Should set the end user", func() {
+			claims, err := NewClientIDBuilder("svc=aaa").
+				WithPermissions(&ClientPermissions{AuthenticationDelegator: true}).
+				WithValidity(time.Hour).
+				WithOnBehalfOf(OnBehalfOf{CallerID: "up=bob"}).
+				Build()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(claims.EffectiveCallerID()).To(Equal("up=bob"))
+
+			_, err = NewClientIDBuilder("svc=aaa").
+				WithValidity(time.Hour).
+				WithOnBehalfOf(OnBehalfOf{CallerID: "up=bob"}).
+				Build()
+			Expect(err).To(MatchError(ErrImpersonationNotPermitted))
+		})
+	})
+})
@@ -0,0 +1,82 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package lru is a small size bounded least recently used cache shared by the policy evaluators
+package lru
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+)
+
+// Cache holds up to a fixed number of values, the least recently used value is evicted when full
+type Cache[V any] struct {
+	size    int
+	entries map[string]*list.Element
+	order   *list.List
+	mu      sync.Mutex
+}
+
+type entry[V any] struct {
+	key   string
+	value V
+}
+
+// New creates a cache holding up to size values
+func New[V any](size int) (*Cache[V], error) {
+	if size < 1 {
+		return nil, fmt.Errorf("cache size must be positive")
+	}
+
+	return &Cache[V]{
+		size:    size,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}, nil
+}
+
+// Get retrieves the value stored for key and marks it as recently used
+func (c *Cache[V]) Get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+
+	c.order.MoveToFront(el)
+
+	return el.Value.(*entry[V]).value, true
+}
+
+// Add stores value for key, evicting the least recently used value when the cache is full
+func (c *Cache[V]) Add(key string, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		el.Value.(*entry[V]).value = value
+		c.order.MoveToFront(el)
+		return
+	}
+
+	if c.order.Len() >= c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*entry[V]).key)
+	}
+
+	c.entries[key] = c.order.PushFront(&entry[V]{key: key, value: value})
+}
+
+// Len is the number of values in the cache
+func (c *Cache[V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package lru
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestLRU(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Internal/LRU")
+}
+
+var _ = Describe("Cache", func() {
+	It("Should require a positive size", func() {
+		_, err := New[int](0)
+		Expect(err).To(MatchError("cache size must be positive"))
+	})
+
+	It("Should evict the least recently used value", func() {
+		c, err := New[int](2)
+		Expect(err).ToNot(HaveOccurred())
+
+		get := func(key string) int {
+			v, ok := c.Get(key)
+			Expect(ok).To(BeTrue())
+			return v
+		}
+
+		c.Add("a", 1)
+		c.Add("b", 2)
+		Expect(get("a")).To(Equal(1))
+
+		c.Add("c", 3)
+		Expect(c.Len()).To(Equal(2))
+
+		_, ok := c.Get("b")
+		Expect(ok).To(BeFalse())
+		Expect(get("a")).To(Equal(1))
+		Expect(get("c")).To(Equal(3))
+
+		c.Add("a", 10)
+		Expect(get("a")).To(Equal(10))
+		Expect(c.Len()).To(Equal(2))
+	})
+})
@@ -0,0 +1,230 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !js
+
+package tokens
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultIntrospectionTimeout is how long introspection requests may take when no timeout is configured
+const DefaultIntrospectionTimeout = 5 * time.Second
+
+// maxIntrospectionErrorBody is how much of a failed introspection response is included in errors
+const maxIntrospectionErrorBody = 256
+
+// IntrospectionFailurePolicy determines how tokens are treated when the introspection endpoint cannot be queried
+type IntrospectionFailurePolicy string
+
+const (
+	// IntrospectionFailClosed rejects tokens when introspection fails
+	IntrospectionFailClosed IntrospectionFailurePolicy = "closed"
+
+	// IntrospectionFailOpen accepts tokens when introspection fails
+	IntrospectionFailOpen IntrospectionFailurePolicy = "open"
+)
+
+// IntrospectionConfig configures a RFC 7662 token introspection client
+type IntrospectionConfig struct {
+	// URL is the introspection endpoint tokens are POSTed to
+	URL string
+
+	// ClientID and ClientSecret authenticate the verifier to the endpoint using HTTP basic authentication when set
+	ClientID     string
+	ClientSecret string
+
+	// BearerToken authenticates the verifier to the endpoint using a bearer token when set
+	BearerToken string
+
+	// TokenTypeHint is sent as the token_type_hint parameter when set
+	TokenTypeHint string
+
+	// Timeout is how long a request may take, defaults to DefaultIntrospectionTimeout
+	Timeout time.Duration
+
+	// FailurePolicy determines if tokens are accepted when introspection fails, defaults to IntrospectionFailClosed
+	FailurePolicy IntrospectionFailurePolicy
+
+	// TLSConfig is used to connect to the endpoint when set and Client is not set
+	TLSConfig *tls.Config
+
+	// Client is the HTTP client to use, overrides TLSConfig
+	Client *http.Client
+
+	// Log receives messages about tokens accepted due to the fail-open policy, can be nil
+	Log *logrus.Entry
+}
+
+// IntrospectionResponse is the response from a RFC 7662 introspection endpoint
+type IntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	NotBefore int64  `json:"nbf,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	Issuer    string `json:"iss,omitempty"`
+	ID        string `json:"jti,omitempty"`
+}
+
+// Introspector checks the status of tokens against a RFC 7662 introspection endpoint
+type Introspector struct {
+	cfg    IntrospectionConfig
+	client *http.Client
+}
+
+// NewIntrospector creates a new introspection client
+func NewIntrospector(cfg IntrospectionConfig) (*Introspector, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("introspection url is required")
+	}
+
+	uri, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid introspection url: %w", err)
+	}
+	if uri.Scheme != "http" && uri.Scheme != "https" {
+		return nil, fmt.Errorf("invalid introspection url: unsupported scheme %q", uri.Scheme)
+	}
+
+	if cfg.Timeout < 0 {
+		return nil, fmt.Errorf("introspection timeout cannot be negative")
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = DefaultIntrospectionTimeout
+	}
+
+	switch cfg.FailurePolicy {
+	case "":
+		cfg.FailurePolicy = IntrospectionFailClosed
+	case IntrospectionFailClosed, IntrospectionFailOpen:
+	default:
+		return nil, fmt.Errorf("invalid introspection failure policy %q", cfg.FailurePolicy)
+	}
+
+	client := cfg.Client
+	if client == nil {
+		client = &http.Client{}
+		if cfg.TLSConfig != nil {
+			client.Transport = &http.Transport{TLSClientConfig: cfg.TLSConfig}
+		}
+	}
+
+	return &Introspector{cfg: cfg, client: client}, nil
+}
+
+// Introspect queries the endpoint for the status of token
+func (i *Introspector) Introspect(ctx context.Context, token string) (*IntrospectionResponse, error) {
+	ctx, span := startSpan(ctx, "tokens.introspect")
+	res, err := i.introspect(ctx, token)
+	span.End(err)
+
+	return res, err
+}
+
+func (i *Introspector) introspect(ctx context.Context, token string) (*IntrospectionResponse, error) {
+	timeout, cancel := context.WithTimeout(ctx, i.cfg.Timeout)
+	defer cancel()
+
+	form := url.Values{}
+	form.Set("token", token)
+	if i.cfg.TokenTypeHint != "" {
+		form.Set("token_type_hint", i.cfg.TokenTypeHint)
+	}
+
+	req, err := http.NewRequestWithContext(timeout, "POST", i.cfg.URL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	switch {
+	case i.cfg.ClientID != "":
+		req.SetBasicAuth(i.cfg.ClientID, i.cfg.ClientSecret)
+	case i.cfg.BearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+i.cfg.BearerToken)
+	}
+
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("request failed: code: %d: %s", resp.StatusCode, truncatedBody(body))
+	}
+
+	var ir IntrospectionResponse
+	err = json.Unmarshal(body, &ir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid introspection response: %w", err)
+	}
+
+	return &ir, nil
+}
+
+// check introspects token and applies the failure policy, nil is returned for active tokens
+func (i *Introspector) check(ctx context.Context, token string) error {
+	ir, err := i.Introspect(ctx, token)
+	if err != nil {
+		if i.cfg.FailurePolicy == IntrospectionFailOpen {
+			if i.cfg.Log != nil {
+				i.cfg.Log.Warnf("Accepting token after introspection failed: %v", err)
+			}
+			return nil
+		}
+
+		return fmt.Errorf("%w: %w", ErrIntrospectionFailed, err)
+	}
+
+	if !ir.Active {
+		return ErrTokenInactive
+	}
+
+	return nil
+}
+
+// WithIntrospection rejects tokens that the introspection endpoint does not report as active, this is done after all other checks passed
+func WithIntrospection(introspector *Introspector) ParseOption {
+	return func(o *parseOptions) error {
+		if introspector == nil {
+			return fmt.Errorf("introspector is required")
+		}
+
+		o.introspect = introspector
+
+		return nil
+	}
+}
+
+// truncatedBody is the start of body for inclusion in errors
+func truncatedBody(body []byte) string {
+	if len(body) <= maxIntrospectionErrorBody {
+		return string(body)
+	}
+
+	return strings.ToValidUTF8(string(body[:maxIntrospectionErrorBody]), "") + "..."
+}
@@ -0,0 +1,189 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !js
+
+package tokens
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Introspection", func() {
+	var (
+		pubK   ed25519.PublicKey
+		priK   ed25519.PrivateKey
+		srv    *httptest.Server
+		active bool
+		status int
+		delay  time.Duration
+		seen   *http.Request
+		form   map[string]string
+	)
+
+	BeforeEach(func() {
+		var err error
+		pubK, priK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		active = true
+		status = 200
+		delay = 0
+		form = map[string]string{}
+
+		srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			seen = r
+			if r.ParseForm() == nil {
+				for k := range r.PostForm {
+					form[k] = r.PostForm.Get(k)
+				}
+			}
+
+			time.Sleep(delay)
+
+			w.WriteHeader(status)
+			json.NewEncoder(w).Encode(map[string]any{"active": active, "sub": "up=bob"})
+		}))
+		DeferCleanup(srv.Close)
+	})
+
+	signedToken := func() string {
+		claims, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		token, err := SignToken(claims, priK)
+		Expect(err).ToNot(HaveOccurred())
+		return token
+	}
+
+	Describe("NewIntrospector", func() {
+		It("Should validate the configuration", func() {
+			_, err := NewIntrospector(IntrospectionConfig{})
+			Expect(err).To(MatchError("introspection url is required"))
+
+			_, err = NewIntrospector(IntrospectionConfig{URL: "ftp://example.net"})
+			Expect(err).To(MatchError(`invalid introspection url: unsupported scheme "ftp"`))
+
+			_, err = NewIntrospector(IntrospectionConfig{URL: srv.URL, Timeout: -1})
+			Expect(err).To(MatchError("introspection timeout cannot be negative"))
+
+			_, err = NewIntrospector(IntrospectionConfig{URL: srv.URL, FailurePolicy: "sideways"})
+			Expect(err).To(MatchError(`invalid introspection failure policy "sideways"`))
+
+			i, err := NewIntrospector(IntrospectionConfig{URL: srv.URL})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(i.cfg.Timeout).To(Equal(DefaultIntrospectionTimeout))
+			Expect(i.cfg.FailurePolicy).To(Equal(IntrospectionFailClosed))
+		})
+	})
+
+	Describe("Introspect", func() {
+		It("Should post the token and parse the response", func() {
+			i, err := NewIntrospector(IntrospectionConfig{URL: srv.URL, ClientID: "verifier", ClientSecret: "s3cret", TokenTypeHint: "access_token"})
+			Expect(err).ToNot(HaveOccurred())
+
+			ir, err := i.Introspect(context.Background(), "the.token.here")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ir.Active).To(BeTrue())
+			Expect(ir.Subject).To(Equal("up=bob"))
+
+			Expect(seen.Method).To(Equal("POST"))
+			Expect(seen.Header.Get("Content-Type")).To(Equal("application/x-www-form-urlencoded"))
+			user, pass, ok := seen.BasicAuth()
+			Expect(ok).To(BeTrue())
+			Expect(user).To(Equal("verifier"))
+			Expect(pass).To(Equal("s3cret"))
+			Expect(form).To(Equal(map[string]string{"token": "the.token.here", "token_type_hint": "access_token"}))
+		})
+
+		It("Should support bearer authentication", func() {
+			i, err := NewIntrospector(IntrospectionConfig{URL: srv.URL, BearerToken: "abc"})
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = i.Introspect(context.Background(), "x")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(seen.Header.Get("Authorization")).To(Equal("Bearer abc"))
+		})
+
+		It("Should fail on unexpected status codes", func() {
+			status = 500
+			i, err := NewIntrospector(IntrospectionConfig{URL: srv.URL})
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = i.Introspect(context.Background(), "x")
+			Expect(err).To(MatchError(ContainSubstring("request failed: code: 500")))
+		})
+
+		It("Should truncate failed responses in errors", func() {
+			large := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(500)
+				w.Write([]byte(strings.Repeat("x", 4096)))
+			}))
+			DeferCleanup(large.Close)
+
+			i, err := NewIntrospector(IntrospectionConfig{URL: large.URL})
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = i.Introspect(context.Background(), "x")
+			Expect(err).To(MatchError("request failed: code: 500: " + strings.Repeat("x", maxIntrospectionErrorBody) + "..."))
+		})
+	})
+
+	Describe("WithIntrospection", func() {
+		It("Should require an introspector", func() {
+			err := ParseToken(signedToken(), &ClientIDClaims{}, pubK, WithIntrospection(nil))
+			Expect(err).To(MatchError("introspector is required"))
+		})
+
+		It("Should accept active tokens and reject inactive ones", func() {
+			i, err := NewIntrospector(IntrospectionConfig{URL: srv.URL})
+			Expect(err).ToNot(HaveOccurred())
+
+			token := signedToken()
+			Expect(ParseToken(token, &ClientIDClaims{}, pubK, WithIntrospection(i))).To(Succeed())
+			Expect(form["token"]).To(Equal(token))
+
+			active = false
+			err = ParseToken(token, &ClientIDClaims{}, pubK, WithIntrospection(i))
+			Expect(err).To(MatchError(ErrTokenInactive))
+		})
+
+		It("Should not introspect tokens that fail verification", func() {
+			i, err := NewIntrospector(IntrospectionConfig{URL: srv.URL})
+			Expect(err).ToNot(HaveOccurred())
+
+			otherPubK, _, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+
+			seen = nil
+			err = ParseToken(signedToken(), &ClientIDClaims{}, otherPubK, WithIntrospection(i))
+			Expect(err).To(HaveOccurred())
+			Expect(seen).To(BeNil())
+		})
+
+		It("Should apply the failure policy on timeouts", func() {
+			delay = 200 * time.Millisecond
+			token := signedToken()
+
+			closed, err := NewIntrospector(IntrospectionConfig{URL: srv.URL, Timeout: 20 * time.Millisecond})
+			Expect(err).ToNot(HaveOccurred())
+			err = ParseToken(token, &ClientIDClaims{}, pubK, WithIntrospection(closed))
+			Expect(err).To(MatchError(ErrIntrospectionFailed))
+			Expect(err).To(MatchError(context.DeadlineExceeded))
+
+			open, err := NewIntrospector(IntrospectionConfig{URL: srv.URL, Timeout: 20 * time.Millisecond, FailurePolicy: IntrospectionFailOpen})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ParseToken(token, &ClientIDClaims{}, pubK, WithIntrospection(open))).To(Succeed())
+		})
+	})
+})
@@ -0,0 +1,270 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"filippo.io/edwards25519"
+)
+
+// ErrDecryptionFailed indicates an encrypted token could not be decrypted
+var ErrDecryptionFailed = errors.New("could not decrypt token")
+
+const (
+	jweAlgECDHES   = "ECDH-ES"
+	jweAlgRSAOAEP  = "RSA-OAEP-256"
+	jweEncA256GCM  = "A256GCM"
+	jweKeySize     = 32
+	jweContentType = "JWT"
+)
+
+type jweEphemeralKey struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+}
+
+type jweHeader struct {
+	Alg string           `json:"alg"`
+	Enc string           `json:"enc"`
+	Cty string           `json:"cty"`
+	Epk *jweEphemeralKey `json:"epk,omitempty"`
+}
+
+// IsEncryptedToken determines if token is a compact JWE as produced by EncryptToken
+func IsEncryptedToken(token string) bool {
+	return strings.Count(strings.TrimSpace(token), ".") == 4
+}
+
+// EncryptToken wraps a signed token in a compact JWE for recipient, a ed25519.PublicKey that is converted to X25519 for
+// ECDH-ES key agreement or a *rsa.PublicKey used with RSA-OAEP-256. Content is encrypted using A256GCM
+func EncryptToken(token string, recipient any) (string, error) {
+	hdr := jweHeader{Enc: jweEncA256GCM, Cty: jweContentType}
+	var cek, encryptedKey []byte
+
+	switch pk := recipient.(type) {
+	case ed25519.PublicKey:
+		err := ValidateEd25519PublicKey(pk)
+		if err != nil {
+			return "", err
+		}
+
+		remote, err := ed25519PublicToX25519(pk)
+		if err != nil {
+			return "", err
+		}
+
+		ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+		if err != nil {
+			return "", err
+		}
+
+		z, err := ephemeral.ECDH(remote)
+		if err != nil {
+			return "", err
+		}
+
+		hdr.Alg = jweAlgECDHES
+		hdr.Epk = &jweEphemeralKey{Kty: "OKP", Crv: "X25519", X: base64.RawURLEncoding.EncodeToString(ephemeral.PublicKey().Bytes())}
+		cek = jweConcatKDF(z, jweEncA256GCM)
+
+	case *rsa.PublicKey:
+		err := validateRSAPublicKey(pk)
+		if err != nil {
+			return "", err
+		}
+
+		cek = make([]byte, jweKeySize)
+		_, err = rand.Read(cek)
+		if err != nil {
+			return "", err
+		}
+
+		encryptedKey, err = rsa.EncryptOAEP(sha256.New(), rand.Reader, pk, cek, nil)
+		if err != nil {
+			return "", err
+		}
+
+		hdr.Alg = jweAlgRSAOAEP
+
+	default:
+		return "", fmt.Errorf("unsupported public key type %T", recipient)
+	}
+
+	hj, err := json.Marshal(hdr)
+	if err != nil {
+		return "", err
+	}
+	protected := base64.RawURLEncoding.EncodeToString(hj)
+
+	gcm, err := jweCipher(cek)
+	if err != nil {
+		return "", err
+	}
+
+	iv := make([]byte, gcm.NonceSize())
+	_, err = rand.Read(iv)
+	if err != nil {
+		return "", err
+	}
+
+	sealed := gcm.Seal(nil, iv, []byte(strings.TrimSpace(token)), []byte(protected))
+	ct, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+
+	enc := base64.RawURLEncoding.EncodeToString
+	return strings.Join([]string{protected, enc(encryptedKey), enc(iv), enc(ct), enc(tag)}, "."), nil
+}
+
+// DecryptToken decrypts a token produced by EncryptToken using key, the ed25519.PrivateKey or *rsa.PrivateKey of the
+// recipient. The returned token still has to be verified using ParseToken
+func DecryptToken(jwe string, key any) (string, error) {
+	parts := strings.Split(strings.TrimSpace(jwe), ".")
+	if len(parts) != 5 {
+		return "", fmt.Errorf("%w: invalid compact jwe", ErrDecryptionFailed)
+	}
+
+	var raw [5][]byte
+	for i, p := range parts {
+		b, err := base64.RawURLEncoding.DecodeString(p)
+		if err != nil {
+			return "", fmt.Errorf("%w: invalid jwe encoding: %w", ErrDecryptionFailed, err)
+		}
+		raw[i] = b
+	}
+
+	var hdr jweHeader
+	err := json.Unmarshal(raw[0], &hdr)
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid jwe header: %w", ErrDecryptionFailed, err)
+	}
+
+	if hdr.Enc != jweEncA256GCM {
+		return "", fmt.Errorf("%w: unsupported content encryption %q", ErrDecryptionFailed, hdr.Enc)
+	}
+
+	var cek []byte
+
+	switch pk := key.(type) {
+	case ed25519.PrivateKey:
+		if hdr.Alg != jweAlgECDHES || hdr.Epk == nil || hdr.Epk.Crv != "X25519" {
+			return "", fmt.Errorf("%w: unsupported key management %q for ed25519 keys", ErrDecryptionFailed, hdr.Alg)
+		}
+		if len(pk) != ed25519.PrivateKeySize {
+			return "", fmt.Errorf("invalid private key size")
+		}
+
+		epk, err := base64.RawURLEncoding.DecodeString(hdr.Epk.X)
+		if err != nil {
+			return "", fmt.Errorf("%w: invalid ephemeral key: %w", ErrDecryptionFailed, err)
+		}
+
+		remote, err := ecdh.X25519().NewPublicKey(epk)
+		if err != nil {
+			return "", fmt.Errorf("%w: invalid ephemeral key: %w", ErrDecryptionFailed, err)
+		}
+
+		local, err := ed25519PrivateToX25519(pk)
+		if err != nil {
+			return "", err
+		}
+
+		z, err := local.ECDH(remote)
+		if err != nil {
+			return "", fmt.Errorf("%w: %w", ErrDecryptionFailed, err)
+		}
+
+		cek = jweConcatKDF(z, hdr.Enc)
+
+	case *rsa.PrivateKey:
+		if hdr.Alg != jweAlgRSAOAEP {
+			return "", fmt.Errorf("%w: unsupported key management %q for rsa keys", ErrDecryptionFailed, hdr.Alg)
+		}
+
+		cek, err = rsa.DecryptOAEP(sha256.New(), nil, pk, raw[1], nil)
+		if err != nil || len(cek) != jweKeySize {
+			return "", fmt.Errorf("%w: invalid encrypted key", ErrDecryptionFailed)
+		}
+
+	default:
+		return "", fmt.Errorf("unsupported private key type %T", key)
+	}
+
+	gcm, err := jweCipher(cek)
+	if err != nil {
+		return "", err
+	}
+
+	if len(raw[2]) != gcm.NonceSize() {
+		return "", fmt.Errorf("%w: invalid initialization vector", ErrDecryptionFailed)
+	}
+
+	plain, err := gcm.Open(nil, raw[2], append(raw[3], raw[4]...), []byte(parts[0]))
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrDecryptionFailed, err)
+	}
+
+	return string(plain), nil
+}
+
+func jweCipher(cek []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// jweConcatKDF derives the content encryption key from the shared secret z as described in RFC 7518 section 4.6.2
+// for direct key agreement without party information
+func jweConcatKDF(z []byte, enc string) []byte {
+	h := sha256.New()
+
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], 1)
+	h.Write(buf[:])
+	h.Write(z)
+	binary.BigEndian.PutUint32(buf[:], uint32(len(enc)))
+	h.Write(buf[:])
+	h.Write([]byte(enc))
+	binary.BigEndian.PutUint32(buf[:], 0)
+	h.Write(buf[:]) // PartyUInfo
+	h.Write(buf[:]) // PartyVInfo
+	binary.BigEndian.PutUint32(buf[:], jweKeySize*8)
+	h.Write(buf[:])
+
+	return h.Sum(nil)
+}
+
+// ed25519PublicToX25519 converts an ed25519 public key to the equivalent X25519 public key
+func ed25519PublicToX25519(pk ed25519.PublicKey) (*ecdh.PublicKey, error) {
+	point, err := new(edwards25519.Point).SetBytes(pk)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+
+	return ecdh.X25519().NewPublicKey(point.BytesMontgomery())
+}
+
+// ed25519PrivateToX25519 converts an ed25519 private key to the equivalent X25519 private key
+func ed25519PrivateToX25519(pk ed25519.PrivateKey) (*ecdh.PrivateKey, error) {
+	h := sha512.Sum512(pk.Seed())
+
+	return ecdh.X25519().NewPrivateKey(h[:32])
+}
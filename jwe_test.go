@@ -0,0 +1,96 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("JWE", func() {
+	var (
+		pubK  ed25519.PublicKey
+		priK  ed25519.PrivateKey
+		token string
+	)
+
+	BeforeEach(func() {
+		var err error
+		pubK, priK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		prov, err := NewProvisioningClaims(true, true, "secret", "", "", nil, "example.net", "", "", "choria", "ginkgo", time.Hour)
+		Expect(err).ToNot(HaveOccurred())
+		token, err = SignToken(prov, priK)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Should encrypt for ed25519 recipients", func() {
+		recipientPubK, recipientPriK, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		enc, err := EncryptToken(token, recipientPubK)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(IsEncryptedToken(enc)).To(BeTrue())
+		Expect(IsEncryptedToken(token)).To(BeFalse())
+		Expect(enc).ToNot(ContainSubstring(strings.Split(token, ".")[1]))
+
+		hdr, err := base64.RawURLEncoding.DecodeString(strings.Split(enc, ".")[0])
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(hdr)).To(ContainSubstring(`"alg":"ECDH-ES"`))
+		Expect(string(hdr)).To(ContainSubstring(`"crv":"X25519"`))
+
+		dec, err := DecryptToken(enc, recipientPriK)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(dec).To(Equal(token))
+
+		_, err = ParseProvisioningToken(dec, pubK)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = DecryptToken(enc, priK)
+		Expect(err).To(MatchError(ErrDecryptionFailed))
+	})
+
+	It("Should encrypt for RSA recipients", func() {
+		enc, err := EncryptToken(token, loadRSAPubKey("testdata/rsa/signer-public.pem"))
+		Expect(err).ToNot(HaveOccurred())
+
+		dec, err := DecryptToken(enc, loadRSAPriKey("testdata/rsa/signer-key.pem"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(dec).To(Equal(token))
+
+		_, err = DecryptToken(enc, loadRSAPriKey("testdata/rsa/other-key.pem"))
+		Expect(err).To(MatchError(ErrDecryptionFailed))
+
+		_, err = DecryptToken(enc, priK)
+		Expect(err).To(MatchError(ContainSubstring(`unsupported key management "RSA-OAEP-256" for ed25519 keys`)))
+	})
+
+	It("Should detect tampering", func() {
+		enc, err := EncryptToken(token, pubK)
+		Expect(err).ToNot(HaveOccurred())
+
+		parts := strings.Split(enc, ".")
+		ct, err := base64.RawURLEncoding.DecodeString(parts[3])
+		Expect(err).ToNot(HaveOccurred())
+		ct[0] ^= 0xff
+		parts[3] = base64.RawURLEncoding.EncodeToString(ct)
+
+		_, err = DecryptToken(strings.Join(parts, "."), priK)
+		Expect(err).To(MatchError(ErrDecryptionFailed))
+
+		_, err = DecryptToken(token, priK)
+		Expect(err).To(MatchError(ContainSubstring("invalid compact jwe")))
+
+		_, err = EncryptToken(token, "x")
+		Expect(err).To(MatchError("unsupported public key type string"))
+	})
+})
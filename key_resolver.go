@@ -0,0 +1,51 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// KeyResolver selects the key to verify a token with, for example by kid header, issuer or organization in multi
+// tenant brokers. It is passed to ParseToken in place of a key and receives the header and claims before they are
+// verified, so they must only be used to look up keys
+type KeyResolver func(header map[string]any, unverifiedClaims jwt.MapClaims) (any, error)
+
+// resolveKey returns pk, or the key selected by pk when it is a KeyResolver
+func resolveKey(token string, pk any) (any, error) {
+	var resolver KeyResolver
+
+	switch r := pk.(type) {
+	case KeyResolver:
+		resolver = r
+	case func(map[string]any, jwt.MapClaims) (any, error):
+		resolver = r
+	default:
+		return pk, nil
+	}
+
+	if resolver == nil {
+		return nil, fmt.Errorf("invalid public key")
+	}
+
+	claims := jwt.MapClaims{}
+	t, err := parseUnverified(token, &claims)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := resolver(t.Header, claims)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve verification key: %w", err)
+	}
+
+	if key == nil {
+		return nil, fmt.Errorf("could not resolve verification key: no key found")
+	}
+
+	return key, nil
+}
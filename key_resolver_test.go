@@ -0,0 +1,94 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("KeyResolver", func() {
+	var (
+		keys     map[string]any
+		tokens   map[string]string
+		resolver KeyResolver
+	)
+
+	BeforeEach(func() {
+		keys = map[string]any{}
+		tokens = map[string]string{}
+
+		for _, issuer := range []string{"acme", "example"} {
+			pubK, priK, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+
+			client, err := NewClientIDClaims("up=bob", nil, "", nil, "", issuer, time.Hour, nil, nil)
+			Expect(err).ToNot(HaveOccurred())
+			tokens[issuer], err = SignToken(client, priK)
+			Expect(err).ToNot(HaveOccurred())
+			keys[issuer] = pubK
+		}
+
+		client, err := NewClientIDClaims("up=bob", nil, "", nil, "", "rsa", time.Hour, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		tokens["rsa"], err = SignToken(client, loadRSAPriKey("testdata/rsa/signer-key.pem"))
+		Expect(err).ToNot(HaveOccurred())
+		keys["rsa"] = loadRSAPubKey("testdata/rsa/signer-public.pem")
+
+		resolver = func(header map[string]any, claims jwt.MapClaims) (any, error) {
+			Expect(header["alg"]).ToNot(BeEmpty())
+
+			issuer, _ := claims["iss"].(string)
+			if issuer == "unknown" {
+				return nil, errors.New("unknown issuer")
+			}
+
+			return keys[issuer], nil
+		}
+	})
+
+	It("Should verify using the resolved key", func() {
+		for issuer, token := range tokens {
+			claims := &ClientIDClaims{}
+			Expect(ParseToken(token, claims, resolver)).To(Succeed())
+			Expect(claims.Issuer).To(Equal(issuer))
+		}
+
+		_, err := ParseClientIDToken(tokens["acme"], resolver, true)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Should accept plain functions", func() {
+		fn := func(header map[string]any, claims jwt.MapClaims) (any, error) { return keys["acme"], nil }
+		Expect(ParseToken(tokens["acme"], &ClientIDClaims{}, fn)).To(Succeed())
+		Expect(ParseToken(tokens["example"], &ClientIDClaims{}, fn)).To(MatchError("ed25519: verification error"))
+	})
+
+	It("Should handle resolver failures", func() {
+		_, priK, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		for issuer, expected := range map[string]string{
+			"unknown": "could not resolve verification key: unknown issuer",
+			"missing": "could not resolve verification key: no key found",
+		} {
+			client, err := NewClientIDClaims("up=bob", nil, "", nil, "", issuer, time.Hour, nil, nil)
+			Expect(err).ToNot(HaveOccurred())
+			token, err := SignToken(client, priK)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(ParseToken(token, &ClientIDClaims{}, resolver)).To(MatchError(expected))
+		}
+
+		Expect(ParseToken(tokens["acme"], &ClientIDClaims{}, KeyResolver(nil))).To(MatchError("invalid public key"))
+		Expect(ParseToken("invalid", &ClientIDClaims{}, resolver)).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,14 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"fmt"
+)
+
+// parseOpenSSHPrivateKey is not supported on js as it would add the ssh package to WebAssembly builds
+func parseOpenSSHPrivateKey(_ []byte) (any, error) {
+	return nil, fmt.Errorf("could not parse signing key: OpenSSH private keys are not supported on this platform")
+}
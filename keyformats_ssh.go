@@ -0,0 +1,29 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !js
+
+package tokens
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// parseOpenSSHPrivateKey parses an unencrypted private key in the OpenSSH format holding an ed25519 or RSA key
+func parseOpenSSHPrivateKey(keydat []byte) (any, error) {
+	key, err := ssh.ParseRawPrivateKey(keydat)
+	if err != nil {
+		var perr *ssh.PassphraseMissingError
+		if errors.As(err, &perr) {
+			return nil, fmt.Errorf("could not parse signing key: passphrase protected keys are not supported")
+		}
+
+		return nil, fmt.Errorf("could not parse signing key: %w", err)
+	}
+
+	return supportedPrivateKey(key)
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/crypto/ssh"
+)
+
+var _ = Describe("Key formats", func() {
+	var (
+		td   string
+		pubK ed25519.PublicKey
+		priK ed25519.PrivateKey
+	)
+
+	BeforeEach(func() {
+		var err error
+		td = GinkgoT().TempDir()
+		pubK, priK, err = ed25519KeyPairFromSeedFile("testdata/ed25519/signer.seed")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	writePEM := func(name string, block *pem.Block) string {
+		f := filepath.Join(td, name)
+		Expect(os.WriteFile(f, pem.EncodeToMemory(block), 0600)).To(Succeed())
+		return f
+	}
+
+	pkcs8File := func() string {
+		der, err := x509.MarshalPKCS8PrivateKey(priK)
+		Expect(err).ToNot(HaveOccurred())
+		return writePEM("key.pem", &pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	}
+
+	opensshFile := func() string {
+		block, err := ssh.MarshalPrivateKey(priK, "choria")
+		Expect(err).ToNot(HaveOccurred())
+		return writePEM("id_ed25519", block)
+	}
+
+	It("Should sign using PKCS#8 and OpenSSH keys", func() {
+		claims, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		for _, f := range []string{pkcs8File(), opensshFile()} {
+			token, err := SignTokenWithKeyFile(claims, f)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ParseToken(token, &ClientIDClaims{}, pubK)).To(Succeed())
+		}
+	})
+
+	It("Should load ed25519 key pairs in all formats", func() {
+		for _, f := range []string{pkcs8File(), opensshFile()} {
+			pub, pri, err := ed25519KeyPairFromSeedFile(f)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pub).To(Equal(pubK))
+			Expect(pri).To(Equal(priK))
+		}
+
+		_, _, err := ed25519KeyPairFromSeedFile("testdata/rsa/signer-key.pem")
+		Expect(err).To(MatchError("testdata/rsa/signer-key.pem does not hold an ed25519 key"))
+	})
+
+	It("Should reject unsupported keys", func() {
+		block, err := ssh.MarshalPrivateKeyWithPassphrase(priK, "choria", []byte("secret"))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = SignTokenWithKeyFile(&ClientIDClaims{}, writePEM("encrypted", block))
+		Expect(err).To(MatchError("could not parse signing key: passphrase protected keys are not supported"))
+
+		ecK, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		der, err := x509.MarshalPKCS8PrivateKey(ecK)
+		Expect(err).ToNot(HaveOccurred())
+		f := writePEM("ec.pem", &pem.Block{Type: "PRIVATE KEY", Bytes: der})
+		_, err = SignTokenWithKeyFile(&ClientIDClaims{}, f)
+		Expect(err).To(MatchError("unsupported key in " + f))
+	})
+})
@@ -0,0 +1,171 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// maxKeyMaterialSize limits how much is read from readers holding keys or tokens
+const maxKeyMaterialSize = 1024 * 1024
+
+func readLimited(r io.Reader) ([]byte, error) {
+	if r == nil {
+		return nil, fmt.Errorf("reader is required")
+	}
+
+	dat, err := io.ReadAll(io.LimitReader(r, maxKeyMaterialSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(dat) > maxKeyMaterialSize {
+		return nil, fmt.Errorf("input exceeds %d bytes", maxKeyMaterialSize)
+	}
+
+	return dat, nil
+}
+
+// ReadSigningKey reads a private key supported by SignTokenWithKeyFile from r
+func ReadSigningKey(r io.Reader) (any, error) {
+	dat, err := readLimited(r)
+	if err != nil {
+		return nil, fmt.Errorf("could not read signing key: %w", err)
+	}
+
+	return parseSigningKey(dat)
+}
+
+// ReadSigningKeyFS reads a private key supported by SignTokenWithKeyFile from name in fsys
+func ReadSigningKeyFS(fsys fs.FS, name string) (any, error) {
+	dat, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, fmt.Errorf("could not read signing key: %w", err)
+	}
+
+	key, err := parseSigningKey(dat)
+	if err != nil {
+		return nil, fmt.Errorf("%w in %v", err, name)
+	}
+
+	return key, nil
+}
+
+// ReadEd25519Seed reads a hex encoded ed25519 seed from r
+func ReadEd25519Seed(r io.Reader) (ed25519.PrivateKey, error) {
+	dat, err := readLimited(r)
+	if err != nil {
+		return nil, fmt.Errorf("could not read seed: %w", err)
+	}
+
+	key, err := parseSigningKey(dat)
+	if err != nil {
+		return nil, err
+	}
+
+	pk, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("not an ed25519 seed")
+	}
+
+	return pk, nil
+}
+
+// ReadVerificationKey reads a RSA public key or certificate in PEM format, or a hex encoded ed25519 public key, from r
+func ReadVerificationKey(r io.Reader) (any, error) {
+	dat, err := readLimited(r)
+	if err != nil {
+		return nil, fmt.Errorf("could not read public key: %w", err)
+	}
+
+	return readRSAOrED25519PublicData(bytes.TrimSpace(dat))
+}
+
+// ReadVerificationKeyFS reads a key supported by ReadVerificationKey from name in fsys
+func ReadVerificationKeyFS(fsys fs.FS, name string) (any, error) {
+	dat, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, fmt.Errorf("could not read public key: %w", err)
+	}
+
+	return readRSAOrED25519PublicData(bytes.TrimSpace(dat))
+}
+
+// SignTokenWithKeyReader signs claims using a private key read from r by ReadSigningKey
+func SignTokenWithKeyReader(claims jwt.Claims, r io.Reader, opts ...SignOption) (string, error) {
+	key, err := ReadSigningKey(r)
+	if err != nil {
+		return "", err
+	}
+
+	return SignToken(claims, key, opts...)
+}
+
+// SignTokenWithKeyFS signs claims using a private key read from name in fsys by ReadSigningKeyFS
+func SignTokenWithKeyFS(claims jwt.Claims, fsys fs.FS, name string, opts ...SignOption) (string, error) {
+	key, err := ReadSigningKeyFS(fsys, name)
+	if err != nil {
+		return "", err
+	}
+
+	return SignToken(claims, key, opts...)
+}
+
+// ReadToken reads a token from r, surrounding white space is removed
+func ReadToken(r io.Reader) (string, error) {
+	dat, err := readLimited(r)
+	if err != nil {
+		return "", fmt.Errorf("could not read token: %w", err)
+	}
+
+	return strings.TrimSpace(string(dat)), nil
+}
+
+// ReadTokenFS reads a token from name in fsys, surrounding white space is removed
+func ReadTokenFS(fsys fs.FS, name string) (string, error) {
+	dat, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return "", fmt.Errorf("could not read token: %w", err)
+	}
+
+	return strings.TrimSpace(string(dat)), nil
+}
+
+// WriteToken writes token to w
+func WriteToken(w io.Writer, token string) error {
+	_, err := io.WriteString(w, token)
+	return err
+}
+
+// TokenSource supplies the current token, it is called whenever a connection needs the token so renewed tokens
+// are picked up
+type TokenSource func() (string, error)
+
+// FileTokenSource is a TokenSource reading the token from path on every call
+func FileTokenSource(path string) TokenSource {
+	return func() (string, error) {
+		tb, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+
+		return strings.TrimSpace(string(tb)), nil
+	}
+}
+
+// FSTokenSource is a TokenSource reading the token from name in fsys on every call
+func FSTokenSource(fsys fs.FS, name string) TokenSource {
+	return func() (string, error) {
+		return ReadTokenFS(fsys, name)
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"os"
+	"strings"
+	"testing/fstest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Key and token IO", func() {
+	var fsys fstest.MapFS
+
+	BeforeEach(func() {
+		fsys = fstest.MapFS{}
+		for _, f := range []string{"ed25519/signer.seed", "ed25519/signer.public", "rsa/signer-key.pem", "rsa/signer-public.pem", "ed25519/good-provisioning.jwt"} {
+			dat, err := os.ReadFile("testdata/" + f)
+			Expect(err).ToNot(HaveOccurred())
+			fsys[f] = &fstest.MapFile{Data: dat}
+		}
+	})
+
+	It("Should read signing and verification keys", func() {
+		key, err := ReadSigningKeyFS(fsys, "ed25519/signer.seed")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(key).To(BeAssignableToTypeOf(ed25519.PrivateKey{}))
+
+		key, err = ReadSigningKey(bytes.NewReader(fsys["rsa/signer-key.pem"].Data))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(key).To(BeAssignableToTypeOf(&rsa.PrivateKey{}))
+
+		seed, err := ReadEd25519Seed(strings.NewReader(string(fsys["ed25519/signer.seed"].Data) + "\n"))
+		Expect(err).ToNot(HaveOccurred())
+
+		pub, err := ReadVerificationKeyFS(fsys, "ed25519/signer.public")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pub).To(Equal(seed.Public()))
+
+		pub, err = ReadVerificationKey(bytes.NewReader(fsys["rsa/signer-public.pem"].Data))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pub).To(BeAssignableToTypeOf(&rsa.PublicKey{}))
+
+		_, err = ReadEd25519Seed(bytes.NewReader(fsys["rsa/signer-key.pem"].Data))
+		Expect(err).To(MatchError("not an ed25519 seed"))
+		_, err = ReadSigningKeyFS(fsys, "ed25519/good-provisioning.jwt")
+		Expect(err).To(MatchError("unsupported key in ed25519/good-provisioning.jwt"))
+		_, err = ReadSigningKeyFS(fsys, "missing")
+		Expect(err).To(MatchError(ContainSubstring("could not read signing key")))
+		_, err = ReadSigningKey(bytes.NewReader(make([]byte, maxKeyMaterialSize+1)))
+		Expect(err).To(MatchError(ContainSubstring("input exceeds")))
+	})
+
+	It("Should sign using keys from readers and file systems", func() {
+		claims, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		token, err := SignTokenWithKeyFS(claims, fsys, "ed25519/signer.seed")
+		Expect(err).ToNot(HaveOccurred())
+		pub, err := ReadVerificationKeyFS(fsys, "ed25519/signer.public")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ParseToken(token, &ClientIDClaims{}, pub)).To(Succeed())
+
+		token, err = SignTokenWithKeyReader(claims, bytes.NewReader(fsys["rsa/signer-key.pem"].Data))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ParseToken(token, &ClientIDClaims{}, loadRSAPubKey("testdata/rsa/signer-public.pem"))).To(Succeed())
+	})
+
+	It("Should read and write tokens", func() {
+		token, err := ReadTokenFS(fsys, "ed25519/good-provisioning.jwt")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(TokenPurpose(token)).To(Equal(ProvisioningPurpose))
+
+		buf := &bytes.Buffer{}
+		Expect(WriteToken(buf, token)).To(Succeed())
+
+		read, err := ReadToken(strings.NewReader(buf.String() + "\n"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(read).To(Equal(token))
+
+		Expect(FSTokenSource(fsys, "ed25519/good-provisioning.jwt")()).To(Equal(token))
+		_, err = FSTokenSource(fsys, "missing")()
+		Expect(err).To(MatchError(ContainSubstring("could not read token")))
+	})
+})
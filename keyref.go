@@ -0,0 +1,245 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrUnsupportedKeyRef indicates a key reference uses a scheme without a registered backend
+var ErrUnsupportedKeyRef = errors.New("unsupported key reference")
+
+// KeyRefBackend resolves key references for a single scheme, location is the part of the reference after scheme://
+type KeyRefBackend interface {
+	// Signer returns a signer for the private key at location
+	Signer(ctx context.Context, location string) (crypto.Signer, error)
+
+	// PublicKey returns the ed25519 or RSA public key at location
+	PublicKey(ctx context.Context, location string) (crypto.PublicKey, error)
+}
+
+// SignerKeyRefBackend adapts a function returning signers, like a KMS client, to a KeyRefBackend, public keys are
+// taken from the signer
+type SignerKeyRefBackend func(ctx context.Context, location string) (crypto.Signer, error)
+
+// Signer implements KeyRefBackend
+func (f SignerKeyRefBackend) Signer(ctx context.Context, location string) (crypto.Signer, error) {
+	return f(ctx, location)
+}
+
+// PublicKey implements KeyRefBackend
+func (f SignerKeyRefBackend) PublicKey(ctx context.Context, location string) (crypto.PublicKey, error) {
+	signer, err := f(ctx, location)
+	if err != nil {
+		return nil, err
+	}
+
+	return signer.Public(), nil
+}
+
+// KeyRefResolver resolves references like file:///etc/choria/issuer.seed, env://ISSUER_SEED or vault://issuer to
+// signers and public keys. References without a scheme are file paths. The kms scheme has no built in backend, one
+// can be added using Register
+type KeyRefResolver struct {
+	backends map[string]KeyRefBackend
+	mu       sync.Mutex
+}
+
+// platformKeyRefBackends creates the built in backends only available on some platforms, like vault
+var platformKeyRefBackends = map[string]func(*keyRefResolverOptions) KeyRefBackend{}
+
+// KeyRefResolverOption configures the built in backends of a KeyRefResolver
+type KeyRefResolverOption func(*keyRefResolverOptions) error
+
+type keyRefResolverOptions struct {
+	tlsc *tls.Config
+	log  *logrus.Entry
+}
+
+// WithKeyRefVaultTLS sets the TLS configuration used to connect to Vault
+func WithKeyRefVaultTLS(tlsc *tls.Config) KeyRefResolverOption {
+	return func(o *keyRefResolverOptions) error {
+		o.tlsc = tlsc
+		return nil
+	}
+}
+
+// WithKeyRefLogger sets the logger used by the Vault backend
+func WithKeyRefLogger(log *logrus.Entry) KeyRefResolverOption {
+	return func(o *keyRefResolverOptions) error {
+		if log == nil {
+			return fmt.Errorf("logger is required")
+		}
+
+		o.log = log
+		return nil
+	}
+}
+
+// NewKeyRefResolver creates a resolver with backends for the file, env and vault schemes
+func NewKeyRefResolver(opts ...KeyRefResolverOption) (*KeyRefResolver, error) {
+	ropts := &keyRefResolverOptions{}
+	for _, opt := range opts {
+		err := opt(ropts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if ropts.log == nil {
+		log := logrus.New()
+		log.SetOutput(io.Discard)
+		ropts.log = logrus.NewEntry(log)
+	}
+
+	resolver := &KeyRefResolver{
+		backends: map[string]KeyRefBackend{
+			"file": fileKeyRefBackend{},
+			"env":  envKeyRefBackend{},
+		},
+	}
+
+	for scheme, backend := range platformKeyRefBackends {
+		resolver.backends[scheme] = backend(ropts)
+	}
+
+	return resolver, nil
+}
+
+// Register adds, or replaces, the backend for scheme
+func (r *KeyRefResolver) Register(scheme string, backend KeyRefBackend) error {
+	if scheme == "" || strings.Contains(scheme, ":") {
+		return fmt.Errorf("invalid scheme %q", scheme)
+	}
+
+	if backend == nil {
+		return fmt.Errorf("backend is required")
+	}
+
+	r.mu.Lock()
+	r.backends[scheme] = backend
+	r.mu.Unlock()
+
+	return nil
+}
+
+// Signer resolves ref to a signer usable with SignToken
+func (r *KeyRefResolver) Signer(ctx context.Context, ref string) (crypto.Signer, error) {
+	backend, location, err := r.backend(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := backend.Signer(ctx, location)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve signer %s: %w", ref, err)
+	}
+
+	return signer, nil
+}
+
+// PublicKey resolves ref to a public key usable with ParseToken
+func (r *KeyRefResolver) PublicKey(ctx context.Context, ref string) (crypto.PublicKey, error) {
+	backend, location, err := r.backend(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	pk, err := backend.PublicKey(ctx, location)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve public key %s: %w", ref, err)
+	}
+
+	return pk, nil
+}
+
+// backend finds the backend handling ref and the location within it
+func (r *KeyRefResolver) backend(ref string) (KeyRefBackend, string, error) {
+	if ref == "" {
+		return nil, "", fmt.Errorf("key reference is required")
+	}
+
+	scheme, location, found := strings.Cut(ref, "://")
+	if !found {
+		scheme, location = "file", ref
+	}
+
+	if location == "" {
+		return nil, "", fmt.Errorf("invalid key reference %s: no location", ref)
+	}
+
+	r.mu.Lock()
+	backend, ok := r.backends[scheme]
+	r.mu.Unlock()
+
+	if !ok {
+		return nil, "", fmt.Errorf("%w scheme %s", ErrUnsupportedKeyRef, scheme)
+	}
+
+	return backend, location, nil
+}
+
+// fileKeyRefBackend reads keys supported by SignTokenWithKeyFile and ParseToken from files
+type fileKeyRefBackend struct{}
+
+func (fileKeyRefBackend) Signer(_ context.Context, location string) (crypto.Signer, error) {
+	key, err := loadSigningKeyFile(location)
+	if err != nil {
+		return nil, err
+	}
+
+	return key.(crypto.Signer), nil
+}
+
+// PublicKey reads a public key or certificate, hex encoded ed25519 public keys and seeds cannot be told apart so
+// files must hold the public key itself
+func (fileKeyRefBackend) PublicKey(_ context.Context, location string) (crypto.PublicKey, error) {
+	dat, err := os.ReadFile(location)
+	if err != nil {
+		return nil, err
+	}
+
+	return readRSAOrED25519PublicData(bytes.TrimSpace(dat))
+}
+
+// envKeyRefBackend reads signing keys, typically hex encoded ed25519 seeds, from environment variables
+type envKeyRefBackend struct{}
+
+func (envKeyRefBackend) Signer(_ context.Context, location string) (crypto.Signer, error) {
+	val, ok := os.LookupEnv(location)
+	if !ok || val == "" {
+		return nil, fmt.Errorf("environment variable %s is not set", location)
+	}
+
+	key, err := parseSigningKey([]byte(val))
+	if errors.Is(err, errUnsupportedSigningKey) {
+		return nil, fmt.Errorf("unsupported key in environment variable %s", location)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return key.(crypto.Signer), nil
+}
+
+func (b envKeyRefBackend) PublicKey(ctx context.Context, location string) (crypto.PublicKey, error) {
+	signer, err := b.Signer(ctx, location)
+	if err != nil {
+		return nil, err
+	}
+
+	return signer.Public(), nil
+}
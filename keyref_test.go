@@ -0,0 +1,151 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("KeyRefResolver", func() {
+	var (
+		resolver *KeyRefResolver
+		ctx      context.Context
+		claims   *ClientIDClaims
+		pubK     ed25519.PublicKey
+		priK     ed25519.PrivateKey
+	)
+
+	BeforeEach(func() {
+		var err error
+		ctx = context.Background()
+		resolver, err = NewKeyRefResolver()
+		Expect(err).ToNot(HaveOccurred())
+		claims, err = NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		pubK, priK, err = ed25519KeyPairFromSeedFile("testdata/ed25519/signer.seed")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	signAndVerify := func(signer crypto.Signer, pk crypto.PublicKey) {
+		token, err := SignTokenContext(ctx, claims, signer)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ParseToken(token, &ClientIDClaims{}, pk)).To(Succeed())
+	}
+
+	It("Should resolve files", func() {
+		for _, ref := range []string{"testdata/ed25519/signer.seed", "file://testdata/ed25519/signer.seed"} {
+			signer, err := resolver.Signer(ctx, ref)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(signer).To(Equal(priK))
+		}
+
+		pk, err := resolver.PublicKey(ctx, "file://testdata/ed25519/signer.public")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pk).To(Equal(pubK))
+
+		signer, err := resolver.Signer(ctx, "file://testdata/rsa/signer-key.pem")
+		Expect(err).ToNot(HaveOccurred())
+		pk, err = resolver.PublicKey(ctx, "file://testdata/rsa/signer-public.pem")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pk).To(BeAssignableToTypeOf(&rsa.PublicKey{}))
+		signAndVerify(signer, pk)
+
+		_, err = resolver.Signer(ctx, "file://testdata/missing.seed")
+		Expect(err).To(MatchError(ContainSubstring("could not resolve signer file://testdata/missing.seed")))
+	})
+
+	It("Should resolve environment variables", func() {
+		seed, err := os.ReadFile("testdata/ed25519/signer.seed")
+		Expect(err).ToNot(HaveOccurred())
+		GinkgoT().Setenv("KEYREF_TEST_SEED", string(seed)+"\n")
+		GinkgoT().Setenv("KEYREF_TEST_INVALID", "invalid")
+
+		signer, err := resolver.Signer(ctx, "env://KEYREF_TEST_SEED")
+		Expect(err).ToNot(HaveOccurred())
+		pk, err := resolver.PublicKey(ctx, "env://KEYREF_TEST_SEED")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pk).To(Equal(pubK))
+		signAndVerify(signer, pk)
+
+		_, err = resolver.Signer(ctx, "env://KEYREF_TEST_UNSET")
+		Expect(err).To(MatchError("could not resolve signer env://KEYREF_TEST_UNSET: environment variable KEYREF_TEST_UNSET is not set"))
+		_, err = resolver.Signer(ctx, "env://KEYREF_TEST_INVALID")
+		Expect(err).To(MatchError(ContainSubstring("unsupported key in environment variable KEYREF_TEST_INVALID")))
+	})
+
+	It("Should resolve Vault keys", func() {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.Method == "GET" && r.URL.Path == "/v1/transit/keys/issuer":
+				fmt.Fprintf(w, `{"data":{"keys":{"1":{"public_key":%q}}}}`, base64.StdEncoding.EncodeToString(pubK))
+
+			case r.Method == "POST" && r.URL.Path == "/v1/transit/sign/issuer":
+				var req struct {
+					Input string `json:"input"`
+				}
+				Expect(json.NewDecoder(r.Body).Decode(&req)).To(Succeed())
+				msg, err := base64.StdEncoding.DecodeString(req.Input)
+				Expect(err).ToNot(HaveOccurred())
+				fmt.Fprintf(w, `{"data":{"signature":"vault:v1:%s"}}`, base64.StdEncoding.EncodeToString(ed25519.Sign(priK, msg)))
+
+			default:
+				w.WriteHeader(404)
+			}
+		}))
+		defer srv.Close()
+
+		GinkgoT().Setenv("VAULT_ADDR", srv.URL)
+		GinkgoT().Setenv("VAULT_TOKEN", "s.token")
+
+		signer, err := resolver.Signer(ctx, "vault://issuer")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(signer).To(BeAssignableToTypeOf(&vaultSigner{}))
+		pk, err := resolver.PublicKey(ctx, "vault://issuer")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pk).To(Equal(pubK))
+		signAndVerify(signer, pk)
+
+		_, err = resolver.PublicKey(ctx, "vault://other")
+		Expect(err).To(MatchError(ContainSubstring("request failed: code: 404")))
+	})
+
+	It("Should support registering backends", func() {
+		_, err := resolver.Signer(ctx, "kms://arn:aws:kms:eu-west-1:123456789012:key/issuer")
+		Expect(err).To(MatchError(ErrUnsupportedKeyRef))
+
+		var location string
+		Expect(resolver.Register("kms", SignerKeyRefBackend(func(_ context.Context, l string) (crypto.Signer, error) {
+			location = l
+			return priK, nil
+		}))).To(Succeed())
+
+		signer, err := resolver.Signer(ctx, "kms://arn:aws:kms:eu-west-1:123456789012:key/issuer")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(location).To(Equal("arn:aws:kms:eu-west-1:123456789012:key/issuer"))
+		pk, err := resolver.PublicKey(ctx, "kms://arn:aws:kms:eu-west-1:123456789012:key/issuer")
+		Expect(err).ToNot(HaveOccurred())
+		signAndVerify(signer, pk)
+
+		Expect(resolver.Register("", SignerKeyRefBackend(nil))).To(MatchError(`invalid scheme ""`))
+		Expect(resolver.Register("kms", nil)).To(MatchError("backend is required"))
+		_, err = resolver.Signer(ctx, "")
+		Expect(err).To(MatchError("key reference is required"))
+		_, err = resolver.Signer(ctx, "env://")
+		Expect(err).To(MatchError(ContainSubstring("no location")))
+	})
+})
@@ -0,0 +1,227 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// ErrNoVerificationKeys indicates a key ring without any keys was used to verify a token
+var ErrNoVerificationKeys = errors.New("no verification keys")
+
+// KeyRing is a set of ed25519 and RSA public keys trusted to verify tokens, safe for concurrent use
+type KeyRing struct {
+	keys []keyRingEntry
+	mu   sync.Mutex
+}
+
+// keyRingEntry is a key and the window during which it is trusted, zero times leave that side of the window open
+type keyRingEntry struct {
+	key       any
+	notBefore time.Time
+	notAfter  time.Time
+}
+
+func (e *keyRingEntry) activeAt(t time.Time) bool {
+	if !e.notBefore.IsZero() && t.Before(e.notBefore) {
+		return false
+	}
+
+	if !e.notAfter.IsZero() && t.After(e.notAfter) {
+		return false
+	}
+
+	return true
+}
+
+// NewKeyRing creates a new key ring holding keys, keys must be ed25519.PublicKey or *rsa.PublicKey
+func NewKeyRing(keys ...any) (*KeyRing, error) {
+	kr := &KeyRing{}
+
+	for _, k := range keys {
+		err := kr.Add(k)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return kr, nil
+}
+
+// ValidatePublicKey ensures key is a ed25519.PublicKey or *rsa.PublicKey that is strong enough to verify tokens
+func ValidatePublicKey(key any) error {
+	switch pk := key.(type) {
+	case ed25519.PublicKey:
+		return ValidateEd25519PublicKey(pk)
+
+	case *rsa.PublicKey:
+		return validateRSAPublicKey(pk)
+
+	default:
+		return fmt.Errorf("unsupported public key type %T", key)
+	}
+}
+
+// Add adds a ed25519.PublicKey or *rsa.PublicKey to the key ring
+func (k *KeyRing) Add(key any) error {
+	return k.AddWithWindow(key, time.Time{}, time.Time{})
+}
+
+// AddWithWindow adds a ed25519.PublicKey or *rsa.PublicKey to the key ring that is only used to verify tokens
+// between notBefore and notAfter, zero times leave that side of the window open
+func (k *KeyRing) AddWithWindow(key any, notBefore time.Time, notAfter time.Time) error {
+	err := ValidatePublicKey(key)
+	if err != nil {
+		return err
+	}
+
+	if !notBefore.IsZero() && !notAfter.IsZero() && notAfter.Before(notBefore) {
+		return fmt.Errorf("not after cannot be before not before")
+	}
+
+	k.mu.Lock()
+	k.keys = append(k.keys, keyRingEntry{key: key, notBefore: notBefore, notAfter: notAfter})
+	k.mu.Unlock()
+
+	return nil
+}
+
+// AddFile adds the RSA or ed25519 public key held in file to the key ring
+func (k *KeyRing) AddFile(file string) error {
+	dat, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("could not read public key: %w", err)
+	}
+
+	pk, err := readRSAOrED25519PublicData(bytes.TrimSpace(dat))
+	if err != nil {
+		return err
+	}
+
+	return k.Add(pk)
+}
+
+// Keys returns a copy of the keys in the key ring regardless of their trust window
+func (k *KeyRing) Keys() []any {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	res := make([]any, 0, len(k.keys))
+	for _, e := range k.keys {
+		res = append(res, e.key)
+	}
+
+	return res
+}
+
+// KeysAt returns the keys in the key ring trusted at t
+func (k *KeyRing) KeysAt(t time.Time) []any {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	var res []any
+	for _, e := range k.keys {
+		if e.activeAt(t) {
+			res = append(res, e.key)
+		}
+	}
+
+	return res
+}
+
+// Contains determines if key is in the key ring
+func (k *KeyRing) Contains(key any) bool {
+	eq, ok := key.(interface{ Equal(crypto.PublicKey) bool })
+	if !ok {
+		return false
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	for _, existing := range k.keys {
+		if eq.Equal(existing.key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Len is the number of keys in the key ring
+func (k *KeyRing) Len() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return len(k.keys)
+}
+
+// clone creates a copy of the key ring retaining the trust windows of its keys
+func (k *KeyRing) clone() *KeyRing {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return &KeyRing{keys: append([]keyRingEntry{}, k.keys...)}
+}
+
+// ParseToken parses token into claims trying every key in the key ring trusted at the time of parsing, it returns
+// the key that verified the signature.
+//
+// When a key verifies the signature but the claims are not valid, for example when the token expired, the key
+// is returned along with the error
+func (k *KeyRing) ParseToken(token string, claims jwt.Claims, opts ...ParseOption) (any, error) {
+	return k.ParseTokenContext(context.Background(), token, claims, opts...)
+}
+
+// ParseTokenContext behaves like ParseToken passing ctx to revocation checkers, introspection and replay guards
+func (k *KeyRing) ParseTokenContext(ctx context.Context, token string, claims jwt.Claims, opts ...ParseOption) (any, error) {
+	popts, err := newParseOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := k.KeysAt(popts.now())
+	if len(keys) == 0 {
+		return nil, ErrNoVerificationKeys
+	}
+
+	for _, key := range keys {
+		err = ParseTokenContext(ctx, token, claims, key, opts...)
+		if err == nil {
+			return key, nil
+		}
+
+		if !isSignatureError(err) {
+			return key, err
+		}
+	}
+
+	return nil, err
+}
+
+// isSignatureError determines if err indicates the token could not be verified using the key
+func isSignatureError(err error) bool {
+	if errors.Is(err, errCompactVerification) || errors.Is(err, ErrorNotSignedByIssuer) {
+		return true
+	}
+
+	var ve *jwt.ValidationError
+	if !errors.As(err, &ve) {
+		return false
+	}
+
+	return ve.Errors&(jwt.ValidationErrorSignatureInvalid|jwt.ValidationErrorUnverifiable) != 0
+}
@@ -0,0 +1,154 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("KeyRing", func() {
+	Describe("Add", func() {
+		It("Should only accept valid keys", func() {
+			kr, err := NewKeyRing()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(kr.Add("x")).To(MatchError("unsupported public key type string"))
+			Expect(kr.Add(ed25519.PublicKey(make([]byte, 32)))).To(MatchError(ErrZeroPublicKey))
+			Expect(kr.Len()).To(Equal(0))
+
+			_, err = NewKeyRing(ed25519.PublicKey(make([]byte, 32)))
+			Expect(err).To(MatchError(ErrZeroPublicKey))
+		})
+
+		It("Should add keys with trust windows", func() {
+			pubK, _, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+			otherK, _, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+
+			now := time.Now()
+			kr := &KeyRing{}
+			Expect(kr.AddWithWindow(pubK, now, now.Add(-time.Hour))).To(MatchError("not after cannot be before not before"))
+			Expect(kr.AddWithWindow(pubK, time.Time{}, now.Add(time.Hour))).To(Succeed())
+			Expect(kr.AddWithWindow(otherK, now.Add(30*time.Minute), time.Time{})).To(Succeed())
+
+			Expect(kr.Keys()).To(Equal([]any{pubK, otherK}))
+			Expect(kr.KeysAt(now)).To(Equal([]any{pubK}))
+			Expect(kr.KeysAt(now.Add(45 * time.Minute))).To(Equal([]any{pubK, otherK}))
+			Expect(kr.KeysAt(now.Add(2 * time.Hour))).To(Equal([]any{otherK}))
+		})
+
+		It("Should load keys from files", func() {
+			kr, err := NewKeyRing()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(kr.AddFile("testdata/ed25519/other.public")).To(Succeed())
+			Expect(kr.AddFile("testdata/rsa/signer-public.pem")).To(Succeed())
+			Expect(kr.AddFile("testdata/missing")).To(MatchError(ContainSubstring("could not read public key")))
+			Expect(kr.Len()).To(Equal(2))
+			Expect(kr.Keys()).To(HaveLen(2))
+		})
+	})
+
+	Describe("Contains", func() {
+		It("Should find ed25519 and RSA keys", func() {
+			pubK, _, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+			otherK, _, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+
+			kr, err := NewKeyRing(pubK)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(kr.AddFile("testdata/rsa/signer-public.pem")).To(Succeed())
+
+			dat, err := os.ReadFile("testdata/rsa/signer-public.pem")
+			Expect(err).ToNot(HaveOccurred())
+			rsaK, err := readRSAOrED25519PublicData(bytes.TrimSpace(dat))
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(kr.Contains(ed25519.PublicKey(append([]byte{}, pubK...)))).To(BeTrue())
+			Expect(kr.Contains(rsaK)).To(BeTrue())
+			Expect(kr.Contains(otherK)).To(BeFalse())
+			Expect(kr.Contains("x")).To(BeFalse())
+		})
+	})
+
+	Describe("ParseToken", func() {
+		var token string
+		var pubK ed25519.PublicKey
+		var priK ed25519.PrivateKey
+
+		BeforeEach(func() {
+			var err error
+			pubK, priK, err = ed25519.GenerateKey(rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+
+			claims, err := newStandardClaims("ginkgo", ProvisioningPurpose, time.Hour, false)
+			Expect(err).ToNot(HaveOccurred())
+			token, err = SignToken(claims, priK)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("Should fail without keys", func() {
+			_, err := (&KeyRing{}).ParseToken(token, &StandardClaims{})
+			Expect(err).To(MatchError(ErrNoVerificationKeys))
+		})
+
+		It("Should try all keys", func() {
+			otherPubK, _ := loadEd25519Seed("testdata/ed25519/other.seed")
+			kr, err := NewKeyRing(loadRSAPubKey("testdata/rsa/signer-public.pem"), otherPubK, pubK)
+			Expect(err).ToNot(HaveOccurred())
+
+			key, err := kr.ParseToken(token, &StandardClaims{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(key).To(Equal(pubK))
+		})
+
+		It("Should only use keys trusted at the time of parsing", func() {
+			kr := &KeyRing{}
+			Expect(kr.AddWithWindow(pubK, time.Time{}, time.Now().Add(30*time.Minute))).To(Succeed())
+
+			key, err := kr.ParseToken(token, &StandardClaims{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(key).To(Equal(pubK))
+
+			_, err = kr.ParseToken(token, &StandardClaims{}, WithVerificationClock(FixedClock(time.Now().Add(45*time.Minute))))
+			Expect(err).To(MatchError(ErrNoVerificationKeys))
+		})
+
+		It("Should fail when no key matches", func() {
+			otherPubK, _ := loadEd25519Seed("testdata/ed25519/other.seed")
+			kr, err := NewKeyRing(otherPubK)
+			Expect(err).ToNot(HaveOccurred())
+
+			key, err := kr.ParseToken(token, &StandardClaims{})
+			Expect(err).To(MatchError(jwt.ErrTokenSignatureInvalid))
+			Expect(key).To(BeNil())
+		})
+
+		It("Should return the key when claims are invalid", func() {
+			claims, err := newStandardClaims("ginkgo", ProvisioningPurpose, time.Hour, false)
+			Expect(err).ToNot(HaveOccurred())
+			claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+			token, err = SignToken(claims, priK)
+			Expect(err).ToNot(HaveOccurred())
+
+			kr, err := NewKeyRing(pubK)
+			Expect(err).ToNot(HaveOccurred())
+
+			key, err := kr.ParseToken(token, &StandardClaims{})
+			Expect(err).To(MatchError(jwt.ErrTokenExpired))
+			Expect(key).To(Equal(pubK))
+		})
+	})
+})
@@ -0,0 +1,186 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrNotAKnownIssuersToken indicates a token is not a known issuers bootstrap document
+var ErrNotAKnownIssuersToken = errors.New("not a known issuers token")
+
+// KnownIssuer is an issuer trusted by a new installation from day zero
+type KnownIssuer struct {
+	// Name is a descriptive name for the issuer
+	Name string `json:"name"`
+
+	// Organization is the organization the key is an org issuer for, when empty the key is trusted to verify tokens directly
+	Organization string `json:"organization,omitempty"`
+
+	// PublicKey is the hex encoded ed25519 public key or PEM encoded RSA public key of the issuer, org issuers must be ed25519
+	PublicKey string `json:"public_key"`
+
+	// Endpoints are URLs where the issuer can be reached, for example to obtain or renew tokens
+	Endpoints []string `json:"endpoints,omitempty"`
+}
+
+// KnownIssuers is a bootstrap document listing the issuers a new installation should trust
+type KnownIssuers struct {
+	Issuers []KnownIssuer `json:"issuers"`
+}
+
+// KnownIssuersClaims is a signed known issuers bootstrap document
+//
+// The "purpose" claim should be set to KnownIssuersPurpose
+type KnownIssuersClaims struct {
+	KnownIssuers
+
+	StandardClaims
+}
+
+func (i *KnownIssuer) publicKey() (any, error) {
+	pk, err := readRSAOrED25519PublicData([]byte(strings.TrimSpace(i.PublicKey)))
+	if err != nil {
+		return nil, err
+	}
+
+	if i.Organization != "" {
+		if _, ok := pk.(ed25519.PublicKey); !ok {
+			return nil, fmt.Errorf("org issuers require ed25519 public keys")
+		}
+	}
+
+	return pk, nil
+}
+
+// Validate checks that every issuer has a name, a valid public key and valid endpoints
+func (k *KnownIssuers) Validate() error {
+	if len(k.Issuers) == 0 {
+		return fmt.Errorf("at least one issuer is required")
+	}
+
+	for i, issuer := range k.Issuers {
+		if issuer.Name == "" {
+			return fmt.Errorf("issuer %d: name is required", i)
+		}
+
+		pk, err := issuer.publicKey()
+		if err != nil {
+			return fmt.Errorf("issuer %s: %w", issuer.Name, err)
+		}
+
+		err = ValidatePublicKey(pk)
+		if err != nil {
+			return fmt.Errorf("issuer %s: %w", issuer.Name, err)
+		}
+
+		for _, e := range issuer.Endpoints {
+			uri, err := url.Parse(e)
+			if err != nil || uri.Scheme == "" || uri.Host == "" {
+				return fmt.Errorf("issuer %s: invalid endpoint %q", issuer.Name, e)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Install adds issuers without an organization to kr and org issuers to orgs, either can be nil to skip those issuers.
+// Keys that are already trusted are not added again
+func (k *KnownIssuers) Install(kr *KeyRing, orgs *OrgRegistry) error {
+	err := k.Validate()
+	if err != nil {
+		return err
+	}
+
+	for _, issuer := range k.Issuers {
+		pk, err := issuer.publicKey()
+		if err != nil {
+			return fmt.Errorf("issuer %s: %w", issuer.Name, err)
+		}
+
+		switch {
+		case issuer.Organization == "" && kr != nil:
+			if kr.Contains(pk) {
+				continue
+			}
+
+			err = kr.Add(pk)
+
+		case issuer.Organization != "" && orgs != nil:
+			err = orgs.AddIssuer(issuer.Organization, pk.(ed25519.PublicKey), issuer.Endpoints...)
+		}
+		if err != nil {
+			return fmt.Errorf("issuer %s: %w", issuer.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Validate checks that the document holds valid known issuers
+func (c *KnownIssuersClaims) Validate() error {
+	if c.Purpose != KnownIssuersPurpose {
+		return ErrNotAKnownIssuersToken
+	}
+
+	return c.KnownIssuers.Validate()
+}
+
+// SignKnownIssuers creates a signed known issuers bootstrap document, typically signed by the Choria project or an org root
+func SignKnownIssuers(issuers *KnownIssuers, issuer string, validity time.Duration, signer any, opts ...ClaimsOption) (string, error) {
+	if issuers == nil {
+		return "", fmt.Errorf("known issuers are required")
+	}
+
+	err := issuers.Validate()
+	if err != nil {
+		return "", err
+	}
+
+	stdClaims, err := newStandardClaims(issuer, KnownIssuersPurpose, validity, false, opts...)
+	if err != nil {
+		return "", err
+	}
+
+	return SignToken(&KnownIssuersClaims{KnownIssuers: *issuers, StandardClaims: *stdClaims}, signer)
+}
+
+// LoadKnownIssuers verifies a known issuers bootstrap document using the root key pk
+func LoadKnownIssuers(doc string, pk any, opts ...ParseOption) (*KnownIssuers, error) {
+	claims := &KnownIssuersClaims{}
+	err := ParseToken(strings.TrimSpace(doc), claims, pk, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse known issuers: %w", err)
+	}
+
+	return &claims.KnownIssuers, nil
+}
+
+// LoadKnownIssuersFile verifies the known issuers bootstrap document in file using pk and installs the issuers into kr and orgs
+func LoadKnownIssuersFile(file string, pk any, kr *KeyRing, orgs *OrgRegistry, opts ...ParseOption) (*KnownIssuers, error) {
+	doc, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read known issuers: %w", err)
+	}
+
+	issuers, err := LoadKnownIssuers(string(doc), pk, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	err = issuers.Install(kr, orgs)
+	if err != nil {
+		return nil, err
+	}
+
+	return issuers, nil
+}
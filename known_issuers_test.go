@@ -0,0 +1,147 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("KnownIssuers", func() {
+	var (
+		rootPubK   ed25519.PublicKey
+		rootPriK   ed25519.PrivateKey
+		signerPubK ed25519.PublicKey
+		orgPubK    ed25519.PublicKey
+		issuers    *KnownIssuers
+		rsaPEM     []byte
+	)
+
+	BeforeEach(func() {
+		var err error
+		rootPubK, rootPriK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		signerPubK, _, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		orgPubK, _, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		rsaPEM, err = os.ReadFile("testdata/rsa/signer-public.pem")
+		Expect(err).ToNot(HaveOccurred())
+
+		issuers = &KnownIssuers{Issuers: []KnownIssuer{
+			{Name: "signer", PublicKey: hex.EncodeToString(signerPubK)},
+			{Name: "legacy", PublicKey: string(rsaPEM)},
+			{Name: "acme", Organization: "acme", PublicKey: hex.EncodeToString(orgPubK), Endpoints: []string{"https://aaa.acme.net/v1"}},
+		}}
+	})
+
+	Describe("Validate", func() {
+		It("Should detect invalid documents", func() {
+			Expect((&KnownIssuers{}).Validate()).To(MatchError("at least one issuer is required"))
+
+			ki := &KnownIssuers{Issuers: []KnownIssuer{{PublicKey: hex.EncodeToString(signerPubK)}}}
+			Expect(ki.Validate()).To(MatchError("issuer 0: name is required"))
+
+			ki.Issuers[0].Name = "signer"
+			ki.Issuers[0].PublicKey = "invalid"
+			Expect(ki.Validate()).To(MatchError(ContainSubstring("issuer signer: could not parse ed25519 public data")))
+
+			ki.Issuers[0].PublicKey = hex.EncodeToString(make([]byte, 32))
+			Expect(ki.Validate()).To(MatchError(ErrZeroPublicKey))
+
+			ki.Issuers[0].PublicKey = string(rsaPEM)
+			ki.Issuers[0].Organization = "acme"
+			Expect(ki.Validate()).To(MatchError("issuer signer: org issuers require ed25519 public keys"))
+
+			ki.Issuers[0].PublicKey = hex.EncodeToString(signerPubK)
+			ki.Issuers[0].Endpoints = []string{"not a url"}
+			Expect(ki.Validate()).To(MatchError(`issuer signer: invalid endpoint "not a url"`))
+
+			Expect(issuers.Validate()).To(Succeed())
+		})
+	})
+
+	Describe("Install", func() {
+		It("Should install keys and organizations", func() {
+			kr, err := NewKeyRing(signerPubK)
+			Expect(err).ToNot(HaveOccurred())
+			orgs := NewOrgRegistry()
+
+			Expect(issuers.Install(kr, orgs)).To(Succeed())
+			Expect(kr.Len()).To(Equal(2))
+			Expect(kr.Contains(signerPubK)).To(BeTrue())
+			Expect(orgs.IsTrustedIssuer("acme", orgPubK)).To(BeTrue())
+			Expect(orgs.Endpoints("acme")).To(Equal([]string{"https://aaa.acme.net/v1"}))
+
+			Expect(issuers.Install(kr, orgs)).To(Succeed())
+			Expect(kr.Len()).To(Equal(2))
+		})
+
+		It("Should skip destinations that are not given", func() {
+			orgs := NewOrgRegistry()
+			Expect(issuers.Install(nil, orgs)).To(Succeed())
+			Expect(orgs.Organizations()).To(Equal([]string{"acme"}))
+
+			kr := &KeyRing{}
+			Expect(issuers.Install(kr, nil)).To(Succeed())
+			Expect(kr.Len()).To(Equal(2))
+		})
+	})
+
+	Describe("Signed documents", func() {
+		It("Should sign and load documents", func() {
+			_, err := SignKnownIssuers(nil, "choria", time.Hour, rootPriK)
+			Expect(err).To(MatchError("known issuers are required"))
+
+			doc, err := SignKnownIssuers(issuers, "choria", time.Hour, rootPriK)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(TokenPurpose(doc)).To(Equal(KnownIssuersPurpose))
+
+			loaded, err := LoadKnownIssuers(doc, rootPubK)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(loaded).To(Equal(issuers))
+
+			_, err = LoadKnownIssuers(doc, signerPubK)
+			Expect(err).To(MatchError(ContainSubstring("could not parse known issuers")))
+		})
+
+		It("Should reject other documents", func() {
+			rl, err := NewRevocationList(RevocationEntry{TokenID: "x"})
+			Expect(err).ToNot(HaveOccurred())
+			doc, err := SignRevocationList(rl, "choria", time.Hour, rootPriK)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = LoadKnownIssuers(doc, rootPubK)
+			Expect(err).To(MatchError(ErrNotAKnownIssuersToken))
+		})
+
+		It("Should load and install from files", func() {
+			doc, err := SignKnownIssuers(issuers, "choria", time.Hour, rootPriK)
+			Expect(err).ToNot(HaveOccurred())
+
+			file := filepath.Join(GinkgoT().TempDir(), "known.jwt")
+			Expect(os.WriteFile(file, []byte(doc+"\n"), 0600)).To(Succeed())
+
+			kr := &KeyRing{}
+			orgs := NewOrgRegistry()
+			_, err = LoadKnownIssuersFile(filepath.Join(filepath.Dir(file), "missing"), rootPubK, kr, orgs)
+			Expect(err).To(MatchError(os.ErrNotExist))
+
+			loaded, err := LoadKnownIssuersFile(file, rootPubK, kr, orgs)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(loaded.Issuers).To(HaveLen(3))
+			Expect(kr.Len()).To(Equal(2))
+			Expect(orgs.Organizations()).To(Equal([]string{"acme"}))
+		})
+	})
+})
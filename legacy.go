@@ -0,0 +1,117 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// LegacyCertificateSuffix is the suffix used by client certificates in the legacy certificate based security model
+	LegacyCertificateSuffix = ".mcollective"
+
+	// LegacyCallerIDProvider is the caller id provider used for legacy certificate identities
+	LegacyCallerIDProvider = "choria"
+
+	// LegacyCertificateProperty is the user property that records the certificate name a token was imported from
+	LegacyCertificateProperty = "legacy_certificate"
+)
+
+var legacyCertNameRe = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9_\-]*)\.mcollective$`)
+
+// LegacyCertificateMapping configures how a legacy certificate identity is converted into a client id token
+type LegacyCertificateMapping struct {
+	// OUMap maps certificate Organizational Units to token organizations, the first matching OU is used
+	OUMap map[string]string
+
+	// DefaultOrganization is used when no OU is mapped, defaults to the standard organization
+	DefaultOrganization string
+
+	// AllowedAgents is a list of agent names or agent.action names the user can perform
+	AllowedAgents []string
+
+	// OPAPolicy is a Open Policy Agent document to be used by the signer to limit the users actions
+	OPAPolicy string
+
+	// Permissions sets additional permissions for the client
+	Permissions *ClientPermissions
+
+	// Properties are additional user properties to set in the token
+	Properties map[string]string
+
+	// Issuer is the issuer to set in the token
+	Issuer string
+
+	// Validity is how long the token will be valid for
+	Validity time.Duration
+}
+
+// LegacyCallerIDFromCertName converts a legacy client certificate name like bob.mcollective into a caller id like choria=bob
+func LegacyCallerIDFromCertName(name string) (string, error) {
+	parts := legacyCertNameRe.FindStringSubmatch(name)
+	if len(parts) != 2 {
+		return "", fmt.Errorf("invalid legacy certificate name %q, must match %s", name, legacyCertNameRe.String())
+	}
+
+	return fmt.Sprintf("%s=%s", LegacyCallerIDProvider, parts[1]), nil
+}
+
+// NewClientIDClaimsFromLegacyCertificate creates client id claims equivalent to the identity held in a legacy client certificate
+func NewClientIDClaimsFromLegacyCertificate(cert *x509.Certificate, mapping LegacyCertificateMapping, pk ed25519.PublicKey, opts ...ClaimsOption) (*ClientIDClaims, error) {
+	if cert == nil {
+		return nil, fmt.Errorf("certificate is required")
+	}
+
+	callerID, err := LegacyCallerIDFromCertName(cert.Subject.CommonName)
+	if err != nil {
+		return nil, err
+	}
+
+	props := map[string]string{}
+	for k, v := range mapping.Properties {
+		props[k] = v
+	}
+	props[LegacyCertificateProperty] = cert.Subject.CommonName
+
+	return NewClientIDClaims(callerID, mapping.AllowedAgents, mapping.organization(cert), props, mapping.OPAPolicy, mapping.Issuer, mapping.Validity, mapping.Permissions, pk, opts...)
+}
+
+// NewClientIDClaimsFromLegacyCertificateFile creates client id claims from the first certificate in a PEM encoded file
+func NewClientIDClaimsFromLegacyCertificateFile(file string, mapping LegacyCertificateMapping, pk ed25519.PublicKey, opts ...ClaimsOption) (*ClientIDClaims, error) {
+	certdat, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read certificate: %w", err)
+	}
+
+	pb, _ := pem.Decode(certdat)
+	if pb == nil || pb.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("no PEM encoded certificate found in %s", file)
+	}
+
+	cert, err := x509.ParseCertificate(pb.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse certificate: %w", err)
+	}
+
+	return NewClientIDClaimsFromLegacyCertificate(cert, mapping, pk, opts...)
+}
+
+func (m *LegacyCertificateMapping) organization(cert *x509.Certificate) string {
+	for _, ou := range cert.Subject.OrganizationalUnit {
+		org, ok := m.OUMap[strings.TrimSpace(ou)]
+		if ok && org != "" {
+			return org
+		}
+	}
+
+	return m.DefaultOrganization
+}
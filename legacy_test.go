@@ -0,0 +1,124 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Legacy Certificates", func() {
+	newCert := func(cn string, ou ...string) *x509.Certificate {
+		pubK, priK, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(1),
+			Subject:      pkix.Name{CommonName: cn, OrganizationalUnit: ou},
+			NotBefore:    time.Now(),
+			NotAfter:     time.Now().Add(time.Hour),
+		}
+
+		der, err := x509.CreateCertificate(rand.Reader, template, template, pubK, priK)
+		Expect(err).ToNot(HaveOccurred())
+
+		cert, err := x509.ParseCertificate(der)
+		Expect(err).ToNot(HaveOccurred())
+
+		return cert
+	}
+
+	Describe("LegacyCallerIDFromCertName", func() {
+		It("Should convert valid names", func() {
+			Expect(LegacyCallerIDFromCertName("bob.mcollective")).To(Equal("choria=bob"))
+			Expect(LegacyCallerIDFromCertName("bob-smith_1.mcollective")).To(Equal("choria=bob-smith_1"))
+		})
+
+		It("Should reject invalid names", func() {
+			for _, n := range []string{"", "bob", "bob.example.net", "1bob.mcollective", ".mcollective"} {
+				_, err := LegacyCallerIDFromCertName(n)
+				Expect(err).To(MatchError(ContainSubstring("invalid legacy certificate name")))
+			}
+		})
+	})
+
+	Describe("NewClientIDClaimsFromLegacyCertificate", func() {
+		It("Should require a certificate", func() {
+			_, err := NewClientIDClaimsFromLegacyCertificate(nil, LegacyCertificateMapping{}, nil)
+			Expect(err).To(MatchError("certificate is required"))
+		})
+
+		It("Should reject non legacy certificates", func() {
+			_, err := NewClientIDClaimsFromLegacyCertificate(newCert("node.example.net"), LegacyCertificateMapping{}, nil)
+			Expect(err).To(MatchError(ContainSubstring("invalid legacy certificate name")))
+		})
+
+		It("Should create equivalent claims", func() {
+			perms := &ClientPermissions{FleetManagement: true}
+			claims, err := NewClientIDClaimsFromLegacyCertificate(newCert("bob.mcollective", "ops", "unmapped"), LegacyCertificateMapping{
+				OUMap:         map[string]string{"dev": "development", "ops": "operations"},
+				AllowedAgents: []string{"rpcutil"},
+				Permissions:   perms,
+				Properties:    map[string]string{"group": "admins"},
+				Issuer:        "ginkgo",
+				Validity:      time.Hour,
+			}, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(claims.CallerID).To(Equal("choria=bob"))
+			Expect(claims.OrganizationUnit).To(Equal("operations"))
+			Expect(claims.AllowedAgents).To(Equal([]string{"rpcutil"}))
+			Expect(claims.Permissions).To(Equal(perms))
+			Expect(claims.UserProperties).To(Equal(map[string]string{"group": "admins", LegacyCertificateProperty: "bob.mcollective"}))
+			Expect(claims.Purpose).To(Equal(ClientIDPurpose))
+			Expect(claims.Issuer).To(Equal("ginkgo"))
+		})
+
+		It("Should use the default organization when no OU is mapped", func() {
+			claims, err := NewClientIDClaimsFromLegacyCertificate(newCert("bob.mcollective", "other"), LegacyCertificateMapping{
+				OUMap:    map[string]string{"dev": "development"},
+				Validity: time.Hour,
+			}, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(claims.OrganizationUnit).To(Equal(defaultOrg))
+
+			claims, err = NewClientIDClaimsFromLegacyCertificate(newCert("bob.mcollective"), LegacyCertificateMapping{
+				DefaultOrganization: "legacy",
+				Validity:            time.Hour,
+			}, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(claims.OrganizationUnit).To(Equal("legacy"))
+		})
+	})
+
+	Describe("NewClientIDClaimsFromLegacyCertificateFile", func() {
+		It("Should read PEM certificates", func() {
+			td := GinkgoT().TempDir()
+			file := filepath.Join(td, "bob.pem")
+
+			_, err := NewClientIDClaimsFromLegacyCertificateFile(file, LegacyCertificateMapping{}, nil)
+			Expect(err).To(MatchError(ContainSubstring("could not read certificate")))
+
+			Expect(os.WriteFile(file, []byte("garbage"), 0600)).To(Succeed())
+			_, err = NewClientIDClaimsFromLegacyCertificateFile(file, LegacyCertificateMapping{}, nil)
+			Expect(err).To(MatchError(ContainSubstring("no PEM encoded certificate found")))
+
+			cert := newCert("bob.mcollective")
+			Expect(os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}), 0600)).To(Succeed())
+			claims, err := NewClientIDClaimsFromLegacyCertificateFile(file, LegacyCertificateMapping{Validity: time.Hour}, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(claims.CallerID).To(Equal("choria=bob"))
+		})
+	})
+})
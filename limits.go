@@ -0,0 +1,79 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// ResourceLimits are ceilings the broker should enforce for connections using a token, zero values are unlimited
+type ResourceLimits struct {
+	// MaxPayload is the largest message in bytes the connection may publish
+	MaxPayload int64 `json:"max_payload,omitempty"`
+
+	// MaxConnections is the number of concurrent connections allowed using the token
+	MaxConnections int64 `json:"max_connections,omitempty"`
+
+	// MaxSubscriptions is the number of subscriptions a connection may hold
+	MaxSubscriptions int64 `json:"max_subscriptions,omitempty"`
+}
+
+// WithResourceLimits sets resource limits on client and server tokens
+func WithResourceLimits(limits ResourceLimits) ClaimsOption {
+	return func(o *claimsOptions) error {
+		err := limits.Validate()
+		if err != nil {
+			return err
+		}
+
+		o.limits = &limits
+
+		return nil
+	}
+}
+
+// Validate ensures no limit is negative
+func (l *ResourceLimits) Validate() error {
+	if l == nil {
+		return nil
+	}
+
+	switch {
+	case l.MaxPayload < 0:
+		return fmt.Errorf("max payload cannot be negative")
+	case l.MaxConnections < 0:
+		return fmt.Errorf("max connections cannot be negative")
+	case l.MaxSubscriptions < 0:
+		return fmt.Errorf("max subscriptions cannot be negative")
+	}
+
+	return nil
+}
+
+// IsUnlimited determines if no limits are set
+func (l *ResourceLimits) IsUnlimited() bool {
+	return l == nil || *l == ResourceLimits{}
+}
+
+type resourceLimitedClaims interface {
+	resourceLimits() *ResourceLimits
+}
+
+func (c *ClientIDClaims) resourceLimits() *ResourceLimits { return c.Limits }
+func (c *ServerClaims) resourceLimits() *ResourceLimits   { return c.Limits }
+
+// ResourceLimitsFromClaims retrieves a copy of the limits set in claims, nil when the claims do not support or hold limits
+func ResourceLimitsFromClaims(claims jwt.Claims) *ResourceLimits {
+	c, ok := claims.(resourceLimitedClaims)
+	if !ok || c.resourceLimits() == nil {
+		return nil
+	}
+
+	limits := *c.resourceLimits()
+
+	return &limits
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ResourceLimits", func() {
+	var (
+		pubK ed25519.PublicKey
+		priK ed25519.PrivateKey
+	)
+
+	BeforeEach(func() {
+		var err error
+		pubK, priK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Should validate limits", func() {
+		_, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, nil, WithResourceLimits(ResourceLimits{MaxPayload: -1}))
+		Expect(err).To(MatchError("max payload cannot be negative"))
+
+		Expect((&ResourceLimits{MaxConnections: -1}).Validate()).To(MatchError("max connections cannot be negative"))
+		Expect((&ResourceLimits{MaxSubscriptions: -1}).Validate()).To(MatchError("max subscriptions cannot be negative"))
+
+		client := &ClientIDClaims{CallerID: "up=bob", Limits: &ResourceLimits{MaxSubscriptions: -1}}
+		Expect(client.Validate()).To(MatchError("max subscriptions cannot be negative"))
+	})
+
+	It("Should detect unlimited limits", func() {
+		var limits *ResourceLimits
+		Expect(limits.IsUnlimited()).To(BeTrue())
+		Expect((&ResourceLimits{}).IsUnlimited()).To(BeTrue())
+		Expect((&ResourceLimits{MaxPayload: 1024}).IsUnlimited()).To(BeFalse())
+	})
+
+	It("Should store limits in client and server tokens", func() {
+		limits := ResourceLimits{MaxPayload: 1024 * 1024, MaxConnections: 2, MaxSubscriptions: 100}
+
+		client, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, nil, WithResourceLimits(limits))
+		Expect(err).ToNot(HaveOccurred())
+		token, err := SignToken(client, priK)
+		Expect(err).ToNot(HaveOccurred())
+		parsedClient, err := ParseClientIDToken(token, pubK, true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ResourceLimitsFromClaims(parsedClient)).To(Equal(&limits))
+
+		serverPubK, _, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		server, err := NewServerClaims("n1.example.net", []string{"choria"}, "", nil, nil, serverPubK, "", time.Hour, WithResourceLimits(limits))
+		Expect(err).ToNot(HaveOccurred())
+		token, err = SignToken(server, priK)
+		Expect(err).ToNot(HaveOccurred())
+		parsedServer, err := ParseServerToken(token, pubK)
+		Expect(err).ToNot(HaveOccurred())
+
+		found := ResourceLimitsFromClaims(parsedServer)
+		Expect(found).To(Equal(&limits))
+		found.MaxPayload = 1
+		Expect(parsedServer.Limits.MaxPayload).To(Equal(int64(1024 * 1024)))
+	})
+
+	It("Should return nil for claims without limits", func() {
+		Expect(ResourceLimitsFromClaims(&ClientIDClaims{})).To(BeNil())
+		Expect(ResourceLimitsFromClaims(&ProvisioningClaims{})).To(BeNil())
+	})
+
+	It("Should report new and lowered limits as reductions", func() {
+		client, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, pubK, WithResourceLimits(ResourceLimits{MaxPayload: 2048}))
+		Expect(err).ToNot(HaveOccurred())
+		token, err := SignToken(client, priK)
+		Expect(err).ToNot(HaveOccurred())
+
+		next := *client
+		next.Limits = &ResourceLimits{MaxPayload: 4096, MaxConnections: 1}
+		report, err := CompatibilityCheck(token, &next)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.Reductions()).To(HaveLen(1))
+		Expect(report.Reductions()[0].Field).To(Equal("limits.max_connections"))
+
+		next.Limits = &ResourceLimits{MaxPayload: 1024}
+		report, err = CompatibilityCheck(token, &next)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.Reductions()).To(HaveLen(1))
+		Expect(report.Reductions()[0].String()).To(Equal(`limits.max_payload: changed from "2048" to "1024"`))
+	})
+})
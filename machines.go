@@ -0,0 +1,129 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"errors"
+	"fmt"
+	"path"
+)
+
+// MachineAction is a lifecycle operation on Autonomous Agents
+type MachineAction string
+
+const (
+	// MachineDeployAction allows deploying Autonomous Agents
+	MachineDeployAction MachineAction = "deploy"
+
+	// MachineStopAction allows stopping and removing Autonomous Agents
+	MachineStopAction MachineAction = "stop"
+
+	// MachineViewAction allows viewing the state of Autonomous Agents
+	MachineViewAction MachineAction = "view"
+)
+
+// ErrMachinePermissionDenied indicates a client may not perform an operation on an Autonomous Agent
+var ErrMachinePermissionDenied = errors.New("autonomous agent permission denied")
+
+// MachineGrant grants lifecycle operations on Autonomous Agents, Machines and Nodes hold patterns using path.Match syntax
+type MachineGrant struct {
+	// Actions are the operations granted
+	Actions []MachineAction `json:"actions"`
+
+	// Machines are patterns of Autonomous Agent names the grant applies to, empty means all
+	Machines []string `json:"machines,omitempty"`
+
+	// Nodes are patterns of node identities the grant applies to, empty means all
+	Nodes []string `json:"nodes,omitempty"`
+}
+
+// Allows determines if the grant allows action on the machine called machine running on node
+func (g *MachineGrant) Allows(action MachineAction, machine string, node string) bool {
+	granted := false
+	for _, a := range g.Actions {
+		if a == action {
+			granted = true
+			break
+		}
+	}
+
+	return granted && matchAnyPattern(g.Machines, machine) && matchAnyPattern(g.Nodes, node)
+}
+
+func (g *MachineGrant) validate() error {
+	if len(g.Actions) == 0 {
+		return fmt.Errorf("autonomous agent grants require at least one action")
+	}
+
+	for _, a := range g.Actions {
+		switch a {
+		case MachineDeployAction, MachineStopAction, MachineViewAction:
+		default:
+			return fmt.Errorf("invalid autonomous agent action %q", a)
+		}
+	}
+
+	for _, pattern := range append(append([]string{}, g.Machines...), g.Nodes...) {
+		_, err := path.Match(pattern, "")
+		if err != nil {
+			return fmt.Errorf("invalid autonomous agent pattern %q: %w", pattern, err)
+		}
+	}
+
+	return nil
+}
+
+// CanManageMachine determines if the client may perform action on the Autonomous Agent called machine running on node.
+//
+// Only explicit grants and org admins are allowed, the fleet management permission does not grant access
+func (c *ClientIDClaims) CanManageMachine(action MachineAction, machine string, node string) bool {
+	if c.Permissions != nil && c.Permissions.OrgAdmin {
+		return true
+	}
+
+	for i := range c.Machines {
+		if c.Machines[i].Allows(action, machine, node) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AuthorizeMachine returns an error when the client may not perform action on the Autonomous Agent called machine running on node
+func (c *ClientIDClaims) AuthorizeMachine(action MachineAction, machine string, node string) error {
+	if !c.CanManageMachine(action, machine, node) {
+		return fmt.Errorf("%w: %s %s on %s", ErrMachinePermissionDenied, action, machine, node)
+	}
+
+	return nil
+}
+
+func validateMachineGrants(grants []MachineGrant) error {
+	for i := range grants {
+		err := grants[i].validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// matchAnyPattern checks value against path.Match patterns, an empty list matches everything
+func matchAnyPattern(patterns []string, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+
+	for _, pattern := range patterns {
+		ok, err := path.Match(pattern, value)
+		if err == nil && ok {
+			return true
+		}
+	}
+
+	return false
+}
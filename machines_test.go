@@ -0,0 +1,78 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Autonomous Agent Permissions", func() {
+	var claims *ClientIDClaims
+
+	BeforeEach(func() {
+		var err error
+		claims, err = NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, &ClientPermissions{FleetManagement: true}, nil)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Describe("CanManageMachine", func() {
+		It("Should not rely on fleet management", func() {
+			Expect(claims.CanManageMachine(MachineViewAction, "nagios", "n1.example.net")).To(BeFalse())
+			Expect(claims.AuthorizeMachine(MachineViewAction, "nagios", "n1.example.net")).To(MatchError(ErrMachinePermissionDenied))
+		})
+
+		It("Should allow org admins", func() {
+			claims.Permissions.OrgAdmin = true
+			Expect(claims.CanManageMachine(MachineDeployAction, "nagios", "n1.example.net")).To(BeTrue())
+		})
+
+		It("Should apply grants with target scoping", func() {
+			claims.Machines = []MachineGrant{
+				{Actions: []MachineAction{MachineViewAction}},
+				{Actions: []MachineAction{MachineDeployAction, MachineStopAction}, Machines: []string{"check_*"}, Nodes: []string{"*.dev.example.net"}},
+			}
+
+			Expect(claims.CanManageMachine(MachineViewAction, "anything", "n1.example.net")).To(BeTrue())
+			Expect(claims.CanManageMachine(MachineDeployAction, "check_disk", "n1.dev.example.net")).To(BeTrue())
+			Expect(claims.CanManageMachine(MachineStopAction, "check_disk", "n1.dev.example.net")).To(BeTrue())
+			Expect(claims.CanManageMachine(MachineDeployAction, "check_disk", "n1.prod.example.net")).To(BeFalse())
+			Expect(claims.CanManageMachine(MachineDeployAction, "nagios", "n1.dev.example.net")).To(BeFalse())
+			Expect(claims.AuthorizeMachine(MachineStopAction, "check_disk", "n1.dev.example.net")).To(Succeed())
+		})
+	})
+
+	Describe("Parsing", func() {
+		It("Should validate grants", func() {
+			pubK, priK, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+
+			claims.Machines = []MachineGrant{{Actions: []MachineAction{MachineViewAction}, Machines: []string{"check_*"}}}
+			token, err := SignToken(claims, priK)
+			Expect(err).ToNot(HaveOccurred())
+
+			parsed, err := ParseClientIDToken(token, pubK, true)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(parsed.CanManageMachine(MachineViewAction, "check_disk", "n1")).To(BeTrue())
+
+			for _, g := range []MachineGrant{
+				{},
+				{Actions: []MachineAction{"delete"}},
+				{Actions: []MachineAction{MachineViewAction}, Nodes: []string{"["}},
+			} {
+				claims.Machines = []MachineGrant{g}
+				token, err = SignToken(claims, priK)
+				Expect(err).ToNot(HaveOccurred())
+
+				_, err = ParseClientIDToken(token, pubK, true)
+				Expect(err).To(HaveOccurred())
+			}
+		})
+	})
+})
@@ -0,0 +1,120 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// VerificationFailure classifies why a token failed to parse for metrics
+type VerificationFailure string
+
+const (
+	// FailureNone indicates the token was parsed successfully
+	FailureNone VerificationFailure = ""
+
+	// FailureMalformed indicates the token could not be decoded
+	FailureMalformed VerificationFailure = "malformed"
+
+	// FailureStrict indicates the token was rejected by strict parsing
+	FailureStrict VerificationFailure = "strict"
+
+	// FailureSignature indicates the signature or issuer chain did not verify
+	FailureSignature VerificationFailure = "signature"
+
+	// FailureExpired indicates the token expired
+	FailureExpired VerificationFailure = "expired"
+
+	// FailureNotYetValid indicates the token is not valid yet or was issued in the future
+	FailureNotYetValid VerificationFailure = "not_yet_valid"
+
+	// FailureAudience indicates the token was issued for a different audience
+	FailureAudience VerificationFailure = "audience"
+
+	// FailureRevoked indicates the token was revoked or is no longer active
+	FailureRevoked VerificationFailure = "revoked"
+
+	// FailureReplayed indicates a single use token was presented again
+	FailureReplayed VerificationFailure = "replayed"
+
+	// FailureOther covers all other reasons such as invalid claims
+	FailureOther VerificationFailure = "other"
+)
+
+// MetricsRecorder receives measurements of token operations, implementations must be safe for concurrent use and fast
+type MetricsRecorder interface {
+	// ObserveSign is called after signing a token of purpose
+	ObserveSign(purpose Purpose, duration time.Duration, err error)
+
+	// ObserveParse is called after parsing a token of purpose, failure is FailureNone for valid tokens
+	ObserveParse(purpose Purpose, duration time.Duration, failure VerificationFailure)
+}
+
+type metricsRecorderHolder struct {
+	recorder MetricsRecorder
+}
+
+var metricsRecorder atomic.Value
+
+// SetMetricsRecorder sets the recorder that observes all signing and parsing, nil disables metrics
+func SetMetricsRecorder(recorder MetricsRecorder) {
+	metricsRecorder.Store(metricsRecorderHolder{recorder: recorder})
+}
+
+func currentMetricsRecorder() MetricsRecorder {
+	h, _ := metricsRecorder.Load().(metricsRecorderHolder)
+	return h.recorder
+}
+
+// VerificationFailureReason classifies an error returned while parsing a token
+func VerificationFailureReason(err error) VerificationFailure {
+	if err == nil {
+		return FailureNone
+	}
+
+	switch {
+	case errors.Is(err, ErrTokenRevoked), errors.Is(err, ErrTokenInactive):
+		return FailureRevoked
+	case errors.Is(err, ErrTokenReplayed):
+		return FailureReplayed
+	case errors.Is(err, ErrTokenTooLarge), errors.Is(err, ErrInvalidTokenType), errors.Is(err, ErrUnexpectedAlgorithm), errors.Is(err, ErrDuplicateJSONKey):
+		return FailureStrict
+	case errors.Is(err, jwt.ErrTokenInvalidAudience):
+		return FailureAudience
+	case isSignatureError(err):
+		return FailureSignature
+	}
+
+	var ve *jwt.ValidationError
+	if errors.As(err, &ve) {
+		switch {
+		case ve.Errors&jwt.ValidationErrorMalformed != 0:
+			return FailureMalformed
+		case ve.Errors&jwt.ValidationErrorExpired != 0:
+			return FailureExpired
+		case ve.Errors&(jwt.ValidationErrorNotValidYet|jwt.ValidationErrorIssuedAt) != 0:
+			return FailureNotYetValid
+		}
+	}
+
+	return FailureOther
+}
+
+// claimsPurpose is the purpose set in claims, falling back to the purpose found in token unless it is oversized
+func claimsPurpose(claims jwt.Claims, token string) Purpose {
+	if sc, ok := claims.(standardClaimsProvider); ok && sc.getStandardClaims().Purpose != UnknownPurpose {
+		return sc.getStandardClaims().Purpose
+	}
+
+	if token == "" || len(token) > DefaultMaxTokenSize {
+		return UnknownPurpose
+	}
+
+	return TokenPurpose(token)
+}
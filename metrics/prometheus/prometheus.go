@@ -0,0 +1,118 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package prometheus records token signing and parsing metrics using Prometheus collectors
+package prometheus
+
+import (
+	"time"
+
+	"github.com/choria-io/tokens"
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// Recorder is a tokens.MetricsRecorder backed by Prometheus collectors
+type Recorder struct {
+	signed        *prom.CounterVec
+	signTime      *prom.HistogramVec
+	parsed        *prom.CounterVec
+	parseTime     *prom.HistogramVec
+	parseFailures *prom.CounterVec
+}
+
+var _ tokens.MetricsRecorder = (*Recorder)(nil)
+
+// New creates a Recorder with metrics named using namespace, the collectors still have to be registered
+func New(namespace string) *Recorder {
+	return &Recorder{
+		signed: prom.NewCounterVec(prom.CounterOpts{
+			Namespace: namespace,
+			Name:      "tokens_signed_total",
+			Help:      "The number of tokens signed by purpose and outcome",
+		}, []string{"purpose", "result"}),
+
+		signTime: prom.NewHistogramVec(prom.HistogramOpts{
+			Namespace: namespace,
+			Name:      "tokens_sign_seconds",
+			Help:      "Time taken to sign tokens",
+			Buckets:   prom.ExponentialBuckets(0.00005, 2, 16),
+		}, []string{"purpose"}),
+
+		parsed: prom.NewCounterVec(prom.CounterOpts{
+			Namespace: namespace,
+			Name:      "tokens_parsed_total",
+			Help:      "The number of tokens parsed by purpose",
+		}, []string{"purpose"}),
+
+		parseTime: prom.NewHistogramVec(prom.HistogramOpts{
+			Namespace: namespace,
+			Name:      "tokens_parse_seconds",
+			Help:      "Time taken to parse and verify tokens",
+			Buckets:   prom.ExponentialBuckets(0.00005, 2, 16),
+		}, []string{"purpose"}),
+
+		parseFailures: prom.NewCounterVec(prom.CounterOpts{
+			Namespace: namespace,
+			Name:      "tokens_verification_failures_total",
+			Help:      "The number of tokens that failed verification by purpose and reason",
+		}, []string{"purpose", "reason"}),
+	}
+}
+
+// Register creates a Recorder, registers its collectors with reg and installs it using tokens.SetMetricsRecorder
+func Register(namespace string, reg prom.Registerer) (*Recorder, error) {
+	r := New(namespace)
+
+	for _, c := range r.Collectors() {
+		err := reg.Register(c)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tokens.SetMetricsRecorder(r)
+
+	return r, nil
+}
+
+// Collectors are all the collectors used by the recorder
+func (r *Recorder) Collectors() []prom.Collector {
+	return []prom.Collector{r.signed, r.signTime, r.parsed, r.parseTime, r.parseFailures}
+}
+
+// ObserveSign implements tokens.MetricsRecorder
+func (r *Recorder) ObserveSign(purpose tokens.Purpose, duration time.Duration, err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+
+	r.signed.WithLabelValues(purposeLabel(purpose), result).Inc()
+	r.signTime.WithLabelValues(purposeLabel(purpose)).Observe(duration.Seconds())
+}
+
+// ObserveParse implements tokens.MetricsRecorder
+func (r *Recorder) ObserveParse(purpose tokens.Purpose, duration time.Duration, failure tokens.VerificationFailure) {
+	p := purposeLabel(purpose)
+
+	r.parsed.WithLabelValues(p).Inc()
+	r.parseTime.WithLabelValues(p).Observe(duration.Seconds())
+
+	if failure != tokens.FailureNone {
+		r.parseFailures.WithLabelValues(p, string(failure)).Inc()
+	}
+}
+
+// purposeLabel limits label values to registered purposes so untrusted tokens cannot create unbounded series
+func purposeLabel(purpose tokens.Purpose) string {
+	if purpose == tokens.UnknownPurpose {
+		return "unknown"
+	}
+
+	if !tokens.IsRegisteredPurpose(purpose) {
+		return "other"
+	}
+
+	return string(purpose)
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheus
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/choria-io/tokens"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestPrometheus(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Metrics/Prometheus")
+}
+
+var _ = Describe("Recorder", func() {
+	It("Should record signing and parsing", func() {
+		reg := prom.NewRegistry()
+		r, err := Register("choria", reg)
+		Expect(err).ToNot(HaveOccurred())
+		DeferCleanup(func() { tokens.SetMetricsRecorder(nil) })
+
+		_, err = Register("choria", reg)
+		Expect(err).To(HaveOccurred())
+
+		pubK, priK, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		client, err := tokens.NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		token, err := tokens.SignToken(client, priK)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = tokens.ParseClientIDToken(token, pubK, true)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = tokens.ParseClientIDToken(token, pubK, true, tokens.WithExpectedAudience("other"))
+		Expect(err).To(HaveOccurred())
+		r.ObserveParse("acme_widget", time.Millisecond, tokens.FailureOther)
+
+		Expect(testutil.ToFloat64(r.signed.WithLabelValues("choria_client_id", "success"))).To(Equal(1.0))
+		Expect(testutil.ToFloat64(r.parsed.WithLabelValues("choria_client_id"))).To(Equal(2.0))
+		Expect(testutil.ToFloat64(r.parseFailures.WithLabelValues("choria_client_id", "audience"))).To(Equal(1.0))
+		Expect(testutil.ToFloat64(r.parseFailures.WithLabelValues("other", "other"))).To(Equal(1.0))
+		Expect(testutil.CollectAndCount(r.parseTime)).To(Equal(2))
+	})
+})
@@ -0,0 +1,119 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type ginkgoMetricsRecorder struct {
+	signed   []Purpose
+	parsed   []Purpose
+	failures []VerificationFailure
+	mu       sync.Mutex
+}
+
+func (r *ginkgoMetricsRecorder) ObserveSign(purpose Purpose, _ time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err == nil {
+		r.signed = append(r.signed, purpose)
+	}
+}
+
+func (r *ginkgoMetricsRecorder) ObserveParse(purpose Purpose, _ time.Duration, failure VerificationFailure) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.parsed = append(r.parsed, purpose)
+	r.failures = append(r.failures, failure)
+}
+
+var _ = Describe("Metrics", func() {
+	var (
+		pubK     ed25519.PublicKey
+		priK     ed25519.PrivateKey
+		recorder *ginkgoMetricsRecorder
+	)
+
+	BeforeEach(func() {
+		var err error
+		pubK, priK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		recorder = &ginkgoMetricsRecorder{}
+		SetMetricsRecorder(recorder)
+		DeferCleanup(func() { SetMetricsRecorder(nil) })
+	})
+
+	It("Should observe signing and parsing", func() {
+		client, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		token, err := SignToken(client, priK)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(ParseToken(token, &ClientIDClaims{}, pubK)).To(Succeed())
+		Expect(ParseToken(token, &jwt.MapClaims{}, pubK)).To(Succeed())
+
+		otherPubK, _, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ParseToken(token, &ClientIDClaims{}, otherPubK)).ToNot(Succeed())
+
+		Expect(recorder.signed).To(Equal([]Purpose{ClientIDPurpose}))
+		Expect(recorder.parsed).To(Equal([]Purpose{ClientIDPurpose, ClientIDPurpose, ClientIDPurpose}))
+		Expect(recorder.failures).To(Equal([]VerificationFailure{FailureNone, FailureNone, FailureSignature}))
+
+		SetMetricsRecorder(nil)
+		Expect(ParseToken(token, &ClientIDClaims{}, pubK)).To(Succeed())
+		Expect(recorder.parsed).To(HaveLen(3))
+	})
+
+	It("Should classify failures", func() {
+		client, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		client.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+		expired, err := SignToken(client, priK)
+		Expect(err).ToNot(HaveOccurred())
+
+		client.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Hour))
+		client.NotBefore = jwt.NewNumericDate(time.Now().Add(time.Minute))
+		future, err := SignToken(client, priK)
+		Expect(err).ToNot(HaveOccurred())
+
+		client.NotBefore = nil
+		valid, err := SignToken(client, priK)
+		Expect(err).ToNot(HaveOccurred())
+
+		rl, err := NewRevocationList()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rl.RevokeTokenID(client.ID, "")).To(Succeed())
+
+		cases := []struct {
+			err    error
+			reason VerificationFailure
+		}{
+			{ParseToken("invalid", &ClientIDClaims{}, pubK), FailureMalformed},
+			{ParseToken(expired, &ClientIDClaims{}, pubK), FailureExpired},
+			{ParseToken(future, &ClientIDClaims{}, pubK), FailureNotYetValid},
+			{ParseToken(valid, &ClientIDClaims{}, pubK, WithExpectedAudience("other")), FailureAudience},
+			{ParseToken(valid, &ClientIDClaims{}, pubK, WithRevocations(rl)), FailureRevoked},
+			{ParseToken(valid, &ClientIDClaims{}, pubK, WithStrictParsing(10)), FailureStrict},
+			{fmt.Errorf("something else"), FailureOther},
+		}
+
+		for i, c := range cases {
+			Expect(VerificationFailureReason(c.err)).To(Equal(c.reason), fmt.Sprintf("case %d: %v", i, c.err))
+		}
+	})
+})
@@ -0,0 +1,287 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !js
+
+package tokens
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/sirupsen/logrus"
+)
+
+// NatsConnectionName derives a NATS connection name and client tags from the claims in token so that broker
+// side monitoring can attribute connections, the token is not verified.
+//
+// The name is in the format purpose:org:identity and the tags are purpose:, org:, identity: and jti: prefixed
+// values, org is omitted for tokens that do not belong to an organization
+func NatsConnectionName(token string) (name string, tags []string, err error) {
+	purpose := TokenPurpose(token)
+	if purpose == UnknownPurpose {
+		return "", nil, fmt.Errorf("unsupported token purpose: %v", purpose)
+	}
+
+	claims := newClaimsForPurpose(purpose)
+	_, err = parseUnverified(token, claims)
+	if err != nil {
+		return "", nil, err
+	}
+
+	raw := jwt.MapClaims{}
+	_, err = parseUnverified(token, raw)
+	if err != nil {
+		return "", nil, err
+	}
+
+	identity := claimsIdentity(claims)
+	if identity == "" {
+		return "", nil, fmt.Errorf("token does not have an identity")
+	}
+
+	org, _ := raw["ou"].(string)
+
+	name = fmt.Sprintf("%s:%s:%s", purpose, org, identity)
+	tags = []string{"purpose:" + string(purpose)}
+
+	if org != "" {
+		tags = append(tags, "org:"+org)
+	}
+
+	tags = append(tags, "identity:"+identity)
+
+	if sc, ok := claims.(standardClaimsProvider); ok && sc.getStandardClaims().ID != "" {
+		tags = append(tags, "jti:"+sc.getStandardClaims().ID)
+	}
+
+	return name, tags, nil
+}
+
+// DefaultNatsInboxTemplate is the template used to create private inboxes for NATS connections
+const DefaultNatsInboxTemplate = "{collective}.reply.{uid}"
+
+// NatsConnectionOption configures optional behavior of the NATS connection helpers
+type NatsConnectionOption func(*natsConnectionOptions) error
+
+type natsConnectionOptions struct {
+	collective    string
+	inboxTemplate string
+}
+
+// WithNatsCollective sets the collective used to create the private inbox, overriding any collective passed to the
+// helpers or found in the token
+func WithNatsCollective(collective string) NatsConnectionOption {
+	return func(o *natsConnectionOptions) error {
+		o.collective = collective
+		return nil
+	}
+}
+
+// WithNatsInboxTemplate sets the template used to create the private inbox, {collective} and {uid} are replaced by
+// the collective and the unique id of the token.  The unique id is required so connections cannot share an inbox,
+// templates without {collective} do not require a collective
+func WithNatsInboxTemplate(template string) NatsConnectionOption {
+	return func(o *natsConnectionOptions) error {
+		if !strings.Contains(template, "{uid}") {
+			return fmt.Errorf("inbox template must include {uid}")
+		}
+
+		o.inboxTemplate = template
+
+		return nil
+	}
+}
+
+// WithNatsInboxPrefix creates private inboxes as prefix.uid rather than collective.reply.uid
+func WithNatsInboxPrefix(prefix string) NatsConnectionOption {
+	return func(o *natsConnectionOptions) error {
+		err := ValidateSubjectLiteral(prefix)
+		if err != nil {
+			return fmt.Errorf("invalid inbox prefix: %w", err)
+		}
+
+		o.inboxTemplate = prefix + ".{uid}"
+
+		return nil
+	}
+}
+
+func newNatsConnectionOptions(collective string, opts ...NatsConnectionOption) (*natsConnectionOptions, error) {
+	nopts := &natsConnectionOptions{collective: collective, inboxTemplate: DefaultNatsInboxTemplate}
+	for _, opt := range opts {
+		err := opt(nopts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if nopts.collective == "" && strings.Contains(nopts.inboxTemplate, "{collective}") {
+		return nil, fmt.Errorf("collective is required")
+	}
+
+	if nopts.collective != "" {
+		err := ValidateSubjectToken(nopts.collective)
+		if err != nil {
+			return nil, fmt.Errorf("invalid collective: %w", err)
+		}
+	}
+
+	return nopts, nil
+}
+
+// inbox creates the private inbox for a connection using a token with unique id uid
+func (o *natsConnectionOptions) inbox(uid string) (string, error) {
+	inbox := strings.NewReplacer("{collective}", o.collective, "{uid}", uid).Replace(o.inboxTemplate)
+
+	err := ValidateSubjectLiteral(inbox)
+	if err != nil {
+		return "", fmt.Errorf("invalid inbox: %w", err)
+	}
+
+	return inbox, nil
+}
+
+// NatsConnectionHelpers constructs token based private inbox and helpers for the nats.UserJWT() function. Tokens of any registered purpose with claims that implement UniqueID() are supported, NatsConnectionName can be used to name the connection.
+//
+// The inbox is collective.reply.uid unless changed using WithNatsInboxTemplate or WithNatsInboxPrefix
+func NatsConnectionHelpers(token string, collective string, seedFile string, log *logrus.Entry, opts ...NatsConnectionOption) (inbox string, jwth func() (string, error), sigh func([]byte) ([]byte, error), err error) {
+	nopts, err := newNatsConnectionOptions(collective, opts...)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	if seedFile == "" {
+		return "", nil, nil, fmt.Errorf("seedfile is required")
+	}
+
+	return natsConnectionHelpers(token, nopts, func(n []byte) ([]byte, error) {
+		log.Debugf("Signing nonce using seed file %s", seedFile)
+		return ed25519SignWithSeedFile(seedFile, n)
+	}, log)
+}
+
+// NatsConnectionHelpersWithKey behaves like NatsConnectionHelpers but signs nonces using the in memory key pk
+func NatsConnectionHelpersWithKey(token string, collective string, pk ed25519.PrivateKey, log *logrus.Entry, opts ...NatsConnectionOption) (inbox string, jwth func() (string, error), sigh func([]byte) ([]byte, error), err error) {
+	nopts, err := newNatsConnectionOptions(collective, opts...)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	if len(pk) != ed25519.PrivateKeySize {
+		return "", nil, nil, fmt.Errorf("invalid private key size")
+	}
+
+	return natsConnectionHelpers(token, nopts, func(n []byte) ([]byte, error) {
+		return ed25519Sign(pk, n)
+	}, log)
+}
+
+// NatsConnectionHelpersWithSeed behaves like NatsConnectionHelpers but signs nonces using a hex encoded seed
+func NatsConnectionHelpersWithSeed(token string, collective string, seed string, log *logrus.Entry, opts ...NatsConnectionOption) (inbox string, jwth func() (string, error), sigh func([]byte) ([]byte, error), err error) {
+	_, err = newNatsConnectionOptions(collective, opts...)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	if seed == "" {
+		return "", nil, nil, fmt.Errorf("seed is required")
+	}
+
+	sb, err := hex.DecodeString(strings.TrimSpace(seed))
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("invalid seed: %w", err)
+	}
+
+	_, pk, err := ed25519KeyPairFromSeed(sb)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	return NatsConnectionHelpersWithKey(token, collective, pk, log, opts...)
+}
+
+// NatsConnectionHelpersWithSigner behaves like NatsConnectionHelpers but signs nonces using signer, typically backed
+// by a KMS or HSM holding an ed25519 key
+func NatsConnectionHelpersWithSigner(token string, collective string, signer crypto.Signer, log *logrus.Entry, opts ...NatsConnectionOption) (inbox string, jwth func() (string, error), sigh func([]byte) ([]byte, error), err error) {
+	nopts, err := newNatsConnectionOptions(collective, opts...)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	if signer == nil {
+		return "", nil, nil, fmt.Errorf("signer is required")
+	}
+
+	if _, ok := signer.Public().(ed25519.PublicKey); !ok {
+		return "", nil, nil, fmt.Errorf("signer does not hold an ed25519 key")
+	}
+
+	return natsConnectionHelpers(token, nopts, func(n []byte) ([]byte, error) {
+		sig, _, err := ed25519SignWithSigner(signer, n)
+		return sig, err
+	}, log)
+}
+
+// natsConnectionHelpers creates the inbox and handlers for token, nonces are signed using sign while the token is valid
+func natsConnectionHelpers(token string, nopts *natsConnectionOptions, sign func([]byte) ([]byte, error), log *logrus.Entry) (inbox string, jwth func() (string, error), sigh func([]byte) ([]byte, error), err error) {
+	claims, err := parseNatsConnectionClaims(token)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	_, uid := claims.UniqueID()
+	isExp := claims.IsExpired
+	exp := claims.ExpireTime()
+
+	inbox, err = nopts.inbox(uid)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	jwth = func() (string, error) {
+		if isExp() {
+			log.Errorf("Cannot sign connection NONCE: token is expired by %v", time.Since(exp))
+			return "", fmt.Errorf("token expired")
+		}
+		return token, nil
+	}
+
+	sigh = func(n []byte) ([]byte, error) {
+		if isExp() {
+			log.Errorf("Cannot sign connection NONCE: token is expired by %v", time.Since(exp))
+			return nil, fmt.Errorf("token expired")
+		}
+		return sign(n)
+	}
+
+	return inbox, jwth, sigh, nil
+}
+
+// parseNatsConnectionClaims decodes, without verifying, token into claims suitable for NATS connections
+func parseNatsConnectionClaims(token string) (natsConnectionClaims, error) {
+	purpose := TokenPurpose(token)
+
+	factory, ok := purposeFactory(purpose)
+	if !ok {
+		return nil, fmt.Errorf("unsupported token purpose: %v", purpose)
+	}
+
+	claims, ok := factory().(natsConnectionClaims)
+	if !ok {
+		return nil, fmt.Errorf("unsupported token purpose: %v", purpose)
+	}
+
+	_, err := parseUnverified(token, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,196 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !js
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+)
+
+var _ = Describe("NatsConnectionName", func() {
+	var priK ed25519.PrivateKey
+
+	BeforeEach(func() {
+		var err error
+		_, priK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Should name client connections", func() {
+		claims, err := NewClientIDClaims("up=bob", nil, "acme", nil, "", "", time.Hour, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		token, err := SignToken(claims, priK)
+		Expect(err).ToNot(HaveOccurred())
+
+		name, tags, err := NatsConnectionName(token)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(name).To(Equal("choria_client_id:acme:up=bob"))
+		Expect(tags).To(Equal([]string{"purpose:choria_client_id", "org:acme", "identity:up=bob", "jti:" + claims.ID}))
+	})
+
+	It("Should name server connections", func() {
+		pubK, _, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		claims, err := NewServerClaims("ginkgo.example.net", []string{"choria"}, "", nil, nil, pubK, "", time.Hour)
+		Expect(err).ToNot(HaveOccurred())
+		token, err := SignToken(claims, priK)
+		Expect(err).ToNot(HaveOccurred())
+
+		name, tags, err := NatsConnectionName(token)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(name).To(Equal("choria_server:choria:ginkgo.example.net"))
+		Expect(tags).To(ContainElement("identity:ginkgo.example.net"))
+	})
+
+	It("Should omit the org for tokens without one", func() {
+		token, err := os.ReadFile("testdata/ed25519/good-provisioning.jwt")
+		Expect(err).ToNot(HaveOccurred())
+
+		name, tags, err := NatsConnectionName(string(token))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(name).To(HavePrefix("choria_provisioning::"))
+		Expect(tags).ToNot(ContainElement(HavePrefix("org:")))
+	})
+
+	It("Should fail for invalid tokens", func() {
+		_, _, err := NatsConnectionName("invalid")
+		Expect(err).To(MatchError("unsupported token purpose: "))
+	})
+})
+
+var _ = Describe("NatsConnectionHelpers variants", func() {
+	var (
+		pubK  ed25519.PublicKey
+		priK  ed25519.PrivateKey
+		token string
+		log   *logrus.Entry
+	)
+
+	BeforeEach(func() {
+		pubK, priK = loadEd25519Seed("testdata/ed25519/other.seed")
+
+		claims, err := NewClientIDClaims("ginkgo", nil, "choria", nil, "", "", time.Hour, nil, pubK)
+		Expect(err).ToNot(HaveOccurred())
+		token, err = SignToken(claims, priK)
+		Expect(err).ToNot(HaveOccurred())
+
+		log = logrus.NewEntry(logrus.New())
+		log.Logger.SetOutput(GinkgoWriter)
+	})
+
+	check := func(inbox string, jh func() (string, error), sigh func([]byte) ([]byte, error), err error) {
+		Expect(err).ToNot(HaveOccurred())
+		Expect(inbox).To(Equal("choria.reply.4bb6777bb903cae3166e826932f7fe94"))
+		Expect(jh()).To(Equal(token))
+
+		sig, err := sigh([]byte("toomanysecrets"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ed25519.Verify(pubK, []byte("toomanysecrets"), sig)).To(BeTrue())
+	}
+
+	It("Should support in memory keys", func() {
+		check(NatsConnectionHelpersWithKey(token, "choria", priK, log))
+
+		_, _, _, err := NatsConnectionHelpersWithKey(token, "choria", nil, log)
+		Expect(err).To(MatchError("invalid private key size"))
+		_, _, _, err = NatsConnectionHelpersWithKey(token, "", priK, log)
+		Expect(err).To(MatchError("collective is required"))
+	})
+
+	It("Should support hex seeds", func() {
+		seed, err := os.ReadFile("testdata/ed25519/other.seed")
+		Expect(err).ToNot(HaveOccurred())
+		check(NatsConnectionHelpersWithSeed(token, "choria", string(seed)+"\n", log))
+
+		_, _, _, err = NatsConnectionHelpersWithSeed(token, "choria", "", log)
+		Expect(err).To(MatchError("seed is required"))
+		_, _, _, err = NatsConnectionHelpersWithSeed(token, "choria", "zz", log)
+		Expect(err).To(MatchError(ContainSubstring("invalid seed")))
+		_, _, _, err = NatsConnectionHelpersWithSeed(token, "choria", "abcd", log)
+		Expect(err).To(MatchError("invalid seed length"))
+	})
+
+	It("Should support crypto.Signer", func() {
+		check(NatsConnectionHelpersWithSigner(token, "choria", &ginkgoContextSigner{PrivateKey: priK}, log))
+
+		_, _, _, err := NatsConnectionHelpersWithSigner(token, "choria", nil, log)
+		Expect(err).To(MatchError("signer is required"))
+		_, _, _, err = NatsConnectionHelpersWithSigner(token, "choria", loadRSAPriKey("testdata/rsa/signer-key.pem"), log)
+		Expect(err).To(MatchError("signer does not hold an ed25519 key"))
+	})
+
+	It("Should refuse to sign using expired tokens", func() {
+		claims, err := NewClientIDClaims("ginkgo", nil, "choria", nil, "", "", time.Hour, nil, pubK)
+		Expect(err).ToNot(HaveOccurred())
+		claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+		expired, err := SignToken(claims, priK)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, jh, sigh, err := NatsConnectionHelpersWithKey(expired, "choria", priK, log)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = jh()
+		Expect(err).To(MatchError("token expired"))
+		_, err = sigh([]byte("x"))
+		Expect(err).To(MatchError("token expired"))
+	})
+})
+
+var _ = Describe("NatsConnectionOption", func() {
+	var (
+		priK  ed25519.PrivateKey
+		token string
+		log   *logrus.Entry
+	)
+
+	BeforeEach(func() {
+		var pubK ed25519.PublicKey
+		pubK, priK = loadEd25519Seed("testdata/ed25519/other.seed")
+
+		claims, err := NewClientIDClaims("ginkgo", nil, "choria", nil, "", "", time.Hour, nil, pubK)
+		Expect(err).ToNot(HaveOccurred())
+		token, err = SignToken(claims, priK)
+		Expect(err).ToNot(HaveOccurred())
+
+		log = logrus.NewEntry(logrus.New())
+		log.Logger.SetOutput(GinkgoWriter)
+	})
+
+	It("Should support inbox templates", func() {
+		inbox, _, _, err := NatsConnectionHelpersWithKey(token, "acme", priK, log, WithNatsInboxTemplate("tenants.{collective}.inbox.{uid}"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(inbox).To(Equal("tenants.acme.inbox.4bb6777bb903cae3166e826932f7fe94"))
+
+		inbox, _, _, err = NatsConnectionHelpers(token, "", "testdata/ed25519/other.seed", log, WithNatsInboxTemplate("_INBOX.{uid}"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(inbox).To(Equal("_INBOX.4bb6777bb903cae3166e826932f7fe94"))
+
+		_, _, _, err = NatsConnectionHelpersWithKey(token, "acme", priK, log, WithNatsInboxTemplate("{collective}.shared"))
+		Expect(err).To(MatchError("inbox template must include {uid}"))
+
+		_, _, _, err = NatsConnectionHelpersWithKey(token, "", priK, log, WithNatsInboxTemplate("{collective}.{uid}"))
+		Expect(err).To(MatchError("collective is required"))
+
+		_, _, _, err = NatsConnectionHelpersWithKey(token, "acme", priK, log, WithNatsInboxTemplate("a..{uid}"))
+		Expect(err).To(MatchError(ErrInvalidSubject))
+	})
+
+	It("Should support inbox prefixes", func() {
+		inbox, _, _, err := NatsConnectionHelpersWithSigner(token, "", priK, log, WithNatsInboxPrefix("acme.replies"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(inbox).To(Equal("acme.replies.4bb6777bb903cae3166e826932f7fe94"))
+
+		_, _, _, err = NatsConnectionHelpersWithSigner(token, "", priK, log, WithNatsInboxPrefix("acme.*"))
+		Expect(err).To(MatchError(ContainSubstring("invalid inbox prefix")))
+	})
+})
@@ -0,0 +1,207 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"fmt"
+)
+
+// NatsSubjectPermission lists subjects allowed and denied for publish or subscribe
+type NatsSubjectPermission struct {
+	// Allow are subjects that are allowed
+	Allow []string `json:"allow,omitempty"`
+
+	// Deny are subjects that are denied, deny takes precedence over allow
+	Deny []string `json:"deny,omitempty"`
+}
+
+// NatsPermissions describes the NATS publish and subscribe permissions a token should have on the broker
+type NatsPermissions struct {
+	// Publish are the publish permissions
+	Publish NatsSubjectPermission `json:"pub"`
+
+	// Subscribe are the subscribe permissions
+	Subscribe NatsSubjectPermission `json:"sub"`
+}
+
+var (
+	streamsUserPublishSubjects = []string{
+		"$JS.API.INFO",
+		"$JS.API.STREAM.NAMES",
+		"$JS.API.STREAM.LIST",
+		"$JS.API.STREAM.INFO.*",
+		"$JS.API.STREAM.MSG.GET.*",
+		"$JS.API.DIRECT.GET.*",
+		"$JS.API.CONSUMER.CREATE.*",
+		"$JS.API.CONSUMER.CREATE.*.>",
+		"$JS.API.CONSUMER.DURABLE.CREATE.*.*",
+		"$JS.API.CONSUMER.DELETE.*.*",
+		"$JS.API.CONSUMER.NAMES.*",
+		"$JS.API.CONSUMER.LIST.*",
+		"$JS.API.CONSUMER.INFO.*.*",
+		"$JS.API.CONSUMER.MSG.NEXT.*.*",
+		"$JS.ACK.>",
+		"$JS.FC.>",
+		"$KV.>",
+	}
+	streamsAdminSubjects     = []string{"$JS.>", "$KV.>", "$O.>"}
+	eventsViewerSubjects     = []string{"choria.lifecycle.event.>", "choria.machine.watcher.>", "choria.machine.transition"}
+	electionPublishSubjects  = []string{"$JS.API.STREAM.INFO.KV_CHORIA_LEADER_ELECTION", "$KV.CHORIA_LEADER_ELECTION.>"}
+	governorPublishSubjects  = []string{"$JS.API.STREAM.INFO.GOVERNOR_*", "$JS.API.STREAM.MSG.GET.GOVERNOR_*", "$JS.API.STREAM.MSG.DELETE.GOVERNOR_*", "$JS.API.DIRECT.GET.GOVERNOR_*", "choria.governor.>"}
+	serverStreamsSubjects    = []string{"$JS.API.STREAM.INFO.*", "$JS.API.STREAM.MSG.GET.*", "$JS.API.DIRECT.GET.*", "$KV.>"}
+	serverLifecycleSubjects  = []string{"choria.lifecycle.>", "choria.machine.>"}
+	federationPublishSubject = "choria.federation.*.federation"
+)
+
+// ClientNatsPermissions maps the permissions of a client token to the NATS permissions the Choria Broker grants it
+// when connected to collective, brokers using auth callout should use this to stay in line with the Choria Broker.
+//
+// SystemUser and ServerProvisioner select the broker account the client connects to and add no subjects, when
+// nothing is allowed all subjects are denied
+func ClientNatsPermissions(claims *ClientIDClaims, collective string) (*NatsPermissions, error) {
+	if claims == nil {
+		return nil, fmt.Errorf("claims are required")
+	}
+
+	err := validateCollectives([]string{collective})
+	if err != nil {
+		return nil, err
+	}
+
+	perms := &NatsPermissions{}
+	p := claims.Permissions
+	if p == nil {
+		p = &ClientPermissions{}
+	}
+
+	if p.OrgAdmin {
+		perms.Publish.Allow = []string{">"}
+		perms.Subscribe.Allow = []string{">"}
+		return perms, nil
+	}
+
+	_, uid := claims.UniqueID()
+	inbox := fmt.Sprintf("%s.reply.%s.>", collective, uid)
+
+	if p.FleetManagement || p.SignedFleetManagement {
+		perms.Publish.Allow = append(perms.Publish.Allow,
+			fmt.Sprintf("%s.broadcast.agent.>", collective),
+			fmt.Sprintf("%s.broadcast.service.>", collective),
+			fmt.Sprintf("%s.node.>", collective),
+			federationPublishSubject,
+		)
+	}
+
+	switch {
+	case p.StreamsAdmin:
+		perms.Publish.Allow = append(perms.Publish.Allow, streamsAdminSubjects...)
+		perms.Subscribe.Allow = append(perms.Subscribe.Allow, streamsAdminSubjects...)
+	case p.StreamsUser:
+		perms.Publish.Allow = append(perms.Publish.Allow, streamsUserPublishSubjects...)
+	}
+
+	if p.Governor && (p.StreamsAdmin || p.StreamsUser) {
+		perms.Publish.Allow = append(perms.Publish.Allow, governorPublishSubjects...)
+	}
+
+	if p.ElectionUser {
+		perms.Publish.Allow = append(perms.Publish.Allow, electionPublishSubjects...)
+	}
+
+	if p.EventsViewer {
+		perms.Subscribe.Allow = append(perms.Subscribe.Allow, eventsViewerSubjects...)
+	}
+
+	if len(perms.Publish.Allow) > 0 {
+		perms.Subscribe.Allow = append([]string{inbox}, perms.Subscribe.Allow...)
+	}
+
+	perms.Publish.Allow = append(perms.Publish.Allow, claims.AdditionalPublishSubjects...)
+	perms.Subscribe.Allow = append(perms.Subscribe.Allow, claims.AdditionalSubscribeSubjects...)
+
+	perms.denyUnlessAllowed()
+
+	return perms, nil
+}
+
+// ServerNatsPermissions maps the permissions of a server token to the NATS permissions the Choria Broker grants it
+// when connected to collective, the server must be a member of the collective
+func ServerNatsPermissions(claims *ServerClaims, collective string) (*NatsPermissions, error) {
+	if claims == nil {
+		return nil, fmt.Errorf("claims are required")
+	}
+
+	err := validateCollectives([]string{collective})
+	if err != nil {
+		return nil, err
+	}
+
+	member := false
+	for _, c := range claims.Collectives {
+		if c == collective {
+			member = true
+			break
+		}
+	}
+	if !member {
+		return nil, fmt.Errorf("server is not a member of collective %s", collective)
+	}
+
+	err = ValidateSubjectLiteral(claims.ChoriaIdentity)
+	if err != nil {
+		return nil, err
+	}
+
+	p := claims.Permissions
+	if p == nil {
+		p = &ServerPermissions{}
+	}
+
+	_, uid := claims.UniqueID()
+	perms := &NatsPermissions{}
+
+	perms.Subscribe.Allow = []string{
+		fmt.Sprintf("%s.broadcast.agent.>", collective),
+		fmt.Sprintf("%s.node.%s", collective, claims.ChoriaIdentity),
+		fmt.Sprintf("%s.reply.%s.>", collective, uid),
+	}
+
+	perms.Publish.Allow = append([]string{
+		fmt.Sprintf("%s.reply.>", collective),
+		fmt.Sprintf("%s.broadcast.agent.registration", collective),
+	}, serverLifecycleSubjects...)
+
+	if p.ServiceHost {
+		perms.Subscribe.Allow = append(perms.Subscribe.Allow, fmt.Sprintf("%s.broadcast.service.>", collective))
+	}
+
+	if p.Submission {
+		perms.Publish.Allow = append(perms.Publish.Allow, fmt.Sprintf("%s.submission.in.>", collective))
+	}
+
+	if p.Streams {
+		perms.Publish.Allow = append(perms.Publish.Allow, serverStreamsSubjects...)
+
+		if p.Governor {
+			perms.Publish.Allow = append(perms.Publish.Allow, governorPublishSubjects...)
+		}
+	}
+
+	perms.Publish.Allow = append(perms.Publish.Allow, claims.AdditionalPublishSubjects...)
+	perms.Subscribe.Allow = append(perms.Subscribe.Allow, claims.AdditionalSubscribeSubjects...)
+
+	return perms, nil
+}
+
+// denyUnlessAllowed denies all subjects for directions with nothing allowed
+func (p *NatsPermissions) denyUnlessAllowed() {
+	if len(p.Publish.Allow) == 0 {
+		p.Publish.Deny = []string{">"}
+	}
+
+	if len(p.Subscribe.Allow) == 0 {
+		p.Subscribe.Deny = []string{">"}
+	}
+}
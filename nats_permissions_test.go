@@ -0,0 +1,139 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NatsPermissions", func() {
+	Describe("ClientNatsPermissions", func() {
+		var client *ClientIDClaims
+
+		BeforeEach(func() {
+			client = &ClientIDClaims{CallerID: "up=bob"}
+		})
+
+		It("Should validate the input", func() {
+			_, err := ClientNatsPermissions(nil, "choria")
+			Expect(err).To(MatchError("claims are required"))
+
+			_, err = ClientNatsPermissions(client, "choria.>")
+			Expect(err).To(MatchError(ErrInvalidSubject))
+		})
+
+		It("Should deny everything without permissions", func() {
+			perms, err := ClientNatsPermissions(client, "choria")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(perms.Publish.Allow).To(BeEmpty())
+			Expect(perms.Publish.Deny).To(Equal([]string{">"}))
+			Expect(perms.Subscribe.Allow).To(BeEmpty())
+			Expect(perms.Subscribe.Deny).To(Equal([]string{">"}))
+		})
+
+		It("Should allow everything for org admins", func() {
+			client.Permissions = &ClientPermissions{OrgAdmin: true, FleetManagement: true}
+			perms, err := ClientNatsPermissions(client, "choria")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(perms.Publish.Allow).To(Equal([]string{">"}))
+			Expect(perms.Subscribe.Allow).To(Equal([]string{">"}))
+			Expect(perms.Publish.Deny).To(BeEmpty())
+		})
+
+		It("Should map fleet management", func() {
+			client.Permissions = &ClientPermissions{FleetManagement: true}
+			client.AdditionalPublishSubjects = []string{"custom.pub"}
+			client.AdditionalSubscribeSubjects = []string{"custom.sub"}
+			_, uid := client.UniqueID()
+
+			perms, err := ClientNatsPermissions(client, "choria")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(perms.Publish.Allow).To(Equal([]string{
+				"choria.broadcast.agent.>",
+				"choria.broadcast.service.>",
+				"choria.node.>",
+				"choria.federation.*.federation",
+				"custom.pub",
+			}))
+			Expect(perms.Subscribe.Allow).To(Equal([]string{"choria.reply." + uid + ".>", "custom.sub"}))
+			Expect(perms.Publish.Deny).To(BeEmpty())
+			Expect(perms.Subscribe.Deny).To(BeEmpty())
+		})
+
+		It("Should map streams, governor, election and events access", func() {
+			client.Permissions = &ClientPermissions{Governor: true}
+			perms, err := ClientNatsPermissions(client, "choria")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(perms.Publish.Allow).To(BeEmpty())
+
+			client.Permissions = &ClientPermissions{StreamsUser: true, Governor: true, ElectionUser: true, EventsViewer: true}
+			perms, err = ClientNatsPermissions(client, "choria")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(perms.Publish.Allow).To(ContainElements("$JS.API.STREAM.INFO.*", "$JS.API.STREAM.INFO.GOVERNOR_*", "$KV.CHORIA_LEADER_ELECTION.>"))
+			Expect(perms.Publish.Allow).ToNot(ContainElement("$JS.>"))
+			Expect(perms.Subscribe.Allow).To(ContainElements("choria.lifecycle.event.>", "choria.machine.transition"))
+
+			client.Permissions = &ClientPermissions{StreamsAdmin: true}
+			perms, err = ClientNatsPermissions(client, "choria")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(perms.Publish.Allow).To(Equal([]string{"$JS.>", "$KV.>", "$O.>"}))
+			Expect(perms.Subscribe.Allow).To(ContainElement("$JS.>"))
+		})
+
+		It("Should allow only subscribing to events for viewers", func() {
+			client.Permissions = &ClientPermissions{EventsViewer: true}
+			perms, err := ClientNatsPermissions(client, "choria")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(perms.Subscribe.Allow).To(Equal([]string{"choria.lifecycle.event.>", "choria.machine.watcher.>", "choria.machine.transition"}))
+			Expect(perms.Publish.Deny).To(Equal([]string{">"}))
+		})
+	})
+
+	Describe("ServerNatsPermissions", func() {
+		var server *ServerClaims
+
+		BeforeEach(func() {
+			server = &ServerClaims{ChoriaIdentity: "n1.example.net", Collectives: []string{"choria"}}
+		})
+
+		It("Should validate the input", func() {
+			_, err := ServerNatsPermissions(nil, "choria")
+			Expect(err).To(MatchError("claims are required"))
+
+			_, err = ServerNatsPermissions(server, "other")
+			Expect(err).To(MatchError("server is not a member of collective other"))
+
+			server.ChoriaIdentity = "n1.>"
+			_, err = ServerNatsPermissions(server, "choria")
+			Expect(err).To(MatchError(ErrInvalidSubject))
+		})
+
+		It("Should map the standard server permissions", func() {
+			_, uid := server.UniqueID()
+			perms, err := ServerNatsPermissions(server, "choria")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(perms.Subscribe.Allow).To(Equal([]string{"choria.broadcast.agent.>", "choria.node.n1.example.net", "choria.reply." + uid + ".>"}))
+			Expect(perms.Publish.Allow).To(Equal([]string{"choria.reply.>", "choria.broadcast.agent.registration", "choria.lifecycle.>", "choria.machine.>"}))
+		})
+
+		It("Should map additional permissions", func() {
+			server.Permissions = &ServerPermissions{Submission: true, ServiceHost: true, Governor: true}
+			server.AdditionalPublishSubjects = []string{"custom.pub"}
+			server.AdditionalSubscribeSubjects = []string{"custom.sub"}
+
+			perms, err := ServerNatsPermissions(server, "choria")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(perms.Subscribe.Allow).To(ContainElements("choria.broadcast.service.>", "custom.sub"))
+			Expect(perms.Publish.Allow).To(ContainElements("choria.submission.in.>", "custom.pub"))
+			Expect(perms.Publish.Allow).ToNot(ContainElement("$JS.API.STREAM.INFO.GOVERNOR_*"))
+
+			server.Permissions.Streams = true
+			perms, err = ServerNatsPermissions(server, "choria")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(perms.Publish.Allow).To(ContainElements("$KV.>", "$JS.API.STREAM.INFO.GOVERNOR_*"))
+		})
+	})
+})
@@ -0,0 +1,134 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !js
+
+package tokens
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// reloadingToken tracks the most recent token obtained from a TokenSource
+type reloadingToken struct {
+	source TokenSource
+	token  string
+	uid    string
+	claims natsConnectionClaims
+	log    *logrus.Entry
+	mu     sync.Mutex
+}
+
+// current fetches the token from the source, when the source fails or supplies an unusable token the previous
+// token is used until it expires
+func (r *reloadingToken) current() (string, natsConnectionClaims) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, err := r.source()
+	switch {
+	case err != nil:
+		r.log.Warnf("Could not reload token, using previous token: %v", err)
+	case token != r.token:
+		err = r.update(token)
+		if err != nil {
+			r.log.Errorf("Could not use reloaded token, using previous token: %v", err)
+		}
+	}
+
+	return r.token, r.claims
+}
+
+func (r *reloadingToken) update(token string) error {
+	claims, err := parseNatsConnectionClaims(token)
+	if err != nil {
+		return err
+	}
+
+	id, uid := claims.UniqueID()
+	if r.uid != "" && uid != r.uid {
+		return fmt.Errorf("token identity changed to %s, reconnect to use it", id)
+	}
+
+	if r.token != "" {
+		r.log.Infof("Using renewed token for %s expiring at %v", id, claims.ExpireTime())
+	}
+
+	r.token = token
+	r.uid = uid
+	r.claims = claims
+
+	return nil
+}
+
+// NatsReloadingConnectionHelpers behaves like NatsConnectionHelpers but obtains the token from source every time
+// the connection authenticates, so long-running connections use renewed tokens without restarting.  Renewed tokens
+// must have the same identity as the initial token as the inbox cannot change, use FileTokenSource to read tokens
+// from disk
+func NatsReloadingConnectionHelpers(source TokenSource, collective string, signer crypto.Signer, log *logrus.Entry, opts ...NatsConnectionOption) (inbox string, jwth func() (string, error), sigh func([]byte) ([]byte, error), err error) {
+	nopts, err := newNatsConnectionOptions(collective, opts...)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	if source == nil {
+		return "", nil, nil, fmt.Errorf("token source is required")
+	}
+
+	if signer == nil {
+		return "", nil, nil, fmt.Errorf("signer is required")
+	}
+
+	if _, ok := signer.Public().(ed25519.PublicKey); !ok {
+		return "", nil, nil, fmt.Errorf("signer does not hold an ed25519 key")
+	}
+
+	token, err := source()
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("could not read token: %w", err)
+	}
+
+	rt := &reloadingToken{source: source, log: log}
+	err = rt.update(token)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	inbox, err = nopts.inbox(rt.uid)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	jwth = func() (string, error) {
+		token, claims := rt.current()
+		if claims.IsExpired() {
+			log.Errorf("Cannot sign connection NONCE: token is expired by %v", time.Since(claims.ExpireTime()))
+			return "", fmt.Errorf("token expired")
+		}
+
+		return token, nil
+	}
+
+	sigh = func(n []byte) ([]byte, error) {
+		rt.mu.Lock()
+		claims := rt.claims
+		rt.mu.Unlock()
+
+		if claims.IsExpired() {
+			log.Errorf("Cannot sign connection NONCE: token is expired by %v", time.Since(claims.ExpireTime()))
+			return nil, fmt.Errorf("token expired")
+		}
+
+		sig, _, err := ed25519SignWithSigner(signer, n)
+		return sig, err
+	}
+
+	return inbox, jwth, sigh, nil
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !js
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+)
+
+var _ = Describe("NatsReloadingConnectionHelpers", func() {
+	var (
+		pubK      ed25519.PublicKey
+		priK      ed25519.PrivateKey
+		log       *logrus.Entry
+		tokenFile string
+	)
+
+	issue := func(caller string, validity time.Duration) string {
+		claims, err := NewClientIDClaims(caller, nil, "choria", nil, "", "", validity, nil, pubK)
+		Expect(err).ToNot(HaveOccurred())
+		if validity < 0 {
+			claims.ExpiresAt.Time = time.Now().Add(validity)
+		}
+		token, err := SignToken(claims, priK)
+		Expect(err).ToNot(HaveOccurred())
+
+		return token
+	}
+
+	BeforeEach(func() {
+		var err error
+		pubK, priK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		log = logrus.NewEntry(logrus.New())
+		log.Logger.SetOutput(GinkgoWriter)
+
+		tokenFile = filepath.Join(GinkgoT().TempDir(), "token.jwt")
+	})
+
+	It("Should validate arguments", func() {
+		_, _, _, err := NatsReloadingConnectionHelpers(nil, "choria", priK, log)
+		Expect(err).To(MatchError("token source is required"))
+		_, _, _, err = NatsReloadingConnectionHelpers(FileTokenSource(tokenFile), "choria", nil, log)
+		Expect(err).To(MatchError("signer is required"))
+		_, _, _, err = NatsReloadingConnectionHelpers(FileTokenSource(tokenFile), "choria", priK, log)
+		Expect(err).To(MatchError(ContainSubstring("could not read token")))
+	})
+
+	It("Should pick up renewed tokens", func() {
+		initial := issue("ginkgo", time.Hour)
+		Expect(os.WriteFile(tokenFile, []byte(initial+"\n"), 0600)).To(Succeed())
+
+		inbox, jh, sigh, err := NatsReloadingConnectionHelpers(FileTokenSource(tokenFile), "choria", priK, log)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(inbox).To(Equal("choria.reply.4bb6777bb903cae3166e826932f7fe94"))
+		Expect(jh()).To(Equal(initial))
+
+		renewed := issue("ginkgo", 2*time.Hour)
+		Expect(os.WriteFile(tokenFile, []byte(renewed), 0600)).To(Succeed())
+		Expect(jh()).To(Equal(renewed))
+
+		sig, err := sigh([]byte("toomanysecrets"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ed25519.Verify(pubK, []byte("toomanysecrets"), sig)).To(BeTrue())
+
+		By("Keeping the previous token when the file is unusable")
+		Expect(os.Remove(tokenFile)).To(Succeed())
+		Expect(jh()).To(Equal(renewed))
+
+		Expect(os.WriteFile(tokenFile, []byte(issue("other", time.Hour)), 0600)).To(Succeed())
+		Expect(jh()).To(Equal(renewed))
+	})
+
+	It("Should support callbacks and fail for expired tokens", func() {
+		current := issue("ginkgo", time.Hour)
+		source := func() (string, error) {
+			if current == "" {
+				return "", errors.New("no token")
+			}
+			return current, nil
+		}
+
+		_, jh, sigh, err := NatsReloadingConnectionHelpers(source, "choria", priK, log)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(jh()).To(Equal(current))
+
+		current = issue("ginkgo", -time.Minute)
+		_, err = jh()
+		Expect(err).To(MatchError("token expired"))
+		_, err = sigh([]byte("x"))
+		Expect(err).To(MatchError("token expired"))
+
+		current = issue("ginkgo", time.Hour)
+		Expect(jh()).To(Equal(current))
+	})
+})
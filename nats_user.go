@@ -0,0 +1,128 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !js
+
+package tokens
+
+import (
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v4"
+	natsjwt "github.com/nats-io/jwt/v2"
+	"github.com/nats-io/nkeys"
+)
+
+// NatsUserPublicKey is the NATS user nkey matching the ed25519 public key held in claims, clients sign the server
+// nonce with the same key they use for Choria
+func NatsUserPublicKey(claims *StandardClaims) (string, error) {
+	pk := claimsPublicKey(claims)
+	if pk == nil {
+		return "", fmt.Errorf("no valid public key set")
+	}
+
+	nk, err := nkeys.Encode(nkeys.PrefixByteUser, pk)
+	if err != nil {
+		return "", err
+	}
+
+	return string(nk), nil
+}
+
+// NatsUserClaims renders a client or server token into NATS user claims for collective, using the same permissions
+// the Choria Broker would grant. The claims expire with the token and carry its resource limits
+func NatsUserClaims(claims jwt.Claims, collective string) (*natsjwt.UserClaims, error) {
+	var perms *NatsPermissions
+	var std *StandardClaims
+	var name string
+	var err error
+
+	switch c := claims.(type) {
+	case *ClientIDClaims:
+		perms, err = ClientNatsPermissions(c, collective)
+		std, name = &c.StandardClaims, c.CallerID
+	case *ServerClaims:
+		perms, err = ServerNatsPermissions(c, collective)
+		std, name = &c.StandardClaims, c.ChoriaIdentity
+	default:
+		return nil, fmt.Errorf("unsupported claims type %T", claims)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sub, err := NatsUserPublicKey(std)
+	if err != nil {
+		return nil, err
+	}
+
+	uc := natsjwt.NewUserClaims(sub)
+	uc.Name = name
+	uc.Pub.Allow.Add(perms.Publish.Allow...)
+	uc.Pub.Deny.Add(perms.Publish.Deny...)
+	uc.Sub.Allow.Add(perms.Subscribe.Allow...)
+	uc.Sub.Deny.Add(perms.Subscribe.Deny...)
+	uc.Tags.Add("collective:" + collective)
+	if std.Purpose != UnknownPurpose {
+		uc.Tags.Add("purpose:" + string(std.Purpose))
+	}
+
+	if exp := std.ExpireTime(); !exp.IsZero() {
+		uc.Expires = exp.Unix()
+	}
+
+	limits := ResourceLimitsFromClaims(claims)
+	if limits != nil {
+		if limits.MaxPayload > 0 {
+			uc.Limits.Payload = limits.MaxPayload
+		}
+		if limits.MaxSubscriptions > 0 {
+			uc.Limits.Subs = limits.MaxSubscriptions
+		}
+	}
+
+	return uc, nil
+}
+
+// NatsUserJWT renders claims into a NATS user JWT for collective signed by signer, an account key or account signing key.
+// When signer is a signing key issuerAccount must be the public key of the account it signs for
+func NatsUserJWT(claims jwt.Claims, collective string, signer nkeys.KeyPair, issuerAccount string) (string, error) {
+	if signer == nil {
+		return "", fmt.Errorf("signer is required")
+	}
+
+	uc, err := NatsUserClaims(claims, collective)
+	if err != nil {
+		return "", err
+	}
+
+	return signNatsUserClaims(uc, signer, issuerAccount)
+}
+
+// signNatsUserClaims validates and encodes uc using the account key or account signing key signer
+func signNatsUserClaims(uc *natsjwt.UserClaims, signer nkeys.KeyPair, issuerAccount string) (string, error) {
+	signerPub, err := signer.PublicKey()
+	if err != nil {
+		return "", err
+	}
+	if !nkeys.IsValidPublicAccountKey(signerPub) {
+		return "", fmt.Errorf("signer must be an account key")
+	}
+
+	if issuerAccount != "" && issuerAccount != signerPub {
+		if !nkeys.IsValidPublicAccountKey(issuerAccount) {
+			return "", fmt.Errorf("invalid issuer account %q", issuerAccount)
+		}
+
+		uc.IssuerAccount = issuerAccount
+	}
+
+	vr := &natsjwt.ValidationResults{}
+	uc.Validate(vr)
+	if len(vr.Errors()) > 0 {
+		return "", fmt.Errorf("invalid nats user claims: %w", vr.Errors()[0])
+	}
+
+	return uc.Encode(signer)
+}
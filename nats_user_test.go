@@ -0,0 +1,116 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !js
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"time"
+
+	natsjwt "github.com/nats-io/jwt/v2"
+	"github.com/nats-io/nkeys"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NatsUser", func() {
+	var (
+		pubK    ed25519.PublicKey
+		priK    ed25519.PrivateKey
+		account nkeys.KeyPair
+		client  *ClientIDClaims
+	)
+
+	BeforeEach(func() {
+		var err error
+		pubK, priK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		account, err = nkeys.CreateAccount()
+		Expect(err).ToNot(HaveOccurred())
+
+		client, err = NewClientIDClaims("up=bob", nil, "choria", nil, "", "", time.Hour, &ClientPermissions{FleetManagement: true}, pubK, WithResourceLimits(ResourceLimits{MaxPayload: 1024}))
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Describe("NatsUserPublicKey", func() {
+		It("Should encode the token key as a user nkey", func() {
+			nk, err := NatsUserPublicKey(&client.StandardClaims)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(nkeys.IsValidPublicUserKey(nk)).To(BeTrue())
+
+			raw, err := nkeys.Decode(nkeys.PrefixByteUser, []byte(nk))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(raw).To(Equal([]byte(pubK)))
+
+			kp, err := nkeys.FromRawSeed(nkeys.PrefixByteUser, priK.Seed())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(kp.PublicKey()).To(Equal(nk))
+
+			_, err = NatsUserPublicKey(&StandardClaims{})
+			Expect(err).To(MatchError("no valid public key set"))
+		})
+	})
+
+	Describe("NatsUserJWT", func() {
+		It("Should render client tokens", func() {
+			token, err := NatsUserJWT(client, "choria", account, "")
+			Expect(err).ToNot(HaveOccurred())
+
+			uc, err := natsjwt.DecodeUserClaims(token)
+			Expect(err).ToNot(HaveOccurred())
+
+			accountPub, _ := account.PublicKey()
+			Expect(uc.Issuer).To(Equal(accountPub))
+			Expect(uc.Name).To(Equal("up=bob"))
+			Expect(uc.Expires).To(Equal(client.ExpiresAt.Unix()))
+			Expect(uc.Limits.Payload).To(Equal(int64(1024)))
+			Expect(uc.Limits.Subs).To(Equal(int64(natsjwt.NoLimit)))
+			Expect(uc.Tags.Contains("collective:choria")).To(BeTrue())
+
+			perms, err := ClientNatsPermissions(client, "choria")
+			Expect(err).ToNot(HaveOccurred())
+			Expect([]string(uc.Pub.Allow)).To(Equal(perms.Publish.Allow))
+			Expect([]string(uc.Sub.Allow)).To(Equal(perms.Subscribe.Allow))
+		})
+
+		It("Should render server tokens", func() {
+			server, err := NewServerClaims("n1.example.net", []string{"choria"}, "choria", nil, nil, pubK, "", time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = NatsUserJWT(server, "other", account, "")
+			Expect(err).To(MatchError("server is not a member of collective other"))
+
+			token, err := NatsUserJWT(server, "choria", account, "")
+			Expect(err).ToNot(HaveOccurred())
+
+			uc, err := natsjwt.DecodeUserClaims(token)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(uc.Name).To(Equal("n1.example.net"))
+			Expect(uc.Sub.Allow.Contains("choria.node.n1.example.net")).To(BeTrue())
+		})
+
+		It("Should support account signing keys", func() {
+			signingKey, err := nkeys.CreateAccount()
+			Expect(err).ToNot(HaveOccurred())
+			accountPub, _ := account.PublicKey()
+
+			_, err = NatsUserJWT(client, "choria", signingKey, "invalid")
+			Expect(err).To(MatchError(`invalid issuer account "invalid"`))
+
+			token, err := NatsUserJWT(client, "choria", signingKey, accountPub)
+			Expect(err).ToNot(HaveOccurred())
+			uc, err := natsjwt.DecodeUserClaims(token)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(uc.IssuerAccount).To(Equal(accountPub))
+
+			operator, err := nkeys.CreateOperator()
+			Expect(err).ToNot(HaveOccurred())
+			_, err = NatsUserJWT(client, "choria", operator, "")
+			Expect(err).To(MatchError("signer must be an account key"))
+		})
+	})
+})
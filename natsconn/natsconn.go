@@ -0,0 +1,61 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package natsconn creates NATS connection options from Choria tokens
+package natsconn
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/choria-io/tokens"
+	"github.com/nats-io/nats.go"
+	"github.com/sirupsen/logrus"
+)
+
+// Options creates the NATS options needed to connect using token and the hex encoded ed25519 seed, the UserJWT
+// handlers, private inbox prefix and connection name are set so the result can be passed directly to nats.Connect.
+//
+// The collective for the inbox is taken from server tokens belonging to a single collective, for other tokens
+// tokens.WithNatsCollective or an inbox template without a collective is required. Tokens do not carry TLS settings
+// so options like nats.RootCAs should be added by the caller. Any error is returned by nats.Connect
+func Options(token string, seed string, opts ...tokens.NatsConnectionOption) []nats.Option {
+	nopts, err := options(token, seed, opts...)
+	if err != nil {
+		return []nats.Option{func(*nats.Options) error {
+			return fmt.Errorf("invalid token connection options: %w", err)
+		}}
+	}
+
+	return nopts
+}
+
+func options(token string, seed string, opts ...tokens.NatsConnectionOption) ([]nats.Option, error) {
+	var collective string
+	if tokens.TokenPurpose(token) == tokens.ServerPurpose {
+		server, err := tokens.ParseServerTokenUnverified(token)
+		if err == nil && len(server.Collectives) == 1 {
+			collective = server.Collectives[0]
+		}
+	}
+
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+
+	inbox, jwth, sigh, err := tokens.NatsConnectionHelpersWithSeed(token, collective, seed, logrus.NewEntry(log), opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	name, _, err := tokens.NatsConnectionName(token)
+	if err != nil {
+		return nil, err
+	}
+
+	return []nats.Option{
+		nats.UserJWT(jwth, sigh),
+		nats.CustomInboxPrefix(inbox),
+		nats.Name(name),
+	}, nil
+}
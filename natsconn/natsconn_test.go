@@ -0,0 +1,99 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package natsconn
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/choria-io/tokens"
+	"github.com/nats-io/nats.go"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestNatsConn(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "NatsConn")
+}
+
+var _ = Describe("Options", func() {
+	var (
+		pubK ed25519.PublicKey
+		priK ed25519.PrivateKey
+		seed string
+	)
+
+	BeforeEach(func() {
+		sb, err := os.ReadFile("../testdata/ed25519/other.seed")
+		Expect(err).ToNot(HaveOccurred())
+		seed = strings.TrimSpace(string(sb))
+
+		s, err := hex.DecodeString(seed)
+		Expect(err).ToNot(HaveOccurred())
+		priK = ed25519.NewKeyFromSeed(s)
+		pubK = priK.Public().(ed25519.PublicKey)
+	})
+
+	apply := func(opts []nats.Option) (nats.Options, error) {
+		o := nats.GetDefaultOptions()
+		for _, opt := range opts {
+			err := opt(&o)
+			if err != nil {
+				return o, err
+			}
+		}
+
+		return o, nil
+	}
+
+	It("Should configure server connections", func() {
+		claims, err := tokens.NewServerClaims("ginkgo.example.net", []string{"choria"}, "choria", nil, nil, pubK, "", time.Hour)
+		Expect(err).ToNot(HaveOccurred())
+		token, err := tokens.SignToken(claims, priK)
+		Expect(err).ToNot(HaveOccurred())
+
+		o, err := apply(Options(token, seed))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(o.Name).To(Equal("choria_server:choria:ginkgo.example.net"))
+		Expect(o.InboxPrefix).To(Equal("choria.reply.3f7c3a791b0eb10da51dca4cdedb9418"))
+		Expect(o.UserJWT()).To(Equal(token))
+
+		sig, err := o.SignatureCB([]byte("toomanysecrets"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ed25519.Verify(pubK, []byte("toomanysecrets"), sig)).To(BeTrue())
+	})
+
+	It("Should configure client connections", func() {
+		claims, err := tokens.NewClientIDClaims("ginkgo", nil, "choria", nil, "", "", time.Hour, nil, pubK)
+		Expect(err).ToNot(HaveOccurred())
+		token, err := tokens.SignToken(claims, priK)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = apply(Options(token, seed))
+		Expect(err).To(MatchError("invalid token connection options: collective is required"))
+
+		o, err := apply(Options(token, seed, tokens.WithNatsCollective("acme")))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(o.Name).To(Equal("choria_client_id:choria:ginkgo"))
+		Expect(o.InboxPrefix).To(Equal("acme.reply.4bb6777bb903cae3166e826932f7fe94"))
+
+		o, err = apply(Options(token, seed, tokens.WithNatsInboxPrefix("_INBOX")))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(o.InboxPrefix).To(Equal("_INBOX.4bb6777bb903cae3166e826932f7fe94"))
+	})
+
+	It("Should fail connecting for invalid tokens", func() {
+		_, err := apply(Options("invalid", seed, tokens.WithNatsCollective("acme")))
+		Expect(err).To(MatchError("invalid token connection options: unsupported token purpose: "))
+
+		_, err = nats.Connect("nats://127.0.0.1:1", Options("invalid", seed)...)
+		Expect(err).To(MatchError(ContainSubstring("invalid token connection options")))
+	})
+})
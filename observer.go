@@ -0,0 +1,208 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// ErrNotAnObserverToken indicates a token is not an observer token
+var ErrNotAnObserverToken = errors.New("not an observer token")
+
+var observerEventSubjects = []string{"choria.lifecycle.>", "choria.machine.>"}
+
+// ObserverClaims is a read only data plane identity that can observe events and registration data but cannot publish beyond its own inbox
+//
+// The "purpose" claim should be set to ObserverPurpose
+type ObserverClaims struct {
+	// Name is the name of the observer, for example the name of a dashboard
+	Name string `json:"name"`
+
+	// Collectives are the collectives the observer can observe
+	Collectives []string `json:"collectives"`
+
+	// Events allows subscribing to lifecycle and autonomous agent events
+	Events bool `json:"events,omitempty"`
+
+	// Registration allows subscribing to registration data
+	Registration bool `json:"registration,omitempty"`
+
+	// AdditionalSubscribeSubjects are additional subjects the observer can subscribe to
+	AdditionalSubscribeSubjects []string `json:"sub_subjects,omitempty"`
+
+	// OrganizationUnit broker account the observer should belong to
+	OrganizationUnit string `json:"ou,omitempty"`
+
+	StandardClaims
+}
+
+// UniqueID returns the name and unique id used to generate private inboxes
+func (c *ObserverClaims) UniqueID() (id string, uid string) {
+	return c.Name, fmt.Sprintf("%x", md5.Sum([]byte(c.Name)))
+}
+
+// NewObserverClaims creates observer claims, at least one of events or registration or an additional subject is required
+func NewObserverClaims(name string, collectives []string, events bool, registration bool, additionalSubjects []string, org string, issuer string, validity time.Duration, pk ed25519.PublicKey, opts ...ClaimsOption) (*ObserverClaims, error) {
+	if name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+
+	if pk == nil {
+		return nil, fmt.Errorf("public key is required")
+	}
+
+	if org == "" {
+		org = defaultOrg
+	}
+
+	stdClaims, err := newStandardClaims(issuer, ObserverPurpose, validity, false, append([]ClaimsOption{withPublicKey(pk)}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &ObserverClaims{
+		Name:                        name,
+		Collectives:                 collectives,
+		Events:                      events,
+		Registration:                registration,
+		AdditionalSubscribeSubjects: additionalSubjects,
+		OrganizationUnit:            org,
+		StandardClaims:              *stdClaims,
+	}
+
+	err = claims.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// NatsPermissions maps the observer rights to NATS permissions, publishing is only allowed to the private inbox
+func (c *ObserverClaims) NatsPermissions() *NatsPermissions {
+	_, uid := c.UniqueID()
+	perms := &NatsPermissions{}
+
+	for _, collective := range c.Collectives {
+		inbox := fmt.Sprintf("%s.reply.%s.>", collective, uid)
+		perms.Publish.Allow = append(perms.Publish.Allow, inbox)
+		perms.Subscribe.Allow = append(perms.Subscribe.Allow, inbox)
+
+		if c.Registration {
+			perms.Subscribe.Allow = append(perms.Subscribe.Allow, fmt.Sprintf("%s.broadcast.agent.registration", collective))
+		}
+	}
+
+	if c.Events {
+		perms.Subscribe.Allow = append(perms.Subscribe.Allow, observerEventSubjects...)
+	}
+
+	perms.Subscribe.Allow = append(perms.Subscribe.Allow, c.AdditionalSubscribeSubjects...)
+
+	return perms
+}
+
+// IsObserverToken determines if this is an observer token
+func IsObserverToken(claims StandardClaims) bool {
+	return claims.Purpose == ObserverPurpose
+}
+
+// ParseObserverTokenUnverified parses the observer token in an unverified manner.
+func ParseObserverTokenUnverified(token string) (*ObserverClaims, error) {
+	claims := &ObserverClaims{}
+	_, err := parseUnverified(token, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	if !IsObserverToken(claims.StandardClaims) {
+		return nil, ErrNotAnObserverToken
+	}
+
+	if claims.OrganizationUnit == "" {
+		claims.OrganizationUnit = defaultOrg
+	}
+
+	return claims, nil
+}
+
+// ParseObserverToken parses token and verifies it with pk
+func ParseObserverToken(token string, pk any, opts ...ParseOption) (*ObserverClaims, error) {
+	claims := &ObserverClaims{}
+	err := ParseToken(token, claims, pk, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse observer token: %w", err)
+	}
+
+	// if we have a tcs we require an issuer expiry to be set and it to not have expired
+	if !claims.verifyIssuerExpiry(claims.TrustChainSignature != "") {
+		return nil, jwt.ErrTokenExpired
+	}
+
+	if claims.OrganizationUnit == "" {
+		claims.OrganizationUnit = defaultOrg
+	}
+
+	return claims, nil
+}
+
+// ParseObserverTokenWithKeyfile parses token and verifies it with the RSA or ED25519 Public key in pkFile
+func ParseObserverTokenWithKeyfile(token string, pkFile string, opts ...ParseOption) (*ObserverClaims, error) {
+	if pkFile == "" {
+		return nil, fmt.Errorf("invalid public key file")
+	}
+
+	certdat, err := os.ReadFile(pkFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not read validation certificate: %s", err)
+	}
+
+	pk, err := readRSAOrED25519PublicData(certdat)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseObserverToken(token, pk, opts...)
+}
+
+// Validate checks the observer has a name, collectives and valid subjects
+func (c *ObserverClaims) Validate() error {
+	if !IsObserverToken(c.StandardClaims) {
+		return ErrNotAnObserverToken
+	}
+
+	if c.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+
+	if len(c.Collectives) == 0 {
+		return fmt.Errorf("at least one collective is required")
+	}
+
+	err := validateCollectives(c.Collectives)
+	if err != nil {
+		return err
+	}
+
+	if !c.Events && !c.Registration && len(c.AdditionalSubscribeSubjects) == 0 {
+		return fmt.Errorf("observers require access to events, registration or additional subjects")
+	}
+
+	for _, s := range c.AdditionalSubscribeSubjects {
+		err := validateSubject(s)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
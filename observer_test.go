@@ -0,0 +1,125 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+)
+
+var _ = Describe("ObserverClaims", func() {
+	var (
+		pubK ed25519.PublicKey
+		priK ed25519.PrivateKey
+		err  error
+	)
+
+	BeforeEach(func() {
+		pubK, priK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Describe("NewObserverClaims", func() {
+		It("Should validate the input", func() {
+			_, err := NewObserverClaims("", []string{"choria"}, true, true, nil, "", "", time.Hour, pubK)
+			Expect(err).To(MatchError("name is required"))
+
+			_, err = NewObserverClaims("noc", []string{"choria"}, true, true, nil, "", "", time.Hour, nil)
+			Expect(err).To(MatchError("public key is required"))
+
+			_, err = NewObserverClaims("noc", nil, true, true, nil, "", "", time.Hour, pubK)
+			Expect(err).To(MatchError("at least one collective is required"))
+
+			_, err = NewObserverClaims("noc", []string{"choria"}, false, false, nil, "", "", time.Hour, pubK)
+			Expect(err).To(MatchError("observers require access to events, registration or additional subjects"))
+
+			_, err = NewObserverClaims("noc", []string{"choria"}, false, false, []string{"a..b"}, "", "", time.Hour, pubK)
+			Expect(err).To(MatchError(ErrInvalidSubject))
+		})
+
+		It("Should create valid claims", func() {
+			claims, err := NewObserverClaims("noc", []string{"choria"}, true, true, nil, "", "ginkgo", time.Hour, pubK)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(claims.Purpose).To(Equal(ObserverPurpose))
+			Expect(claims.Name).To(Equal("noc"))
+			Expect(claims.OrganizationUnit).To(Equal(defaultOrg))
+		})
+	})
+
+	Describe("NatsPermissions", func() {
+		It("Should only allow publishing to the inbox", func() {
+			claims, err := NewObserverClaims("noc", []string{"choria", "other"}, true, true, []string{"custom.>"}, "", "", time.Hour, pubK)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, uid := claims.UniqueID()
+			perms := claims.NatsPermissions()
+			Expect(perms.Publish.Allow).To(Equal([]string{"choria.reply." + uid + ".>", "other.reply." + uid + ".>"}))
+			Expect(perms.Subscribe.Allow).To(Equal([]string{
+				"choria.reply." + uid + ".>",
+				"choria.broadcast.agent.registration",
+				"other.reply." + uid + ".>",
+				"other.broadcast.agent.registration",
+				"choria.lifecycle.>",
+				"choria.machine.>",
+				"custom.>",
+			}))
+		})
+
+		It("Should only include requested access", func() {
+			claims, err := NewObserverClaims("noc", []string{"choria"}, false, true, nil, "", "", time.Hour, pubK)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, uid := claims.UniqueID()
+			Expect(claims.NatsPermissions().Subscribe.Allow).To(Equal([]string{"choria.reply." + uid + ".>", "choria.broadcast.agent.registration"}))
+		})
+	})
+
+	Describe("ParseObserverToken", func() {
+		It("Should parse valid tokens", func() {
+			claims, err := NewObserverClaims("noc", []string{"choria"}, true, false, nil, "", "", time.Hour, pubK)
+			Expect(err).ToNot(HaveOccurred())
+
+			token, err := SignToken(claims, priK)
+			Expect(err).ToNot(HaveOccurred())
+
+			parsed, err := ParseObserverToken(token, pubK)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(parsed.Events).To(BeTrue())
+
+			unverified, err := ParseObserverTokenUnverified(token)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(unverified.Name).To(Equal("noc"))
+
+			_, err = ParseObserverTokenWithKeyfile(token, "")
+			Expect(err).To(MatchError("invalid public key file"))
+
+			log := logrus.NewEntry(logrus.New())
+			log.Logger.SetOutput(GinkgoWriter)
+			inbox, _, _, err := NatsConnectionHelpers(token, "choria", "testdata/ed25519/other.seed", log)
+			Expect(err).ToNot(HaveOccurred())
+			_, uid := claims.UniqueID()
+			Expect(inbox).To(Equal("choria.reply." + uid))
+		})
+
+		It("Should reject other tokens", func() {
+			claims, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			token, err := SignToken(claims, priK)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = ParseObserverToken(token, pubK)
+			Expect(err).To(MatchError(ErrNotAnObserverToken))
+
+			_, err = ParseObserverTokenUnverified(token)
+			Expect(err).To(MatchError(ErrNotAnObserverToken))
+		})
+	})
+})
@@ -0,0 +1,372 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// ErrInvalidOIDCToken indicates an external OIDC ID token could not be verified or mapped
+var ErrInvalidOIDCToken = errors.New("invalid oidc id token")
+
+// oidcSigningMethods are the asymmetric algorithms accepted for ID tokens, shared secret algorithms are never accepted
+var oidcSigningMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// OIDCExchangeConfig configures how ID tokens from an external OIDC provider are exchanged for client tokens
+type OIDCExchangeConfig struct {
+	// Issuer is the expected "iss" claim of ID tokens
+	Issuer string
+
+	// Audience is the client id that ID tokens must be issued for
+	Audience string
+
+	// Keys are the provider signing keys by key id, see ParseJWKS, a key with an empty id is used for ID tokens without a "kid" header
+	Keys map[string]any
+
+	// Validity is how long the produced client tokens are valid for
+	Validity time.Duration
+
+	// Leeway allows for clock skew when validating ID token times
+	Leeway time.Duration
+
+	// SkipNonceCheck allows ID tokens to be verified without a nonce, only for flows where the provider does not
+	// issue nonces as it allows captured ID tokens to be exchanged again
+	SkipNonceCheck bool
+
+	// Mapping is how ID token claims map to client token claims
+	Mapping OIDCClaimMapping
+}
+
+// OIDCClaimMapping maps claims in an ID token to client token claims
+type OIDCClaimMapping struct {
+	// CallerIDClaim is the claim identifying the user, defaults to "sub"
+	CallerIDClaim string
+
+	// CallerIDPrefix is prefixed to the caller id, defaults to "oidc" giving caller ids like "oidc=bob"
+	CallerIDPrefix string
+
+	// Organization is the organization unit set in client tokens
+	Organization string
+
+	// GroupsClaim is the claim holding the groups of the user, defaults to "groups"
+	GroupsClaim string
+
+	// Groups maps group names to the access granted to members
+	Groups map[string]OIDCGroupGrant
+
+	// RequireGroup rejects users that are not a member of any mapped group
+	RequireGroup bool
+
+	// Properties copies string claims into user properties, keyed by claim name with the property name as value
+	Properties map[string]string
+}
+
+// OIDCGroupGrant is the access granted to members of a group, grants of all matching groups are combined
+type OIDCGroupGrant struct {
+	// AllowedAgents are the agents or agent.action names members may access
+	AllowedAgents []string
+
+	// Permissions are the client permissions granted to members
+	Permissions *ClientPermissions
+}
+
+// OIDCExchanger verifies ID tokens from an external OIDC provider and maps them to client tokens
+type OIDCExchanger struct {
+	cfg OIDCExchangeConfig
+}
+
+// NewOIDCExchanger creates an exchanger using cfg
+func NewOIDCExchanger(cfg OIDCExchangeConfig) (*OIDCExchanger, error) {
+	if cfg.Issuer == "" {
+		return nil, fmt.Errorf("issuer is required")
+	}
+
+	if cfg.Audience == "" {
+		return nil, fmt.Errorf("audience is required")
+	}
+
+	if len(cfg.Keys) == 0 {
+		return nil, fmt.Errorf("at least one provider key is required")
+	}
+
+	for kid, k := range cfg.Keys {
+		switch k.(type) {
+		case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+		default:
+			return nil, fmt.Errorf("key %q: unsupported public key type %T", kid, k)
+		}
+	}
+
+	if cfg.Validity <= 0 {
+		return nil, fmt.Errorf("validity is required")
+	}
+
+	if cfg.Leeway < 0 {
+		return nil, fmt.Errorf("leeway cannot be negative")
+	}
+
+	if cfg.Mapping.CallerIDClaim == "" {
+		cfg.Mapping.CallerIDClaim = "sub"
+	}
+	if cfg.Mapping.CallerIDPrefix == "" {
+		cfg.Mapping.CallerIDPrefix = "oidc"
+	}
+	if cfg.Mapping.GroupsClaim == "" {
+		cfg.Mapping.GroupsClaim = "groups"
+	}
+
+	return &OIDCExchanger{cfg: cfg}, nil
+}
+
+// Verify verifies the signature, issuer, audience, times and nonce of idToken and returns its claims, the nonce is
+// required unless the SkipNonceCheck setting is set
+func (e *OIDCExchanger) Verify(idToken string, nonce string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	parser := jwt.NewParser(jwt.WithValidMethods(oidcSigningMethods), jwt.WithoutClaimsValidation())
+
+	_, err := parser.ParseWithClaims(idToken, claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		pk, ok := e.cfg.Keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+
+		return pk, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidOIDCToken, err)
+	}
+
+	now := currentTime()
+
+	switch {
+	case !claims.VerifyIssuer(e.cfg.Issuer, true):
+		return nil, fmt.Errorf("%w: unexpected issuer", ErrInvalidOIDCToken)
+	case !claims.VerifyAudience(e.cfg.Audience, true):
+		return nil, fmt.Errorf("%w: not issued for audience %s", ErrInvalidOIDCToken, e.cfg.Audience)
+	case !claims.VerifyExpiresAt(now.Add(-e.cfg.Leeway).Unix(), true):
+		return nil, fmt.Errorf("%w: token is expired", ErrInvalidOIDCToken)
+	case !claims.VerifyNotBefore(now.Add(e.cfg.Leeway).Unix(), false):
+		return nil, fmt.Errorf("%w: token is not valid yet", ErrInvalidOIDCToken)
+	case !claims.VerifyIssuedAt(now.Add(e.cfg.Leeway).Unix(), false):
+		return nil, fmt.Errorf("%w: token used before issued", ErrInvalidOIDCToken)
+	}
+
+	switch {
+	case nonce != "":
+		found, _ := claims["nonce"].(string)
+		if subtle.ConstantTimeCompare([]byte(found), []byte(nonce)) != 1 {
+			return nil, fmt.Errorf("%w: nonce does not match", ErrInvalidOIDCToken)
+		}
+
+	case !e.cfg.SkipNonceCheck:
+		return nil, fmt.Errorf("%w: nonce is required", ErrInvalidOIDCToken)
+	}
+
+	return claims, nil
+}
+
+// Exchange verifies idToken and maps it to client claims for the holder of pk, the claims are returned unsigned
+// so the caller can sign them using SignToken
+func (e *OIDCExchanger) Exchange(idToken string, nonce string, issuer string, pk ed25519.PublicKey, opts ...ClaimsOption) (*ClientIDClaims, error) {
+	claims, err := e.Verify(idToken, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	m := e.cfg.Mapping
+
+	user, _ := claims[m.CallerIDClaim].(string)
+	if user == "" {
+		return nil, fmt.Errorf("%w: claim %s is required", ErrInvalidOIDCToken, m.CallerIDClaim)
+	}
+
+	agents, perms, matched := m.grants(claims)
+	if m.RequireGroup && !matched {
+		return nil, fmt.Errorf("%w: %s is not a member of any mapped group", ErrInvalidOIDCToken, user)
+	}
+
+	var props map[string]string
+	for claim, prop := range m.Properties {
+		v, ok := claims[claim].(string)
+		if !ok {
+			continue
+		}
+
+		if props == nil {
+			props = map[string]string{}
+		}
+		props[prop] = v
+	}
+
+	return NewClientIDClaims(fmt.Sprintf("%s=%s", m.CallerIDPrefix, user), agents, m.Organization, props, "", issuer, e.cfg.Validity, perms, pk, opts...)
+}
+
+// grants combines the grants of every mapped group the user is a member of
+func (m *OIDCClaimMapping) grants(claims jwt.MapClaims) ([]string, *ClientPermissions, bool) {
+	var groups []string
+
+	switch g := claims[m.GroupsClaim].(type) {
+	case string:
+		groups = []string{g}
+	case []any:
+		for _, v := range g {
+			if s, ok := v.(string); ok {
+				groups = append(groups, s)
+			}
+		}
+	}
+
+	seen := map[string]bool{}
+	var agents []string
+	var perms *ClientPermissions
+	matched := false
+
+	for _, group := range groups {
+		grant, ok := m.Groups[group]
+		if !ok {
+			continue
+		}
+		matched = true
+
+		for _, a := range grant.AllowedAgents {
+			if !seen[a] {
+				seen[a] = true
+				agents = append(agents, a)
+			}
+		}
+
+		if grant.Permissions != nil {
+			if perms == nil {
+				perms = &ClientPermissions{}
+			}
+			mergePermissions(perms, grant.Permissions)
+		}
+	}
+
+	sort.Strings(agents)
+
+	return agents, perms, matched
+}
+
+// ParseJWKS parses a JSON Web Key Set, as published by OIDC providers, into keys by key id.
+// Keys intended for encryption are skipped
+func ParseJWKS(data []byte) (map[string]any, error) {
+	var set struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			Use string `json:"use"`
+			Crv string `json:"crv"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+
+	err := json.Unmarshal(data, &set)
+	if err != nil {
+		return nil, fmt.Errorf("invalid jwks: %w", err)
+	}
+
+	keys := map[string]any{}
+	for i, k := range set.Keys {
+		if k.Use == "enc" {
+			continue
+		}
+
+		var pk any
+
+		switch k.Kty {
+		case "RSA":
+			n, e := jwksInt(k.N), jwksInt(k.E)
+			if n == nil || e == nil || !e.IsInt64() {
+				return nil, fmt.Errorf("jwks key %d: invalid rsa key", i)
+			}
+			rpk := &rsa.PublicKey{N: n, E: int(e.Int64())}
+			err = validateRSAPublicKey(rpk)
+			if err != nil {
+				return nil, fmt.Errorf("jwks key %d: %w", i, err)
+			}
+			pk = rpk
+
+		case "EC":
+			var curve elliptic.Curve
+			switch k.Crv {
+			case "P-256":
+				curve = elliptic.P256()
+			case "P-384":
+				curve = elliptic.P384()
+			case "P-521":
+				curve = elliptic.P521()
+			default:
+				return nil, fmt.Errorf("jwks key %d: unsupported curve %q", i, k.Crv)
+			}
+			x, y := jwksInt(k.X), jwksInt(k.Y)
+			if x == nil || y == nil || !curve.IsOnCurve(x, y) {
+				return nil, fmt.Errorf("jwks key %d: invalid ec key", i)
+			}
+			pk = &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+
+		case "OKP":
+			x, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(k.X, "="))
+			if err != nil || k.Crv != "Ed25519" {
+				return nil, fmt.Errorf("jwks key %d: invalid okp key", i)
+			}
+			err = ValidateEd25519PublicKey(x)
+			if err != nil {
+				return nil, fmt.Errorf("jwks key %d: %w", i, err)
+			}
+			pk = ed25519.PublicKey(x)
+
+		default:
+			return nil, fmt.Errorf("jwks key %d: unsupported key type %q", i, k.Kty)
+		}
+
+		if _, ok := keys[k.Kid]; ok {
+			return nil, fmt.Errorf("jwks key %d: duplicate key id %q", i, k.Kid)
+		}
+		keys[k.Kid] = pk
+	}
+
+	return keys, nil
+}
+
+func jwksInt(v string) *big.Int {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(v, "="))
+	if err != nil || len(b) == 0 {
+		return nil
+	}
+
+	return new(big.Int).SetBytes(b)
+}
+
+// mergePermissions sets every permission held in src on dst
+func mergePermissions(dst any, src any) {
+	dv := reflect.ValueOf(dst).Elem()
+	sv := reflect.ValueOf(src).Elem()
+
+	for i := 0; i < dv.NumField(); i++ {
+		if dv.Field(i).Kind() == reflect.Bool && sv.Field(i).Bool() {
+			dv.Field(i).SetBool(true)
+		}
+	}
+}
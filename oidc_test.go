@@ -0,0 +1,174 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("OIDC", func() {
+	var (
+		providerKey *rsa.PrivateKey
+		exchanger   *OIDCExchanger
+		userPubK    ed25519.PublicKey
+		claims      jwt.MapClaims
+	)
+
+	idToken := func(method jwt.SigningMethod, kid string, key any) string {
+		t := jwt.NewWithClaims(method, claims)
+		if kid != "" {
+			t.Header["kid"] = kid
+		}
+		s, err := t.SignedString(key)
+		Expect(err).ToNot(HaveOccurred())
+
+		return s
+	}
+
+	BeforeEach(func() {
+		var err error
+		providerKey = loadRSAPriKey("testdata/rsa/signer-key.pem")
+		userPubK, _, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		exchanger, err = NewOIDCExchanger(OIDCExchangeConfig{
+			Issuer:   "https://sso.example.net",
+			Audience: "choria",
+			Keys:     map[string]any{"k1": &providerKey.PublicKey},
+			Validity: time.Hour,
+			Mapping: OIDCClaimMapping{
+				CallerIDClaim: "email",
+				Organization:  "choria",
+				RequireGroup:  true,
+				Properties:    map[string]string{"name": "full_name"},
+				Groups: map[string]OIDCGroupGrant{
+					"ops":    {AllowedAgents: []string{"rpcutil", "puppet"}, Permissions: &ClientPermissions{FleetManagement: true}},
+					"stream": {AllowedAgents: []string{"rpcutil"}, Permissions: &ClientPermissions{StreamsUser: true}},
+				},
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		claims = jwt.MapClaims{
+			"iss":    "https://sso.example.net",
+			"aud":    []string{"choria", "other"},
+			"sub":    "1234",
+			"email":  "bob@example.net",
+			"name":   "Bob",
+			"nonce":  "n-1",
+			"groups": []string{"ops", "stream", "unmapped"},
+			"iat":    time.Now().Unix(),
+			"exp":    time.Now().Add(time.Minute).Unix(),
+		}
+	})
+
+	Describe("NewOIDCExchanger", func() {
+		It("Should validate the configuration", func() {
+			_, err := NewOIDCExchanger(OIDCExchangeConfig{})
+			Expect(err).To(MatchError("issuer is required"))
+			_, err = NewOIDCExchanger(OIDCExchangeConfig{Issuer: "x", Audience: "y"})
+			Expect(err).To(MatchError("at least one provider key is required"))
+			_, err = NewOIDCExchanger(OIDCExchangeConfig{Issuer: "x", Audience: "y", Keys: map[string]any{"": []byte("secret")}})
+			Expect(err).To(MatchError(`key "": unsupported public key type []uint8`))
+		})
+	})
+
+	Describe("Exchange", func() {
+		It("Should map ID tokens to client claims", func() {
+			client, err := exchanger.Exchange(idToken(jwt.SigningMethodRS256, "k1", providerKey), "n-1", "choria", userPubK)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(client.CallerID).To(Equal("oidc=bob@example.net"))
+			Expect(client.OrganizationUnit).To(Equal("choria"))
+			Expect(client.AllowedAgents).To(Equal([]string{"puppet", "rpcutil"}))
+			Expect(client.Permissions).To(Equal(&ClientPermissions{FleetManagement: true, StreamsUser: true}))
+			Expect(client.UserProperties).To(Equal(map[string]string{"full_name": "Bob"}))
+			Expect(client.PublicKey).ToNot(BeEmpty())
+		})
+
+		It("Should reject users without mapped groups", func() {
+			claims["groups"] = "unmapped"
+			_, err := exchanger.Exchange(idToken(jwt.SigningMethodRS256, "k1", providerKey), "n-1", "choria", userPubK)
+			Expect(err).To(MatchError(ContainSubstring("bob@example.net is not a member of any mapped group")))
+		})
+	})
+
+	Describe("Verify", func() {
+		It("Should verify the token", func() {
+			_, err := exchanger.Verify(idToken(jwt.SigningMethodRS256, "k1", providerKey), "n-1")
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = exchanger.Verify(idToken(jwt.SigningMethodRS256, "k1", providerKey), "n-2")
+			Expect(err).To(MatchError(ErrInvalidOIDCToken))
+			Expect(err).To(MatchError(ContainSubstring("nonce does not match")))
+
+			_, err = exchanger.Verify(idToken(jwt.SigningMethodRS256, "k1", providerKey), "")
+			Expect(err).To(MatchError("invalid oidc id token: nonce is required"))
+
+			_, err = exchanger.Verify(idToken(jwt.SigningMethodRS256, "k2", providerKey), "")
+			Expect(err).To(MatchError(ContainSubstring(`unknown signing key "k2"`)))
+
+			_, err = exchanger.Verify(idToken(jwt.SigningMethodHS256, "k1", []byte("secret")), "")
+			Expect(err).To(MatchError(ContainSubstring("signing method HS256 is invalid")))
+
+			claims["aud"] = "other"
+			_, err = exchanger.Verify(idToken(jwt.SigningMethodRS256, "k1", providerKey), "")
+			Expect(err).To(MatchError(ContainSubstring("not issued for audience choria")))
+
+			claims["aud"] = "choria"
+			claims["iss"] = "https://evil.example.net"
+			_, err = exchanger.Verify(idToken(jwt.SigningMethodRS256, "k1", providerKey), "")
+			Expect(err).To(MatchError(ContainSubstring("unexpected issuer")))
+
+			claims["iss"] = "https://sso.example.net"
+			claims["exp"] = time.Now().Add(-time.Minute).Unix()
+			_, err = exchanger.Verify(idToken(jwt.SigningMethodRS256, "k1", providerKey), "")
+			Expect(err).To(MatchError(ContainSubstring("token is expired")))
+		})
+	})
+
+	Describe("ParseJWKS", func() {
+		It("Should parse provider keys", func() {
+			ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+			edPubK, _, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+
+			b64 := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+			jwks := fmt.Sprintf(`{"keys":[
+				{"kid":"rsa","kty":"RSA","use":"sig","n":%q,"e":%q},
+				{"kid":"ec","kty":"EC","crv":"P-256","x":%q,"y":%q},
+				{"kid":"ed","kty":"OKP","crv":"Ed25519","x":%q},
+				{"kid":"enc","kty":"RSA","use":"enc","n":"","e":""}
+			]}`, b64(providerKey.PublicKey.N.Bytes()), b64(big.NewInt(int64(providerKey.PublicKey.E)).Bytes()), b64(ecKey.PublicKey.X.Bytes()), b64(ecKey.PublicKey.Y.Bytes()), b64(edPubK))
+
+			keys, err := ParseJWKS([]byte(jwks))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(keys).To(HaveLen(3))
+			Expect(keys["rsa"]).To(Equal(&providerKey.PublicKey))
+			Expect(keys["ec"].(*ecdsa.PublicKey).Equal(&ecKey.PublicKey)).To(BeTrue())
+			Expect(keys["ed"]).To(Equal(edPubK))
+
+			_, err = ParseJWKS([]byte(`{"keys":[{"kty":"oct","k":"c2VjcmV0"}]}`))
+			Expect(err).To(MatchError(`jwks key 0: unsupported key type "oct"`))
+
+			exchanger, err = NewOIDCExchanger(OIDCExchangeConfig{Issuer: "https://sso.example.net", Audience: "choria", Keys: keys, Validity: time.Hour, SkipNonceCheck: true})
+			Expect(err).ToNot(HaveOccurred())
+			_, err = exchanger.Verify(idToken(jwt.SigningMethodES256, "ec", ecKey), "")
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,706 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// ClaimsOption configures optional settings when creating new claims
+type ClaimsOption func(*claimsOptions) error
+
+type claimsOptions struct {
+	audience          []string
+	issuerMetadata    *IssuerMetadata
+	notBefore         time.Time
+	publicKey         ed25519.PublicKey
+	allowIssuerKey    bool
+	customClaims      map[string]any
+	subSubjects       []string
+	limits            *ResourceLimits
+	serverMetadata    map[string]string
+	chainMaxDepth     int
+	chainDelegation   time.Time
+	issuerConstraints *ChainIssuerConstraints
+	clock             func() time.Time
+	attestation       *HardwareAttestation
+}
+
+// WithAudience sets the audiences the token is intended for, verifiers can require a specific audience using WithExpectedAudience
+func WithAudience(aud ...string) ClaimsOption {
+	return func(o *claimsOptions) error {
+		for _, a := range aud {
+			if a == "" {
+				return fmt.Errorf("audience cannot be empty")
+			}
+		}
+
+		o.audience = append(o.audience, aud...)
+
+		return nil
+	}
+}
+
+// WithIssuerMetadata stamps contact and policy information about the issuer into the token, overriding any default set using SetDefaultIssuerMetadata
+func WithIssuerMetadata(md IssuerMetadata) ClaimsOption {
+	return func(o *claimsOptions) error {
+		err := md.validate()
+		if err != nil {
+			return err
+		}
+
+		o.issuerMetadata = &md
+
+		return nil
+	}
+}
+
+// WithNotBefore sets the time before which the token will not be accepted, defaults to the issue time
+func WithNotBefore(t time.Time) ClaimsOption {
+	return func(o *claimsOptions) error {
+		if t.IsZero() {
+			return fmt.Errorf("not before time cannot be zero")
+		}
+
+		o.notBefore = t
+
+		return nil
+	}
+}
+
+// WithClaimsClock determines the issue and expiry times of new claims using now rather than the clock set using SetClock
+func WithClaimsClock(now func() time.Time) ClaimsOption {
+	return func(o *claimsOptions) error {
+		if now == nil {
+			return fmt.Errorf("clock is required")
+		}
+
+		o.clock = now
+
+		return nil
+	}
+}
+
+// WithCustomClaims adds arbitrary site specific data to the token, values must be JSON encodable
+func WithCustomClaims(claims map[string]any) ClaimsOption {
+	return func(o *claimsOptions) error {
+		if o.customClaims == nil {
+			o.customClaims = make(map[string]any)
+		}
+
+		for k, v := range claims {
+			if k == "" {
+				return fmt.Errorf("custom claim names cannot be empty")
+			}
+
+			_, err := json.Marshal(v)
+			if err != nil {
+				return fmt.Errorf("invalid custom claim %q: %w", k, err)
+			}
+
+			o.customClaims[k] = v
+		}
+
+		return nil
+	}
+}
+
+// WithServerSubscribeSubjects grants servers created using NewServerClaims additional subjects to subscribe to,
+// subjects may not overlap the subjects the Choria Broker reserves for the system or any collective
+func WithServerSubscribeSubjects(subjects ...string) ClaimsOption {
+	return func(o *claimsOptions) error {
+		o.subSubjects = append(o.subSubjects, subjects...)
+		return nil
+	}
+}
+
+// AllowIssuerPublicKey allows a token to embed the same public key as its issuer, by default this is an error both when
+// creating the token and when later setting the issuer using AddOrgIssuerData, SetOrgIssuer or SetChainIssuer
+func AllowIssuerPublicKey() ClaimsOption {
+	return func(o *claimsOptions) error {
+		o.allowIssuerKey = true
+		return nil
+	}
+}
+
+// withPublicKey is used by constructors to set the public key on the token, the key is validated before being stored
+func withPublicKey(pk ed25519.PublicKey) ClaimsOption {
+	return func(o *claimsOptions) error {
+		o.publicKey = pk
+		return nil
+	}
+}
+
+func newClaimsOptions(opts ...ClaimsOption) (*claimsOptions, error) {
+	copts := &claimsOptions{}
+
+	if defaultIssuerMetadata != nil {
+		md := *defaultIssuerMetadata
+		copts.issuerMetadata = &md
+	}
+
+	for _, opt := range opts {
+		err := opt(copts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return copts, nil
+}
+
+func (o *claimsOptions) apply(claims *StandardClaims) error {
+	if len(o.audience) > 0 {
+		claims.Audience = jwt.ClaimStrings(o.audience)
+	}
+
+	if o.issuerMetadata != nil {
+		claims.IssuerMetadata = o.issuerMetadata
+	}
+
+	if o.publicKey != nil {
+		err := ValidateEd25519PublicKey(o.publicKey)
+		if err != nil {
+			return err
+		}
+
+		if !o.allowIssuerKey && isIssuerPublicKey(claims.Issuer, o.publicKey) {
+			return ErrIssuerPublicKey
+		}
+
+		claims.PublicKey = hex.EncodeToString(o.publicKey)
+	}
+
+	claims.allowIssuerKey = o.allowIssuerKey
+
+	if len(o.customClaims) > 0 {
+		claims.CustomClaims = o.customClaims
+	}
+
+	claims.ChainMaxDepth = o.chainMaxDepth
+
+	if !o.chainDelegation.IsZero() {
+		if claims.ExpiresAt != nil && o.chainDelegation.After(claims.ExpiresAt.Time) {
+			return fmt.Errorf("chain delegation expiry cannot be after the expiry time")
+		}
+
+		claims.ChainDelegationExpiresAt = jwt.NewNumericDate(o.chainDelegation)
+	}
+
+	if !o.notBefore.IsZero() {
+		if claims.ExpiresAt != nil && !o.notBefore.Before(claims.ExpiresAt.Time) {
+			return fmt.Errorf("not before time must be before the expiry time")
+		}
+
+		claims.NotBefore = jwt.NewNumericDate(o.notBefore)
+	}
+
+	return nil
+}
+
+// isIssuerPublicKey determines if pk is the key embedded in an org or chain issuer string
+func isIssuerPublicKey(issuer string, pk ed25519.PublicKey) bool {
+	pks := hex.EncodeToString(pk)
+
+	switch {
+	case strings.HasPrefix(issuer, OrgIssuerPrefix):
+		return strings.TrimPrefix(issuer, OrgIssuerPrefix) == pks
+	case strings.HasPrefix(issuer, ChainIssuerPrefix):
+		parts := strings.Split(strings.TrimPrefix(issuer, ChainIssuerPrefix), ".")
+		return len(parts) == 2 && parts[1] == pks
+	default:
+		return false
+	}
+}
+
+// SignOption configures optional behavior when signing tokens
+type SignOption func(*signOptions) error
+
+type signOptions struct {
+	codec       Codec
+	compress    bool
+	issuerChain []string
+	ctx         context.Context
+}
+
+// WithCodec encodes the claims using the codec registered for contentType rather than JSON
+func WithCodec(contentType string) SignOption {
+	return func(o *signOptions) error {
+		codec, ok := codecFor(contentType)
+		if !ok {
+			return fmt.Errorf("%w %q", ErrUnknownCodec, contentType)
+		}
+
+		o.codec = codec
+
+		return nil
+	}
+}
+
+func (o *signOptions) context() context.Context {
+	if o.ctx == nil {
+		return context.Background()
+	}
+
+	return o.ctx
+}
+
+func newSignOptions(opts ...SignOption) (*signOptions, error) {
+	sopts := &signOptions{}
+	for _, opt := range opts {
+		err := opt(sopts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return sopts, nil
+}
+
+// ParseOption configures optional verification behavior when parsing tokens
+type ParseOption func(*parseOptions) error
+
+var (
+	// ErrTokenInactive indicates the introspection endpoint reported the token as not active
+	ErrTokenInactive = errors.New("token is not active")
+
+	// ErrIntrospectionFailed indicates the introspection endpoint could not be queried or gave an invalid response
+	ErrIntrospectionFailed = errors.New("token introspection failed")
+)
+
+// tokenIntrospector checks tokens with an external service, like an Introspector, after all other checks passed
+type tokenIntrospector interface {
+	check(ctx context.Context, token string) error
+}
+
+type parseOptions struct {
+	audience    string
+	leeway      time.Duration
+	maxValidity time.Duration
+	rsaSunset   *RSASunsetPolicy
+	compact     bool
+	revocations RevocationChecker
+	introspect  tokenIntrospector
+	needChain   bool
+	strict      *strictParsing
+	replay      *ReplayGuard
+	ctx         context.Context
+	clock       func() time.Time
+	attestation *AttestationVerifier
+
+	// accessCheck enforces the access restrictions of client tokens for a request from accessSource
+	accessCheck  bool
+	accessSource net.IP
+
+	// signerKnown indicates the key passed to the parser signed the token, chain issuers are not resolved
+	signerKnown bool
+}
+
+// ErrTokenValidityTooLong indicates a token was issued with a validity longer than the verifier allows
+var ErrTokenValidityTooLong = errors.New("token validity exceeds the allowed maximum")
+
+// WithExpectedAudience requires that the token was issued for the audience aud
+func WithExpectedAudience(aud string) ParseOption {
+	return func(o *parseOptions) error {
+		if aud == "" {
+			return fmt.Errorf("audience cannot be empty")
+		}
+
+		o.audience = aud
+
+		return nil
+	}
+}
+
+// WithLeeway allows for clock skew between the issuer and verifier when validating the exp, nbf and iat claims
+func WithLeeway(leeway time.Duration) ParseOption {
+	return func(o *parseOptions) error {
+		if leeway < 0 {
+			return fmt.Errorf("leeway cannot be negative")
+		}
+
+		o.leeway = leeway
+
+		return nil
+	}
+}
+
+// WithVerificationClock validates the exp, nbf and iat claims against now rather than the clock set using SetClock
+func WithVerificationClock(now func() time.Time) ParseOption {
+	return func(o *parseOptions) error {
+		if now == nil {
+			return fmt.Errorf("clock is required")
+		}
+
+		o.clock = now
+
+		return nil
+	}
+}
+
+// WithAccessSource enforces the access restrictions of client tokens at the time of parsing for a request made from
+// source, source may be nil when it is not known, see ClientIDClaims.CheckAccess. Other tokens are not affected
+func WithAccessSource(source net.IP) ParseOption {
+	return func(o *parseOptions) error {
+		o.accessCheck = true
+		o.accessSource = source
+
+		return nil
+	}
+}
+
+// WithRequiredAttestation requires server tokens to hold a hardware attestation verified by v, other tokens are not
+// affected. Server tokens and tokens without a purpose fail verification unless parsed into ServerClaims
+func WithRequiredAttestation(v *AttestationVerifier) ParseOption {
+	return func(o *parseOptions) error {
+		if v == nil {
+			return fmt.Errorf("attestation verifier is required")
+		}
+
+		o.attestation = v
+
+		return nil
+	}
+}
+
+// WithMaxValidity rejects tokens where the time between issue and expiry exceeds max, tokens without these times are rejected
+func WithMaxValidity(max time.Duration) ParseOption {
+	return func(o *parseOptions) error {
+		if max <= 0 {
+			return fmt.Errorf("maximum validity must be positive")
+		}
+
+		o.maxValidity = max
+
+		return nil
+	}
+}
+
+// WithCompactVerification verifies ed25519 signed tokens using a minimal verifier that does not use the general purpose jwt parser,
+// this reduces the cost of verification on hot paths. Other tokens are verified as normal
+func WithCompactVerification() ParseOption {
+	return func(o *parseOptions) error {
+		o.compact = true
+		return nil
+	}
+}
+
+// WithRevocations rejects tokens that are revoked in rl, it cannot be combined with WithRevocationChecker
+func WithRevocations(rl *RevocationList) ParseOption {
+	return func(o *parseOptions) error {
+		if rl == nil {
+			return fmt.Errorf("revocation list is required")
+		}
+
+		if o.revocations != nil {
+			return ErrRevocationsConflict
+		}
+
+		o.revocations = rl
+
+		return nil
+	}
+}
+
+// WithRevocationChecker rejects tokens that checker reports as revoked, failures to check revocation status also reject the token.
+// It cannot be combined with WithRevocations, merge lists or wrap checkers to consult several sources
+func WithRevocationChecker(checker RevocationChecker) ParseOption {
+	return func(o *parseOptions) error {
+		if checker == nil {
+			return fmt.Errorf("revocation checker is required")
+		}
+
+		if o.revocations != nil {
+			return ErrRevocationsConflict
+		}
+
+		o.revocations = checker
+
+		return nil
+	}
+}
+
+// WithRequiredIssuerChain rejects tokens issued by chain issuers that do not embed the issuer chain, see WithIssuerChain
+func WithRequiredIssuerChain() ParseOption {
+	return func(o *parseOptions) error {
+		o.needChain = true
+		return nil
+	}
+}
+
+// WithRSASunsetPolicy notes, warns about or rejects RSA signed tokens according to the policy
+func WithRSASunsetPolicy(policy RSASunsetPolicy) ParseOption {
+	return func(o *parseOptions) error {
+		err := policy.validate()
+		if err != nil {
+			return err
+		}
+
+		o.rsaSunset = &policy
+
+		return nil
+	}
+}
+
+func newParseOptions(opts ...ParseOption) (*parseOptions, error) {
+	popts := &parseOptions{}
+	for _, opt := range opts {
+		err := opt(popts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return popts, nil
+}
+
+// context is the context of the parse operation, see ParseTokenContext
+func (o *parseOptions) context() context.Context {
+	if o.ctx == nil {
+		return context.Background()
+	}
+
+	return o.ctx
+}
+
+// validateParsed performs all checks that follow signature verification, stopping at the first problem
+func (o *parseOptions) validateParsed(token string, claims jwt.Claims) error {
+	var failure error
+
+	o.checkParsed(token, claims, true, func(err error) bool {
+		failure = err
+		return false
+	})
+
+	return failure
+}
+
+// checkParsed performs all checks that follow signature verification and passes every problem found to collect,
+// checking stops when collect returns false. The replay guard consumes the token so it is only used when useReplay is set
+func (o *parseOptions) checkParsed(token string, claims jwt.Claims, useReplay bool, collect func(err error) bool) {
+	failed := func(err error) bool {
+		return err != nil && !collect(err)
+	}
+
+	if failed(MigrateClaims(claims)) {
+		return
+	}
+
+	if failed(o.verifyTimes(claims)) {
+		return
+	}
+
+	if o.maxValidity > 0 && failed(o.verifyValidity(claims)) {
+		return
+	}
+
+	if o.audience != "" && failed(o.verifyAudience(claims)) {
+		return
+	}
+
+	if o.needChain && failed(verifyChainPresent(claims)) {
+		return
+	}
+
+	if v, ok := claims.(Validator); ok && failed(v.Validate()) {
+		return
+	}
+
+	if cc, ok := claims.(*ClientIDClaims); ok && o.accessCheck && failed(cc.CheckAccess(o.now(), o.accessSource)) {
+		return
+	}
+
+	if failed(o.verifyAttestation(token, claims)) {
+		return
+	}
+
+	if o.revocations != nil && failed(o.verifyRevocation(claims)) {
+		return
+	}
+
+	if o.introspect != nil && failed(o.introspect.check(o.context(), token)) {
+		return
+	}
+
+	if useReplay && o.replay != nil {
+		failed(o.replay.use(o.context(), claims, o.leeway))
+	}
+}
+
+type audienceVerifier interface {
+	VerifyAudience(cmp string, req bool) bool
+}
+
+// verifyAudience ensures the token was issued for the expected audience
+func (o *parseOptions) verifyAudience(claims jwt.Claims) error {
+	av, ok := claims.(audienceVerifier)
+	if !ok || !av.VerifyAudience(o.audience, true) {
+		return jwt.ErrTokenInvalidAudience
+	}
+
+	return nil
+}
+
+// verifyChainPresent ensures tokens issued by chain issuers embed their issuer chain
+func verifyChainPresent(claims jwt.Claims) error {
+	sc, ok := claims.(standardClaimsProvider)
+	if ok && strings.HasPrefix(sc.getStandardClaims().Issuer, ChainIssuerPrefix) && len(sc.getStandardClaims().IssuerChain) == 0 {
+		return fmt.Errorf("%w: issuer chain is required", ErrInvalidIssuerChain)
+	}
+
+	return nil
+}
+
+// verifyRevocation ensures the token is not revoked
+func (o *parseOptions) verifyRevocation(claims jwt.Claims) error {
+	var revoked bool
+	var err error
+
+	if cc, ok := o.revocations.(ContextRevocationChecker); ok {
+		revoked, err = cc.IsRevokedContext(o.context(), claims)
+	} else {
+		revoked, err = o.revocations.IsRevoked(claims)
+	}
+	if err != nil {
+		return fmt.Errorf("could not check revocation status: %w", err)
+	}
+	if revoked {
+		return ErrTokenRevoked
+	}
+
+	return nil
+}
+
+// verifyTimes validates the exp, iat and nbf claims allowing for the configured leeway,
+// claims of types we do not know are validated using their own Valid() method
+func (o *parseOptions) verifyTimes(claims jwt.Claims) error {
+	exp, iat, nbf, ok := claimsTimes(claims)
+	if !ok {
+		return claims.Valid()
+	}
+
+	now := o.now()
+	vErr := &jwt.ValidationError{}
+
+	if exp != nil && !now.Add(-o.leeway).Before(exp.Time) {
+		vErr.Inner = fmt.Errorf("%s by %s", jwt.ErrTokenExpired, now.Sub(exp.Time))
+		vErr.Errors |= jwt.ValidationErrorExpired
+	}
+
+	if iat != nil && now.Add(o.leeway).Before(iat.Time) {
+		vErr.Inner = jwt.ErrTokenUsedBeforeIssued
+		vErr.Errors |= jwt.ValidationErrorIssuedAt
+	}
+
+	if nbf != nil && now.Add(o.leeway).Before(nbf.Time) {
+		vErr.Inner = jwt.ErrTokenNotValidYet
+		vErr.Errors |= jwt.ValidationErrorNotValidYet
+	}
+
+	if vErr.Errors == 0 {
+		return nil
+	}
+
+	return vErr
+}
+
+// verifyValidity ensures the token was not issued for longer than the maximum validity
+func (o *parseOptions) verifyValidity(claims jwt.Claims) error {
+	exp, iat, _, ok := claimsTimes(claims)
+	if !ok || exp == nil || iat == nil {
+		return fmt.Errorf("%w: token does not have issue and expiry times", ErrTokenValidityTooLong)
+	}
+
+	validity := exp.Sub(iat.Time)
+	if validity > o.maxValidity {
+		return fmt.Errorf("%w: %v exceeds %v", ErrTokenValidityTooLong, validity, o.maxValidity)
+	}
+
+	return nil
+}
+
+// claimsTimes extracts the exp, iat and nbf claims from claims of types we know
+func claimsTimes(claims jwt.Claims) (exp *jwt.NumericDate, iat *jwt.NumericDate, nbf *jwt.NumericDate, ok bool) {
+	switch c := claims.(type) {
+	case standardClaimsProvider:
+		sc := c.getStandardClaims()
+		return sc.ExpiresAt, sc.IssuedAt, sc.NotBefore, true
+	case *jwt.RegisteredClaims:
+		return c.ExpiresAt, c.IssuedAt, c.NotBefore, true
+	case *jwt.MapClaims:
+		return mapClaimsTime(*c, "exp"), mapClaimsTime(*c, "iat"), mapClaimsTime(*c, "nbf"), true
+	default:
+		return nil, nil, nil, false
+	}
+}
+
+func mapClaimsTime(claims jwt.MapClaims, key string) *jwt.NumericDate {
+	var ts float64
+
+	switch v := claims[key].(type) {
+	case float64:
+		ts = v
+	case int64:
+		ts = float64(v)
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return nil
+		}
+		ts = f
+	default:
+		return nil
+	}
+
+	return jwt.NewNumericDate(time.Unix(0, int64(ts*float64(time.Second))))
+}
+
+// now is the current time according to the configured clock
+func (o *parseOptions) now() time.Time {
+	if o.clock != nil {
+		return o.clock()
+	}
+
+	return currentTime()
+}
+
+// now is the current time according to the configured clock
+func (o *claimsOptions) now() time.Time {
+	if o.clock != nil {
+		return o.clock()
+	}
+
+	return currentTime()
+}
+
+// verifyAttestation verifies the hardware attestation of server tokens when required, server tokens and tokens
+// without a purpose must be parsed into ServerClaims so their attestation can be verified
+func (o *parseOptions) verifyAttestation(token string, claims jwt.Claims) error {
+	if o.attestation == nil {
+		return nil
+	}
+
+	sc, ok := claims.(*ServerClaims)
+	if ok {
+		return o.attestation.verifyAt(sc, o.now())
+	}
+
+	switch TokenPurpose(token) {
+	case ServerPurpose, UnknownPurpose:
+		return fmt.Errorf("%w: server tokens must be parsed into ServerClaims, got %T", ErrAttestationFailed, claims)
+	}
+
+	return nil
+}
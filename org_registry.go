@@ -0,0 +1,176 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// OrgRegistry holds the org issuer keys and issuer endpoints trusted for each organization, safe for concurrent use
+type OrgRegistry struct {
+	orgs map[string]*registeredOrg
+	mu   sync.Mutex
+}
+
+type registeredOrg struct {
+	keys      *KeyRing
+	endpoints []string
+}
+
+// NewOrgRegistry creates an empty organization registry
+func NewOrgRegistry() *OrgRegistry {
+	return &OrgRegistry{orgs: map[string]*registeredOrg{}}
+}
+
+// AddIssuer trusts pk as an org issuer for org along with the endpoints where that issuer can be reached, keys and
+// endpoints already known are not added again
+func (r *OrgRegistry) AddIssuer(org string, pk ed25519.PublicKey, endpoints ...string) error {
+	return r.AddIssuerWithWindow(org, pk, time.Time{}, time.Time{}, endpoints...)
+}
+
+// AddIssuerWithWindow trusts pk as an org issuer for org between notBefore and notAfter, zero times leave that side
+// of the window open, see AddIssuer
+func (r *OrgRegistry) AddIssuerWithWindow(org string, pk ed25519.PublicKey, notBefore time.Time, notAfter time.Time, endpoints ...string) error {
+	if org == "" {
+		return fmt.Errorf("organization name is required")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.orgs == nil {
+		r.orgs = map[string]*registeredOrg{}
+	}
+
+	o, ok := r.orgs[org]
+	if !ok {
+		o = &registeredOrg{keys: &KeyRing{}}
+	}
+
+	if !o.keys.Contains(pk) {
+		err := o.keys.AddWithWindow(pk, notBefore, notAfter)
+		if err != nil {
+			return err
+		}
+	}
+
+	r.orgs[org] = o
+
+	for _, e := range endpoints {
+		known := false
+		for _, existing := range o.endpoints {
+			if existing == e {
+				known = true
+				break
+			}
+		}
+		if !known && e != "" {
+			o.endpoints = append(o.endpoints, e)
+		}
+	}
+
+	return nil
+}
+
+// AddOrganization trusts the org issuer keys and endpoints of a organization from a trust configuration
+func (r *OrgRegistry) AddOrganization(org TrustedOrganization) error {
+	if len(org.IssuerKeys) == 0 {
+		return fmt.Errorf("organization %s requires at least one issuer key", org.Name)
+	}
+
+	for _, k := range org.IssuerKeys {
+		pk, err := hex.DecodeString(k)
+		if err != nil {
+			return fmt.Errorf("invalid issuer key for organization %s: %w", org.Name, err)
+		}
+
+		err = r.AddIssuer(org.Name, ed25519.PublicKey(pk), org.Endpoints...)
+		if err != nil {
+			return fmt.Errorf("invalid issuer key for organization %s: %w", org.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// TrustedOrganizations exports the organizations and their org issuers for use in a trust configuration, keys are
+// exported without their trust windows
+func (r *OrgRegistry) TrustedOrganizations() []TrustedOrganization {
+	var res []TrustedOrganization
+
+	for _, name := range r.Organizations() {
+		r.mu.Lock()
+		o := r.orgs[name]
+		org := TrustedOrganization{Name: name, Endpoints: append([]string(nil), o.endpoints...)}
+		for _, k := range o.keys.Keys() {
+			if pk, ok := k.(ed25519.PublicKey); ok {
+				org.IssuerKeys = append(org.IssuerKeys, hex.EncodeToString(pk))
+			}
+		}
+		r.mu.Unlock()
+
+		res = append(res, org)
+	}
+
+	return res
+}
+
+// Organizations lists the names of all known organizations
+func (r *OrgRegistry) Organizations() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var res []string
+	for name := range r.orgs {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+
+	return res
+}
+
+// KeyRing creates a key ring holding the org issuer keys trusted for org, keys only verify tokens during their trust window
+func (r *OrgRegistry) KeyRing(org string) (*KeyRing, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	o, ok := r.orgs[org]
+	if !ok {
+		return nil, fmt.Errorf("organization %s is not trusted", org)
+	}
+
+	return o.keys.clone(), nil
+}
+
+// Endpoints are the issuer endpoints known for org
+func (r *OrgRegistry) Endpoints(org string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	o, ok := r.orgs[org]
+	if !ok {
+		return nil
+	}
+
+	return append([]string{}, o.endpoints...)
+}
+
+// IsTrustedIssuer determines if pk is a trusted org issuer for org
+func (r *OrgRegistry) IsTrustedIssuer(org string, pk ed25519.PublicKey) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	o, ok := r.orgs[org]
+	if !ok {
+		return false
+	}
+
+	return o.keys.Contains(pk)
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("OrgRegistry", func() {
+	var (
+		acmeK, otherK ed25519.PublicKey
+		reg           *OrgRegistry
+	)
+
+	BeforeEach(func() {
+		var err error
+		acmeK, _, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		otherK, _, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		reg = NewOrgRegistry()
+	})
+
+	Describe("AddIssuer", func() {
+		It("Should validate the organization and key", func() {
+			Expect(reg.AddIssuer("", acmeK)).To(MatchError("organization name is required"))
+			Expect(reg.AddIssuer("acme", ed25519.PublicKey(make([]byte, 32)))).To(MatchError(ErrZeroPublicKey))
+			Expect(reg.Organizations()).To(BeEmpty())
+		})
+
+		It("Should add keys and endpoints once", func() {
+			Expect(reg.AddIssuer("acme", acmeK, "https://aaa.acme.net")).To(Succeed())
+			Expect(reg.AddIssuer("acme", acmeK, "https://aaa.acme.net", "https://aaa2.acme.net", "")).To(Succeed())
+			Expect(reg.AddIssuer("other", otherK)).To(Succeed())
+
+			Expect(reg.Organizations()).To(Equal([]string{"acme", "other"}))
+			Expect(reg.Endpoints("acme")).To(Equal([]string{"https://aaa.acme.net", "https://aaa2.acme.net"}))
+			Expect(reg.Endpoints("other")).To(BeEmpty())
+			Expect(reg.Endpoints("unknown")).To(BeNil())
+
+			kr, err := reg.KeyRing("acme")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(kr.Keys()).To(Equal([]any{acmeK}))
+
+			_, err = reg.KeyRing("unknown")
+			Expect(err).To(MatchError("organization unknown is not trusted"))
+		})
+
+		It("Should keep the trust window of keys", func() {
+			now := time.Now()
+			Expect(reg.AddIssuerWithWindow("acme", acmeK, now, now.Add(-time.Hour))).To(MatchError("not after cannot be before not before"))
+			Expect(reg.Organizations()).To(BeEmpty())
+
+			Expect(reg.AddIssuerWithWindow("acme", acmeK, time.Time{}, now.Add(time.Hour))).To(Succeed())
+
+			kr, err := reg.KeyRing("acme")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(kr.KeysAt(now)).To(Equal([]any{acmeK}))
+			Expect(kr.KeysAt(now.Add(2 * time.Hour))).To(BeEmpty())
+		})
+
+		It("Should work with a zero value registry", func() {
+			reg = &OrgRegistry{}
+			Expect(reg.AddIssuer("acme", acmeK)).To(Succeed())
+			Expect(reg.IsTrustedIssuer("acme", acmeK)).To(BeTrue())
+		})
+	})
+
+	Describe("Trusted organizations", func() {
+		It("Should add and export trust configuration organizations", func() {
+			Expect(reg.AddOrganization(TrustedOrganization{Name: "acme"})).To(MatchError("organization acme requires at least one issuer key"))
+			Expect(reg.AddOrganization(TrustedOrganization{Name: "acme", IssuerKeys: []string{"x"}})).To(MatchError(ContainSubstring("invalid issuer key for organization acme")))
+
+			org := TrustedOrganization{Name: "acme", IssuerKeys: []string{hex.EncodeToString(acmeK)}, Endpoints: []string{"https://aaa.acme.net"}}
+			Expect(reg.AddOrganization(org)).To(Succeed())
+			Expect(reg.AddIssuer("other", otherK)).To(Succeed())
+
+			Expect(reg.IsTrustedIssuer("acme", acmeK)).To(BeTrue())
+			Expect(reg.Endpoints("acme")).To(Equal([]string{"https://aaa.acme.net"}))
+			Expect(reg.TrustedOrganizations()).To(Equal([]TrustedOrganization{
+				org,
+				{Name: "other", IssuerKeys: []string{hex.EncodeToString(otherK)}},
+			}))
+
+			cfg := &TrustConfig{Organizations: reg.TrustedOrganizations()}
+			copied, err := cfg.OrgRegistry()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(copied.TrustedOrganizations()).To(Equal(reg.TrustedOrganizations()))
+		})
+	})
+
+	Describe("IsTrustedIssuer", func() {
+		It("Should only trust keys for their organization", func() {
+			Expect(reg.AddIssuer("acme", acmeK)).To(Succeed())
+			Expect(reg.AddIssuer("other", otherK)).To(Succeed())
+
+			Expect(reg.IsTrustedIssuer("acme", acmeK)).To(BeTrue())
+			Expect(reg.IsTrustedIssuer("acme", otherK)).To(BeFalse())
+			Expect(reg.IsTrustedIssuer("unknown", acmeK)).To(BeFalse())
+		})
+	})
+})
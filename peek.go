@@ -0,0 +1,226 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"time"
+)
+
+// TokenPeek holds header fields and selected claims of a token read without verifying it, it must only be used to
+// route tokens to the code that will parse and verify them
+type TokenPeek struct {
+	// Algorithm is the signing algorithm from the alg header
+	Algorithm string
+
+	// KeyID is the optional kid header
+	KeyID string
+
+	// Purpose is the purpose of the token as determined by TokenPurpose
+	Purpose Purpose
+
+	// Identity is the caller id, identity or name of the token, falling back to the subject and token id
+	Identity string
+
+	// ExpiresAt is the exp claim, zero when the token does not expire
+	ExpiresAt time.Time
+}
+
+// peekClaims decodes only the claims needed by PeekToken when the token cannot be scanned
+type peekClaims struct {
+	CallerID string `json:"callerid"`
+	Identity string `json:"identity"`
+	Name     string `json:"name"`
+
+	StandardClaims
+}
+
+// PeekToken reads the algorithm, key id, purpose, identity and expiry of token without verifying it and without
+// decoding the rest of the claims
+func PeekToken(token string) (TokenPeek, error) {
+	peek, ok := scanPeek(token)
+	if ok {
+		return peek, nil
+	}
+
+	claims := peekClaims{}
+	t, err := parseUnverified(token, &claims)
+	if err != nil {
+		return TokenPeek{}, err
+	}
+
+	peek = TokenPeek{
+		Algorithm: t.Method.Alg(),
+		Purpose:   claims.Purpose,
+		Identity:  firstNonEmpty(claims.CallerID, claims.Identity, claims.Name, claims.Subject, claims.ID),
+	}
+
+	if claims.ExpiresAt != nil {
+		peek.ExpiresAt = claims.ExpiresAt.Time
+	}
+
+	peek.KeyID, _ = t.Header["kid"].(string)
+
+	if peek.Purpose == UnknownPurpose && claims.Subject == string(ProvisioningPurpose) {
+		peek.Purpose = ProvisioningPurpose
+	}
+
+	return peek, nil
+}
+
+// scanPeek reads the fields of a TokenPeek without the regular decoder, ok is false when it has to be used
+func scanPeek(token string) (peek TokenPeek, ok bool) {
+	hdr, payload, ok := tokenSegments(token)
+	if !ok {
+		return peek, false
+	}
+
+	bp := scanBuffers.Get().(*[]byte)
+	defer scanBuffers.Put(bp)
+
+	buf, ok := b64URLDecode((*bp)[:0], hdr)
+	if !ok {
+		return peek, false
+	}
+
+	var alg, kid []byte
+	var encoded bool
+
+	ok = scanJSONObject(buf, func(key []byte, val []byte, kind byte) bool {
+		// the jwt parser decodes the header into a map so only cty and zip, read into a struct, are case insensitive
+		switch {
+		case jsonKeyMatches(key, "cty", true), jsonKeyMatches(key, "zip", true):
+			encoded = true
+		case string(key) == "alg":
+			alg = val
+			return kind == '"'
+		case string(key) == "kid":
+			kid = nil
+			if kind == '"' {
+				kid = val
+			}
+			return kind != '\\'
+		}
+
+		return true
+	})
+	if !ok || encoded {
+		return peek, false
+	}
+
+	for _, k := range knownAlgorithms {
+		if string(alg) == k {
+			peek.Algorithm = k
+		}
+	}
+	if peek.Algorithm == "" {
+		return peek, false
+	}
+
+	peek.KeyID = string(kid)
+
+	// the header is copied out, the buffer can now hold the claims
+	buf, ok = b64URLDecode(buf[:0], payload)
+	*bp = buf[:0]
+	if !ok {
+		return peek, false
+	}
+
+	var purpose, sub, jti []byte
+	var ids [3][]byte
+	var exp int64
+	var hasExp bool
+
+	ok = scanJSONObject(buf, func(key []byte, val []byte, kind byte) bool {
+		var dst *[]byte
+
+		switch {
+		case jsonKeyMatches(key, "exp", true):
+			if kind == 'n' {
+				hasExp = false
+				return true
+			}
+
+			exp, hasExp = jsonInteger(val)
+			return hasExp
+		case jsonKeyMatches(key, "purpose", true):
+			dst = &purpose
+		case jsonKeyMatches(key, "sub", true):
+			dst = &sub
+		case jsonKeyMatches(key, "jti", true):
+			dst = &jti
+		case jsonKeyMatches(key, "callerid", true):
+			dst = &ids[0]
+		case jsonKeyMatches(key, "identity", true):
+			dst = &ids[1]
+		case jsonKeyMatches(key, "name", true):
+			dst = &ids[2]
+		default:
+			return true
+		}
+
+		switch kind {
+		case '"':
+			*dst = val
+		case 'n':
+			*dst = nil
+		default:
+			// escaped strings need the decoder and other types fail to decode
+			return false
+		}
+
+		return true
+	})
+	if !ok {
+		return peek, false
+	}
+
+	switch {
+	case len(purpose) > 0:
+		peek.Purpose = internPurpose(purpose)
+	case string(sub) == string(ProvisioningPurpose):
+		peek.Purpose = ProvisioningPurpose
+	}
+
+	for _, id := range [][]byte{ids[0], ids[1], ids[2], sub, jti} {
+		if len(id) > 0 {
+			peek.Identity = string(id)
+			break
+		}
+	}
+
+	if hasExp {
+		peek.ExpiresAt = time.Unix(exp, 0)
+	}
+
+	return peek, true
+}
+
+// jsonInteger parses a JSON number made only of digits, valid is false for anything the regular decoder must handle
+func jsonInteger(val []byte) (n int64, valid bool) {
+	if len(val) == 0 || len(val) > 15 {
+		return 0, false
+	}
+
+	for _, c := range val {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+
+		n = n*10 + int64(c-'0')
+	}
+
+	return n, true
+}
+
+// firstNonEmpty returns the first of vals that is not empty
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
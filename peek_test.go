@@ -0,0 +1,118 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PeekToken", func() {
+	var (
+		priK   ed25519.PrivateKey
+		client *ClientIDClaims
+		token  string
+	)
+
+	BeforeEach(func() {
+		var err error
+		_, priK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		client, err = NewClientIDClaims("up=bob", []string{"rpcutil"}, "", nil, "", "", time.Hour, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		token, err = SignToken(client, priK)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Should peek at signed tokens", func() {
+		peek, err := PeekToken(token)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(peek.Algorithm).To(Equal(algEdDSA))
+		Expect(peek.Purpose).To(Equal(ClientIDPurpose))
+		Expect(peek.Identity).To(Equal("up=bob"))
+		Expect(peek.ExpiresAt).To(BeTemporally("==", client.ExpiresAt.Time))
+
+		pubK, _, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		server, err := NewServerClaims("n1.example.net", []string{"choria"}, "", nil, nil, pubK, "", time.Hour)
+		Expect(err).ToNot(HaveOccurred())
+		signed, err := SignToken(server, loadRSAPriKey("testdata/rsa/signer-key.pem"))
+		Expect(err).ToNot(HaveOccurred())
+
+		peek, err = PeekToken(signed)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(peek.Algorithm).To(Equal(algRS256))
+		Expect(peek.Purpose).To(Equal(ServerPurpose))
+		Expect(peek.Identity).To(Equal("n1.example.net"))
+
+		compressed, err := SignToken(client, priK, WithCompression())
+		Expect(err).ToNot(HaveOccurred())
+		peek, err = PeekToken(compressed)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(peek.Identity).To(Equal("up=bob"))
+		Expect(peek.ExpiresAt).To(BeTemporally("==", client.ExpiresAt.Time))
+	})
+
+	It("Should agree with the regular decoder", func() {
+		craft := func(hdr string, payload string) string {
+			parts := strings.Split(token, ".")
+			parts[0] = base64.RawURLEncoding.EncodeToString([]byte(hdr))
+			parts[1] = base64.RawURLEncoding.EncodeToString([]byte(payload))
+			return strings.Join(parts, ".")
+		}
+
+		cases := map[string][2]string{
+			"kid":          {`{"alg":"EdDSA","kid":"k1"}`, `{"purpose":"choria_server","identity":"n1","exp":1700000000}`},
+			"non string":   {`{"alg":"EdDSA","kid":1}`, `{"purpose":"choria_server","name":"x","exp":null}`},
+			"provisioning": {`{"alg":"EdDSA"}`, `{"sub":"choria_provisioning","jti":"x"}`},
+			"token id":     {`{"alg":"EdDSA"}`, `{"jti":"2a1b","callerid":""}`},
+			"escapes":      {`{"alg":"EdDSA","kid":"k1"}`, `{"purpose":"choria_client_id","callerid":"up=b\u006fb"}`},
+			"fraction":     {`{"alg":"EdDSA"}`, `{"exp":1700000000.5}`},
+			"zero expiry":  {`{"alg":"EdDSA"}`, `{"exp":0}`},
+		}
+
+		for name, c := range cases {
+			crafted := craft(c[0], c[1])
+
+			peek, err := PeekToken(crafted)
+			Expect(err).ToNot(HaveOccurred(), name)
+
+			claims := peekClaims{}
+			t, err := parseUnverified(crafted, &claims)
+			Expect(err).ToNot(HaveOccurred(), name)
+
+			kid, _ := t.Header["kid"].(string)
+			Expect(peek.KeyID).To(Equal(kid), name)
+			Expect(peek.Purpose).To(Equal(TokenPurpose(crafted)), name)
+			Expect(peek.Identity).To(Equal(firstNonEmpty(claims.CallerID, claims.Identity, claims.Name, claims.Subject, claims.ID)), name)
+			if claims.ExpiresAt == nil {
+				Expect(peek.ExpiresAt.IsZero()).To(BeTrue(), name)
+			} else {
+				Expect(peek.ExpiresAt).To(BeTemporally("==", claims.ExpiresAt.Time), name)
+			}
+		}
+
+		_, err := PeekToken(craft(`{"alg":"EdDSA"}`, `{"purpose":1}`))
+		Expect(err).To(HaveOccurred())
+		_, err = PeekToken("garbage")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Should only allocate for the identity", func() {
+		Expect(testing.AllocsPerRun(100, func() { PeekToken(token) })).To(BeNumerically("<=", 1))
+	})
+})
+
+func BenchmarkPeekToken(b *testing.B) {
+	benchmarkTokenField(b, func(token string) { PeekToken(token) })
+}
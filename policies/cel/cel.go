@@ -0,0 +1,229 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package cel evaluates Common Expression Language policies embedded in client tokens, a light weight
+// alternative to the Open Policy Agent support in the policies/opa package
+package cel
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/choria-io/tokens"
+	"github.com/choria-io/tokens/internal/lru"
+	"github.com/google/cel-go/cel"
+)
+
+// DefaultCacheSize is the number of compiled policies kept by an Evaluator
+const DefaultCacheSize = 100
+
+// ErrNoPolicy indicates the token does not hold a policy
+var ErrNoPolicy = errors.New("token does not have a cel policy")
+
+// Option configures an Evaluator
+type Option func(*Evaluator) error
+
+// WithCacheSize sets the number of compiled policies to cache
+func WithCacheSize(size int) Option {
+	return func(e *Evaluator) error {
+		if size < 1 {
+			return fmt.Errorf("cache size must be positive")
+		}
+
+		e.size = size
+
+		return nil
+	}
+}
+
+// Evaluator evaluates CEL policies, policies are boolean expressions over the variables agent, action, data,
+// collective, callerid, properties, filter, time and unix_time, for example:
+//
+//	agent == "puppet" && action in ["status", "last_run_summary"]
+type Evaluator struct {
+	env   *cel.Env
+	size  int
+	cache *lru.Cache[cel.Program]
+}
+
+var _ tokens.PolicyEvaluator = (*Evaluator)(nil)
+
+// NewEvaluator creates a new CEL policy evaluator
+func NewEvaluator(opts ...Option) (*Evaluator, error) {
+	e := &Evaluator{
+		size: DefaultCacheSize,
+	}
+
+	for _, opt := range opts {
+		err := opt(e)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var err error
+	e.cache, err = lru.New[cel.Program](e.size)
+	if err != nil {
+		return nil, err
+	}
+
+	e.env, err = cel.NewEnv(
+		cel.Variable("agent", cel.StringType),
+		cel.Variable("action", cel.StringType),
+		cel.Variable("data", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("collective", cel.StringType),
+		cel.Variable("callerid", cel.StringType),
+		cel.Variable("properties", cel.MapType(cel.StringType, cel.StringType)),
+		cel.Variable("filter", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("time", cel.TimestampType),
+		cel.Variable("unix_time", cel.IntType),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not create cel environment: %w", err)
+	}
+
+	return e, nil
+}
+
+// Language is the policy language handled by the evaluator
+func (e *Evaluator) Language() tokens.PolicyLanguage {
+	return tokens.PolicyLanguageCEL
+}
+
+// Allowed determines if the client holding claims may perform req, AllowsAction is used for tokens without a policy
+// and tokens holding a policy in another language are not evaluated
+func (e *Evaluator) Allowed(ctx context.Context, claims *tokens.ClientIDClaims, req *tokens.PolicyRequest) (bool, error) {
+	if claims == nil {
+		return false, fmt.Errorf("claims are required")
+	}
+	if req == nil || req.Agent == "" || req.Action == "" {
+		return false, fmt.Errorf("request agent and action are required")
+	}
+
+	lang, err := claims.PolicyLanguage()
+	if err != nil {
+		return false, err
+	}
+
+	switch lang {
+	case "":
+		return claims.AllowsAction(req.Agent, req.Action), nil
+	case tokens.PolicyLanguageCEL:
+	default:
+		return false, fmt.Errorf("%w: %s", tokens.ErrUnsupportedPolicyLanguage, lang)
+	}
+
+	return e.Evaluate(ctx, claims.CELPolicy, Input(claims, req))
+}
+
+// Evaluate evaluates policy using input, the policy is compiled once and cached
+func (e *Evaluator) Evaluate(ctx context.Context, policy string, input map[string]any) (bool, error) {
+	if policy == "" {
+		return false, ErrNoPolicy
+	}
+
+	prg, err := e.prepare(policy)
+	if err != nil {
+		return false, err
+	}
+
+	out, _, err := prg.ContextEval(ctx, input)
+	if err != nil {
+		return false, fmt.Errorf("policy evaluation failed: %w", err)
+	}
+
+	allowed, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("policy produced a non boolean result")
+	}
+
+	return allowed, nil
+}
+
+// CachedPolicies is the number of compiled policies currently cached
+func (e *Evaluator) CachedPolicies() int {
+	return e.cache.Len()
+}
+
+// Input creates the policy variables for req made by the client holding claims
+func Input(claims *tokens.ClientIDClaims, req *tokens.PolicyRequest) map[string]any {
+	ts := req.Time
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+
+	data := req.Data
+	if data == nil {
+		data = map[string]any{}
+	}
+
+	props := claims.UserProperties
+	if props == nil {
+		props = map[string]string{}
+	}
+
+	filter := req.Filter
+	if filter == nil {
+		filter = &tokens.PolicyFilter{}
+	}
+
+	return map[string]any{
+		"agent":      req.Agent,
+		"action":     req.Action,
+		"data":       data,
+		"collective": req.Collective,
+		"callerid":   claims.CallerID,
+		"properties": props,
+		"time":       ts.UTC(),
+		"unix_time":  ts.Unix(),
+		"filter": map[string]any{
+			"agent":    nonNil(filter.Agents),
+			"cf_class": nonNil(filter.Classes),
+			"fact":     nonNil(filter.Facts),
+			"identity": nonNil(filter.Identities),
+			"compound": filter.Compound,
+		},
+	}
+}
+
+func nonNil(v []string) []string {
+	if v == nil {
+		return []string{}
+	}
+
+	return v
+}
+
+// prepare compiles policy or retrieves it from the cache, the least recently used entry is evicted once the cache is full
+func (e *Evaluator) prepare(policy string) (cel.Program, error) {
+	sum := sha256.Sum256([]byte(policy))
+	key := hex.EncodeToString(sum[:])
+
+	prg, ok := e.cache.Get(key)
+	if ok {
+		return prg, nil
+	}
+
+	ast, iss := e.env.Compile(policy)
+	if iss.Err() != nil {
+		return nil, fmt.Errorf("could not compile policy: %w", iss.Err())
+	}
+
+	if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+		return nil, fmt.Errorf("could not compile policy: policy must produce a boolean result, not %s", ast.OutputType())
+	}
+
+	prg, err := e.env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("could not compile policy: %w", err)
+	}
+
+	e.cache.Add(key, prg)
+
+	return prg, nil
+}
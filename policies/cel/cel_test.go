@@ -0,0 +1,145 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cel
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/choria-io/tokens"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestCEL(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Policies/CEL")
+}
+
+const testPolicy = `(agent == "puppet" && action == "status") || (agent == "service" && properties.group == "admins" && size(filter.identity) > 0)`
+
+var _ = Describe("Evaluator", func() {
+	var (
+		e      *Evaluator
+		claims *tokens.ClientIDClaims
+		ctx    context.Context
+	)
+
+	BeforeEach(func() {
+		var err error
+		e, err = NewEvaluator()
+		Expect(err).ToNot(HaveOccurred())
+
+		claims = &tokens.ClientIDClaims{
+			CallerID:       "up=bob",
+			UserProperties: map[string]string{"group": "admins"},
+			CELPolicy:      testPolicy,
+		}
+		ctx = context.Background()
+	})
+
+	Describe("NewEvaluator", func() {
+		It("Should validate options", func() {
+			_, err := NewEvaluator(WithCacheSize(0))
+			Expect(err).To(MatchError("cache size must be positive"))
+		})
+	})
+
+	Describe("Allowed", func() {
+		It("Should require claims and a request", func() {
+			_, err := e.Allowed(ctx, nil, &tokens.PolicyRequest{Agent: "puppet", Action: "status"})
+			Expect(err).To(MatchError("claims are required"))
+
+			_, err = e.Allowed(ctx, claims, &tokens.PolicyRequest{Agent: "puppet"})
+			Expect(err).To(MatchError("request agent and action are required"))
+		})
+
+		It("Should evaluate the policy", func() {
+			Expect(e.Allowed(ctx, claims, &tokens.PolicyRequest{Agent: "puppet", Action: "status"})).To(BeTrue())
+			Expect(e.Allowed(ctx, claims, &tokens.PolicyRequest{Agent: "puppet", Action: "disable"})).To(BeFalse())
+			Expect(e.Allowed(ctx, claims, &tokens.PolicyRequest{Agent: "service", Action: "stop"})).To(BeFalse())
+			Expect(e.Allowed(ctx, claims, &tokens.PolicyRequest{Agent: "service", Action: "stop", Filter: &tokens.PolicyFilter{Identities: []string{"n1"}}})).To(BeTrue())
+
+			claims.UserProperties = map[string]string{"group": "users"}
+			Expect(e.Allowed(ctx, claims, &tokens.PolicyRequest{Agent: "service", Action: "stop", Filter: &tokens.PolicyFilter{Identities: []string{"n1"}}})).To(BeFalse())
+		})
+
+		It("Should use allowed agents without a policy", func() {
+			claims.CELPolicy = ""
+			claims.AllowedAgents = []string{"rpcutil"}
+
+			Expect(e.Allowed(ctx, claims, &tokens.PolicyRequest{Agent: "rpcutil", Action: "ping"})).To(BeTrue())
+			Expect(e.Allowed(ctx, claims, &tokens.PolicyRequest{Agent: "puppet", Action: "status"})).To(BeFalse())
+
+			claims.ACL = []tokens.ActionGrant{{Agent: "puppet", Actions: []string{"status"}}}
+			Expect(e.Allowed(ctx, claims, &tokens.PolicyRequest{Agent: "rpcutil", Action: "ping"})).To(BeFalse())
+			Expect(e.Allowed(ctx, claims, &tokens.PolicyRequest{Agent: "puppet", Action: "status"})).To(BeTrue())
+		})
+
+		It("Should not evaluate policies in other languages", func() {
+			claims.CELPolicy = ""
+			claims.OPAPolicy = "package io\nallow = true"
+			claims.AllowedAgents = []string{"*"}
+
+			allowed, err := e.Allowed(ctx, claims, &tokens.PolicyRequest{Agent: "rpcutil", Action: "ping"})
+			Expect(err).To(MatchError(tokens.ErrUnsupportedPolicyLanguage))
+			Expect(allowed).To(BeFalse())
+		})
+
+		It("Should be usable through a policy verifier", func() {
+			v, err := tokens.NewPolicyVerifier(e)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(v.Allowed(ctx, claims, &tokens.PolicyRequest{Agent: "puppet", Action: "status"})).To(BeTrue())
+		})
+	})
+
+	Describe("Evaluate", func() {
+		It("Should handle invalid policies", func() {
+			_, err := e.Evaluate(ctx, "", nil)
+			Expect(err).To(MatchError(ErrNoPolicy))
+
+			_, err = e.Evaluate(ctx, "agent ==", nil)
+			Expect(err).To(MatchError(ContainSubstring("could not compile policy")))
+
+			_, err = e.Evaluate(ctx, "unknown == 1", nil)
+			Expect(err).To(MatchError(ContainSubstring("could not compile policy")))
+
+			_, err = e.Evaluate(ctx, "agent", nil)
+			Expect(err).To(MatchError(ContainSubstring("policy must produce a boolean result")))
+
+			_, err = e.Evaluate(ctx, "data.x", map[string]any{"data": map[string]any{"x": "yes"}})
+			Expect(err).To(MatchError("policy produced a non boolean result"))
+		})
+
+		It("Should cache compiled policies", func() {
+			e, err := NewEvaluator(WithCacheSize(2))
+			Expect(err).ToNot(HaveOccurred())
+
+			input := Input(claims, &tokens.PolicyRequest{Agent: "puppet", Action: "status"})
+			for i := 0; i < 3; i++ {
+				Expect(e.Evaluate(ctx, testPolicy, input)).To(BeTrue())
+			}
+			Expect(e.CachedPolicies()).To(Equal(1))
+
+			Expect(e.Evaluate(ctx, "true", nil)).To(BeTrue())
+			Expect(e.Evaluate(ctx, "false", nil)).To(BeFalse())
+			Expect(e.CachedPolicies()).To(Equal(2))
+		})
+	})
+
+	Describe("Input", func() {
+		It("Should expose the request and claims", func() {
+			ts := time.Unix(1700000000, 0)
+			input := Input(claims, &tokens.PolicyRequest{Agent: "puppet", Action: "status", Collective: "choria", Time: ts})
+			Expect(input["callerid"]).To(Equal("up=bob"))
+			Expect(input["collective"]).To(Equal("choria"))
+			Expect(input["unix_time"]).To(Equal(int64(1700000000)))
+			Expect(input["data"]).To(Equal(map[string]any{}))
+
+			Expect(e.Evaluate(ctx, `time == timestamp("2023-11-14T22:13:20Z") && unix_time == 1700000000`, input)).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,206 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package opa evaluates the Open Policy Agent policies embedded in client tokens
+package opa
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/choria-io/tokens"
+	"github.com/choria-io/tokens/internal/lru"
+	"github.com/open-policy-agent/opa/rego"
+)
+
+// DefaultQuery is the query evaluated against token policies, policies are written in package io and set allow
+const DefaultQuery = "data.io.allow"
+
+// DefaultCacheSize is the number of compiled policies kept by an Evaluator
+const DefaultCacheSize = 100
+
+// ErrNoPolicy indicates the token does not hold a policy
+var ErrNoPolicy = errors.New("token does not have an opa policy")
+
+// Option configures an Evaluator
+type Option func(*Evaluator) error
+
+// WithQuery sets the query to evaluate, the query must produce a boolean result
+func WithQuery(query string) Option {
+	return func(e *Evaluator) error {
+		if query == "" {
+			return fmt.Errorf("query is required")
+		}
+
+		e.query = query
+
+		return nil
+	}
+}
+
+// WithCacheSize sets the number of compiled policies to cache
+func WithCacheSize(size int) Option {
+	return func(e *Evaluator) error {
+		if size < 1 {
+			return fmt.Errorf("cache size must be positive")
+		}
+
+		e.size = size
+
+		return nil
+	}
+}
+
+// Evaluator evaluates token policies, compiled policies are cached keyed by their content so repeated
+// requests by the same users do not compile the policy again
+type Evaluator struct {
+	query string
+	size  int
+	cache *lru.Cache[*rego.PreparedEvalQuery]
+}
+
+var _ tokens.PolicyEvaluator = (*Evaluator)(nil)
+
+// NewEvaluator creates a new policy evaluator
+func NewEvaluator(opts ...Option) (*Evaluator, error) {
+	e := &Evaluator{
+		query: DefaultQuery,
+		size:  DefaultCacheSize,
+	}
+
+	for _, opt := range opts {
+		err := opt(e)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var err error
+	e.cache, err = lru.New[*rego.PreparedEvalQuery](e.size)
+	if err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// Language is the policy language handled by the evaluator
+func (e *Evaluator) Language() tokens.PolicyLanguage {
+	return tokens.PolicyLanguageRego
+}
+
+// Allowed determines if the client holding claims may perform req. When the token has no policy the
+// ACL or AllowedAgents are consulted instead using AllowsAction, tokens holding a policy in another language are not evaluated
+func (e *Evaluator) Allowed(ctx context.Context, claims *tokens.ClientIDClaims, req *tokens.PolicyRequest) (bool, error) {
+	if claims == nil {
+		return false, fmt.Errorf("claims are required")
+	}
+	if req == nil || req.Agent == "" || req.Action == "" {
+		return false, fmt.Errorf("request agent and action are required")
+	}
+
+	lang, err := claims.PolicyLanguage()
+	if err != nil {
+		return false, err
+	}
+
+	switch lang {
+	case "":
+		return claims.AllowsAction(req.Agent, req.Action), nil
+	case tokens.PolicyLanguageRego:
+	default:
+		return false, fmt.Errorf("%w: %s", tokens.ErrUnsupportedPolicyLanguage, lang)
+	}
+
+	return e.Evaluate(ctx, claims.OPAPolicy, Input(claims, req))
+}
+
+// Evaluate evaluates policy using input, the policy is compiled once and cached
+func (e *Evaluator) Evaluate(ctx context.Context, policy string, input map[string]any) (bool, error) {
+	if policy == "" {
+		return false, ErrNoPolicy
+	}
+
+	query, err := e.prepare(ctx, policy)
+	if err != nil {
+		return false, err
+	}
+
+	rs, err := query.Eval(ctx, rego.EvalInput(input))
+	if err != nil {
+		return false, fmt.Errorf("policy evaluation failed: %w", err)
+	}
+
+	if len(rs) != 1 || len(rs[0].Expressions) != 1 {
+		return false, nil
+	}
+
+	allowed, ok := rs[0].Expressions[0].Value.(bool)
+	if !ok {
+		return false, fmt.Errorf("policy produced a non boolean result")
+	}
+
+	return allowed, nil
+}
+
+// CachedPolicies is the number of compiled policies currently cached
+func (e *Evaluator) CachedPolicies() int {
+	return e.cache.Len()
+}
+
+// Input creates the policy input document for req made by the client holding claims
+func Input(claims *tokens.ClientIDClaims, req *tokens.PolicyRequest) map[string]any {
+	ts := req.Time
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+
+	input := map[string]any{
+		"agent":      req.Agent,
+		"action":     req.Action,
+		"data":       req.Data,
+		"collective": req.Collective,
+		"time":       ts.UTC().Format(time.RFC3339),
+		"unix_time":  ts.Unix(),
+		"callerid":   claims.CallerID,
+		"properties": claims.UserProperties,
+		"filter":     &tokens.PolicyFilter{},
+	}
+
+	if req.Data == nil {
+		input["data"] = map[string]any{}
+	}
+	if claims.UserProperties == nil {
+		input["properties"] = map[string]string{}
+	}
+	if req.Filter != nil {
+		input["filter"] = req.Filter
+	}
+
+	return input
+}
+
+// prepare compiles policy or retrieves it from the cache, the least recently used entry is evicted once the cache is full
+func (e *Evaluator) prepare(ctx context.Context, policy string) (*rego.PreparedEvalQuery, error) {
+	sum := sha256.Sum256([]byte(policy))
+	key := hex.EncodeToString(sum[:])
+
+	query, ok := e.cache.Get(key)
+	if ok {
+		return query, nil
+	}
+
+	prepared, err := rego.New(rego.Query(e.query), rego.Module("policy.rego", policy)).PrepareForEval(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not compile policy: %w", err)
+	}
+
+	e.cache.Add(key, &prepared)
+
+	return &prepared, nil
+}
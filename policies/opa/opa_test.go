@@ -0,0 +1,157 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package opa
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/choria-io/tokens"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestOPA(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Policies/OPA")
+}
+
+const testPolicy = `package io
+
+default allow = false
+
+allow {
+	input.agent == "puppet"
+	input.action == "status"
+}
+
+allow {
+	input.agent == "service"
+	input.properties.group == "admins"
+	count(input.filter.identity) > 0
+}
+`
+
+var _ = Describe("Evaluator", func() {
+	var (
+		e      *Evaluator
+		claims *tokens.ClientIDClaims
+		ctx    context.Context
+	)
+
+	BeforeEach(func() {
+		var err error
+		e, err = NewEvaluator()
+		Expect(err).ToNot(HaveOccurred())
+
+		claims = &tokens.ClientIDClaims{
+			CallerID:       "up=bob",
+			UserProperties: map[string]string{"group": "admins"},
+			OPAPolicy:      testPolicy,
+		}
+		ctx = context.Background()
+	})
+
+	Describe("NewEvaluator", func() {
+		It("Should validate options", func() {
+			_, err := NewEvaluator(WithCacheSize(0))
+			Expect(err).To(MatchError("cache size must be positive"))
+
+			_, err = NewEvaluator(WithQuery(""))
+			Expect(err).To(MatchError("query is required"))
+		})
+	})
+
+	Describe("Allowed", func() {
+		It("Should require claims and a request", func() {
+			_, err := e.Allowed(ctx, nil, &tokens.PolicyRequest{Agent: "puppet", Action: "status"})
+			Expect(err).To(MatchError("claims are required"))
+
+			_, err = e.Allowed(ctx, claims, &tokens.PolicyRequest{Agent: "puppet"})
+			Expect(err).To(MatchError("request agent and action are required"))
+		})
+
+		It("Should evaluate the policy", func() {
+			Expect(e.Allowed(ctx, claims, &tokens.PolicyRequest{Agent: "puppet", Action: "status"})).To(BeTrue())
+			Expect(e.Allowed(ctx, claims, &tokens.PolicyRequest{Agent: "puppet", Action: "disable"})).To(BeFalse())
+			Expect(e.Allowed(ctx, claims, &tokens.PolicyRequest{Agent: "service", Action: "stop"})).To(BeFalse())
+			Expect(e.Allowed(ctx, claims, &tokens.PolicyRequest{Agent: "service", Action: "stop", Filter: &tokens.PolicyFilter{Identities: []string{"n1"}}})).To(BeTrue())
+
+			claims.UserProperties = nil
+			Expect(e.Allowed(ctx, claims, &tokens.PolicyRequest{Agent: "service", Action: "stop", Filter: &tokens.PolicyFilter{Identities: []string{"n1"}}})).To(BeFalse())
+		})
+
+		It("Should use allowed agents without a policy", func() {
+			claims.OPAPolicy = ""
+			claims.AllowedAgents = []string{"rpcutil"}
+
+			Expect(e.Allowed(ctx, claims, &tokens.PolicyRequest{Agent: "rpcutil", Action: "ping"})).To(BeTrue())
+			Expect(e.Allowed(ctx, claims, &tokens.PolicyRequest{Agent: "puppet", Action: "status"})).To(BeFalse())
+
+			claims.ACL = []tokens.ActionGrant{{Agent: "puppet", Actions: []string{"status"}}}
+			Expect(e.Allowed(ctx, claims, &tokens.PolicyRequest{Agent: "rpcutil", Action: "ping"})).To(BeFalse())
+			Expect(e.Allowed(ctx, claims, &tokens.PolicyRequest{Agent: "puppet", Action: "status"})).To(BeTrue())
+		})
+
+		It("Should not evaluate policies in other languages", func() {
+			claims.OPAPolicy = ""
+			claims.CELPolicy = "true"
+			claims.AllowedAgents = []string{"*"}
+
+			allowed, err := e.Allowed(ctx, claims, &tokens.PolicyRequest{Agent: "rpcutil", Action: "ping"})
+			Expect(err).To(MatchError(tokens.ErrUnsupportedPolicyLanguage))
+			Expect(allowed).To(BeFalse())
+		})
+	})
+
+	Describe("Evaluate", func() {
+		It("Should handle invalid policies", func() {
+			_, err := e.Evaluate(ctx, "", nil)
+			Expect(err).To(MatchError(ErrNoPolicy))
+
+			_, err = e.Evaluate(ctx, "package io\nallow {", nil)
+			Expect(err).To(MatchError(ContainSubstring("could not compile policy")))
+
+			ok, err := e.Evaluate(ctx, "package other\nallow = true", nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ok).To(BeFalse())
+
+			_, err = e.Evaluate(ctx, "package io\nallow = \"yes\"", nil)
+			Expect(err).To(MatchError("policy produced a non boolean result"))
+		})
+
+		It("Should cache compiled policies", func() {
+			e, err := NewEvaluator(WithCacheSize(2))
+			Expect(err).ToNot(HaveOccurred())
+
+			for i := 0; i < 3; i++ {
+				Expect(e.Evaluate(ctx, testPolicy, map[string]any{"agent": "puppet", "action": "status"})).To(BeTrue())
+			}
+			Expect(e.CachedPolicies()).To(Equal(1))
+
+			Expect(e.Evaluate(ctx, "package io\nallow = true", nil)).To(BeTrue())
+			Expect(e.Evaluate(ctx, "package io\nallow = false", nil)).To(BeFalse())
+			Expect(e.CachedPolicies()).To(Equal(2))
+
+			// the least recently used policy is the one compiled again
+			Expect(e.Evaluate(ctx, "package io\nallow = true", nil)).To(BeTrue())
+			Expect(e.Evaluate(ctx, testPolicy, map[string]any{"agent": "puppet", "action": "status"})).To(BeTrue())
+			Expect(e.CachedPolicies()).To(Equal(2))
+		})
+	})
+
+	Describe("Input", func() {
+		It("Should expose the request and claims", func() {
+			ts := time.Unix(1700000000, 0)
+			input := Input(claims, &tokens.PolicyRequest{Agent: "puppet", Action: "status", Collective: "choria", Time: ts})
+			Expect(input["callerid"]).To(Equal("up=bob"))
+			Expect(input["collective"]).To(Equal("choria"))
+			Expect(input["unix_time"]).To(Equal(int64(1700000000)))
+			Expect(input["data"]).To(Equal(map[string]any{}))
+			Expect(input["filter"]).To(Equal(&tokens.PolicyFilter{}))
+		})
+	})
+})
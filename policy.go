@@ -0,0 +1,138 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// PolicyLanguage is the language a client token policy is written in
+type PolicyLanguage string
+
+const (
+	// PolicyLanguageRego is an Open Policy Agent policy stored in OPAPolicy
+	PolicyLanguageRego PolicyLanguage = "rego"
+
+	// PolicyLanguageCEL is a Common Expression Language policy stored in CELPolicy
+	PolicyLanguageCEL PolicyLanguage = "cel"
+)
+
+// ErrUnsupportedPolicyLanguage indicates no evaluator is available for the policy language used by a token
+var ErrUnsupportedPolicyLanguage = errors.New("unsupported policy language")
+
+// PolicyFilter is the discovery filter of a request evaluated by a policy
+type PolicyFilter struct {
+	// Agents are agent names the targeted nodes must have
+	Agents []string `json:"agent,omitempty"`
+
+	// Classes are configuration management classes the targeted nodes must have
+	Classes []string `json:"cf_class,omitempty"`
+
+	// Facts are fact filters like country=za
+	Facts []string `json:"fact,omitempty"`
+
+	// Identities are identities of targeted nodes
+	Identities []string `json:"identity,omitempty"`
+
+	// Compound is a compound filter expression
+	Compound string `json:"compound,omitempty"`
+}
+
+// PolicyRequest describes an action a client wishes to perform
+type PolicyRequest struct {
+	// Agent is the agent being invoked
+	Agent string
+
+	// Action is the action being invoked
+	Action string
+
+	// Data is the request data passed to the action
+	Data map[string]any
+
+	// Filter is the discovery filter of the request
+	Filter *PolicyFilter
+
+	// Collective is the collective the request targets
+	Collective string
+
+	// Time is when the request is made, defaults to now
+	Time time.Time
+}
+
+// PolicyEvaluator evaluates client token policies written in one language, see the policies/opa and policies/cel packages
+type PolicyEvaluator interface {
+	// Language is the policy language handled by the evaluator
+	Language() PolicyLanguage
+
+	// Allowed determines if the client holding claims may perform req
+	Allowed(ctx context.Context, claims *ClientIDClaims, req *PolicyRequest) (bool, error)
+}
+
+// PolicyVerifier answers authorization queries for client tokens using the evaluator matching the policy language of each token
+type PolicyVerifier struct {
+	evaluators map[PolicyLanguage]PolicyEvaluator
+}
+
+// NewPolicyVerifier creates a verifier supporting the languages of evaluators
+func NewPolicyVerifier(evaluators ...PolicyEvaluator) (*PolicyVerifier, error) {
+	v := &PolicyVerifier{evaluators: make(map[PolicyLanguage]PolicyEvaluator)}
+
+	for _, e := range evaluators {
+		if e == nil {
+			return nil, fmt.Errorf("evaluator cannot be nil")
+		}
+
+		if _, ok := v.evaluators[e.Language()]; ok {
+			return nil, fmt.Errorf("duplicate evaluator for policy language %s", e.Language())
+		}
+
+		v.evaluators[e.Language()] = e
+	}
+
+	return v, nil
+}
+
+// Allowed determines if the client holding claims may perform req, tokens without a policy are checked using AllowsAction
+func (v *PolicyVerifier) Allowed(ctx context.Context, claims *ClientIDClaims, req *PolicyRequest) (bool, error) {
+	if claims == nil {
+		return false, fmt.Errorf("claims are required")
+	}
+	if req == nil || req.Agent == "" || req.Action == "" {
+		return false, fmt.Errorf("request agent and action are required")
+	}
+
+	lang, err := claims.PolicyLanguage()
+	if err != nil {
+		return false, err
+	}
+
+	if lang == "" {
+		return claims.AllowsAction(req.Agent, req.Action), nil
+	}
+
+	e, ok := v.evaluators[lang]
+	if !ok {
+		return false, fmt.Errorf("%w: %s", ErrUnsupportedPolicyLanguage, lang)
+	}
+
+	return e.Allowed(ctx, claims, req)
+}
+
+// PolicyLanguage is the language of the policy held by the token, empty when the token has no policy
+func (c *ClientIDClaims) PolicyLanguage() (PolicyLanguage, error) {
+	switch {
+	case c.OPAPolicy != "" && c.CELPolicy != "":
+		return "", fmt.Errorf("tokens may only hold one of an opa or cel policy")
+	case c.OPAPolicy != "":
+		return PolicyLanguageRego, nil
+	case c.CELPolicy != "":
+		return PolicyLanguageCEL, nil
+	default:
+		return "", nil
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type fakePolicyEvaluator struct {
+	lang    PolicyLanguage
+	allowed bool
+	calls   int
+}
+
+func (f *fakePolicyEvaluator) Language() PolicyLanguage { return f.lang }
+
+func (f *fakePolicyEvaluator) Allowed(_ context.Context, _ *ClientIDClaims, _ *PolicyRequest) (bool, error) {
+	f.calls++
+	return f.allowed, nil
+}
+
+var _ = Describe("Policies", func() {
+	var (
+		rego *fakePolicyEvaluator
+		cel  *fakePolicyEvaluator
+		ctx  context.Context
+		req  *PolicyRequest
+	)
+
+	BeforeEach(func() {
+		rego = &fakePolicyEvaluator{lang: PolicyLanguageRego, allowed: true}
+		cel = &fakePolicyEvaluator{lang: PolicyLanguageCEL}
+		ctx = context.Background()
+		req = &PolicyRequest{Agent: "puppet", Action: "status"}
+	})
+
+	Describe("PolicyLanguage", func() {
+		It("Should detect the language", func() {
+			c := &ClientIDClaims{}
+			Expect(c.PolicyLanguage()).To(BeEmpty())
+
+			c.OPAPolicy = "package io"
+			Expect(c.PolicyLanguage()).To(Equal(PolicyLanguageRego))
+
+			c.OPAPolicy = ""
+			c.CELPolicy = "true"
+			Expect(c.PolicyLanguage()).To(Equal(PolicyLanguageCEL))
+
+			c.OPAPolicy = "package io"
+			_, err := c.PolicyLanguage()
+			Expect(err).To(MatchError("tokens may only hold one of an opa or cel policy"))
+			Expect(c.Validate()).To(MatchError("tokens may only hold one of an opa or cel policy"))
+		})
+	})
+
+	Describe("NewPolicyVerifier", func() {
+		It("Should reject duplicate languages", func() {
+			_, err := NewPolicyVerifier(rego, &fakePolicyEvaluator{lang: PolicyLanguageRego})
+			Expect(err).To(MatchError("duplicate evaluator for policy language rego"))
+
+			_, err = NewPolicyVerifier(nil)
+			Expect(err).To(MatchError("evaluator cannot be nil"))
+		})
+	})
+
+	Describe("Allowed", func() {
+		It("Should dispatch to the evaluator for the policy language", func() {
+			v, err := NewPolicyVerifier(rego, cel)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(v.Allowed(ctx, &ClientIDClaims{OPAPolicy: "package io"}, req)).To(BeTrue())
+			Expect(v.Allowed(ctx, &ClientIDClaims{CELPolicy: "false"}, req)).To(BeFalse())
+			Expect(rego.calls).To(Equal(1))
+			Expect(cel.calls).To(Equal(1))
+		})
+
+		It("Should use allowed agents for tokens without policies", func() {
+			v, err := NewPolicyVerifier()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(v.Allowed(ctx, &ClientIDClaims{AllowedAgents: []string{"puppet"}}, req)).To(BeTrue())
+			Expect(v.Allowed(ctx, &ClientIDClaims{AllowedAgents: []string{"rpcutil"}}, req)).To(BeFalse())
+
+			acl := []ActionGrant{{Agent: "rpcutil"}}
+			Expect(v.Allowed(ctx, &ClientIDClaims{AllowedAgents: []string{"puppet"}, ACL: acl}, req)).To(BeFalse())
+			acl = []ActionGrant{{Agent: "pup*"}}
+			Expect(v.Allowed(ctx, &ClientIDClaims{ACL: acl}, req)).To(BeTrue())
+		})
+
+		It("Should fail for unsupported languages and invalid requests", func() {
+			v, err := NewPolicyVerifier(rego)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = v.Allowed(ctx, &ClientIDClaims{CELPolicy: "true"}, req)
+			Expect(err).To(MatchError(ErrUnsupportedPolicyLanguage))
+
+			_, err = v.Allowed(ctx, nil, req)
+			Expect(err).To(MatchError("claims are required"))
+
+			_, err = v.Allowed(ctx, &ClientIDClaims{}, &PolicyRequest{Agent: "puppet"})
+			Expect(err).To(MatchError("request agent and action are required"))
+		})
+	})
+})
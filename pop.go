@@ -0,0 +1,289 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// ErrProofOfPossessionFailed indicates a token holder could not prove it holds the private key matching the token
+var ErrProofOfPossessionFailed = errors.New("proof of possession failed")
+
+// challengeNonceSize is the number of random bytes in a challenge
+const challengeNonceSize = 32
+
+// Challenge is a random value a key holder signs to prove it holds the private key of a token or of a key it
+// wants to be issued a token for. Every challenge can be answered only once
+type Challenge struct {
+	// Nonce is the random value to sign
+	Nonce []byte `json:"nonce"`
+
+	// Identity when set binds the answer to the identity of a server or caller id of a client
+	Identity string `json:"identity,omitempty"`
+
+	// ExpiresAt is when the challenge can no longer be answered
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ChallengeResponse is the answer to a challenge made by a key holder that does not yet have a token, for example
+// a server being provisioned
+type ChallengeResponse struct {
+	// PublicKey is the hex encoded ed25519 public key that answered the challenge
+	PublicKey string `json:"public_key"`
+
+	// Signature is the hex encoded signature made using the private key matching PublicKey
+	Signature string `json:"signature"`
+}
+
+// NewChallenge creates a challenge that can be answered within validity
+func NewChallenge(validity time.Duration) (*Challenge, error) {
+	if validity <= 0 {
+		return nil, fmt.Errorf("validity is required")
+	}
+
+	nonce := make([]byte, challengeNonceSize)
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Challenge{Nonce: nonce, ExpiresAt: currentTime().Add(validity).UTC()}, nil
+}
+
+// NewIdentityChallenge creates a challenge that can be answered within validity for identity only
+func NewIdentityChallenge(identity string, validity time.Duration) (*Challenge, error) {
+	if identity == "" {
+		return nil, fmt.Errorf("identity is required")
+	}
+
+	c, err := NewChallenge(validity)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Identity = identity
+
+	return c, nil
+}
+
+// message is the message signed to answer the challenge, it binds the answer to a specific token when tokenID
+// is set and to the identity of the challenge
+func (c *Challenge) message(tokenID string) []byte {
+	return []byte(fmt.Sprintf("choria_pop:%q:%q:%s", tokenID, c.Identity, hex.EncodeToString(c.Nonce)))
+}
+
+// check ensures the challenge can still be answered
+func (c *Challenge) check() error {
+	if c == nil || len(c.Nonce) == 0 {
+		return fmt.Errorf("%w: challenge is required", ErrProofOfPossessionFailed)
+	}
+
+	if currentTime().After(c.ExpiresAt) {
+		return fmt.Errorf("%w: challenge has expired", ErrProofOfPossessionFailed)
+	}
+
+	return nil
+}
+
+// use records the use of the challenge in guard so it can not be answered again
+func (c *Challenge) use(ctx context.Context, guard *ReplayGuard) error {
+	err := guard.UseNonce(ctx, "pop:"+hex.EncodeToString(c.Nonce), c.ExpiresAt)
+	if errors.Is(err, ErrNonceReplayed) {
+		return fmt.Errorf("%w: %w", ErrProofOfPossessionFailed, err)
+	}
+
+	return err
+}
+
+// AnswerChallenge signs challenge for token using signer, the ed25519 private key matching the public key in the token
+func AnswerChallenge(challenge *Challenge, token string, signer crypto.Signer) ([]byte, error) {
+	if challenge == nil || len(challenge.Nonce) == 0 {
+		return nil, fmt.Errorf("challenge is required")
+	}
+
+	claims, err := ParseTokenUnverified(token)
+	if err != nil {
+		return nil, err
+	}
+
+	jti, _ := claims["jti"].(string)
+	sig, _, err := ed25519SignWithSigner(signer, challenge.message(jti))
+	if err != nil {
+		return nil, err
+	}
+
+	return sig, nil
+}
+
+// AnswerKeyChallenge signs challenge using signer, the ed25519 key the holder wants to be issued a token for
+func AnswerKeyChallenge(challenge *Challenge, signer crypto.Signer) (*ChallengeResponse, error) {
+	if challenge == nil || len(challenge.Nonce) == 0 {
+		return nil, fmt.Errorf("challenge is required")
+	}
+
+	sig, pk, err := ed25519SignWithSigner(signer, challenge.message(""))
+	if err != nil {
+		return nil, err
+	}
+
+	return &ChallengeResponse{PublicKey: hex.EncodeToString(pk), Signature: hex.EncodeToString(sig)}, nil
+}
+
+// VerifyProofOfPossession verifies token into claims using pk and that sig answers challenge using the private key
+// matching the public key embedded in the token. Challenges with an identity must be answered using a token for
+// that identity, guard records the challenge so it is accepted only once
+func VerifyProofOfPossession(token string, claims jwt.Claims, pk any, challenge *Challenge, sig []byte, guard *ReplayGuard, opts ...ParseOption) error {
+	if guard == nil {
+		return fmt.Errorf("replay guard is required")
+	}
+
+	err := challenge.check()
+	if err != nil {
+		return err
+	}
+
+	popts, err := newParseOptions(opts...)
+	if err != nil {
+		return err
+	}
+
+	err = ParseTokenContext(popts.context(), token, claims, pk, opts...)
+	if err != nil {
+		return err
+	}
+
+	sc, ok := claims.(standardClaimsProvider)
+	if !ok {
+		return fmt.Errorf("unsupported claims %T", claims)
+	}
+	std := sc.getStandardClaims()
+
+	if challenge.Identity != "" && holderIdentity(claims) != challenge.Identity {
+		return fmt.Errorf("%w: token is not for %s", ErrProofOfPossessionFailed, challenge.Identity)
+	}
+
+	holder := claimsPublicKey(std)
+	if holder == nil {
+		return fmt.Errorf("%w: token does not hold a public key", ErrProofOfPossessionFailed)
+	}
+
+	valid, err := ed25519Verify(holder, challenge.message(std.ID), sig)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrProofOfPossessionFailed, err)
+	}
+	if !valid {
+		return fmt.Errorf("%w: invalid signature", ErrProofOfPossessionFailed)
+	}
+
+	return challenge.use(popts.context(), guard)
+}
+
+// VerifyKeyChallenge verifies that response answers challenge before it expired and returns the public key that
+// answered it, the token issuer can then issue a token for this key and the identity of the challenge. Guard
+// records the challenge so it is accepted only once
+func VerifyKeyChallenge(ctx context.Context, challenge *Challenge, response *ChallengeResponse, guard *ReplayGuard) (ed25519.PublicKey, error) {
+	if guard == nil {
+		return nil, fmt.Errorf("replay guard is required")
+	}
+
+	pk, err := verifyKeyChallenge(challenge, response)
+	if err != nil {
+		return nil, err
+	}
+
+	err = challenge.use(ctx, guard)
+	if err != nil {
+		return nil, err
+	}
+
+	return pk, nil
+}
+
+// VerifyKeyChallengeForClaims verifies response like VerifyKeyChallenge and that it was made using the private key
+// matching the public key embedded in claims, claims must already be verified
+func VerifyKeyChallengeForClaims(ctx context.Context, challenge *Challenge, response *ChallengeResponse, claims jwt.Claims, guard *ReplayGuard) error {
+	if guard == nil {
+		return fmt.Errorf("replay guard is required")
+	}
+
+	sc, ok := claims.(standardClaimsProvider)
+	if !ok {
+		return fmt.Errorf("unsupported claims %T", claims)
+	}
+
+	holder := claimsPublicKey(sc.getStandardClaims())
+	if holder == nil {
+		return fmt.Errorf("%w: token does not hold a public key", ErrProofOfPossessionFailed)
+	}
+
+	pk, err := verifyKeyChallenge(challenge, response)
+	if err != nil {
+		return err
+	}
+
+	if challenge.Identity != "" && holderIdentity(claims) != challenge.Identity {
+		return fmt.Errorf("%w: token is not for %s", ErrProofOfPossessionFailed, challenge.Identity)
+	}
+
+	if subtle.ConstantTimeCompare(pk, holder) != 1 {
+		return fmt.Errorf("%w: public key does not match the token", ErrProofOfPossessionFailed)
+	}
+
+	return challenge.use(ctx, guard)
+}
+
+// verifyKeyChallenge verifies the signature in response without recording the use of challenge
+func verifyKeyChallenge(challenge *Challenge, response *ChallengeResponse) (ed25519.PublicKey, error) {
+	err := challenge.check()
+	if err != nil {
+		return nil, err
+	}
+
+	if response == nil {
+		return nil, fmt.Errorf("%w: response is required", ErrProofOfPossessionFailed)
+	}
+
+	pk, err := hex.DecodeString(response.PublicKey)
+	if err != nil || len(pk) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("%w: invalid public key", ErrProofOfPossessionFailed)
+	}
+
+	sig, err := hex.DecodeString(response.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid signature", ErrProofOfPossessionFailed)
+	}
+
+	valid, err := ed25519Verify(pk, challenge.message(""), sig)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrProofOfPossessionFailed, err)
+	}
+	if !valid {
+		return nil, fmt.Errorf("%w: invalid signature", ErrProofOfPossessionFailed)
+	}
+
+	return pk, nil
+}
+
+// holderIdentity is the server identity or client caller id of claims, empty for other tokens
+func holderIdentity(claims jwt.Claims) string {
+	switch c := claims.(type) {
+	case *ServerClaims:
+		return c.ChoriaIdentity
+	case *ClientIDClaims:
+		return c.CallerID
+	default:
+		return ""
+	}
+}
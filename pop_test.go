@@ -0,0 +1,216 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ProofOfPossession", func() {
+	var (
+		issuerPubK ed25519.PublicKey
+		issuerPriK ed25519.PrivateKey
+		holderPubK ed25519.PublicKey
+		holderPriK ed25519.PrivateKey
+		token      string
+		guard      *ReplayGuard
+		ctx        context.Context
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		issuerPubK, issuerPriK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		holderPubK, holderPriK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		client, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, holderPubK)
+		Expect(err).ToNot(HaveOccurred())
+		token, err = SignToken(client, issuerPriK)
+		Expect(err).ToNot(HaveOccurred())
+
+		guard, err = NewReplayGuard(nil, time.Hour)
+		Expect(err).ToNot(HaveOccurred())
+		ctx = context.Background()
+
+		DeferCleanup(func() { SetClock(nil) })
+	})
+
+	It("Should create challenges", func() {
+		_, err := NewChallenge(0)
+		Expect(err).To(MatchError("validity is required"))
+
+		c1, err := NewChallenge(time.Minute)
+		Expect(err).ToNot(HaveOccurred())
+		c2, err := NewChallenge(time.Minute)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c1.Nonce).To(HaveLen(32))
+		Expect(c1.Nonce).ToNot(Equal(c2.Nonce))
+		Expect(c1.Identity).To(BeEmpty())
+
+		_, err = NewIdentityChallenge("", time.Minute)
+		Expect(err).To(MatchError("identity is required"))
+		_, err = NewIdentityChallenge("n1.example.net", 0)
+		Expect(err).To(MatchError("validity is required"))
+
+		c1, err = NewIdentityChallenge("n1.example.net", time.Minute)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c1.Identity).To(Equal("n1.example.net"))
+		Expect(c1.ExpiresAt).To(BeTemporally("~", time.Now().Add(time.Minute), time.Second))
+	})
+
+	It("Should verify the holder", func() {
+		challenge, err := NewChallenge(time.Minute)
+		Expect(err).ToNot(HaveOccurred())
+
+		sig, err := AnswerChallenge(challenge, token, holderPriK)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(VerifyProofOfPossession(token, &ClientIDClaims{}, issuerPubK, challenge, sig, nil)).To(MatchError("replay guard is required"))
+
+		claims := &ClientIDClaims{}
+		Expect(VerifyProofOfPossession(token, claims, issuerPubK, challenge, sig, guard)).To(Succeed())
+		Expect(claims.CallerID).To(Equal("up=bob"))
+
+		challenge, err = NewChallenge(time.Minute)
+		Expect(err).ToNot(HaveOccurred())
+		sig, err = AnswerChallenge(challenge, token, issuerPriK)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(VerifyProofOfPossession(token, &ClientIDClaims{}, issuerPubK, challenge, sig, guard)).To(MatchError(ErrProofOfPossessionFailed))
+	})
+
+	It("Should accept every challenge only once", func() {
+		challenge, err := NewChallenge(time.Minute)
+		Expect(err).ToNot(HaveOccurred())
+		sig, err := AnswerChallenge(challenge, token, holderPriK)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(VerifyProofOfPossession(token, &ClientIDClaims{}, issuerPubK, challenge, sig, guard)).To(Succeed())
+		err = VerifyProofOfPossession(token, &ClientIDClaims{}, issuerPubK, challenge, sig, guard)
+		Expect(err).To(MatchError(ErrProofOfPossessionFailed))
+		Expect(err).To(MatchError(ErrNonceReplayed))
+
+		challenge, err = NewChallenge(time.Minute)
+		Expect(err).ToNot(HaveOccurred())
+		response, err := AnswerKeyChallenge(challenge, holderPriK)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = VerifyKeyChallenge(ctx, challenge, response, guard)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = VerifyKeyChallenge(ctx, challenge, response, guard)
+		Expect(err).To(MatchError(ErrNonceReplayed))
+	})
+
+	It("Should bind answers to the identity of the challenge", func() {
+		challenge, err := NewIdentityChallenge("up=bob", time.Minute)
+		Expect(err).ToNot(HaveOccurred())
+		sig, err := AnswerChallenge(challenge, token, holderPriK)
+		Expect(err).ToNot(HaveOccurred())
+
+		other := *challenge
+		other.Identity = "up=alice"
+		Expect(VerifyProofOfPossession(token, &ClientIDClaims{}, issuerPubK, &other, sig, guard)).To(MatchError("proof of possession failed: token is not for up=alice"))
+
+		Expect(VerifyProofOfPossession(token, &ClientIDClaims{}, issuerPubK, challenge, sig, guard)).To(Succeed())
+	})
+
+	It("Should reject expired challenges and unverified tokens", func() {
+		challenge, err := NewChallenge(time.Minute)
+		Expect(err).ToNot(HaveOccurred())
+		sig, err := AnswerChallenge(challenge, token, holderPriK)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(VerifyProofOfPossession(token, &ClientIDClaims{}, holderPriK.Public(), challenge, sig, guard)).To(HaveOccurred())
+
+		challenge.ExpiresAt = time.Now().Add(-time.Second)
+		Expect(VerifyProofOfPossession(token, &ClientIDClaims{}, issuerPubK, challenge, sig, guard)).To(MatchError(ContainSubstring("challenge has expired")))
+	})
+
+	It("Should require tokens holding a public key", func() {
+		client, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		token, err = SignToken(client, issuerPriK)
+		Expect(err).ToNot(HaveOccurred())
+
+		challenge, err := NewChallenge(time.Minute)
+		Expect(err).ToNot(HaveOccurred())
+		sig, err := AnswerChallenge(challenge, token, holderPriK)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(VerifyProofOfPossession(token, &ClientIDClaims{}, issuerPubK, challenge, sig, guard)).To(MatchError(ContainSubstring("token does not hold a public key")))
+	})
+
+	Describe("Key challenges", func() {
+		var challenge *Challenge
+
+		BeforeEach(func() {
+			var err error
+			challenge, err = NewIdentityChallenge("n1.example.net", time.Minute)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("Should answer and verify challenges", func() {
+			response, err := AnswerKeyChallenge(challenge, holderPriK)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = VerifyKeyChallenge(ctx, challenge, response, nil)
+			Expect(err).To(MatchError("replay guard is required"))
+
+			pk, err := VerifyKeyChallenge(ctx, challenge, response, guard)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pk).To(Equal(holderPubK))
+		})
+
+		It("Should detect invalid responses", func() {
+			response, err := AnswerKeyChallenge(challenge, holderPriK)
+			Expect(err).ToNot(HaveOccurred())
+
+			other, err := NewIdentityChallenge("n1.example.net", time.Minute)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = VerifyKeyChallenge(ctx, other, response, guard)
+			Expect(err).To(MatchError("proof of possession failed: invalid signature"))
+
+			other.Nonce = challenge.Nonce
+			other.Identity = "n2.example.net"
+			_, err = VerifyKeyChallenge(ctx, other, response, guard)
+			Expect(err).To(MatchError("proof of possession failed: invalid signature"))
+
+			SetClock(FixedClock(challenge.ExpiresAt.Add(time.Second)))
+			_, err = VerifyKeyChallenge(ctx, challenge, response, guard)
+			Expect(err).To(MatchError("proof of possession failed: challenge has expired"))
+			SetClock(nil)
+
+			response.PublicKey = "invalid"
+			_, err = VerifyKeyChallenge(ctx, challenge, response, guard)
+			Expect(err).To(MatchError("proof of possession failed: invalid public key"))
+
+			_, err = VerifyKeyChallenge(ctx, challenge, nil, guard)
+			Expect(err).To(MatchError(ErrProofOfPossessionFailed))
+		})
+
+		It("Should bind responses to tokens", func() {
+			response, err := AnswerKeyChallenge(challenge, holderPriK)
+			Expect(err).ToNot(HaveOccurred())
+
+			claims, err := NewServerClaims("n2.example.net", []string{"choria"}, "", nil, nil, holderPubK, "", time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(VerifyKeyChallengeForClaims(ctx, challenge, response, claims, guard)).To(MatchError("proof of possession failed: token is not for n1.example.net"))
+
+			claims, err = NewServerClaims("n1.example.net", []string{"choria"}, "", nil, nil, issuerPubK, "", time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(VerifyKeyChallengeForClaims(ctx, challenge, response, claims, guard)).To(MatchError("proof of possession failed: public key does not match the token"))
+
+			claims, err = NewServerClaims("n1.example.net", []string{"choria"}, "", nil, nil, holderPubK, "", time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(VerifyKeyChallengeForClaims(ctx, challenge, response, claims, guard)).To(Succeed())
+		})
+	})
+})
@@ -5,6 +5,7 @@
 package tokens
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"strings"
@@ -14,25 +15,26 @@ import (
 )
 
 type ProvisioningClaims struct {
-	Token            string    `json:"cht"`
-	Secure           bool      `json:"chs"`
-	URLs             string    `json:"chu,omitempty"`
-	SRVDomain        string    `json:"chsrv,omitempty"`
-	ProvDefault      bool      `json:"chpd"`
-	ProvRegData      string    `json:"chrd,omitempty"`
-	ProvFacts        string    `json:"chf,omitempty"`
-	ProvNatsUser     string    `json:"chusr,omitempty"`
-	ProvNatsPass     string    `json:"chpwd,omitempty"`
-	Extensions       MapClaims `json:"extensions"`
-	OrganizationUnit string    `json:"ou,omitempty"`
-	ProtoV2          bool      `json:"v2,omitempty"`
-	AllowUpdate      bool      `json:"update,omitempty"`
+	Token            string               `json:"cht"`
+	Secure           bool                 `json:"chs"`
+	URLs             string               `json:"chu,omitempty"`
+	Brokers          []ProvisioningBroker `json:"chb,omitempty"`
+	SRVDomain        string               `json:"chsrv,omitempty"`
+	ProvDefault      bool                 `json:"chpd"`
+	ProvRegData      string               `json:"chrd,omitempty"`
+	ProvFacts        string               `json:"chf,omitempty"`
+	ProvNatsUser     string               `json:"chusr,omitempty"`
+	ProvNatsPass     string               `json:"chpwd,omitempty"`
+	Extensions       MapClaims            `json:"extensions"`
+	OrganizationUnit string               `json:"ou,omitempty"`
+	ProtoV2          bool                 `json:"v2,omitempty"`
+	AllowUpdate      bool                 `json:"update,omitempty"`
 
 	StandardClaims
 }
 
 // NewProvisioningClaims generates new ProvisioningClaims
-func NewProvisioningClaims(secure bool, byDefault bool, token string, user string, password string, urls []string, srvDomain string, registrationDataFile string, factsDataFile string, org string, issuer string, validity time.Duration) (*ProvisioningClaims, error) {
+func NewProvisioningClaims(secure bool, byDefault bool, token string, user string, password string, urls []string, srvDomain string, registrationDataFile string, factsDataFile string, org string, issuer string, validity time.Duration, opts ...ClaimsOption) (*ProvisioningClaims, error) {
 	if org == "" {
 		org = defaultOrg
 	}
@@ -41,7 +43,7 @@ func NewProvisioningClaims(secure bool, byDefault bool, token string, user strin
 		return nil, fmt.Errorf("srv domain or urls required")
 	}
 
-	stdClaims, err := newStandardClaims(issuer, ProvisioningPurpose, validity, true)
+	stdClaims, err := newStandardClaims(issuer, ProvisioningPurpose, validity, true, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -61,6 +63,58 @@ func NewProvisioningClaims(secure bool, byDefault bool, token string, user strin
 	}, nil
 }
 
+// SetExtension stores value under key in the extension data, value must be JSON serializable and is signed with
+// the rest of the claims so provisioning targets can trust site specific bootstrap data
+func (c *ProvisioningClaims) SetExtension(key string, value any) error {
+	if key == "" {
+		return fmt.Errorf("extension key is required")
+	}
+
+	_, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("invalid value for extension %s: %w", key, err)
+	}
+
+	if c.Extensions == nil {
+		c.Extensions = MapClaims{}
+	}
+	c.Extensions[key] = value
+
+	return nil
+}
+
+// Extension retrieves the extension value stored under key
+func (c *ProvisioningClaims) Extension(key string) (any, bool) {
+	v, ok := c.Extensions[key]
+	return v, ok
+}
+
+// ExtensionString retrieves the extension value stored under key when it is a string
+func (c *ProvisioningClaims) ExtensionString(key string) (string, bool) {
+	v, ok := c.Extensions[key].(string)
+	return v, ok
+}
+
+// DecodeExtension decodes the extension value stored under key into target, typically a pointer to a struct
+func (c *ProvisioningClaims) DecodeExtension(key string, target any) error {
+	v, ok := c.Extensions[key]
+	if !ok {
+		return fmt.Errorf("extension %s not found", key)
+	}
+
+	j, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(j, target)
+	if err != nil {
+		return fmt.Errorf("could not decode extension %s: %w", key, err)
+	}
+
+	return nil
+}
+
 // IsProvisioningToken determines if this is a provisioning token
 func IsProvisioningToken(claims StandardClaims) bool {
 	if claims.Subject == string(ProvisioningPurpose) {
@@ -71,9 +125,9 @@ func IsProvisioningToken(claims StandardClaims) bool {
 }
 
 // ParseProvisioningToken parses token and verifies it with pk
-func ParseProvisioningToken(token string, pk any) (*ProvisioningClaims, error) {
+func ParseProvisioningToken(token string, pk any, opts ...ParseOption) (*ProvisioningClaims, error) {
 	claims := &ProvisioningClaims{}
-	err := ParseToken(token, claims, pk)
+	err := ParseToken(token, claims, pk, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("could not parse provisioner token: %s", err)
 	}
@@ -86,6 +140,11 @@ func ParseProvisioningToken(token string, pk any) (*ProvisioningClaims, error) {
 		claims.OrganizationUnit = defaultOrg
 	}
 
+	err = validateProvisioningBrokers(claims.Brokers)
+	if err != nil {
+		return nil, err
+	}
+
 	// if we have a tcs we require an issuer expiry to be set and it to not have expired
 	if !claims.verifyIssuerExpiry(claims.TrustChainSignature != "") {
 		return nil, jwt.ErrTokenExpired
@@ -95,7 +154,7 @@ func ParseProvisioningToken(token string, pk any) (*ProvisioningClaims, error) {
 }
 
 // ParseProvisioningTokenWithKeyfile parses token and verifies it with the RSA Public key in pkFile, does not support ed25519
-func ParseProvisioningTokenWithKeyfile(token string, pkFile string) (*ProvisioningClaims, error) {
+func ParseProvisioningTokenWithKeyfile(token string, pkFile string, opts ...ParseOption) (*ProvisioningClaims, error) {
 	if pkFile == "" {
 		return nil, fmt.Errorf("invalid public key file")
 	}
@@ -110,7 +169,7 @@ func ParseProvisioningTokenWithKeyfile(token string, pkFile string) (*Provisioni
 		return nil, err
 	}
 
-	return ParseProvisioningToken(token, pk)
+	return ParseProvisioningToken(token, pk, opts...)
 }
 
 // ParseProvisionTokenUnverified parses the provisioning token in an unverified manner.
@@ -121,7 +180,7 @@ func ParseProvisioningTokenWithKeyfile(token string, pkFile string) (*Provisioni
 // intended purpose of this token and function.
 func ParseProvisionTokenUnverified(token string) (*ProvisioningClaims, error) {
 	claims := &ProvisioningClaims{}
-	_, _, err := new(jwt.Parser).ParseUnverified(token, claims)
+	_, err := parseUnverified(token, claims)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,136 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ProvisioningBroker is a broker provisioning targets connect to, brokers are tried in order of Priority
+type ProvisioningBroker struct {
+	// URL is the broker URL like nats://prov1.example.net:4222
+	URL string `json:"url"`
+
+	// Priority orders the brokers, lower values are tried first and equal values keep their order in the token
+	Priority int `json:"priority,omitempty"`
+
+	// TLS holds optional hints on how to connect securely to this broker
+	TLS *ProvisioningBrokerTLS `json:"tls,omitempty"`
+}
+
+// ProvisioningBrokerTLS holds TLS hints for a single broker
+type ProvisioningBrokerTLS struct {
+	// ServerName is the name to verify the broker certificate against when it differs from the URL host
+	ServerName string `json:"server_name,omitempty"`
+
+	// CASHA256 is the hex encoded SHA256 fingerprint of the CA certificate the broker certificate is signed by
+	CASHA256 string `json:"ca_sha256,omitempty"`
+
+	// Insecure indicates the broker does not use TLS, overriding Secure
+	Insecure bool `json:"insecure,omitempty"`
+}
+
+// NewProvisioningClaimsWithBrokers generates new ProvisioningClaims using an ordered list of brokers rather than a
+// list of URLs, URLs is set to the broker URLs in priority order for provisioning targets that do not support brokers
+func NewProvisioningClaimsWithBrokers(secure bool, byDefault bool, token string, user string, password string, brokers []ProvisioningBroker, registrationDataFile string, factsDataFile string, org string, issuer string, validity time.Duration, opts ...ClaimsOption) (*ProvisioningClaims, error) {
+	if len(brokers) == 0 {
+		return nil, fmt.Errorf("at least one broker is required")
+	}
+
+	claims, err := NewProvisioningClaims(secure, byDefault, token, user, password, []string{brokers[0].URL}, "", registrationDataFile, factsDataFile, org, issuer, validity, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	err = claims.SetBrokers(brokers...)
+	if err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// SetBrokers validates and stores brokers, URLs is updated to the broker URLs in priority order
+func (c *ProvisioningClaims) SetBrokers(brokers ...ProvisioningBroker) error {
+	err := validateProvisioningBrokers(brokers)
+	if err != nil {
+		return err
+	}
+
+	c.Brokers = brokers
+	c.URLs = strings.Join(c.OrderedBrokerURLs(), ",")
+
+	return nil
+}
+
+// OrderedBrokers returns the brokers in the order they should be tried, tokens without brokers list their URLs
+func (c *ProvisioningClaims) OrderedBrokers() []ProvisioningBroker {
+	if len(c.Brokers) == 0 {
+		var res []ProvisioningBroker
+		for _, u := range strings.Split(c.URLs, ",") {
+			u = strings.TrimSpace(u)
+			if u != "" {
+				res = append(res, ProvisioningBroker{URL: u})
+			}
+		}
+
+		return res
+	}
+
+	res := make([]ProvisioningBroker, len(c.Brokers))
+	copy(res, c.Brokers)
+	sort.SliceStable(res, func(i, j int) bool { return res[i].Priority < res[j].Priority })
+
+	return res
+}
+
+// OrderedBrokerURLs returns the URLs of the brokers in the order they should be tried
+func (c *ProvisioningClaims) OrderedBrokerURLs() []string {
+	var res []string
+	for _, b := range c.OrderedBrokers() {
+		res = append(res, b.URL)
+	}
+
+	return res
+}
+
+// IsSecure determines if the connection to b should use TLS given the Secure setting of the token
+func (b ProvisioningBroker) IsSecure(secure bool) bool {
+	if b.TLS != nil && b.TLS.Insecure {
+		return false
+	}
+
+	return secure
+}
+
+func validateProvisioningBrokers(brokers []ProvisioningBroker) error {
+	seen := map[string]bool{}
+
+	for i, b := range brokers {
+		u, err := url.Parse(b.URL)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("broker %d: invalid url %q", i, b.URL)
+		}
+
+		if seen[b.URL] {
+			return fmt.Errorf("broker %d: duplicate url %s", i, b.URL)
+		}
+		seen[b.URL] = true
+
+		if b.TLS != nil && b.TLS.CASHA256 != "" {
+			fp, err := hex.DecodeString(b.TLS.CASHA256)
+			if err != nil || len(fp) != 32 {
+				return fmt.Errorf("broker %d: invalid CA fingerprint", i)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Provisioning brokers", func() {
+	brokers := func() []ProvisioningBroker {
+		return []ProvisioningBroker{
+			{URL: "nats://prov3.example.net:4222", Priority: 20, TLS: &ProvisioningBrokerTLS{Insecure: true}},
+			{URL: "nats://prov1.example.net:4222", Priority: 10, TLS: &ProvisioningBrokerTLS{ServerName: "prov.example.net", CASHA256: strings.Repeat("ab", 32)}},
+			{URL: "nats://prov2.example.net:4222", Priority: 10},
+		}
+	}
+
+	It("Should create and parse tokens with brokers in priority order", func() {
+		claims, err := NewProvisioningClaimsWithBrokers(true, true, "x", "", "", brokers(), "", "", "", "Ginkgo", time.Hour)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(claims.URLs).To(Equal("nats://prov1.example.net:4222,nats://prov2.example.net:4222,nats://prov3.example.net:4222"))
+
+		token, err := SignToken(claims, loadRSAPriKey("testdata/rsa/signer-key.pem"))
+		Expect(err).ToNot(HaveOccurred())
+
+		t, err := ParseProvisioningToken(token, loadRSAPubKey("testdata/rsa/signer-public.pem"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(t.OrderedBrokerURLs()).To(Equal([]string{"nats://prov1.example.net:4222", "nats://prov2.example.net:4222", "nats://prov3.example.net:4222"}))
+
+		ordered := t.OrderedBrokers()
+		Expect(ordered[0].TLS.ServerName).To(Equal("prov.example.net"))
+		Expect(ordered[0].IsSecure(t.Secure)).To(BeTrue())
+		Expect(ordered[1].IsSecure(t.Secure)).To(BeTrue())
+		Expect(ordered[2].IsSecure(t.Secure)).To(BeFalse())
+		Expect(t.Brokers[0].URL).To(Equal("nats://prov3.example.net:4222"))
+	})
+
+	It("Should list URLs for tokens without brokers", func() {
+		claims, err := NewProvisioningClaims(true, true, "x", "", "", []string{"nats://b1:4222", " nats://b2:4222"}, "", "", "", "", "Ginkgo", time.Hour)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(claims.OrderedBrokers()).To(Equal([]ProvisioningBroker{{URL: "nats://b1:4222"}, {URL: "nats://b2:4222"}}))
+
+		claims.URLs = ""
+		Expect(claims.OrderedBrokerURLs()).To(BeEmpty())
+	})
+
+	It("Should validate brokers", func() {
+		_, err := NewProvisioningClaimsWithBrokers(true, true, "x", "", "", nil, "", "", "", "Ginkgo", time.Hour)
+		Expect(err).To(MatchError("at least one broker is required"))
+
+		claims := &ProvisioningClaims{}
+		Expect(claims.SetBrokers(ProvisioningBroker{URL: "prov1:4222"})).To(MatchError(`broker 0: invalid url "prov1:4222"`))
+		Expect(claims.SetBrokers(ProvisioningBroker{URL: "nats://p:4222"}, ProvisioningBroker{URL: "nats://p:4222"})).To(MatchError("broker 1: duplicate url nats://p:4222"))
+		Expect(claims.SetBrokers(ProvisioningBroker{URL: "nats://p:4222", TLS: &ProvisioningBrokerTLS{CASHA256: "abc"}})).To(MatchError("broker 0: invalid CA fingerprint"))
+
+		claims, err = NewProvisioningClaims(true, true, "x", "", "", []string{"nats://b1:4222"}, "", "", "", "", "Ginkgo", time.Hour)
+		Expect(err).ToNot(HaveOccurred())
+		claims.Brokers = []ProvisioningBroker{{URL: "invalid"}}
+		token, err := SignToken(claims, loadRSAPriKey("testdata/rsa/signer-key.pem"))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = ParseProvisioningToken(token, loadRSAPubKey("testdata/rsa/signer-public.pem"))
+		Expect(err).To(MatchError(`broker 0: invalid url "invalid"`))
+	})
+})
@@ -111,6 +111,69 @@ var _ = Describe("ProvisioningClaims", func() {
 			Expect(t.Secure).To(BeTrue())
 			Expect(t.Extensions).To(Equal(MapClaims{"hello": "world"}))
 		})
+
+		It("Should support custom claims", func() {
+			pclaims, err := NewProvisioningClaims(true, true, "x", "usr", "toomanysecrets", []string{"nats://example.net:4222"}, "", "", "", "", "Ginkgo", time.Hour, WithCustomClaims(map[string]any{"site": "lon1", "rack": 10}))
+			Expect(err).ToNot(HaveOccurred())
+			token, err := SignToken(pclaims, loadRSAPriKey("testdata/rsa/signer-key.pem"))
+			Expect(err).ToNot(HaveOccurred())
+
+			t, err := ParseProvisioningToken(token, loadRSAPubKey("testdata/rsa/signer-public.pem"))
+			Expect(err).ToNot(HaveOccurred())
+			site, ok := t.CustomClaimString("site")
+			Expect(ok).To(BeTrue())
+			Expect(site).To(Equal("lon1"))
+			rack, ok := t.CustomClaim("rack")
+			Expect(ok).To(BeTrue())
+			Expect(rack).To(Equal(float64(10)))
+			_, ok = t.CustomClaim("missing")
+			Expect(ok).To(BeFalse())
+		})
+
+		It("Should support extension data", func() {
+			pclaims, err := NewProvisioningClaims(true, true, "x", "usr", "toomanysecrets", []string{"nats://example.net:4222"}, "", "", "", "", "Ginkgo", time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pclaims.SetExtension("site", "lon1")).To(Succeed())
+			Expect(pclaims.SetExtension("hardware", map[string]any{"class": "edge", "cores": 4})).To(Succeed())
+			Expect(pclaims.SetExtension("", "x")).To(MatchError("extension key is required"))
+			Expect(pclaims.SetExtension("invalid", func() {})).To(MatchError(ContainSubstring("invalid value for extension invalid")))
+
+			token, err := SignToken(pclaims, loadRSAPriKey("testdata/rsa/signer-key.pem"))
+			Expect(err).ToNot(HaveOccurred())
+
+			t, err := ParseProvisioningToken(token, loadRSAPubKey("testdata/rsa/signer-public.pem"))
+			Expect(err).ToNot(HaveOccurred())
+			site, ok := t.ExtensionString("site")
+			Expect(ok).To(BeTrue())
+			Expect(site).To(Equal("lon1"))
+			_, ok = t.ExtensionString("hardware")
+			Expect(ok).To(BeFalse())
+			_, ok = t.Extension("missing")
+			Expect(ok).To(BeFalse())
+
+			var hw struct {
+				Class string `json:"class"`
+				Cores int    `json:"cores"`
+			}
+			Expect(t.DecodeExtension("hardware", &hw)).To(Succeed())
+			Expect(hw.Class).To(Equal("edge"))
+			Expect(hw.Cores).To(Equal(4))
+			Expect(t.DecodeExtension("missing", &hw)).To(MatchError("extension missing not found"))
+			Expect(t.DecodeExtension("site", &hw)).To(MatchError(ContainSubstring("could not decode extension site")))
+		})
+
+		It("Should enforce the audience when requested", func() {
+			pclaims, err := NewProvisioningClaims(true, true, "x", "usr", "toomanysecrets", []string{"nats://example.net:4222"}, "", "", "", "", "Ginkgo", time.Hour, WithAudience("choria_provisioner"))
+			Expect(err).ToNot(HaveOccurred())
+			token, err := SignToken(pclaims, loadRSAPriKey("testdata/rsa/signer-key.pem"))
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = ParseProvisioningToken(token, loadRSAPubKey("testdata/rsa/signer-public.pem"), WithExpectedAudience("choria_broker"))
+			Expect(err).To(MatchError("could not parse provisioner token: token has invalid audience"))
+
+			_, err = ParseProvisioningToken(token, loadRSAPubKey("testdata/rsa/signer-public.pem"), WithExpectedAudience("choria_provisioner"))
+			Expect(err).ToNot(HaveOccurred())
+		})
 	})
 
 	Describe("ParseProvisioningTokenWithKeyfile", func() {
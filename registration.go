@@ -0,0 +1,212 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// ErrNotARegistrationToken indicates a token is not a registration token
+var ErrNotARegistrationToken = errors.New("not a registration token")
+
+// RegistrationClaims allows a lightweight agent to publish registration and inventory data for a single identity, it has no other rights
+//
+// The "purpose" claim should be set to RegistrationPurpose
+type RegistrationClaims struct {
+	// Identity is the identity the registration data is published for
+	Identity string `json:"identity"`
+
+	// Collectives are the collectives the registration data is published to
+	Collectives []string `json:"collectives"`
+
+	// PublishSubjects are the subjects registration data may be published to, defaults to the standard registration subject in every collective.
+	// Custom subjects must be literal subjects at or below the standard registration subject of one of the collectives
+	PublishSubjects []string `json:"pub_subjects,omitempty"`
+
+	// OrganizationUnit broker account the agent should belong to
+	OrganizationUnit string `json:"ou,omitempty"`
+
+	StandardClaims
+}
+
+// UniqueID returns the identity and unique id used to generate private inboxes
+func (c *RegistrationClaims) UniqueID() (id string, uid string) {
+	return c.Identity, fmt.Sprintf("%x", md5.Sum([]byte(c.Identity)))
+}
+
+// RegistrationSubjects are the subjects the holder may publish registration data to
+func (c *RegistrationClaims) RegistrationSubjects() []string {
+	if len(c.PublishSubjects) > 0 {
+		return c.PublishSubjects
+	}
+
+	subjects := make([]string, len(c.Collectives))
+	for i, collective := range c.Collectives {
+		subjects[i] = fmt.Sprintf("%s.broadcast.agent.registration", collective)
+	}
+
+	return subjects
+}
+
+// NewRegistrationClaims generates new RegistrationClaims, when subjects is empty the standard registration subjects are allowed
+func NewRegistrationClaims(identity string, collectives []string, subjects []string, org string, issuer string, validity time.Duration, pk ed25519.PublicKey, opts ...ClaimsOption) (*RegistrationClaims, error) {
+	if identity == "" {
+		return nil, fmt.Errorf("identity is required")
+	}
+
+	if len(collectives) == 0 {
+		return nil, fmt.Errorf("at least one collective is required")
+	}
+
+	if pk == nil {
+		return nil, fmt.Errorf("public key is required")
+	}
+
+	if org == "" {
+		org = defaultOrg
+	}
+
+	stdClaims, err := newStandardClaims(issuer, RegistrationPurpose, validity, false, append([]ClaimsOption{withPublicKey(pk)}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &RegistrationClaims{
+		Identity:         identity,
+		Collectives:      collectives,
+		PublishSubjects:  subjects,
+		OrganizationUnit: org,
+		StandardClaims:   *stdClaims,
+	}
+
+	err = claims.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// IsRegistrationToken determines if this is a registration token
+func IsRegistrationToken(claims StandardClaims) bool {
+	return claims.Purpose == RegistrationPurpose
+}
+
+// ParseRegistrationTokenUnverified parses the registration token in an unverified manner.
+func ParseRegistrationTokenUnverified(token string) (*RegistrationClaims, error) {
+	claims := &RegistrationClaims{}
+	_, err := parseUnverified(token, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	if !IsRegistrationToken(claims.StandardClaims) {
+		return nil, ErrNotARegistrationToken
+	}
+
+	if claims.OrganizationUnit == "" {
+		claims.OrganizationUnit = defaultOrg
+	}
+
+	return claims, nil
+}
+
+// ParseRegistrationToken parses token and verifies it with pk
+func ParseRegistrationToken(token string, pk any, opts ...ParseOption) (*RegistrationClaims, error) {
+	claims := &RegistrationClaims{}
+	err := ParseToken(token, claims, pk, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse registration token: %w", err)
+	}
+
+	// if we have a tcs we require an issuer expiry to be set and it to not have expired
+	if !claims.verifyIssuerExpiry(claims.TrustChainSignature != "") {
+		return nil, jwt.ErrTokenExpired
+	}
+
+	if claims.OrganizationUnit == "" {
+		claims.OrganizationUnit = defaultOrg
+	}
+
+	return claims, nil
+}
+
+// ParseRegistrationTokenWithKeyfile parses token and verifies it with the RSA or ED25519 Public key in pkFile
+func ParseRegistrationTokenWithKeyfile(token string, pkFile string, opts ...ParseOption) (*RegistrationClaims, error) {
+	if pkFile == "" {
+		return nil, fmt.Errorf("invalid public key file")
+	}
+
+	certdat, err := os.ReadFile(pkFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not read validation certificate: %s", err)
+	}
+
+	pk, err := readRSAOrED25519PublicData(certdat)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseRegistrationToken(token, pk, opts...)
+}
+
+// Validate checks the registration identity, collectives and subjects
+func (c *RegistrationClaims) Validate() error {
+	if !IsRegistrationToken(c.StandardClaims) {
+		return ErrNotARegistrationToken
+	}
+
+	if c.Identity == "" {
+		return fmt.Errorf("identity is required")
+	}
+
+	err := ValidateSubjectLiteral(c.Identity)
+	if err != nil {
+		return fmt.Errorf("invalid identity: %w", err)
+	}
+
+	if len(c.Collectives) == 0 {
+		return fmt.Errorf("at least one collective is required")
+	}
+
+	err = validateCollectives(c.Collectives)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range c.PublishSubjects {
+		err := c.validateRegistrationSubject(s)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateRegistrationSubject ensures subject is a literal subject at or below the registration subject of one of the collectives
+func (c *RegistrationClaims) validateRegistrationSubject(subject string) error {
+	err := ValidateSubjectLiteral(subject)
+	if err != nil {
+		return err
+	}
+
+	for _, collective := range c.Collectives {
+		standard := fmt.Sprintf("%s.broadcast.agent.registration", collective)
+		if subject == standard || strings.HasPrefix(subject, standard+".") {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %q is not a registration subject", ErrInvalidSubject, subject)
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RegistrationClaims", func() {
+	var (
+		pubK ed25519.PublicKey
+		priK ed25519.PrivateKey
+		err  error
+	)
+
+	BeforeEach(func() {
+		pubK, priK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Describe("NewRegistrationClaims", func() {
+		It("Should validate the input", func() {
+			_, err := NewRegistrationClaims("", []string{"choria"}, nil, "", "", time.Hour, pubK)
+			Expect(err).To(MatchError("identity is required"))
+
+			_, err = NewRegistrationClaims("n1.example.net", nil, nil, "", "", time.Hour, pubK)
+			Expect(err).To(MatchError("at least one collective is required"))
+
+			_, err = NewRegistrationClaims("n1.example.net", []string{"choria"}, nil, "", "", time.Hour, nil)
+			Expect(err).To(MatchError("public key is required"))
+
+			_, err = NewRegistrationClaims("n1.example.net", []string{"choria"}, []string{"bad subject"}, "", "", time.Hour, pubK)
+			Expect(err).To(MatchError(ErrInvalidSubject))
+
+			for _, subject := range []string{"inventory.n1", "choria.broadcast.agent.>", "choria.broadcast.agent.*", "other.broadcast.agent.registration", "choria.broadcast.agent.rpcutil", "choria.broadcast.agent.registrationx"} {
+				_, err = NewRegistrationClaims("n1.example.net", []string{"choria"}, []string{subject}, "", "", time.Hour, pubK)
+				Expect(err).To(MatchError(ErrInvalidSubject), subject)
+			}
+		})
+
+		It("Should create valid claims", func() {
+			claims, err := NewRegistrationClaims("n1.example.net", []string{"choria", "other"}, nil, "", "ginkgo", time.Hour, pubK)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(claims.Purpose).To(Equal(RegistrationPurpose))
+			Expect(claims.Identity).To(Equal("n1.example.net"))
+			Expect(claims.OrganizationUnit).To(Equal(defaultOrg))
+			Expect(claims.RegistrationSubjects()).To(Equal([]string{"choria.broadcast.agent.registration", "other.broadcast.agent.registration"}))
+
+			claims, err = NewRegistrationClaims("n1.example.net", []string{"choria"}, []string{"choria.broadcast.agent.registration.n1"}, "", "ginkgo", time.Hour, pubK)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(claims.RegistrationSubjects()).To(Equal([]string{"choria.broadcast.agent.registration.n1"}))
+		})
+	})
+
+	Describe("ParseRegistrationToken", func() {
+		It("Should parse valid tokens", func() {
+			claims, err := NewRegistrationClaims("n1.example.net", []string{"choria"}, nil, "", "", time.Hour, pubK)
+			Expect(err).ToNot(HaveOccurred())
+
+			token, err := SignToken(claims, priK)
+			Expect(err).ToNot(HaveOccurred())
+
+			parsed, err := ParseRegistrationToken(token, pubK)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(parsed.Identity).To(Equal("n1.example.net"))
+
+			id, _ := parsed.UniqueID()
+			Expect(id).To(Equal("n1.example.net"))
+
+			unverified, err := ParseRegistrationTokenUnverified(token)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(unverified.Collectives).To(Equal([]string{"choria"}))
+
+			_, err = ParseRegistrationTokenWithKeyfile(token, "")
+			Expect(err).To(MatchError("invalid public key file"))
+		})
+
+		It("Should not accept server tokens", func() {
+			claims, err := NewServerClaims("n1.example.net", []string{"choria"}, "", nil, nil, pubK, "", time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+
+			token, err := SignToken(claims, priK)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = ParseRegistrationToken(token, pubK)
+			Expect(err).To(MatchError(ErrNotARegistrationToken))
+
+			_, err = ParseRegistrationTokenUnverified(token)
+			Expect(err).To(MatchError(ErrNotARegistrationToken))
+		})
+	})
+})
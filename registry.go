@@ -0,0 +1,171 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// ClaimsFactory creates a new empty claims structure used to parse tokens of a specific purpose
+type ClaimsFactory func() jwt.Claims
+
+// ErrUnknownPurpose indicates a token has a purpose that is not registered
+var ErrUnknownPurpose = errors.New("unknown token purpose")
+
+var (
+	purposes   = map[Purpose]ClaimsFactory{}
+	purposesMu sync.RWMutex
+)
+
+// natsConnectionClaims are claims that can be used with NatsConnectionHelpers
+type natsConnectionClaims interface {
+	jwt.Claims
+	UniqueID() (id string, uid string)
+	IsExpired() bool
+	ExpireTime() time.Time
+}
+
+func init() {
+	builtin := map[Purpose]ClaimsFactory{
+		ClientIDPurpose:        func() jwt.Claims { return &ClientIDClaims{} },
+		ServerPurpose:          func() jwt.Claims { return &ServerClaims{} },
+		ProvisioningPurpose:    func() jwt.Claims { return &ProvisioningClaims{} },
+		ServiceAccountPurpose:  func() jwt.Claims { return &ServiceAccountClaims{} },
+		StreamPurpose:          func() jwt.Claims { return &StreamClaims{} },
+		RegistrationPurpose:    func() jwt.Claims { return &RegistrationClaims{} },
+		SchedulerPurpose:       func() jwt.Claims { return &SchedulerClaims{} },
+		ConfigPurpose:          func() jwt.Claims { return &ConfigClaims{} },
+		ObserverPurpose:        func() jwt.Claims { return &ObserverClaims{} },
+		TrustConfigPurpose:     func() jwt.Claims { return &TrustConfigClaims{} },
+		RevocationListPurpose:  func() jwt.Claims { return &RevocationListClaims{} },
+		KnownIssuersPurpose:    func() jwt.Claims { return &KnownIssuersClaims{} },
+		FederationTrustPurpose: func() jwt.Claims { return &FederationTrustClaims{} },
+		TrustBundlePurpose:     func() jwt.Claims { return &TrustBundleClaims{} },
+	}
+
+	for p, f := range builtin {
+		err := RegisterPurpose(p, f)
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterPurpose registers a custom token purpose and a factory creating claims for it, this allows
+// ParseAnyToken and NatsConnectionHelpers to handle token types defined outside of this package.
+//
+// Claims that should work with NatsConnectionHelpers must implement UniqueID(), IsExpired() and ExpireTime(),
+// embedding StandardClaims provides the latter two. Purposes can only be registered once.
+func RegisterPurpose(purpose Purpose, factory ClaimsFactory) error {
+	if purpose == UnknownPurpose {
+		return fmt.Errorf("purpose is required")
+	}
+
+	if factory == nil {
+		return fmt.Errorf("claims factory is required")
+	}
+
+	purposesMu.Lock()
+	defer purposesMu.Unlock()
+
+	_, ok := purposes[purpose]
+	if ok {
+		return fmt.Errorf("purpose %s is already registered", purpose)
+	}
+
+	purposes[purpose] = factory
+
+	return nil
+}
+
+// RegisteredPurposes lists all known purposes, sorted
+func RegisteredPurposes() []Purpose {
+	purposesMu.RLock()
+	defer purposesMu.RUnlock()
+
+	res := make([]Purpose, 0, len(purposes))
+	for p := range purposes {
+		res = append(res, p)
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i] < res[j] })
+
+	return res
+}
+
+// IsRegisteredPurpose determines if purpose has been registered
+func IsRegisteredPurpose(purpose Purpose) bool {
+	_, ok := purposeFactory(purpose)
+	return ok
+}
+
+// NewClaimsForPurpose creates empty claims for a registered purpose
+func NewClaimsForPurpose(purpose Purpose) (jwt.Claims, error) {
+	factory, ok := purposeFactory(purpose)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownPurpose, purpose)
+	}
+
+	return factory(), nil
+}
+
+// ParseAnyToken parses and verifies a token of any registered purpose using pk, the returned claims
+// can be cast to the type created by the factory for the purpose
+func ParseAnyToken(token string, pk any, opts ...ParseOption) (jwt.Claims, error) {
+	purpose := TokenPurpose(token)
+
+	claims, err := NewClaimsForPurpose(purpose)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ParseToken(token, claims, pk, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// ParseAnyTokenUnverified parses a token of any registered purpose without verifying it
+func ParseAnyTokenUnverified(token string) (jwt.Claims, error) {
+	purpose := TokenPurpose(token)
+
+	claims, err := NewClaimsForPurpose(purpose)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = parseUnverified(token, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+func purposeFactory(purpose Purpose) (ClaimsFactory, bool) {
+	purposesMu.RLock()
+	f, ok := purposes[purpose]
+	purposesMu.RUnlock()
+
+	return f, ok
+}
+
+// newClaimsForPurpose creates an empty claims structure suitable for parsing tokens of purpose, unknown purposes use StandardClaims
+func newClaimsForPurpose(purpose Purpose) jwt.Claims {
+	claims, err := NewClaimsForPurpose(purpose)
+	if err != nil {
+		return &StandardClaims{}
+	}
+
+	return claims
+}
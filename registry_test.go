@@ -0,0 +1,125 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+)
+
+const ginkgoCustomPurpose Purpose = "ginkgo_custom"
+
+type ginkgoCustomClaims struct {
+	Name string `json:"name"`
+	StandardClaims
+}
+
+func (c *ginkgoCustomClaims) UniqueID() (string, string) {
+	return c.Name, "ginkgo_" + c.Name
+}
+
+var _ = Describe("Registry", func() {
+	var (
+		pubK ed25519.PublicKey
+		priK ed25519.PrivateKey
+	)
+
+	BeforeEach(func() {
+		if !IsRegisteredPurpose(ginkgoCustomPurpose) {
+			Expect(RegisterPurpose(ginkgoCustomPurpose, func() jwt.Claims { return &ginkgoCustomClaims{} })).To(Succeed())
+		}
+
+		var err error
+		pubK, priK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	customToken := func() string {
+		std, err := newStandardClaims("ginkgo", ginkgoCustomPurpose, time.Hour, false)
+		Expect(err).ToNot(HaveOccurred())
+
+		token, err := SignToken(&ginkgoCustomClaims{Name: "custom", StandardClaims: *std}, priK)
+		Expect(err).ToNot(HaveOccurred())
+
+		return token
+	}
+
+	Describe("RegisterPurpose", func() {
+		It("Should validate registrations", func() {
+			Expect(RegisterPurpose("", func() jwt.Claims { return nil })).To(MatchError("purpose is required"))
+			Expect(RegisterPurpose("x", nil)).To(MatchError("claims factory is required"))
+			Expect(RegisterPurpose(ClientIDPurpose, func() jwt.Claims { return nil })).To(MatchError("purpose choria_client_id is already registered"))
+		})
+
+		It("Should include built in and custom purposes", func() {
+			Expect(RegisteredPurposes()).To(ContainElements(ClientIDPurpose, ServerPurpose, ProvisioningPurpose, ginkgoCustomPurpose))
+		})
+	})
+
+	Describe("NewClaimsForPurpose", func() {
+		It("Should create the right claims", func() {
+			claims, err := NewClaimsForPurpose(ServerPurpose)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(claims).To(BeAssignableToTypeOf(&ServerClaims{}))
+
+			_, err = NewClaimsForPurpose("unknown")
+			Expect(err).To(MatchError(ErrUnknownPurpose))
+		})
+	})
+
+	Describe("ParseAnyToken", func() {
+		It("Should parse custom tokens", func() {
+			token := customToken()
+			Expect(TokenPurpose(token)).To(Equal(ginkgoCustomPurpose))
+
+			claims, err := ParseAnyToken(token, pubK)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(claims.(*ginkgoCustomClaims).Name).To(Equal("custom"))
+
+			claims, err = ParseAnyTokenUnverified(token)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(claims.(*ginkgoCustomClaims).Name).To(Equal("custom"))
+		})
+
+		It("Should parse built in tokens", func() {
+			client, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, nil)
+			Expect(err).ToNot(HaveOccurred())
+			token, err := SignToken(client, priK)
+			Expect(err).ToNot(HaveOccurred())
+
+			claims, err := ParseAnyToken(token, pubK)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(claims.(*ClientIDClaims).CallerID).To(Equal("up=bob"))
+		})
+
+		It("Should fail for unknown purposes", func() {
+			std, err := newStandardClaims("ginkgo", "unknown", time.Hour, false)
+			Expect(err).ToNot(HaveOccurred())
+			token, err := SignToken(std, priK)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = ParseAnyToken(token, pubK)
+			Expect(err).To(MatchError(ErrUnknownPurpose))
+		})
+	})
+
+	Describe("NatsConnectionHelpers", func() {
+		It("Should support custom tokens", func() {
+			log := logrus.NewEntry(logrus.New())
+			log.Logger.SetOutput(GinkgoWriter)
+
+			inbox, jh, _, err := NatsConnectionHelpers(customToken(), "choria", "testdata/ed25519/other.seed", log)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(inbox).To(Equal("choria.reply.ginkgo_custom"))
+			Expect(jh()).ToNot(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,197 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// ErrTokenNotReissuable indicates a token cannot be reissued
+var ErrTokenNotReissuable = errors.New("token cannot be reissued")
+
+// ReissueOptions configures how tokens are reissued after an issuer key replacement
+type ReissueOptions struct {
+	// Keys verifies tokens before they are reissued, when nil tokens are reissued without verification
+	Keys *KeyRing
+
+	// Issuer replaces the issuer of reissued tokens, org issuers are always updated to the new key
+	Issuer string
+
+	// DryRun determines what would be reissued without saving any tokens to the store
+	DryRun bool
+}
+
+// ReissueResult describes the outcome of reissuing a single token
+type ReissueResult struct {
+	// Name is the name the token is stored under
+	Name string `json:"name"`
+
+	// PreviousTokenID is the token id of the original token
+	PreviousTokenID string `json:"previous_token_id,omitempty"`
+
+	// TokenID is the token id of the reissued token
+	TokenID string `json:"token_id,omitempty"`
+
+	// Reissued indicates the token was reissued
+	Reissued bool `json:"reissued"`
+
+	// Reason is why the token was not reissued
+	Reason string `json:"reason,omitempty"`
+}
+
+// ReissueToken signs a new copy of token using signer, the new token has a new token id with the original recorded in ReissuedFrom and keeps its expiry time.
+//
+// Expired tokens and those issued by chain issuers cannot be reissued. Org issuer chain data is updated when signer is an ed25519 key
+func ReissueToken(token string, signer any, opts ReissueOptions) (string, error) {
+	claims, std, err := parseForReissue(token, opts.Keys)
+	if err != nil {
+		return "", err
+	}
+
+	err = prepareReissue(std, signer, opts.Issuer, currentTime(), ErrTokenNotReissuable)
+	if err != nil {
+		return "", err
+	}
+
+	return SignToken(claims, signer)
+}
+
+// ReissueStore reissues every non expired token in store using signer, replacing the stored tokens unless DryRun is set.
+//
+// Tokens that cannot be reissued are reported in the results and left untouched, an error is only returned when the store fails
+func ReissueStore(store TokenStore, signer any, opts ReissueOptions) ([]*ReissueResult, error) {
+	names, err := store.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*ReissueResult
+
+	for _, name := range names {
+		token, err := store.Load(name)
+		if err != nil {
+			return results, err
+		}
+
+		res := &ReissueResult{Name: name}
+		results = append(results, res)
+
+		claims, std, err := parseForReissue(token, opts.Keys)
+		if std != nil {
+			res.PreviousTokenID = std.ID
+		}
+		if err != nil {
+			res.Reason = err.Error()
+			continue
+		}
+
+		err = prepareReissue(std, signer, opts.Issuer, currentTime(), ErrTokenNotReissuable)
+		if err != nil {
+			res.Reason = err.Error()
+			continue
+		}
+
+		res.TokenID = std.ID
+
+		if opts.DryRun {
+			continue
+		}
+
+		signed, err := SignToken(claims, signer)
+		if err != nil {
+			res.Reason = err.Error()
+			continue
+		}
+
+		err = store.Save(name, signed)
+		if err != nil {
+			return results, err
+		}
+
+		res.Reissued = true
+	}
+
+	return results, nil
+}
+
+func parseForReissue(token string, keys *KeyRing) (jwt.Claims, *StandardClaims, error) {
+	claims := newClaimsForPurpose(TokenPurpose(token))
+
+	sc, ok := claims.(standardClaimsProvider)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: unsupported claims", ErrTokenNotReissuable)
+	}
+
+	var err error
+	if keys != nil {
+		_, err = keys.ParseToken(token, claims)
+	} else {
+		_, err = parseUnverified(token, claims)
+	}
+
+	std := sc.getStandardClaims()
+
+	switch {
+	case errors.Is(err, jwt.ErrTokenExpired) || (err == nil && std.IsExpired()):
+		return claims, std, fmt.Errorf("%w: %v", ErrTokenNotReissuable, jwt.ErrTokenExpired)
+	case err != nil:
+		return claims, std, fmt.Errorf("%w: %v", ErrTokenNotReissuable, err)
+	case strings.HasPrefix(std.Issuer, ChainIssuerPrefix):
+		return claims, std, fmt.Errorf("%w: tokens issued by chain issuers must be reissued by the chain issuer", ErrTokenNotReissuable)
+	}
+
+	return claims, std, nil
+}
+
+// prepareReissue gives std a new token id issued at now recording the previous id in ReissuedFrom, sets issuer when
+// not empty and updates org issuer data using signer, failures are reported wrapping notReissuable
+func prepareReissue(std *StandardClaims, signer any, issuer string, now time.Time, notReissuable error) error {
+	issued := jwt.NewNumericDate(now.UTC())
+	id, err := newTokenID(issued.Time)
+	if err != nil {
+		return err
+	}
+
+	std.ReissuedFrom = std.ID
+	std.ID = id
+	std.IssuedAt = issued
+
+	// tokens created using AllowIssuerPublicKey keep holding the public key of their issuer
+	pk, err := hex.DecodeString(std.PublicKey)
+	if err == nil && len(pk) == ed25519.PublicKeySize && isIssuerPublicKey(std.Issuer, pk) {
+		std.allowIssuerKey = true
+	}
+
+	edSigner, isEd := signer.(ed25519.PrivateKey)
+
+	switch {
+	case strings.HasPrefix(std.Issuer, OrgIssuerPrefix) && std.TrustChainSignature != "":
+		// chain issuers must remain chain issuers so they are always re-signed by the new org issuer
+		if !isEd {
+			return fmt.Errorf("%w: org issuer tokens require a ed25519 signer", notReissuable)
+		}
+
+		return std.AddOrgIssuerData(edSigner)
+
+	case issuer != "":
+		std.Issuer = issuer
+
+	case strings.HasPrefix(std.Issuer, OrgIssuerPrefix):
+		if !isEd {
+			return fmt.Errorf("%w: org issuer tokens require a ed25519 signer", notReissuable)
+		}
+
+		return std.SetOrgIssuer(edSigner.Public().(ed25519.PublicKey))
+	}
+
+	return nil
+}
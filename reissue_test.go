@@ -0,0 +1,177 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Reissue", func() {
+	var (
+		oldPubK, newPubK ed25519.PublicKey
+		oldPriK, newPriK ed25519.PrivateKey
+	)
+
+	BeforeEach(func() {
+		var err error
+		oldPubK, oldPriK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		newPubK, newPriK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	clientToken := func(caller string, validity time.Duration) (string, *ClientIDClaims) {
+		claims, err := NewClientIDClaims(caller, nil, "", nil, "", "ginkgo", time.Hour, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(validity))
+
+		token, err := SignToken(claims, oldPriK)
+		Expect(err).ToNot(HaveOccurred())
+
+		return token, claims
+	}
+
+	Describe("ReissueToken", func() {
+		It("Should reissue tokens preserving lineage", func() {
+			token, orig := clientToken("up=bob", 2*time.Hour)
+
+			reissued, err := ReissueToken(token, newPriK, ReissueOptions{})
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = ParseClientIDToken(reissued, oldPubK, true)
+			Expect(err).To(HaveOccurred())
+
+			claims, err := ParseClientIDToken(reissued, newPubK, true)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(claims.CallerID).To(Equal("up=bob"))
+			Expect(claims.ID).ToNot(Equal(orig.ID))
+			Expect(claims.ReissuedFrom).To(Equal(orig.ID))
+			Expect(claims.ExpiresAt.Time).To(Equal(orig.ExpiresAt.Time))
+			Expect(claims.Issuer).To(Equal("ginkgo"))
+		})
+
+		It("Should update the issuer when requested", func() {
+			token, _ := clientToken("up=bob", time.Hour)
+
+			reissued, err := ReissueToken(token, newPriK, ReissueOptions{Issuer: "new issuer"})
+			Expect(err).ToNot(HaveOccurred())
+
+			claims, err := ParseClientIDToken(reissued, newPubK, true)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(claims.Issuer).To(Equal("new issuer"))
+		})
+
+		It("Should verify tokens using the key ring", func() {
+			token, _ := clientToken("up=bob", time.Hour)
+
+			kr, err := NewKeyRing(newPubK)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = ReissueToken(token, newPriK, ReissueOptions{Keys: kr})
+			Expect(err).To(MatchError(ErrTokenNotReissuable))
+
+			Expect(kr.Add(oldPubK)).To(Succeed())
+			_, err = ReissueToken(token, newPriK, ReissueOptions{Keys: kr})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("Should not reissue expired tokens", func() {
+			token, _ := clientToken("up=bob", -time.Hour)
+
+			_, err := ReissueToken(token, newPriK, ReissueOptions{})
+			Expect(err).To(MatchError(ErrTokenNotReissuable))
+			Expect(err).To(MatchError(ContainSubstring("token is expired")))
+		})
+
+		It("Should re-sign org issuer chain data", func() {
+			handlerPubK, _, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+
+			handler, err := NewClientIDClaims("handler", nil, "", nil, "", "", time.Hour, nil, handlerPubK)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(handler.AddOrgIssuerData(oldPriK)).To(Succeed())
+
+			token, err := SignToken(handler, oldPriK)
+			Expect(err).ToNot(HaveOccurred())
+
+			reissued, err := ReissueToken(token, newPriK, ReissueOptions{})
+			Expect(err).ToNot(HaveOccurred())
+
+			claims, err := ParseClientIDToken(reissued, newPubK, true)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(claims.Issuer).To(Equal(OrgIssuerPrefix + hex.EncodeToString(newPubK)))
+			Expect(claims.IsChainedIssuer(true)).To(BeTrue())
+			Expect(claims.ReissuedFrom).To(Equal(handler.ID))
+		})
+
+		It("Should not reissue tokens from chain issuers", func() {
+			handlerPubK, handlerPriK, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+
+			handler, err := NewClientIDClaims("handler", nil, "", nil, "", "", time.Hour, nil, handlerPubK)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(handler.AddOrgIssuerData(oldPriK)).To(Succeed())
+
+			user, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(user.AddChainIssuerData(handler, handlerPriK)).To(Succeed())
+
+			token, err := SignToken(user, handlerPriK)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = ReissueToken(token, newPriK, ReissueOptions{})
+			Expect(err).To(MatchError(ContainSubstring("must be reissued by the chain issuer")))
+		})
+	})
+
+	Describe("ReissueStore", func() {
+		var store *DirectoryStore
+
+		BeforeEach(func() {
+			var err error
+			store, err = NewDirectoryStore(GinkgoT().TempDir())
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("Should reissue all valid tokens", func() {
+			valid, orig := clientToken("up=valid", time.Hour)
+			expired, _ := clientToken("up=expired", -time.Hour)
+			Expect(store.Save("valid", valid)).To(Succeed())
+			Expect(store.Save("expired", expired)).To(Succeed())
+
+			results, err := ReissueStore(store, newPriK, ReissueOptions{DryRun: true})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results).To(HaveLen(2))
+			Expect(results[0].Name).To(Equal("expired"))
+			Expect(results[0].Reissued).To(BeFalse())
+			Expect(results[0].Reason).To(ContainSubstring("token is expired"))
+			Expect(results[1].Name).To(Equal("valid"))
+			Expect(results[1].Reissued).To(BeFalse())
+			Expect(results[1].TokenID).ToNot(BeEmpty())
+			Expect(store.Load("valid")).To(Equal(valid))
+
+			results, err = ReissueStore(store, newPriK, ReissueOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results[1].Reissued).To(BeTrue())
+			Expect(results[1].PreviousTokenID).To(Equal(orig.ID))
+
+			token, err := store.Load("valid")
+			Expect(err).ToNot(HaveOccurred())
+			claims, err := ParseClientIDToken(token, newPubK, true)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(claims.ID).To(Equal(results[1].TokenID))
+			Expect(claims.ReissuedFrom).To(Equal(orig.ID))
+
+			Expect(store.Load("expired")).To(Equal(expired))
+		})
+	})
+})
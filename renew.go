@@ -0,0 +1,167 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// ErrTokenNotRenewable indicates a token cannot be renewed
+var ErrTokenNotRenewable = errors.New("token cannot be renewed")
+
+// RenewOption configures optional behavior when renewing tokens
+type RenewOption func(*renewOptions) error
+
+type renewOptions struct {
+	grace     time.Duration
+	verifyKey any
+	revoked   func(claims *StandardClaims) (bool, error)
+	clock     func() time.Time
+}
+
+// WithRenewalGracePeriod allows tokens that expired less than grace ago to be renewed
+func WithRenewalGracePeriod(grace time.Duration) RenewOption {
+	return func(o *renewOptions) error {
+		if grace < 0 {
+			return fmt.Errorf("grace period cannot be negative")
+		}
+
+		o.grace = grace
+
+		return nil
+	}
+}
+
+// WithRenewalVerificationKey verifies the token being renewed using pk rather than the public key of the signer
+func WithRenewalVerificationKey(pk any) RenewOption {
+	return func(o *renewOptions) error {
+		if pk == nil {
+			return fmt.Errorf("invalid public key")
+		}
+
+		o.verifyKey = pk
+
+		return nil
+	}
+}
+
+// WithRenewalRevocationCheck refuses to renew tokens for which revoked returns true
+func WithRenewalRevocationCheck(revoked func(claims *StandardClaims) (bool, error)) RenewOption {
+	return func(o *renewOptions) error {
+		if revoked == nil {
+			return fmt.Errorf("revocation check is required")
+		}
+
+		o.revoked = revoked
+
+		return nil
+	}
+}
+
+// WithRenewalClock verifies the token and sets the new issue and expiry times using now rather than the clock set using SetClock
+func WithRenewalClock(now func() time.Time) RenewOption {
+	return func(o *renewOptions) error {
+		if now == nil {
+			return fmt.Errorf("clock is required")
+		}
+
+		o.clock = now
+
+		return nil
+	}
+}
+
+// RenewToken verifies a client or server token and signs a copy of it with a new token id, issue time and expiry,
+// the original token id is recorded in ReissuedFrom. When validity is 0 the lifetime of the original token is kept.
+//
+// By default the token is verified using the public key of signer and must not be expired, tokens issued by chain
+// issuers cannot be renewed and org issuer chain data is updated which requires a ed25519 signer
+func RenewToken(token string, signer any, validity time.Duration, opts ...RenewOption) (string, error) {
+	if validity < 0 {
+		return "", fmt.Errorf("validity cannot be negative")
+	}
+
+	ropts := &renewOptions{}
+	for _, opt := range opts {
+		err := opt(ropts)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if ropts.verifyKey == nil {
+		s, ok := signer.(crypto.Signer)
+		if !ok {
+			return "", fmt.Errorf("unsupported signing key %T", signer)
+		}
+		ropts.verifyKey = s.Public()
+	}
+
+	var claims jwt.Claims
+	var std *StandardClaims
+
+	switch TokenPurpose(token) {
+	case ClientIDPurpose:
+		c := &ClientIDClaims{}
+		claims, std = c, &c.StandardClaims
+	case ServerPurpose:
+		c := &ServerClaims{}
+		claims, std = c, &c.StandardClaims
+	default:
+		return "", fmt.Errorf("%w: only client and server tokens can be renewed", ErrTokenNotRenewable)
+	}
+
+	popts := []ParseOption{WithLeeway(ropts.grace)}
+	if ropts.clock != nil {
+		popts = append(popts, WithVerificationClock(ropts.clock))
+	}
+
+	err := ParseToken(token, claims, ropts.verifyKey, popts...)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrTokenNotRenewable, err)
+	}
+
+	if strings.HasPrefix(std.Issuer, ChainIssuerPrefix) {
+		return "", fmt.Errorf("%w: tokens issued by chain issuers must be renewed by the chain issuer", ErrTokenNotRenewable)
+	}
+
+	if ropts.revoked != nil {
+		revoked, err := ropts.revoked(std)
+		if err != nil {
+			return "", fmt.Errorf("%w: could not check revocation status: %v", ErrTokenNotRenewable, err)
+		}
+		if revoked {
+			return "", fmt.Errorf("%w: %w", ErrTokenNotRenewable, ErrTokenRevoked)
+		}
+	}
+
+	if validity == 0 {
+		if std.IssuedAt == nil || std.ExpiresAt == nil {
+			return "", fmt.Errorf("%w: validity is required for tokens without an issue or expiry time", ErrTokenNotRenewable)
+		}
+		validity = std.ExpiresAt.Sub(std.IssuedAt.Time)
+	}
+
+	issued := currentTime()
+	if ropts.clock != nil {
+		issued = ropts.clock()
+	}
+
+	err = prepareReissue(std, signer, "", issued, ErrTokenNotRenewable)
+	if err != nil {
+		return "", err
+	}
+
+	std.NotBefore = std.IssuedAt
+	std.ExpiresAt = jwt.NewNumericDate(std.IssuedAt.Add(validity))
+
+	return SignToken(claims, signer)
+}
@@ -0,0 +1,142 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RenewToken", func() {
+	var (
+		pubK ed25519.PublicKey
+		priK ed25519.PrivateKey
+	)
+
+	BeforeEach(func() {
+		var err error
+		pubK, priK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	clientToken := func(expires time.Duration) (string, *ClientIDClaims) {
+		claims, err := NewClientIDClaims("up=bob", []string{"rpcutil"}, "", nil, "", "ginkgo", 2*time.Hour, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(expires))
+
+		token, err := SignToken(claims, priK)
+		Expect(err).ToNot(HaveOccurred())
+
+		return token, claims
+	}
+
+	It("Should renew client tokens preserving claims", func() {
+		token, orig := clientToken(time.Hour)
+
+		renewed, err := RenewToken(token, priK, 24*time.Hour)
+		Expect(err).ToNot(HaveOccurred())
+
+		claims, err := ParseClientIDToken(renewed, pubK, true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(claims.CallerID).To(Equal("up=bob"))
+		Expect(claims.AllowedAgents).To(Equal([]string{"rpcutil"}))
+		Expect(claims.Issuer).To(Equal("ginkgo"))
+		Expect(claims.ID).ToNot(Equal(orig.ID))
+		Expect(claims.ReissuedFrom).To(Equal(orig.ID))
+		Expect(claims.ExpiresAt.Time).To(BeTemporally("~", time.Now().Add(24*time.Hour), 2*time.Second))
+		Expect(claims.TokenIDTime()).To(Equal(claims.IssuedAt.Time))
+	})
+
+	It("Should keep the original lifetime by default", func() {
+		sclaims, err := NewServerClaims("ginkgo.example.net", []string{"choria"}, "choria", nil, nil, pubK, "ginkgo", 3*time.Hour, AllowIssuerPublicKey())
+		Expect(err).ToNot(HaveOccurred())
+		token, err := SignToken(sclaims, priK)
+		Expect(err).ToNot(HaveOccurred())
+
+		renewed, err := RenewToken(token, priK, 0)
+		Expect(err).ToNot(HaveOccurred())
+
+		claims, err := ParseServerToken(renewed, pubK)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(claims.ChoriaIdentity).To(Equal("ginkgo.example.net"))
+		Expect(claims.ExpiresAt.Sub(claims.IssuedAt.Time)).To(Equal(3 * time.Hour))
+	})
+
+	It("Should only renew valid client and server tokens", func() {
+		_, err := RenewToken("x", priK, time.Hour)
+		Expect(err).To(MatchError(ErrTokenNotRenewable))
+
+		token, _ := clientToken(time.Hour)
+		_, err = RenewToken(token, priK, -1*time.Hour)
+		Expect(err).To(MatchError("validity cannot be negative"))
+
+		_, otherPriK, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = RenewToken(token, otherPriK, time.Hour)
+		Expect(err).To(MatchError(ContainSubstring("verification error")))
+
+		_, err = RenewToken(token, otherPriK, time.Hour, WithRenewalVerificationKey(pubK))
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Should support a grace period for expired tokens", func() {
+		token, _ := clientToken(-time.Minute)
+
+		_, err := RenewToken(token, priK, time.Hour)
+		Expect(err).To(MatchError(ErrTokenNotRenewable))
+		Expect(err).To(MatchError(ContainSubstring("token is expired")))
+
+		_, err = RenewToken(token, priK, time.Hour, WithRenewalGracePeriod(time.Second))
+		Expect(err).To(MatchError(ErrTokenNotRenewable))
+
+		_, err = RenewToken(token, priK, time.Hour, WithRenewalGracePeriod(time.Hour))
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Should not renew revoked tokens", func() {
+		token, orig := clientToken(time.Hour)
+
+		_, err := RenewToken(token, priK, time.Hour, WithRenewalRevocationCheck(func(c *StandardClaims) (bool, error) {
+			return c.ID == orig.ID, nil
+		}))
+		Expect(err).To(MatchError(ErrTokenRevoked))
+		Expect(err).To(MatchError(ErrTokenNotRenewable))
+
+		_, err = RenewToken(token, priK, time.Hour, WithRenewalRevocationCheck(func(c *StandardClaims) (bool, error) {
+			return false, errors.New("unavailable")
+		}))
+		Expect(err).To(MatchError("token cannot be renewed: could not check revocation status: unavailable"))
+
+		_, err = RenewToken(token, priK, time.Hour, WithRenewalRevocationCheck(func(c *StandardClaims) (bool, error) {
+			return false, nil
+		}))
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Should update org issuer chain data", func() {
+		handlerPubK, _, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		handler, err := NewClientIDClaims("handler", nil, "", nil, "", "", time.Hour, nil, handlerPubK)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(handler.AddOrgIssuerData(priK)).To(Succeed())
+		token, err := SignToken(handler, priK)
+		Expect(err).ToNot(HaveOccurred())
+
+		renewed, err := RenewToken(token, priK, time.Hour)
+		Expect(err).ToNot(HaveOccurred())
+
+		claims, err := ParseClientIDToken(renewed, pubK, true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(claims.ID).ToNot(Equal(handler.ID))
+		Expect(claims.IsChainedIssuer(true)).To(BeTrue())
+	})
+})
@@ -0,0 +1,176 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var (
+	// ErrTokenReplayed indicates a single use token was presented more than once
+	ErrTokenReplayed = errors.New("token has already been used")
+
+	// ErrTokenIDRequired indicates a token without a jti was presented to a ReplayGuard
+	ErrTokenIDRequired = errors.New("token id is required")
+
+	// ErrNonceReplayed indicates a single use nonce was presented more than once
+	ErrNonceReplayed = errors.New("nonce has already been used")
+)
+
+// ReplayStore records the ids of tokens that have been used, implementations must be safe for concurrent use
+type ReplayStore interface {
+	// MarkSeen records jti as used until expires and reports if it was already recorded, checking and recording
+	// must be atomic so concurrent presentations of the same token are detected
+	MarkSeen(ctx context.Context, jti string, expires time.Time) (seen bool, err error)
+}
+
+// ReplayGuard enforces that tokens are used only once, typically provisioning and enrollment tokens
+type ReplayGuard struct {
+	store  ReplayStore
+	maxTTL time.Duration
+}
+
+// NewReplayGuard creates a ReplayGuard recording used tokens in store, when store is nil a MemoryReplayStore is used.
+// Tokens are remembered until they expire, tokens without an expiry time are remembered for maxTTL
+func NewReplayGuard(store ReplayStore, maxTTL time.Duration) (*ReplayGuard, error) {
+	if maxTTL <= 0 {
+		return nil, fmt.Errorf("maximum ttl is required")
+	}
+
+	if store == nil {
+		store = NewMemoryReplayStore()
+	}
+
+	return &ReplayGuard{store: store, maxTTL: maxTTL}, nil
+}
+
+// Use records the use of the token with claims and fails with ErrTokenReplayed when it was used before, claims
+// should already be verified
+func (g *ReplayGuard) Use(ctx context.Context, claims jwt.Claims) error {
+	return g.use(ctx, claims, 0)
+}
+
+// use records the use of claims, tokens are remembered for leeway past their expiry as they are accepted until then
+func (g *ReplayGuard) use(ctx context.Context, claims jwt.Claims, leeway time.Duration) error {
+	sc, ok := claims.(standardClaimsProvider)
+	if !ok {
+		return fmt.Errorf("%w: unsupported claims %T", ErrTokenIDRequired, claims)
+	}
+
+	std := sc.getStandardClaims()
+	if std.ID == "" {
+		return ErrTokenIDRequired
+	}
+
+	expires := currentTime().Add(g.maxTTL)
+	if exp := std.ExpireTime(); !exp.IsZero() {
+		expires = exp.Add(leeway)
+	}
+
+	seen, err := g.store.MarkSeen(ctx, string(std.Purpose)+":"+std.ID, expires)
+	if err != nil {
+		return fmt.Errorf("could not check token replay: %w", err)
+	}
+	if seen {
+		return ErrTokenReplayed
+	}
+
+	return nil
+}
+
+// UseNonce records the use of nonce, typically from a signed request, and fails with ErrNonceReplayed when it was
+// used before. The nonce is remembered until expires or, when expires is zero, for the maximum ttl of the guard
+func (g *ReplayGuard) UseNonce(ctx context.Context, nonce string, expires time.Time) error {
+	if nonce == "" {
+		return fmt.Errorf("nonce is required")
+	}
+
+	if expires.IsZero() {
+		expires = currentTime().Add(g.maxTTL)
+	}
+
+	seen, err := g.store.MarkSeen(ctx, "nonce:"+nonce, expires)
+	if err != nil {
+		return fmt.Errorf("could not check nonce replay: %w", err)
+	}
+	if seen {
+		return ErrNonceReplayed
+	}
+
+	return nil
+}
+
+// WithReplayGuard rejects tokens that were already used, tokens are only recorded once all other checks passed
+func WithReplayGuard(guard *ReplayGuard) ParseOption {
+	return func(o *parseOptions) error {
+		if guard == nil {
+			return fmt.Errorf("replay guard is required")
+		}
+
+		o.replay = guard
+
+		return nil
+	}
+}
+
+// MemoryReplayStore is a ReplayStore keeping used token ids in memory, suitable for a single verifier
+type MemoryReplayStore struct {
+	seen      map[string]time.Time
+	nextPrune time.Time
+	mu        sync.Mutex
+}
+
+var _ ReplayStore = (*MemoryReplayStore)(nil)
+
+// memoryReplayPruneInterval is how often expired entries are removed from a MemoryReplayStore
+const memoryReplayPruneInterval = time.Minute
+
+// NewMemoryReplayStore creates an empty MemoryReplayStore
+func NewMemoryReplayStore() *MemoryReplayStore {
+	return &MemoryReplayStore{seen: map[string]time.Time{}}
+}
+
+// MarkSeen implements ReplayStore
+func (s *MemoryReplayStore) MarkSeen(_ context.Context, jti string, expires time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := currentTime()
+	if now.After(s.nextPrune) {
+		s.prune(now)
+		s.nextPrune = now.Add(memoryReplayPruneInterval)
+	}
+
+	exp, ok := s.seen[jti]
+	if ok && now.Before(exp) {
+		return true, nil
+	}
+
+	s.seen[jti] = expires
+
+	return false, nil
+}
+
+// Len is the number of token ids being remembered, including expired ones not yet pruned
+func (s *MemoryReplayStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.seen)
+}
+
+func (s *MemoryReplayStore) prune(now time.Time) {
+	for jti, exp := range s.seen {
+		if !now.Before(exp) {
+			delete(s.seen, jti)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type ginkgoReplayStore struct {
+	err error
+}
+
+func (s *ginkgoReplayStore) MarkSeen(context.Context, string, time.Time) (bool, error) {
+	return false, s.err
+}
+
+var _ = Describe("ReplayGuard", func() {
+	var (
+		pubK  ed25519.PublicKey
+		priK  ed25519.PrivateKey
+		guard *ReplayGuard
+		token string
+	)
+
+	BeforeEach(func() {
+		var err error
+		pubK, priK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		guard, err = NewReplayGuard(nil, time.Hour)
+		Expect(err).ToNot(HaveOccurred())
+
+		prov, err := NewProvisioningClaims(true, true, "s3cret", "", "", nil, "example.net", "", "", "", "", time.Hour)
+		Expect(err).ToNot(HaveOccurred())
+		token, err = SignToken(prov, priK)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Should require a ttl", func() {
+		_, err := NewReplayGuard(nil, 0)
+		Expect(err).To(MatchError("maximum ttl is required"))
+		Expect(ParseToken(token, &ProvisioningClaims{}, pubK, WithReplayGuard(nil))).To(MatchError("replay guard is required"))
+	})
+
+	It("Should accept a token only once", func() {
+		_, err := ParseProvisioningToken(token, pubK, WithReplayGuard(guard))
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = ParseProvisioningToken(token, pubK, WithReplayGuard(guard))
+		Expect(err).To(MatchError(ContainSubstring(ErrTokenReplayed.Error())))
+
+		err = ParseToken(token, &ProvisioningClaims{}, pubK, WithReplayGuard(guard))
+		Expect(err).To(MatchError(ErrTokenReplayed))
+		Expect(VerificationFailureReason(err)).To(Equal(FailureReplayed))
+	})
+
+	It("Should only record tokens that pass all other checks", func() {
+		Expect(ParseToken(token, &ProvisioningClaims{}, pubK, WithReplayGuard(guard), WithExpectedAudience("other"))).ToNot(Succeed())
+		Expect(ParseToken(token, &ProvisioningClaims{}, pubK, WithReplayGuard(guard))).To(Succeed())
+	})
+
+	It("Should detect concurrent presentation", func() {
+		var (
+			wg       sync.WaitGroup
+			accepted atomic.Int32
+		)
+
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if ParseToken(token, &ProvisioningClaims{}, pubK, WithReplayGuard(guard)) == nil {
+					accepted.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+
+		Expect(accepted.Load()).To(Equal(int32(1)))
+	})
+
+	It("Should require token ids", func() {
+		Expect(guard.Use(context.Background(), &jwt.MapClaims{})).To(MatchError(ErrTokenIDRequired))
+
+		prov := &ProvisioningClaims{}
+		Expect(guard.Use(context.Background(), prov)).To(MatchError(ErrTokenIDRequired))
+	})
+
+	It("Should accept a nonce only once", func() {
+		Expect(guard.UseNonce(context.Background(), "", time.Time{})).To(MatchError("nonce is required"))
+		Expect(guard.UseNonce(context.Background(), "n1", time.Time{})).To(Succeed())
+		Expect(guard.UseNonce(context.Background(), "n1", time.Now().Add(time.Minute))).To(MatchError(ErrNonceReplayed))
+		Expect(guard.UseNonce(context.Background(), "n2", time.Now().Add(time.Minute))).To(Succeed())
+
+		// nonces and token ids do not collide
+		prov, err := ParseProvisioningToken(token, pubK)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(guard.UseNonce(context.Background(), prov.ID, time.Time{})).To(Succeed())
+		Expect(guard.Use(context.Background(), prov)).To(Succeed())
+	})
+
+	It("Should handle store failures", func() {
+		guard, err := NewReplayGuard(&ginkgoReplayStore{err: errors.New("store down")}, time.Hour)
+		Expect(err).ToNot(HaveOccurred())
+
+		err = ParseToken(token, &ProvisioningClaims{}, pubK, WithReplayGuard(guard))
+		Expect(err).To(MatchError("could not check token replay: store down"))
+	})
+
+	Describe("MemoryReplayStore", func() {
+		It("Should forget expired tokens", func() {
+			store := NewMemoryReplayStore()
+			ctx := context.Background()
+
+			seen, err := store.MarkSeen(ctx, "expired", time.Now().Add(-time.Second))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(seen).To(BeFalse())
+			seen, err = store.MarkSeen(ctx, "expired", time.Now().Add(time.Hour))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(seen).To(BeFalse())
+			seen, err = store.MarkSeen(ctx, "expired", time.Now().Add(time.Hour))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(seen).To(BeTrue())
+
+			_, err = store.MarkSeen(ctx, "old", time.Now().Add(-time.Second))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(store.Len()).To(Equal(2))
+
+			store.nextPrune = time.Time{}
+			_, err = store.MarkSeen(ctx, "new", time.Now().Add(time.Hour))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(store.Len()).To(Equal(2))
+		})
+	})
+})
@@ -0,0 +1,159 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// TokenReport describes a token for operators, as shown by CLI tools and dashboards
+type TokenReport struct {
+	Purpose   Purpose   `json:"purpose"`
+	Identity  string    `json:"identity,omitempty"`
+	TokenID   string    `json:"jti,omitempty"`
+	Issuer    string    `json:"issuer,omitempty"`
+	Algorithm string    `json:"algorithm"`
+	PublicKey string    `json:"public_key,omitempty"`
+	IssuedAt  time.Time `json:"issued_at,omitempty"`
+	NotBefore time.Time `json:"not_before,omitempty"`
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+
+	// Expired indicates the token, or its issuer, has expired
+	Expired bool `json:"expired"`
+
+	// NotYetValid indicates the not before time of the token is in the future
+	NotYetValid bool `json:"not_yet_valid"`
+
+	// Permissions are the names of the permissions granted to client and server tokens
+	Permissions []string `json:"permissions,omitempty"`
+
+	// Chain is the verified chain of trust for tokens issued by org or chain issuers
+	Chain *ChainExplanation `json:"chain,omitempty"`
+
+	// SignatureValid indicates the token, and any issuer chain, was signed by the trusted key
+	SignatureValid bool   `json:"signature_valid"`
+	SignatureError string `json:"signature_error,omitempty"`
+
+	// Valid indicates the token passes ParseToken using the trusted key
+	Valid bool   `json:"valid"`
+	Error string `json:"error,omitempty"`
+}
+
+// DescribeToken reports on the contents and validity of token, trusted is any key accepted by ParseToken. An error
+// is only returned when the token can not be decoded, verification failures are recorded in the report
+func DescribeToken(token string, trusted any) (*TokenReport, error) {
+	purpose := TokenPurpose(token)
+	claims := newClaimsForPurpose(purpose)
+
+	t, err := parseUnverified(token, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	sc, ok := claims.(standardClaimsProvider)
+	if !ok {
+		return nil, fmt.Errorf("unsupported claims %T", claims)
+	}
+	std := sc.getStandardClaims()
+
+	report := &TokenReport{
+		Purpose:   purpose,
+		Identity:  claimsIdentity(claims),
+		TokenID:   std.ID,
+		Issuer:    std.Issuer,
+		Algorithm: t.Method.Alg(),
+		PublicKey: std.PublicKey,
+		ExpiresAt: std.ExpireTime(),
+	}
+
+	if std.IssuedAt != nil {
+		report.IssuedAt = std.IssuedAt.Time
+	}
+	if std.NotBefore != nil {
+		report.NotBefore = std.NotBefore.Time
+		report.NotYetValid = currentTime().Before(report.NotBefore)
+	}
+
+	report.Expired = !report.ExpiresAt.IsZero() && currentTime().After(report.ExpiresAt)
+
+	report.Permissions = claimsPermissions(claims)
+
+	edpk, isED25519 := trusted.(ed25519.PublicKey)
+	if isED25519 && (strings.HasPrefix(std.Issuer, OrgIssuerPrefix) || strings.HasPrefix(std.Issuer, ChainIssuerPrefix)) {
+		report.Chain, err = ExplainChain(token, edpk)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = reportSignature(token, claims, t.Method.Alg(), trusted)
+	report.SignatureValid = err == nil
+	if err != nil {
+		report.SignatureError = err.Error()
+	}
+
+	err = ParseToken(token, newClaimsForPurpose(purpose), trusted)
+	report.Valid = err == nil
+	if err != nil {
+		report.Error = err.Error()
+	}
+
+	return report, nil
+}
+
+// reportSignature verifies the signature of token, and the chain of trust leading to it, without validating claims
+func reportSignature(token string, claims jwt.Claims, alg string, trusted any) error {
+	if trusted == nil {
+		return fmt.Errorf("invalid public key")
+	}
+
+	key, _, err := (&parseOptions{}).verificationKey(alg, claims, trusted)
+	if err != nil {
+		return err
+	}
+
+	return verifyTokenSignature(token, key)
+}
+
+// claimsPermissions lists the permissions granted to client and server tokens
+func claimsPermissions(claims jwt.Claims) []string {
+	switch c := claims.(type) {
+	case *ClientIDClaims:
+		return grantedPermissions(c.Permissions)
+	case *ServerClaims:
+		return grantedPermissions(c.Permissions)
+	default:
+		return nil
+	}
+}
+
+// grantedPermissions lists the json names of the boolean permissions set in perms, a pointer to a permissions struct
+func grantedPermissions(perms any) []string {
+	pv := reflect.ValueOf(perms)
+	if pv.IsNil() {
+		return nil
+	}
+
+	pv = pv.Elem()
+	t := pv.Type()
+
+	var granted []string
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Type.Kind() != reflect.Bool || !pv.Field(i).Bool() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		granted = append(granted, name)
+	}
+
+	return granted
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DescribeToken", func() {
+	var (
+		pubK ed25519.PublicKey
+		priK ed25519.PrivateKey
+	)
+
+	BeforeEach(func() {
+		var err error
+		pubK, priK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Should fail for invalid tokens", func() {
+		_, err := DescribeToken("invalid", pubK)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Should describe valid tokens", func() {
+		client, err := NewClientIDClaims("up=bob", []string{"rpcutil"}, "", nil, "", "ginkgo", time.Hour, &ClientPermissions{FleetManagement: true, StreamsUser: true}, nil)
+		Expect(err).ToNot(HaveOccurred())
+		token, err := SignToken(client, priK)
+		Expect(err).ToNot(HaveOccurred())
+
+		report, err := DescribeToken(token, pubK)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.Purpose).To(Equal(ClientIDPurpose))
+		Expect(report.Identity).To(Equal("up=bob"))
+		Expect(report.TokenID).To(Equal(client.ID))
+		Expect(report.Issuer).To(Equal("ginkgo"))
+		Expect(report.Algorithm).To(Equal(algEdDSA))
+		Expect(report.ExpiresAt).To(BeTemporally("==", client.ExpiresAt.Time))
+		Expect(report.Expired).To(BeFalse())
+		Expect(report.Permissions).To(Equal([]string{"streams_user", "fleet_management"}))
+		Expect(report.Chain).To(BeNil())
+		Expect(report.SignatureValid).To(BeTrue())
+		Expect(report.Valid).To(BeTrue())
+		Expect(report.Error).To(BeEmpty())
+	})
+
+	It("Should report expired tokens and invalid signatures separately", func() {
+		client, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		client.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+		token, err := SignToken(client, priK)
+		Expect(err).ToNot(HaveOccurred())
+
+		report, err := DescribeToken(token, pubK)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.Expired).To(BeTrue())
+		Expect(report.SignatureValid).To(BeTrue())
+		Expect(report.Valid).To(BeFalse())
+		Expect(report.Error).To(ContainSubstring("expired"))
+
+		otherPubK, _, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		report, err = DescribeToken(token, otherPubK)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.SignatureValid).To(BeFalse())
+		Expect(report.SignatureError).To(Equal("ed25519: verification error"))
+
+		report, err = DescribeToken(token, loadRSAPubKey("testdata/rsa/signer-public.pem"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.SignatureError).To(Equal("ed25519 public key required"))
+	})
+
+	It("Should include the chain of trust", func() {
+		handlerPubK, handlerPriK, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		handler, err := NewClientIDClaims("aaa=login", nil, "", nil, "", "", time.Hour, &ClientPermissions{AuthenticationDelegator: true}, handlerPubK)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(handler.AddOrgIssuerData(priK)).To(Succeed())
+		handlerJWT, err := SignToken(handler, priK)
+		Expect(err).ToNot(HaveOccurred())
+
+		server, err := NewServerClaims("n1.example.net", []string{"choria"}, "", &ServerPermissions{Submission: true}, nil, pubK, "", time.Hour)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(server.AddChainIssuerData(handler, handlerPriK)).To(Succeed())
+		token, err := SignToken(server, handlerPriK, WithIssuerChain(handlerJWT))
+		Expect(err).ToNot(HaveOccurred())
+
+		report, err := DescribeToken(token, pubK)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.Purpose).To(Equal(ServerPurpose))
+		Expect(report.Permissions).To(Equal([]string{"submission"}))
+		Expect(report.Chain).ToNot(BeNil())
+		Expect(report.Chain.Valid).To(BeTrue())
+		Expect(report.Chain.Links).To(HaveLen(3))
+		Expect(report.SignatureValid).To(BeTrue())
+		Expect(report.Valid).To(BeTrue())
+
+		otherPubK, _, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		report, err = DescribeToken(token, otherPubK)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.Chain.Valid).To(BeFalse())
+		Expect(report.SignatureValid).To(BeFalse())
+		Expect(report.SignatureError).To(ContainSubstring("not signed by issuer"))
+	})
+})
@@ -0,0 +1,336 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var (
+	// ErrTokenRevoked indicates a token has been revoked
+	ErrTokenRevoked = errors.New("token has been revoked")
+
+	// ErrNotARevocationListToken indicates a token is not a revocation list
+	ErrNotARevocationListToken = errors.New("not a revocation list token")
+
+	// ErrRevocationsConflict indicates more than one revocation list or checker was given while parsing a token
+	ErrRevocationsConflict = errors.New("only one revocation list or checker can be used")
+)
+
+// RevocationChecker determines if a token has been revoked, implementations can be backed by any storage
+type RevocationChecker interface {
+	IsRevoked(claims jwt.Claims) (bool, error)
+}
+
+// ContextRevocationChecker is a RevocationChecker that performs remote lookups, ParseTokenContext passes its context
+// to IsRevokedContext so lookups respect the deadline of the caller
+type ContextRevocationChecker interface {
+	RevocationChecker
+	IsRevokedContext(ctx context.Context, claims jwt.Claims) (bool, error)
+}
+
+// RevocationEntry revokes a single token by token id, all tokens issued to an identity up to a point in time or
+// all tokens issued by a compromised chain issuer
+type RevocationEntry struct {
+	// TokenID is the token id, the jti claim, of a revoked token
+	TokenID string `json:"jti,omitempty"`
+
+	// Identity revokes all tokens for the caller id or identity issued at or before RevokedAt
+	Identity string `json:"identity,omitempty"`
+
+	// IssuerPublicKey is the hex encoded ed25519 public key of a chain issuer, revoking the chain issuer and every token issued below it
+	IssuerPublicKey string `json:"issuer_public_key,omitempty"`
+
+	// RevokedAt is when the revocation was made
+	RevokedAt time.Time `json:"revoked_at"`
+
+	// Reason is why the token was revoked
+	Reason string `json:"reason,omitempty"`
+}
+
+// RevocationList is a in-memory RevocationChecker holding revoked tokens and identities, safe for concurrent use
+type RevocationList struct {
+	entries []RevocationEntry
+	mu      sync.RWMutex
+}
+
+var _ RevocationChecker = (*RevocationList)(nil)
+
+// RevocationListClaims is a issuer signed document holding revocation entries
+//
+// The "purpose" claim should be set to RevocationListPurpose
+type RevocationListClaims struct {
+	// Revocations are the revoked tokens and identities
+	Revocations []RevocationEntry `json:"revocations"`
+
+	StandardClaims
+}
+
+// NewRevocationList creates a revocation list holding entries
+func NewRevocationList(entries ...RevocationEntry) (*RevocationList, error) {
+	rl := &RevocationList{}
+
+	for _, e := range entries {
+		err := rl.Add(e)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return rl, nil
+}
+
+// Add adds e to the list, entries must have exactly one of a token id or identity
+func (r *RevocationList) Add(e RevocationEntry) error {
+	err := e.validate()
+	if err != nil {
+		return err
+	}
+
+	if e.RevokedAt.IsZero() {
+		e.RevokedAt = currentTime().UTC()
+	}
+
+	e.IssuerPublicKey = strings.ToLower(e.IssuerPublicKey)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, existing := range r.entries {
+		switch {
+		case e.TokenID != "" && existing.TokenID == e.TokenID:
+			return nil
+
+		case e.IssuerPublicKey != "" && existing.IssuerPublicKey == e.IssuerPublicKey:
+			return nil
+
+		case e.Identity != "" && existing.Identity == e.Identity:
+			// a later identity revocation covers more tokens so it replaces the earlier one
+			if e.RevokedAt.After(existing.RevokedAt) {
+				r.entries[i] = e
+			}
+			return nil
+		}
+	}
+
+	r.entries = append(r.entries, e)
+
+	return nil
+}
+
+// RevokeTokenID revokes the token with token id jti
+func (r *RevocationList) RevokeTokenID(jti string, reason string) error {
+	return r.Add(RevocationEntry{TokenID: jti, Reason: reason})
+}
+
+// RevokeIdentity revokes all tokens issued to identity up to now
+func (r *RevocationList) RevokeIdentity(identity string, reason string) error {
+	return r.Add(RevocationEntry{Identity: identity, Reason: reason})
+}
+
+// RevokeIssuerPublicKey revokes the chain issuer holding pk along with every token issued by it or by chain issuers below it
+func (r *RevocationList) RevokeIssuerPublicKey(pk ed25519.PublicKey, reason string) error {
+	return r.Add(RevocationEntry{IssuerPublicKey: hex.EncodeToString(pk), Reason: reason})
+}
+
+// Merge adds all the entries from other to the list
+func (r *RevocationList) Merge(other *RevocationList) error {
+	if other == nil {
+		return nil
+	}
+
+	for _, e := range other.Entries() {
+		err := r.Add(e)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Entries returns a copy of the entries in the list
+func (r *RevocationList) Entries() []RevocationEntry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]RevocationEntry{}, r.entries...)
+}
+
+// Len is the number of entries in the list
+func (r *RevocationList) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.entries)
+}
+
+// IsRevoked determines if claims are revoked by their token id, identity or by the public key of any chain issuer they hold
+func (r *RevocationList) IsRevoked(claims jwt.Claims) (bool, error) {
+	var jti string
+	var iat *jwt.NumericDate
+	var keys map[string]struct{}
+
+	if sc, ok := claims.(standardClaimsProvider); ok {
+		jti = sc.getStandardClaims().ID
+		iat = sc.getStandardClaims().IssuedAt
+		keys = chainPublicKeys(sc.getStandardClaims())
+	}
+
+	identity := claimsIdentity(claims)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, e := range r.entries {
+		switch {
+		case e.TokenID != "" && e.TokenID == jti:
+			return true, nil
+
+		case e.Identity != "" && e.Identity == identity:
+			if iat == nil || !iat.After(e.RevokedAt) {
+				return true, nil
+			}
+
+		case e.IssuerPublicKey != "":
+			if _, ok := keys[e.IssuerPublicKey]; ok {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// chainPublicKeys are the public keys of std and of every chain issuer above it, found in the issuer and any embedded issuer chain
+func chainPublicKeys(std *StandardClaims) map[string]struct{} {
+	keys := make(map[string]struct{})
+
+	add := func(c *StandardClaims) {
+		if c.PublicKey != "" {
+			keys[strings.ToLower(c.PublicKey)] = struct{}{}
+		}
+
+		if strings.HasPrefix(c.Issuer, ChainIssuerPrefix) {
+			_, pk, found := strings.Cut(strings.TrimPrefix(c.Issuer, ChainIssuerPrefix), ".")
+			if found && pk != "" {
+				keys[strings.ToLower(pk)] = struct{}{}
+			}
+		}
+	}
+
+	add(std)
+
+	for _, token := range std.IssuerChain {
+		issuer := &ClientIDClaims{}
+		_, err := parseUnverified(token, issuer)
+		if err == nil {
+			add(&issuer.StandardClaims)
+		}
+	}
+
+	return keys
+}
+
+func (e *RevocationEntry) validate() error {
+	if e.IssuerPublicKey != "" {
+		if e.TokenID != "" || e.Identity != "" {
+			return fmt.Errorf("revocation entries cannot combine an issuer public key with a token id or identity")
+		}
+
+		pk, err := hex.DecodeString(e.IssuerPublicKey)
+		if err != nil {
+			return fmt.Errorf("invalid issuer public key: %w", err)
+		}
+
+		err = ValidateEd25519PublicKey(pk)
+		if err != nil {
+			return fmt.Errorf("invalid issuer public key: %w", err)
+		}
+
+		return nil
+	}
+
+	if e.TokenID == "" && e.Identity == "" {
+		return fmt.Errorf("revocation entries require a token id, identity or issuer public key")
+	}
+
+	if e.TokenID != "" && e.Identity != "" {
+		return fmt.Errorf("revocation entries cannot have both a token id and identity")
+	}
+
+	return nil
+}
+
+// Validate checks that every revocation entry is valid
+func (c *RevocationListClaims) Validate() error {
+	if c.Purpose != RevocationListPurpose {
+		return ErrNotARevocationListToken
+	}
+
+	for i := range c.Revocations {
+		err := c.Revocations[i].validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SignRevocationList creates a signed revocation list document that can be loaded using LoadRevocationList
+func SignRevocationList(rl *RevocationList, issuer string, validity time.Duration, signer any, opts ...ClaimsOption) (string, error) {
+	if rl == nil {
+		return "", fmt.Errorf("revocation list is required")
+	}
+
+	stdClaims, err := newStandardClaims(issuer, RevocationListPurpose, validity, false, opts...)
+	if err != nil {
+		return "", err
+	}
+
+	return SignToken(&RevocationListClaims{Revocations: rl.Entries(), StandardClaims: *stdClaims}, signer)
+}
+
+// LoadRevocationList verifies a revocation list document using pk and creates a revocation list from it
+func LoadRevocationList(doc string, pk any, opts ...ParseOption) (*RevocationList, error) {
+	claims, err := parseRevocationList(doc, pk, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewRevocationList(claims.Revocations...)
+}
+
+// parseRevocationList verifies a revocation list document using pk
+func parseRevocationList(doc string, pk any, opts ...ParseOption) (*RevocationListClaims, error) {
+	claims := &RevocationListClaims{}
+	err := ParseToken(strings.TrimSpace(doc), claims, pk, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse revocation list: %w", err)
+	}
+
+	return claims, nil
+}
+
+// LoadRevocationListFile verifies the revocation list document in file using pk and creates a revocation list from it
+func LoadRevocationListFile(file string, pk any, opts ...ParseOption) (*RevocationList, error) {
+	doc, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read revocation list: %w", err)
+	}
+
+	return LoadRevocationList(string(doc), pk, opts...)
+}
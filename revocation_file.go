@@ -0,0 +1,108 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !js
+
+package tokens
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// FileRevocationChecker is a RevocationChecker backed by a signed revocation list document in a file,
+// the file is loaded again whenever it changes or once the loaded document expired
+type FileRevocationChecker struct {
+	file    string
+	pk      any
+	opts    []ParseOption
+	popts   *parseOptions
+	list    *RevocationList
+	modTime time.Time
+	size    int64
+	expires time.Time
+	mu      sync.Mutex
+}
+
+var _ RevocationChecker = (*FileRevocationChecker)(nil)
+
+// NewFileRevocationChecker creates a checker using the revocation list document in file verified using pk
+func NewFileRevocationChecker(file string, pk any, opts ...ParseOption) (*FileRevocationChecker, error) {
+	if file == "" {
+		return nil, fmt.Errorf("revocation list file is required")
+	}
+
+	if pk == nil {
+		return nil, fmt.Errorf("invalid public key")
+	}
+
+	popts, err := newParseOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &FileRevocationChecker{file: file, pk: pk, opts: opts, popts: popts}
+
+	_, err = c.current()
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// IsRevoked determines if claims are revoked by the revocation list in the file, an error is returned when the
+// file changed and could not be loaded
+func (c *FileRevocationChecker) IsRevoked(claims jwt.Claims) (bool, error) {
+	list, err := c.current()
+	if err != nil {
+		return false, err
+	}
+
+	return list.IsRevoked(claims)
+}
+
+// current loads the revocation list when the file changed since it was last loaded or the loaded document expired,
+// an expired document is therefore rejected rather than used until the file changes
+func (c *FileRevocationChecker) current() (*RevocationList, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stat, err := os.Stat(c.file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read revocation list: %w", err)
+	}
+
+	if c.list != nil && stat.ModTime().Equal(c.modTime) && stat.Size() == c.size && (c.expires.IsZero() || c.popts.now().Before(c.expires)) {
+		return c.list, nil
+	}
+
+	c.list = nil
+
+	doc, err := os.ReadFile(c.file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read revocation list: %w", err)
+	}
+
+	claims, err := parseRevocationList(string(doc), c.pk, c.opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := NewRevocationList(claims.Revocations...)
+	if err != nil {
+		return nil, err
+	}
+
+	c.list = list
+	c.modTime = stat.ModTime()
+	c.size = stat.Size()
+	c.expires = claims.ExpireTime()
+
+	return list, nil
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !js
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FileRevocationChecker", func() {
+	var (
+		pubK ed25519.PublicKey
+		priK ed25519.PrivateKey
+		file string
+	)
+
+	BeforeEach(func() {
+		var err error
+		pubK, priK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		file = filepath.Join(GinkgoT().TempDir(), "revoked.jwt")
+	})
+
+	writeList := func(entries ...RevocationEntry) {
+		rl, err := NewRevocationList(entries...)
+		Expect(err).ToNot(HaveOccurred())
+		doc, err := SignRevocationList(rl, "ginkgo", time.Hour, priK)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(os.WriteFile(file, []byte(doc), 0600)).To(Succeed())
+	}
+
+	It("Should require a valid file", func() {
+		_, err := NewFileRevocationChecker("", pubK)
+		Expect(err).To(MatchError("revocation list file is required"))
+
+		_, err = NewFileRevocationChecker(file, pubK)
+		Expect(err).To(MatchError(os.ErrNotExist))
+
+		writeList(RevocationEntry{TokenID: "jti1"})
+		_, err = NewFileRevocationChecker(file, nil)
+		Expect(err).To(MatchError("invalid public key"))
+
+		otherPubK, _, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = NewFileRevocationChecker(file, otherPubK)
+		Expect(err).To(MatchError(ContainSubstring("verification error")))
+	})
+
+	It("Should reload the file when it changes", func() {
+		bob, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		writeList(RevocationEntry{TokenID: "jti1"})
+
+		checker, err := NewFileRevocationChecker(file, pubK)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(checker.IsRevoked(bob)).To(BeFalse())
+
+		writeList(RevocationEntry{TokenID: "jti1"}, RevocationEntry{TokenID: bob.ID})
+		Expect(os.Chtimes(file, time.Now().Add(time.Minute), time.Now().Add(time.Minute))).To(Succeed())
+		Expect(checker.IsRevoked(bob)).To(BeTrue())
+
+		token, err := SignToken(bob, priK)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = ParseClientIDToken(token, pubK, true, WithRevocationChecker(checker))
+		Expect(err).To(MatchError(ErrTokenRevoked))
+
+		Expect(os.WriteFile(file, []byte("invalid"), 0600)).To(Succeed())
+		_, err = checker.IsRevoked(bob)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Should not use the loaded list once it expired", func() {
+		bob, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		writeList(RevocationEntry{TokenID: "jti1"})
+
+		now := time.Now()
+		checker, err := NewFileRevocationChecker(file, pubK, WithVerificationClock(func() time.Time { return now }))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(checker.IsRevoked(bob)).To(BeFalse())
+
+		now = now.Add(2 * time.Hour)
+		_, err = checker.IsRevoked(bob)
+		Expect(err).To(MatchError(ContainSubstring("expired")))
+	})
+})
@@ -0,0 +1,252 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type ginkgoRevocationChecker struct {
+	revoked bool
+	err     error
+}
+
+func (c *ginkgoRevocationChecker) IsRevoked(jwt.Claims) (bool, error) {
+	return c.revoked, c.err
+}
+
+var _ = Describe("RevocationList", func() {
+	var (
+		pubK ed25519.PublicKey
+		priK ed25519.PrivateKey
+	)
+
+	BeforeEach(func() {
+		var err error
+		pubK, priK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	clientClaims := func(caller string) *ClientIDClaims {
+		claims, err := NewClientIDClaims(caller, nil, "", nil, "", "", time.Hour, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		return claims
+	}
+
+	Describe("Add", func() {
+		It("Should validate and deduplicate entries", func() {
+			rl, err := NewRevocationList()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(rl.Add(RevocationEntry{})).To(MatchError("revocation entries require a token id, identity or issuer public key"))
+			Expect(rl.Add(RevocationEntry{TokenID: "x", Identity: "y"})).To(MatchError("revocation entries cannot have both a token id and identity"))
+
+			Expect(rl.RevokeTokenID("jti1", "leaked")).To(Succeed())
+			Expect(rl.RevokeTokenID("jti1", "again")).To(Succeed())
+			Expect(rl.Len()).To(Equal(1))
+			Expect(rl.Entries()[0].Reason).To(Equal("leaked"))
+			Expect(rl.Entries()[0].RevokedAt).ToNot(BeZero())
+
+			early := time.Now().Add(-time.Hour).UTC()
+			Expect(rl.Add(RevocationEntry{Identity: "up=bob", RevokedAt: early})).To(Succeed())
+			Expect(rl.RevokeIdentity("up=bob", "left")).To(Succeed())
+			Expect(rl.Add(RevocationEntry{Identity: "up=bob", RevokedAt: early.Add(-time.Hour)})).To(Succeed())
+			Expect(rl.Len()).To(Equal(2))
+			Expect(rl.Entries()[1].Reason).To(Equal("left"))
+		})
+	})
+
+	Describe("Merge", func() {
+		It("Should merge lists", func() {
+			a, err := NewRevocationList(RevocationEntry{TokenID: "jti1"})
+			Expect(err).ToNot(HaveOccurred())
+			b, err := NewRevocationList(RevocationEntry{TokenID: "jti1"}, RevocationEntry{TokenID: "jti2"})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(a.Merge(b)).To(Succeed())
+			Expect(a.Merge(nil)).To(Succeed())
+			Expect(a.Len()).To(Equal(2))
+		})
+	})
+
+	Describe("IsRevoked", func() {
+		It("Should match token ids and identities", func() {
+			bob := clientClaims("up=bob")
+			alice := clientClaims("up=alice")
+
+			rl, err := NewRevocationList(RevocationEntry{TokenID: bob.ID})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(rl.IsRevoked(bob)).To(BeTrue())
+			Expect(rl.IsRevoked(alice)).To(BeFalse())
+
+			Expect(rl.Add(RevocationEntry{Identity: "up=alice", RevokedAt: time.Now().Add(time.Minute)})).To(Succeed())
+			Expect(rl.IsRevoked(alice)).To(BeTrue())
+
+			alice.IssuedAt = jwt.NewNumericDate(time.Now().Add(time.Hour))
+			Expect(rl.IsRevoked(alice)).To(BeFalse())
+		})
+	})
+
+	Describe("RevokeIssuerPublicKey", func() {
+		It("Should validate issuer public keys", func() {
+			rl, err := NewRevocationList()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(rl.Add(RevocationEntry{IssuerPublicKey: "x"})).To(MatchError(ContainSubstring("invalid issuer public key")))
+			Expect(rl.Add(RevocationEntry{IssuerPublicKey: hex.EncodeToString(pubK), TokenID: "x"})).To(MatchError("revocation entries cannot combine an issuer public key with a token id or identity"))
+
+			Expect(rl.RevokeIssuerPublicKey(pubK, "compromised")).To(Succeed())
+			Expect(rl.Add(RevocationEntry{IssuerPublicKey: strings.ToUpper(hex.EncodeToString(pubK))})).To(Succeed())
+			Expect(rl.Len()).To(Equal(1))
+		})
+
+		It("Should revoke every token issued below a chain issuer", func() {
+			handlerPubK, handlerPriK, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+			handler, err := NewClientIDClaims("aaa=login", nil, "", nil, "", "", time.Hour, &ClientPermissions{AuthenticationDelegator: true}, handlerPubK)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(handler.AddOrgIssuerData(priK)).To(Succeed())
+			handlerJWT, err := SignToken(handler, priK)
+			Expect(err).ToNot(HaveOccurred())
+
+			userPubK, _, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+			user, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, userPubK)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(user.AddChainIssuerData(handler, handlerPriK)).To(Succeed())
+			token, err := SignToken(user, handlerPriK, WithIssuerChain(handlerJWT))
+			Expect(err).ToNot(HaveOccurred())
+
+			rl, err := NewRevocationList()
+			Expect(err).ToNot(HaveOccurred())
+			_, err = ParseClientIDToken(token, pubK, true, WithRevocations(rl))
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(rl.RevokeIssuerPublicKey(handlerPubK, "compromised")).To(Succeed())
+
+			// published as a signed document
+			doc, err := SignRevocationList(rl, "ginkgo", time.Hour, priK)
+			Expect(err).ToNot(HaveOccurred())
+			loaded, err := LoadRevocationList(doc, pubK)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = ParseClientIDToken(token, pubK, true, WithRevocations(loaded))
+			Expect(err).To(MatchError(ErrTokenRevoked))
+			_, err = ParseClientIDToken(handlerJWT, pubK, true, WithRevocations(loaded))
+			Expect(err).To(MatchError(ErrTokenRevoked))
+
+			// the org issuer keeps working for other tokens
+			_, err = ParseClientIDToken(handlerJWT, pubK, true)
+			Expect(err).ToNot(HaveOccurred())
+			other, err := SignToken(clientClaims("up=alice"), priK)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = ParseClientIDToken(other, pubK, true, WithRevocations(loaded))
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+
+	Describe("Documents", func() {
+		It("Should sign and load revocation lists", func() {
+			rl, err := NewRevocationList(RevocationEntry{TokenID: "jti1", Reason: "leaked"}, RevocationEntry{Identity: "up=bob"})
+			Expect(err).ToNot(HaveOccurred())
+
+			doc, err := SignRevocationList(rl, "ginkgo", time.Hour, priK)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(TokenPurpose(doc)).To(Equal(RevocationListPurpose))
+
+			otherPubK, _, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = LoadRevocationList(doc, otherPubK)
+			Expect(err).To(MatchError(ContainSubstring("verification error")))
+
+			file := filepath.Join(GinkgoT().TempDir(), "revoked.jwt")
+			Expect(os.WriteFile(file, []byte(doc+"\n"), 0600)).To(Succeed())
+
+			loaded, err := LoadRevocationListFile(file, pubK)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(loaded.Len()).To(Equal(2))
+			Expect(loaded.Entries()[0].Reason).To(Equal("leaked"))
+			Expect(loaded.Entries()[0].RevokedAt.Equal(rl.Entries()[0].RevokedAt)).To(BeTrue())
+		})
+
+		It("Should only load revocation lists", func() {
+			token, err := SignToken(clientClaims("up=bob"), priK)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = LoadRevocationList(token, pubK)
+			Expect(err).To(MatchError(ErrNotARevocationListToken))
+		})
+	})
+
+	Describe("WithRevocations", func() {
+		It("Should reject revoked tokens", func() {
+			bob := clientClaims("up=bob")
+			token, err := SignToken(bob, priK)
+			Expect(err).ToNot(HaveOccurred())
+
+			rl, err := NewRevocationList()
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = ParseClientIDToken(token, pubK, true, WithRevocations(rl))
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(rl.RevokeTokenID(bob.ID, "leaked")).To(Succeed())
+
+			_, err = ParseClientIDToken(token, pubK, true, WithRevocations(rl))
+			Expect(err).To(MatchError(ErrTokenRevoked))
+
+			_, err = ParseClientIDToken(token, pubK, true, WithRevocations(nil))
+			Expect(err).To(MatchError("could not parse client id token: revocation list is required"))
+		})
+	})
+
+	Describe("WithRevocationChecker", func() {
+		It("Should consult the checker", func() {
+			token, err := SignToken(clientClaims("up=bob"), priK)
+			Expect(err).ToNot(HaveOccurred())
+
+			checker := &ginkgoRevocationChecker{}
+			_, err = ParseClientIDToken(token, pubK, true, WithRevocationChecker(checker))
+			Expect(err).ToNot(HaveOccurred())
+
+			checker.revoked = true
+			_, err = ParseClientIDToken(token, pubK, true, WithRevocationChecker(checker))
+			Expect(err).To(MatchError(ErrTokenRevoked))
+
+			checker.err = errors.New("backend unavailable")
+			_, err = ParseClientIDToken(token, pubK, true, WithRevocationChecker(checker))
+			Expect(err).To(MatchError("could not parse client id token: could not check revocation status: backend unavailable"))
+
+			_, err = ParseClientIDToken(token, pubK, true, WithRevocationChecker(nil))
+			Expect(err).To(MatchError("could not parse client id token: revocation checker is required"))
+		})
+
+		It("Should not combine revocation lists and checkers", func() {
+			token, err := SignToken(clientClaims("up=bob"), priK)
+			Expect(err).ToNot(HaveOccurred())
+
+			rl, err := NewRevocationList()
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = ParseClientIDToken(token, pubK, true, WithRevocations(rl), WithRevocationChecker(&ginkgoRevocationChecker{}))
+			Expect(err).To(MatchError(ErrRevocationsConflict))
+
+			_, err = ParseClientIDToken(token, pubK, true, WithRevocationChecker(&ginkgoRevocationChecker{}), WithRevocations(rl))
+			Expect(err).To(MatchError(ErrRevocationsConflict))
+		})
+	})
+})
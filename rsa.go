@@ -0,0 +1,155 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultMinimumRSAKeySize is the smallest RSA key, in bits, accepted by default
+	DefaultMinimumRSAKeySize = 2048
+
+	// RecommendedRSAKeySize is the recommended size, in bits, for new RSA keys
+	RecommendedRSAKeySize = 4096
+
+	// minimumRSAExponent is the smallest public exponent we consider safe to use
+	minimumRSAExponent = 65537
+)
+
+var (
+	// ErrWeakRSAKey indicates a RSA key smaller than the configured minimum size
+	ErrWeakRSAKey = errors.New("rsa key is too small")
+
+	// ErrWeakRSAExponent indicates a RSA key with a known weak public exponent
+	ErrWeakRSAExponent = errors.New("rsa key has a weak public exponent")
+
+	// ErrRSATokenSunset indicates a RSA signed token was presented after the RSA sunset date
+	ErrRSATokenSunset = errors.New("rsa signed tokens are no longer accepted")
+
+	minimumRSAKeySize = DefaultMinimumRSAKeySize
+)
+
+// SetMinimumRSAKeySize sets the smallest RSA key, in bits, that will be accepted when signing and verifying tokens
+func SetMinimumRSAKeySize(bits int) error {
+	if bits < 1024 {
+		return fmt.Errorf("minimum rsa key size cannot be less than 1024 bits")
+	}
+
+	minimumRSAKeySize = bits
+
+	return nil
+}
+
+func validateRSAPublicKey(pk *rsa.PublicKey) error {
+	if pk == nil || pk.N == nil {
+		return fmt.Errorf("invalid rsa public key")
+	}
+
+	if pk.N.BitLen() < minimumRSAKeySize {
+		return fmt.Errorf("%w: %d bits while %d is required", ErrWeakRSAKey, pk.N.BitLen(), minimumRSAKeySize)
+	}
+
+	if pk.E < minimumRSAExponent || pk.E%2 == 0 {
+		return fmt.Errorf("%w: %d", ErrWeakRSAExponent, pk.E)
+	}
+
+	return nil
+}
+
+// RSASunsetPhase indicates where in the RSA sunset process a verifier is
+type RSASunsetPhase string
+
+const (
+	// RSASunsetNotice is the phase before the warning date, RSA tokens are accepted and noted
+	RSASunsetNotice RSASunsetPhase = "notice"
+
+	// RSASunsetWarning is the phase between the warning and reject dates, RSA tokens are accepted with a warning
+	RSASunsetWarning RSASunsetPhase = "warning"
+
+	// RSASunsetRejected is the phase after the reject date, RSA tokens are rejected
+	RSASunsetRejected RSASunsetPhase = "rejected"
+)
+
+// RSASunsetPolicy configures the phased removal of RSA signed tokens in favour of ed25519 ones
+type RSASunsetPolicy struct {
+	// WarnAfter is the time after which accepted RSA tokens are logged as warnings, zero to never warn
+	WarnAfter time.Time
+
+	// RejectAfter is the time after which RSA tokens are rejected, zero to never reject
+	RejectAfter time.Time
+
+	// Log receives messages about RSA tokens being seen, can be nil
+	Log *logrus.Entry
+
+	// Notify is called for every RSA token seen along with the phase, suitable for updating metrics
+	Notify func(phase RSASunsetPhase, claims jwt.Claims)
+}
+
+func (p *RSASunsetPolicy) validate() error {
+	if !p.WarnAfter.IsZero() && !p.RejectAfter.IsZero() && p.RejectAfter.Before(p.WarnAfter) {
+		return fmt.Errorf("rsa sunset reject time cannot be before the warn time")
+	}
+
+	return nil
+}
+
+// Phase determines the phase of the sunset process at time t
+func (p *RSASunsetPolicy) Phase(t time.Time) RSASunsetPhase {
+	switch {
+	case !p.RejectAfter.IsZero() && !t.Before(p.RejectAfter):
+		return RSASunsetRejected
+	case !p.WarnAfter.IsZero() && !t.Before(p.WarnAfter):
+		return RSASunsetWarning
+	default:
+		return RSASunsetNotice
+	}
+}
+
+// observe records a RSA signed token being seen at now and determines if it may be used
+func (p *RSASunsetPolicy) observe(claims jwt.Claims, now time.Time) error {
+	phase := p.Phase(now)
+
+	if p.Notify != nil {
+		p.Notify(phase, claims)
+	}
+
+	if phase == RSASunsetRejected {
+		if p.Log != nil {
+			p.Log.Errorf("Rejecting RSA signed token for %q, RSA tokens are not accepted since %v", claimsIdentity(claims), p.RejectAfter)
+		}
+
+		return ErrRSATokenSunset
+	}
+
+	return nil
+}
+
+// accepted logs the acceptance of a RSA signed token that passed all validation at now
+func (p *RSASunsetPolicy) accepted(claims jwt.Claims, now time.Time) {
+	if p.Log == nil {
+		return
+	}
+
+	id := claimsIdentity(claims)
+
+	switch p.Phase(now) {
+	case RSASunsetWarning:
+		if p.RejectAfter.IsZero() {
+			p.Log.Warnf("Accepted deprecated RSA signed token for %q, please migrate to ed25519", id)
+		} else {
+			p.Log.Warnf("Accepted deprecated RSA signed token for %q, RSA tokens will be rejected after %v", id, p.RejectAfter)
+		}
+
+	case RSASunsetNotice:
+		p.Log.Infof("Accepted RSA signed token for %q", id)
+	}
+}
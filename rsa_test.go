@@ -0,0 +1,172 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/sirupsen/logrus"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RSA", func() {
+	AfterEach(func() {
+		Expect(SetMinimumRSAKeySize(DefaultMinimumRSAKeySize)).To(Succeed())
+	})
+
+	Describe("SetMinimumRSAKeySize", func() {
+		It("Should not allow very small keys", func() {
+			Expect(SetMinimumRSAKeySize(512)).To(MatchError("minimum rsa key size cannot be less than 1024 bits"))
+			Expect(minimumRSAKeySize).To(Equal(DefaultMinimumRSAKeySize))
+		})
+	})
+
+	Describe("Weak keys", func() {
+		var weak *rsa.PrivateKey
+		var claims *StandardClaims
+
+		BeforeEach(func() {
+			var err error
+			weak, err = rsa.GenerateKey(rand.Reader, 1024)
+			Expect(err).ToNot(HaveOccurred())
+
+			claims, err = newStandardClaims("ginkgo", ProvisioningPurpose, time.Hour, false)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("Should refuse to sign using small keys", func() {
+			_, err := SignToken(claims, weak)
+			Expect(err).To(MatchError(ErrWeakRSAKey))
+
+			Expect(SetMinimumRSAKeySize(1024)).To(Succeed())
+			_, err = SignToken(claims, weak)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("Should refuse to verify using small keys", func() {
+			Expect(SetMinimumRSAKeySize(1024)).To(Succeed())
+			token, err := SignToken(claims, weak)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ParseToken(token, &StandardClaims{}, &weak.PublicKey)).To(Succeed())
+
+			Expect(SetMinimumRSAKeySize(DefaultMinimumRSAKeySize)).To(Succeed())
+			Expect(ParseToken(token, &StandardClaims{}, &weak.PublicKey)).To(MatchError(ErrWeakRSAKey))
+		})
+
+		It("Should refuse weak exponents", func() {
+			token, err := SignToken(claims, loadRSAPriKey("testdata/rsa/signer-key.pem"))
+			Expect(err).ToNot(HaveOccurred())
+
+			pubK := loadRSAPubKey("testdata/rsa/signer-public.pem")
+			pubK.E = 3
+			Expect(ParseToken(token, &StandardClaims{}, pubK)).To(MatchError(ErrWeakRSAExponent))
+		})
+	})
+
+	Describe("RSA Sunset", func() {
+		var token string
+		var seen []RSASunsetPhase
+
+		BeforeEach(func() {
+			claims, err := newStandardClaims("ginkgo", ProvisioningPurpose, time.Hour, false)
+			Expect(err).ToNot(HaveOccurred())
+			token, err = SignToken(claims, loadRSAPriKey("testdata/rsa/signer-key.pem"))
+			Expect(err).ToNot(HaveOccurred())
+			seen = nil
+		})
+
+		notify := func(phase RSASunsetPhase, _ jwt.Claims) {
+			seen = append(seen, phase)
+		}
+
+		It("Should validate the policy", func() {
+			err := ParseToken(token, &StandardClaims{}, loadRSAPubKey("testdata/rsa/signer-public.pem"), WithRSASunsetPolicy(RSASunsetPolicy{
+				WarnAfter:   time.Now(),
+				RejectAfter: time.Now().Add(-time.Hour),
+			}))
+			Expect(err).To(MatchError("rsa sunset reject time cannot be before the warn time"))
+		})
+
+		It("Should determine the correct phase", func() {
+			now := time.Now()
+			policy := RSASunsetPolicy{WarnAfter: now.Add(time.Hour), RejectAfter: now.Add(2 * time.Hour)}
+			Expect(policy.Phase(now)).To(Equal(RSASunsetNotice))
+			Expect(policy.Phase(now.Add(time.Hour))).To(Equal(RSASunsetWarning))
+			Expect(policy.Phase(now.Add(3 * time.Hour))).To(Equal(RSASunsetRejected))
+			Expect((&RSASunsetPolicy{}).Phase(now)).To(Equal(RSASunsetNotice))
+		})
+
+		It("Should accept and note tokens before the reject date", func() {
+			claims := &StandardClaims{}
+			err := ParseToken(token, claims, loadRSAPubKey("testdata/rsa/signer-public.pem"), WithRSASunsetPolicy(RSASunsetPolicy{
+				WarnAfter:   time.Now().Add(time.Hour),
+				RejectAfter: time.Now().Add(2 * time.Hour),
+				Notify:      notify,
+			}))
+			Expect(err).ToNot(HaveOccurred())
+
+			err = ParseToken(token, claims, loadRSAPubKey("testdata/rsa/signer-public.pem"), WithRSASunsetPolicy(RSASunsetPolicy{
+				WarnAfter: time.Now().Add(-time.Hour),
+				Notify:    notify,
+			}))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(seen).To(Equal([]RSASunsetPhase{RSASunsetNotice, RSASunsetWarning}))
+		})
+
+		It("Should reject tokens after the reject date", func() {
+			err := ParseToken(token, &StandardClaims{}, loadRSAPubKey("testdata/rsa/signer-public.pem"), WithRSASunsetPolicy(RSASunsetPolicy{
+				RejectAfter: time.Now().Add(-time.Hour),
+				Notify:      notify,
+			}))
+			Expect(err).To(MatchError(ErrRSATokenSunset))
+			Expect(seen).To(Equal([]RSASunsetPhase{RSASunsetRejected}))
+		})
+
+		It("Should only log tokens as accepted once they are valid", func() {
+			out := &bytes.Buffer{}
+			logger := logrus.New()
+			logger.SetOutput(out)
+
+			claims, err := newStandardClaims("ginkgo", ProvisioningPurpose, time.Hour, false)
+			Expect(err).ToNot(HaveOccurred())
+			claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+			expired, err := SignToken(claims, loadRSAPriKey("testdata/rsa/signer-key.pem"))
+			Expect(err).ToNot(HaveOccurred())
+
+			policy := RSASunsetPolicy{WarnAfter: time.Now().Add(-time.Hour), Log: logrus.NewEntry(logger)}
+
+			err = ParseToken(expired, &StandardClaims{}, loadRSAPubKey("testdata/rsa/signer-public.pem"), WithRSASunsetPolicy(policy))
+			Expect(err).To(HaveOccurred())
+			Expect(out.String()).To(BeEmpty())
+
+			err = ParseToken(token, &StandardClaims{}, loadRSAPubKey("testdata/rsa/signer-public.pem"), WithRSASunsetPolicy(policy))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(out.String()).To(ContainSubstring("Accepted deprecated RSA signed token"))
+		})
+
+		It("Should not affect ed25519 tokens", func() {
+			pubK, priK, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+			claims, err := newStandardClaims("ginkgo", ProvisioningPurpose, time.Hour, false)
+			Expect(err).ToNot(HaveOccurred())
+			token, err := SignToken(claims, priK)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = ParseToken(token, &StandardClaims{}, pubK, WithRSASunsetPolicy(RSASunsetPolicy{
+				RejectAfter: time.Now().Add(-time.Hour),
+				Notify:      notify,
+			}))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(seen).To(BeEmpty())
+		})
+	})
+})
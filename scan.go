@@ -0,0 +1,370 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// The functions here extract single fields from tokens without decoding the full claims, they run for every inbound
+// connection so they avoid allocating. Anything unusual, like escaped strings, codecs or compression, is handed to
+// the regular decoder so results always match it
+
+// knownAlgorithms are returned by the scanner to avoid allocating a string for the algorithm
+var knownAlgorithms = []string{algEdDSA, algRS256, algRS384, algRS512, "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "HS256", "HS384", "HS512", "none"}
+
+// knownPurposes are returned by the scanner to avoid allocating a string for built in purposes
+var knownPurposes = []Purpose{ClientIDPurpose, ServerPurpose, ProvisioningPurpose, ServiceAccountPurpose, StreamPurpose, RegistrationPurpose, SchedulerPurpose, ConfigPurpose, ObserverPurpose, TrustConfigPurpose, RevocationListPurpose, KnownIssuersPurpose, FederationTrustPurpose, TrustBundlePurpose}
+
+var scanBuffers = sync.Pool{New: func() any { b := make([]byte, 0, 2048); return &b }}
+
+var b64URLAlphabet = func() [256]byte {
+	var t [256]byte
+	for i := range t {
+		t[i] = 0xff
+	}
+
+	for i, c := range "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_" {
+		t[c] = byte(i)
+	}
+
+	return t
+}()
+
+// scanPurpose extracts the purpose of token, ok is false when the regular decoder has to be used
+func scanPurpose[T string | []byte](token T) (purpose Purpose, ok bool) {
+	hdr, payload, ok := tokenSegments(token)
+	if !ok {
+		return UnknownPurpose, false
+	}
+
+	bp := scanBuffers.Get().(*[]byte)
+	defer scanBuffers.Put(bp)
+
+	buf, ok := b64URLDecode((*bp)[:0], hdr)
+	if !ok {
+		return UnknownPurpose, false
+	}
+
+	cty, zip, _, ok := jsonStringFields(buf, "cty", "zip", true)
+	if !ok || cty != nil || zip != nil {
+		return UnknownPurpose, false
+	}
+
+	buf, ok = b64URLDecode(buf[:0], payload)
+	*bp = buf[:0]
+	if !ok {
+		return UnknownPurpose, false
+	}
+
+	p, sub, _, ok := jsonStringFields(buf, "purpose", "sub", true)
+	if !ok {
+		return UnknownPurpose, false
+	}
+
+	if len(p) == 0 {
+		if string(sub) == string(ProvisioningPurpose) {
+			return ProvisioningPurpose, true
+		}
+
+		return UnknownPurpose, true
+	}
+
+	return internPurpose(p), true
+}
+
+// scanAlgorithm extracts the alg header of token, ok is false when the regular decoder has to be used
+func scanAlgorithm[T string | []byte](token T) (alg string, ok bool) {
+	hdr, _, ok := tokenSegments(token)
+	if !ok {
+		return "", false
+	}
+
+	bp := scanBuffers.Get().(*[]byte)
+	defer scanBuffers.Put(bp)
+
+	buf, ok := b64URLDecode((*bp)[:0], hdr)
+	*bp = buf[:0]
+	if !ok {
+		return "", false
+	}
+
+	// the jwt parser decodes the header into a map so the key is case sensitive
+	a, _, found, ok := jsonStringFields(buf, "alg", "", false)
+	if !ok || !found {
+		return "", false
+	}
+
+	for _, k := range knownAlgorithms {
+		if string(a) == k {
+			return k, true
+		}
+	}
+
+	return "", false
+}
+
+func internPurpose(p []byte) Purpose {
+	for _, k := range knownPurposes {
+		if string(p) == string(k) {
+			return k
+		}
+	}
+
+	purposesMu.RLock()
+	defer purposesMu.RUnlock()
+
+	for k := range purposes {
+		if string(p) == string(k) {
+			return k
+		}
+	}
+
+	return Purpose(p)
+}
+
+// tokenSegments splits a token into its header and payload, the token must have exactly three segments
+func tokenSegments[T string | []byte](token T) (hdr T, payload T, ok bool) {
+	first, second := -1, -1
+
+	for i := 0; i < len(token); i++ {
+		if token[i] != '.' {
+			continue
+		}
+
+		switch {
+		case first == -1:
+			first = i
+		case second == -1:
+			second = i
+		default:
+			return hdr, payload, false
+		}
+	}
+
+	if second == -1 {
+		return hdr, payload, false
+	}
+
+	return token[:first], token[first+1 : second], true
+}
+
+// b64URLDecode appends the raw url base64 decoded src to dst, padding and whitespace are not supported
+func b64URLDecode[T string | []byte](dst []byte, src T) ([]byte, bool) {
+	if len(src)%4 == 1 {
+		return dst, false
+	}
+
+	var acc uint32
+	var bits uint
+
+	for i := 0; i < len(src); i++ {
+		v := b64URLAlphabet[src[i]]
+		if v == 0xff {
+			return dst, false
+		}
+
+		acc = acc<<6 | uint32(v)
+		bits += 6
+
+		if bits >= 8 {
+			bits -= 8
+			dst = append(dst, byte(acc>>bits))
+		}
+	}
+
+	// trailing bits must be zero as in the strict decoder
+	if acc&(1<<bits-1) != 0 {
+		return dst, false
+	}
+
+	return dst, true
+}
+
+// jsonStringFields finds the string values of the top level keys k1 and k2 in the JSON object data, keys are matched
+// case insensitively when fold is set like encoding/json does for structs. Later keys win and values of other types are
+// ignored. ok is false when data is not a valid JSON object or when keys or the matched values hold escapes or non
+// ASCII characters that need the regular decoder
+func jsonStringFields(data []byte, k1 string, k2 string, fold bool) (v1 []byte, v2 []byte, found1 bool, ok bool) {
+	ok = scanJSONObject(data, func(key []byte, val []byte, kind byte) bool {
+		m1 := jsonKeyMatches(key, k1, fold)
+		m2 := jsonKeyMatches(key, k2, fold)
+
+		switch {
+		case !m1 && !m2:
+			return true
+		case kind == '\\':
+			return false
+		case kind != '"':
+			return true
+		case m1:
+			v1, found1 = val, true
+		default:
+			v2 = val
+		}
+
+		return true
+	})
+	if !ok {
+		return nil, nil, false, false
+	}
+
+	return v1, v2, found1, true
+}
+
+// scanJSONObject calls visit for every top level key in the JSON object data, kind is '"' for strings with val
+// holding the content, '\\' for strings that hold escapes or non ASCII characters and otherwise the first byte of
+// the raw value in val. Scanning stops when visit returns false. It is false when data is not a valid JSON object,
+// when a key needs the regular decoder or when visit stopped the scan
+func scanJSONObject(data []byte, visit func(key []byte, val []byte, kind byte) bool) bool {
+	if !json.Valid(data) {
+		return false
+	}
+
+	i := skipJSONSpace(data, 0)
+	if i >= len(data) || data[i] != '{' {
+		return false
+	}
+	i++
+
+	for {
+		i = skipJSONSpace(data, i)
+		if data[i] == '}' {
+			return true
+		}
+
+		key, end, simple := jsonString(data, i)
+		if !simple {
+			return false
+		}
+
+		i = skipJSONSpace(data, end)
+		i = skipJSONSpace(data, i+1) // the colon
+
+		var val []byte
+		kind := data[i]
+
+		if kind == '"' {
+			val, end, simple = jsonString(data, i)
+			if !simple {
+				kind = '\\'
+			}
+		} else {
+			end = skipJSONValue(data, i)
+			val = data[i:end]
+		}
+
+		if !visit(key, val, kind) {
+			return false
+		}
+
+		i = skipJSONSpace(data, end)
+		if data[i] == ',' {
+			i++
+		}
+	}
+}
+
+// jsonKeyMatches compares ASCII keys, optionally case insensitively
+func jsonKeyMatches(key []byte, want string, fold bool) bool {
+	if len(key) != len(want) || want == "" {
+		return false
+	}
+
+	if !fold {
+		return string(key) == want
+	}
+
+	for i := 0; i < len(key); i++ {
+		a, b := key[i], want[i]
+		if 'A' <= a && a <= 'Z' {
+			a += 'a' - 'A'
+		}
+		if a != b {
+			return false
+		}
+	}
+
+	return true
+}
+
+// jsonString returns the content of the string starting at data[i] and the index after it, simple is false when
+// the string holds escapes or non ASCII characters
+func jsonString(data []byte, i int) (val []byte, end int, simple bool) {
+	simple = true
+
+	for j := i + 1; j < len(data); j++ {
+		switch c := data[j]; {
+		case c == '\\':
+			simple = false
+			j++
+		case c == '"':
+			return data[i+1 : j], j + 1, simple
+		case c >= 0x80:
+			simple = false
+		}
+	}
+
+	return nil, len(data), false
+}
+
+// skipJSONValue returns the index after the non string value starting at data[i], data must be valid JSON
+func skipJSONValue(data []byte, i int) int {
+	depth := 0
+
+	for ; i < len(data); i++ {
+		switch data[i] {
+		case '"':
+			_, end, _ := jsonString(data, i)
+			i = end - 1
+		case '{', '[':
+			depth++
+		case '}', ']':
+			if depth == 0 {
+				return i
+			}
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		case ',', ' ', '\t', '\r', '\n':
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+
+	return i
+}
+
+func skipJSONSpace(data []byte, i int) int {
+	for i < len(data) {
+		switch data[i] {
+		case ' ', '\t', '\r', '\n':
+			i++
+		default:
+			return i
+		}
+	}
+
+	return i
+}
+
+// tokenAlgorithm determines the signing algorithm of token without decoding its claims
+func tokenAlgorithm[T string | []byte](token T) (string, error) {
+	alg, ok := scanAlgorithm(token)
+	if ok {
+		return alg, nil
+	}
+
+	claims := StandardClaims{}
+	t, err := parseUnverified(string(token), &claims)
+	if err != nil {
+		return "", err
+	}
+
+	return t.Method.Alg(), nil
+}
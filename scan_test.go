@@ -0,0 +1,160 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Field Scanning", func() {
+	var (
+		priK  ed25519.PrivateKey
+		token string
+	)
+
+	// craft replaces the header and payload of token with the given JSON documents
+	craft := func(hdr string, payload string) string {
+		parts := strings.Split(token, ".")
+		parts[0] = base64.RawURLEncoding.EncodeToString([]byte(hdr))
+		parts[1] = base64.RawURLEncoding.EncodeToString([]byte(payload))
+		return strings.Join(parts, ".")
+	}
+
+	BeforeEach(func() {
+		var err error
+		_, priK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		claims, err := NewClientIDClaims("up=bob", []string{"rpcutil"}, "", nil, "", "", time.Hour, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		token, err = SignToken(claims, priK)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Should scan signed tokens without the regular decoder", func() {
+		purpose, ok := scanPurpose(token)
+		Expect(ok).To(BeTrue())
+		Expect(purpose).To(Equal(ClientIDPurpose))
+
+		purpose, ok = scanPurpose([]byte(token))
+		Expect(ok).To(BeTrue())
+		Expect(purpose).To(Equal(ClientIDPurpose))
+
+		alg, ok := scanAlgorithm(token)
+		Expect(ok).To(BeTrue())
+		Expect(alg).To(Equal(algEdDSA))
+
+		prov, err := NewProvisioningClaims(true, true, "x", "", "", nil, "example.net", "", "", "choria", "", time.Hour)
+		Expect(err).ToNot(HaveOccurred())
+		signed, err := SignToken(prov, priK)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(TokenPurpose(signed)).To(Equal(ProvisioningPurpose))
+	})
+
+	It("Should agree with the regular decoder", func() {
+		cases := map[string][2]string{
+			"plain":             {`{"alg":"EdDSA","typ":"JWT"}`, `{"purpose":"choria_client_id","sub":"x"}`},
+			"spaces":            {` { "alg" : "RS256" } `, ` { "sub" : "x" , "purpose" : "choria_server" } `},
+			"nested":            {`{"alg":"EdDSA","x":{"alg":"RS256"}}`, `{"x":{"purpose":"choria_server"},"y":[1,"}"],"purpose":"choria_client_id"}`},
+			"duplicate":         {`{"alg":"RS256","alg":"EdDSA"}`, `{"purpose":"choria_server","purpose":"choria_client_id"}`},
+			"case":              {`{"ALG":"RS256","alg":"EdDSA"}`, `{"Purpose":"choria_server"}`},
+			"escapes":           {`{"alg":"EdDSA"}`, `{"purp\u006fse":"choria\u005fserver"}`},
+			"provisioning sub":  {`{"alg":"EdDSA"}`, `{"sub":"choria_provisioning"}`},
+			"no purpose":        {`{"alg":"EdDSA"}`, `{"sub":"x"}`},
+			"custom purpose":    {`{"alg":"EdDSA"}`, `{"purpose":"acme_widget"}`},
+			"non string":        {`{"alg":"EdDSA"}`, `{"purpose":1}`},
+			"codec":             {`{"alg":"EdDSA","cty":"acme"}`, `{"purpose":"choria_server"}`},
+			"unknown algorithm": {`{"alg":"XX"}`, `{"purpose":"choria_server"}`},
+			"invalid json":      {`{"alg":"EdDSA"`, `{"purpose":"choria_server"`},
+			"not an object":     {`["alg","EdDSA"]`, `"choria_server"`},
+		}
+
+		for name, c := range cases {
+			crafted := craft(c[0], c[1])
+
+			Expect(TokenPurpose(crafted)).To(Equal(tokenPurpose(crafted)), name)
+			Expect(TokenPurposeBytes([]byte(crafted))).To(Equal(tokenPurpose(crafted)), name)
+
+			// only the header is inspected so invalid claims do not have to fail
+			t, err := parseUnverified(crafted, &StandardClaims{})
+			if err != nil {
+				continue
+			}
+
+			alg, err := TokenSigningAlgorithm(crafted)
+			Expect(err).ToNot(HaveOccurred(), name)
+			Expect(alg).To(Equal(t.Method.Alg()), name)
+		}
+	})
+
+	It("Should fall back for malformed and encoded tokens", func() {
+		_, ok := scanPurpose("a.b")
+		Expect(ok).To(BeFalse())
+		_, ok = scanPurpose("a.b.c.d")
+		Expect(ok).To(BeFalse())
+		_, ok = scanPurpose("e30=.e30.x")
+		Expect(ok).To(BeFalse())
+
+		compressed, err := SignToken(&ClientIDClaims{CallerID: "up=bob", StandardClaims: StandardClaims{Purpose: ClientIDPurpose}}, priK, WithCompression())
+		Expect(err).ToNot(HaveOccurred())
+		_, ok = scanPurpose(compressed)
+		Expect(ok).To(BeFalse())
+		Expect(TokenPurpose(compressed)).To(Equal(ClientIDPurpose))
+
+		Expect(TokenPurpose("garbage")).To(Equal(UnknownPurpose))
+		_, err = TokenSigningAlgorithm("garbage")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Should not allocate for known purposes", func() {
+		Expect(testing.AllocsPerRun(100, func() { TokenPurpose(token) })).To(BeZero())
+		Expect(testing.AllocsPerRun(100, func() { TokenPurposeBytes([]byte(token)) })).To(BeZero())
+		Expect(testing.AllocsPerRun(100, func() { TokenSigningAlgorithm(token) })).To(BeZero())
+	})
+})
+
+func benchmarkTokenField(b *testing.B, f func(token string)) {
+	_, priK, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	claims, err := NewClientIDClaims("up=bob", []string{"rpcutil"}, "", nil, "", "", time.Hour, nil, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	token, err := SignToken(claims, priK)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		f(token)
+	}
+}
+
+func BenchmarkTokenPurpose(b *testing.B) {
+	benchmarkTokenField(b, func(token string) { TokenPurpose(token) })
+}
+
+func BenchmarkTokenPurposeDecoded(b *testing.B) {
+	benchmarkTokenField(b, func(token string) { tokenPurpose(token) })
+}
+
+func BenchmarkTokenSigningAlgorithm(b *testing.B) {
+	benchmarkTokenField(b, func(token string) { TokenSigningAlgorithm(token) })
+}
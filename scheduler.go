@@ -0,0 +1,223 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var (
+	ErrNotASchedulerToken     = errors.New("not a scheduler token")
+	ErrSchedulerActionDenied  = errors.New("action is not allowed by the scheduled job")
+	ErrOutsideExecutionWindow = errors.New("outside of the job execution window")
+)
+
+// NodeFilter describes the nodes a scheduled job may target using the standard Choria discovery filters
+type NodeFilter struct {
+	// Collective is the collective to target
+	Collective string `json:"collective,omitempty"`
+
+	// Identities are identity filters
+	Identities []string `json:"identities,omitempty"`
+
+	// Classes are configuration management class filters
+	Classes []string `json:"classes,omitempty"`
+
+	// Facts are fact filters
+	Facts []string `json:"facts,omitempty"`
+
+	// Agents are agent filters
+	Agents []string `json:"agents,omitempty"`
+
+	// Compound is a compound filter
+	Compound string `json:"compound,omitempty"`
+}
+
+// SchedulerClaims describes a scheduled job the Choria scheduler may run on behalf of a user
+//
+// The "purpose" claim should be set to SchedulerPurpose
+type SchedulerClaims struct {
+	// JobName is the name of the scheduled job
+	JobName string `json:"job"`
+
+	// CallerID is the caller id the job runs on behalf of
+	CallerID string `json:"callerid"`
+
+	// AllowedAgents is a list of agent names or agent.action names the job can perform, * allows all
+	AllowedAgents []string `json:"agents"`
+
+	// Filter restricts the nodes the job may target, it is not enforced by this package and should be used by the
+	// scheduler as the discovery filter for the job
+	Filter *NodeFilter `json:"filter,omitempty"`
+
+	// WindowStart is the time before which the job may not execute
+	WindowStart *jwt.NumericDate `json:"window_start,omitempty"`
+
+	// WindowEnd is the time after which the job may not execute
+	WindowEnd *jwt.NumericDate `json:"window_end,omitempty"`
+
+	StandardClaims
+}
+
+// NewSchedulerClaims generates new SchedulerClaims, zero window times leave the window open ended
+func NewSchedulerClaims(job string, callerID string, allowedAgents []string, filter *NodeFilter, windowStart time.Time, windowEnd time.Time, issuer string, validity time.Duration, opts ...ClaimsOption) (*SchedulerClaims, error) {
+	if job == "" {
+		return nil, fmt.Errorf("job name is required")
+	}
+
+	if callerID == "" {
+		return nil, fmt.Errorf("caller id is required")
+	}
+
+	stdClaims, err := newStandardClaims(issuer, SchedulerPurpose, validity, false, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &SchedulerClaims{
+		JobName:        job,
+		CallerID:       callerID,
+		AllowedAgents:  allowedAgents,
+		Filter:         filter,
+		StandardClaims: *stdClaims,
+	}
+	claims.Subject = callerID
+
+	if !windowStart.IsZero() {
+		claims.WindowStart = jwt.NewNumericDate(windowStart)
+	}
+
+	if !windowEnd.IsZero() {
+		if claims.ExpiresAt != nil && windowEnd.After(claims.ExpiresAt.Time) {
+			return nil, fmt.Errorf("execution window cannot end after the token expires")
+		}
+
+		claims.WindowEnd = jwt.NewNumericDate(windowEnd)
+	}
+
+	err = claims.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// CanInvoke determines if the job may invoke action on agent, AllowedAgents is matched like the AllowedAgents of client tokens
+func (c *SchedulerClaims) CanInvoke(agent string, action string) bool {
+	return agentAllowed(c.AllowedAgents, agent+"."+action)
+}
+
+// IsWithinWindow determines if t falls within the execution window
+func (c *SchedulerClaims) IsWithinWindow(t time.Time) bool {
+	if c.WindowStart != nil && t.Before(c.WindowStart.Time) {
+		return false
+	}
+
+	if c.WindowEnd != nil && t.After(c.WindowEnd.Time) {
+		return false
+	}
+
+	return true
+}
+
+// Authorize checks that the job may invoke action on agent at now, the Filter is not checked, the scheduler should
+// use it as the discovery filter for the job
+func (c *SchedulerClaims) Authorize(agent string, action string, now time.Time) error {
+	if !c.IsWithinWindow(now) {
+		return ErrOutsideExecutionWindow
+	}
+
+	if !c.CanInvoke(agent, action) {
+		return fmt.Errorf("%w: %s.%s", ErrSchedulerActionDenied, agent, action)
+	}
+
+	return nil
+}
+
+// IsSchedulerToken determines if this is a scheduler token
+func IsSchedulerToken(claims StandardClaims) bool {
+	return claims.Purpose == SchedulerPurpose
+}
+
+// ParseSchedulerTokenUnverified parses the scheduler token in an unverified manner.
+func ParseSchedulerTokenUnverified(token string) (*SchedulerClaims, error) {
+	claims := &SchedulerClaims{}
+	_, err := parseUnverified(token, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	if !IsSchedulerToken(claims.StandardClaims) {
+		return nil, ErrNotASchedulerToken
+	}
+
+	return claims, nil
+}
+
+// ParseSchedulerToken parses token and verifies it with pk
+func ParseSchedulerToken(token string, pk any, opts ...ParseOption) (*SchedulerClaims, error) {
+	claims := &SchedulerClaims{}
+	err := ParseToken(token, claims, pk, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse scheduler token: %w", err)
+	}
+
+	// if we have a tcs we require an issuer expiry to be set and it to not have expired
+	if !claims.verifyIssuerExpiry(claims.TrustChainSignature != "") {
+		return nil, jwt.ErrTokenExpired
+	}
+
+	return claims, nil
+}
+
+// ParseSchedulerTokenWithKeyfile parses token and verifies it with the RSA or ED25519 Public key in pkFile
+func ParseSchedulerTokenWithKeyfile(token string, pkFile string, opts ...ParseOption) (*SchedulerClaims, error) {
+	if pkFile == "" {
+		return nil, fmt.Errorf("invalid public key file")
+	}
+
+	certdat, err := os.ReadFile(pkFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not read validation certificate: %s", err)
+	}
+
+	pk, err := readRSAOrED25519PublicData(certdat)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseSchedulerToken(token, pk, opts...)
+}
+
+// Validate checks the job, caller, agents and execution window are sane
+func (c *SchedulerClaims) Validate() error {
+	if !IsSchedulerToken(c.StandardClaims) {
+		return ErrNotASchedulerToken
+	}
+
+	if c.JobName == "" {
+		return fmt.Errorf("job name is required")
+	}
+
+	if c.CallerID == "" {
+		return fmt.Errorf("caller id is required")
+	}
+
+	if len(c.AllowedAgents) == 0 {
+		return fmt.Errorf("at least one allowed agent is required")
+	}
+
+	if c.WindowStart != nil && c.WindowEnd != nil && !c.WindowStart.Before(c.WindowEnd.Time) {
+		return fmt.Errorf("execution window must start before it ends")
+	}
+
+	return nil
+}
@@ -0,0 +1,128 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SchedulerClaims", func() {
+	var (
+		pubK ed25519.PublicKey
+		priK ed25519.PrivateKey
+		err  error
+	)
+
+	BeforeEach(func() {
+		pubK, priK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Describe("NewSchedulerClaims", func() {
+		It("Should validate the input", func() {
+			_, err := NewSchedulerClaims("", "up=bob", []string{"rpcutil"}, nil, time.Time{}, time.Time{}, "", time.Hour)
+			Expect(err).To(MatchError("job name is required"))
+
+			_, err = NewSchedulerClaims("backup", "", []string{"rpcutil"}, nil, time.Time{}, time.Time{}, "", time.Hour)
+			Expect(err).To(MatchError("caller id is required"))
+
+			_, err = NewSchedulerClaims("backup", "up=bob", nil, nil, time.Time{}, time.Time{}, "", time.Hour)
+			Expect(err).To(MatchError("at least one allowed agent is required"))
+
+			_, err = NewSchedulerClaims("backup", "up=bob", []string{"rpcutil"}, nil, time.Now().Add(time.Minute), time.Now(), "", time.Hour)
+			Expect(err).To(MatchError("execution window must start before it ends"))
+
+			_, err = NewSchedulerClaims("backup", "up=bob", []string{"rpcutil"}, nil, time.Time{}, time.Now().Add(2*time.Hour), "", time.Hour)
+			Expect(err).To(MatchError("execution window cannot end after the token expires"))
+		})
+
+		It("Should create valid claims", func() {
+			start := time.Now().Add(time.Minute)
+			end := start.Add(10 * time.Minute)
+			filter := &NodeFilter{Collective: "choria", Classes: []string{"backup"}}
+
+			claims, err := NewSchedulerClaims("backup", "up=bob", []string{"backup.run"}, filter, start, end, "ginkgo", time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(claims.Purpose).To(Equal(SchedulerPurpose))
+			Expect(claims.JobName).To(Equal("backup"))
+			Expect(claims.CallerID).To(Equal("up=bob"))
+			Expect(claims.Subject).To(Equal("up=bob"))
+			Expect(claims.Filter).To(Equal(filter))
+			Expect(claims.WindowStart.Time).To(BeTemporally("~", start, time.Second))
+			Expect(claims.WindowEnd.Time).To(BeTemporally("~", end, time.Second))
+		})
+	})
+
+	Describe("Authorization", func() {
+		It("Should check agents and actions", func() {
+			claims, err := NewSchedulerClaims("backup", "up=bob", []string{"backup.run", "rpcutil", "puppet.*"}, nil, time.Time{}, time.Time{}, "", time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(claims.CanInvoke("backup", "run")).To(BeTrue())
+			Expect(claims.CanInvoke("backup", "delete")).To(BeFalse())
+			Expect(claims.CanInvoke("rpcutil", "ping")).To(BeTrue())
+			Expect(claims.CanInvoke("puppet", "disable")).To(BeTrue())
+			Expect(claims.CanInvoke("service", "stop")).To(BeFalse())
+
+			Expect(claims.Authorize("backup", "run", time.Now())).To(Succeed())
+			Expect(claims.Authorize("backup", "delete", time.Now())).To(MatchError(ErrSchedulerActionDenied))
+
+			claims.AllowedAgents = []string{"*"}
+			Expect(claims.CanInvoke("service", "stop")).To(BeTrue())
+		})
+
+		It("Should check the execution window", func() {
+			start := time.Now().Add(time.Minute)
+			claims, err := NewSchedulerClaims("backup", "up=bob", []string{"*"}, nil, start, start.Add(time.Minute), "", time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(claims.IsWithinWindow(time.Now())).To(BeFalse())
+			Expect(claims.IsWithinWindow(start.Add(30 * time.Second))).To(BeTrue())
+			Expect(claims.IsWithinWindow(start.Add(2 * time.Minute))).To(BeFalse())
+			Expect(claims.Authorize("rpcutil", "ping", time.Now())).To(MatchError(ErrOutsideExecutionWindow))
+			Expect(claims.Authorize("rpcutil", "ping", start.Add(30*time.Second))).To(Succeed())
+		})
+	})
+
+	Describe("ParseSchedulerToken", func() {
+		It("Should parse valid tokens", func() {
+			claims, err := NewSchedulerClaims("backup", "up=bob", []string{"backup.run"}, nil, time.Time{}, time.Time{}, "", time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+
+			token, err := SignToken(claims, priK)
+			Expect(err).ToNot(HaveOccurred())
+
+			parsed, err := ParseSchedulerToken(token, pubK)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(parsed.JobName).To(Equal("backup"))
+
+			unverified, err := ParseSchedulerTokenUnverified(token)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(unverified.AllowedAgents).To(Equal([]string{"backup.run"}))
+
+			_, err = ParseSchedulerTokenWithKeyfile(token, "")
+			Expect(err).To(MatchError("invalid public key file"))
+		})
+
+		It("Should reject other tokens", func() {
+			claims, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			token, err := SignToken(claims, priK)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = ParseSchedulerToken(token, pubK)
+			Expect(err).To(MatchError(ErrNotASchedulerToken))
+
+			_, err = ParseSchedulerTokenUnverified(token)
+			Expect(err).To(MatchError(ErrNotASchedulerToken))
+		})
+	})
+})
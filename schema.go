@@ -0,0 +1,200 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	// SchemaVersionLegacy is the schema version of tokens that do not have a schema claim
+	SchemaVersionLegacy = 1
+
+	// ClientIDSchemaVersion is the current schema version of client tokens
+	ClientIDSchemaVersion = 2
+
+	// ServerSchemaVersion is the current schema version of server tokens
+	ServerSchemaVersion = 2
+
+	// ProvisioningSchemaVersion is the current schema version of provisioning tokens
+	ProvisioningSchemaVersion = 2
+)
+
+// ErrUnsupportedSchemaVersion indicates a token was issued using a newer schema than this package understands
+var ErrUnsupportedSchemaVersion = errors.New("unsupported token schema version")
+
+// ClaimsMigration upgrades claims from one schema version to the next in place.
+//
+// Migrations run after the token signature was verified and before the claims are validated, the claims returned
+// from parsing therefore differ from those that were signed. Migrations should only translate older layouts and
+// never grant anything the signed claims did not
+type ClaimsMigration func(claims jwt.Claims) error
+
+var (
+	// schemaVersions are the current schema versions, purposes not listed are SchemaVersionLegacy
+	schemaVersions = map[Purpose]int{
+		ClientIDPurpose:     ClientIDSchemaVersion,
+		ServerPurpose:       ServerSchemaVersion,
+		ProvisioningPurpose: ProvisioningSchemaVersion,
+	}
+
+	migrations = map[Purpose]map[int]ClaimsMigration{}
+	migrateMu  sync.RWMutex
+)
+
+// RegisterSchemaVersion sets the current schema version of a custom purpose, it can only be set once and the
+// versions of the built in purposes are fixed
+func RegisterSchemaVersion(purpose Purpose, version int) error {
+	if purpose == UnknownPurpose {
+		return fmt.Errorf("purpose is required")
+	}
+
+	if version < SchemaVersionLegacy {
+		return fmt.Errorf("invalid schema version %d", version)
+	}
+
+	migrateMu.Lock()
+	defer migrateMu.Unlock()
+
+	if _, ok := schemaVersions[purpose]; ok {
+		return fmt.Errorf("schema version of %s is already registered", purpose)
+	}
+
+	schemaVersions[purpose] = version
+
+	return nil
+}
+
+func init() {
+	for _, p := range []Purpose{ClientIDPurpose, ServerPurpose, ProvisioningPurpose} {
+		err := RegisterClaimsMigration(p, SchemaVersionLegacy, migrateLegacyLayout)
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterClaimsMigration registers a migration upgrading claims of purpose from schema version from to from+1, only
+// versions older than the current schema version of the purpose can be migrated, see RegisterSchemaVersion
+func RegisterClaimsMigration(purpose Purpose, from int, migration ClaimsMigration) error {
+	if purpose == UnknownPurpose {
+		return fmt.Errorf("purpose is required")
+	}
+
+	if from < SchemaVersionLegacy || from >= CurrentSchemaVersion(purpose) {
+		return fmt.Errorf("invalid schema version %d", from)
+	}
+
+	if migration == nil {
+		return fmt.Errorf("migration is required")
+	}
+
+	migrateMu.Lock()
+	defer migrateMu.Unlock()
+
+	if migrations[purpose] == nil {
+		migrations[purpose] = map[int]ClaimsMigration{}
+	}
+
+	if _, ok := migrations[purpose][from]; ok {
+		return fmt.Errorf("migration from schema version %d of %s is already registered", from, purpose)
+	}
+
+	migrations[purpose][from] = migration
+
+	return nil
+}
+
+// CurrentSchemaVersion is the schema version new tokens of purpose are issued with
+func CurrentSchemaVersion(purpose Purpose) int {
+	migrateMu.RLock()
+	defer migrateMu.RUnlock()
+
+	current, ok := schemaVersions[purpose]
+	if !ok {
+		return SchemaVersionLegacy
+	}
+
+	return current
+}
+
+// Schema is the schema version the claims are in, tokens without a schema claim are SchemaVersionLegacy
+func (c *StandardClaims) Schema() int {
+	if c.SchemaVersion == 0 {
+		return SchemaVersionLegacy
+	}
+
+	return c.SchemaVersion
+}
+
+// MigrateClaims upgrades claims in place to the current schema version of their purpose, claims issued with a newer
+// schema than is supported are rejected so fields never silently change meaning. Parsing tokens calls this
+// automatically once the signature is verified, see ClaimsMigration
+func MigrateClaims(claims jwt.Claims) error {
+	sc, ok := claims.(standardClaimsProvider)
+	if !ok {
+		return nil
+	}
+
+	std := sc.getStandardClaims()
+	purpose := std.Purpose
+	if purpose == UnknownPurpose {
+		purpose = Purpose(std.Subject)
+	}
+
+	current := CurrentSchemaVersion(purpose)
+	version := std.Schema()
+
+	if version > current {
+		return fmt.Errorf("%w: %s token uses schema version %d, only %d is supported", ErrUnsupportedSchemaVersion, purpose, version, current)
+	}
+
+	for ; version < current; version++ {
+		migrateMu.RLock()
+		migration := migrations[purpose][version]
+		migrateMu.RUnlock()
+
+		if migration != nil {
+			err := migration(claims)
+			if err != nil {
+				return fmt.Errorf("could not migrate %s token from schema version %d: %w", purpose, version, err)
+			}
+		}
+
+		std.SchemaVersion = version + 1
+	}
+
+	return nil
+}
+
+// migrateLegacyLayout upgrades tokens that indicated their purpose in the subject and relied on verifiers defaulting
+// the organization
+func migrateLegacyLayout(claims jwt.Claims) error {
+	std := claims.(standardClaimsProvider).getStandardClaims()
+	if std.Purpose == UnknownPurpose && IsRegisteredPurpose(Purpose(std.Subject)) {
+		std.Purpose = Purpose(std.Subject)
+	}
+
+	switch c := claims.(type) {
+	case *ClientIDClaims:
+		if c.OrganizationUnit == "" {
+			c.OrganizationUnit = defaultOrg
+		}
+	case *ServerClaims:
+		if c.OrganizationUnit == "" {
+			c.OrganizationUnit = defaultOrg
+		}
+	case *ProvisioningClaims:
+		if c.OrganizationUnit == "" {
+			c.OrganizationUnit = defaultOrg
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Schema versions", func() {
+	var (
+		pubK ed25519.PublicKey
+		priK ed25519.PrivateKey
+	)
+
+	BeforeEach(func() {
+		var err error
+		pubK, priK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Should issue tokens with the current schema version", func() {
+		claims, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(claims.SchemaVersion).To(Equal(ClientIDSchemaVersion))
+		Expect(CurrentSchemaVersion(ServerPurpose)).To(Equal(ServerSchemaVersion))
+		Expect(CurrentSchemaVersion(ProvisioningPurpose)).To(Equal(ProvisioningSchemaVersion))
+		Expect(CurrentSchemaVersion(StreamPurpose)).To(Equal(SchemaVersionLegacy))
+		Expect((&StandardClaims{}).Schema()).To(Equal(SchemaVersionLegacy))
+	})
+
+	It("Should migrate legacy layouts on parse", func() {
+		legacy := &ProvisioningClaims{
+			Token: "x",
+			URLs:  "nats://prov:4222",
+			StandardClaims: StandardClaims{
+				RegisteredClaims: jwt.RegisteredClaims{
+					Subject:   string(ProvisioningPurpose),
+					ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+				},
+			},
+		}
+		token, err := SignToken(legacy, priK)
+		Expect(err).ToNot(HaveOccurred())
+
+		claims := &ProvisioningClaims{}
+		Expect(ParseToken(token, claims, pubK)).To(Succeed())
+		Expect(claims.Purpose).To(Equal(ProvisioningPurpose))
+		Expect(claims.OrganizationUnit).To(Equal("choria"))
+		Expect(claims.SchemaVersion).To(Equal(ProvisioningSchemaVersion))
+	})
+
+	It("Should reject tokens with newer schema versions", func() {
+		claims, err := NewServerClaims("ginkgo.example.net", []string{"choria"}, "", nil, nil, pubK, "", time.Hour)
+		Expect(err).ToNot(HaveOccurred())
+		claims.SchemaVersion = ServerSchemaVersion + 1
+		token, err := SignToken(claims, priK)
+		Expect(err).ToNot(HaveOccurred())
+
+		err = ParseToken(token, &ServerClaims{}, pubK)
+		Expect(err).To(MatchError(ErrUnsupportedSchemaVersion))
+		Expect(err).To(MatchError(ContainSubstring("choria_server token uses schema version 3, only 2 is supported")))
+	})
+
+	It("Should support registering migrations", func() {
+		purpose := Purpose("ginkgo_schema")
+		Expect(RegisterClaimsMigration(purpose, 1, func(jwt.Claims) error { return nil })).To(MatchError("invalid schema version 1"))
+		Expect(RegisterSchemaVersion(purpose, 0)).To(MatchError("invalid schema version 0"))
+		Expect(RegisterSchemaVersion(purpose, 3)).To(Succeed())
+		Expect(RegisterSchemaVersion(purpose, 4)).To(MatchError("schema version of ginkgo_schema is already registered"))
+		Expect(RegisterSchemaVersion(ClientIDPurpose, 4)).To(MatchError("schema version of choria_client_id is already registered"))
+		Expect(CurrentSchemaVersion(purpose)).To(Equal(3))
+
+		Expect(RegisterClaimsMigration(purpose, 1, func(claims jwt.Claims) error {
+			c := claims.(*StandardClaims)
+			c.Subject = "migrated"
+			return nil
+		})).To(Succeed())
+		Expect(RegisterClaimsMigration(purpose, 2, func(claims jwt.Claims) error {
+			return errors.New("failed")
+		})).To(Succeed())
+		Expect(RegisterClaimsMigration(purpose, 1, func(jwt.Claims) error { return nil })).To(MatchError("migration from schema version 1 of ginkgo_schema is already registered"))
+		Expect(RegisterClaimsMigration(purpose, 0, func(jwt.Claims) error { return nil })).To(MatchError("invalid schema version 0"))
+		Expect(RegisterClaimsMigration(purpose, 3, func(jwt.Claims) error { return nil })).To(MatchError("invalid schema version 3"))
+		Expect(RegisterClaimsMigration(purpose, 2, nil)).To(MatchError("migration is required"))
+		Expect(RegisterClaimsMigration(ClientIDPurpose, ClientIDSchemaVersion, func(jwt.Claims) error { return nil })).To(MatchError("invalid schema version 2"))
+		Expect(CurrentSchemaVersion(purpose)).To(Equal(3))
+
+		claims := &StandardClaims{Purpose: purpose}
+		err := MigrateClaims(claims)
+		Expect(err).To(MatchError("could not migrate ginkgo_schema token from schema version 2: failed"))
+		Expect(claims.Subject).To(Equal("migrated"))
+		Expect(claims.SchemaVersion).To(Equal(2))
+
+		Expect(MigrateClaims(&jwt.RegisteredClaims{})).To(Succeed())
+	})
+})
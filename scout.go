@@ -0,0 +1,104 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"errors"
+	"fmt"
+	"path"
+)
+
+// ScoutAction is an action a client can perform on Scout checks
+type ScoutAction string
+
+const (
+	// ScoutViewAction allows viewing the state of checks
+	ScoutViewAction ScoutAction = "view"
+
+	// ScoutTriggerAction allows triggering immediate check runs
+	ScoutTriggerAction ScoutAction = "trigger"
+
+	// ScoutMaintenanceAction allows placing checks in and out of maintenance mode
+	ScoutMaintenanceAction ScoutAction = "maintenance"
+)
+
+// ErrScoutPermissionDenied indicates a client may not perform an action on a Scout check
+var ErrScoutPermissionDenied = errors.New("scout permission denied")
+
+// ScoutPermissions grants rights over Scout checks, each list holds check name patterns like disk_* using path.Match syntax
+type ScoutPermissions struct {
+	// View are patterns of checks the client can view
+	View []string `json:"view,omitempty"`
+
+	// Trigger are patterns of checks the client can trigger
+	Trigger []string `json:"trigger,omitempty"`
+
+	// Maintenance are patterns of checks the client can place in maintenance mode
+	Maintenance []string `json:"maintenance,omitempty"`
+}
+
+// Allows determines if action may be performed on the check called check
+func (p *ScoutPermissions) Allows(action ScoutAction, check string) bool {
+	if p == nil || check == "" {
+		return false
+	}
+
+	var patterns []string
+
+	switch action {
+	case ScoutViewAction:
+		patterns = p.View
+	case ScoutTriggerAction:
+		patterns = p.Trigger
+	case ScoutMaintenanceAction:
+		patterns = p.Maintenance
+	default:
+		return false
+	}
+
+	for _, pattern := range patterns {
+		ok, err := path.Match(pattern, check)
+		if err == nil && ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *ScoutPermissions) validate() error {
+	if p == nil {
+		return nil
+	}
+
+	for _, patterns := range [][]string{p.View, p.Trigger, p.Maintenance} {
+		for _, pattern := range patterns {
+			_, err := path.Match(pattern, "")
+			if err != nil {
+				return fmt.Errorf("invalid scout check pattern %q: %w", pattern, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// CanScout determines if the client may perform action on the Scout check called check, org admins can perform all actions
+func (c *ClientIDClaims) CanScout(action ScoutAction, check string) bool {
+	if c.Permissions != nil && c.Permissions.OrgAdmin {
+		return true
+	}
+
+	return c.Scout.Allows(action, check)
+}
+
+// AuthorizeScout returns an error when the client may not perform action on the Scout check called check
+func (c *ClientIDClaims) AuthorizeScout(action ScoutAction, check string) error {
+	if !c.CanScout(action, check) {
+		return fmt.Errorf("%w: %s %s", ErrScoutPermissionDenied, action, check)
+	}
+
+	return nil
+}
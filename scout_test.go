@@ -0,0 +1,76 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Scout", func() {
+	var claims *ClientIDClaims
+
+	BeforeEach(func() {
+		var err error
+		claims, err = NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Describe("CanScout", func() {
+		It("Should deny without permissions", func() {
+			Expect(claims.CanScout(ScoutViewAction, "disk")).To(BeFalse())
+			Expect(claims.AuthorizeScout(ScoutViewAction, "disk")).To(MatchError(ErrScoutPermissionDenied))
+		})
+
+		It("Should allow org admins", func() {
+			claims.Permissions = &ClientPermissions{OrgAdmin: true}
+			Expect(claims.CanScout(ScoutMaintenanceAction, "disk")).To(BeTrue())
+		})
+
+		It("Should match check patterns per action", func() {
+			claims.Scout = &ScoutPermissions{
+				View:        []string{"*"},
+				Trigger:     []string{"disk_*", "load"},
+				Maintenance: []string{"disk_root"},
+			}
+
+			Expect(claims.CanScout(ScoutViewAction, "anything")).To(BeTrue())
+			Expect(claims.CanScout(ScoutTriggerAction, "disk_var")).To(BeTrue())
+			Expect(claims.CanScout(ScoutTriggerAction, "load")).To(BeTrue())
+			Expect(claims.CanScout(ScoutTriggerAction, "swap")).To(BeFalse())
+			Expect(claims.CanScout(ScoutMaintenanceAction, "disk_root")).To(BeTrue())
+			Expect(claims.CanScout(ScoutMaintenanceAction, "disk_var")).To(BeFalse())
+			Expect(claims.CanScout(ScoutAction("delete"), "disk_root")).To(BeFalse())
+			Expect(claims.CanScout(ScoutViewAction, "")).To(BeFalse())
+			Expect(claims.AuthorizeScout(ScoutTriggerAction, "disk_var")).To(Succeed())
+		})
+	})
+
+	Describe("Parsing", func() {
+		It("Should round trip and validate patterns", func() {
+			pubK, priK, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+
+			claims.Scout = &ScoutPermissions{View: []string{"disk_*"}}
+			token, err := SignToken(claims, priK)
+			Expect(err).ToNot(HaveOccurred())
+
+			parsed, err := ParseClientIDToken(token, pubK, true)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(parsed.CanScout(ScoutViewAction, "disk_root")).To(BeTrue())
+
+			claims.Scout = &ScoutPermissions{View: []string{"disk_["}}
+			token, err = SignToken(claims, priK)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = ParseClientIDToken(token, pubK, true)
+			Expect(err).To(MatchError(ContainSubstring("invalid scout check pattern")))
+		})
+	})
+})
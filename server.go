@@ -47,6 +47,18 @@ type ServerClaims struct {
 	// AdditionalPublishSubjects are additional subjects the server can publish to facilitate for example custom registration paths
 	AdditionalPublishSubjects []string `json:"pub_subjects,omitempty"`
 
+	// AdditionalSubscribeSubjects are additional subjects the server can subscribe to, for example for site specific adapters
+	AdditionalSubscribeSubjects []string `json:"sub_subjects,omitempty"`
+
+	// Limits are resource ceilings the broker should enforce for the server
+	Limits *ResourceLimits `json:"limits,omitempty"`
+
+	// Metadata are facts about the server, like datacenter or rack, asserted by the issuer
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	// Attestation proves the server key was created on genuine hardware, see AttestationVerifier
+	Attestation *HardwareAttestation `json:"attestation,omitempty"`
+
 	StandardClaims
 }
 
@@ -103,7 +115,7 @@ func (c *ServerClaims) IsMatchingSeedFile(file string) (bool, error) {
 	return c.IsMatchingPublicKey(pubK)
 }
 
-func NewServerClaims(identity string, collectives []string, org string, perms *ServerPermissions, additionalPublish []string, pk ed25519.PublicKey, issuer string, validity time.Duration) (*ServerClaims, error) {
+func NewServerClaims(identity string, collectives []string, org string, perms *ServerPermissions, additionalPublish []string, pk ed25519.PublicKey, issuer string, validity time.Duration, opts ...ClaimsOption) (*ServerClaims, error) {
 	if identity == "" {
 		return nil, fmt.Errorf("identity is required")
 	}
@@ -124,21 +136,35 @@ func NewServerClaims(identity string, collectives []string, org string, perms *S
 		return nil, fmt.Errorf("validity is required")
 	}
 
-	stdClaims, err := newStandardClaims(issuer, ServerPurpose, validity, false)
+	copts, err := newClaimsOptions(opts...)
 	if err != nil {
 		return nil, err
 	}
 
-	stdClaims.PublicKey = hex.EncodeToString(pk)
+	stdClaims, err := newStandardClaims(issuer, ServerPurpose, validity, false, append([]ClaimsOption{withPublicKey(pk)}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &ServerClaims{
+		ChoriaIdentity:              identity,
+		Collectives:                 collectives,
+		Permissions:                 perms,
+		OrganizationUnit:            org,
+		AdditionalPublishSubjects:   additionalPublish,
+		AdditionalSubscribeSubjects: copts.subSubjects,
+		Limits:                      copts.limits,
+		Metadata:                    copts.serverMetadata,
+		Attestation:                 copts.attestation,
+		StandardClaims:              *stdClaims,
+	}
+
+	err = claims.Validate()
+	if err != nil {
+		return nil, err
+	}
 
-	return &ServerClaims{
-		ChoriaIdentity:            identity,
-		Collectives:               collectives,
-		Permissions:               perms,
-		OrganizationUnit:          org,
-		AdditionalPublishSubjects: additionalPublish,
-		StandardClaims:            *stdClaims,
-	}, nil
+	return claims, nil
 }
 
 // UnverifiedIdentityFromServerToken extracts the identity from a server token.
@@ -150,7 +176,7 @@ func NewServerClaims(identity string, collectives []string, org string, perms *S
 // An empty identity will result in an error
 func UnverifiedIdentityFromServerToken(token string) (*jwt.Token, string, error) {
 	claims := &ServerClaims{}
-	t, _, err := new(jwt.Parser).ParseUnverified(token, claims)
+	t, err := parseUnverified(token, claims)
 	if err != nil {
 		return nil, "", err
 	}
@@ -168,7 +194,7 @@ func UnverifiedIdentityFromServerToken(token string) (*jwt.Token, string, error)
 
 func IsServerTokenString(token string) (bool, error) {
 	claims := &ServerClaims{}
-	_, _, err := new(jwt.Parser).ParseUnverified(token, claims)
+	_, err := parseUnverified(token, claims)
 	if err != nil {
 		return false, err
 	}
@@ -194,7 +220,7 @@ func ParseServerTokenFileUnverified(file string) (*ServerClaims, error) {
 // ParseServerTokenUnverified parses the server token in an unverified manner.
 func ParseServerTokenUnverified(token string) (*ServerClaims, error) {
 	claims := &ServerClaims{}
-	_, _, err := new(jwt.Parser).ParseUnverified(token, claims)
+	_, err := parseUnverified(token, claims)
 	if err != nil {
 		return nil, err
 	}
@@ -207,9 +233,9 @@ func ParseServerTokenUnverified(token string) (*ServerClaims, error) {
 }
 
 // ParseServerToken parses token and verifies it with pk
-func ParseServerToken(token string, pk any) (*ServerClaims, error) {
+func ParseServerToken(token string, pk any, opts ...ParseOption) (*ServerClaims, error) {
 	claims := &ServerClaims{}
-	err := ParseToken(token, claims, pk)
+	err := ParseToken(token, claims, pk, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("could not parse server id token: %w", err)
 	}
@@ -228,8 +254,74 @@ func ParseServerToken(token string, pk any) (*ServerClaims, error) {
 	return claims, nil
 }
 
+// serverReservedSubjects are the subjects the Choria Broker reserves for the system and for requests, replies and
+// node addressing in every collective of the organization
+var serverReservedSubjects = []string{"$SYS.>", "$JS.>", "$KV.>", "$O.>", "choria.federation.>", "*.broadcast.>", "*.node.>", "*.reply.>", "*.submission.>"}
+
+// validateServerSubscribeSubjects ensures additional server subscribe subjects are valid and do not reach the
+// subjects reserved by the Choria Broker
+func validateServerSubscribeSubjects(subjects []string) error {
+	for _, subject := range subjects {
+		err := validateSubject(subject)
+		if err != nil {
+			return err
+		}
+
+		for _, r := range serverReservedSubjects {
+			if subjectsOverlap(subject, r) {
+				return fmt.Errorf("%w: %q overlaps reserved subject %q", ErrInvalidSubject, subject, r)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Validate checks that server tokens have an identity and collectives and that additional subscribe subjects do not
+// reach subjects reserved by the Choria Broker
+func (c *ServerClaims) Validate() error {
+	if !IsServerToken(c.StandardClaims) {
+		return nil
+	}
+
+	if c.ChoriaIdentity == "" {
+		return fmt.Errorf("identity is required")
+	}
+
+	err := ValidateSubjectLiteral(c.ChoriaIdentity)
+	if err != nil {
+		return fmt.Errorf("invalid identity: %w", err)
+	}
+
+	if len(c.Collectives) == 0 {
+		return fmt.Errorf("at least one collective is required")
+	}
+
+	err = c.Limits.Validate()
+	if err != nil {
+		return err
+	}
+
+	err = validateServerMetadata(c.Metadata)
+	if err != nil {
+		return err
+	}
+
+	err = validateHardwareAttestation(c.Attestation)
+	if err != nil {
+		return err
+	}
+
+	err = validateServerSubscribeSubjects(c.AdditionalSubscribeSubjects)
+	if err != nil {
+		return fmt.Errorf("invalid subscribe subject: %w", err)
+	}
+
+	return validateCollectives(c.Collectives)
+}
+
 // ParseServerTokenWithKeyfile parses token and verifies it with the RSA Public key or ed25519 public key in pkFile
-func ParseServerTokenWithKeyfile(token string, pkFile string) (*ServerClaims, error) {
+func ParseServerTokenWithKeyfile(token string, pkFile string, opts ...ParseOption) (*ServerClaims, error) {
 	if pkFile == "" {
 		return nil, fmt.Errorf("invalid public key file")
 	}
@@ -244,5 +336,5 @@ func ParseServerTokenWithKeyfile(token string, pkFile string) (*ServerClaims, er
 		return nil, err
 	}
 
-	return ParseServerToken(token, pk)
+	return ParseServerToken(token, pk, opts...)
 }
@@ -0,0 +1,60 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"fmt"
+)
+
+const (
+	// MaxServerMetadataEntries is the most metadata items a server token may hold
+	MaxServerMetadataEntries = 32
+
+	// MaxServerMetadataSize is the combined size in bytes of all metadata keys and values a server token may hold
+	MaxServerMetadataSize = 2048
+)
+
+// WithServerMetadata signs metadata like datacenter, rack or hardware model into servers created using NewServerClaims,
+// the metadata is asserted by the issuer so inventory tooling can trust it over facts reported by the server itself
+func WithServerMetadata(md map[string]string) ClaimsOption {
+	return func(o *claimsOptions) error {
+		if o.serverMetadata == nil {
+			o.serverMetadata = make(map[string]string)
+		}
+
+		for k, v := range md {
+			o.serverMetadata[k] = v
+		}
+
+		return validateServerMetadata(o.serverMetadata)
+	}
+}
+
+// MetadataValue retrieves the metadata item key
+func (c *ServerClaims) MetadataValue(key string) (string, bool) {
+	v, ok := c.Metadata[key]
+	return v, ok
+}
+
+func validateServerMetadata(md map[string]string) error {
+	if len(md) > MaxServerMetadataEntries {
+		return fmt.Errorf("server metadata may hold at most %d items", MaxServerMetadataEntries)
+	}
+
+	size := 0
+	for k, v := range md {
+		if k == "" {
+			return fmt.Errorf("server metadata keys cannot be empty")
+		}
+
+		size += len(k) + len(v)
+	}
+
+	if size > MaxServerMetadataSize {
+		return fmt.Errorf("server metadata exceeds %d bytes", MaxServerMetadataSize)
+	}
+
+	return nil
+}
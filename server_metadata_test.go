@@ -0,0 +1,63 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Server metadata", func() {
+	var pubK ed25519.PublicKey
+
+	BeforeEach(func() {
+		var err error
+		pubK, _, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Should sign metadata into server tokens", func() {
+		claims, err := NewServerClaims("ginkgo.example.net", []string{"choria"}, "", nil, nil, pubK, "ginkgo issuer", time.Hour, WithServerMetadata(map[string]string{"dc": "lon1", "rack": "r12"}), WithServerMetadata(map[string]string{"model": "R640"}))
+		Expect(err).ToNot(HaveOccurred())
+		signed, err := SignTokenWithKeyFile(claims, "testdata/ed25519/signer.seed")
+		Expect(err).ToNot(HaveOccurred())
+
+		parsed, err := ParseServerTokenWithKeyfile(signed, "testdata/ed25519/signer.public")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(parsed.Metadata).To(Equal(map[string]string{"dc": "lon1", "rack": "r12", "model": "R640"}))
+		dc, ok := parsed.MetadataValue("dc")
+		Expect(ok).To(BeTrue())
+		Expect(dc).To(Equal("lon1"))
+		_, ok = parsed.MetadataValue("missing")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("Should limit the metadata", func() {
+		create := func(md map[string]string) error {
+			_, err := NewServerClaims("ginkgo.example.net", []string{"choria"}, "", nil, nil, pubK, "ginkgo issuer", time.Hour, WithServerMetadata(md))
+			return err
+		}
+
+		Expect(create(map[string]string{"": "x"})).To(MatchError("server metadata keys cannot be empty"))
+		Expect(create(map[string]string{"notes": strings.Repeat("x", MaxServerMetadataSize)})).To(MatchError(fmt.Sprintf("server metadata exceeds %d bytes", MaxServerMetadataSize)))
+
+		many := map[string]string{}
+		for i := 0; i <= MaxServerMetadataEntries; i++ {
+			many[fmt.Sprintf("k%d", i)] = "v"
+		}
+		Expect(create(many)).To(MatchError(fmt.Sprintf("server metadata may hold at most %d items", MaxServerMetadataEntries)))
+
+		claims, err := NewServerClaims("ginkgo.example.net", []string{"choria"}, "", nil, nil, pubK, "ginkgo issuer", time.Hour)
+		Expect(err).ToNot(HaveOccurred())
+		claims.Metadata = many
+		Expect(claims.Validate()).To(MatchError(ContainSubstring("at most")))
+	})
+})
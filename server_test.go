@@ -44,6 +44,46 @@ var _ = Describe("ServerClaims", func() {
 			Expect(err).To(MatchError("public key is required"))
 		})
 
+		It("Should validate the public key", func() {
+			_, err := NewServerClaims("ginkgo.example.net", []string{"choria"}, "", nil, nil, make([]byte, 32), "", time.Hour)
+			Expect(err).To(MatchError(ErrZeroPublicKey))
+
+			_, err = NewServerClaims("ginkgo.example.net", []string{"choria"}, "", nil, nil, pubK, "C-x."+hex.EncodeToString(pubK), time.Hour)
+			Expect(err).To(MatchError(ErrIssuerPublicKey))
+		})
+
+		It("Should support additional subjects", func() {
+			claims, err := NewServerClaims("ginkgo.example.net", []string{"choria"}, "", nil, []string{"site.adapter.out"}, pubK, "", time.Hour, WithServerSubscribeSubjects("site.adapter.*.in"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(claims.AdditionalPublishSubjects).To(Equal([]string{"site.adapter.out"}))
+			Expect(claims.AdditionalSubscribeSubjects).To(Equal([]string{"site.adapter.*.in"}))
+
+			_, err = NewServerClaims("ginkgo.example.net", []string{"choria"}, "", nil, nil, pubK, "", time.Hour, WithServerSubscribeSubjects("choria.broadcast.agent.rpcutil"))
+			Expect(err).To(MatchError("invalid subscribe subject: invalid subject: \"choria.broadcast.agent.rpcutil\" overlaps reserved subject \"*.broadcast.>\""))
+
+			_, err = NewServerClaims("ginkgo.example.net", []string{"choria"}, "", nil, nil, pubK, "", time.Hour, WithServerSubscribeSubjects("other.node.n2.example.net"))
+			Expect(err).To(MatchError(ContainSubstring("overlaps reserved subject \"*.node.>\"")))
+
+			_, err = NewServerClaims("ginkgo.example.net", []string{"choria"}, "", nil, nil, pubK, "", time.Hour, WithServerSubscribeSubjects(">"))
+			Expect(err).To(MatchError(ErrInvalidSubject))
+
+			claims, err = NewServerClaims("ginkgo.example.net", []string{"choria"}, "", nil, []string{"choria.broadcast.agent.registration.custom"}, pubK, "", time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(claims.AdditionalPublishSubjects).To(Equal([]string{"choria.broadcast.agent.registration.custom"}))
+		})
+
+		It("Should reject reserved subscribe subjects when parsing", func() {
+			claims, err := NewServerClaims("ginkgo.example.net", []string{"choria"}, "", nil, nil, pubK, "", time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+			claims.AdditionalSubscribeSubjects = []string{"choria.reply.>"}
+
+			token, err := SignToken(claims, priK)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = ParseServerToken(token, pubK)
+			Expect(err).To(MatchError(ContainSubstring("invalid subscribe subject")))
+		})
+
 		It("Should create a valid token", func() {
 			perms := &ServerPermissions{Submission: true}
 			claims, err := NewServerClaims("ginkgo.example.net", []string{"choria"}, "ginkgo_org", perms, []string{"choria.registration"}, pubK, "ginkgo issuer", 365*24*time.Hour)
@@ -253,5 +293,18 @@ var _ = Describe("ServerClaims", func() {
 			Expect(err).ToNot(HaveOccurred())
 			Expect(claims.ChoriaIdentity).To(Equal("ginkgo.example.net"))
 		})
+
+		It("Should enforce the audience when requested", func() {
+			claims, err := NewServerClaims("ginkgo.example.net", []string{"choria"}, "ginkgo_org", nil, nil, pubK, "ginkgo issuer", time.Hour, WithAudience("choria_broker"))
+			Expect(err).ToNot(HaveOccurred())
+			signed, err := SignTokenWithKeyFile(claims, "testdata/ed25519/signer.seed")
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = ParseServerTokenWithKeyfile(signed, "testdata/ed25519/signer.public", WithExpectedAudience("choria_aaa"))
+			Expect(err).To(MatchError("could not parse server id token: token has invalid audience"))
+
+			_, err = ParseServerTokenWithKeyfile(signed, "testdata/ed25519/signer.public", WithExpectedAudience("choria_broker"))
+			Expect(err).ToNot(HaveOccurred())
+		})
 	})
 })
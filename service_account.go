@@ -0,0 +1,236 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	// DefaultServiceAccountValidity is the validity used for service accounts when none is given
+	DefaultServiceAccountValidity = 365 * 24 * time.Hour
+
+	// ServiceAccountCallerIDProvider is the caller id provider used for service accounts
+	ServiceAccountCallerIDProvider = "service"
+)
+
+var (
+	ErrNotAServiceAccountToken        = errors.New("not a service account token")
+	ErrInvalidServiceAccountName      = errors.New("invalid service account name")
+	ErrServiceAccountPermissionDenied = errors.New("permission is not allowed for service accounts")
+
+	serviceAccountNameRe = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.\-]*$`)
+)
+
+// ServiceAccountClaims represents a headless automation identity that is neither an interactive client nor a server
+//
+// The "purpose" claim should be set to ServiceAccountPurpose
+type ServiceAccountClaims struct {
+	// Name is the name of the service account, the caller id will be service=<name>
+	Name string `json:"name"`
+
+	// Owner is a contact for the team or person responsible for the service account
+	Owner string `json:"owner,omitempty"`
+
+	// AllowedAgents is a list of agent names or agent.action names this service account can perform
+	AllowedAgents []string `json:"agents,omitempty"`
+
+	// OrganizationUnit broker account the service account should belong to
+	OrganizationUnit string `json:"ou,omitempty"`
+
+	// Permissions sets additional permissions for the service account, administrative permissions are not allowed
+	Permissions *ClientPermissions `json:"permissions,omitempty"`
+
+	// AdditionalPublishSubjects are additional subjects the service account can publish to
+	AdditionalPublishSubjects []string `json:"pub_subjects,omitempty"`
+
+	// AdditionalSubscribeSubjects are additional subjects the service account can subscribe to
+	AdditionalSubscribeSubjects []string `json:"sub_subjects,omitempty"`
+
+	StandardClaims
+}
+
+// CallerID is the choria caller id for the service account
+func (c *ServiceAccountClaims) CallerID() string {
+	return fmt.Sprintf("%s=%s", ServiceAccountCallerIDProvider, c.Name)
+}
+
+// UniqueID returns the caller id and unique id used to generate private inboxes
+func (c *ServiceAccountClaims) UniqueID() (id string, uid string) {
+	cid := c.CallerID()
+	return cid, fmt.Sprintf("%x", md5.Sum([]byte(cid)))
+}
+
+// NewServiceAccountClaims generates new ServiceAccountClaims, when validity is 0 DefaultServiceAccountValidity is used
+//
+// When perms is nil the service account will have only the ExtendedServiceLifetime permission, administrative
+// permissions like OrgAdmin, SystemUser, StreamsAdmin, AuthenticationDelegator and ServerProvisioner are not allowed
+func NewServiceAccountClaims(name string, owner string, allowedAgents []string, org string, issuer string, validity time.Duration, perms *ClientPermissions, pk ed25519.PublicKey, opts ...ClaimsOption) (*ServiceAccountClaims, error) {
+	if !serviceAccountNameRe.MatchString(name) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidServiceAccountName, name)
+	}
+
+	if pk == nil {
+		return nil, fmt.Errorf("public key is required")
+	}
+
+	if validity == 0 {
+		validity = DefaultServiceAccountValidity
+	}
+
+	if perms == nil {
+		perms = &ClientPermissions{}
+	} else {
+		p := *perms
+		perms = &p
+	}
+	perms.ExtendedServiceLifetime = true
+
+	err := validateServiceAccountPermissions(perms)
+	if err != nil {
+		return nil, err
+	}
+
+	stdClaims, err := newStandardClaims(issuer, ServiceAccountPurpose, validity, false, append([]ClaimsOption{withPublicKey(pk)}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	if org == "" {
+		org = defaultOrg
+	}
+
+	claims := &ServiceAccountClaims{
+		Name:             name,
+		Owner:            owner,
+		AllowedAgents:    allowedAgents,
+		OrganizationUnit: org,
+		Permissions:      perms,
+		StandardClaims:   *stdClaims,
+	}
+	claims.Subject = claims.CallerID()
+
+	return claims, nil
+}
+
+// IsServiceAccountToken determines if this is a service account token
+func IsServiceAccountToken(claims StandardClaims) bool {
+	return claims.Purpose == ServiceAccountPurpose
+}
+
+// IsServiceAccountTokenString calls IsServiceAccountToken on the token in a string
+func IsServiceAccountTokenString(token string) (bool, error) {
+	claims := &ServiceAccountClaims{}
+	_, err := parseUnverified(token, claims)
+	if err != nil {
+		return false, err
+	}
+
+	return IsServiceAccountToken(claims.StandardClaims), nil
+}
+
+// ParseServiceAccountTokenUnverified parses the service account token in an unverified manner.
+func ParseServiceAccountTokenUnverified(token string) (*ServiceAccountClaims, error) {
+	claims := &ServiceAccountClaims{}
+	_, err := parseUnverified(token, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	if !IsServiceAccountToken(claims.StandardClaims) {
+		return nil, ErrNotAServiceAccountToken
+	}
+
+	if claims.OrganizationUnit == "" {
+		claims.OrganizationUnit = defaultOrg
+	}
+
+	return claims, nil
+}
+
+// ParseServiceAccountToken parses token and verifies it with pk
+func ParseServiceAccountToken(token string, pk any, opts ...ParseOption) (*ServiceAccountClaims, error) {
+	claims := &ServiceAccountClaims{}
+	err := ParseToken(token, claims, pk, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse service account token: %w", err)
+	}
+
+	// if we have a tcs we require an issuer expiry to be set and it to not have expired
+	if !claims.verifyIssuerExpiry(claims.TrustChainSignature != "") {
+		return nil, jwt.ErrTokenExpired
+	}
+
+	if claims.OrganizationUnit == "" {
+		claims.OrganizationUnit = defaultOrg
+	}
+
+	return claims, nil
+}
+
+// ParseServiceAccountTokenWithKeyfile parses token and verifies it with the RSA or ED25519 Public key in pkFile
+func ParseServiceAccountTokenWithKeyfile(token string, pkFile string, opts ...ParseOption) (*ServiceAccountClaims, error) {
+	if pkFile == "" {
+		return nil, fmt.Errorf("invalid public key file")
+	}
+
+	certdat, err := os.ReadFile(pkFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not read validation certificate: %s", err)
+	}
+
+	pk, err := readRSAOrED25519PublicData(certdat)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseServiceAccountToken(token, pk, opts...)
+}
+
+// Validate checks the account name, public key and permissions
+func (c *ServiceAccountClaims) Validate() error {
+	if !IsServiceAccountToken(c.StandardClaims) {
+		return ErrNotAServiceAccountToken
+	}
+
+	if !serviceAccountNameRe.MatchString(c.Name) {
+		return fmt.Errorf("%w: %q", ErrInvalidServiceAccountName, c.Name)
+	}
+
+	if c.PublicKey == "" {
+		return fmt.Errorf("no public key in service account token")
+	}
+
+	return validateServiceAccountPermissions(c.Permissions)
+}
+
+func validateServiceAccountPermissions(perms *ClientPermissions) error {
+	if perms == nil {
+		return nil
+	}
+
+	switch {
+	case perms.OrgAdmin:
+		return fmt.Errorf("%w: org_admin", ErrServiceAccountPermissionDenied)
+	case perms.SystemUser:
+		return fmt.Errorf("%w: system_user", ErrServiceAccountPermissionDenied)
+	case perms.StreamsAdmin:
+		return fmt.Errorf("%w: streams_admin", ErrServiceAccountPermissionDenied)
+	case perms.AuthenticationDelegator:
+		return fmt.Errorf("%w: authentication_delegator", ErrServiceAccountPermissionDenied)
+	case perms.ServerProvisioner:
+		return fmt.Errorf("%w: provisioner", ErrServiceAccountPermissionDenied)
+	}
+
+	return nil
+}
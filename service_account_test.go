@@ -0,0 +1,150 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ServiceAccountClaims", func() {
+	var (
+		pubK ed25519.PublicKey
+		priK ed25519.PrivateKey
+		err  error
+	)
+
+	BeforeEach(func() {
+		pubK, priK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Describe("NewServiceAccountClaims", func() {
+		It("Should validate the name", func() {
+			for _, n := range []string{"", "-backup", "backup job", "backup=job"} {
+				_, err := NewServiceAccountClaims(n, "", nil, "", "", 0, nil, pubK)
+				Expect(err).To(MatchError(ErrInvalidServiceAccountName))
+			}
+		})
+
+		It("Should require a public key", func() {
+			_, err := NewServiceAccountClaims("backup", "", nil, "", "", 0, nil, nil)
+			Expect(err).To(MatchError("public key is required"))
+		})
+
+		It("Should reject administrative permissions", func() {
+			for _, p := range []*ClientPermissions{{OrgAdmin: true}, {SystemUser: true}, {StreamsAdmin: true}, {AuthenticationDelegator: true}, {ServerProvisioner: true}} {
+				_, err := NewServiceAccountClaims("backup", "", nil, "", "", 0, p, pubK)
+				Expect(err).To(MatchError(ErrServiceAccountPermissionDenied))
+			}
+		})
+
+		It("Should create long lived restricted claims by default", func() {
+			claims, err := NewServiceAccountClaims("backup", "ops@example.net", []string{"rpcutil"}, "", "ginkgo", 0, nil, pubK)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(claims.Purpose).To(Equal(ServiceAccountPurpose))
+			Expect(claims.Name).To(Equal("backup"))
+			Expect(claims.Owner).To(Equal("ops@example.net"))
+			Expect(claims.CallerID()).To(Equal("service=backup"))
+			Expect(claims.Subject).To(Equal("service=backup"))
+			Expect(claims.AllowedAgents).To(Equal([]string{"rpcutil"}))
+			Expect(claims.OrganizationUnit).To(Equal(defaultOrg))
+			Expect(claims.Permissions).To(Equal(&ClientPermissions{ExtendedServiceLifetime: true}))
+			Expect(claims.PublicKey).To(Equal(hex.EncodeToString(pubK)))
+			Expect(claims.ExpiresAt.Time).To(BeTemporally("~", time.Now().Add(DefaultServiceAccountValidity), time.Second))
+		})
+
+		It("Should not modify the supplied permissions", func() {
+			perms := &ClientPermissions{FleetManagement: true}
+			claims, err := NewServiceAccountClaims("backup", "", nil, "", "", time.Hour, perms, pubK)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(claims.Permissions).To(Equal(&ClientPermissions{FleetManagement: true, ExtendedServiceLifetime: true}))
+			Expect(perms.ExtendedServiceLifetime).To(BeFalse())
+			Expect(claims.ExpiresAt.Time).To(BeTemporally("~", time.Now().Add(time.Hour), time.Second))
+		})
+	})
+
+	Describe("UniqueID", func() {
+		It("Should be based on the caller id", func() {
+			claims, err := NewServiceAccountClaims("backup", "", nil, "", "", 0, nil, pubK)
+			Expect(err).ToNot(HaveOccurred())
+
+			id, uid := claims.UniqueID()
+			Expect(id).To(Equal("service=backup"))
+			Expect(uid).To(Equal(fmt.Sprintf("%x", md5.Sum([]byte("service=backup")))))
+		})
+	})
+
+	Describe("ParseServiceAccountToken", func() {
+		It("Should parse and validate tokens", func() {
+			claims, err := NewServiceAccountClaims("backup", "", nil, "", "", 0, nil, pubK)
+			Expect(err).ToNot(HaveOccurred())
+
+			token, err := SignToken(claims, priK)
+			Expect(err).ToNot(HaveOccurred())
+
+			parsed, err := ParseServiceAccountToken(token, pubK)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(parsed.CallerID()).To(Equal("service=backup"))
+
+			Expect(IsServiceAccountTokenString(token)).To(BeTrue())
+
+			unverified, err := ParseServiceAccountTokenUnverified(token)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(unverified.Name).To(Equal("backup"))
+		})
+
+		It("Should reject other tokens", func() {
+			claims, err := NewClientIDClaims("up=ginkgo", nil, "", nil, "", "", time.Hour, nil, pubK)
+			Expect(err).ToNot(HaveOccurred())
+
+			token, err := SignToken(claims, priK)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = ParseServiceAccountToken(token, pubK)
+			Expect(err).To(MatchError(ErrNotAServiceAccountToken))
+
+			_, err = ParseServiceAccountTokenUnverified(token)
+			Expect(err).To(MatchError(ErrNotAServiceAccountToken))
+
+			Expect(IsServiceAccountTokenString(token)).To(BeFalse())
+		})
+
+		It("Should reject tokens with elevated permissions", func() {
+			claims, err := NewServiceAccountClaims("backup", "", nil, "", "", 0, nil, pubK)
+			Expect(err).ToNot(HaveOccurred())
+			claims.Permissions.OrgAdmin = true
+
+			token, err := SignToken(claims, priK)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = ParseServiceAccountToken(token, pubK)
+			Expect(err).To(MatchError(ErrServiceAccountPermissionDenied))
+		})
+
+		It("Should support key files", func() {
+			_, err := ParseServiceAccountTokenWithKeyfile("", "")
+			Expect(err).To(MatchError("invalid public key file"))
+
+			_, prik := loadEd25519Seed("testdata/ed25519/other.seed")
+			claims, err := NewServiceAccountClaims("backup", "", nil, "", "", 0, nil, pubK)
+			Expect(err).ToNot(HaveOccurred())
+
+			token, err := SignToken(claims, prik)
+			Expect(err).ToNot(HaveOccurred())
+
+			parsed, err := ParseServiceAccountTokenWithKeyfile(token, "testdata/ed25519/other.public")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(parsed.PublicKey).To(Equal(hex.EncodeToString(pubK)))
+		})
+	})
+})
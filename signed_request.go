@@ -0,0 +1,136 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// ErrInvalidRequestSignature indicates a signed request was not signed by the holder of the token it carries
+var ErrInvalidRequestSignature = errors.New("invalid request signature")
+
+// SignedRequest is an envelope holding a payload, the token of the caller and the signature of the payload made
+// using the private key of that token.
+//
+// As with Choria RPC requests the signature is the ed25519 signature of the exact payload bytes, without hashing
+// or framing, hex encoded
+type SignedRequest struct {
+	// Token is the token of the caller
+	Token string `json:"token"`
+
+	// Payload is the signed data
+	Payload []byte `json:"payload"`
+
+	// Signature is the hex encoded ed25519 signature of Payload
+	Signature string `json:"signature"`
+}
+
+// SignRequest signs payload using signer, the ed25519 private key matching the public key in token, and returns
+// the JSON encoded SignedRequest
+func SignRequest(token string, signer crypto.Signer, payload []byte) ([]byte, error) {
+	holder, err := tokenHolderKey(token)
+	if err != nil {
+		return nil, err
+	}
+
+	sig, pk, err := ed25519SignWithSigner(signer, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(pk, holder) {
+		return nil, fmt.Errorf("signer does not match the public key of the token")
+	}
+
+	return json.Marshal(&SignedRequest{Token: token, Payload: payload, Signature: hex.EncodeToString(sig)})
+}
+
+// SignRequestWithSeedFile signs payload using the ed25519 seed stored in seedFile, see SignRequest
+func SignRequestWithSeedFile(token string, seedFile string, payload []byte) ([]byte, error) {
+	_, pri, err := ed25519KeyPairFromSeedFile(seedFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return SignRequest(token, pri, payload)
+}
+
+// VerifyRequest verifies the token in bundle, a JSON encoded SignedRequest, using trustStore and that the payload
+// was signed by the holder of the token. The verified payload and claims of the caller are returned
+func VerifyRequest(bundle []byte, trustStore *KeyRing, opts ...ParseOption) ([]byte, jwt.Claims, error) {
+	if trustStore == nil {
+		return nil, nil, fmt.Errorf("trust store is required")
+	}
+
+	req := &SignedRequest{}
+	err := json.Unmarshal(bundle, req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid signed request: %w", err)
+	}
+
+	if req.Token == "" || req.Signature == "" {
+		return nil, nil, fmt.Errorf("invalid signed request: token and signature are required")
+	}
+
+	sig, err := hex.DecodeString(req.Signature)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidRequestSignature, err)
+	}
+
+	claims := newClaimsForPurpose(TokenPurpose(req.Token))
+	_, err = trustStore.ParseToken(req.Token, claims, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sc, ok := claims.(standardClaimsProvider)
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported claims %T", claims)
+	}
+
+	holder := claimsPublicKey(sc.getStandardClaims())
+	if holder == nil {
+		return nil, nil, fmt.Errorf("%w: token does not hold a public key", ErrInvalidRequestSignature)
+	}
+
+	valid, err := ed25519Verify(holder, req.Payload, sig)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidRequestSignature, err)
+	}
+	if !valid {
+		return nil, nil, ErrInvalidRequestSignature
+	}
+
+	return req.Payload, claims, nil
+}
+
+// tokenHolderKey is the ed25519 public key embedded in token, the token is not verified
+func tokenHolderKey(token string) (ed25519.PublicKey, error) {
+	claims := newClaimsForPurpose(TokenPurpose(token))
+	_, err := parseUnverified(token, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	sc, ok := claims.(standardClaimsProvider)
+	if !ok {
+		return nil, fmt.Errorf("unsupported claims %T", claims)
+	}
+
+	holder := claimsPublicKey(sc.getStandardClaims())
+	if holder == nil {
+		return nil, fmt.Errorf("token does not hold a public key")
+	}
+
+	return holder, nil
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Signed requests", func() {
+	var (
+		issuerPub ed25519.PublicKey
+		issuerPri ed25519.PrivateKey
+		callerPri ed25519.PrivateKey
+		token     string
+		trust     *KeyRing
+	)
+
+	BeforeEach(func() {
+		var err error
+		issuerPub, issuerPri, err = ed25519.GenerateKey(nil)
+		Expect(err).ToNot(HaveOccurred())
+		callerPub, pri, err := ed25519.GenerateKey(nil)
+		Expect(err).ToNot(HaveOccurred())
+		callerPri = pri
+
+		claims, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, callerPub)
+		Expect(err).ToNot(HaveOccurred())
+		token, err = SignToken(claims, issuerPri)
+		Expect(err).ToNot(HaveOccurred())
+
+		trust, err = NewKeyRing(issuerPub)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Should sign and verify requests", func() {
+		bundle, err := SignRequest(token, callerPri, []byte(`{"agent":"rpcutil","action":"ping"}`))
+		Expect(err).ToNot(HaveOccurred())
+
+		req := &SignedRequest{}
+		Expect(json.Unmarshal(bundle, req)).To(Succeed())
+		Expect(req.Token).To(Equal(token))
+		Expect(req.Signature).To(Equal(hex.EncodeToString(ed25519.Sign(callerPri, req.Payload))))
+
+		payload, claims, err := VerifyRequest(bundle, trust)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(payload)).To(Equal(`{"agent":"rpcutil","action":"ping"}`))
+		Expect(claims.(*ClientIDClaims).CallerID).To(Equal("up=bob"))
+	})
+
+	It("Should sign using seed files", func() {
+		pk, _ := loadEd25519Seed("testdata/ed25519/signer.seed")
+		claims, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, pk)
+		Expect(err).ToNot(HaveOccurred())
+		token, err = SignToken(claims, issuerPri)
+		Expect(err).ToNot(HaveOccurred())
+
+		bundle, err := SignRequestWithSeedFile(token, "testdata/ed25519/signer.seed", []byte("payload"))
+		Expect(err).ToNot(HaveOccurred())
+		_, _, err = VerifyRequest(bundle, trust)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = SignRequestWithSeedFile(token, "testdata/ed25519/other.seed", []byte("payload"))
+		Expect(err).To(MatchError("signer does not match the public key of the token"))
+	})
+
+	It("Should detect invalid requests", func() {
+		bundle, err := SignRequest(token, callerPri, []byte("payload"))
+		Expect(err).ToNot(HaveOccurred())
+
+		req := &SignedRequest{}
+		Expect(json.Unmarshal(bundle, req)).To(Succeed())
+		req.Payload = []byte("other payload")
+		tampered, err := json.Marshal(req)
+		Expect(err).ToNot(HaveOccurred())
+		_, _, err = VerifyRequest(tampered, trust)
+		Expect(err).To(MatchError(ErrInvalidRequestSignature))
+
+		otherPub, _, err := ed25519.GenerateKey(nil)
+		Expect(err).ToNot(HaveOccurred())
+		other, err := NewKeyRing(otherPub)
+		Expect(err).ToNot(HaveOccurred())
+		_, _, err = VerifyRequest(bundle, other)
+		Expect(isSignatureError(err)).To(BeTrue())
+
+		_, _, err = VerifyRequest([]byte("{}"), trust)
+		Expect(err).To(MatchError("invalid signed request: token and signature are required"))
+		_, _, err = VerifyRequest(bundle, nil)
+		Expect(err).To(MatchError("trust store is required"))
+	})
+})
@@ -0,0 +1,278 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// maxSimulationBuckets is the most buckets a simulation can report
+	maxSimulationBuckets = 100000
+
+	// maxSimulationEvents is the most issuance and renewal events a simulation can count
+	maxSimulationEvents = 50000000
+)
+
+// IssuancePolicy describes how long tokens issued to a group of the fleet are valid and when they are renewed
+type IssuancePolicy struct {
+	// Validity is how long issued tokens are valid for, zero for tokens that never expire and are issued once
+	Validity time.Duration `json:"validity"`
+
+	// RenewBefore is how long before expiry tokens are renewed, defaults to a third of Validity
+	RenewBefore time.Duration `json:"renew_before,omitempty"`
+}
+
+// renewalInterval is the time between signatures for a single token, zero when tokens are never renewed
+func (p IssuancePolicy) renewalInterval() time.Duration {
+	if p.Validity == 0 {
+		return 0
+	}
+
+	before := p.RenewBefore
+	if before == 0 {
+		before = p.Validity / 3
+	}
+
+	return p.Validity - before
+}
+
+func (p IssuancePolicy) validate() error {
+	if p.Validity < 0 {
+		return fmt.Errorf("validity cannot be negative")
+	}
+
+	if p.RenewBefore < 0 {
+		return fmt.Errorf("renew before cannot be negative")
+	}
+
+	if p.Validity > 0 && p.RenewBefore >= p.Validity {
+		return fmt.Errorf("renew before must be shorter than the validity")
+	}
+
+	return nil
+}
+
+// FleetGroup describes a group of identical fleet members that are issued tokens under the same policy
+type FleetGroup struct {
+	// Name is a descriptive name for the group
+	Name string `json:"name"`
+
+	// Purpose is the purpose of tokens issued to the group
+	Purpose Purpose `json:"purpose"`
+
+	// Count is how many members are in the group, each receiving one token
+	Count int `json:"count"`
+
+	// Policy is the issuance policy for the group
+	Policy IssuancePolicy `json:"policy"`
+
+	// RolloutPeriod spreads the initial issuance evenly over this period, zero issues all tokens at the start
+	RolloutPeriod time.Duration `json:"rollout_period,omitempty"`
+}
+
+// SimulationOptions configures an issuance simulation
+type SimulationOptions struct {
+	// Start is the time the simulation starts, defaults to now
+	Start time.Time
+
+	// Duration is how long to simulate for
+	Duration time.Duration
+
+	// Interval is the size of the buckets signatures are counted in, defaults to an hour
+	Interval time.Duration
+}
+
+// SimulationBucket is the signer load during one interval of a simulation
+type SimulationBucket struct {
+	// Start is when the interval starts
+	Start time.Time `json:"start"`
+
+	// Issued is how many tokens were issued for the first time
+	Issued int `json:"issued"`
+
+	// Renewed is how many tokens were renewed
+	Renewed int `json:"renewed"`
+
+	// Signatures is the total number of signing operations
+	Signatures int `json:"signatures"`
+
+	// Active is how many tokens are held by the fleet at the end of the interval
+	Active int `json:"active"`
+}
+
+// GroupEstimate summarizes the simulated issuance for a single fleet group
+type GroupEstimate struct {
+	// Name is the name of the group
+	Name string `json:"name"`
+
+	// Purpose is the purpose of tokens issued to the group
+	Purpose Purpose `json:"purpose"`
+
+	// Tokens is how many tokens the group holds once fully rolled out
+	Tokens int `json:"tokens"`
+
+	// RenewalInterval is how often each token is renewed, zero when tokens are not renewed
+	RenewalInterval time.Duration `json:"renewal_interval"`
+
+	// Issued is how many tokens were issued for the first time during the simulation
+	Issued int `json:"issued"`
+
+	// Renewed is how many renewals were performed during the simulation
+	Renewed int `json:"renewed"`
+
+	// SteadyStateRate is the long term signatures per second once all tokens are rolled out
+	SteadyStateRate float64 `json:"steady_state_rate"`
+}
+
+// SimulationReport is the expected token counts and signer load for a fleet over time
+type SimulationReport struct {
+	// Start is when the simulation starts
+	Start time.Time `json:"start"`
+
+	// End is when the simulation ends
+	End time.Time `json:"end"`
+
+	// Interval is the size of each bucket
+	Interval time.Duration `json:"interval"`
+
+	// Buckets is the signer load over time
+	Buckets []*SimulationBucket `json:"buckets"`
+
+	// Groups summarizes each fleet group
+	Groups []*GroupEstimate `json:"groups"`
+
+	// TotalTokens is how many tokens the fleet holds once fully rolled out
+	TotalTokens int `json:"total_tokens"`
+
+	// TotalSignatures is how many signing operations were performed during the simulation
+	TotalSignatures int `json:"total_signatures"`
+
+	// PeakSignatures is the most signatures performed in a single bucket
+	PeakSignatures int `json:"peak_signatures"`
+
+	// PeakAt is the start of the first bucket with the most signatures
+	PeakAt time.Time `json:"peak_at"`
+
+	// PeakRate is the signatures per second during the peak bucket
+	PeakRate float64 `json:"peak_rate"`
+
+	// SteadyStateRate is the long term signatures per second for the whole fleet
+	SteadyStateRate float64 `json:"steady_state_rate"`
+}
+
+// SimulateIssuance computes the expected token counts, renewal schedules and signer load for a fleet over time
+func SimulateIssuance(groups []FleetGroup, opts SimulationOptions) (*SimulationReport, error) {
+	if opts.Duration <= 0 {
+		return nil, fmt.Errorf("simulation duration is required")
+	}
+	if opts.Interval < 0 {
+		return nil, fmt.Errorf("simulation interval cannot be negative")
+	}
+	if opts.Interval == 0 {
+		opts.Interval = time.Hour
+	}
+	if opts.Start.IsZero() {
+		opts.Start = currentTime()
+	}
+
+	buckets := (opts.Duration + opts.Interval - 1) / opts.Interval
+	if buckets > maxSimulationBuckets {
+		return nil, fmt.Errorf("simulation requires %d buckets, at most %d are supported, use a longer interval", buckets, maxSimulationBuckets)
+	}
+
+	events := 0.0
+	for i, g := range groups {
+		if g.Count < 0 {
+			return nil, fmt.Errorf("group %d (%s): count cannot be negative", i, g.Name)
+		}
+		if g.RolloutPeriod < 0 {
+			return nil, fmt.Errorf("group %d (%s): rollout period cannot be negative", i, g.Name)
+		}
+		err := g.Policy.validate()
+		if err != nil {
+			return nil, fmt.Errorf("group %d (%s): %w", i, g.Name, err)
+		}
+
+		events += float64(g.Count)
+		if interval := g.Policy.renewalInterval(); interval > 0 {
+			events += float64(g.Count) * float64(opts.Duration) / float64(interval)
+		}
+	}
+
+	if events > maxSimulationEvents {
+		return nil, fmt.Errorf("simulation requires about %.0f events, at most %d are supported, use a shorter duration or longer renewal intervals", events, maxSimulationEvents)
+	}
+
+	report := &SimulationReport{
+		Start:    opts.Start,
+		End:      opts.Start.Add(opts.Duration),
+		Interval: opts.Interval,
+	}
+
+	report.Buckets = make([]*SimulationBucket, buckets)
+	for i := range report.Buckets {
+		report.Buckets[i] = &SimulationBucket{Start: opts.Start.Add(time.Duration(i) * opts.Interval)}
+	}
+
+	for _, g := range groups {
+		est := &GroupEstimate{
+			Name:            g.Name,
+			Purpose:         g.Purpose,
+			Tokens:          g.Count,
+			RenewalInterval: g.Policy.renewalInterval(),
+		}
+		if est.RenewalInterval > 0 {
+			est.SteadyStateRate = float64(g.Count) / est.RenewalInterval.Seconds()
+		}
+
+		for i := 0; i < g.Count; i++ {
+			issued := time.Duration(0)
+			if g.RolloutPeriod > 0 {
+				issued = time.Duration(int64(g.RolloutPeriod) / int64(g.Count) * int64(i))
+			}
+			if issued >= opts.Duration {
+				continue
+			}
+
+			first := int(issued / opts.Interval)
+			report.Buckets[first].Issued++
+			est.Issued++
+
+			if est.RenewalInterval == 0 {
+				continue
+			}
+
+			for t := issued + est.RenewalInterval; t < opts.Duration; t += est.RenewalInterval {
+				report.Buckets[int(t/opts.Interval)].Renewed++
+				est.Renewed++
+			}
+		}
+
+		report.Groups = append(report.Groups, est)
+		report.TotalTokens += est.Tokens
+		report.SteadyStateRate += est.SteadyStateRate
+	}
+
+	active := 0
+	for _, b := range report.Buckets {
+		active += b.Issued
+		b.Active = active
+		b.Signatures = b.Issued + b.Renewed
+		report.TotalSignatures += b.Signatures
+
+		if b.Signatures > report.PeakSignatures {
+			report.PeakSignatures = b.Signatures
+			report.PeakAt = b.Start
+		}
+	}
+
+	if report.PeakSignatures > 0 {
+		report.PeakRate = float64(report.PeakSignatures) / opts.Interval.Seconds()
+	}
+
+	return report, nil
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SimulateIssuance", func() {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	It("Should validate the options and groups", func() {
+		_, err := SimulateIssuance(nil, SimulationOptions{})
+		Expect(err).To(MatchError("simulation duration is required"))
+
+		_, err = SimulateIssuance(nil, SimulationOptions{Duration: time.Hour, Interval: -1})
+		Expect(err).To(MatchError("simulation interval cannot be negative"))
+
+		_, err = SimulateIssuance([]FleetGroup{{Name: "servers", Count: -1}}, SimulationOptions{Duration: time.Hour})
+		Expect(err).To(MatchError("group 0 (servers): count cannot be negative"))
+
+		_, err = SimulateIssuance([]FleetGroup{{Name: "servers", Count: 1, RolloutPeriod: -1}}, SimulationOptions{Duration: time.Hour})
+		Expect(err).To(MatchError("group 0 (servers): rollout period cannot be negative"))
+
+		_, err = SimulateIssuance([]FleetGroup{{Name: "servers", Count: 1, Policy: IssuancePolicy{Validity: time.Hour, RenewBefore: time.Hour}}}, SimulationOptions{Duration: time.Hour})
+		Expect(err).To(MatchError("group 0 (servers): renew before must be shorter than the validity"))
+	})
+
+	It("Should refuse simulations that are too large", func() {
+		_, err := SimulateIssuance(nil, SimulationOptions{Duration: 365 * 24 * time.Hour, Interval: time.Second})
+		Expect(err).To(MatchError(ContainSubstring("at most 100000 are supported, use a longer interval")))
+
+		_, err = SimulateIssuance([]FleetGroup{{Name: "servers", Count: 1000, Policy: IssuancePolicy{Validity: time.Millisecond}}}, SimulationOptions{Duration: 24 * time.Hour})
+		Expect(err).To(MatchError(ContainSubstring("at most 50000000 are supported, use a shorter duration or longer renewal intervals")))
+	})
+
+	It("Should default the interval and start", func() {
+		report, err := SimulateIssuance(nil, SimulationOptions{Duration: 24 * time.Hour})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.Interval).To(Equal(time.Hour))
+		Expect(report.Buckets).To(HaveLen(24))
+		Expect(report.Start).To(BeTemporally("~", time.Now(), time.Second))
+		Expect(report.End.Sub(report.Start)).To(Equal(24 * time.Hour))
+	})
+
+	It("Should issue tokens that never expire once", func() {
+		report, err := SimulateIssuance([]FleetGroup{{Name: "static", Purpose: ServerPurpose, Count: 10}}, SimulationOptions{Start: start, Duration: 10 * time.Hour})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.TotalSignatures).To(Equal(10))
+		Expect(report.Buckets[0].Issued).To(Equal(10))
+		Expect(report.Buckets[9].Active).To(Equal(10))
+		Expect(report.Groups[0].RenewalInterval).To(BeZero())
+		Expect(report.Groups[0].Renewed).To(BeZero())
+		Expect(report.SteadyStateRate).To(BeZero())
+	})
+
+	It("Should compute renewal schedules and peaks", func() {
+		groups := []FleetGroup{
+			{Name: "servers", Purpose: ServerPurpose, Count: 100, Policy: IssuancePolicy{Validity: 3 * time.Hour, RenewBefore: time.Hour}},
+			{Name: "clients", Purpose: ClientIDPurpose, Count: 10, Policy: IssuancePolicy{Validity: 6 * time.Hour}},
+		}
+
+		report, err := SimulateIssuance(groups, SimulationOptions{Start: start, Duration: 10 * time.Hour})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.Buckets).To(HaveLen(10))
+		Expect(report.TotalTokens).To(Equal(110))
+
+		servers := report.Groups[0]
+		Expect(servers.RenewalInterval).To(Equal(2 * time.Hour))
+		Expect(servers.Issued).To(Equal(100))
+		Expect(servers.Renewed).To(Equal(400))
+		Expect(servers.SteadyStateRate).To(BeNumerically("~", 100.0/7200, 0.0001))
+
+		clients := report.Groups[1]
+		Expect(clients.RenewalInterval).To(Equal(4 * time.Hour))
+		Expect(clients.Renewed).To(Equal(20))
+
+		Expect(report.TotalSignatures).To(Equal(530))
+		Expect(report.Buckets[0].Signatures).To(Equal(110))
+		Expect(report.Buckets[1].Signatures).To(BeZero())
+		Expect(report.Buckets[4].Signatures).To(Equal(110))
+		Expect(report.PeakSignatures).To(Equal(110))
+		Expect(report.PeakAt).To(Equal(start))
+		Expect(report.PeakRate).To(BeNumerically("~", 110.0/3600, 0.0001))
+	})
+
+	It("Should spread the initial issuance over the rollout period", func() {
+		groups := []FleetGroup{
+			{Name: "servers", Purpose: ServerPurpose, Count: 100, RolloutPeriod: 4 * time.Hour, Policy: IssuancePolicy{Validity: 12 * time.Hour}},
+		}
+
+		report, err := SimulateIssuance(groups, SimulationOptions{Start: start, Duration: 2 * time.Hour})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.Buckets[0].Issued).To(Equal(25))
+		Expect(report.Buckets[0].Active).To(Equal(25))
+		Expect(report.Buckets[1].Issued).To(Equal(25))
+		Expect(report.Buckets[1].Active).To(Equal(50))
+		Expect(report.Groups[0].Issued).To(Equal(50))
+		Expect(report.Groups[0].Tokens).To(Equal(100))
+		Expect(report.PeakSignatures).To(Equal(25))
+	})
+})
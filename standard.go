@@ -5,23 +5,57 @@
 package tokens
 
 import (
-	"context"
+	"crypto"
 	"crypto/ed25519"
-	"crypto/tls"
+	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"net/url"
 	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/segmentio/ksuid"
-	"github.com/sirupsen/logrus"
 )
 
+// IssuerMetadata describes the issuer of a token to assist with incident response
+type IssuerMetadata struct {
+	// Contact is the security contact for the issuer, typically an email address
+	Contact string `json:"contact,omitempty"`
+
+	// RevocationURL is where leaked or compromised tokens should be reported
+	RevocationURL string `json:"revocation_url,omitempty"`
+
+	// PolicyURL is a document describing the issuance policy of the issuer
+	PolicyURL string `json:"policy_url,omitempty"`
+}
+
+func (m *IssuerMetadata) validate() error {
+	for _, u := range []string{m.RevocationURL, m.PolicyURL} {
+		if u == "" {
+			continue
+		}
+
+		uri, err := url.Parse(u)
+		if err != nil {
+			return fmt.Errorf("invalid issuer metadata url %q: %w", u, err)
+		}
+		if !uri.IsAbs() {
+			return fmt.Errorf("invalid issuer metadata url %q: not an absolute url", u)
+		}
+	}
+
+	return nil
+}
+
 type StandardClaims struct {
 	// Purpose indicates the type of JWT for type discovery
 	Purpose Purpose `json:"purpose"`
 
+	// SchemaVersion is the version of the claims layout for the purpose, see MigrateClaims
+	SchemaVersion int `json:"schema,omitempty"`
+
 	// TrustChainSignature is a structure that helps to verify a chain of trust to a org issuer
 	TrustChainSignature string `json:"tcs,omitempty"`
 
@@ -31,9 +65,106 @@ type StandardClaims struct {
 	// IssuerExpiresAt is the expiry time of the issuer, if set will be checked in addition to the expiry time of the token itself
 	IssuerExpiresAt *jwt.NumericDate `json:"issexp,omitempty"`
 
+	// IssuerMetadata holds optional contact and policy information about the issuer
+	IssuerMetadata *IssuerMetadata `json:"issuer_meta,omitempty"`
+
+	// CustomClaims holds arbitrary site specific data, it is signed along with the rest of the token
+	CustomClaims map[string]any `json:"custom_claims,omitempty"`
+
+	// ReissuedFrom is the token id of the token this one replaced when it was reissued or renewed
+	ReissuedFrom string `json:"reissued_from,omitempty"`
+
+	// IssuerChain holds the tokens of the chain issuers that issued this token, starting with the one signed by the org issuer
+	IssuerChain []string `json:"issuer_chain,omitempty"`
+
+	// ChainMaxDepth is how many further levels of chain issuers a chain issuer may delegate to, 0 prevents further delegation
+	ChainMaxDepth int `json:"chain_max_depth,omitempty"`
+
+	// ChainDelegationExpiresAt is when a chain issuer loses the ability to issue tokens, it can be before the chain issuer token expires
+	ChainDelegationExpiresAt *jwt.NumericDate `json:"chain_delegation_exp,omitempty"`
+
+	// IssuerConstraints limits the access this token may grant to tokens it issues when it is a chain issuer
+	IssuerConstraints *ChainIssuerConstraints `json:"issuer_constraints,omitempty"`
+
+	// allowIssuerKey is set by AllowIssuerPublicKey to allow an issuer that holds PublicKey
+	allowIssuerKey bool
+
 	jwt.RegisteredClaims
 }
 
+type standardClaimsProvider interface {
+	getStandardClaims() *StandardClaims
+}
+
+func (c *StandardClaims) getStandardClaims() *StandardClaims {
+	return c
+}
+
+// TokenID is the unique id of the token, the jti claim, suitable for audit correlation and revocation
+func (c *StandardClaims) TokenID() string {
+	return c.ID
+}
+
+// TokenIDTime is the time embedded in the time ordered token id
+func (c *StandardClaims) TokenIDTime() (time.Time, error) {
+	kid, err := ksuid.Parse(c.ID)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid token id: %w", err)
+	}
+
+	return kid.Time(), nil
+}
+
+// CustomClaim retrieves a custom claim by name
+func (c *StandardClaims) CustomClaim(name string) (any, bool) {
+	if c.CustomClaims == nil {
+		return nil, false
+	}
+
+	v, ok := c.CustomClaims[name]
+
+	return v, ok
+}
+
+// CustomClaimString retrieves a custom claim by name, it will only succeed for string values
+func (c *StandardClaims) CustomClaimString(name string) (string, bool) {
+	v, ok := c.CustomClaim(name)
+	if !ok {
+		return "", false
+	}
+
+	s, ok := v.(string)
+
+	return s, ok
+}
+
+// IssuerContact is the security contact of the issuer, empty when not known
+func (c *StandardClaims) IssuerContact() string {
+	if c.IssuerMetadata == nil {
+		return ""
+	}
+
+	return c.IssuerMetadata.Contact
+}
+
+// IssuerRevocationURL is where compromised tokens should be reported, empty when not known
+func (c *StandardClaims) IssuerRevocationURL() string {
+	if c.IssuerMetadata == nil {
+		return ""
+	}
+
+	return c.IssuerMetadata.RevocationURL
+}
+
+// IssuerPolicyURL is the issuance policy document of the issuer, empty when not known
+func (c *StandardClaims) IssuerPolicyURL() string {
+	if c.IssuerMetadata == nil {
+		return ""
+	}
+
+	return c.IssuerMetadata.PolicyURL
+}
+
 // ExpireTime determines the expiry time based on issuer expiry and token expiry
 func (c *StandardClaims) ExpireTime() time.Time {
 	var iexp, exp time.Time
@@ -61,7 +192,7 @@ func (c *StandardClaims) ExpireTime() time.Time {
 
 // IsExpired checks if the token has expired
 func (c *StandardClaims) IsExpired() bool {
-	return time.Now().After(c.ExpireTime())
+	return currentTime().After(c.ExpireTime())
 }
 
 // AddOrgIssuerData adds the data that a Chain Issuer needs to be able to issue clients in an Org managed by an Issuer
@@ -71,35 +202,43 @@ func (c *StandardClaims) AddOrgIssuerData(priK ed25519.PrivateKey) error {
 		return err
 	}
 
+	pk := priK.Public().(ed25519.PublicKey)
+	err = c.checkIssuerKey(pk)
+	if err != nil {
+		return err
+	}
+
 	sig, err := ed25519Sign(priK, dat)
 	if err != nil {
 		return err
 	}
 
-	c.SetOrgIssuer(priK.Public().(ed25519.PublicKey))
+	c.SetOrgIssuer(pk)
 	c.SetChainIssuerTrustSignature(sig)
 
 	return nil
 }
 
-// AddOrgIssuerDataUsingVault adds the data that a Chain Issuer needs to be able to issue clients in an Org managed by an Issuer by using Vault to sign the data using the named key `priK`.
-func (c *StandardClaims) AddOrgIssuerDataUsingVault(ctx context.Context, tlsc *tls.Config, priK string, log *logrus.Entry) error {
-	issuer, err := getVaultIssuerPubKey(ctx, tlsc, priK, log)
+// AddOrgIssuerDataUsingSigner adds the data that a Chain Issuer needs to be able to issue clients in an Org managed by an Issuer
+// by using signer to sign the data, this supports org issuer keys held in a cloud KMS or HSM. The signer must hold an ed25519 key.
+//
+// The chain issuer token can then be signed by the same signer using SignToken
+func (c *StandardClaims) AddOrgIssuerDataUsingSigner(signer crypto.Signer) error {
+	dat, err := c.OrgIssuerChainData()
 	if err != nil {
 		return err
 	}
 
-	dat, err := c.OrgIssuerChainData()
+	sig, pk, err := ed25519SignWithSigner(signer, dat)
 	if err != nil {
 		return err
 	}
 
-	sig, err := signWithVault(ctx, tlsc, priK, dat, log)
+	err = c.SetOrgIssuer(pk)
 	if err != nil {
 		return err
 	}
 
-	c.SetOrgIssuer(issuer)
 	c.SetChainIssuerTrustSignature(sig)
 
 	return nil
@@ -183,6 +322,9 @@ func (c *StandardClaims) IsChainedIssuer(verify bool) bool {
 
 // OrgIssuerChainData creates data that the org issuer would sign and embed in the token as TrustChainSignature.
 // See AddOrgIssuerData for a one-shot way to set the needed data when you have access to the private key.
+//
+// When the token limits the delegation of a chain issuer a digest of those limits is included, tokens issued by
+// such a chain issuer can then only be verified when they embed its token using WithIssuerChain
 func (c *StandardClaims) OrgIssuerChainData() ([]byte, error) {
 	if c.ID == "" {
 		return nil, fmt.Errorf("no token id set")
@@ -191,16 +333,68 @@ func (c *StandardClaims) OrgIssuerChainData() ([]byte, error) {
 		return nil, fmt.Errorf("no public key set")
 	}
 
-	return []byte(fmt.Sprintf("%s.%s", c.ID, c.PublicKey)), nil
+	digest, err := c.delegationDigest()
+	if err != nil {
+		return nil, err
+	}
+
+	if digest == "" {
+		return []byte(fmt.Sprintf("%s.%s", c.ID, c.PublicKey)), nil
+	}
+
+	return []byte(fmt.Sprintf("%s.%s.%s", c.ID, c.PublicKey, digest)), nil
+}
+
+// delegationDigest is a digest over the limits placed on a chain issuer, empty when it has none
+func (c *StandardClaims) delegationDigest() (string, error) {
+	if c.ChainMaxDepth == 0 && c.IssuerConstraints == nil && c.ChainDelegationExpiresAt == nil {
+		return "", nil
+	}
+
+	limits, err := json.Marshal(map[string]any{
+		"chain_max_depth":      c.ChainMaxDepth,
+		"chain_delegation_exp": c.ChainDelegationExpiresAt,
+		"issuer_constraints":   c.IssuerConstraints,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(limits)
+
+	return hex.EncodeToString(sum[:]), nil
 }
 
-// SetOrgIssuer sets the issuer field for users issued by the Org Issuer
+// SetOrgIssuer sets the issuer field for users issued by the Org Issuer, the token may not hold pk unless it was
+// created using AllowIssuerPublicKey.
+//
 // See AddOrgIssuerData for a one-shot way to set the needed data when you have access to the private key.
-func (c *StandardClaims) SetOrgIssuer(pk ed25519.PublicKey) {
+func (c *StandardClaims) SetOrgIssuer(pk ed25519.PublicKey) error {
+	err := c.checkIssuerKey(pk)
+	if err != nil {
+		return err
+	}
+
 	c.Issuer = fmt.Sprintf("%s%s", OrgIssuerPrefix, hex.EncodeToString(pk))
+
+	return nil
+}
+
+// checkIssuerKey ensures the token does not hold pk, the public key of its issuer, unless AllowIssuerPublicKey was used
+func (c *StandardClaims) checkIssuerKey(pk ed25519.PublicKey) error {
+	if c.allowIssuerKey || c.PublicKey == "" {
+		return nil
+	}
+
+	if strings.EqualFold(c.PublicKey, hex.EncodeToString(pk)) {
+		return ErrIssuerPublicKey
+	}
+
+	return nil
 }
 
-// SetChainIssuer used by Login Handlers that create users in a chain to set an appropriate issuer on created users
+// SetChainIssuer used by Login Handlers that create users in a chain to set an appropriate issuer on created users,
+// the user may not hold the public key of the chain issuer unless it was created using AllowIssuerPublicKey.
 // See AddChainIssuerData for a one-shot way to set the needed data when you have access to the private key.
 func (c *StandardClaims) SetChainIssuer(ci *ClientIDClaims) error {
 	if ci.ID == "" {
@@ -214,11 +408,25 @@ func (c *StandardClaims) SetChainIssuer(ci *ClientIDClaims) error {
 		return fmt.Errorf("issuer has no expiry")
 	}
 
-	c.Issuer = fmt.Sprintf("%s%s.%s", ChainIssuerPrefix, ci.ID, ci.PublicKey)
+	pk, err := hex.DecodeString(ci.PublicKey)
+	if err != nil {
+		return fmt.Errorf("invalid issuer public key: %w", err)
+	}
+
+	err = c.checkIssuerKey(pk)
+	if err != nil {
+		return err
+	}
+
+	c.Issuer = chainIssuerName(&ci.StandardClaims)
 	c.IssuerExpiresAt = ci.ExpiresAt
 
+	if ci.ChainDelegationExpiresAt != nil && ci.ChainDelegationExpiresAt.Before(ci.ExpiresAt.Time) {
+		c.IssuerExpiresAt = ci.ChainDelegationExpiresAt
+	}
+
 	if c.ExpiresAt == nil || c.IssuerExpiresAt.Before(c.ExpiresAt.Time) {
-		c.ExpiresAt = ci.ExpiresAt
+		c.ExpiresAt = c.IssuerExpiresAt
 	}
 
 	return nil
@@ -285,15 +493,19 @@ func (c *StandardClaims) ParseChainIssuerData() (id string, pk ed25519.PublicKey
 		return "", nil, "", nil, fmt.Errorf("invalid public key in issuer data")
 	}
 
-	parts = strings.Split(c.TrustChainSignature, ".")
-	if len(parts) != 2 {
+	// the tcs of the creator is everything before the last signature, it holds further signatures when
+	// the creator was itself issued by a chain issuer
+	idx := strings.LastIndex(c.TrustChainSignature, ".")
+	if idx == -1 {
 		return "", nil, "", nil, fmt.Errorf("invalid trust chain signature")
 	}
-	if len(parts[0]) == 0 || len(parts[1]) == 0 {
-		return "", nil, "", nil, fmt.Errorf("invalid trust chain signature")
+	for _, part := range strings.Split(c.TrustChainSignature, ".") {
+		if len(part) == 0 {
+			return "", nil, "", nil, fmt.Errorf("invalid trust chain signature")
+		}
 	}
-	tcs = parts[0]
-	sig, err = hex.DecodeString(parts[1])
+	tcs = c.TrustChainSignature[:idx]
+	sig, err = hex.DecodeString(c.TrustChainSignature[idx+1:])
 	if err != nil {
 		return "", nil, "", nil, fmt.Errorf("invalid signature in chain signature: %w", err)
 	}
@@ -333,9 +545,16 @@ func (c *StandardClaims) verifyIssuerRequiredClaims() error {
 
 // IsSignedByIssuer uses the chain data in Issuer and TrustChainSignature to determine if an issuer signed a token
 func (c *StandardClaims) IsSignedByIssuer(pk ed25519.PublicKey) (bool, ed25519.PublicKey, error) {
+	valid, signer, _, err := c.verifyIssuer(pk, currentTime())
+
+	return valid, signer, err
+}
+
+// verifyIssuer implements IsSignedByIssuer as at now, it also returns the chain issuers verified from any embedded issuer chain
+func (c *StandardClaims) verifyIssuer(pk ed25519.PublicKey, now time.Time) (bool, ed25519.PublicKey, []*ClientIDClaims, error) {
 	err := c.verifyIssuerRequiredClaims()
 	if err != nil {
-		return false, nil, err
+		return false, nil, nil, err
 	}
 
 	switch {
@@ -349,25 +568,25 @@ func (c *StandardClaims) IsSignedByIssuer(pk ed25519.PublicKey) (bool, ed25519.P
 		// supplied issuer public key
 
 		if c.Issuer != fmt.Sprintf("%s%s", OrgIssuerPrefix, hex.EncodeToString(pk)) {
-			return false, nil, fmt.Errorf("public keys do not match")
+			return false, nil, nil, fmt.Errorf("public keys do not match")
 		}
 
 		sig, err := hex.DecodeString(c.TrustChainSignature)
 		if err != nil {
-			return false, nil, fmt.Errorf("invalid trust chain signature: %w", err)
+			return false, nil, nil, fmt.Errorf("invalid trust chain signature: %w", err)
 		}
 
 		dat, err := c.OrgIssuerChainData()
 		if err != nil {
-			return false, nil, err
+			return false, nil, nil, err
 		}
 
 		valid, err := ed25519Verify(pk, dat, sig)
 		if err != nil {
-			return false, nil, err
+			return false, nil, nil, err
 		}
 
-		return valid, pk, err
+		return valid, pk, nil, err
 
 	case strings.HasPrefix(c.Issuer, ChainIssuerPrefix):
 		// This is a token that was created by one in the chain - not the org issuer.
@@ -386,27 +605,59 @@ func (c *StandardClaims) IsSignedByIssuer(pk ed25519.PublicKey) (bool, ed25519.P
 		// We can confirm the tcs is valid and matches whats in the sig made by
 		// the creator because we verify it using the requested issuer pubk
 		if c.IssuerExpiresAt == nil || c.IssuerExpiresAt.IsZero() {
-			return false, nil, fmt.Errorf("no issuer expires set")
+			return false, nil, nil, fmt.Errorf("no issuer expires set")
 		}
 
-		_, hPubk, tcs, sig, err := c.ParseChainIssuerData()
+		hID, hPubk, tcs, sig, err := c.ParseChainIssuerData()
 		if err != nil {
-			return false, nil, err
+			return false, nil, nil, err
 		}
 
 		// this is the signature from the handler
 		// now we check the signature is data + "." + sig(id+ "." + data)
 		ok, err := ed25519Verify(hPubk, []byte(fmt.Sprintf("%s.%s", c.ID, tcs)), sig)
 		if err != nil {
-			return false, nil, fmt.Errorf("chain signature validation failed: %w", err)
+			return false, nil, nil, fmt.Errorf("chain signature validation failed: %w", err)
+		}
+		if !ok {
+			return false, nil, nil, fmt.Errorf("invalid chain signature")
+		}
+
+		// an embedded issuer chain holds the creator tokens back to the org issuer, verifying it covers the creator
+		if len(c.IssuerChain) > 0 {
+			issuers, err := verifyIssuerChain(c, pk, now)
+			if err != nil {
+				return false, nil, nil, err
+			}
+
+			return true, hPubk, issuers, nil
+		}
+
+		// a creator that was issued by another chain issuer has a multi part tcs that the org issuer did not sign
+		// directly, the creator tokens embedded in the issuer chain are needed to get back to the org issuer
+		if strings.Contains(tcs, ".") {
+			return false, nil, nil, fmt.Errorf("multi level chain issued tokens require an embedded issuer chain")
+		}
+
+		// the tcs must be the org issuer signature made over the creator id and public key, see OrgIssuerChainData,
+		// without this check any key could act as a creator. Creators with delegation limits had those signed too
+		// and so can only be verified using the embedded issuer chain handled above
+		tcsSig, err := hex.DecodeString(tcs)
+		if err != nil {
+			return false, nil, nil, fmt.Errorf("invalid trust chain signature: %w", err)
+		}
+
+		ok, err = ed25519Verify(pk, []byte(fmt.Sprintf("%s.%s", hID, hex.EncodeToString(hPubk))), tcsSig)
+		if err != nil {
+			return false, nil, nil, fmt.Errorf("issuer signature validation failed: %w", err)
 		}
 		if !ok {
-			return false, nil, fmt.Errorf("invalid chain signature")
+			return false, nil, nil, fmt.Errorf("chain issuer was not signed by the org issuer")
 		}
 
-		return true, hPubk, nil
+		return true, hPubk, nil, nil
 
 	default:
-		return false, nil, fmt.Errorf("unsupported issuer format")
+		return false, nil, nil, fmt.Errorf("unsupported issuer format")
 	}
 }
@@ -32,6 +32,66 @@ var _ = Describe("StandardClaims", func() {
 
 	})
 
+	Describe("TokenID", func() {
+		It("Should expose a unique time based id", func() {
+			Expect(c.TokenID()).To(Equal(c.ID))
+			Expect(c.TokenID()).To(HaveLen(27))
+
+			t, err := c.TokenIDTime()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(t).To(BeTemporally("==", c.IssuedAt.Time))
+
+			other, err := newStandardClaims("ginkgo", "", time.Hour, false)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(other.TokenID()).ToNot(Equal(c.TokenID()))
+
+			c.ID = "x"
+			_, err = c.TokenIDTime()
+			Expect(err).To(MatchError(MatchRegexp("invalid token id")))
+		})
+	})
+
+	Describe("IssuerMetadata", func() {
+		AfterEach(func() {
+			Expect(SetDefaultIssuerMetadata(nil)).To(Succeed())
+		})
+
+		It("Should handle missing metadata", func() {
+			Expect(c.IssuerContact()).To(Equal(""))
+			Expect(c.IssuerRevocationURL()).To(Equal(""))
+			Expect(c.IssuerPolicyURL()).To(Equal(""))
+		})
+
+		It("Should validate urls", func() {
+			_, err := newStandardClaims("ginkgo", "", time.Hour, false, WithIssuerMetadata(IssuerMetadata{PolicyURL: "/policy"}))
+			Expect(err).To(MatchError(`invalid issuer metadata url "/policy": not an absolute url`))
+
+			Expect(SetDefaultIssuerMetadata(&IssuerMetadata{RevocationURL: "revoke"})).To(MatchError(`invalid issuer metadata url "revoke": not an absolute url`))
+		})
+
+		It("Should stamp the default metadata and support overrides", func() {
+			Expect(SetDefaultIssuerMetadata(&IssuerMetadata{Contact: "security@example.net", PolicyURL: "https://example.net/policy"})).To(Succeed())
+
+			c, err := newStandardClaims("ginkgo", "", time.Hour, false)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(c.IssuerContact()).To(Equal("security@example.net"))
+			Expect(c.IssuerPolicyURL()).To(Equal("https://example.net/policy"))
+			Expect(c.IssuerRevocationURL()).To(Equal(""))
+
+			c, err = newStandardClaims("ginkgo", "", time.Hour, false, WithIssuerMetadata(IssuerMetadata{Contact: "noc@example.net", RevocationURL: "https://example.net/revoke"}))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(c.IssuerContact()).To(Equal("noc@example.net"))
+			Expect(c.IssuerRevocationURL()).To(Equal("https://example.net/revoke"))
+			Expect(c.IssuerPolicyURL()).To(Equal(""))
+
+			token, err := SignToken(c, priK)
+			Expect(err).ToNot(HaveOccurred())
+			parsed := &StandardClaims{}
+			Expect(ParseToken(token, parsed, pubK)).To(Succeed())
+			Expect(parsed.IssuerMetadata).To(Equal(c.IssuerMetadata))
+		})
+	})
+
 	Describe("Chain Issuer", func() {
 		Describe("ExpireTime", func() {
 			It("Should handle all nil", func() {
@@ -203,6 +263,11 @@ var _ = Describe("StandardClaims", func() {
 				Expect(err).To(MatchError("invalid trust chain signature"))
 				Expect(ok).To(BeFalse())
 
+				c.TrustChainSignature = "foo..abcd"
+				ok, _, err = c.IsSignedByIssuer(pubK)
+				Expect(err).To(MatchError("invalid trust chain signature"))
+				Expect(ok).To(BeFalse())
+
 				c.TrustChainSignature = "foo.!!"
 				ok, _, err = c.IsSignedByIssuer(pubK)
 				Expect(err).To(MatchError("invalid signature in chain signature: encoding/hex: invalid byte: U+0021 '!'"))
@@ -284,6 +349,52 @@ var _ = Describe("StandardClaims", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(ok).To(BeTrue())
 			})
+
+			It("Should require the org issuer to have signed the chain issuer", func() {
+				issuePubK, _, err := ed25519.GenerateKey(rand.Reader)
+				Expect(err).ToNot(HaveOccurred())
+				_, roguePriK, err := ed25519.GenerateKey(rand.Reader)
+				Expect(err).ToNot(HaveOccurred())
+				handlerPubK, handlerPrik, err := ed25519.GenerateKey(rand.Reader)
+				Expect(err).ToNot(HaveOccurred())
+				userPubK, _, err := ed25519.GenerateKey(rand.Reader)
+				Expect(err).ToNot(HaveOccurred())
+
+				// the handler claims the org issuer but its tcs was made by another key
+				handler, err := NewClientIDClaims("choria=handler", nil, "", nil, "", "", time.Minute, nil, handlerPubK)
+				Expect(err).ToNot(HaveOccurred())
+				handler.SetOrgIssuer(issuePubK)
+				hdat, err := handler.OrgIssuerChainData()
+				Expect(err).ToNot(HaveOccurred())
+				hsig, err := ed25519Sign(roguePriK, hdat)
+				Expect(err).ToNot(HaveOccurred())
+				handler.TrustChainSignature = hex.EncodeToString(hsig)
+
+				user, err := NewClientIDClaims("choria=user", nil, "", nil, "", "", time.Minute, nil, userPubK)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(user.SetChainIssuer(handler)).To(Succeed())
+				udat, err := user.ChainIssuerData(handler.TrustChainSignature)
+				Expect(err).ToNot(HaveOccurred())
+				usig, err := ed25519Sign(handlerPrik, udat)
+				Expect(err).ToNot(HaveOccurred())
+				user.SetChainUserTrustSignature(handler, usig)
+
+				ok, _, err := user.IsSignedByIssuer(issuePubK)
+				Expect(err).To(MatchError("chain issuer was not signed by the org issuer"))
+				Expect(ok).To(BeFalse())
+
+				// a tcs that is not a signature at all
+				handler.TrustChainSignature = "x"
+				udat, err = user.ChainIssuerData(handler.TrustChainSignature)
+				Expect(err).ToNot(HaveOccurred())
+				usig, err = ed25519Sign(handlerPrik, udat)
+				Expect(err).ToNot(HaveOccurred())
+				user.SetChainUserTrustSignature(handler, usig)
+
+				ok, _, err = user.IsSignedByIssuer(issuePubK)
+				Expect(err).To(MatchError("invalid trust chain signature: encoding/hex: invalid byte: U+0078 'x'"))
+				Expect(ok).To(BeFalse())
+			})
 		})
 	})
 
@@ -0,0 +1,96 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var (
+	ErrTokenNotFound    = errors.New("token not found")
+	ErrInvalidTokenName = errors.New("invalid token name")
+)
+
+// TokenStore stores signed tokens by name
+type TokenStore interface {
+	// Save stores token under name, replacing any existing token
+	Save(name string, token string) error
+	// Load retrieves the token stored under name
+	Load(name string) (string, error)
+	// Delete removes the token stored under name
+	Delete(name string) error
+	// List lists the names of all stored tokens
+	List() ([]string, error)
+	// ListTokens lists stored tokens in a deterministic order one page at a time
+	ListTokens(opts ListOptions) (*TokenPage, error)
+	// Quarantine moves the token stored under name into a quarantine area along with the reason
+	Quarantine(name string, reason string) (*QuarantineRecord, error)
+}
+
+// QuarantineRecord describes a token that was moved into quarantine
+type QuarantineRecord struct {
+	// ID uniquely identifies the quarantined token
+	ID string `json:"id"`
+
+	// Name is the name the token was stored under
+	Name string `json:"name"`
+
+	// Reason is why the token was quarantined
+	Reason string `json:"reason"`
+
+	// QuarantinedAt is when the token was quarantined
+	QuarantinedAt time.Time `json:"quarantined_at"`
+
+	// Purpose is the purpose of the token
+	Purpose Purpose `json:"purpose,omitempty"`
+
+	// Identity is the caller id or identity the token was for
+	Identity string `json:"identity,omitempty"`
+
+	// TokenID is the unique id of the token
+	TokenID string `json:"token_id,omitempty"`
+
+	// ExpiresAt is when the token expires
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
+// invalidTokenReason determines why a token should be quarantined, empty when it is valid
+func invalidTokenReason(token string, keys *KeyRing) string {
+	claims := newClaimsForPurpose(TokenPurpose(token))
+
+	if keys != nil && keys.Len() > 0 {
+		_, err := keys.ParseToken(token, claims)
+		if err != nil {
+			return err.Error()
+		}
+
+		return ""
+	}
+
+	_, err := parseUnverified(token, claims)
+	if err != nil {
+		return err.Error()
+	}
+
+	sc, ok := claims.(standardClaimsProvider)
+	if ok && sc.getStandardClaims().IsExpired() {
+		return jwt.ErrTokenExpired.Error()
+	}
+
+	return ""
+}
+
+func validateStoreName(name string) error {
+	if name == "" || strings.HasPrefix(name, ".") || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("%w: %q", ErrInvalidTokenName, name)
+	}
+
+	return nil
+}
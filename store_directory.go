@@ -0,0 +1,479 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !js
+
+package tokens
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	storeTokenExtension    = ".jwt"
+	storeMetadataExtension = ".json"
+	quarantineDirectory    = "quarantine"
+)
+
+// DirectoryStore is a TokenStore that keeps every token in a file named <name>.jwt in a directory,
+// quarantined tokens are kept in the quarantine sub directory along with their metadata
+type DirectoryStore struct {
+	dir string
+
+	// listed caches token details used to order listings, keyed by name
+	listed map[string]*listedToken
+	mu     sync.Mutex
+}
+
+// listedToken is the cached description of a token file, valid while the file is unchanged
+type listedToken struct {
+	modTime time.Time
+	size    int64
+	entry   TokenListEntry
+}
+
+var _ TokenStore = (*DirectoryStore)(nil)
+
+// NewDirectoryStore creates a store in dir, creating the directory if needed
+func NewDirectoryStore(dir string) (*DirectoryStore, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("directory is required")
+	}
+
+	err := os.MkdirAll(filepath.Join(dir, quarantineDirectory), 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DirectoryStore{dir: dir}, nil
+}
+
+// Save stores token under name, replacing any existing token
+func (s *DirectoryStore) Save(name string, token string) error {
+	path, err := s.tokenPath(name)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(path, []byte(token), 0600)
+}
+
+// Load retrieves the token stored under name
+func (s *DirectoryStore) Load(name string) (string, error) {
+	path, err := s.tokenPath(name)
+	if err != nil {
+		return "", err
+	}
+
+	dat, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("%w: %s", ErrTokenNotFound, name)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(dat)), nil
+}
+
+// Delete removes the token stored under name
+func (s *DirectoryStore) Delete(name string) error {
+	path, err := s.tokenPath(name)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: %s", ErrTokenNotFound, name)
+	}
+
+	return err
+}
+
+// List lists the names of all stored tokens sorted by name
+func (s *DirectoryStore) List() ([]string, error) {
+	return listTokenFiles(s.dir)
+}
+
+// Quarantine moves the token stored under name into the quarantine directory and records the reason
+func (s *DirectoryStore) Quarantine(name string, reason string) (*QuarantineRecord, error) {
+	token, err := s.Load(name)
+	if err != nil {
+		return nil, err
+	}
+
+	now := currentTime().UTC()
+	rec := &QuarantineRecord{
+		ID:            fmt.Sprintf("%s-%d", name, now.UnixNano()),
+		Name:          name,
+		Reason:        reason,
+		QuarantinedAt: now,
+		Purpose:       TokenPurpose(token),
+	}
+
+	claims := newClaimsForPurpose(rec.Purpose)
+	_, err = parseUnverified(token, claims)
+	if err == nil {
+		rec.Identity = claimsIdentity(claims)
+		if sc, ok := claims.(standardClaimsProvider); ok {
+			rec.TokenID = sc.getStandardClaims().TokenID()
+			rec.ExpiresAt = sc.getStandardClaims().ExpireTime()
+		}
+	}
+
+	j, err := json.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+
+	qdir := filepath.Join(s.dir, quarantineDirectory)
+	err = os.WriteFile(filepath.Join(qdir, rec.ID+storeMetadataExtension), j, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	src, _ := s.tokenPath(name)
+	err = os.Rename(src, filepath.Join(qdir, rec.ID+storeTokenExtension))
+	if err != nil {
+		os.Remove(filepath.Join(qdir, rec.ID+storeMetadataExtension))
+		return nil, err
+	}
+
+	return rec, nil
+}
+
+// QuarantineInvalid moves all expired tokens, and those that fail verification using keys, into quarantine.
+// When keys is nil only expiry is checked
+func (s *DirectoryStore) QuarantineInvalid(keys *KeyRing) ([]*QuarantineRecord, error) {
+	names, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var records []*QuarantineRecord
+
+	for _, name := range names {
+		token, err := s.Load(name)
+		if err != nil {
+			return records, err
+		}
+
+		reason := invalidTokenReason(token, keys)
+		if reason == "" {
+			continue
+		}
+
+		rec, err := s.Quarantine(name, reason)
+		if err != nil {
+			return records, err
+		}
+
+		records = append(records, rec)
+	}
+
+	return records, nil
+}
+
+// Quarantined lists all quarantined tokens, oldest first
+func (s *DirectoryStore) Quarantined() ([]*QuarantineRecord, error) {
+	qdir := filepath.Join(s.dir, quarantineDirectory)
+
+	matches, err := filepath.Glob(filepath.Join(qdir, "*"+storeMetadataExtension))
+	if err != nil {
+		return nil, err
+	}
+
+	var records []*QuarantineRecord
+	for _, m := range matches {
+		dat, err := os.ReadFile(m)
+		if err != nil {
+			return nil, err
+		}
+
+		rec := &QuarantineRecord{}
+		err = json.Unmarshal(dat, rec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quarantine record %s: %w", filepath.Base(m), err)
+		}
+
+		records = append(records, rec)
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].QuarantinedAt.Before(records[j].QuarantinedAt)
+	})
+
+	return records, nil
+}
+
+// LoadQuarantined retrieves the token quarantined with id
+func (s *DirectoryStore) LoadQuarantined(id string) (string, error) {
+	err := validateStoreName(id)
+	if err != nil {
+		return "", err
+	}
+
+	dat, err := os.ReadFile(filepath.Join(s.dir, quarantineDirectory, id+storeTokenExtension))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("%w: %s", ErrTokenNotFound, id)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(dat)), nil
+}
+
+// Restore moves the token quarantined with id back into the store under its original name, an existing token with that name is not replaced
+func (s *DirectoryStore) Restore(id string) error {
+	qdir := filepath.Join(s.dir, quarantineDirectory)
+
+	err := validateStoreName(id)
+	if err != nil {
+		return err
+	}
+
+	dat, err := os.ReadFile(filepath.Join(qdir, id+storeMetadataExtension))
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: %s", ErrTokenNotFound, id)
+	}
+	if err != nil {
+		return err
+	}
+
+	rec := &QuarantineRecord{}
+	err = json.Unmarshal(dat, rec)
+	if err != nil {
+		return fmt.Errorf("invalid quarantine record %s: %w", id, err)
+	}
+
+	dst, err := s.tokenPath(rec.Name)
+	if err != nil {
+		return err
+	}
+
+	_, err = os.Stat(dst)
+	if err == nil {
+		return fmt.Errorf("token %s already exists", rec.Name)
+	}
+
+	err = os.Rename(filepath.Join(qdir, id+storeTokenExtension), dst)
+	if err != nil {
+		return err
+	}
+
+	return os.Remove(filepath.Join(qdir, id+storeMetadataExtension))
+}
+
+func (s *DirectoryStore) tokenPath(name string) (string, error) {
+	err := validateStoreName(name)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(s.dir, name+storeTokenExtension), nil
+}
+
+// ListTokens lists stored tokens a page at a time. Ordering by name only reads the tokens in the page, the other
+// orders read each token once and cache a small summary of it until the token file changes
+func (s *DirectoryStore) ListTokens(opts ListOptions) (*TokenPage, error) {
+	if opts.OrderBy == "" {
+		opts.OrderBy = OrderByName
+	}
+
+	switch opts.OrderBy {
+	case OrderByName, OrderByExpiry, OrderBySubject:
+	default:
+		return nil, fmt.Errorf("invalid token order %q", opts.OrderBy)
+	}
+
+	if opts.Limit < 0 {
+		return nil, fmt.Errorf("limit cannot be negative")
+	}
+
+	cursor, err := decodeListCursor(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.OrderBy != OrderByName {
+		s.pruneListed(names)
+	}
+
+	var after *sortableTokenEntry
+	if cursor != nil {
+		after = &sortableTokenEntry{entry: &TokenListEntry{Name: cursor.Name}, key: cursor.Key}
+	}
+
+	entries := make([]*sortableTokenEntry, 0, len(names))
+	for _, name := range names {
+		e := &sortableTokenEntry{entry: &TokenListEntry{Name: name}}
+
+		if opts.OrderBy != OrderByName {
+			e.entry, err = s.listedEntry(name)
+			if err != nil {
+				return nil, err
+			}
+			e.key = listSortKey(opts.OrderBy, e.entry)
+		}
+
+		if after != nil && !listEntryBefore(after, e, opts.Descending) {
+			continue
+		}
+
+		entries = append(entries, e)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return listEntryBefore(entries[i], entries[j], opts.Descending)
+	})
+
+	end := len(entries)
+	if opts.Limit > 0 && opts.Limit < end {
+		end = opts.Limit
+	}
+
+	page := &TokenPage{Tokens: []*TokenListEntry{}}
+	for _, e := range entries[:end] {
+		if opts.OrderBy == OrderByName {
+			e.entry, err = s.listedEntry(e.entry.Name)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		page.Tokens = append(page.Tokens, e.entry)
+	}
+
+	if end < len(entries) && end > 0 {
+		last := entries[end-1]
+		page.NextCursor, err = encodeListCursor(listCursor{Order: opts.OrderBy, Descending: opts.Descending, Key: last.key, Name: last.entry.Name})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return page, nil
+}
+
+// listedEntry describes the token stored under name, reusing the cached description while the file is unchanged
+func (s *DirectoryStore) listedEntry(name string) (*TokenListEntry, error) {
+	path, err := s.tokenPath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	st, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrTokenNotFound, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	cached, ok := s.listed[name]
+	s.mu.Unlock()
+
+	if ok && cached.size == st.Size() && cached.modTime.Equal(st.ModTime()) {
+		e := cached.entry
+		return &e, nil
+	}
+
+	e := &TokenListEntry{Name: name}
+	err = s.describeListEntry(e)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	if s.listed == nil {
+		s.listed = make(map[string]*listedToken)
+	}
+	s.listed[name] = &listedToken{modTime: st.ModTime(), size: st.Size(), entry: *e}
+	s.mu.Unlock()
+
+	return e, nil
+}
+
+// pruneListed removes cached descriptions of tokens that are no longer stored
+func (s *DirectoryStore) pruneListed(names []string) {
+	current := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		current[name] = struct{}{}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for name := range s.listed {
+		if _, ok := current[name]; !ok {
+			delete(s.listed, name)
+		}
+	}
+}
+
+// describeListEntry fills in the details of e from the stored token, tokens that cannot be parsed are listed without details
+func (s *DirectoryStore) describeListEntry(e *TokenListEntry) error {
+	token, err := s.Load(e.Name)
+	if err != nil {
+		return err
+	}
+
+	e.Purpose = TokenPurpose(token)
+
+	claims := newClaimsForPurpose(e.Purpose)
+	_, err = parseUnverified(token, claims)
+	if err != nil {
+		return nil
+	}
+
+	if sc, ok := claims.(standardClaimsProvider); ok {
+		std := sc.getStandardClaims()
+		e.Subject = std.Subject
+		if std.ExpiresAt != nil {
+			e.ExpiresAt = std.ExpireTime()
+		}
+	}
+
+	if e.Subject == "" {
+		e.Subject = claimsIdentity(claims)
+	}
+
+	return nil
+}
+
+func listTokenFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), storeTokenExtension) {
+			continue
+		}
+
+		names = append(names, strings.TrimSuffix(e.Name(), storeTokenExtension))
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
@@ -0,0 +1,145 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TokenOrder is the order in which stored tokens are listed
+type TokenOrder string
+
+const (
+	// OrderByName lists tokens by the name they are stored under
+	OrderByName TokenOrder = "name"
+
+	// OrderByExpiry lists tokens by expiry time, tokens that do not expire are listed last
+	OrderByExpiry TokenOrder = "expiry"
+
+	// OrderBySubject lists tokens by subject
+	OrderBySubject TokenOrder = "subject"
+)
+
+// ErrInvalidCursor indicates a pagination cursor that could not be used with the list options
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// noExpirySortKey sorts tokens without an expiry time after all others
+const noExpirySortKey = "99999999999999999999"
+
+// ListOptions configures listing tokens in a TokenStore
+type ListOptions struct {
+	// OrderBy is the order to list tokens in, defaults to OrderByName
+	OrderBy TokenOrder
+
+	// Descending reverses the order
+	Descending bool
+
+	// Limit is the most tokens to return in a page, 0 returns all remaining tokens
+	Limit int
+
+	// Cursor continues listing after the page that produced it, must be used with the same order
+	Cursor string
+}
+
+// TokenListEntry describes a stored token
+type TokenListEntry struct {
+	// Name is the name the token is stored under
+	Name string `json:"name"`
+
+	// Subject is the sub claim or, when not set, the caller id or identity the token is for
+	Subject string `json:"subject,omitempty"`
+
+	// Purpose is the purpose of the token
+	Purpose Purpose `json:"purpose,omitempty"`
+
+	// ExpiresAt is when the token expires, zero when it does not expire or could not be parsed
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
+// TokenPage is a page of stored tokens
+type TokenPage struct {
+	// Tokens are the tokens in this page
+	Tokens []*TokenListEntry `json:"tokens"`
+
+	// NextCursor retrieves the next page, empty when this is the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+type listCursor struct {
+	Order      TokenOrder `json:"o"`
+	Descending bool       `json:"d,omitempty"`
+	Key        string     `json:"k,omitempty"`
+	Name       string     `json:"n"`
+}
+
+type sortableTokenEntry struct {
+	entry *TokenListEntry
+	key   string
+}
+
+func listSortKey(order TokenOrder, e *TokenListEntry) string {
+	switch order {
+	case OrderByExpiry:
+		if e.ExpiresAt.IsZero() || e.ExpiresAt.Unix() < 0 {
+			return noExpirySortKey
+		}
+		return fmt.Sprintf("%020d", e.ExpiresAt.Unix())
+	case OrderBySubject:
+		return e.Subject
+	default:
+		return ""
+	}
+}
+
+// listEntryBefore orders by sort key and then name so that the order is total and cursors are stable
+func listEntryBefore(a *sortableTokenEntry, b *sortableTokenEntry, descending bool) bool {
+	c := strings.Compare(a.key, b.key)
+	if c == 0 {
+		c = strings.Compare(a.entry.Name, b.entry.Name)
+	}
+
+	if descending {
+		return c > 0
+	}
+
+	return c < 0
+}
+
+func encodeListCursor(c listCursor) (string, error) {
+	j, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(j), nil
+}
+
+func decodeListCursor(opts ListOptions) (*listCursor, error) {
+	if opts.Cursor == "" {
+		return nil, nil
+	}
+
+	j, err := base64.RawURLEncoding.DecodeString(opts.Cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidCursor, err)
+	}
+
+	c := &listCursor{}
+	err = json.Unmarshal(j, c)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidCursor, err)
+	}
+
+	if c.Order != opts.OrderBy || c.Descending != opts.Descending {
+		return nil, fmt.Errorf("%w: cursor was created for a different order", ErrInvalidCursor)
+	}
+
+	return c, nil
+}
@@ -0,0 +1,158 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !js
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DirectoryStore Listing", func() {
+	var (
+		store *DirectoryStore
+		priK  ed25519.PrivateKey
+	)
+
+	BeforeEach(func() {
+		var err error
+		store, err = NewDirectoryStore(GinkgoT().TempDir())
+		Expect(err).ToNot(HaveOccurred())
+
+		_, priK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	save := func(name string, caller string, validity time.Duration) {
+		claims, err := NewClientIDClaims(caller, nil, "", nil, "", "", time.Hour, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(validity))
+
+		token, err := SignToken(claims, priK)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(store.Save(name, token)).To(Succeed())
+	}
+
+	names := func(page *TokenPage) []string {
+		var res []string
+		for _, t := range page.Tokens {
+			res = append(res, t.Name)
+		}
+		return res
+	}
+
+	BeforeEach(func() {
+		save("a", "up=zed", 3*time.Hour)
+		save("b", "up=amy", time.Hour)
+		save("c", "up=kim", 2*time.Hour)
+		save("d", "up=amy", 4*time.Hour)
+		Expect(store.Save("e", "not a token")).To(Succeed())
+	})
+
+	It("Should list by name by default", func() {
+		page, err := store.ListTokens(ListOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(names(page)).To(Equal([]string{"a", "b", "c", "d", "e"}))
+		Expect(page.NextCursor).To(BeEmpty())
+		Expect(page.Tokens[1].Subject).To(Equal("up=amy"))
+		Expect(page.Tokens[1].Purpose).To(Equal(ClientIDPurpose))
+		Expect(page.Tokens[1].ExpiresAt).To(BeTemporally("~", time.Now().Add(time.Hour), 2*time.Second))
+		Expect(page.Tokens[4].Subject).To(BeEmpty())
+	})
+
+	It("Should order by expiry and subject", func() {
+		page, err := store.ListTokens(ListOptions{OrderBy: OrderByExpiry})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(names(page)).To(Equal([]string{"b", "c", "a", "d", "e"}))
+
+		page, err = store.ListTokens(ListOptions{OrderBy: OrderByExpiry, Descending: true})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(names(page)).To(Equal([]string{"e", "d", "a", "c", "b"}))
+
+		page, err = store.ListTokens(ListOptions{OrderBy: OrderBySubject})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(names(page)).To(Equal([]string{"e", "b", "d", "c", "a"}))
+	})
+
+	It("Should page through tokens", func() {
+		for _, order := range []TokenOrder{OrderByName, OrderByExpiry, OrderBySubject} {
+			for _, desc := range []bool{false, true} {
+				all, err := store.ListTokens(ListOptions{OrderBy: order, Descending: desc})
+				Expect(err).ToNot(HaveOccurred())
+
+				var paged []string
+				opts := ListOptions{OrderBy: order, Descending: desc, Limit: 2}
+				for {
+					page, err := store.ListTokens(opts)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(len(page.Tokens)).To(BeNumerically("<=", 2))
+					paged = append(paged, names(page)...)
+
+					if page.NextCursor == "" {
+						break
+					}
+					opts.Cursor = page.NextCursor
+				}
+
+				Expect(paged).To(Equal(names(all)))
+			}
+		}
+	})
+
+	It("Should continue from the cursor when tokens change", func() {
+		page, err := store.ListTokens(ListOptions{Limit: 2})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(names(page)).To(Equal([]string{"a", "b"}))
+
+		Expect(store.Delete("b")).To(Succeed())
+		save("aa", "up=new", time.Hour)
+
+		page, err = store.ListTokens(ListOptions{Limit: 2, Cursor: page.NextCursor})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(names(page)).To(Equal([]string{"c", "d"}))
+	})
+
+	It("Should reuse token details until tokens change", func() {
+		page, err := store.ListTokens(ListOptions{OrderBy: OrderByExpiry, Limit: 2})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(names(page)).To(Equal([]string{"b", "c"}))
+		Expect(store.listed).To(HaveLen(5))
+
+		page.Tokens[0].Subject = "changed"
+		page, err = store.ListTokens(ListOptions{OrderBy: OrderByExpiry, Limit: 2})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(page.Tokens[0].Subject).To(Equal("up=amy"))
+
+		save("b", "up=amy", 5*time.Hour)
+		Expect(store.Delete("e")).To(Succeed())
+
+		page, err = store.ListTokens(ListOptions{OrderBy: OrderByExpiry})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(names(page)).To(Equal([]string{"c", "a", "d", "b"}))
+		Expect(store.listed).To(HaveLen(4))
+	})
+
+	It("Should reject invalid options", func() {
+		_, err := store.ListTokens(ListOptions{OrderBy: "x"})
+		Expect(err).To(MatchError(`invalid token order "x"`))
+
+		_, err = store.ListTokens(ListOptions{Limit: -1})
+		Expect(err).To(MatchError("limit cannot be negative"))
+
+		_, err = store.ListTokens(ListOptions{Cursor: "!!"})
+		Expect(err).To(MatchError(ErrInvalidCursor))
+
+		page, err := store.ListTokens(ListOptions{Limit: 1})
+		Expect(err).ToNot(HaveOccurred())
+		_, err = store.ListTokens(ListOptions{OrderBy: OrderByExpiry, Limit: 1, Cursor: page.NextCursor})
+		Expect(err).To(MatchError(ErrInvalidCursor))
+	})
+})
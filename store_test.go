@@ -0,0 +1,157 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !js
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DirectoryStore", func() {
+	var (
+		store *DirectoryStore
+		dir   string
+		pubK  ed25519.PublicKey
+		priK  ed25519.PrivateKey
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir = GinkgoT().TempDir()
+		store, err = NewDirectoryStore(dir)
+		Expect(err).ToNot(HaveOccurred())
+
+		pubK, priK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	clientToken := func(caller string, validity time.Duration) string {
+		claims, err := NewClientIDClaims(caller, nil, "", nil, "", "", time.Hour, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(validity))
+
+		token, err := SignToken(claims, priK)
+		Expect(err).ToNot(HaveOccurred())
+
+		return token
+	}
+
+	Describe("Basic operations", func() {
+		It("Should save, load, list and delete tokens", func() {
+			Expect(store.Save("bob", "token.bob")).To(Succeed())
+			Expect(store.Save("alice", "token.alice")).To(Succeed())
+
+			Expect(store.List()).To(Equal([]string{"alice", "bob"}))
+			Expect(store.Load("bob")).To(Equal("token.bob"))
+
+			Expect(store.Delete("bob")).To(Succeed())
+			Expect(store.Delete("bob")).To(MatchError(ErrTokenNotFound))
+
+			_, err := store.Load("bob")
+			Expect(err).To(MatchError(ErrTokenNotFound))
+			Expect(store.List()).To(Equal([]string{"alice"}))
+		})
+
+		It("Should validate names", func() {
+			for _, n := range []string{"", ".hidden", "../bob", `a\b`} {
+				Expect(store.Save(n, "x")).To(MatchError(ErrInvalidTokenName))
+			}
+		})
+	})
+
+	Describe("Quarantine", func() {
+		It("Should move tokens into quarantine with metadata", func() {
+			token := clientToken("up=bob", time.Hour)
+			Expect(store.Save("bob", token)).To(Succeed())
+
+			rec, err := store.Quarantine("bob", "testing")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rec.Name).To(Equal("bob"))
+			Expect(rec.Reason).To(Equal("testing"))
+			Expect(rec.Purpose).To(Equal(ClientIDPurpose))
+			Expect(rec.Identity).To(Equal("up=bob"))
+			Expect(rec.TokenID).ToNot(BeEmpty())
+			Expect(rec.ExpiresAt).To(BeTemporally("~", time.Now().Add(time.Hour), time.Second))
+
+			Expect(store.List()).To(BeEmpty())
+			Expect(filepath.Join(dir, "quarantine", rec.ID+".json")).To(BeARegularFile())
+			Expect(store.LoadQuarantined(rec.ID)).To(Equal(token))
+
+			recs, err := store.Quarantined()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(recs).To(HaveLen(1))
+			Expect(recs[0].ID).To(Equal(rec.ID))
+
+			_, err = store.Quarantine("bob", "testing")
+			Expect(err).To(MatchError(ErrTokenNotFound))
+		})
+
+		It("Should restore quarantined tokens", func() {
+			token := clientToken("up=bob", time.Hour)
+			Expect(store.Save("bob", token)).To(Succeed())
+
+			rec, err := store.Quarantine("bob", "testing")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(store.Save("bob", "replacement")).To(Succeed())
+			Expect(store.Restore(rec.ID)).To(MatchError("token bob already exists"))
+
+			Expect(store.Delete("bob")).To(Succeed())
+			Expect(store.Restore(rec.ID)).To(Succeed())
+			Expect(store.Load("bob")).To(Equal(token))
+			Expect(store.Quarantined()).To(BeEmpty())
+
+			Expect(store.Restore(rec.ID)).To(MatchError(ErrTokenNotFound))
+		})
+
+		It("Should quarantine expired and invalid tokens", func() {
+			Expect(store.Save("valid", clientToken("up=valid", time.Hour))).To(Succeed())
+			Expect(store.Save("expired", clientToken("up=expired", -time.Hour))).To(Succeed())
+			Expect(store.Save("corrupt", "garbage")).To(Succeed())
+
+			recs, err := store.QuarantineInvalid(nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(recs).To(HaveLen(2))
+			Expect(recs[0].Name).To(Equal("corrupt"))
+			Expect(recs[1].Name).To(Equal("expired"))
+			Expect(recs[1].Reason).To(Equal(jwt.ErrTokenExpired.Error()))
+			Expect(store.List()).To(Equal([]string{"valid"}))
+		})
+
+		It("Should quarantine tokens failing verification", func() {
+			Expect(store.Save("valid", clientToken("up=valid", time.Hour))).To(Succeed())
+
+			otherPubK, _ := loadEd25519Seed("testdata/ed25519/other.seed")
+			other, err := NewKeyRing(otherPubK)
+			Expect(err).ToNot(HaveOccurred())
+			trusted, err := NewKeyRing(pubK)
+			Expect(err).ToNot(HaveOccurred())
+
+			recs, err := store.QuarantineInvalid(trusted)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(recs).To(BeEmpty())
+
+			recs, err = store.QuarantineInvalid(other)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(recs).To(HaveLen(1))
+			Expect(recs[0].Reason).To(Equal("ed25519: verification error"))
+		})
+
+		It("Should handle corrupt records", func() {
+			Expect(os.WriteFile(filepath.Join(dir, "quarantine", "x.json"), []byte("{"), 0600)).To(Succeed())
+			_, err := store.Quarantined()
+			Expect(err).To(MatchError(ContainSubstring("invalid quarantine record x.json")))
+		})
+	})
+})
@@ -0,0 +1,197 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var (
+	ErrNotAStreamToken = errors.New("not a stream token")
+	ErrInvalidSubject  = errors.New("invalid subject")
+)
+
+// StreamClaims grants access to specific Choria Streams subjects and leafnode connections without full client rights
+//
+// The "purpose" claim should be set to StreamPurpose
+type StreamClaims struct {
+	// Name is the name of the stream consumer or leafnode
+	Name string `json:"name"`
+
+	// PublishSubjects are the subjects the holder can publish to
+	PublishSubjects []string `json:"pub_subjects,omitempty"`
+
+	// SubscribeSubjects are the subjects the holder can subscribe to
+	SubscribeSubjects []string `json:"sub_subjects,omitempty"`
+
+	// Leafnode allows the holder to connect to the broker as a leafnode
+	Leafnode bool `json:"leafnode,omitempty"`
+
+	// OrganizationUnit broker account the holder should belong to
+	OrganizationUnit string `json:"ou,omitempty"`
+
+	StandardClaims
+}
+
+// UniqueID returns the name and unique id used to generate private inboxes
+func (c *StreamClaims) UniqueID() (id string, uid string) {
+	return c.Name, fmt.Sprintf("%x", md5.Sum([]byte(c.Name)))
+}
+
+// NewStreamClaims generates new StreamClaims, at least one subject or leafnode access is required
+func NewStreamClaims(name string, pubSubjects []string, subSubjects []string, leafnode bool, org string, issuer string, validity time.Duration, pk ed25519.PublicKey, opts ...ClaimsOption) (*StreamClaims, error) {
+	if name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+
+	if pk == nil {
+		return nil, fmt.Errorf("public key is required")
+	}
+
+	if org == "" {
+		org = defaultOrg
+	}
+
+	stdClaims, err := newStandardClaims(issuer, StreamPurpose, validity, false, append([]ClaimsOption{withPublicKey(pk)}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &StreamClaims{
+		Name:              name,
+		PublishSubjects:   pubSubjects,
+		SubscribeSubjects: subSubjects,
+		Leafnode:          leafnode,
+		OrganizationUnit:  org,
+		StandardClaims:    *stdClaims,
+	}
+
+	err = claims.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// IsStreamToken determines if this is a stream token
+func IsStreamToken(claims StandardClaims) bool {
+	return claims.Purpose == StreamPurpose
+}
+
+// ParseStreamTokenUnverified parses the stream token in an unverified manner.
+func ParseStreamTokenUnverified(token string) (*StreamClaims, error) {
+	claims := &StreamClaims{}
+	_, err := parseUnverified(token, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	if !IsStreamToken(claims.StandardClaims) {
+		return nil, ErrNotAStreamToken
+	}
+
+	if claims.OrganizationUnit == "" {
+		claims.OrganizationUnit = defaultOrg
+	}
+
+	return claims, nil
+}
+
+// ParseStreamToken parses token and verifies it with pk
+func ParseStreamToken(token string, pk any, opts ...ParseOption) (*StreamClaims, error) {
+	claims := &StreamClaims{}
+	err := ParseToken(token, claims, pk, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse stream token: %w", err)
+	}
+
+	// if we have a tcs we require an issuer expiry to be set and it to not have expired
+	if !claims.verifyIssuerExpiry(claims.TrustChainSignature != "") {
+		return nil, jwt.ErrTokenExpired
+	}
+
+	if claims.OrganizationUnit == "" {
+		claims.OrganizationUnit = defaultOrg
+	}
+
+	return claims, nil
+}
+
+// ParseStreamTokenWithKeyfile parses token and verifies it with the RSA or ED25519 Public key in pkFile
+func ParseStreamTokenWithKeyfile(token string, pkFile string, opts ...ParseOption) (*StreamClaims, error) {
+	if pkFile == "" {
+		return nil, fmt.Errorf("invalid public key file")
+	}
+
+	certdat, err := os.ReadFile(pkFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not read validation certificate: %s", err)
+	}
+
+	pk, err := readRSAOrED25519PublicData(certdat)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseStreamToken(token, pk, opts...)
+}
+
+// Validate checks the stream name and subjects
+func (c *StreamClaims) Validate() error {
+	if !IsStreamToken(c.StandardClaims) {
+		return ErrNotAStreamToken
+	}
+
+	if c.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+
+	if len(c.PublishSubjects) == 0 && len(c.SubscribeSubjects) == 0 && !c.Leafnode {
+		return fmt.Errorf("at least one subject or leafnode access is required")
+	}
+
+	for _, s := range append(append([]string{}, c.PublishSubjects...), c.SubscribeSubjects...) {
+		err := validateSubject(s)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateSubject checks that subject is a valid NATS subject, wildcards are allowed
+func validateSubject(subject string) error {
+	if subject == "" {
+		return fmt.Errorf("%w: subject cannot be empty", ErrInvalidSubject)
+	}
+
+	if strings.ContainsAny(subject, " \t\r\n") {
+		return fmt.Errorf("%w: %q contains white space", ErrInvalidSubject, subject)
+	}
+
+	tokens := strings.Split(subject, ".")
+	for i, t := range tokens {
+		switch {
+		case t == "":
+			return fmt.Errorf("%w: %q contains an empty token", ErrInvalidSubject, subject)
+		case t == ">" && i != len(tokens)-1:
+			return fmt.Errorf("%w: %q has > before the last token", ErrInvalidSubject, subject)
+		case len(t) > 1 && strings.ContainsAny(t, "*>"):
+			return fmt.Errorf("%w: %q contains a partial wildcard", ErrInvalidSubject, subject)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,126 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("StreamClaims", func() {
+	var (
+		pubK ed25519.PublicKey
+		priK ed25519.PrivateKey
+		err  error
+	)
+
+	BeforeEach(func() {
+		pubK, priK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Describe("NewStreamClaims", func() {
+		It("Should require a name and public key", func() {
+			_, err := NewStreamClaims("", nil, nil, false, "", "", time.Hour, pubK)
+			Expect(err).To(MatchError("name is required"))
+
+			_, err = NewStreamClaims("events", nil, nil, false, "", "", time.Hour, nil)
+			Expect(err).To(MatchError("public key is required"))
+		})
+
+		It("Should require some access", func() {
+			_, err := NewStreamClaims("events", nil, nil, false, "", "", time.Hour, pubK)
+			Expect(err).To(MatchError("at least one subject or leafnode access is required"))
+
+			_, err = NewStreamClaims("events", nil, nil, true, "", "", time.Hour, pubK)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("Should validate subjects", func() {
+			for _, s := range []string{"", "a b", "a..b", "a.>.b", "a.b*", "a.>>"} {
+				_, err := NewStreamClaims("events", []string{s}, nil, false, "", "", time.Hour, pubK)
+				Expect(err).To(MatchError(ErrInvalidSubject), s)
+			}
+
+			_, err := NewStreamClaims("events", []string{"choria.events.>"}, []string{"choria.*.lifecycle"}, false, "", "", time.Hour, pubK)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("Should create valid claims", func() {
+			claims, err := NewStreamClaims("events", []string{"choria.events.>"}, []string{"choria.lifecycle.>"}, true, "", "ginkgo", time.Hour, pubK)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(claims.Purpose).To(Equal(StreamPurpose))
+			Expect(claims.Name).To(Equal("events"))
+			Expect(claims.PublishSubjects).To(Equal([]string{"choria.events.>"}))
+			Expect(claims.SubscribeSubjects).To(Equal([]string{"choria.lifecycle.>"}))
+			Expect(claims.Leafnode).To(BeTrue())
+			Expect(claims.OrganizationUnit).To(Equal(defaultOrg))
+			Expect(claims.PublicKey).To(Equal(hex.EncodeToString(pubK)))
+
+			id, _ := claims.UniqueID()
+			Expect(id).To(Equal("events"))
+		})
+	})
+
+	Describe("ParseStreamToken", func() {
+		It("Should parse valid tokens", func() {
+			claims, err := NewStreamClaims("events", nil, []string{"choria.lifecycle.>"}, false, "", "", time.Hour, pubK)
+			Expect(err).ToNot(HaveOccurred())
+
+			token, err := SignToken(claims, priK)
+			Expect(err).ToNot(HaveOccurred())
+
+			parsed, err := ParseStreamToken(token, pubK)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(parsed.SubscribeSubjects).To(Equal([]string{"choria.lifecycle.>"}))
+
+			unverified, err := ParseStreamTokenUnverified(token)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(unverified.Name).To(Equal("events"))
+
+			_, err = ParseStreamTokenWithKeyfile(token, "")
+			Expect(err).To(MatchError("invalid public key file"))
+		})
+
+		It("Should default the organization unit when parsing", func() {
+			claims, err := NewStreamClaims("events", nil, []string{"choria.lifecycle.>"}, false, "", "", time.Hour, pubK)
+			Expect(err).ToNot(HaveOccurred())
+			claims.OrganizationUnit = ""
+
+			Expect(claims.Validate()).To(Succeed())
+			Expect(claims.OrganizationUnit).To(BeEmpty())
+
+			token, err := SignToken(claims, priK)
+			Expect(err).ToNot(HaveOccurred())
+
+			parsed, err := ParseStreamToken(token, pubK)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(parsed.OrganizationUnit).To(Equal("choria"))
+
+			unverified, err := ParseStreamTokenUnverified(token)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(unverified.OrganizationUnit).To(Equal("choria"))
+		})
+
+		It("Should reject other tokens", func() {
+			claims, err := NewClientIDClaims("up=ginkgo", nil, "", nil, "", "", time.Hour, nil, pubK)
+			Expect(err).ToNot(HaveOccurred())
+
+			token, err := SignToken(claims, priK)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = ParseStreamToken(token, pubK)
+			Expect(err).To(MatchError(ErrNotAStreamToken))
+
+			_, err = ParseStreamTokenUnverified(token)
+			Expect(err).To(MatchError(ErrNotAStreamToken))
+		})
+	})
+})
@@ -0,0 +1,245 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DefaultMaxTokenSize is the largest token accepted by WithStrictParsing unless another limit is given
+const DefaultMaxTokenSize = 64 * 1024
+
+var (
+	// ErrTokenTooLarge indicates a token exceeded the size allowed by strict parsing
+	ErrTokenTooLarge = errors.New("token exceeds the maximum size")
+
+	// ErrInvalidTokenType indicates the typ header was missing or not JWT during strict parsing
+	ErrInvalidTokenType = errors.New("token typ header must be JWT")
+
+	// ErrUnexpectedAlgorithm indicates a token was signed using an algorithm strict parsing does not allow
+	ErrUnexpectedAlgorithm = errors.New("unexpected signing algorithm")
+
+	// ErrDuplicateJSONKey indicates the header or claims of a token hold the same key more than once
+	ErrDuplicateJSONKey = errors.New("duplicate JSON key")
+)
+
+type strictParsing struct {
+	maxSize    int
+	algorithms []string
+}
+
+// WithStrictParsing hardens parsing of tokens from untrusted sources. Tokens larger than maxSize, or
+// DefaultMaxTokenSize when 0, are rejected before being decoded, the typ header must be JWT, only the given
+// algorithms are accepted, defaulting to all supported ones, and the header and claims may not hold duplicate keys.
+// Claims encoded using a codec must use a registered codec and are decoded by it to detect duplicates
+func WithStrictParsing(maxSize int, algorithms ...string) ParseOption {
+	return func(o *parseOptions) error {
+		if maxSize < 0 {
+			return fmt.Errorf("maximum token size cannot be negative")
+		}
+
+		if maxSize == 0 {
+			maxSize = DefaultMaxTokenSize
+		}
+
+		for _, alg := range algorithms {
+			supported := false
+			for _, m := range validMethods {
+				if m == alg {
+					supported = true
+				}
+			}
+
+			if !supported {
+				return fmt.Errorf("unsupported signing algorithm %q", alg)
+			}
+		}
+
+		if len(algorithms) == 0 {
+			algorithms = validMethods
+		}
+
+		o.strict = &strictParsing{maxSize: maxSize, algorithms: algorithms}
+
+		return nil
+	}
+}
+
+// check performs the strict parsing checks on token before it is decoded
+func (s *strictParsing) check(token string) error {
+	if len(token) > s.maxSize {
+		return fmt.Errorf("%w: %d bytes exceeds %d", ErrTokenTooLarge, len(token), s.maxSize)
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return fmt.Errorf("token contains an invalid number of segments")
+	}
+
+	hdrb, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return fmt.Errorf("could not decode token header: %w", err)
+	}
+
+	err = checkDuplicateJSONKeys(hdrb)
+	if err != nil {
+		return fmt.Errorf("invalid token header: %w", err)
+	}
+
+	h := struct {
+		Typ string `json:"typ"`
+		codecHeader
+	}{}
+	err = json.Unmarshal(hdrb, &h)
+	if err != nil {
+		return fmt.Errorf("could not decode token header: %w", err)
+	}
+
+	if !strings.EqualFold(h.Typ, "JWT") {
+		return fmt.Errorf("%w: got %q", ErrInvalidTokenType, h.Typ)
+	}
+
+	allowed := false
+	for _, alg := range s.algorithms {
+		if alg == h.Alg {
+			allowed = true
+		}
+	}
+	if !allowed {
+		return fmt.Errorf("%w %q", ErrUnexpectedAlgorithm, h.Alg)
+	}
+
+	claims, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return fmt.Errorf("could not decode token claims: %w", err)
+	}
+
+	if h.Zip == compressionDeflate {
+		claims, err = inflateClaims(claims)
+		if err != nil {
+			return err
+		}
+	}
+
+	if h.Cty != "" {
+		return checkCodecClaims(h.Cty, claims)
+	}
+
+	err = checkDuplicateJSONKeys(claims)
+	if err != nil {
+		return fmt.Errorf("invalid token claims: %w", err)
+	}
+
+	return nil
+}
+
+// checkCodecClaims decodes claims using the codec registered for cty, codecs reject exact duplicate keys and
+// keys differing only in case are rejected here as encoding/json would merge them into the same field
+func checkCodecClaims(cty string, claims []byte) error {
+	codec, ok := codecFor(cty)
+	if !ok {
+		return fmt.Errorf("%w %q", ErrUnknownCodec, cty)
+	}
+
+	generic, err := codec.Unmarshal(claims)
+	if err != nil {
+		return fmt.Errorf("invalid token claims: %w", err)
+	}
+
+	err = checkDuplicateMapKeys(generic)
+	if err != nil {
+		return fmt.Errorf("invalid token claims: %w", err)
+	}
+
+	return nil
+}
+
+// checkDuplicateMapKeys ensures no map in v holds keys that differ only in case
+func checkDuplicateMapKeys(v any) error {
+	switch t := v.(type) {
+	case map[string]any:
+		keys := make(map[string]bool, len(t))
+		for k, e := range t {
+			key := strings.ToLower(k)
+			if keys[key] {
+				return fmt.Errorf("%w %q", ErrDuplicateClaimKey, k)
+			}
+			keys[key] = true
+
+			err := checkDuplicateMapKeys(e)
+			if err != nil {
+				return err
+			}
+		}
+
+	case []any:
+		for _, e := range t {
+			err := checkDuplicateMapKeys(e)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkDuplicateJSONKeys ensures no object in the JSON document data holds the same key twice
+func checkDuplicateJSONKeys(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	// keys seen in each open object, nil entries are arrays
+	var stack []map[string]bool
+	expectKey := false
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			if len(stack) > 0 {
+				return io.ErrUnexpectedEOF
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := tok.(type) {
+		case json.Delim:
+			switch t {
+			case '{':
+				stack = append(stack, map[string]bool{})
+				expectKey = true
+				continue
+			case '[':
+				stack = append(stack, nil)
+			default:
+				stack = stack[:len(stack)-1]
+			}
+
+		case string:
+			if expectKey {
+				// encoding/json matches keys case insensitively to struct fields
+				key := strings.ToLower(t)
+				keys := stack[len(stack)-1]
+				if keys[key] {
+					return fmt.Errorf("%w %q", ErrDuplicateJSONKey, t)
+				}
+				keys[key] = true
+				expectKey = false
+				continue
+			}
+		}
+
+		// after a value the next token in an object is a key
+		expectKey = len(stack) > 0 && stack[len(stack)-1] != nil
+	}
+}
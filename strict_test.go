@@ -0,0 +1,122 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Strict Parsing", func() {
+	var (
+		pubK   ed25519.PublicKey
+		priK   ed25519.PrivateKey
+		client *ClientIDClaims
+		token  string
+	)
+
+	// craft signs hdr and payload using priK
+	craft := func(hdr string, payload string) string {
+		signing := base64.RawURLEncoding.EncodeToString([]byte(hdr)) + "." + base64.RawURLEncoding.EncodeToString([]byte(payload))
+		return signing + "." + base64.RawURLEncoding.EncodeToString(ed25519.Sign(priK, []byte(signing)))
+	}
+
+	BeforeEach(func() {
+		var err error
+		pubK, priK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		client, err = NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		token, err = SignToken(client, priK)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Should validate its settings", func() {
+		err := ParseToken(token, &ClientIDClaims{}, pubK, WithStrictParsing(-1))
+		Expect(err).To(MatchError("maximum token size cannot be negative"))
+		err = ParseToken(token, &ClientIDClaims{}, pubK, WithStrictParsing(0, "none"))
+		Expect(err).To(MatchError(`unsupported signing algorithm "none"`))
+	})
+
+	It("Should parse well formed tokens", func() {
+		Expect(ParseToken(token, &ClientIDClaims{}, pubK, WithStrictParsing(0))).To(Succeed())
+		Expect(ParseToken(token, &ClientIDClaims{}, pubK, WithStrictParsing(len(token), algEdDSA))).To(Succeed())
+
+		compressed, err := SignToken(client, priK, WithCompression())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ParseToken(compressed, &ClientIDClaims{}, pubK, WithStrictParsing(0))).To(Succeed())
+	})
+
+	It("Should reject large tokens", func() {
+		err := ParseToken(token, &ClientIDClaims{}, pubK, WithStrictParsing(len(token)-1))
+		Expect(err).To(MatchError(ErrTokenTooLarge))
+	})
+
+	It("Should require the typ header", func() {
+		payload, err := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[1])
+		Expect(err).ToNot(HaveOccurred())
+
+		crafted := craft(`{"alg":"EdDSA"}`, string(payload))
+		Expect(ParseToken(crafted, &ClientIDClaims{}, pubK)).To(Succeed())
+		err = ParseToken(crafted, &ClientIDClaims{}, pubK, WithStrictParsing(0))
+		Expect(err).To(MatchError(ErrInvalidTokenType))
+
+		err = ParseToken(craft(`{"alg":"EdDSA","typ":"JOSE"}`, string(payload)), &ClientIDClaims{}, pubK, WithStrictParsing(0))
+		Expect(err).To(MatchError(ErrInvalidTokenType))
+	})
+
+	It("Should reject unexpected algorithms", func() {
+		err := ParseToken(token, &ClientIDClaims{}, pubK, WithStrictParsing(0, algRS256))
+		Expect(err).To(MatchError(ErrUnexpectedAlgorithm))
+
+		err = ParseToken(craft(`{"alg":"none","typ":"JWT"}`, `{}`), &ClientIDClaims{}, pubK, WithStrictParsing(0))
+		Expect(err).To(MatchError(ErrUnexpectedAlgorithm))
+	})
+
+	It("Should reject duplicate keys", func() {
+		crafted := craft(`{"alg":"EdDSA","typ":"JWT"}`, `{"purpose":"choria_client_id","callerid":"up=bob","CallerID":"up=admin"}`)
+		err := ParseToken(crafted, &ClientIDClaims{}, pubK, WithStrictParsing(0))
+		Expect(err).To(MatchError(ErrDuplicateJSONKey))
+		Expect(err).To(MatchError(ContainSubstring("invalid token claims")))
+
+		err = ParseToken(craft(`{"alg":"EdDSA","typ":"JWT","alg":"EdDSA"}`, `{}`), &ClientIDClaims{}, pubK, WithStrictParsing(0))
+		Expect(err).To(MatchError(ErrDuplicateJSONKey))
+		Expect(err).To(MatchError(ContainSubstring("invalid token header")))
+	})
+
+	It("Should check claims encoded using codecs", func() {
+		if _, ok := codecFor(ginkgoCodecContentType); !ok {
+			Expect(RegisterCodec(&ginkgoCodec{})).To(Succeed())
+		}
+
+		encoded, err := SignToken(client, priK, WithCodec(ginkgoCodecContentType))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ParseToken(encoded, &ClientIDClaims{}, pubK, WithStrictParsing(0))).To(Succeed())
+
+		payload := base64.StdEncoding.EncodeToString([]byte(`{"purpose":"choria_client_id","callerid":"up=bob","CallerID":"up=admin"}`))
+		err = ParseToken(craft(`{"alg":"EdDSA","typ":"JWT","cty":"ginkgo+json"}`, payload), &ClientIDClaims{}, pubK, WithStrictParsing(0))
+		Expect(err).To(MatchError(ErrDuplicateClaimKey))
+		Expect(err).To(MatchError(ContainSubstring("invalid token claims")))
+
+		err = ParseToken(craft(`{"alg":"EdDSA","typ":"JWT","cty":"unknown"}`, payload), &ClientIDClaims{}, pubK, WithStrictParsing(0))
+		Expect(err).To(MatchError(ErrUnknownCodec))
+	})
+
+	It("Should only consider keys within the same object", func() {
+		Expect(checkDuplicateJSONKeys([]byte(`{"a":{"a":1,"b":[{"a":1},{"a":2}]},"b":["a","a"]}`))).To(Succeed())
+		Expect(checkDuplicateJSONKeys([]byte(`{"a":{"b":1,"c":{},"b":2}}`))).To(MatchError(ErrDuplicateJSONKey))
+		Expect(checkDuplicateJSONKeys([]byte(`{"a":1`))).ToNot(Succeed())
+
+		Expect(checkDuplicateMapKeys(map[string]any{"a": map[string]any{"a": 1}, "b": []any{map[string]any{"a": 1}}})).To(Succeed())
+		Expect(checkDuplicateMapKeys(map[string]any{"a": []any{map[string]any{"b": 1, "B": 2}}})).To(MatchError(ErrDuplicateClaimKey))
+	})
+})
@@ -0,0 +1,106 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// subjectUnsafe are characters that would let an interpolated value change the meaning of a subject
+const subjectUnsafe = ".*>"
+
+// ValidateSubjectToken checks that value can be safely interpolated into a NATS subject as a single token, values
+// with white space, control characters, wildcards or token separators are rejected
+func ValidateSubjectToken(value string) error {
+	if value == "" {
+		return fmt.Errorf("%w: subject token cannot be empty", ErrInvalidSubject)
+	}
+
+	if !utf8.ValidString(value) {
+		return fmt.Errorf("%w: %q is not valid utf-8", ErrInvalidSubject, value)
+	}
+
+	for _, r := range value {
+		switch {
+		case unicode.IsSpace(r) || unicode.IsControl(r):
+			return fmt.Errorf("%w: %q contains white space or control characters", ErrInvalidSubject, value)
+		case strings.ContainsRune(subjectUnsafe, r):
+			return fmt.Errorf("%w: %q contains %q", ErrInvalidSubject, value, r)
+		}
+	}
+
+	return nil
+}
+
+// ValidateSubjectLiteral checks that value can be safely interpolated into a NATS subject as one or more dot separated
+// tokens, for example a FQDN server identity, wildcards, empty tokens, white space and control characters are rejected
+func ValidateSubjectLiteral(value string) error {
+	if value == "" {
+		return fmt.Errorf("%w: subject cannot be empty", ErrInvalidSubject)
+	}
+
+	for _, t := range strings.Split(value, ".") {
+		err := ValidateSubjectToken(t)
+		if err != nil {
+			return fmt.Errorf("%w: %q has an invalid token", ErrInvalidSubject, value)
+		}
+	}
+
+	return nil
+}
+
+// EscapeSubjectToken encodes value so that it is always a single safe NATS subject token, unsafe bytes and % are
+// encoded as %XX and an empty value is encoded as a lone %. Different values always produce different tokens
+func EscapeSubjectToken(value string) string {
+	if value == "" {
+		return "%"
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c <= ' ' || c >= 0x7f || c == '%' || strings.IndexByte(subjectUnsafe, c) >= 0 {
+			fmt.Fprintf(&b, "%%%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+
+	return b.String()
+}
+
+// validateCollectives ensures every collective can be used as a subject token
+func validateCollectives(collectives []string) error {
+	for _, c := range collectives {
+		err := ValidateSubjectToken(c)
+		if err != nil {
+			return fmt.Errorf("invalid collective: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// subjectsOverlap determines if any subject could be matched by both the patterns a and b
+func subjectsOverlap(a string, b string) bool {
+	at := strings.Split(a, ".")
+	bt := strings.Split(b, ".")
+
+	for i := 0; i < len(at) && i < len(bt); i++ {
+		switch {
+		case at[i] == ">" || bt[i] == ">":
+			return true
+		case at[i] == "*" || bt[i] == "*" || at[i] == bt[i]:
+			continue
+		default:
+			return false
+		}
+	}
+
+	return len(at) == len(bt)
+}
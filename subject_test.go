@@ -0,0 +1,169 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"math/big"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// randomSubjectValue creates values biased towards characters that are meaningful in subjects
+func randomSubjectValue() string {
+	alphabet := []rune("ab1_-=.*> \t\r\n\x00\x7f%é")
+
+	n, err := rand.Int(rand.Reader, big.NewInt(12))
+	Expect(err).ToNot(HaveOccurred())
+
+	var b strings.Builder
+	for i := int64(0); i < n.Int64(); i++ {
+		idx, err := rand.Int(rand.Reader, big.NewInt(int64(len(alphabet))))
+		Expect(err).ToNot(HaveOccurred())
+		b.WriteRune(alphabet[idx.Int64()])
+	}
+
+	return b.String()
+}
+
+var _ = Describe("Subject interpolation", func() {
+	Describe("ValidateSubjectToken", func() {
+		DescribeTable("Rejected values",
+			func(value string) {
+				Expect(ValidateSubjectToken(value)).To(MatchError(ErrInvalidSubject))
+			},
+			Entry("empty", ""),
+			Entry("dot", "a.b"),
+			Entry("star", "*"),
+			Entry("partial star", "a*"),
+			Entry("greater than", ">"),
+			Entry("space", "a b"),
+			Entry("tab", "a\tb"),
+			Entry("new line", "a\nb"),
+			Entry("nul", "a\x00"),
+			Entry("del", "a\x7f"),
+			Entry("non breaking space", "a\u00a0b"),
+			Entry("invalid utf-8", "a\xff"),
+		)
+
+		DescribeTable("Accepted values",
+			func(value string) {
+				Expect(ValidateSubjectToken(value)).To(Succeed())
+			},
+			Entry("simple", "mcollective"),
+			Entry("caller id", "choria=bob"),
+			Entry("dashes", "my-collective_1"),
+			Entry("unicode", "café"),
+		)
+
+		It("Should never accept values that change the structure of a subject", func() {
+			for i := 0; i < 5000; i++ {
+				value := randomSubjectValue()
+				if ValidateSubjectToken(value) != nil {
+					continue
+				}
+
+				subject := "choria." + value + ".reply"
+				Expect(strings.Split(subject, ".")).To(HaveLen(3), "value %q", value)
+				Expect(validateSubject(subject)).To(Succeed(), "value %q", value)
+				Expect(subject).ToNot(ContainSubstring("*"))
+				Expect(subject).ToNot(ContainSubstring(">"))
+			}
+		})
+	})
+
+	Describe("ValidateSubjectLiteral", func() {
+		It("Should allow dotted literals without wildcards", func() {
+			Expect(ValidateSubjectLiteral("node1.example.net")).To(Succeed())
+			Expect(ValidateSubjectLiteral("")).To(MatchError(ErrInvalidSubject))
+			Expect(ValidateSubjectLiteral("node1..example")).To(MatchError(ErrInvalidSubject))
+			Expect(ValidateSubjectLiteral("node1.example.")).To(MatchError(ErrInvalidSubject))
+			Expect(ValidateSubjectLiteral("node1.*")).To(MatchError(ErrInvalidSubject))
+			Expect(ValidateSubjectLiteral("node1.>")).To(MatchError(ErrInvalidSubject))
+			Expect(ValidateSubjectLiteral("node1 .example")).To(MatchError(ErrInvalidSubject))
+		})
+	})
+
+	Describe("EscapeSubjectToken", func() {
+		It("Should escape unsafe characters", func() {
+			Expect(EscapeSubjectToken("bob")).To(Equal("bob"))
+			Expect(EscapeSubjectToken("")).To(Equal("%"))
+			Expect(EscapeSubjectToken("a.b")).To(Equal("a%2Eb"))
+			Expect(EscapeSubjectToken("a b>*")).To(Equal("a%20b%3E%2A"))
+			Expect(EscapeSubjectToken("100%")).To(Equal("100%25"))
+			Expect(EscapeSubjectToken("é")).To(Equal("%C3%A9"))
+		})
+
+		It("Should always produce distinct safe tokens", func() {
+			seen := map[string]string{}
+
+			for i := 0; i < 5000; i++ {
+				value := randomSubjectValue()
+				escaped := EscapeSubjectToken(value)
+				Expect(ValidateSubjectToken(escaped)).To(Succeed(), "value %q", value)
+
+				if prev, ok := seen[escaped]; ok {
+					Expect(prev).To(Equal(value))
+				}
+				seen[escaped] = value
+			}
+		})
+	})
+
+	Describe("subjectsOverlap", func() {
+		It("Should detect overlapping patterns", func() {
+			Expect(subjectsOverlap("a.b", "a.b")).To(BeTrue())
+			Expect(subjectsOverlap("a.*", "a.b")).To(BeTrue())
+			Expect(subjectsOverlap("a.>", "a.b.c")).To(BeTrue())
+			Expect(subjectsOverlap("*.b.c", "a.>")).To(BeTrue())
+			Expect(subjectsOverlap(">", "x")).To(BeTrue())
+			Expect(subjectsOverlap("a.b", "a.c")).To(BeFalse())
+			Expect(subjectsOverlap("a.b", "a.b.c")).To(BeFalse())
+			Expect(subjectsOverlap("a.*", "a.b.c")).To(BeFalse())
+			Expect(subjectsOverlap("a", "a.>")).To(BeFalse())
+		})
+	})
+
+	Describe("Claims", func() {
+		var pubK ed25519.PublicKey
+
+		BeforeEach(func() {
+			var err error
+			pubK, _, err = ed25519.GenerateKey(rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("Should reject unsafe collectives and identities", func() {
+			_, err := NewServerClaims("node.example.net", []string{"choria.>"}, "", nil, nil, pubK, "", time.Hour)
+			Expect(err).To(MatchError(ErrInvalidSubject))
+
+			server := &ServerClaims{ChoriaIdentity: "node.example.net", Collectives: []string{"choria.>"}, StandardClaims: StandardClaims{Purpose: ServerPurpose}}
+			Expect(server.Validate()).To(MatchError(ErrInvalidSubject))
+			server.Collectives = []string{"choria"}
+			server.ChoriaIdentity = "node.*"
+			Expect(server.Validate()).To(MatchError(ErrInvalidSubject))
+			server.ChoriaIdentity = "node.example.net"
+			Expect(server.Validate()).To(Succeed())
+
+			_, err = NewRegistrationClaims("node.example.net", []string{"*"}, nil, "", "", time.Hour, pubK)
+			Expect(err).To(MatchError("invalid collective: invalid subject: \"*\" contains '*'"))
+
+			_, err = NewRegistrationClaims("node >", []string{"choria"}, nil, "", "", time.Hour, pubK)
+			Expect(err).To(MatchError(ErrInvalidSubject))
+
+			_, err = NewObserverClaims("watcher", []string{"choria reply"}, true, false, nil, "", "", time.Hour, pubK)
+			Expect(err).To(MatchError(ErrInvalidSubject))
+		})
+
+		It("Should reject unsafe collectives in connection helpers", func() {
+			_, _, _, err := NatsConnectionHelpers("x", "choria.>", "seed", nil)
+			Expect(err).To(MatchError("invalid collective: invalid subject: \"choria.>\" contains '.'"))
+		})
+	})
+})
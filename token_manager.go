@@ -0,0 +1,336 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !js
+
+package tokens
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// TokenRenewer obtains a renewed copy of token, for example from an AAA service, a provisioner or a local signer.
+// key is the private key of the token holder for renewers that need to prove possession
+type TokenRenewer func(ctx context.Context, token string, key ed25519.PrivateKey) (string, error)
+
+// LocalTokenRenewer is a TokenRenewer that renews tokens using RenewToken and signer
+func LocalTokenRenewer(signer any, validity time.Duration, opts ...RenewOption) TokenRenewer {
+	return func(_ context.Context, token string, _ ed25519.PrivateKey) (string, error) {
+		return RenewToken(token, signer, validity, opts...)
+	}
+}
+
+// maxTokenRenewalBackoff limits how long failed renewals back off, unless the retry interval is longer
+const maxTokenRenewalBackoff = 30 * time.Minute
+
+// TokenManagerOption configures optional behavior of a TokenManager
+type TokenManagerOption func(*TokenManager) error
+
+// WithTokenRenewalWindow renews tokens once less than window of their validity remains, by default tokens are renewed
+// when a third of their validity remains
+func WithTokenRenewalWindow(window time.Duration) TokenManagerOption {
+	return func(m *TokenManager) error {
+		if window <= 0 {
+			return fmt.Errorf("renewal window must be positive")
+		}
+
+		m.window = window
+
+		return nil
+	}
+}
+
+// WithTokenRenewalRetryInterval sets how long to wait before retrying failed renewals, defaults to one minute. Repeated
+// failures double the interval up to 30 minutes and renewals are never scheduled closer together than the interval
+func WithTokenRenewalRetryInterval(interval time.Duration) TokenManagerOption {
+	return func(m *TokenManager) error {
+		if interval <= 0 {
+			return fmt.Errorf("retry interval must be positive")
+		}
+
+		m.retry = interval
+
+		return nil
+	}
+}
+
+// WithTokenManagerLogger logs renewals and failures to log
+func WithTokenManagerLogger(log *logrus.Entry) TokenManagerOption {
+	return func(m *TokenManager) error {
+		m.log = log
+		return nil
+	}
+}
+
+// TokenManager owns a token file and the matching seed, it renews the token before it expires, rewrites the file and
+// notifies subscribers of the new token
+type TokenManager struct {
+	tokenFile string
+	key       ed25519.PrivateKey
+	pk        any
+	renewer   TokenRenewer
+	window    time.Duration
+	retry     time.Duration
+	log       *logrus.Entry
+
+	token       string
+	claims      natsConnectionClaims
+	subscribers map[int]func(token string)
+	nextSub     int
+	mu          sync.Mutex
+}
+
+// NewTokenManager creates a TokenManager for the token in tokenFile held by the key in the hex encoded seedFile,
+// renewals are obtained from renewer and must be signed by pk, a public key or KeyResolver
+func NewTokenManager(tokenFile string, seedFile string, pk any, renewer TokenRenewer, opts ...TokenManagerOption) (*TokenManager, error) {
+	if pk == nil {
+		return nil, fmt.Errorf("public key is required")
+	}
+
+	if renewer == nil {
+		return nil, fmt.Errorf("renewer is required")
+	}
+
+	_, key, err := ed25519KeyPairFromSeedFile(seedFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not load seed: %w", err)
+	}
+
+	m := &TokenManager{
+		tokenFile:   tokenFile,
+		key:         key,
+		pk:          pk,
+		renewer:     renewer,
+		retry:       time.Minute,
+		subscribers: map[int]func(string){},
+	}
+
+	for _, opt := range opts {
+		err = opt(m)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if m.log == nil {
+		log := logrus.New()
+		log.SetOutput(io.Discard)
+		m.log = logrus.NewEntry(log)
+	}
+
+	tb, err := os.ReadFile(tokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not read token: %w", err)
+	}
+
+	token := strings.TrimSpace(string(tb))
+	claims, err := parseNatsConnectionClaims(token)
+	if err != nil {
+		return nil, err
+	}
+
+	m.token = token
+	m.claims = claims
+
+	return m, nil
+}
+
+// Token is the current token
+func (m *TokenManager) Token() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.token
+}
+
+// ExpireTime is the expiry time of the current token
+func (m *TokenManager) ExpireTime() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.claims.ExpireTime()
+}
+
+// Subscribe calls fn with every renewed token until the returned function is called, fn is called synchronously
+// after the token file is updated
+func (m *TokenManager) Subscribe(fn func(token string)) (unsubscribe func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := m.nextSub
+	m.nextSub++
+	m.subscribers[id] = fn
+
+	return func() {
+		m.mu.Lock()
+		delete(m.subscribers, id)
+		m.mu.Unlock()
+	}
+}
+
+// RenewalTime is when the current token will be renewed by Run
+func (m *TokenManager) RenewalTime() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.renewalTime()
+}
+
+func (m *TokenManager) renewalTime() time.Time {
+	exp := m.claims.ExpireTime()
+
+	window := m.window
+	if window == 0 {
+		var validity time.Duration
+		if sc, ok := m.claims.(standardClaimsProvider); ok && sc.getStandardClaims().IssuedAt != nil {
+			validity = exp.Sub(sc.getStandardClaims().IssuedAt.Time)
+		}
+		window = validity / 3
+	}
+
+	return exp.Add(-window)
+}
+
+// Renew immediately renews the token, replaces the token file and notifies subscribers
+func (m *TokenManager) Renew(ctx context.Context) error {
+	current := m.Token()
+
+	token, err := m.renewer(ctx, current, m.key)
+	if err != nil {
+		return fmt.Errorf("could not renew token: %w", err)
+	}
+
+	token = strings.TrimSpace(token)
+	claims, err := m.verifyRenewed(ctx, token)
+	if err != nil {
+		return fmt.Errorf("invalid renewed token: %w", err)
+	}
+
+	m.mu.Lock()
+	_, uid := m.claims.UniqueID()
+	if _, nuid := claims.UniqueID(); nuid != uid {
+		m.mu.Unlock()
+		return fmt.Errorf("invalid renewed token: identity changed")
+	}
+
+	perm := os.FileMode(0600)
+	if st, err := os.Stat(m.tokenFile); err == nil {
+		perm = st.Mode().Perm()
+	}
+
+	err = writeFileAtomic(m.tokenFile, []byte(token), perm)
+	if err != nil {
+		m.mu.Unlock()
+		return fmt.Errorf("could not save renewed token: %w", err)
+	}
+
+	m.token = token
+	m.claims = claims
+
+	subscribers := make([]func(string), 0, len(m.subscribers))
+	for _, fn := range m.subscribers {
+		subscribers = append(subscribers, fn)
+	}
+	m.mu.Unlock()
+
+	m.log.Infof("Renewed token %s, expires at %v", m.tokenFile, claims.ExpireTime())
+
+	for _, fn := range subscribers {
+		fn(token)
+	}
+
+	return nil
+}
+
+// verifyRenewed verifies token and ensures it is held by the key of the manager
+func (m *TokenManager) verifyRenewed(ctx context.Context, token string) (natsConnectionClaims, error) {
+	claims, err := parseNatsConnectionClaims(token)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ParseTokenContext(ctx, token, claims, m.pk)
+	if err != nil {
+		return nil, err
+	}
+
+	sc, ok := claims.(standardClaimsProvider)
+	if !ok {
+		return nil, fmt.Errorf("unsupported claims %T", claims)
+	}
+
+	holder := claimsPublicKey(sc.getStandardClaims())
+	if holder == nil || !holder.Equal(m.key.Public()) {
+		return nil, fmt.Errorf("public key does not match the seed")
+	}
+
+	return claims, nil
+}
+
+// retryDelay is how long to wait after failures consecutive failed renewals
+func (m *TokenManager) retryDelay(failures int) time.Duration {
+	limit := maxTokenRenewalBackoff
+	if m.retry > limit {
+		limit = m.retry
+	}
+
+	delay := m.retry
+	for i := 1; i < failures && delay < limit; i++ {
+		delay *= 2
+	}
+
+	if delay > limit {
+		return limit
+	}
+
+	return delay
+}
+
+// Run renews the token before it expires until ctx is done, failed renewals are retried with backoff
+func (m *TokenManager) Run(ctx context.Context) error {
+	timer := time.NewTimer(time.Until(m.RenewalTime()))
+	defer timer.Stop()
+
+	failures := 0
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case <-timer.C:
+			err := m.Renew(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+
+				failures++
+				delay := m.retryDelay(failures)
+				m.log.Errorf("Token renewal failed, retrying in %v: %v", delay, err)
+				timer.Reset(delay)
+				continue
+			}
+
+			failures = 0
+
+			next := time.Until(m.RenewalTime())
+			if next < m.retry {
+				m.log.Warnf("Renewed token %s is due for renewal in %v, waiting %v, the renewal window might exceed the token validity", m.tokenFile, next, m.retry)
+				next = m.retry
+			}
+
+			timer.Reset(next)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !js
+
+package tokens
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TokenManager", func() {
+	var (
+		issuerPubK ed25519.PublicKey
+		issuerPriK ed25519.PrivateKey
+		holderPubK ed25519.PublicKey
+		tokenFile  string
+		seedFile   string
+		token      string
+	)
+
+	BeforeEach(func() {
+		var (
+			holderPriK ed25519.PrivateKey
+			err        error
+		)
+
+		issuerPubK, issuerPriK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		holderPubK, holderPriK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		dir := GinkgoT().TempDir()
+		tokenFile = filepath.Join(dir, "token.jwt")
+		seedFile = filepath.Join(dir, "token.seed")
+		Expect(os.WriteFile(seedFile, []byte(hex.EncodeToString(holderPriK.Seed())), 0600)).To(Succeed())
+
+		claims, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, holderPubK)
+		Expect(err).ToNot(HaveOccurred())
+		token, err = SignToken(claims, issuerPriK)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(os.WriteFile(tokenFile, []byte(token), 0600)).To(Succeed())
+	})
+
+	It("Should validate arguments", func() {
+		_, err := NewTokenManager(tokenFile, seedFile, nil, LocalTokenRenewer(issuerPriK, 0))
+		Expect(err).To(MatchError("public key is required"))
+
+		_, err = NewTokenManager(tokenFile, seedFile, issuerPubK, nil)
+		Expect(err).To(MatchError("renewer is required"))
+
+		_, err = NewTokenManager(tokenFile, "/nonexisting", issuerPubK, LocalTokenRenewer(issuerPriK, 0))
+		Expect(err).To(MatchError(ContainSubstring("could not load seed")))
+
+		_, err = NewTokenManager("/nonexisting", seedFile, issuerPubK, LocalTokenRenewer(issuerPriK, 0))
+		Expect(err).To(MatchError(ContainSubstring("could not read token")))
+
+		_, err = NewTokenManager(tokenFile, seedFile, issuerPubK, LocalTokenRenewer(issuerPriK, 0), WithTokenRenewalWindow(0))
+		Expect(err).To(MatchError("renewal window must be positive"))
+	})
+
+	It("Should schedule renewal before expiry", func() {
+		m, err := NewTokenManager(tokenFile, seedFile, issuerPubK, LocalTokenRenewer(issuerPriK, 0))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m.Token()).To(Equal(token))
+		Expect(m.RenewalTime()).To(BeTemporally("~", m.ExpireTime().Add(-20*time.Minute), time.Second))
+
+		m, err = NewTokenManager(tokenFile, seedFile, issuerPubK, LocalTokenRenewer(issuerPriK, 0), WithTokenRenewalWindow(5*time.Minute))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m.RenewalTime()).To(BeTemporally("==", m.ExpireTime().Add(-5*time.Minute)))
+	})
+
+	It("Should renew, save and notify", func() {
+		var seenKey ed25519.PrivateKey
+		renewer := func(ctx context.Context, token string, key ed25519.PrivateKey) (string, error) {
+			seenKey = key
+			return RenewToken(token, issuerPriK, 2*time.Hour)
+		}
+
+		m, err := NewTokenManager(tokenFile, seedFile, issuerPubK, renewer)
+		Expect(err).ToNot(HaveOccurred())
+
+		var notified []string
+		unsubscribe := m.Subscribe(func(token string) { notified = append(notified, token) })
+
+		Expect(m.Renew(context.Background())).To(Succeed())
+		Expect(seenKey.Public()).To(Equal(holderPubK))
+		Expect(m.Token()).ToNot(Equal(token))
+		Expect(notified).To(Equal([]string{m.Token()}))
+
+		saved, err := os.ReadFile(tokenFile)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(saved)).To(Equal(m.Token()))
+
+		claims, err := ParseClientIDToken(string(saved), issuerPubK, true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(claims.ExpireTime()).To(BeTemporally("~", time.Now().Add(2*time.Hour), time.Second))
+
+		unsubscribe()
+		Expect(m.Renew(context.Background())).To(Succeed())
+		Expect(notified).To(HaveLen(1))
+	})
+
+	It("Should reject unusable renewals", func() {
+		other, err := NewClientIDClaims("up=alice", nil, "", nil, "", "", time.Hour, nil, holderPubK)
+		Expect(err).ToNot(HaveOccurred())
+		otherToken, err := SignToken(other, issuerPriK)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, otherIssuerPriK, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		sameHolder, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, holderPubK)
+		Expect(err).ToNot(HaveOccurred())
+		untrusted, err := SignToken(sameHolder, otherIssuerPriK)
+		Expect(err).ToNot(HaveOccurred())
+
+		otherHolderPubK, _, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		otherKey, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, otherHolderPubK)
+		Expect(err).ToNot(HaveOccurred())
+		otherKeyToken, err := SignToken(otherKey, issuerPriK)
+		Expect(err).ToNot(HaveOccurred())
+
+		for renewed, expected := range map[string]string{
+			"invalid":     "invalid renewed token: unsupported token purpose: ",
+			otherToken:    "invalid renewed token: identity changed",
+			untrusted:     "invalid renewed token: ed25519: verification error",
+			otherKeyToken: "invalid renewed token: public key does not match the seed",
+		} {
+			renewed := renewed
+			m, err := NewTokenManager(tokenFile, seedFile, issuerPubK, func(context.Context, string, ed25519.PrivateKey) (string, error) {
+				return renewed, nil
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(m.Renew(context.Background())).To(MatchError(expected))
+			Expect(m.Token()).To(Equal(token))
+		}
+
+		saved, err := os.ReadFile(tokenFile)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(saved)).To(Equal(token))
+	})
+
+	It("Should keep the file mode and symlinks of the token file", func() {
+		dir := filepath.Dir(tokenFile)
+		Expect(os.Mkdir(filepath.Join(dir, "data"), 0700)).To(Succeed())
+		target := filepath.Join(dir, "data", "token.jwt")
+		Expect(os.Rename(tokenFile, target)).To(Succeed())
+		Expect(os.Chmod(target, 0640)).To(Succeed())
+		Expect(os.Symlink(filepath.Join("data", "token.jwt"), tokenFile)).To(Succeed())
+
+		m, err := NewTokenManager(tokenFile, seedFile, issuerPubK, LocalTokenRenewer(issuerPriK, time.Hour))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m.Renew(context.Background())).To(Succeed())
+
+		link, err := os.Lstat(tokenFile)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(link.Mode() & os.ModeSymlink).ToNot(BeZero())
+
+		st, err := os.Stat(target)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(st.Mode().Perm()).To(Equal(os.FileMode(0640)))
+
+		saved, err := os.ReadFile(target)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(saved)).To(Equal(m.Token()))
+	})
+
+	It("Should back off failed renewals", func() {
+		m, err := NewTokenManager(tokenFile, seedFile, issuerPubK, LocalTokenRenewer(issuerPriK, 0), WithTokenRenewalRetryInterval(time.Minute))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m.retryDelay(1)).To(Equal(time.Minute))
+		Expect(m.retryDelay(3)).To(Equal(4 * time.Minute))
+		Expect(m.retryDelay(100)).To(Equal(maxTokenRenewalBackoff))
+
+		m, err = NewTokenManager(tokenFile, seedFile, issuerPubK, LocalTokenRenewer(issuerPriK, 0), WithTokenRenewalRetryInterval(time.Hour))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m.retryDelay(3)).To(Equal(time.Hour))
+	})
+
+	It("Should not renew continuously when the window exceeds the validity", func() {
+		var attempts atomic.Int32
+		renewer := func(ctx context.Context, token string, key ed25519.PrivateKey) (string, error) {
+			attempts.Add(1)
+			return RenewToken(token, issuerPriK, time.Hour)
+		}
+
+		m, err := NewTokenManager(tokenFile, seedFile, issuerPubK, renewer, WithTokenRenewalWindow(2*time.Hour), WithTokenRenewalRetryInterval(100*time.Millisecond))
+		Expect(err).ToNot(HaveOccurred())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() { done <- m.Run(ctx) }()
+
+		time.Sleep(250 * time.Millisecond)
+		cancel()
+		Eventually(done).Should(Receive(BeNil()))
+
+		Expect(attempts.Load()).To(BeNumerically(">=", 1))
+		Expect(attempts.Load()).To(BeNumerically("<=", 4))
+	})
+
+	It("Should renew in the background and retry failures", func() {
+		var attempts atomic.Int32
+		renewer := func(ctx context.Context, token string, key ed25519.PrivateKey) (string, error) {
+			if attempts.Add(1) == 1 {
+				return "", errors.New("service unavailable")
+			}
+			return RenewToken(token, issuerPriK, 24*time.Hour)
+		}
+
+		m, err := NewTokenManager(tokenFile, seedFile, issuerPubK, renewer, WithTokenRenewalWindow(2*time.Hour), WithTokenRenewalRetryInterval(10*time.Millisecond))
+		Expect(err).ToNot(HaveOccurred())
+
+		renewed := make(chan string, 1)
+		m.Subscribe(func(token string) { renewed <- token })
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() { done <- m.Run(ctx) }()
+
+		Eventually(renewed).Should(Receive())
+		Expect(attempts.Load()).To(Equal(int32(2)))
+		Expect(m.RenewalTime()).To(BeTemporally("~", time.Now().Add(22*time.Hour), time.Minute))
+
+		cancel()
+		Eventually(done).Should(Receive(BeNil()))
+	})
+})
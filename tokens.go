@@ -7,17 +7,12 @@ package tokens
 import (
 	"bytes"
 	"context"
+	"crypto"
 	"crypto/ed25519"
 	"crypto/rsa"
-	"crypto/tls"
-	"encoding/base64"
 	"encoding/hex"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
-	"net/http"
-	"net/url"
 	"os"
 	"regexp"
 	"strings"
@@ -25,7 +20,6 @@ import (
 
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/segmentio/ksuid"
-	"github.com/sirupsen/logrus"
 )
 
 var (
@@ -48,7 +42,28 @@ const (
 	DefaultValidity   = time.Hour
 )
 
-var defaultIssuer = "Choria Tokens Package"
+var (
+	defaultIssuer         = "Choria Tokens Package"
+	defaultIssuerMetadata *IssuerMetadata
+)
+
+// SetDefaultIssuerMetadata sets issuer metadata that will be stamped into every token created by this package, nil disables it
+func SetDefaultIssuerMetadata(md *IssuerMetadata) error {
+	if md == nil {
+		defaultIssuerMetadata = nil
+		return nil
+	}
+
+	err := md.validate()
+	if err != nil {
+		return err
+	}
+
+	dmd := *md
+	defaultIssuerMetadata = &dmd
+
+	return nil
+}
 
 // Purpose indicates what kind of token a JWT is and helps us parse it into the right data structure
 type Purpose string
@@ -68,85 +83,289 @@ const (
 
 	// ServerPurpose indicates a JWT is a ServerClaims JWT
 	ServerPurpose Purpose = "choria_server"
+
+	// ServiceAccountPurpose indicates a JWT is a ServiceAccountClaims JWT
+	ServiceAccountPurpose Purpose = "choria_service_account"
+
+	// StreamPurpose indicates a JWT is a StreamClaims JWT
+	StreamPurpose Purpose = "choria_stream"
+
+	// RegistrationPurpose indicates a JWT is a RegistrationClaims JWT
+	RegistrationPurpose Purpose = "choria_registration"
+
+	// SchedulerPurpose indicates a JWT is a SchedulerClaims JWT
+	SchedulerPurpose Purpose = "choria_scheduler"
+
+	// ConfigPurpose indicates a JWT is a ConfigClaims JWT
+	ConfigPurpose Purpose = "choria_config"
+
+	// ObserverPurpose indicates a JWT is a ObserverClaims JWT
+	ObserverPurpose Purpose = "choria_observer"
+
+	// TrustConfigPurpose indicates a JWT is a TrustConfigClaims JWT
+	TrustConfigPurpose Purpose = "choria_trust_config"
+
+	// RevocationListPurpose indicates a JWT is a RevocationListClaims JWT
+	RevocationListPurpose Purpose = "choria_revocation_list"
+
+	// KnownIssuersPurpose indicates a JWT is a KnownIssuersClaims JWT
+	KnownIssuersPurpose Purpose = "choria_known_issuers"
+
+	// FederationTrustPurpose indicates a JWT is a FederationTrustClaims JWT
+	FederationTrustPurpose Purpose = "choria_federation_trust"
+
+	// TrustBundlePurpose indicates a JWT is a TrustBundleClaims JWT
+	TrustBundlePurpose Purpose = "choria_trust_bundle"
 )
 
 // MapClaims are free form map claims
 type MapClaims jwt.MapClaims
 
+// Validator is implemented by claims that can check their own structure and content
+type Validator interface {
+	Validate() error
+}
+
 // ParseToken parses token into claims and verify the token is valid using the pk,
 // if the token is signed by a chain issuer then pk must be the org issuer pk and
-// the chain will be verified
-func ParseToken(token string, claims jwt.Claims, pk any) error {
+// the chain will be verified, claims implementing Validator are validated after verification.
+// pk can be a KeyResolver that selects the key based on the token
+func ParseToken(token string, claims jwt.Claims, pk any, opts ...ParseOption) error {
+	recorder := currentMetricsRecorder()
+	if recorder == nil {
+		return parseToken(token, claims, pk, opts...)
+	}
+
+	start := time.Now()
+	err := parseToken(token, claims, pk, opts...)
+	recorder.ObserveParse(claimsPurpose(claims, token), time.Since(start), VerificationFailureReason(err))
+
+	return err
+}
+
+func parseToken(token string, claims jwt.Claims, pk any, opts ...ParseOption) error {
 	if pk == nil {
 		return fmt.Errorf("invalid public key")
 	}
 
-	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
-		switch t.Method.Alg() {
-		case algRS256, algRS512, algRS384:
-			pk, ok := pk.(*rsa.PublicKey)
-			if !ok {
-				return nil, fmt.Errorf("rsa public key required")
-			}
-			return pk, nil
+	popts, err := newParseOptions(opts...)
+	if err != nil {
+		return err
+	}
 
-		case algEdDSA:
-			pk, ok := pk.(ed25519.PublicKey)
-			if !ok {
-				return nil, fmt.Errorf("ed25519 public key required")
-			}
+	if !tracingEnabled() {
+		return parseTokenWithOptions(token, claims, pk, popts)
+	}
 
-			var sc *StandardClaims
+	var span TraceSpan
+	popts.ctx, span = startSpan(popts.context(), "tokens.parse")
+	err = parseTokenWithOptions(token, claims, pk, popts)
+	if purpose := claimsPurpose(claims, token); purpose != UnknownPurpose {
+		span.SetAttribute(TraceAttributePurpose, string(purpose))
+	}
+	if sc, ok := claims.(standardClaimsProvider); ok && sc.getStandardClaims().Issuer != "" {
+		span.SetAttribute(TraceAttributeIssuer, sc.getStandardClaims().Issuer)
+	}
+	span.End(err)
 
-			// if it's a client and from a chain we will verify it using the chain issuer pubk
-			client, ok := claims.(*ClientIDClaims)
-			if ok && strings.HasPrefix(client.Issuer, ChainIssuerPrefix) {
-				sc = &client.StandardClaims
-			}
+	return err
+}
 
-			// if it's a server and from a chain we will verify it using the chain issuer pubk
-			server, ok := claims.(*ServerClaims)
-			if ok && strings.HasPrefix(server.Issuer, ChainIssuerPrefix) {
-				sc = &server.StandardClaims
-			}
+func parseTokenWithOptions(token string, claims jwt.Claims, pk any, popts *parseOptions) error {
+	var err error
 
-			if sc != nil {
-				valid, signerPk, err := sc.IsSignedByIssuer(pk)
-				if err != nil {
-					return nil, fmt.Errorf("%w: %s", ErrorNotSignedByIssuer, err)
-				}
-				if !valid {
-					return nil, ErrorNotSignedByIssuer
-				}
-				pk = signerPk
-			}
+	if popts.strict != nil {
+		err = popts.strict.check(token)
+		if err != nil {
+			return err
+		}
+	}
+
+	pk, err = resolveKey(token, pk)
+	if err != nil {
+		return err
+	}
 
-			return pk, nil
+	var isRSA bool
 
-		default:
-			return nil, fmt.Errorf("unsupported signing method %v in token", t.Method)
+	if edpk, ok := pk.(ed25519.PublicKey); ok && popts.compact {
+		err = parseTokenCompact(popts.context(), token, claims, edpk, popts.now())
+		if err == nil {
+			return popts.validateParsed(token, claims)
+		}
+		if !errors.Is(err, errCompactUnsupported) {
+			return err
 		}
-	}, jwt.WithValidMethods(validMethods))
+	}
+
+	codec, compressed, err := tokenEncoding(token)
+	if err != nil {
+		return &jwt.ValidationError{Inner: err, Errors: jwt.ValidationErrorMalformed}
+	}
+
+	if codec != nil || compressed {
+		isRSA, err = parseTokenWithCodec(token, claims, pk, popts)
+	} else {
+		_, err = jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+			key, rsa, err := popts.verificationKey(t.Method.Alg(), claims, pk)
+			isRSA = rsa
+
+			return key, err
+		}, jwt.WithValidMethods(validMethods), jwt.WithoutClaimsValidation())
+	}
+	if err != nil {
+		return err
+	}
+
+	err = popts.validateParsed(token, claims)
 	if err != nil {
 		return err
 	}
 
+	if isRSA && popts.rsaSunset != nil {
+		popts.rsaSunset.accepted(claims, popts.now())
+	}
+
 	return nil
 }
 
+// verificationKey determines the key to verify a token signed using alg, claims must already be decoded
+func (o *parseOptions) verificationKey(alg string, claims jwt.Claims, pk any) (key any, isRSA bool, err error) {
+	switch alg {
+	case algRS256, algRS512, algRS384:
+		pk, ok := pk.(*rsa.PublicKey)
+		if !ok {
+			return nil, true, fmt.Errorf("rsa public key required")
+		}
+
+		err := validateRSAPublicKey(pk)
+		if err != nil {
+			return nil, true, err
+		}
+
+		if o.rsaSunset != nil {
+			err = o.rsaSunset.observe(claims, o.now())
+			if err != nil {
+				return nil, true, err
+			}
+		}
+
+		return pk, true, nil
+
+	case algEdDSA:
+		pk, ok := pk.(ed25519.PublicKey)
+		if !ok {
+			return nil, false, fmt.Errorf("ed25519 public key required")
+		}
+
+		if o.signerKnown {
+			return pk, false, nil
+		}
+
+		key, err := chainSigningKey(o.context(), claims, pk, o.now())
+
+		return key, false, err
+
+	default:
+		return nil, false, fmt.Errorf("unsupported signing method %v in token", alg)
+	}
+}
+
+// chainSigningKey determines the key that signed claims, for client and server tokens issued by a chain
+// issuer this is the chain issuer key after verifying the chain against the org issuer key pk
+func chainSigningKey(ctx context.Context, claims jwt.Claims, pk ed25519.PublicKey, now time.Time) (ed25519.PublicKey, error) {
+	var sc *StandardClaims
+
+	// if it's a client and from a chain we will verify it using the chain issuer pubk
+	client, ok := claims.(*ClientIDClaims)
+	if ok && strings.HasPrefix(client.Issuer, ChainIssuerPrefix) {
+		sc = &client.StandardClaims
+	}
+
+	// if it's a server and from a chain we will verify it using the chain issuer pubk
+	server, ok := claims.(*ServerClaims)
+	if ok && strings.HasPrefix(server.Issuer, ChainIssuerPrefix) {
+		sc = &server.StandardClaims
+	}
+
+	if sc == nil {
+		return pk, nil
+	}
+
+	_, span := startSpan(ctx, "tokens.verify_chain")
+	setClaimsAttributes(span, claims)
+
+	signerPk, err := verifyChainSigner(sc, claims, pk, now)
+	span.End(err)
+
+	return signerPk, err
+}
+
+// verifyChainSigner verifies the issuer chain in sc against the org issuer key pk at now and returns the chain issuer key,
+// the issuer chain is verified once and the verified chain issuers are used to enforce their constraints
+func verifyChainSigner(sc *StandardClaims, claims jwt.Claims, pk ed25519.PublicKey, now time.Time) (ed25519.PublicKey, error) {
+	valid, signerPk, issuers, err := sc.verifyIssuer(pk, now)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrorNotSignedByIssuer, err)
+	}
+	if !valid {
+		return nil, ErrorNotSignedByIssuer
+	}
+
+	err = verifyIssuerConstraints(issuers, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return signerPk, nil
+}
+
+type uniqueIDClaims interface {
+	UniqueID() (id string, uid string)
+}
+
+// claimsIdentity determines a human friendly identity for claims, used in logging and reporting
+func claimsIdentity(claims jwt.Claims) string {
+	if c, ok := claims.(uniqueIDClaims); ok {
+		id, _ := c.UniqueID()
+		if id != "" {
+			return id
+		}
+	}
+
+	if c, ok := claims.(standardClaimsProvider); ok {
+		sc := c.getStandardClaims()
+		if sc.Subject != "" {
+			return sc.Subject
+		}
+
+		return sc.ID
+	}
+
+	return ""
+}
+
 // ParseTokenUnverified parses token into claims and DOES not verify the token validity in any way
 func ParseTokenUnverified(token string) (jwt.MapClaims, error) {
-	parser := new(jwt.Parser)
 	claims := new(jwt.MapClaims)
-	_, _, err := parser.ParseUnverified(token, claims)
+	_, err := parseUnverified(token, claims)
 	return *claims, err
 }
 
 // TokenPurpose parses, without validating, token and checks for a Purpose field in it
 func TokenPurpose(token string) Purpose {
-	parser := new(jwt.Parser)
+	purpose, ok := scanPurpose(token)
+	if ok {
+		return purpose
+	}
+
+	return tokenPurpose(token)
+}
+
+// tokenPurpose determines the purpose by decoding all the claims
+func tokenPurpose(token string) Purpose {
 	claims := StandardClaims{}
-	parser.ParseUnverified(token, &claims)
+	parseUnverified(token, &claims)
 
 	if claims.Purpose == UnknownPurpose {
 		if claims.RegisteredClaims.Subject == string(ProvisioningPurpose) {
@@ -159,265 +378,205 @@ func TokenPurpose(token string) Purpose {
 
 // TokenPurposeBytes called TokenPurpose with a bytes input
 func TokenPurposeBytes(token []byte) Purpose {
-	return TokenPurpose(string(token))
+	purpose, ok := scanPurpose(token)
+	if ok {
+		return purpose
+	}
+
+	return tokenPurpose(string(token))
 }
 
 // TokenSigningAlgorithmBytes determines the signing algorithm used for a token
 func TokenSigningAlgorithmBytes(token []byte) (string, error) {
-	return TokenSigningAlgorithm(string(token))
+	return tokenAlgorithm(token)
 }
 
-// TokenSigningAlgorithm determines the signing algorithm used for a token
+// TokenSigningAlgorithm determines the signing algorithm used for a token, only the header is decoded for common algorithms
 func TokenSigningAlgorithm(token string) (string, error) {
-	parser := new(jwt.Parser)
-	claims := StandardClaims{}
-	t, _, err := parser.ParseUnverified(token, &claims)
+	return tokenAlgorithm(token)
+}
+
+// SignTokenWithKeyFile signs a JWT using a RSA private key in PEM format, an ed25519 key in PKCS#8 PEM or OpenSSH
+// format or a hex encoded ed25519 seed, the format is detected automatically
+func SignTokenWithKeyFile(claims jwt.Claims, pkFile string) (string, error) {
+	key, err := loadSigningKeyFile(pkFile)
 	if err != nil {
 		return "", err
 	}
 
-	return t.Method.Alg(), nil
+	return SignToken(claims, key)
 }
 
-// SignTokenWithKeyFile signs a JWT using an RSA Private Key in PEM format
-func SignTokenWithKeyFile(claims jwt.Claims, pkFile string) (string, error) {
+// loadSigningKeyFile reads a key supported by parseSigningKey from pkFile
+func loadSigningKeyFile(pkFile string) (any, error) {
 	keydat, err := os.ReadFile(pkFile)
 	if err != nil {
-		return "", fmt.Errorf("could not read signing key: %s", err)
+		return nil, fmt.Errorf("could not read signing key: %s", err)
+	}
+
+	key, err := parseSigningKey(keydat)
+	if errors.Is(err, errUnsupportedSigningKey) {
+		return nil, fmt.Errorf("unsupported key in %v", pkFile)
+	}
+
+	return key, err
+}
+
+var errUnsupportedSigningKey = errors.New("unsupported key")
+
+// parseSigningKey parses a RSA private key in PEM format, an ed25519 or RSA key in PKCS#8 PEM or OpenSSH format
+// or a hex encoded ed25519 seed
+func parseSigningKey(keydat []byte) (any, error) {
+	keydat = bytes.TrimSpace(keydat)
+
+	switch {
+	case bytes.HasPrefix(keydat, []byte(keyHeader)):
+		return parsePKCS8PrivateKey(keydat)
+	case bytes.HasPrefix(keydat, []byte(opensshKeyHeader)):
+		return parseOpenSSHPrivateKey(keydat)
 	}
 
-	if bytes.HasPrefix(keydat, []byte(rsaKeyHeader)) || bytes.HasPrefix(keydat, []byte(keyHeader)) {
+	if bytes.HasPrefix(keydat, []byte(rsaKeyHeader)) {
 		key, err := jwt.ParseRSAPrivateKeyFromPEM(keydat)
 		if err != nil {
-			return "", fmt.Errorf("could not parse signing key: %s", err)
+			return nil, fmt.Errorf("could not parse signing key: %s", err)
 		}
 
-		return SignToken(claims, key)
+		return key, nil
 	}
 
 	if len(keydat) == ed25519.PrivateKeySize {
 		seed, err := hex.DecodeString(string(keydat))
 		if err != nil {
-			return "", fmt.Errorf("invalid ed25519 seed file: %v", err)
+			return nil, fmt.Errorf("invalid ed25519 seed file: %v", err)
 		}
 
-		return SignToken(claims, ed25519.NewKeyFromSeed(seed))
+		return ed25519.NewKeyFromSeed(seed), nil
 	}
 
-	return "", fmt.Errorf("unsupported key in %v", pkFile)
+	return nil, errUnsupportedSigningKey
 }
 
-// SignToken signs a JWT using an RSA Private Key
-func SignToken(claims jwt.Claims, pk any) (string, error) {
-	var stoken string
-	var err error
-
-	switch pri := pk.(type) {
-	case ed25519.PrivateKey:
-		token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
-		stoken, err = token.SignedString(pri)
-
-	case *rsa.PrivateKey:
-		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
-		stoken, err = token.SignedString(pri)
-
-	default:
-		return "", fmt.Errorf("unsupported private key")
-	}
-
+// SignToken signs a JWT using an RSA or ed25519 Private Key or a crypto.Signer holding an ed25519 key
+func SignToken(claims jwt.Claims, pk any, opts ...SignOption) (string, error) {
+	sopts, err := newSignOptions(opts...)
 	if err != nil {
-		return "", fmt.Errorf("could not sign token using key: %s", err)
+		return "", err
 	}
 
-	return stoken, nil
-}
-
-// SaveAndSignTokenWithKeyFile signs a token using SignTokenWithKeyFile and saves it to outFile
-func SaveAndSignTokenWithKeyFile(claims jwt.Claims, pkFile string, outFile string, perm os.FileMode) error {
-	token, err := SignTokenWithKeyFile(claims, pkFile)
+	method, err := signingMethodFor(pk)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	return os.WriteFile(outFile, []byte(token), perm)
+	return signTokenWithMethod(claims, pk, method, sopts)
 }
 
-func getVaultIssuerPubKey(ctx context.Context, tlsc *tls.Config, key string, log *logrus.Entry) (ed25519.PublicKey, error) {
-	vt := os.Getenv("VAULT_TOKEN")
-	va := os.Getenv("VAULT_ADDR")
-
-	if vt == "" || va == "" {
-		return nil, fmt.Errorf("requires VAULT_TOKEN and VAULT_ADDR environment variables")
-	}
-
-	uri, err := url.Parse(va)
-	if err != nil {
-		return nil, err
-	}
-
-	uri.Path = fmt.Sprintf("/v1/transit/keys/%s", key)
-	client := &http.Client{}
-	if tlsc != nil {
-		client.Transport = &http.Transport{TLSClientConfig: tlsc}
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "GET", uri.String(), nil)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Add("X-Vault-Token", vt)
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+// signingMethodFor validates pk and determines the signing method to use with it
+func signingMethodFor(pk any) (jwt.SigningMethod, error) {
+	switch pri := pk.(type) {
+	case ed25519.PrivateKey:
+		return jwt.SigningMethodEdDSA, nil
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
+	case *rsa.PrivateKey:
+		err := validateRSAPublicKey(&pri.PublicKey)
+		if err != nil {
+			return nil, err
+		}
 
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("request failed: code: %d: %s", resp.StatusCode, string(body))
-	}
+		return jwt.SigningMethodRS256, nil
 
-	log.Debugf("JSON Response: %s", string(body))
+	case crypto.Signer:
+		// ed25519 keys held in a KMS or HSM
+		_, ok := pri.Public().(ed25519.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("unsupported private key")
+		}
 
-	var vr struct {
-		Data struct {
-			Keys map[string]struct {
-				PublicKey []byte `json:"public_key"`
-			} `json:"keys"`
-		} `json:"data"`
-	}
-	err = json.Unmarshal(body, &vr)
-	if err != nil {
-		return nil, err
-	}
+		return jwt.SigningMethodEdDSA, nil
 
-	if len(vr.Data.Keys) == 0 {
-		return nil, fmt.Errorf("did not receive keys in response")
+	default:
+		return nil, fmt.Errorf("unsupported private key")
 	}
+}
 
-	pk, ok := vr.Data.Keys["1"]
-	if !ok {
-		return nil, fmt.Errorf("did not receive keys in response")
+func signTokenWithMethod(claims jwt.Claims, pk any, method jwt.SigningMethod, sopts *signOptions) (string, error) {
+	recorder := currentMetricsRecorder()
+	if recorder == nil && !tracingEnabled() {
+		return signClaims(claims, pk, method, sopts)
 	}
 
-	if len(pk.PublicKey) != ed25519.PublicKeySize {
-		return nil, fmt.Errorf("did not receive a valid public key in response")
+	_, span := startSpan(sopts.context(), "tokens.sign")
+	start := time.Now()
+	token, err := signClaims(claims, pk, method, sopts)
+	if recorder != nil {
+		recorder.ObserveSign(claimsPurpose(claims, ""), time.Since(start), err)
 	}
+	setClaimsAttributes(span, claims)
+	span.End(err)
 
-	return pk.PublicKey, nil
+	return token, err
 }
 
-func signWithVault(ctx context.Context, tlsc *tls.Config, key string, ss []byte, log *logrus.Entry) ([]byte, error) {
-	vt := os.Getenv("VAULT_TOKEN")
-	va := os.Getenv("VAULT_ADDR")
-
-	if vt == "" || va == "" {
-		return nil, fmt.Errorf("requires VAULT_TOKEN and VAULT_ADDR environment variables")
-	}
-
-	uri, err := url.Parse(va)
-	if err != nil {
-		return nil, err
-	}
-
-	uri.Path = fmt.Sprintf("/v1/transit/sign/%s", key)
+func signClaims(claims jwt.Claims, pk any, method jwt.SigningMethod, sopts *signOptions) (string, error) {
+	var err error
 
-	dat := map[string]any{
-		"signature_algorithm": "ed25519",
-		"input":               base64.StdEncoding.EncodeToString(ss),
-	}
-	jdat, err := json.Marshal(dat)
-	if err != nil {
-		return nil, err
-	}
-	log.Debugf("JSON Request: %s", string(jdat))
+	if len(sopts.issuerChain) > 0 {
+		sc, ok := claims.(standardClaimsProvider)
+		if !ok {
+			return "", fmt.Errorf("%w: claims do not support issuer chains", ErrInvalidIssuerChain)
+		}
 
-	client := &http.Client{}
-	if tlsc != nil {
-		client.Transport = &http.Transport{TLSClientConfig: tlsc}
+		err = embedIssuerChain(sc.getStandardClaims(), sopts.issuerChain)
+		if err != nil {
+			return "", err
+		}
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", uri.String(), bytes.NewBuffer(jdat))
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Add("X-Vault-Token", vt)
+	var stoken string
 
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
+	if sopts.codec != nil || sopts.compress {
+		stoken, err = signWithCodec(claims, sopts.codec, sopts.compress, method, pk)
+	} else {
+		stoken, err = jwt.NewWithClaims(method, claims).SignedString(pk)
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
-	}
-
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("request failed: code: %d: %s", resp.StatusCode, string(body))
+		return "", fmt.Errorf("could not sign token using key: %s", err)
 	}
 
-	log.Debugf("JSON Response: %s", string(body))
-
-	var vr struct {
-		Data struct {
-			Sig string `json:"signature"`
-		} `json:"data"`
-	}
-	err = json.Unmarshal(body, &vr)
+	err = recordIssuance(sopts.context(), claims, method.Alg(), signerIdentity(pk))
 	if err != nil {
-		return nil, err
-	}
-
-	if vr.Data.Sig == "" {
-		return nil, fmt.Errorf("no signature in response: %s", string(body))
+		return "", err
 	}
 
-	const vaultSigPrefix = "vault:v1:"
-
-	if !strings.HasPrefix(vr.Data.Sig, vaultSigPrefix) {
-		return nil, fmt.Errorf("invalid signature, no vault:v1 prefix")
-	}
+	return stoken, nil
+}
 
-	signature, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(vr.Data.Sig, vaultSigPrefix))
+// newTokenID creates a unique, time ordered, token id to be used as the jti claim.
+//
+// The id is a KSUID based on t, chain issuer verification relies on the id being derived from the issue time
+func newTokenID(t time.Time) (string, error) {
+	id, err := ksuid.NewRandomWithTime(t)
 	if err != nil {
-		return nil, fmt.Errorf("could not decode vault response: %w", err)
+		return "", err
 	}
 
-	return signature, nil
+	return id.String(), nil
 }
 
-// SaveAndSignTokenWithVault signs a token using the named key in a Vault Transit engine.  Requires VAULT_TOKEN and VAULT_ADDR to be set.
-func SaveAndSignTokenWithVault(ctx context.Context, claims jwt.Claims, key string, outFile string, perm os.FileMode, tlsc *tls.Config, log *logrus.Entry) error {
-	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
-	ss, err := token.SigningString()
-	if err != nil {
-		return err
-	}
-
-	signature, err := signWithVault(ctx, tlsc, key, []byte(ss), log)
+func newStandardClaims(issuer string, purpose Purpose, validity time.Duration, setSubject bool, opts ...ClaimsOption) (*StandardClaims, error) {
+	copts, err := newClaimsOptions(opts...)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	signed := fmt.Sprintf("%s.%s", ss, strings.TrimRight(base64.RawURLEncoding.EncodeToString(signature), "="))
-
-	return os.WriteFile(outFile, []byte(signed), perm)
-}
-
-func newStandardClaims(issuer string, purpose Purpose, validity time.Duration, setSubject bool) (*StandardClaims, error) {
 	if issuer == "" {
 		issuer = defaultIssuer
 	}
 
-	now := jwt.NewNumericDate(time.Now().UTC())
-	id, err := ksuid.NewRandomWithTime(now.Time)
+	now := jwt.NewNumericDate(copts.now().UTC())
+	id, err := newTokenID(now.Time)
 	if err != nil {
 		return nil, err
 	}
@@ -427,9 +586,10 @@ func newStandardClaims(issuer string, purpose Purpose, validity time.Duration, s
 	}
 
 	claims := &StandardClaims{
-		Purpose: purpose,
+		Purpose:       purpose,
+		SchemaVersion: CurrentSchemaVersion(purpose),
 		RegisteredClaims: jwt.RegisteredClaims{
-			ID:        id.String(),
+			ID:        id,
 			Issuer:    issuer,
 			IssuedAt:  now,
 			NotBefore: now,
@@ -441,6 +601,11 @@ func newStandardClaims(issuer string, purpose Purpose, validity time.Duration, s
 		claims.Subject = string(purpose)
 	}
 
+	err = copts.apply(claims)
+	if err != nil {
+		return nil, err
+	}
+
 	return claims, nil
 }
 
@@ -468,67 +633,6 @@ func readRSAOrED25519PublicData(dat []byte) (any, error) {
 	return pk, nil
 }
 
-// NatsConnectionHelpers constructs token based private inbox and helpers for the nats.UserJWT() function. Only Server and Client tokens are supported.
-func NatsConnectionHelpers(token string, collective string, seedFile string, log *logrus.Entry) (inbox string, jwth func() (string, error), sigh func([]byte) ([]byte, error), err error) {
-	if collective == "" {
-		return "", nil, nil, fmt.Errorf("collective is required")
-	}
-
-	if seedFile == "" {
-		return "", nil, nil, fmt.Errorf("seedfile is required")
-	}
-
-	purpose := TokenPurpose(token)
-
-	var uid string
-	var isExp func() bool
-	var exp time.Time
-
-	switch purpose {
-	case ClientIDPurpose:
-		client, err := ParseClientIDTokenUnverified(token)
-		if err != nil {
-			return "", nil, nil, err
-		}
-		_, uid = client.UniqueID()
-		isExp = client.IsExpired
-		exp = client.ExpireTime()
-
-	case ServerPurpose:
-		server, err := ParseServerTokenUnverified(token)
-		if err != nil {
-			return "", nil, nil, err
-		}
-		_, uid = server.UniqueID()
-		isExp = server.IsExpired
-		exp = server.ExpireTime()
-
-	default:
-		return "", nil, nil, fmt.Errorf("unsupported token purpose: %v", purpose)
-	}
-
-	inbox = fmt.Sprintf("%s.reply.%s", collective, uid)
-
-	jwth = func() (string, error) {
-		if isExp() {
-			log.Errorf("Cannot sign connection NONCE: token is expired by %v", time.Since(exp))
-			return "", fmt.Errorf("token expired")
-		}
-		return token, nil
-	}
-
-	sigh = func(n []byte) ([]byte, error) {
-		if isExp() {
-			log.Errorf("Cannot sign connection NONCE: token is expired by %v", time.Since(exp))
-			return nil, fmt.Errorf("token expired")
-		}
-		log.Debugf("Signing nonce using seed file %s", seedFile)
-		return ed25519SignWithSeedFile(seedFile, n)
-	}
-
-	return inbox, jwth, sigh, nil
-}
-
 // IsEncodedEd25519Key determines if b holds valid characters for a hex encoded public key or seed
 func IsEncodedEd25519Key(b []byte) bool {
 	if len(b) != 64 {
@@ -8,6 +8,7 @@ import (
 	"crypto/ed25519"
 	"crypto/rsa"
 	"encoding/hex"
+	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -48,6 +49,20 @@ func loadRSAPriKey(k string) *rsa.PrivateKey {
 	return pK
 }
 
+type ginkgoValidatedClaims struct {
+	Name string `json:"name"`
+
+	StandardClaims
+}
+
+func (c *ginkgoValidatedClaims) Validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+
+	return nil
+}
+
 var _ = Describe("Tokens", func() {
 	var (
 		provJWTRSA     []byte
@@ -159,6 +174,109 @@ var _ = Describe("Tokens", func() {
 			})
 		})
 
+		Describe("Time validation", func() {
+			var pubK ed25519.PublicKey
+			var priK ed25519.PrivateKey
+
+			BeforeEach(func() {
+				pubK, priK = loadEd25519Seed("testdata/ed25519/signer.seed")
+			})
+
+			It("Should validate not before with leeway", func() {
+				claims, err := newStandardClaims("ginkgo", ProvisioningPurpose, time.Hour, false, WithNotBefore(time.Now().Add(30*time.Second)))
+				Expect(err).ToNot(HaveOccurred())
+				token, err := SignToken(claims, priK)
+				Expect(err).ToNot(HaveOccurred())
+
+				err = ParseToken(token, &StandardClaims{}, pubK)
+				Expect(err).To(MatchError(jwt.ErrTokenNotValidYet))
+
+				err = ParseToken(token, &jwt.MapClaims{}, pubK)
+				Expect(err).To(MatchError(jwt.ErrTokenNotValidYet))
+
+				err = ParseToken(token, &StandardClaims{}, pubK, WithLeeway(time.Minute))
+				Expect(err).ToNot(HaveOccurred())
+
+				err = ParseToken(token, &jwt.MapClaims{}, pubK, WithLeeway(time.Minute))
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("Should validate expiry with leeway", func() {
+				claims, err := newStandardClaims("ginkgo", ProvisioningPurpose, time.Hour, false)
+				Expect(err).ToNot(HaveOccurred())
+				claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-10 * time.Second))
+				token, err := SignToken(claims, priK)
+				Expect(err).ToNot(HaveOccurred())
+
+				err = ParseToken(token, &StandardClaims{}, pubK)
+				Expect(err).To(MatchError(jwt.ErrTokenExpired))
+				Expect(err.Error()).To(MatchRegexp("token is expired by"))
+
+				err = ParseToken(token, &StandardClaims{}, pubK, WithLeeway(time.Minute))
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("Should enforce the maximum validity", func() {
+				claims, err := newStandardClaims("ginkgo", ProvisioningPurpose, 9*time.Hour, false)
+				Expect(err).ToNot(HaveOccurred())
+				token, err := SignToken(claims, priK)
+				Expect(err).ToNot(HaveOccurred())
+
+				err = ParseToken(token, &StandardClaims{}, pubK, WithMaxValidity(8*time.Hour))
+				Expect(err).To(MatchError(ErrTokenValidityTooLong))
+				Expect(err).To(MatchError("token validity exceeds the allowed maximum: 9h0m0s exceeds 8h0m0s"))
+
+				err = ParseToken(token, &jwt.MapClaims{}, pubK, WithMaxValidity(8*time.Hour))
+				Expect(err).To(MatchError(ErrTokenValidityTooLong))
+
+				err = ParseToken(token, &StandardClaims{}, pubK, WithMaxValidity(9*time.Hour))
+				Expect(err).ToNot(HaveOccurred())
+
+				claims.IssuedAt = nil
+				token, err = SignToken(claims, priK)
+				Expect(err).ToNot(HaveOccurred())
+				err = ParseToken(token, &StandardClaims{}, pubK, WithMaxValidity(9*time.Hour))
+				Expect(err).To(MatchError("token validity exceeds the allowed maximum: token does not have issue and expiry times"))
+
+				err = ParseToken(token, &StandardClaims{}, pubK, WithMaxValidity(0))
+				Expect(err).To(MatchError("maximum validity must be positive"))
+			})
+
+			It("Should reject invalid settings", func() {
+				_, err := newStandardClaims("ginkgo", ProvisioningPurpose, time.Hour, false, WithNotBefore(time.Now().Add(2*time.Hour)))
+				Expect(err).To(MatchError("not before time must be before the expiry time"))
+
+				err = ParseToken(string(provJWTED25519), &StandardClaims{}, pubK, WithLeeway(-1*time.Second))
+				Expect(err).To(MatchError("leeway cannot be negative"))
+			})
+		})
+
+		Describe("Validator", func() {
+			It("Should validate claims after verification", func() {
+				pubK, priK := loadEd25519Seed("testdata/ed25519/signer.seed")
+				otherPubK, _ := loadEd25519Seed("testdata/ed25519/other.seed")
+
+				stdClaims, err := newStandardClaims("ginkgo", ProvisioningPurpose, time.Hour, false)
+				Expect(err).ToNot(HaveOccurred())
+				token, err := SignToken(&ginkgoValidatedClaims{StandardClaims: *stdClaims}, priK)
+				Expect(err).ToNot(HaveOccurred())
+
+				err = ParseToken(token, &ginkgoValidatedClaims{}, otherPubK)
+				Expect(err).To(MatchError("ed25519: verification error"))
+
+				err = ParseToken(token, &ginkgoValidatedClaims{}, pubK)
+				Expect(err).To(MatchError("name is required"))
+
+				token, err = SignToken(&ginkgoValidatedClaims{Name: "ginkgo", StandardClaims: *stdClaims}, priK)
+				Expect(err).ToNot(HaveOccurred())
+
+				claims := &ginkgoValidatedClaims{}
+				err = ParseToken(token, claims, pubK)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(claims.Name).To(Equal("ginkgo"))
+			})
+		})
+
 		Describe("RSA", func() {
 			It("Should parse and verify the token", func() {
 				claims := &jwt.MapClaims{}
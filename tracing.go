@@ -0,0 +1,86 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+const (
+	// TraceAttributePurpose is the span attribute holding the purpose of the token
+	TraceAttributePurpose = "choria.token.purpose"
+
+	// TraceAttributeIssuer is the span attribute holding the issuer of the token
+	TraceAttributeIssuer = "choria.token.issuer"
+)
+
+// Tracer creates spans around signing, parsing, chain validation and remote calls, see SetTracer. Spans only
+// carry the purpose and issuer of tokens, never the tokens themselves
+type Tracer interface {
+	// Start begins a span called name as a child of any span in ctx
+	Start(ctx context.Context, name string) (context.Context, TraceSpan)
+}
+
+// TraceSpan is a span started by a Tracer
+type TraceSpan interface {
+	// SetAttribute records a string attribute on the span
+	SetAttribute(key string, value string)
+
+	// End completes the span, err is recorded when not nil
+	End(err error)
+}
+
+type tracerHolder struct {
+	tracer Tracer
+}
+
+var currentTracer atomic.Value
+
+// SetTracer sets the tracer used for all token operations, nil disables tracing
+func SetTracer(tracer Tracer) {
+	currentTracer.Store(tracerHolder{tracer: tracer})
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(string, string) {}
+func (noopSpan) End(error)                   {}
+
+// startSpan starts a span using the configured tracer, a span that does nothing is returned when tracing is disabled
+func startSpan(ctx context.Context, name string) (context.Context, TraceSpan) {
+	h, _ := currentTracer.Load().(tracerHolder)
+	if h.tracer == nil {
+		return ctx, noopSpan{}
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return h.tracer.Start(ctx, name)
+}
+
+// tracingEnabled determines if a tracer is configured, used to avoid computing span attributes
+func tracingEnabled() bool {
+	h, _ := currentTracer.Load().(tracerHolder)
+	return h.tracer != nil
+}
+
+// setClaimsAttributes records the purpose and issuer of claims on span
+func setClaimsAttributes(span TraceSpan, claims any) {
+	sc, ok := claims.(standardClaimsProvider)
+	if !ok {
+		return
+	}
+
+	std := sc.getStandardClaims()
+	if std.Purpose != UnknownPurpose {
+		span.SetAttribute(TraceAttributePurpose, string(std.Purpose))
+	}
+	if std.Issuer != "" {
+		span.SetAttribute(TraceAttributeIssuer, std.Issuer)
+	}
+}
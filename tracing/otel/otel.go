@@ -0,0 +1,63 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package otel creates OpenTelemetry spans for token signing, parsing, chain validation and remote key operations
+package otel
+
+import (
+	"context"
+
+	"github.com/choria-io/tokens"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName is the instrumentation name used when creating the OpenTelemetry tracer
+const TracerName = "github.com/choria-io/tokens"
+
+// Tracer is a tokens.Tracer backed by an OpenTelemetry tracer
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+type span struct {
+	span trace.Span
+}
+
+var _ tokens.Tracer = (*Tracer)(nil)
+var _ tokens.TraceSpan = (*span)(nil)
+
+// New creates a Tracer using tracers from tp
+func New(tp trace.TracerProvider) *Tracer {
+	return &Tracer{tracer: tp.Tracer(TracerName)}
+}
+
+// Register creates a Tracer using tp and installs it using tokens.SetTracer
+func Register(tp trace.TracerProvider) *Tracer {
+	t := New(tp)
+	tokens.SetTracer(t)
+
+	return t
+}
+
+// Start implements tokens.Tracer
+func (t *Tracer) Start(ctx context.Context, name string) (context.Context, tokens.TraceSpan) {
+	ctx, s := t.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindInternal))
+
+	return ctx, &span{span: s}
+}
+
+func (s *span) SetAttribute(key string, value string) {
+	s.span.SetAttributes(attribute.String(key, value))
+}
+
+func (s *span) End(err error) {
+	if err != nil {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+	}
+
+	s.span.End()
+}
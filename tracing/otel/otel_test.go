@@ -0,0 +1,72 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package otel
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/choria-io/tokens"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestOTel(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Tracing/OTel")
+}
+
+var _ = Describe("Tracer", func() {
+	It("Should create spans for signing and parsing", func() {
+		recorder := tracetest.NewSpanRecorder()
+		tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+		DeferCleanup(func() { tp.Shutdown(context.Background()) })
+
+		Register(tp)
+		DeferCleanup(func() { tokens.SetTracer(nil) })
+
+		pubK, priK, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		otherPubK, _, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		client, err := tokens.NewClientIDClaims("up=bob", nil, "", nil, "", "acme", time.Hour, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		ctx, parent := tp.Tracer("ginkgo").Start(context.Background(), "request")
+		token, err := tokens.SignTokenContext(ctx, client, priK)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tokens.ParseTokenContext(ctx, token, &tokens.ClientIDClaims{}, pubK)).To(Succeed())
+		Expect(tokens.ParseTokenContext(ctx, token, &tokens.ClientIDClaims{}, otherPubK)).ToNot(Succeed())
+		parent.End()
+
+		spans := recorder.Ended()
+		Expect(spans).To(HaveLen(4))
+
+		expected := []attribute.KeyValue{
+			attribute.String(tokens.TraceAttributePurpose, string(tokens.ClientIDPurpose)),
+			attribute.String(tokens.TraceAttributeIssuer, "acme"),
+		}
+
+		for i, name := range []string{"tokens.sign", "tokens.parse", "tokens.parse"} {
+			Expect(spans[i].Name()).To(Equal(name))
+			Expect(spans[i].Parent().SpanID()).To(Equal(parent.SpanContext().SpanID()))
+			Expect(spans[i].Attributes()).To(ConsistOf(expected))
+			Expect(spans[i].InstrumentationScope().Name).To(Equal(TracerName))
+		}
+
+		Expect(spans[1].Status().Code).To(Equal(codes.Unset))
+		Expect(spans[2].Status().Code).To(Equal(codes.Error))
+		Expect(spans[2].Status().Description).To(Equal("ed25519: verification error"))
+		Expect(spans[2].Events()).To(HaveLen(1))
+	})
+})
@@ -0,0 +1,126 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type ginkgoSpan struct {
+	name   string
+	parent string
+	attrs  map[string]string
+	err    error
+	ended  bool
+}
+
+func (s *ginkgoSpan) SetAttribute(key string, value string) { s.attrs[key] = value }
+func (s *ginkgoSpan) End(err error)                         { s.err = err; s.ended = true }
+
+type ginkgoSpanKey struct{}
+
+type ginkgoTracer struct {
+	spans []*ginkgoSpan
+	mu    sync.Mutex
+}
+
+func (t *ginkgoTracer) Start(ctx context.Context, name string) (context.Context, TraceSpan) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	span := &ginkgoSpan{name: name, attrs: map[string]string{}}
+	if parent, ok := ctx.Value(ginkgoSpanKey{}).(*ginkgoSpan); ok {
+		span.parent = parent.name
+	}
+	t.spans = append(t.spans, span)
+
+	return context.WithValue(ctx, ginkgoSpanKey{}, span), span
+}
+
+var _ = Describe("Tracing", func() {
+	var (
+		pubK   ed25519.PublicKey
+		priK   ed25519.PrivateKey
+		tracer *ginkgoTracer
+	)
+
+	BeforeEach(func() {
+		var err error
+		pubK, priK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		tracer = &ginkgoTracer{}
+		SetTracer(tracer)
+		DeferCleanup(func() { SetTracer(nil) })
+	})
+
+	It("Should trace signing and parsing", func() {
+		client, err := NewClientIDClaims("up=bob", nil, "", nil, "", "acme", time.Hour, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		token, err := SignTokenContext(context.WithValue(context.Background(), ginkgoSpanKey{}, &ginkgoSpan{name: "request"}), client, priK)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(ParseToken(token, &ClientIDClaims{}, pubK)).To(Succeed())
+
+		otherPubK, _, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ParseToken(token, &ClientIDClaims{}, otherPubK)).ToNot(Succeed())
+
+		Expect(tracer.spans).To(HaveLen(3))
+		for i, name := range []string{"tokens.sign", "tokens.parse", "tokens.parse"} {
+			span := tracer.spans[i]
+			Expect(span.name).To(Equal(name))
+			Expect(span.ended).To(BeTrue())
+			Expect(span.attrs).To(Equal(map[string]string{TraceAttributePurpose: string(ClientIDPurpose), TraceAttributeIssuer: "acme"}))
+			for _, v := range span.attrs {
+				Expect(strings.Contains(token, v)).To(BeFalse())
+			}
+		}
+		Expect(tracer.spans[0].parent).To(Equal("request"))
+		Expect(tracer.spans[1].err).ToNot(HaveOccurred())
+		Expect(tracer.spans[2].err).To(HaveOccurred())
+
+		SetTracer(nil)
+		Expect(ParseToken(token, &ClientIDClaims{}, pubK)).To(Succeed())
+		Expect(tracer.spans).To(HaveLen(3))
+	})
+
+	It("Should trace chain verification within parsing", func() {
+		orgPubK, orgPriK, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		signer, err := NewClientIDClaims("aaa=login", nil, "", nil, "", "", time.Hour, &ClientPermissions{AuthenticationDelegator: true}, pubK)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(signer.AddOrgIssuerData(orgPriK)).To(Succeed())
+
+		userPubK, _, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		client, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, userPubK)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(client.AddChainIssuerData(signer, priK)).To(Succeed())
+
+		token, err := SignToken(client, priK)
+		Expect(err).ToNot(HaveOccurred())
+
+		tracer.spans = nil
+		Expect(ParseTokenContext(context.Background(), token, &ClientIDClaims{}, orgPubK)).To(Succeed())
+
+		var names []string
+		for _, span := range tracer.spans {
+			names = append(names, span.name)
+		}
+		Expect(names).To(Equal([]string{"tokens.parse", "tokens.verify_chain"}))
+		Expect(tracer.spans[1].parent).To(Equal("tokens.parse"))
+		Expect(tracer.spans[1].attrs[TraceAttributeIssuer]).To(HavePrefix(ChainIssuerPrefix))
+	})
+})
@@ -0,0 +1,336 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrNotATrustConfigToken indicates a token is not a trust configuration document
+var ErrNotATrustConfigToken = errors.New("not a trust configuration token")
+
+// TrustConfig describes everything an environment trusts when verifying tokens, it is exported as a signed
+// document using ExportTrustConfig so that other environments can be configured to mirror it
+type TrustConfig struct {
+	// Environment is the name of the environment the configuration was exported from
+	Environment string `json:"environment"`
+
+	// Keys are the keys trusted to verify tokens, hex encoded ed25519 public keys or PEM encoded RSA public keys
+	Keys []string `json:"keys,omitempty"`
+
+	// Organizations are the organizations whose org issuers are trusted
+	Organizations []TrustedOrganization `json:"organizations,omitempty"`
+
+	// RevocationSources are locations revocation information is obtained from, typically URLs
+	RevocationSources []string `json:"revocation_sources,omitempty"`
+
+	// Profile is how tokens are verified
+	Profile VerificationProfile `json:"profile"`
+}
+
+// TrustedOrganization is an organization and the org issuer keys trusted for it
+type TrustedOrganization struct {
+	// Name is the name of the organization
+	Name string `json:"name"`
+
+	// IssuerKeys are hex encoded ed25519 public keys of the org issuers
+	IssuerKeys []string `json:"issuer_keys"`
+
+	// Endpoints are URLs where the org issuers can be reached, for example to obtain or renew tokens
+	Endpoints []string `json:"endpoints,omitempty"`
+}
+
+// VerificationProfile holds the settings used when verifying tokens
+type VerificationProfile struct {
+	// Audience is the audience tokens must be issued for
+	Audience string `json:"audience,omitempty"`
+
+	// Leeway allows for clock skew when validating token times
+	Leeway time.Duration `json:"leeway,omitempty"`
+
+	// RSAWarnAfter is the time after which RSA signed tokens are warned about
+	RSAWarnAfter *time.Time `json:"rsa_warn_after,omitempty"`
+
+	// RSARejectAfter is the time after which RSA signed tokens are rejected
+	RSARejectAfter *time.Time `json:"rsa_reject_after,omitempty"`
+}
+
+// TrustConfigClaims is a signed document holding a TrustConfig
+//
+// The "purpose" claim should be set to TrustConfigPurpose
+type TrustConfigClaims struct {
+	// Trust is the exported trust configuration
+	Trust TrustConfig `json:"trust"`
+
+	StandardClaims
+}
+
+// NewTrustConfig creates a trust configuration for environment holding the keys in keys, keys can be nil
+func NewTrustConfig(environment string, keys *KeyRing) (*TrustConfig, error) {
+	if environment == "" {
+		return nil, fmt.Errorf("environment is required")
+	}
+
+	cfg := &TrustConfig{Environment: environment}
+
+	if keys != nil {
+		for _, k := range keys.Keys() {
+			err := cfg.AddKey(k)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return cfg, nil
+}
+
+// AddKey adds a ed25519.PublicKey or *rsa.PublicKey to the trusted keys
+func (c *TrustConfig) AddKey(key any) error {
+	encoded, err := encodePublicKey(key)
+	if err != nil {
+		return err
+	}
+
+	c.Keys = append(c.Keys, encoded)
+
+	return nil
+}
+
+// encodePublicKey validates key and encodes it as hex for ed25519 keys or PEM for RSA keys
+func encodePublicKey(key any) (string, error) {
+	switch pk := key.(type) {
+	case ed25519.PublicKey:
+		err := ValidateEd25519PublicKey(pk)
+		if err != nil {
+			return "", err
+		}
+
+		return hex.EncodeToString(pk), nil
+
+	case *rsa.PublicKey:
+		err := validateRSAPublicKey(pk)
+		if err != nil {
+			return "", err
+		}
+
+		der, err := x509.MarshalPKIXPublicKey(pk)
+		if err != nil {
+			return "", err
+		}
+
+		return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), nil
+
+	default:
+		return "", fmt.Errorf("unsupported public key type %T", key)
+	}
+}
+
+// AddOrganization trusts the org issuer keys for the organization name
+func (c *TrustConfig) AddOrganization(name string, keys ...ed25519.PublicKey) error {
+	if name == "" {
+		return fmt.Errorf("organization name is required")
+	}
+
+	if len(keys) == 0 {
+		return fmt.Errorf("at least one issuer key is required")
+	}
+
+	for _, o := range c.Organizations {
+		if o.Name == name {
+			return fmt.Errorf("organization %s is already trusted", name)
+		}
+	}
+
+	org := TrustedOrganization{Name: name}
+	for _, k := range keys {
+		err := ValidateEd25519PublicKey(k)
+		if err != nil {
+			return err
+		}
+
+		org.IssuerKeys = append(org.IssuerKeys, hex.EncodeToString(k))
+	}
+
+	c.Organizations = append(c.Organizations, org)
+
+	return nil
+}
+
+// KeyRing creates a key ring holding the trusted keys
+func (c *TrustConfig) KeyRing() (*KeyRing, error) {
+	kr := &KeyRing{}
+
+	for _, k := range c.Keys {
+		pk, err := readRSAOrED25519PublicData([]byte(strings.TrimSpace(k)))
+		if err != nil {
+			return nil, err
+		}
+
+		err = kr.Add(pk)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return kr, nil
+}
+
+// OrgRegistry creates an organization registry trusting the org issuers of every organization
+func (c *TrustConfig) OrgRegistry() (*OrgRegistry, error) {
+	reg := NewOrgRegistry()
+
+	for _, o := range c.Organizations {
+		err := reg.AddOrganization(o)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return reg, nil
+}
+
+// OrganizationKeyRing creates a key ring holding the org issuer keys trusted for the organization name
+func (c *TrustConfig) OrganizationKeyRing(name string) (*KeyRing, error) {
+	for _, o := range c.Organizations {
+		if o.Name != name {
+			continue
+		}
+
+		reg := NewOrgRegistry()
+		err := reg.AddOrganization(o)
+		if err != nil {
+			return nil, err
+		}
+
+		return reg.KeyRing(name)
+	}
+
+	return nil, fmt.Errorf("organization %s is not trusted", name)
+}
+
+// ParseOptions creates the parse options matching the verification profile
+func (c *TrustConfig) ParseOptions() []ParseOption {
+	var opts []ParseOption
+
+	if c.Profile.Audience != "" {
+		opts = append(opts, WithExpectedAudience(c.Profile.Audience))
+	}
+
+	if c.Profile.Leeway > 0 {
+		opts = append(opts, WithLeeway(c.Profile.Leeway))
+	}
+
+	if c.Profile.RSAWarnAfter != nil || c.Profile.RSARejectAfter != nil {
+		opts = append(opts, WithRSASunsetPolicy(c.Profile.rsaSunsetPolicy()))
+	}
+
+	return opts
+}
+
+func (p *VerificationProfile) rsaSunsetPolicy() RSASunsetPolicy {
+	policy := RSASunsetPolicy{}
+	if p.RSAWarnAfter != nil {
+		policy.WarnAfter = *p.RSAWarnAfter
+	}
+	if p.RSARejectAfter != nil {
+		policy.RejectAfter = *p.RSARejectAfter
+	}
+
+	return policy
+}
+
+// Validate checks that the keys, organizations and verification profile are valid
+func (c *TrustConfig) Validate() error {
+	if c.Environment == "" {
+		return fmt.Errorf("environment is required")
+	}
+
+	if len(c.Keys) == 0 && len(c.Organizations) == 0 {
+		return fmt.Errorf("at least one key or organization is required")
+	}
+
+	_, err := c.KeyRing()
+	if err != nil {
+		return err
+	}
+
+	seen := map[string]bool{}
+	for _, o := range c.Organizations {
+		if o.Name == "" {
+			return fmt.Errorf("organization name is required")
+		}
+		if seen[o.Name] {
+			return fmt.Errorf("organization %s is listed more than once", o.Name)
+		}
+		seen[o.Name] = true
+
+		err = NewOrgRegistry().AddOrganization(o)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, s := range c.RevocationSources {
+		if strings.TrimSpace(s) == "" {
+			return fmt.Errorf("revocation sources cannot be empty")
+		}
+	}
+
+	if c.Profile.Leeway < 0 {
+		return fmt.Errorf("leeway cannot be negative")
+	}
+
+	policy := c.Profile.rsaSunsetPolicy()
+
+	return policy.validate()
+}
+
+// Validate checks that the document holds a valid trust configuration
+func (c *TrustConfigClaims) Validate() error {
+	if c.Purpose != TrustConfigPurpose {
+		return ErrNotATrustConfigToken
+	}
+
+	return c.Trust.Validate()
+}
+
+// ExportTrustConfig creates a signed trust configuration document that can be imported in another environment using ImportTrustConfig
+func ExportTrustConfig(cfg *TrustConfig, issuer string, validity time.Duration, signer any, opts ...ClaimsOption) (string, error) {
+	if cfg == nil {
+		return "", fmt.Errorf("trust configuration is required")
+	}
+
+	err := cfg.Validate()
+	if err != nil {
+		return "", err
+	}
+
+	stdClaims, err := newStandardClaims(issuer, TrustConfigPurpose, validity, false, opts...)
+	if err != nil {
+		return "", err
+	}
+
+	return SignToken(&TrustConfigClaims{Trust: *cfg, StandardClaims: *stdClaims}, signer)
+}
+
+// ImportTrustConfig verifies a document created using ExportTrustConfig with pk and returns the trust configuration it holds
+func ImportTrustConfig(doc string, pk any, opts ...ParseOption) (*TrustConfig, error) {
+	claims := &TrustConfigClaims{}
+	err := ParseToken(strings.TrimSpace(doc), claims, pk, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse trust configuration: %w", err)
+	}
+
+	return &claims.Trust, nil
+}
@@ -0,0 +1,349 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrNotATrustBundleToken indicates a token is not a trust bundle
+var ErrNotATrustBundleToken = errors.New("not a trust bundle token")
+
+// TrustBundle holds the keys an organization wants others to trust, it is distributed as a signed document
+// created using ExportTrustBundle
+type TrustBundle struct {
+	// Organization is the organization the bundle describes
+	Organization string `json:"organization"`
+
+	// IssuerKeys are the org issuer keys of the organization, they must be ed25519 keys
+	IssuerKeys []TrustBundleKey `json:"issuer_keys"`
+
+	// DelegatedSigners are keys trusted to sign tokens directly, for example provisioners or AAA services
+	DelegatedSigners []TrustBundleKey `json:"delegated_signers,omitempty"`
+}
+
+// TrustBundleKey is a key in a trust bundle and the window during which it is trusted
+type TrustBundleKey struct {
+	// Name is a descriptive name for the key
+	Name string `json:"name,omitempty"`
+
+	// Key is the hex encoded ed25519 public key or PEM encoded RSA public key
+	Key string `json:"key"`
+
+	// NotBefore is the time the key becomes trusted, unset means it is trusted immediately
+	NotBefore *time.Time `json:"not_before,omitempty"`
+
+	// NotAfter is the time after which the key is no longer trusted, unset means it is trusted indefinitely
+	NotAfter *time.Time `json:"not_after,omitempty"`
+}
+
+// TrustBundleClaims is a signed trust bundle
+//
+// The "purpose" claim should be set to TrustBundlePurpose
+type TrustBundleClaims struct {
+	// Bundle is the exported trust bundle
+	Bundle TrustBundle `json:"bundle"`
+
+	StandardClaims
+}
+
+// NewTrustBundle creates an empty trust bundle for organization
+func NewTrustBundle(organization string) (*TrustBundle, error) {
+	if organization == "" {
+		return nil, fmt.Errorf("organization is required")
+	}
+
+	return &TrustBundle{Organization: organization}, nil
+}
+
+// newTrustBundleKey encodes key with a validity window, zero times leave that side of the window open
+func newTrustBundleKey(name string, key any, notBefore time.Time, notAfter time.Time) (TrustBundleKey, error) {
+	encoded, err := encodePublicKey(key)
+	if err != nil {
+		return TrustBundleKey{}, err
+	}
+
+	k := TrustBundleKey{Name: name, Key: encoded}
+	if !notBefore.IsZero() {
+		t := notBefore.UTC()
+		k.NotBefore = &t
+	}
+	if !notAfter.IsZero() {
+		t := notAfter.UTC()
+		k.NotAfter = &t
+	}
+
+	return k, k.validate()
+}
+
+// AddIssuerKey adds an org issuer key trusted between notBefore and notAfter, zero times leave the window open
+func (b *TrustBundle) AddIssuerKey(name string, pk ed25519.PublicKey, notBefore time.Time, notAfter time.Time) error {
+	k, err := newTrustBundleKey(name, pk, notBefore, notAfter)
+	if err != nil {
+		return err
+	}
+
+	b.IssuerKeys = append(b.IssuerKeys, k)
+
+	return nil
+}
+
+// AddDelegatedSigner adds a ed25519.PublicKey or *rsa.PublicKey trusted to sign tokens between notBefore and notAfter
+func (b *TrustBundle) AddDelegatedSigner(name string, pk any, notBefore time.Time, notAfter time.Time) error {
+	k, err := newTrustBundleKey(name, pk, notBefore, notAfter)
+	if err != nil {
+		return err
+	}
+
+	b.DelegatedSigners = append(b.DelegatedSigners, k)
+
+	return nil
+}
+
+// PublicKey parses the key
+func (k *TrustBundleKey) PublicKey() (any, error) {
+	return readRSAOrED25519PublicData([]byte(strings.TrimSpace(k.Key)))
+}
+
+// ActiveAt determines if the key is trusted at t
+func (k *TrustBundleKey) ActiveAt(t time.Time) bool {
+	if k.NotBefore != nil && t.Before(*k.NotBefore) {
+		return false
+	}
+
+	if k.NotAfter != nil && t.After(*k.NotAfter) {
+		return false
+	}
+
+	return true
+}
+
+func (k *TrustBundleKey) validate() error {
+	pk, err := k.PublicKey()
+	if err != nil {
+		return err
+	}
+
+	err = ValidatePublicKey(pk)
+	if err != nil {
+		return err
+	}
+
+	if k.NotBefore != nil && k.NotAfter != nil && k.NotAfter.Before(*k.NotBefore) {
+		return fmt.Errorf("not after cannot be before not before")
+	}
+
+	return nil
+}
+
+// Validate checks that the bundle has an organization, at least one issuer key and that all keys are valid
+func (b *TrustBundle) Validate() error {
+	if b.Organization == "" {
+		return fmt.Errorf("organization is required")
+	}
+
+	if len(b.IssuerKeys) == 0 {
+		return fmt.Errorf("at least one issuer key is required")
+	}
+
+	for i, k := range b.IssuerKeys {
+		err := k.validate()
+		if err != nil {
+			return fmt.Errorf("issuer key %d: %w", i, err)
+		}
+
+		pk, _ := k.PublicKey()
+		if _, ok := pk.(ed25519.PublicKey); !ok {
+			return fmt.Errorf("issuer key %d: org issuers require ed25519 public keys", i)
+		}
+	}
+
+	for i, k := range b.DelegatedSigners {
+		err := k.validate()
+		if err != nil {
+			return fmt.Errorf("delegated signer %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// ActiveIssuerKeys are the org issuer keys trusted at t
+func (b *TrustBundle) ActiveIssuerKeys(t time.Time) ([]ed25519.PublicKey, error) {
+	var res []ed25519.PublicKey
+
+	for i, k := range b.IssuerKeys {
+		if !k.ActiveAt(t) {
+			continue
+		}
+
+		pk, err := k.PublicKey()
+		if err != nil {
+			return nil, fmt.Errorf("issuer key %d: %w", i, err)
+		}
+
+		edpk, ok := pk.(ed25519.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("issuer key %d: org issuers require ed25519 public keys", i)
+		}
+
+		res = append(res, edpk)
+	}
+
+	return res, nil
+}
+
+// ActiveSigners creates a key ring holding the delegated signers trusted at t
+func (b *TrustBundle) ActiveSigners(t time.Time) (*KeyRing, error) {
+	kr := &KeyRing{}
+
+	for i, k := range b.DelegatedSigners {
+		if !k.ActiveAt(t) {
+			continue
+		}
+
+		pk, err := k.PublicKey()
+		if err != nil {
+			return nil, fmt.Errorf("delegated signer %d: %w", i, err)
+		}
+
+		err = kr.Add(pk)
+		if err != nil {
+			return nil, fmt.Errorf("delegated signer %d: %w", i, err)
+		}
+	}
+
+	return kr, nil
+}
+
+// window is the trust window of the key as used by KeyRing.AddWithWindow
+func (k *TrustBundleKey) window() (notBefore time.Time, notAfter time.Time) {
+	if k.NotBefore != nil {
+		notBefore = *k.NotBefore
+	}
+	if k.NotAfter != nil {
+		notAfter = *k.NotAfter
+	}
+
+	return notBefore, notAfter
+}
+
+// Install adds the delegated signers to kr and the org issuer keys to orgs, either can be nil to skip those keys.
+// Keys keep their trust window so they only verify tokens while trusted, keys no longer trusted at t and keys that
+// are already trusted are not added
+func (b *TrustBundle) Install(kr *KeyRing, orgs *OrgRegistry, t time.Time) error {
+	err := b.Validate()
+	if err != nil {
+		return err
+	}
+
+	if kr != nil {
+		for i, k := range b.DelegatedSigners {
+			if k.NotAfter != nil && t.After(*k.NotAfter) {
+				continue
+			}
+
+			pk, err := k.PublicKey()
+			if err != nil {
+				return fmt.Errorf("delegated signer %d: %w", i, err)
+			}
+
+			if kr.Contains(pk) {
+				continue
+			}
+
+			notBefore, notAfter := k.window()
+			err = kr.AddWithWindow(pk, notBefore, notAfter)
+			if err != nil {
+				return fmt.Errorf("delegated signer %d: %w", i, err)
+			}
+		}
+	}
+
+	if orgs != nil {
+		for i, k := range b.IssuerKeys {
+			if k.NotAfter != nil && t.After(*k.NotAfter) {
+				continue
+			}
+
+			pk, err := k.PublicKey()
+			if err != nil {
+				return fmt.Errorf("issuer key %d: %w", i, err)
+			}
+
+			edpk, ok := pk.(ed25519.PublicKey)
+			if !ok {
+				return fmt.Errorf("issuer key %d: org issuers require ed25519 public keys", i)
+			}
+
+			if orgs.IsTrustedIssuer(b.Organization, edpk) {
+				continue
+			}
+
+			notBefore, notAfter := k.window()
+			err = orgs.AddIssuerWithWindow(b.Organization, edpk, notBefore, notAfter)
+			if err != nil {
+				return fmt.Errorf("issuer key %d: %w", i, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Validate checks that the document holds a valid trust bundle
+func (c *TrustBundleClaims) Validate() error {
+	if c.Purpose != TrustBundlePurpose {
+		return ErrNotATrustBundleToken
+	}
+
+	return c.Bundle.Validate()
+}
+
+// ExportTrustBundle creates a signed trust bundle that can be verified and loaded using ImportTrustBundle, signer is
+// typically an org issuer listed in the bundle
+func ExportTrustBundle(bundle *TrustBundle, issuer string, validity time.Duration, signer any, opts ...ClaimsOption) (string, error) {
+	if bundle == nil {
+		return "", fmt.Errorf("trust bundle is required")
+	}
+
+	err := bundle.Validate()
+	if err != nil {
+		return "", err
+	}
+
+	stdClaims, err := newStandardClaims(issuer, TrustBundlePurpose, validity, false, opts...)
+	if err != nil {
+		return "", err
+	}
+
+	return SignToken(&TrustBundleClaims{Bundle: *bundle, StandardClaims: *stdClaims}, signer)
+}
+
+// ImportTrustBundle verifies a document created using ExportTrustBundle with pk and returns the trust bundle it holds
+func ImportTrustBundle(doc string, pk any, opts ...ParseOption) (*TrustBundle, error) {
+	claims := &TrustBundleClaims{}
+	err := ParseToken(strings.TrimSpace(doc), claims, pk, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse trust bundle: %w", err)
+	}
+
+	return &claims.Bundle, nil
+}
+
+// ImportTrustBundleFile verifies the trust bundle in file using pk
+func ImportTrustBundleFile(file string, pk any, opts ...ParseOption) (*TrustBundle, error) {
+	doc, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read trust bundle: %w", err)
+	}
+
+	return ImportTrustBundle(string(doc), pk, opts...)
+}
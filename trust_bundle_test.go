@@ -0,0 +1,127 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TrustBundle", func() {
+	var (
+		orgPubK    ed25519.PublicKey
+		orgPriK    ed25519.PrivateKey
+		nextPubK   ed25519.PublicKey
+		signerPubK ed25519.PublicKey
+		bundle     *TrustBundle
+		now        time.Time
+	)
+
+	BeforeEach(func() {
+		var err error
+		orgPubK, orgPriK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		nextPubK, _, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		signerPubK, _, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		now = time.Now().UTC()
+
+		bundle, err = NewTrustBundle("acme")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(bundle.AddIssuerKey("current", orgPubK, time.Time{}, now.Add(24*time.Hour))).To(Succeed())
+		Expect(bundle.AddIssuerKey("next", nextPubK, now.Add(12*time.Hour), time.Time{})).To(Succeed())
+		Expect(bundle.AddDelegatedSigner("provisioner", signerPubK, time.Time{}, time.Time{})).To(Succeed())
+		Expect(bundle.AddDelegatedSigner("legacy", loadRSAPubKey("testdata/rsa/signer-public.pem"), time.Time{}, now.Add(-time.Hour))).To(Succeed())
+	})
+
+	Describe("Validate", func() {
+		It("Should detect invalid bundles", func() {
+			_, err := NewTrustBundle("")
+			Expect(err).To(MatchError("organization is required"))
+
+			Expect(bundle.Validate()).To(Succeed())
+			Expect((&TrustBundle{Organization: "acme"}).Validate()).To(MatchError("at least one issuer key is required"))
+
+			Expect(bundle.AddIssuerKey("bad", orgPubK, now, now.Add(-time.Hour))).To(MatchError("not after cannot be before not before"))
+
+			bundle.IssuerKeys = append(bundle.IssuerKeys, bundle.DelegatedSigners[1])
+			Expect(bundle.Validate()).To(MatchError("issuer key 2: org issuers require ed25519 public keys"))
+		})
+	})
+
+	Describe("Active keys", func() {
+		It("Should honor validity windows", func() {
+			keys, err := bundle.ActiveIssuerKeys(now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(keys).To(Equal([]ed25519.PublicKey{orgPubK}))
+
+			keys, err = bundle.ActiveIssuerKeys(now.Add(18 * time.Hour))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(keys).To(Equal([]ed25519.PublicKey{orgPubK, nextPubK}))
+
+			keys, err = bundle.ActiveIssuerKeys(now.Add(48 * time.Hour))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(keys).To(Equal([]ed25519.PublicKey{nextPubK}))
+
+			kr, err := bundle.ActiveSigners(now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(kr.Keys()).To(Equal([]any{signerPubK}))
+
+			kr, err = bundle.ActiveSigners(now.Add(-2 * time.Hour))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(kr.Len()).To(Equal(2))
+		})
+
+		It("Should install keys with their trust windows", func() {
+			kr := &KeyRing{}
+			orgs := NewOrgRegistry()
+
+			Expect(bundle.Install(kr, orgs, now)).To(Succeed())
+			Expect(bundle.Install(kr, orgs, now)).To(Succeed())
+			Expect(kr.Keys()).To(Equal([]any{signerPubK}))
+			Expect(orgs.IsTrustedIssuer("acme", orgPubK)).To(BeTrue())
+			Expect(orgs.IsTrustedIssuer("acme", nextPubK)).To(BeTrue())
+
+			issuers, err := orgs.KeyRing("acme")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(issuers.KeysAt(now)).To(Equal([]any{orgPubK}))
+			Expect(issuers.KeysAt(now.Add(18 * time.Hour))).To(Equal([]any{orgPubK, nextPubK}))
+			Expect(issuers.KeysAt(now.Add(48 * time.Hour))).To(Equal([]any{nextPubK}))
+		})
+	})
+
+	Describe("Export and Import", func() {
+		It("Should round trip signed bundles", func() {
+			doc, err := ExportTrustBundle(bundle, "I-acme", time.Hour, orgPriK)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(TokenPurpose(doc)).To(Equal(TrustBundlePurpose))
+
+			_, err = ImportTrustBundle(doc, nextPubK)
+			Expect(err).To(HaveOccurred())
+
+			file := filepath.Join(GinkgoT().TempDir(), "acme.jwt")
+			Expect(os.WriteFile(file, []byte(doc+"\n"), 0600)).To(Succeed())
+
+			imported, err := ImportTrustBundleFile(file, orgPubK)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(imported.Organization).To(Equal("acme"))
+			Expect(imported.IssuerKeys).To(HaveLen(2))
+			Expect(imported.IssuerKeys[0].NotAfter.Equal(*bundle.IssuerKeys[0].NotAfter)).To(BeTrue())
+
+			cfgDoc, err := ExportTrustConfig(&TrustConfig{Environment: "x", Keys: []string{bundle.IssuerKeys[0].Key}}, "I-acme", time.Hour, orgPriK)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = ImportTrustBundle(cfgDoc, orgPubK)
+			Expect(err).To(MatchError(ErrNotATrustBundleToken))
+		})
+	})
+})
@@ -0,0 +1,147 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TrustConfig", func() {
+	var (
+		signerPubK ed25519.PublicKey
+		signerPriK ed25519.PrivateKey
+		orgPubK    ed25519.PublicKey
+		keys       *KeyRing
+	)
+
+	BeforeEach(func() {
+		var err error
+		signerPubK, signerPriK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		orgPubK, _, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		keys, err = NewKeyRing(signerPubK, loadRSAPubKey("testdata/rsa/signer-public.pem"))
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Describe("NewTrustConfig", func() {
+		It("Should encode the key ring", func() {
+			_, err := NewTrustConfig("", keys)
+			Expect(err).To(MatchError("environment is required"))
+
+			cfg, err := NewTrustConfig("production", keys)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cfg.Keys).To(HaveLen(2))
+			Expect(cfg.Keys[1]).To(HavePrefix("-----BEGIN PUBLIC KEY"))
+
+			kr, err := cfg.KeyRing()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(kr.Keys()).To(Equal(keys.Keys()))
+		})
+	})
+
+	Describe("Organizations", func() {
+		It("Should manage trusted organizations", func() {
+			cfg, err := NewTrustConfig("production", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(cfg.AddOrganization("acme")).To(MatchError("at least one issuer key is required"))
+			Expect(cfg.AddOrganization("acme", orgPubK)).To(Succeed())
+			Expect(cfg.AddOrganization("acme", orgPubK)).To(MatchError("organization acme is already trusted"))
+
+			kr, err := cfg.OrganizationKeyRing("acme")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(kr.Keys()).To(Equal([]any{orgPubK}))
+
+			_, err = cfg.OrganizationKeyRing("other")
+			Expect(err).To(MatchError("organization other is not trusted"))
+		})
+	})
+
+	Describe("Validate", func() {
+		It("Should detect invalid configurations", func() {
+			cfg := &TrustConfig{}
+			Expect(cfg.Validate()).To(MatchError("environment is required"))
+
+			cfg.Environment = "staging"
+			Expect(cfg.Validate()).To(MatchError("at least one key or organization is required"))
+
+			cfg.Keys = []string{"invalid"}
+			Expect(cfg.Validate()).To(HaveOccurred())
+
+			cfg.Keys = nil
+			cfg.Organizations = []TrustedOrganization{{Name: "acme"}}
+			Expect(cfg.Validate()).To(MatchError("organization acme requires at least one issuer key"))
+
+			Expect(cfg.AddKey(signerPubK)).To(Succeed())
+			cfg.Organizations = nil
+			cfg.RevocationSources = []string{" "}
+			Expect(cfg.Validate()).To(MatchError("revocation sources cannot be empty"))
+
+			cfg.RevocationSources = nil
+			warn := time.Now()
+			reject := warn.Add(-time.Hour)
+			cfg.Profile.RSAWarnAfter = &warn
+			cfg.Profile.RSARejectAfter = &reject
+			Expect(cfg.Validate()).To(MatchError("rsa sunset reject time cannot be before the warn time"))
+
+			cfg.Profile.RSARejectAfter = nil
+			Expect(cfg.Validate()).To(Succeed())
+		})
+	})
+
+	Describe("ExportTrustConfig", func() {
+		It("Should export and import the configuration", func() {
+			cfg, err := NewTrustConfig("production", keys)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cfg.AddOrganization("acme", orgPubK)).To(Succeed())
+
+			reject := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+			cfg.RevocationSources = []string{"https://revocations.example.net/list"}
+			cfg.Profile = VerificationProfile{Audience: "choria_broker", Leeway: 30 * time.Second, RSARejectAfter: &reject}
+
+			doc, err := ExportTrustConfig(cfg, "production issuer", time.Hour, signerPriK)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(TokenPurpose(doc)).To(Equal(TrustConfigPurpose))
+
+			otherPubK, _, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = ImportTrustConfig(doc, otherPubK)
+			Expect(err).To(MatchError(ContainSubstring("verification error")))
+
+			imported, err := ImportTrustConfig(doc, signerPubK)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(imported.Environment).To(Equal("production"))
+			Expect(imported.Keys).To(Equal(cfg.Keys))
+			Expect(imported.Organizations).To(Equal(cfg.Organizations))
+			Expect(imported.RevocationSources).To(Equal(cfg.RevocationSources))
+			Expect(imported.Profile.Audience).To(Equal("choria_broker"))
+			Expect(imported.Profile.Leeway).To(Equal(30 * time.Second))
+			Expect(imported.Profile.RSARejectAfter.Equal(reject)).To(BeTrue())
+			Expect(imported.ParseOptions()).To(HaveLen(3))
+		})
+
+		It("Should only import trust configuration documents", func() {
+			claims, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, nil)
+			Expect(err).ToNot(HaveOccurred())
+			token, err := SignToken(claims, signerPriK)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = ImportTrustConfig(token, signerPubK)
+			Expect(err).To(MatchError(ErrNotATrustConfigToken))
+		})
+
+		It("Should not export invalid configurations", func() {
+			_, err := ExportTrustConfig(&TrustConfig{Environment: "x"}, "", time.Hour, signerPriK)
+			Expect(err).To(MatchError("at least one key or organization is required"))
+		})
+	})
+})
@@ -0,0 +1,152 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// ValidationProblem is a single reason a token is not valid
+type ValidationProblem struct {
+	// Reason classifies the problem
+	Reason VerificationFailure `json:"reason"`
+
+	// Message describes the problem
+	Message string `json:"message"`
+
+	// Err is the underlying error
+	Err error `json:"-"`
+}
+
+// ValidationReport lists every problem found with a token, see ValidateToken
+type ValidationReport struct {
+	// Purpose is the purpose of the token
+	Purpose Purpose `json:"purpose"`
+
+	// Problems are all the problems found, empty for valid tokens
+	Problems []ValidationProblem `json:"problems,omitempty"`
+}
+
+// Valid indicates no problems were found
+func (r *ValidationReport) Valid() bool {
+	return len(r.Problems) == 0
+}
+
+// Err joins all problems into a single error, nil for valid tokens
+func (r *ValidationReport) Err() error {
+	var errs []error
+	for _, p := range r.Problems {
+		errs = append(errs, p.Err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// HasReason determines if any problem is of the given reason
+func (r *ValidationReport) HasReason(reason VerificationFailure) bool {
+	for _, p := range r.Problems {
+		if p.Reason == reason {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *ValidationReport) add(err error) {
+	if err == nil {
+		return
+	}
+
+	r.Problems = append(r.Problems, ValidationProblem{Reason: VerificationFailureReason(err), Message: err.Error(), Err: err})
+}
+
+// ValidateToken checks token like ParseToken but rather than stopping at the first failure it collects every problem,
+// like an expired token that is also revoked and for the wrong audience, for diagnostic tools. claims are decoded
+// from the token but must not be trusted when problems are found. An error is only returned when the token can not
+// be decoded or the options are invalid. Replay guards are not consulted as validating would use the token
+func ValidateToken(token string, claims jwt.Claims, pk any, opts ...ParseOption) (*ValidationReport, error) {
+	return ValidateTokenContext(context.Background(), token, claims, pk, opts...)
+}
+
+// ValidateTokenContext behaves like ValidateToken, ctx is passed to introspection and revocation checks
+func ValidateTokenContext(ctx context.Context, token string, claims jwt.Claims, pk any, opts ...ParseOption) (*ValidationReport, error) {
+	popts, err := newParseOptions(append(opts, withParseContext(ctx))...)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ValidationReport{Purpose: TokenPurpose(token)}
+
+	if popts.strict != nil {
+		report.add(popts.strict.check(token))
+	}
+
+	t, err := parseUnverified(token, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	if pk == nil {
+		report.add(fmt.Errorf("%w: invalid public key", jwt.ErrTokenSignatureInvalid))
+	} else {
+		key, err := resolveKey(token, pk)
+		if err == nil {
+			err = reportSignature(token, claims, t.Method.Alg(), key)
+		}
+		if err != nil && !isSignatureError(err) {
+			err = &jwt.ValidationError{Inner: err, Errors: jwt.ValidationErrorSignatureInvalid}
+		}
+		report.add(err)
+	}
+
+	popts.checkParsed(token, claims, false, func(err error) bool {
+		report.addParsed(popts, claims, err)
+		return true
+	})
+	report.addIssuerExpiry(popts, claims)
+
+	return report, nil
+}
+
+// addParsed records a problem found after signature verification, time problems are recorded individually
+func (r *ValidationReport) addParsed(popts *parseOptions, claims jwt.Claims, err error) {
+	exp, iat, nbf, known := claimsTimes(claims)
+
+	var ve *jwt.ValidationError
+	if !known || !errors.As(err, &ve) || ve.Errors&(jwt.ValidationErrorExpired|jwt.ValidationErrorIssuedAt|jwt.ValidationErrorNotValidYet) == 0 {
+		r.add(err)
+		return
+	}
+
+	now := popts.now()
+
+	if ve.Errors&jwt.ValidationErrorExpired != 0 {
+		r.add(&jwt.ValidationError{Inner: fmt.Errorf("%s by %s", jwt.ErrTokenExpired, now.Sub(exp.Time)), Errors: jwt.ValidationErrorExpired})
+	}
+	if ve.Errors&jwt.ValidationErrorIssuedAt != 0 {
+		r.add(&jwt.ValidationError{Inner: fmt.Errorf("%s at %s", jwt.ErrTokenUsedBeforeIssued, iat.Time), Errors: jwt.ValidationErrorIssuedAt})
+	}
+	if ve.Errors&jwt.ValidationErrorNotValidYet != 0 {
+		r.add(&jwt.ValidationError{Inner: fmt.Errorf("%s until %s", jwt.ErrTokenNotValidYet, nbf.Time), Errors: jwt.ValidationErrorNotValidYet})
+	}
+}
+
+// addIssuerExpiry records tokens whose issuer has expired
+func (r *ValidationReport) addIssuerExpiry(popts *parseOptions, claims jwt.Claims) {
+	sc, ok := claims.(standardClaimsProvider)
+	if !ok {
+		return
+	}
+
+	iexp := sc.getStandardClaims().IssuerExpiresAt
+	if iexp != nil && !popts.now().Add(-popts.leeway).Before(iexp.Time) {
+		r.add(&jwt.ValidationError{Inner: fmt.Errorf("issuer expired at %s", iexp.Time), Errors: jwt.ValidationErrorExpired})
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ValidateToken", func() {
+	var (
+		pubK ed25519.PublicKey
+		priK ed25519.PrivateKey
+	)
+
+	BeforeEach(func() {
+		var err error
+		pubK, priK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Should report valid tokens", func() {
+		claims, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, nil, WithAudience("choria"))
+		Expect(err).ToNot(HaveOccurred())
+		token, err := SignToken(claims, priK)
+		Expect(err).ToNot(HaveOccurred())
+
+		report, err := ValidateToken(token, &ClientIDClaims{}, pubK, WithExpectedAudience("choria"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.Valid()).To(BeTrue())
+		Expect(report.Err()).ToNot(HaveOccurred())
+		Expect(report.Purpose).To(Equal(ClientIDPurpose))
+	})
+
+	It("Should collect all problems", func() {
+		claims, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, nil, WithAudience("other"))
+		Expect(err).ToNot(HaveOccurred())
+		claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+		token, err := SignToken(claims, priK)
+		Expect(err).ToNot(HaveOccurred())
+
+		rl, err := NewRevocationList()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rl.RevokeTokenID(claims.ID, "compromised")).To(Succeed())
+
+		otherK, _, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		report, err := ValidateToken(token, &ClientIDClaims{}, otherK, WithExpectedAudience("choria"), WithRevocations(rl))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.Valid()).To(BeFalse())
+
+		var reasons []VerificationFailure
+		for _, p := range report.Problems {
+			reasons = append(reasons, p.Reason)
+		}
+		Expect(reasons).To(Equal([]VerificationFailure{FailureSignature, FailureExpired, FailureAudience, FailureRevoked}))
+		Expect(report.HasReason(FailureExpired)).To(BeTrue())
+		Expect(report.HasReason(FailureStrict)).To(BeFalse())
+		Expect(errors.Is(report.Err(), ErrTokenRevoked)).To(BeTrue())
+		Expect(errors.Is(report.Err(), jwt.ErrTokenInvalidAudience)).To(BeTrue())
+	})
+
+	It("Should report invalid claims and time problems individually", func() {
+		claims, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		claims.NotBefore = jwt.NewNumericDate(time.Now().Add(time.Hour))
+		claims.IssuedAt = jwt.NewNumericDate(time.Now().Add(time.Hour))
+		claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+		claims.ChainMaxDepth = -1
+		token, err := SignToken(claims, priK)
+		Expect(err).ToNot(HaveOccurred())
+
+		report, err := ValidateToken(token, &ClientIDClaims{}, pubK)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.Problems).To(HaveLen(4))
+		Expect(report.Problems[0].Reason).To(Equal(FailureExpired))
+		Expect(report.Problems[1].Reason).To(Equal(FailureNotYetValid))
+		Expect(report.Problems[1].Message).To(ContainSubstring("token used before issued"))
+		Expect(report.Problems[2].Reason).To(Equal(FailureNotYetValid))
+		Expect(report.Problems[3].Message).To(Equal("chain max depth cannot be negative"))
+	})
+
+	It("Should apply the same checks as parsing", func() {
+		claims, err := NewClientIDBuilder("up=bob").WithAccessRestrictions(AccessRestrictions{SourceCIDRs: []string{"10.0.0.0/8"}}).Build()
+		Expect(err).ToNot(HaveOccurred())
+		token, err := SignToken(claims, priK)
+		Expect(err).ToNot(HaveOccurred())
+
+		parseErr := ParseToken(token, &ClientIDClaims{}, pubK, WithAccessSource(net.ParseIP("192.168.1.1")))
+		Expect(parseErr).To(HaveOccurred())
+
+		report, err := ValidateToken(token, &ClientIDClaims{}, pubK, WithAccessSource(net.ParseIP("192.168.1.1")))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.Problems).To(HaveLen(1))
+		Expect(report.Problems[0].Message).To(Equal(parseErr.Error()))
+
+		report, err = ValidateToken(token, &ClientIDClaims{}, pubK, WithAccessSource(net.ParseIP("10.1.1.1")))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.Valid()).To(BeTrue())
+	})
+
+	It("Should fail for undecodable tokens", func() {
+		_, err := ValidateToken("invalid", &ClientIDClaims{}, pubK)
+		Expect(err).To(HaveOccurred())
+		_, err = ValidateToken("invalid", &ClientIDClaims{}, pubK, WithLeeway(-1))
+		Expect(err).To(MatchError("leeway cannot be negative"))
+	})
+})
@@ -0,0 +1,297 @@
+// Copyright (c) 2021-2023, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !js
+
+package tokens
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/sirupsen/logrus"
+)
+
+func init() {
+	platformKeyRefBackends["vault"] = func(o *keyRefResolverOptions) KeyRefBackend {
+		return &vaultKeyRefBackend{tlsc: o.tlsc, log: o.log}
+	}
+}
+
+// AddOrgIssuerDataUsingVault adds the data that a Chain Issuer needs to be able to issue clients in an Org managed by an Issuer by using Vault to sign the data using the named key `priK`.
+func (c *StandardClaims) AddOrgIssuerDataUsingVault(ctx context.Context, tlsc *tls.Config, priK string, log *logrus.Entry) error {
+	issuer, err := getVaultIssuerPubKey(ctx, tlsc, priK, log)
+	if err != nil {
+		return err
+	}
+
+	err = c.checkIssuerKey(issuer)
+	if err != nil {
+		return err
+	}
+
+	dat, err := c.OrgIssuerChainData()
+	if err != nil {
+		return err
+	}
+
+	sig, err := signWithVault(ctx, tlsc, priK, dat, log)
+	if err != nil {
+		return err
+	}
+
+	err = c.SetOrgIssuer(issuer)
+	if err != nil {
+		return err
+	}
+
+	c.SetChainIssuerTrustSignature(sig)
+
+	return nil
+}
+
+func getVaultIssuerPubKey(ctx context.Context, tlsc *tls.Config, key string, log *logrus.Entry) (ed25519.PublicKey, error) {
+	ctx, span := startSpan(ctx, "tokens.vault.public_key")
+	pk, err := fetchVaultIssuerPubKey(ctx, tlsc, key, log)
+	span.End(err)
+
+	return pk, err
+}
+
+func fetchVaultIssuerPubKey(ctx context.Context, tlsc *tls.Config, key string, log *logrus.Entry) (ed25519.PublicKey, error) {
+	vt := os.Getenv("VAULT_TOKEN")
+	va := os.Getenv("VAULT_ADDR")
+
+	if vt == "" || va == "" {
+		return nil, fmt.Errorf("requires VAULT_TOKEN and VAULT_ADDR environment variables")
+	}
+
+	uri, err := url.Parse(va)
+	if err != nil {
+		return nil, err
+	}
+
+	uri.Path = fmt.Sprintf("/v1/transit/keys/%s", key)
+	client := &http.Client{}
+	if tlsc != nil {
+		client.Transport = &http.Transport{TLSClientConfig: tlsc}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", uri.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("X-Vault-Token", vt)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("request failed: code: %d: %s", resp.StatusCode, string(body))
+	}
+
+	log.Debugf("JSON Response: %s", string(body))
+
+	var vr struct {
+		Data struct {
+			Keys map[string]struct {
+				PublicKey []byte `json:"public_key"`
+			} `json:"keys"`
+		} `json:"data"`
+	}
+	err = json.Unmarshal(body, &vr)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(vr.Data.Keys) == 0 {
+		return nil, fmt.Errorf("did not receive keys in response")
+	}
+
+	pk, ok := vr.Data.Keys["1"]
+	if !ok {
+		return nil, fmt.Errorf("did not receive keys in response")
+	}
+
+	if len(pk.PublicKey) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("did not receive a valid public key in response")
+	}
+
+	return pk.PublicKey, nil
+}
+
+func signWithVault(ctx context.Context, tlsc *tls.Config, key string, ss []byte, log *logrus.Entry) ([]byte, error) {
+	ctx, span := startSpan(ctx, "tokens.vault.sign")
+	sig, err := vaultSign(ctx, tlsc, key, ss, log)
+	span.End(err)
+
+	return sig, err
+}
+
+func vaultSign(ctx context.Context, tlsc *tls.Config, key string, ss []byte, log *logrus.Entry) ([]byte, error) {
+	vt := os.Getenv("VAULT_TOKEN")
+	va := os.Getenv("VAULT_ADDR")
+
+	if vt == "" || va == "" {
+		return nil, fmt.Errorf("requires VAULT_TOKEN and VAULT_ADDR environment variables")
+	}
+
+	uri, err := url.Parse(va)
+	if err != nil {
+		return nil, err
+	}
+
+	uri.Path = fmt.Sprintf("/v1/transit/sign/%s", key)
+
+	dat := map[string]any{
+		"signature_algorithm": "ed25519",
+		"input":               base64.StdEncoding.EncodeToString(ss),
+	}
+	jdat, err := json.Marshal(dat)
+	if err != nil {
+		return nil, err
+	}
+	log.Debugf("JSON Request: %s", string(jdat))
+
+	client := &http.Client{}
+	if tlsc != nil {
+		client.Transport = &http.Transport{TLSClientConfig: tlsc}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", uri.String(), bytes.NewBuffer(jdat))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("X-Vault-Token", vt)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("request failed: code: %d: %s", resp.StatusCode, string(body))
+	}
+
+	log.Debugf("JSON Response: %s", string(body))
+
+	var vr struct {
+		Data struct {
+			Sig string `json:"signature"`
+		} `json:"data"`
+	}
+	err = json.Unmarshal(body, &vr)
+	if err != nil {
+		return nil, err
+	}
+
+	if vr.Data.Sig == "" {
+		return nil, fmt.Errorf("no signature in response: %s", string(body))
+	}
+
+	const vaultSigPrefix = "vault:v1:"
+
+	if !strings.HasPrefix(vr.Data.Sig, vaultSigPrefix) {
+		return nil, fmt.Errorf("invalid signature, no vault:v1 prefix")
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(vr.Data.Sig, vaultSigPrefix))
+	if err != nil {
+		return nil, fmt.Errorf("could not decode vault response: %w", err)
+	}
+
+	return signature, nil
+}
+
+// SaveAndSignTokenWithVault signs a token using the named key in a Vault Transit engine.  Requires VAULT_TOKEN and VAULT_ADDR to be set.
+func SaveAndSignTokenWithVault(ctx context.Context, claims jwt.Claims, key string, outFile string, perm os.FileMode, tlsc *tls.Config, log *logrus.Entry) error {
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	ss, err := token.SigningString()
+	if err != nil {
+		return err
+	}
+
+	signature, err := signWithVault(ctx, tlsc, key, []byte(ss), log)
+	if err != nil {
+		return err
+	}
+
+	signed := fmt.Sprintf("%s.%s", ss, strings.TrimRight(base64.RawURLEncoding.EncodeToString(signature), "="))
+
+	err = recordIssuance(ctx, claims, jwt.SigningMethodEdDSA.Alg(), "vault:"+key)
+	if err != nil {
+		return err
+	}
+
+	return SaveToken(signed, outFile, perm)
+}
+
+// vaultKeyRefBackend uses ed25519 keys in the Vault Transit engine, requires VAULT_TOKEN and VAULT_ADDR to be set
+type vaultKeyRefBackend struct {
+	tlsc *tls.Config
+	log  *logrus.Entry
+}
+
+func (b *vaultKeyRefBackend) Signer(ctx context.Context, location string) (crypto.Signer, error) {
+	pk, err := getVaultIssuerPubKey(ctx, b.tlsc, location, b.log)
+	if err != nil {
+		return nil, err
+	}
+
+	return &vaultSigner{key: location, pk: pk, tlsc: b.tlsc, log: b.log}, nil
+}
+
+func (b *vaultKeyRefBackend) PublicKey(ctx context.Context, location string) (crypto.PublicKey, error) {
+	return getVaultIssuerPubKey(ctx, b.tlsc, location, b.log)
+}
+
+// vaultSigner is a ContextSigner signing using a key in the Vault Transit engine
+type vaultSigner struct {
+	key  string
+	pk   ed25519.PublicKey
+	tlsc *tls.Config
+	log  *logrus.Entry
+}
+
+func (s *vaultSigner) Public() crypto.PublicKey {
+	return s.pk
+}
+
+func (s *vaultSigner) Sign(rand io.Reader, msg []byte, opts crypto.SignerOpts) ([]byte, error) {
+	return s.SignContext(context.Background(), rand, msg, opts)
+}
+
+func (s *vaultSigner) SignContext(ctx context.Context, _ io.Reader, msg []byte, opts crypto.SignerOpts) ([]byte, error) {
+	if opts != nil && opts.HashFunc() != crypto.Hash(0) {
+		return nil, fmt.Errorf("vault signer only supports ed25519 signatures")
+	}
+
+	return signWithVault(ctx, s.tlsc, s.key, msg, s.log)
+}
@@ -0,0 +1,206 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// VerifierCache remembers tokens whose signatures, including any issuer chain, were verified so that repeated
+// verification of the same token only decodes it and performs the time, audience, revocation and other checks.
+//
+// Only tokens verified using an ed25519.PublicKey are cached, entries expire after the cache ttl or when the token expires
+type VerifierCache struct {
+	ttl        time.Duration
+	maxEntries int
+	entries    map[[sha256.Size]byte]time.Time
+	stats      VerifierCacheStats
+	mu         sync.Mutex
+}
+
+// VerifierCacheStats describes the effectiveness of a VerifierCache
+type VerifierCacheStats struct {
+	// Hits is how many verifications were answered from the cache
+	Hits uint64
+
+	// Misses is how many verifications required verifying the signature
+	Misses uint64
+
+	// Entries is the number of cached tokens
+	Entries int
+}
+
+// NewVerifierCache creates a cache holding up to maxEntries verified tokens for at most ttl
+func NewVerifierCache(ttl time.Duration, maxEntries int) (*VerifierCache, error) {
+	if ttl <= 0 {
+		return nil, fmt.Errorf("ttl must be positive")
+	}
+
+	if maxEntries <= 0 {
+		return nil, fmt.Errorf("max entries must be positive")
+	}
+
+	return &VerifierCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[[sha256.Size]byte]time.Time),
+	}, nil
+}
+
+// ParseToken behaves like ParseToken but skips signature verification for tokens previously verified using pk
+func (c *VerifierCache) ParseToken(token string, claims jwt.Claims, pk any, opts ...ParseOption) error {
+	edpk, ok := pk.(ed25519.PublicKey)
+	if !ok {
+		return ParseToken(token, claims, pk, opts...)
+	}
+
+	popts, err := newParseOptions(opts...)
+	if err != nil {
+		return err
+	}
+
+	key := verifierCacheKey(token, edpk)
+
+	if c.cached(key, popts.now()) {
+		if popts.strict != nil {
+			err = popts.strict.check(token)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = parseUnverified(token, claims)
+		if err != nil {
+			return err
+		}
+
+		return popts.validateParsed(token, claims)
+	}
+
+	err = ParseToken(token, claims, pk, opts...)
+	if err != nil {
+		return err
+	}
+
+	c.store(key, claims, popts.now())
+
+	return nil
+}
+
+// Purge removes all cached tokens, for example after trusted keys were rotated
+func (c *VerifierCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[[sha256.Size]byte]time.Time)
+}
+
+// Stats reports cache hits, misses and size
+func (c *VerifierCache) Stats() VerifierCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Entries = len(c.entries)
+
+	return stats
+}
+
+func (c *VerifierCache) cached(key [sha256.Size]byte, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires, ok := c.entries[key]
+	if ok && now.Before(expires) {
+		c.stats.Hits++
+		return true
+	}
+
+	if ok {
+		delete(c.entries, key)
+	}
+
+	c.stats.Misses++
+
+	return false
+}
+
+func (c *VerifierCache) store(key [sha256.Size]byte, claims jwt.Claims, now time.Time) {
+	expires := now.Add(c.ttl)
+
+	if sc, ok := claims.(standardClaimsProvider); ok {
+		expires = verifiedUntil(sc.getStandardClaims(), expires)
+	}
+
+	if !now.Before(expires) {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) >= c.maxEntries {
+		for k, e := range c.entries {
+			if !now.Before(e) {
+				delete(c.entries, k)
+			}
+		}
+	}
+
+	// still full, make room by dropping an arbitrary entry
+	for k := range c.entries {
+		if len(c.entries) < c.maxEntries {
+			break
+		}
+		delete(c.entries, k)
+	}
+
+	c.entries[key] = expires
+}
+
+func verifierCacheKey(token string, pk ed25519.PublicKey) [sha256.Size]byte {
+	h := sha256.New()
+	h.Write(pk)
+	h.Write([]byte(token))
+
+	var key [sha256.Size]byte
+	copy(key[:], h.Sum(nil))
+
+	return key
+}
+
+// verifiedUntil limits expires to when the verification of std lapses, that is the expiry of the token and its
+// issuer and the expiry and delegation expiry of every token in its issuer chain
+func verifiedUntil(std *StandardClaims, expires time.Time) time.Time {
+	earliest := func(t time.Time) {
+		if !t.IsZero() && t.Before(expires) {
+			expires = t
+		}
+	}
+
+	earliest(std.ExpireTime())
+
+	for _, link := range std.IssuerChain {
+		issuer := &ClientIDClaims{}
+		_, err := parseUnverified(link, issuer)
+		if err != nil {
+			// the chain was verified so this cannot happen, but never cache what we cannot bound
+			return time.Time{}
+		}
+
+		earliest(issuer.ExpireTime())
+		if issuer.ChainDelegationExpiresAt != nil {
+			earliest(issuer.ChainDelegationExpiresAt.Time)
+		}
+	}
+
+	return expires
+}
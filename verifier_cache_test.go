@@ -0,0 +1,138 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("VerifierCache", func() {
+	var (
+		pubK  ed25519.PublicKey
+		priK  ed25519.PrivateKey
+		cache *VerifierCache
+		token string
+	)
+
+	BeforeEach(func() {
+		var err error
+		pubK, priK, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		cache, err = NewVerifierCache(time.Minute, 2)
+		Expect(err).ToNot(HaveOccurred())
+
+		serverPubK, _, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		server, err := NewServerClaims("n1.example.net", []string{"choria"}, "", nil, nil, serverPubK, "", time.Hour)
+		Expect(err).ToNot(HaveOccurred())
+		token, err = SignToken(server, priK)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Should validate its settings", func() {
+		_, err := NewVerifierCache(0, 1)
+		Expect(err).To(MatchError("ttl must be positive"))
+		_, err = NewVerifierCache(time.Minute, 0)
+		Expect(err).To(MatchError("max entries must be positive"))
+	})
+
+	It("Should cache verified tokens", func() {
+		for i := 0; i < 3; i++ {
+			claims := &ServerClaims{}
+			Expect(cache.ParseToken(token, claims, pubK)).To(Succeed())
+			Expect(claims.ChoriaIdentity).To(Equal("n1.example.net"))
+		}
+
+		Expect(cache.Stats()).To(Equal(VerifierCacheStats{Hits: 2, Misses: 1, Entries: 1}))
+
+		cache.Purge()
+		Expect(cache.Stats().Entries).To(Equal(0))
+	})
+
+	It("Should not cache failures and still apply parse options on hits", func() {
+		otherPubK, _, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(cache.ParseToken(token, &ServerClaims{}, otherPubK)).ToNot(Succeed())
+		Expect(cache.ParseToken(token, &ServerClaims{}, otherPubK)).ToNot(Succeed())
+		Expect(cache.Stats().Entries).To(Equal(0))
+
+		Expect(cache.ParseToken(token, &ServerClaims{}, pubK)).To(Succeed())
+		err = cache.ParseToken(token, &ServerClaims{}, pubK, WithExpectedAudience("other"))
+		Expect(err).To(HaveOccurred())
+		Expect(cache.Stats().Hits).To(Equal(uint64(1)))
+	})
+
+	It("Should expire entries using the verification clock", func() {
+		Expect(cache.ParseToken(token, &ServerClaims{}, pubK)).To(Succeed())
+
+		later := WithVerificationClock(FixedClock(time.Now().Add(2 * time.Minute)))
+		Expect(cache.ParseToken(token, &ServerClaims{}, pubK, later)).To(Succeed())
+		Expect(cache.Stats()).To(Equal(VerifierCacheStats{Hits: 0, Misses: 2, Entries: 1}))
+
+		expired := WithVerificationClock(FixedClock(time.Now().Add(2 * time.Hour)))
+		Expect(cache.ParseToken(token, &ServerClaims{}, pubK, expired)).To(MatchError(ContainSubstring("expired")))
+		Expect(cache.Stats().Hits).To(Equal(uint64(0)))
+	})
+
+	It("Should bound the number of entries", func() {
+		for i := 0; i < 5; i++ {
+			client, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, nil)
+			Expect(err).ToNot(HaveOccurred())
+			t, err := SignToken(client, priK)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cache.ParseToken(t, &ClientIDClaims{}, pubK)).To(Succeed())
+		}
+
+		Expect(cache.Stats().Entries).To(Equal(2))
+	})
+
+	It("Should expire entries with the token", func() {
+		client, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Second, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		t, err := SignToken(client, priK)
+		Expect(err).ToNot(HaveOccurred())
+
+		key := verifierCacheKey(t, pubK)
+		Expect(cache.ParseToken(t, &ClientIDClaims{}, pubK)).To(Succeed())
+		Expect(cache.entries[key]).To(BeTemporally("~", client.ExpiresAt.Time, time.Second))
+	})
+
+	It("Should expire entries with the delegation of chain issuers", func() {
+		delegation := time.Now().Add(30 * time.Second)
+		issuerPubK, issuerPriK, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		issuer, err := NewClientIDClaims("aaa=login", nil, "", nil, "", "", time.Hour, &ClientPermissions{AuthenticationDelegator: true}, issuerPubK, WithChainDelegationExpiry(delegation))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(issuer.AddOrgIssuerData(priK)).To(Succeed())
+		issuerJWT, err := SignToken(issuer, priK)
+		Expect(err).ToNot(HaveOccurred())
+
+		userPubK, _, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		user, err := NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, userPubK)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(user.AddChainIssuerData(issuer, issuerPriK)).To(Succeed())
+		user.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Hour))
+		user.IssuerExpiresAt = user.ExpiresAt
+		t, err := SignToken(user, issuerPriK, WithIssuerChain(issuerJWT))
+		Expect(err).ToNot(HaveOccurred())
+
+		key := verifierCacheKey(t, pubK)
+		Expect(cache.ParseToken(t, &ClientIDClaims{}, pubK)).To(Succeed())
+		Expect(cache.entries[key]).To(BeTemporally("~", delegation, time.Second))
+
+		lapsed := WithVerificationClock(FixedClock(delegation.Add(time.Second)))
+		Expect(cache.ParseToken(t, &ClientIDClaims{}, pubK, lapsed)).To(MatchError(ErrChainDelegationExpired))
+		Expect(cache.Stats().Hits).To(Equal(uint64(0)))
+	})
+})
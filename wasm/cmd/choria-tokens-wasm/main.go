@@ -0,0 +1,44 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build js && wasm
+
+// Command choria-tokens-wasm registers a choriaTokens object in the JavaScript global scope with purpose(token) and
+// verify(token, key, audience) functions, verify returns a JSON document.
+//
+//	GOOS=js GOARCH=wasm go build -o choria-tokens.wasm ./wasm/cmd/choria-tokens-wasm
+package main
+
+import (
+	"syscall/js"
+
+	"github.com/choria-io/tokens/wasm"
+)
+
+func main() {
+	js.Global().Set("choriaTokens", js.ValueOf(map[string]any{
+		"purpose": js.FuncOf(func(_ js.Value, args []js.Value) any {
+			if len(args) < 1 {
+				return ""
+			}
+
+			return wasm.Purpose(args[0].String())
+		}),
+
+		"verify": js.FuncOf(func(_ js.Value, args []js.Value) any {
+			if len(args) < 2 {
+				return `{"error":"token and key are required"}`
+			}
+
+			audience := ""
+			if len(args) > 2 && args[2].Type() == js.TypeString {
+				audience = args[2].String()
+			}
+
+			return wasm.VerifyJSON(args[0].String(), args[1].String(), audience)
+		}),
+	}))
+
+	select {}
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package wasm exposes token inspection and verification in a form suitable for WebAssembly builds, keys and
+// results are passed as strings and JSON so browser based dashboards can verify tokens client side without file
+// or network access. See cmd/choria-tokens-wasm for the JavaScript bindings.
+//
+// On js the tokens package leaves out the NATS, Vault, introspection, OpenSSH key and file persistence features so
+// they do not add to the size of the WebAssembly binary
+package wasm
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/choria-io/tokens"
+)
+
+// Result is the outcome of Verify
+type Result struct {
+	// Report describes the token
+	Report *tokens.TokenReport `json:"report"`
+
+	// Validation lists every problem found with the token
+	Validation *tokens.ValidationReport `json:"validation"`
+}
+
+// Purpose returns the purpose of token without verifying it
+func Purpose(token string) string {
+	return string(tokens.TokenPurpose(token))
+}
+
+// Verify describes and validates token using key, a hex encoded ed25519 public key or a PEM encoded RSA public key
+// or certificate, audience is required when not empty
+func Verify(token string, key string, audience string) (*Result, error) {
+	pk, err := tokens.ReadVerificationKey(strings.NewReader(key))
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := tokens.DescribeToken(token, pk)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := tokens.NewClaimsForPurpose(tokens.TokenPurpose(token))
+	if err != nil {
+		return nil, err
+	}
+
+	var opts []tokens.ParseOption
+	if audience != "" {
+		opts = append(opts, tokens.WithExpectedAudience(audience))
+	}
+
+	validation, err := tokens.ValidateToken(token, claims, pk, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{Report: report, Validation: validation}, nil
+}
+
+// VerifyJSON behaves like Verify and returns the result as JSON, failures are returned as a JSON object with an
+// error key so callers in JavaScript always receive a JSON document
+func VerifyJSON(token string, key string, audience string) string {
+	res, err := Verify(token, key, audience)
+	if err != nil {
+		return errorJSON(err)
+	}
+
+	j, err := json.Marshal(res)
+	if err != nil {
+		return errorJSON(err)
+	}
+
+	return string(j)
+}
+
+func errorJSON(err error) string {
+	j, jerr := json.Marshal(map[string]string{"error": err.Error()})
+	if jerr != nil {
+		return fmt.Sprintf(`{"error":%q}`, err.Error())
+	}
+
+	return string(j)
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package wasm
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/choria-io/tokens"
+)
+
+// Ginkgo does not support js/wasm, these tests run the verification path under node using
+// GOOS=js GOARCH=wasm go test with go_js_wasm_exec in the PATH
+
+func signedToken(t *testing.T) (string, string) {
+	t.Helper()
+
+	pub, pri, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate key: %v", err)
+	}
+
+	claims, err := tokens.NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, nil, tokens.WithAudience("choria"))
+	if err != nil {
+		t.Fatalf("could not create claims: %v", err)
+	}
+
+	token, err := tokens.SignToken(claims, pri)
+	if err != nil {
+		t.Fatalf("could not sign token: %v", err)
+	}
+
+	return token, hex.EncodeToString(pub)
+}
+
+func TestPurpose(t *testing.T) {
+	token, _ := signedToken(t)
+
+	if p := Purpose(token); p != "choria_client_id" {
+		t.Fatalf("expected choria_client_id got %q", p)
+	}
+
+	if p := Purpose("invalid"); p != "" {
+		t.Fatalf("expected no purpose got %q", p)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	token, pubKey := signedToken(t)
+
+	res, err := Verify(token, pubKey, "choria")
+	if err != nil {
+		t.Fatalf("verify failed: %v", err)
+	}
+	if res.Report.Identity != "up=bob" || !res.Report.Valid || !res.Validation.Valid() {
+		t.Fatalf("expected a valid token for up=bob got %+v", res.Report)
+	}
+
+	res, err = Verify(token, pubKey, "other")
+	if err != nil {
+		t.Fatalf("verify failed: %v", err)
+	}
+	if !res.Validation.HasReason(tokens.FailureAudience) {
+		t.Fatalf("expected an audience failure")
+	}
+
+	_, otherKey := signedToken(t)
+	res, err = Verify(token, otherKey, "")
+	if err != nil {
+		t.Fatalf("verify failed: %v", err)
+	}
+	if res.Validation.Valid() {
+		t.Fatalf("expected a token signed by another key to be invalid")
+	}
+}
+
+func TestVerifyJSON(t *testing.T) {
+	token, pubKey := signedToken(t)
+
+	var res map[string]any
+	err := json.Unmarshal([]byte(VerifyJSON(token, pubKey, "")), &res)
+	if err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	report, _ := res["report"].(map[string]any)
+	if report["identity"] != "up=bob" {
+		t.Fatalf("expected a report for up=bob got %v", res)
+	}
+
+	err = json.Unmarshal([]byte(VerifyJSON(token, "invalid", "")), &res)
+	if err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if msg, _ := res["error"].(string); !strings.Contains(msg, "could not parse ed25519 public data") {
+		t.Fatalf("expected a key parse error got %v", res)
+	}
+}
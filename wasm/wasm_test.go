@@ -0,0 +1,64 @@
+// Copyright (c) 2026-2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !js
+
+package wasm
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/choria-io/tokens"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestWASM(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "WASM")
+}
+
+var _ = Describe("WASM", func() {
+	var token, pubKey string
+
+	BeforeEach(func() {
+		claims, err := tokens.NewClientIDClaims("up=bob", nil, "", nil, "", "", time.Hour, nil, nil, tokens.WithAudience("choria"))
+		Expect(err).ToNot(HaveOccurred())
+		token, err = tokens.SignTokenWithKeyFile(claims, "../testdata/ed25519/signer.seed")
+		Expect(err).ToNot(HaveOccurred())
+
+		pk, err := os.ReadFile("../testdata/ed25519/signer.public")
+		Expect(err).ToNot(HaveOccurred())
+		pubKey = string(pk)
+	})
+
+	It("Should detect the purpose", func() {
+		Expect(Purpose(token)).To(Equal("choria_client_id"))
+		Expect(Purpose("invalid")).To(Equal(""))
+	})
+
+	It("Should verify tokens", func() {
+		res, err := Verify(token, pubKey, "choria")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.Report.Identity).To(Equal("up=bob"))
+		Expect(res.Report.Valid).To(BeTrue())
+		Expect(res.Validation.Valid()).To(BeTrue())
+
+		res, err = Verify(token, pubKey, "other")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.Validation.HasReason(tokens.FailureAudience)).To(BeTrue())
+	})
+
+	It("Should produce JSON", func() {
+		var res map[string]any
+		Expect(json.Unmarshal([]byte(VerifyJSON(token, pubKey, "")), &res)).To(Succeed())
+		Expect(res["report"]).To(HaveKeyWithValue("identity", "up=bob"))
+
+		Expect(json.Unmarshal([]byte(VerifyJSON(token, "invalid", "")), &res)).To(Succeed())
+		Expect(res["error"]).To(ContainSubstring("could not parse ed25519 public data"))
+	})
+})